@@ -0,0 +1,138 @@
+// Package age encrypts shared vault bundles to X25519 recipients in the age
+// file format (https://age-encryption.org/v1) using filippo.io/age, so they
+// can be opened with gpasswd or with the age command-line tool using keys
+// from age-keygen
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// ErrNoIdentityMatched is returned when none of the identities can open a file
+var ErrNoIdentityMatched = errors.New("no identity matched any of the file's recipients")
+
+// Recipient is an X25519 public key ("age1...")
+type Recipient struct {
+	x25519 *age.X25519Recipient
+}
+
+// ParseRecipient parses an "age1..." public key
+func ParseRecipient(s string) (*Recipient, error) {
+	r, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("malformed recipient %q: %w", s, err)
+	}
+	return &Recipient{x25519: r}, nil
+}
+
+// String returns the "age1..." encoding of the recipient
+func (r *Recipient) String() string {
+	return r.x25519.String()
+}
+
+// Identity is an X25519 private key ("AGE-SECRET-KEY-1...")
+type Identity struct {
+	x25519 *age.X25519Identity
+}
+
+// GenerateIdentity creates a new random identity
+func GenerateIdentity() (*Identity, error) {
+	i, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return &Identity{x25519: i}, nil
+}
+
+// ParseIdentity parses an "AGE-SECRET-KEY-1..." private key
+func ParseIdentity(s string) (*Identity, error) {
+	i, err := age.ParseX25519Identity(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("malformed identity: %w", err)
+	}
+	return &Identity{x25519: i}, nil
+}
+
+// ParseIdentities reads an identity file in age-keygen format: one key per
+// line, blank lines and "#" comments ignored
+func ParseIdentities(data []byte) ([]*Identity, error) {
+	var identities []*Identity
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		identity, err := ParseIdentity(line)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, errors.New("no identities found")
+	}
+	return identities, nil
+}
+
+// Recipient returns the public key matching the identity
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{x25519: i.x25519.Recipient()}
+}
+
+// String returns the "AGE-SECRET-KEY-1..." encoding of the identity
+func (i *Identity) String() string {
+	return i.x25519.String()
+}
+
+// Encrypt encrypts plaintext to all recipients
+func Encrypt(plaintext []byte, recipients []*Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	rs := make([]age.Recipient, len(recipients))
+	for i, r := range recipients {
+		rs[i] = r.x25519
+	}
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, rs...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decrypt opens an age file with the first matching identity
+func Decrypt(data []byte, identities []*Identity) ([]byte, error) {
+	ids := make([]age.Identity, len(identities))
+	for i, identity := range identities {
+		ids[i] = identity.x25519
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), ids...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, ErrNoIdentityMatched
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,91 @@
+package age
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	alice, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eve, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger than one 64 KiB payload chunk
+	plaintext := bytes.Repeat([]byte("shared vault bundle\n"), 5000)
+	data, err := Encrypt(plaintext, []*Recipient{alice.Recipient(), bob.Recipient()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("age-encryption.org/v1\n-> X25519 ")) {
+		t.Fatalf("unexpected header: %q", data[:40])
+	}
+
+	for _, identity := range []*Identity{alice, bob} {
+		got, err := Decrypt(data, []*Identity{eve, identity})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatal("decrypted data differs")
+		}
+	}
+
+	if _, err := Decrypt(data, []*Identity{eve}); !errors.Is(err, ErrNoIdentityMatched) {
+		t.Errorf("Decrypt with the wrong identity = %v, want ErrNoIdentityMatched", err)
+	}
+
+	data[len(data)-1] ^= 1
+	if _, err := Decrypt(data, []*Identity{alice}); err == nil {
+		t.Error("Decrypt accepted a modified payload")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(identity.String(), "AGE-SECRET-KEY-1") {
+		t.Errorf("identity = %q", identity.String())
+	}
+	if !strings.HasPrefix(identity.Recipient().String(), "age1") {
+		t.Errorf("recipient = %q", identity.Recipient().String())
+	}
+
+	file := "# created: 2024-01-01\n\n" + identity.String() + "\n"
+	identities, err := ParseIdentities([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 || identities[0].Recipient().String() != identity.Recipient().String() {
+		t.Error("parsed identity does not match")
+	}
+
+	recipient, err := ParseRecipient(" " + identity.Recipient().String() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipient.String() != identity.Recipient().String() {
+		t.Error("parsed recipient does not match")
+	}
+
+	for _, bad := range []string{"", "age1", identity.String()} {
+		if _, err := ParseRecipient(bad); err == nil {
+			t.Errorf("ParseRecipient(%q) succeeded", bad)
+		}
+	}
+	if _, err := ParseIdentities([]byte("# only a comment\n")); err == nil {
+		t.Error("ParseIdentities accepted a file without keys")
+	}
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// checkTick is how often the agent looks for vaults due for a check
+const checkTick = time.Minute
+
+// CheckFunc audits a vault with its key and returns the number of findings
+// by kind. It runs in the background of the agent and must not keep the
+// key, which is wiped when it returns
+type CheckFunc func(vault string, key []byte) (map[string]int, error)
+
+// CheckResult is the outcome of the last background check of a vault
+// It holds counts only, never entry names or secrets
+type CheckResult struct {
+	Vault     string         `json:"vault"`
+	CheckedAt time.Time      `json:"checked_at"`
+	Findings  map[string]int `json:"findings,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// Total returns the number of findings of every kind
+func (r CheckResult) Total() int {
+	total := 0
+	for _, count := range r.Findings {
+		total += count
+	}
+	return total
+}
+
+// CheckEvery makes the agent run check on each vault it holds a key for,
+// right after the key is handed over and then every interval
+// Call it before Serve; results are kept in memory only
+func (s *Server) CheckEvery(interval time.Duration, check CheckFunc) {
+	s.checkInterval = interval
+	s.check = check
+}
+
+// runChecks checks vaults that are due until the agent stops
+func (s *Server) runChecks() {
+	if s.check == nil || s.checkInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(checkTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		for _, vault := range s.dueVaults(time.Now()) {
+			s.checkVault(vault)
+		}
+	}
+}
+
+// dueVaults returns the vaults never checked since their key was handed
+// over, or not within the check interval
+func (s *Server) dueVaults(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []string
+	for vault, held := range s.keys {
+		if held.checkedAt.IsZero() || now.Sub(held.checkedAt) >= s.checkInterval {
+			due = append(due, vault)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// checkVault runs the check on a copy of a vault's key, if the agent still
+// holds it
+func (s *Server) checkVault(vault string) {
+	s.mu.Lock()
+	held, ok := s.keys[vault]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	held.checkedAt = time.Now()
+	key := append([]byte(nil), held.key...)
+	s.mu.Unlock()
+
+	findings, err := s.check(vault, key)
+	wipe(key)
+
+	result := &CheckResult{Vault: vault, CheckedAt: time.Now().UTC(), Findings: findings}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.results[vault] = result
+	s.mu.Unlock()
+}
+
+// checkResults returns the last check of every vault sorted by vault; the
+// caller holds s.mu
+func (s *Server) checkResults() []CheckResult {
+	results := make([]CheckResult, 0, len(s.results))
+	for _, result := range s.results {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Vault < results[j].Vault })
+	return results
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dialTimeout bounds connecting to and talking with the agent, so a hung
+// agent never blocks a command
+const dialTimeout = 2 * time.Second
+
+// Status describes a running agent
+type Status struct {
+	PID         int
+	Protocol    int           // protocol version spoken with the agent
+	Vaults      int           // vaults currently unlocked
+	IdleTimeout time.Duration // 0 = keys are kept until locked
+	Checks      []CheckResult // last background check of each vault
+}
+
+// Get returns the key the agent holds for a vault
+// Fails with ErrNoAgent if no agent listens on socket, with ErrNotUnlocked
+// if it has no key for the vault and with ErrRefused if this program may
+// not have it
+func Get(socket, vault string) ([]byte, error) {
+	resp, err := call(socket, request{Op: OpGet, Vault: vault})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// GetFields returns the fields key of a vault (see crypto.FieldsKey),
+// derived by the agent so the vault key never leaves it
+func GetFields(socket, vault string) ([]byte, error) {
+	resp, err := call(socket, request{Op: OpGetFields, Vault: vault})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// Put hands the key of a vault to the agent
+// Like Lock and Stop it fails with ErrRefused unless this program runs the
+// agent's executable (or the request was confirmed)
+func Put(socket, vault string, key []byte) error {
+	_, err := call(socket, request{Op: OpPut, Vault: vault, Key: key})
+	return err
+}
+
+// Lock makes the agent forget every key
+func Lock(socket string) error {
+	_, err := call(socket, request{Op: OpLock})
+	return err
+}
+
+// Stop makes the agent forget every key and exit
+func Stop(socket string) error {
+	_, err := call(socket, request{Op: OpStop})
+	return err
+}
+
+// GetStatus asks the agent about itself
+func GetStatus(socket string) (*Status, error) {
+	resp, err := call(socket, request{Op: OpStatus})
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		PID:         resp.PID,
+		Protocol:    resp.Version,
+		Vaults:      resp.Vaults,
+		IdleTimeout: time.Duration(resp.IdleTimeout) * time.Second,
+		Checks:      resp.Checks,
+	}, nil
+}
+
+// call sends one request and reads the response
+// It says hello first and falls back to JSON lines for protocol 1 agents,
+// which refuse the hello but keep reading
+func call(socket string, req request) (*response, error) {
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return nil, ErrNoAgent
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	reader := bufio.NewReader(conn)
+	hello, err := exchange(conn, reader, request{Version: ProtocolVersion, Op: OpHello, Capabilities: capabilities}, readLine, writeLine)
+	if err != nil {
+		return nil, err
+	}
+
+	read, write := readFrame, writeFrame
+	agentCapabilities := hello.Capabilities
+	req.Version = hello.Version
+	if !hello.OK || hello.Version <= legacyVersion {
+		read, write = readLine, writeLine
+		agentCapabilities = legacyCapabilities
+		req.Version = legacyVersion
+	}
+	if !supports(agentCapabilities, req.Op) {
+		return nil, ErrUnsupported
+	}
+
+	resp, err := exchange(conn, reader, req, read, write)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		switch resp.Error {
+		case ErrNotUnlocked.Error():
+			return nil, ErrNotUnlocked
+		case ErrRefused.Error():
+			return nil, ErrRefused
+		}
+		return nil, errors.New("agent: " + resp.Error)
+	}
+	return resp, nil
+}
+
+// exchange sends one message and reads the answer
+func exchange(conn net.Conn, reader *bufio.Reader, req request, read readFunc, write writeFunc) (*response, error) {
+	if err := write(conn, req); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+	data, err := read(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid agent response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package agent
+
+// lockedCopy copies key; this platform cannot keep it out of swap
+func lockedCopy(key []byte) []byte {
+	return append([]byte(nil), key...)
+}
+
+// unlockMemory wipes a key from lockedCopy
+func unlockMemory(locked []byte) {
+	wipe(locked)
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package agent
+
+import "golang.org/x/sys/unix"
+
+// lockedCopy copies key into memory that is kept out of swap where the
+// system allows it (RLIMIT_MEMLOCK); otherwise it is an ordinary copy
+func lockedCopy(key []byte) []byte {
+	locked := make([]byte, len(key))
+	unix.Mlock(locked)
+	copy(locked, key)
+	return locked
+}
+
+// unlockMemory wipes a key from lockedCopy and releases the lock
+func unlockMemory(locked []byte) {
+	wipe(locked)
+	unix.Munlock(locked)
+}
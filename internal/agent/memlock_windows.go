@@ -0,0 +1,28 @@
+//go:build windows
+
+package agent
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockedCopy copies key into memory that is kept out of the page file
+// where the system allows it; otherwise it is an ordinary copy
+func lockedCopy(key []byte) []byte {
+	locked := make([]byte, len(key))
+	if len(locked) > 0 {
+		windows.VirtualLock(uintptr(unsafe.Pointer(&locked[0])), uintptr(len(locked)))
+	}
+	copy(locked, key)
+	return locked
+}
+
+// unlockMemory wipes a key from lockedCopy and releases the lock
+func unlockMemory(locked []byte) {
+	wipe(locked)
+	if len(locked) > 0 {
+		windows.VirtualUnlock(uintptr(unsafe.Pointer(&locked[0])), uintptr(len(locked)))
+	}
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// errNoPeerCredentials is returned by peerExecutable where the platform
+// cannot tell which program is on the other end of a socket
+var errNoPeerCredentials = errors.New("peer credentials are not supported on this platform")
+
+// authorizePeer decides whether the client on conn may make a restricted
+// request: it must run the same executable as the agent, so other programs
+// of the user can't take a vault key, swap in another one or drop the
+// agent's keys. Where the peer's executable cannot be told, the confirm
+// callback (if any) has to approve each request
+//
+// This keeps other programs from reading the key, not from using gpasswd:
+// any process of the same user can still run 'gpasswd show' and get what
+// it prints while the agent holds the key. The user account stays the
+// security boundary
+func (s *Server) authorizePeer(conn net.Conn, op, vault string) error {
+	peer, err := peerExecutable(conn)
+	if err == nil {
+		if sameExecutable(peer, s.executable) {
+			return nil
+		}
+		return ErrRefused
+	}
+	if errors.Is(err, errNoPeerCredentials) && s.confirm != nil && s.confirm(op, vault) {
+		return nil
+	}
+	return ErrRefused
+}
+
+// sameExecutable reports whether the two paths name the same file
+func sameExecutable(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// ownExecutable returns the agent's executable with symlinks resolved
+func ownExecutable() string {
+	self, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		return resolved
+	}
+	return self
+}
@@ -0,0 +1,43 @@
+//go:build darwin
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerExecutable returns the executable of the process on the other end of
+// conn, from its LOCAL_PEERCRED/LOCAL_PEERPID credentials
+func peerExecutable(conn net.Conn) (string, error) {
+	var (
+		cred *unix.Xucred
+		pid  int
+	)
+	err := withSocketFD(conn, func(fd int) error {
+		var err error
+		if cred, err = unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED); err != nil {
+			return err
+		}
+		pid, err = unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return "", errors.New("peer runs as another user")
+	}
+
+	// kern.procargs2 starts with argc, then the executable path
+	args, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil || len(args) < 5 {
+		return "", errors.New("cannot read the peer's executable")
+	}
+	path, _, _ := bytes.Cut(args[4:], []byte{0})
+	return string(path), nil
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package agent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerExecutable returns a path to the executable of the process on the
+// other end of conn, from its SO_PEERCRED credentials
+func peerExecutable(conn net.Conn) (string, error) {
+	var cred *unix.Ucred
+	err := withSocketFD(conn, func(fd int) error {
+		var err error
+		cred, err = unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return "", errors.New("peer runs as another user")
+	}
+	return "/proc/" + strconv.Itoa(int(cred.Pid)) + "/exe", nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package agent
+
+import "net"
+
+// peerExecutable is not available on this platform; key requests need the
+// confirm callback instead
+func peerExecutable(conn net.Conn) (string, error) {
+	return "", errNoPeerCredentials
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"errors"
+	"net"
+)
+
+// withSocketFD runs f with the descriptor of a Unix socket connection
+func withSocketFD(conn net.Conn, f func(fd int) error) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("not a Unix socket")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := raw.Control(func(fd uintptr) { ferr = f(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
@@ -0,0 +1,176 @@
+// Package agent keeps derived vault keys in memory for a while, so the
+// master password only has to be typed once per session
+//
+// The agent listens on a Unix domain socket (also used on Windows 10 and
+// later, which support AF_UNIX) inside a directory only the user can open.
+// A connection opens with a hello, a single JSON line in which client and
+// agent exchange their protocol versions and capabilities (the operations
+// they know). From protocol 2 on, requests and responses then travel as
+// length-prefixed JSON frames. Protocol 1 clients send no hello and talk
+// JSON lines throughout, which the agent still answers; a client meeting a
+// protocol 1 agent, which refuses the hello, falls back to lines. Clients
+// check the agent's capabilities before a request, so an operation the
+// running agent lacks fails with ErrUnsupported instead of a vague error
+//
+// Clients that only show entry details, such as previews, ask for the
+// fields key instead of the vault key: it opens metadata and notes but
+// never passwords, OTP secrets or private keys. The vault key itself is
+// only handed to clients running the agent's own executable, told from the
+// socket's peer credentials, and only they may put a key or lock and stop
+// the agent; where the platform has none, each such request needs a
+// confirmation. This is no boundary between programs of the same user:
+// any of them can run gpasswd itself while the agent holds the key
+//
+// With CheckEvery the agent also audits the vaults it holds keys for in the
+// background; only the number of findings is kept, and only in memory
+package agent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ProtocolVersion is the version of the request and response format
+//
+//	1: one JSON line per request and response
+//	2: a hello exchanging capabilities, then length-prefixed JSON frames
+const ProtocolVersion = 2
+
+// legacyVersion is the protocol of agents and clients without a hello
+const legacyVersion = 1
+
+// maxMessageSize bounds a hello line or frame, so a bad peer can't make
+// the other side allocate without limit
+const maxMessageSize = 1 << 20
+
+// Operations
+const (
+	OpHello     = "hello"      // exchange protocol versions and capabilities
+	OpGet       = "get"        // return the key of a vault
+	OpGetFields = "get-fields" // return the fields key of a vault
+	OpPut       = "put"        // remember the key of a vault
+	OpLock      = "lock"       // forget every key
+	OpStatus    = "status"     // report unlocked vaults and background checks
+	OpStop      = "stop"       // forget every key and exit
+)
+
+// ErrNoAgent is returned when no agent is listening on the socket
+var ErrNoAgent = errors.New("no agent running")
+
+// ErrNotUnlocked is returned when the agent has no key for a vault
+var ErrNotUnlocked = errors.New("vault not unlocked in the agent")
+
+// ErrUnsupported is returned when the running agent doesn't know an
+// operation, e.g. an older agent asked for a newer feature
+var ErrUnsupported = errors.New("the running agent does not support this request; restart it with 'gpasswd agent stop' and 'gpasswd agent start'")
+
+// ErrRefused is returned when the agent won't take a request that hands
+// out, replaces or drops vault keys from the requesting program
+var ErrRefused = errors.New("the agent only takes this request from gpasswd itself")
+
+// request is one line sent to the agent
+type request struct {
+	Version int    `json:"version"`
+	Op      string `json:"op"`
+	Vault   string `json:"vault,omitempty"`
+	Key     []byte `json:"key,omitempty"`
+
+	Capabilities []string `json:"capabilities,omitempty"` // hello: operations the client knows
+}
+
+// response is the agent's answer to a request
+type response struct {
+	Version int    `json:"version"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Key     []byte `json:"key,omitempty"`
+
+	Vaults      int   `json:"vaults,omitempty"`       // status: unlocked vaults
+	IdleTimeout int64 `json:"idle_timeout,omitempty"` // status: seconds, 0 = none
+	PID         int   `json:"pid,omitempty"`          // status: agent process
+
+	Checks []CheckResult `json:"checks,omitempty"` // status: last background checks
+
+	Capabilities []string `json:"capabilities,omitempty"` // hello: operations the agent answers
+}
+
+// capabilities are the operations this version answers
+var capabilities = []string{OpHello, OpGet, OpGetFields, OpPut, OpLock, OpStatus, OpStop}
+
+// legacyCapabilities are the operations of protocol 1 agents
+var legacyCapabilities = []string{OpGet, OpGetFields, OpPut, OpLock, OpStatus, OpStop}
+
+// supports reports whether an agent with these capabilities answers op
+func supports(capabilities []string, op string) bool {
+	return slices.Contains(capabilities, op)
+}
+
+// negotiate returns the protocol both sides speak
+func negotiate(theirs int) int {
+	return max(legacyVersion, min(theirs, ProtocolVersion))
+}
+
+// readFunc reads one message; writeFunc writes one
+type (
+	readFunc  func(r *bufio.Reader) ([]byte, error)
+	writeFunc func(w io.Writer, v any) error
+)
+
+// readLine reads one JSON line of at most maxMessageSize bytes
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return line, nil
+	}
+}
+
+// writeLine writes v as one JSON line
+func writeLine(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// readFrame reads one frame: a 4-byte big-endian length, then as many
+// bytes of JSON
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds %d", size, maxMessageSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeFrame writes v as one frame
+func writeFrame(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxMessageSize {
+		return fmt.Errorf("frame of %d bytes exceeds %d", len(data), maxMessageSize)
+	}
+	_, err = w.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...))
+	return err
+}
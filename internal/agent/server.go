@@ -0,0 +1,294 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/securefs"
+)
+
+// heldKey is a vault key kept by the agent
+type heldKey struct {
+	key       []byte // locked in memory where the platform allows
+	lastUsed  time.Time
+	checkedAt time.Time // last background check, zero = not yet
+}
+
+// Server holds vault keys and answers requests on a socket
+type Server struct {
+	idleTimeout time.Duration
+	executable  string                      // clients must run this to get, put or drop keys
+	confirm     func(op, vault string) bool // approves requests from unknown peers
+
+	mu      sync.Mutex
+	keys    map[string]*heldKey
+	results map[string]*CheckResult // last background check by vault
+
+	checkInterval time.Duration
+	check         CheckFunc
+	wake          chan struct{} // a key was handed over
+
+	listener net.Listener
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer creates an agent that forgets a key after idleTimeout without
+// use (0 keeps keys until the agent is locked or stopped)
+func NewServer(idleTimeout time.Duration) *Server {
+	return &Server{
+		idleTimeout: idleTimeout,
+		executable:  ownExecutable(),
+		keys:        make(map[string]*heldKey),
+		results:     make(map[string]*CheckResult),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+// ConfirmRequests makes the agent ask confirm before carrying out a
+// restricted request (see authorize) from a client whose executable the
+// platform cannot tell (Windows); without it such requests are refused
+// vault is empty for lock and stop
+func (s *Server) ConfirmRequests(confirm func(op, vault string) bool) {
+	s.confirm = confirm
+}
+
+// Listen creates the socket at path, replacing a stale one left by an agent
+// that is no longer running
+func Listen(path string) (net.Listener, error) {
+	if err := securefs.MkdirAll(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create agent directory: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already running on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := securefs.Restrict(path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve answers requests on ln until Stop is called or a client sends stop
+func (s *Server) Serve(ln net.Listener) error {
+	s.listener = ln
+	go s.expireIdle()
+	go s.runChecks()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Stop wipes every key and closes the listener
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		s.lock()
+		close(s.done)
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	})
+}
+
+// handle answers the requests of one connection
+// A hello switches it to frames; a protocol 1 client starts right away with
+// a request line and keeps talking lines
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	read, write := readLine, writeLine
+	version := legacyVersion
+	data, err := read(reader)
+	if err != nil {
+		return
+	}
+	var hello request
+	if json.Unmarshal(data, &hello) == nil && hello.Op == OpHello && hello.Version > legacyVersion {
+		version = negotiate(hello.Version)
+		if err := writeLine(conn, response{Version: version, OK: true, Capabilities: capabilities}); err != nil {
+			return
+		}
+		read, write = readFrame, writeFrame
+		if data, err = read(reader); err != nil {
+			return
+		}
+	}
+
+	for {
+		req, resp := s.process(conn, data, version)
+		if err := write(conn, resp); err != nil {
+			return
+		}
+		if req.Op == OpStop && resp.OK {
+			s.Stop()
+			return
+		}
+		if data, err = read(reader); err != nil {
+			return
+		}
+	}
+}
+
+// process parses, authorizes and answers one request in the negotiated
+// protocol version
+func (s *Server) process(conn net.Conn, data []byte, version int) (request, response) {
+	var req request
+	var resp response
+	if err := json.Unmarshal(data, &req); err != nil {
+		resp.Error = "malformed request"
+	} else if version == legacyVersion && req.Version > legacyVersion {
+		resp.Error = fmt.Sprintf("protocol version %d needs a hello first (agent speaks %d)", req.Version, ProtocolVersion)
+	} else if err := s.authorize(conn, req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp = s.answer(req)
+	}
+	resp.Version = version
+	return req, resp
+}
+
+// authorize checks that the client may make req. Requests that hand out,
+// replace or drop vault keys (get, put, lock and stop) must come from the
+// agent's own executable; get-fields and status are open to any program
+// of the user
+func (s *Server) authorize(conn net.Conn, req request) error {
+	switch req.Op {
+	case OpGet:
+		s.mu.Lock()
+		_, ok := s.keys[req.Vault]
+		s.mu.Unlock()
+		if !ok {
+			return ErrNotUnlocked
+		}
+	case OpPut, OpLock, OpStop:
+	default:
+		return nil
+	}
+	return s.authorizePeer(conn, req.Op, req.Vault)
+}
+
+// answer carries out one request; process sets the version of the
+// response
+func (s *Server) answer(req request) response {
+	resp := response{OK: true}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Op {
+	case OpGet, OpGetFields: // get was authorized in handle, like put, lock and stop
+		held, ok := s.keys[req.Vault]
+		if !ok {
+			return response{Error: ErrNotUnlocked.Error()}
+		}
+		held.lastUsed = time.Now()
+		if req.Op == OpGetFields {
+			resp.Key = crypto.FieldsKey(held.key)
+			break
+		}
+		// A copy, since the key may be wiped before the response is sent
+		resp.Key = append([]byte(nil), held.key...)
+	case OpPut:
+		if req.Vault == "" || len(req.Key) == 0 {
+			return response{Error: "put needs a vault and a key"}
+		}
+		s.forget(req.Vault)
+		s.keys[req.Vault] = &heldKey{key: lockedCopy(req.Key), lastUsed: time.Now()}
+		wipe(req.Key)
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	case OpLock, OpStop:
+		for vault := range s.keys {
+			s.forget(vault)
+		}
+	case OpHello:
+		resp.Capabilities = capabilities
+	case OpStatus:
+		resp.Vaults = len(s.keys)
+		resp.IdleTimeout = int64(s.idleTimeout / time.Second)
+		resp.PID = os.Getpid()
+		resp.Checks = s.checkResults()
+	default:
+		return response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+	return resp
+}
+
+// lock forgets every key
+func (s *Server) lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for vault := range s.keys {
+		s.forget(vault)
+	}
+}
+
+// forget wipes and drops the key of a vault; the caller holds s.mu
+func (s *Server) forget(vault string) {
+	if held, ok := s.keys[vault]; ok {
+		unlockMemory(held.key)
+		delete(s.keys, vault)
+	}
+}
+
+// expireIdle forgets keys that have not been used within the idle timeout
+func (s *Server) expireIdle() {
+	if s.idleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for vault, held := range s.keys {
+				if now.Sub(held.lastUsed) >= s.idleTimeout {
+					s.forget(vault)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// wipe overwrites b with zeros
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// startServer runs an agent on a socket in a temporary directory
+func startServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(0)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return server, socket
+}
+
+func TestGetKeyOnlyForOwnExecutable(t *testing.T) {
+	server, socket := startServer(t)
+	key := bytes.Repeat([]byte{7}, 32)
+	if err := Put(socket, "/vault.db", append([]byte(nil), key...)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test binary is both the agent and the client here
+	got, err := Get(socket, "/vault.db")
+	if err != nil {
+		t.Fatalf("Get from the agent's own executable: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("Get returned another key")
+	}
+
+	if _, err := Get(socket, "/other.db"); !errors.Is(err, ErrNotUnlocked) {
+		t.Errorf("Get for an unknown vault = %v, want ErrNotUnlocked", err)
+	}
+
+	// Any other program gets the fields key but not the vault key
+	server.executable = filepath.Join(t.TempDir(), "not-gpasswd")
+	if _, err := Get(socket, "/vault.db"); !errors.Is(err, ErrRefused) {
+		t.Errorf("Get from another executable = %v, want ErrRefused", err)
+	}
+	fields, err := GetFields(socket, "/vault.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fields, crypto.FieldsKey(key)) {
+		t.Error("GetFields returned another key")
+	}
+
+	// Nor may it replace the key or drop the agent's keys
+	if err := Put(socket, "/vault.db", bytes.Repeat([]byte{9}, 32)); !errors.Is(err, ErrRefused) {
+		t.Errorf("Put from another executable = %v, want ErrRefused", err)
+	}
+	if err := Lock(socket); !errors.Is(err, ErrRefused) {
+		t.Errorf("Lock from another executable = %v, want ErrRefused", err)
+	}
+	if err := Stop(socket); !errors.Is(err, ErrRefused) {
+		t.Errorf("Stop from another executable = %v, want ErrRefused", err)
+	}
+	if _, err := GetStatus(socket); err != nil {
+		t.Errorf("GetStatus from another executable: %v", err)
+	}
+}
+
+// fakeAgent answers hellos and requests the way an older agent would:
+// answer gets each parsed request line
+func fakeAgent(t *testing.T, answer func(req request) response) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := readLine(reader)
+					if err != nil {
+						return
+					}
+					var req request
+					if err := json.Unmarshal(line, &req); err != nil {
+						return
+					}
+					if err := writeLine(conn, answer(req)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return socket
+}
+
+func TestProtocolOneClient(t *testing.T) {
+	_, socket := startServer(t)
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A protocol 1 client sends request lines without a hello
+	reader := bufio.NewReader(conn)
+	for range 2 {
+		if _, err := conn.Write([]byte(`{"version":1,"op":"status"}` + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		line, err := readLine(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !resp.OK || resp.Version != 1 || resp.PID == 0 {
+			t.Errorf("status over lines = %+v", resp)
+		}
+	}
+}
+
+func TestProtocolNegotiation(t *testing.T) {
+	_, socket := startServer(t)
+	status, err := GetStatus(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Protocol != ProtocolVersion {
+		t.Errorf("protocol with a current agent = %d, want %d", status.Protocol, ProtocolVersion)
+	}
+
+	// A protocol 1 agent refuses the hello and keeps reading lines
+	legacy := fakeAgent(t, func(req request) response {
+		if req.Version > 1 {
+			return response{Version: 1, Error: "unsupported protocol version"}
+		}
+		if req.Op != OpStatus {
+			return response{Version: 1, Error: ErrNotUnlocked.Error()}
+		}
+		return response{Version: 1, OK: true, PID: 42}
+	})
+	status, err = GetStatus(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Protocol != 1 || status.PID != 42 {
+		t.Errorf("status from a protocol 1 agent = %+v", status)
+	}
+	if _, err := Get(legacy, "/vault.db"); !errors.Is(err, ErrNotUnlocked) {
+		t.Errorf("Get from a protocol 1 agent = %v, want ErrNotUnlocked", err)
+	}
+
+	// A newer agent without an operation is caught before the request
+	limited := fakeAgent(t, func(req request) response {
+		return response{Version: ProtocolVersion, OK: true, Capabilities: []string{OpHello, OpStatus}}
+	})
+	if _, err := GetFields(limited, "/vault.db"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetFields from an agent without it = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, request{Version: ProtocolVersion, Op: OpStatus}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := readFrame(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil || req.Op != OpStatus {
+		t.Errorf("frame round trip = %+v, %v", req, err)
+	}
+
+	oversized := binary.BigEndian.AppendUint32(nil, maxMessageSize+1)
+	if _, err := readFrame(bufio.NewReader(bytes.NewReader(oversized))); err == nil {
+		t.Error("an oversized frame was accepted")
+	}
+	truncated := append(binary.BigEndian.AppendUint32(nil, 10), "{}"...)
+	if _, err := readFrame(bufio.NewReader(bytes.NewReader(truncated))); err == nil {
+		t.Error("a truncated frame was accepted")
+	}
+}
@@ -0,0 +1,74 @@
+// Package atomicfile writes files so that readers only ever see the old or
+// the complete new content, never a partial file
+package atomicfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kitsnail/gpasswd/internal/securefs"
+)
+
+// WriteFile writes data to path atomically with the given permissions
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return Write(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Write streams the content produced by write into path atomically
+// The content goes to a temporary file next to path (so the final rename
+// never crosses a filesystem), which is synced to disk and then renamed
+// over path. If write fails, path is left untouched
+func Write(path string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	// Mode bits alone don't make a file private on Windows
+	if perm&0077 == 0 {
+		if err := securefs.Restrict(tmp.Name()); err != nil {
+			return err
+		}
+	}
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir persists the rename; not every platform can sync a directory,
+// so failures are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Access anomaly heuristics. Reads are compared against the vault's own
+// history, so hints only appear once there is enough of it
+const (
+	// AccessWindow is how much read history the checks look at
+	AccessWindow = 30 * 24 * time.Hour
+
+	// recentAccess is the window in which an unusual read is reported
+	recentAccess = 7 * 24 * time.Hour
+
+	// minBaselineReads is the least history needed to call anything unusual
+	minBaselineReads = 20
+
+	// unusualHourShare is the share of all reads below which an hour of the
+	// day counts as unusual
+	unusualHourShare = 0.03
+
+	// burstReads and burstFactor flag an entry read at least burstReads
+	// times in the last day and burstFactor times its usual daily rate
+	burstReads  = 5
+	burstFactor = 3
+)
+
+// checkAccess flags entries read at unusual hours or unusually often
+// access maps entry IDs to read times within AccessWindow
+func (r *Report) checkAccess(entries []*models.Entry, access map[string][]time.Time, now time.Time) {
+	var byHour [24]int
+	total := 0
+	for _, reads := range access {
+		for _, at := range reads {
+			byHour[at.Local().Hour()]++
+			total++
+		}
+	}
+	if total < minBaselineReads {
+		return
+	}
+
+	for _, entry := range entries {
+		reads := access[entry.ID]
+
+		unusual := 0
+		var example time.Time
+		lastDay := 0
+		for _, at := range reads {
+			if now.Sub(at) <= recentAccess && float64(byHour[at.Local().Hour()]) < unusualHourShare*float64(total) {
+				unusual++
+				example = at
+			}
+			if now.Sub(at) <= 24*time.Hour {
+				lastDay++
+			}
+		}
+
+		var hints []string
+		if unusual > 0 {
+			hints = append(hints, fmt.Sprintf("read %d time(s) at unusual hours this week (last %s)",
+				unusual, example.Local().Format("Mon 15:04")))
+		}
+
+		usualPerDay := float64(len(reads)-lastDay) / (AccessWindow.Hours()/24 - 1)
+		if lastDay >= burstReads && float64(lastDay) >= burstFactor*usualPerDay {
+			hints = append(hints, fmt.Sprintf("read %d times in the last 24h (usually %.1f/day)", lastDay, usualPerDay))
+		}
+
+		if len(hints) > 0 {
+			r.add(entry, IssueUnusualAccess, strings.Join(hints, "; "))
+		}
+	}
+}
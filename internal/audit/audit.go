@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/breach"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/sites"
+)
+
+// IssueType identifies the kind of problem found for an entry
+type IssueType string
+
+const (
+	IssueWeak     IssueType = "weak"
+	IssueReused   IssueType = "reused"
+	IssueBreached IssueType = "breached"
+	IssueExpiring IssueType = "expiring"
+	IssueExpired  IssueType = "expired"
+
+	// IssueIncomplete is an entry lacking what its type needs, e.g. a token
+	// without a value (see models.Entry.Missing)
+	IssueIncomplete IssueType = "incomplete"
+
+	// IssueNo2FA is a login without an OTP secret on a site known to offer
+	// TOTP (see Options.Check2FA)
+	IssueNo2FA IssueType = "no-2fa"
+
+	// IssueUnusualAccess is a hint, not a weakness: the entry was read at an
+	// unusual time or unusually often (see Options.Access)
+	IssueUnusualAccess IssueType = "unusual-access"
+)
+
+// Finding describes a single issue for a single entry
+// Findings never contain secrets, only entry metadata and a description
+type Finding struct {
+	EntryID   string    `json:"entry_id"`
+	EntryName string    `json:"entry_name"`
+	Category  string    `json:"category"`
+	Type      IssueType `json:"type"`
+	Detail    string    `json:"detail"`
+}
+
+// Report is the result of auditing a set of entries
+type Report struct {
+	Total    int       `json:"total"`
+	Findings []Finding `json:"findings"`
+}
+
+// Options configures which checks are run
+type Options struct {
+	// BreachChecker enables breach lookups when non-nil
+	BreachChecker breach.Checker
+
+	// Policy decides which passwords are weak (default: crypto.DefaultPolicy)
+	Policy *crypto.Policy
+
+	// ExpiringWithin reports entries expiring within this window (0 = only
+	// entries that have already expired)
+	ExpiringWithin time.Duration
+
+	// Check2FA reports logins on sites known to support TOTP (see
+	// sites.SupportsTOTP) that have no OTP secret stored
+	Check2FA bool
+
+	// Access enables access anomaly hints; it maps entry IDs to the times
+	// each entry was read within AccessWindow
+	Access map[string][]time.Time
+}
+
+// Run audits the given decrypted entries
+func Run(entries []*models.Entry, opts Options) (*Report, error) {
+	report := &Report{
+		Total:    len(entries),
+		Findings: make([]Finding, 0),
+	}
+
+	policy := crypto.DefaultPolicy()
+	if opts.Policy != nil {
+		policy = *opts.Policy
+	}
+
+	// Group entries by password to detect reuse
+	byPassword := make(map[string][]*models.Entry)
+	now := time.Now()
+
+	for _, entry := range entries {
+		if missing := entry.Missing(); missing != "" {
+			report.add(entry, IssueIncomplete, "needs "+missing)
+		}
+
+		if entry.ExpiresAt != nil {
+			if entry.ExpiresAt.Before(now) {
+				report.add(entry, IssueExpired, fmt.Sprintf("expired on %s", entry.ExpiresAt.Format("2006-01-02")))
+			} else if entry.ExpiresWithin(now, opts.ExpiringWithin) {
+				days := int(entry.ExpiresAt.Sub(now).Hours() / 24)
+				report.add(entry, IssueExpiring, fmt.Sprintf("expires in %d day(s) on %s", days, entry.ExpiresAt.Format("2006-01-02")))
+			}
+		}
+
+		if opts.Check2FA && entry.IsLogin() && entry.OTP == "" {
+			if domain, ok := sites.SupportsTOTP(entry.URL); ok {
+				report.add(entry, IssueNo2FA, domain+" supports TOTP, but no OTP secret is stored")
+			}
+		}
+
+		// Password-less logins and notes have nothing to check; private keys
+		// are generated, not chosen, and never leave the vault
+		if entry.Password == "" || entry.IsKey() {
+			continue
+		}
+
+		byPassword[entry.Password] = append(byPassword[entry.Password], entry)
+
+		// Token values are chosen by the issuing service, not the user
+		if entry.IsLogin() {
+			if violations := policy.Violations(entry.Password); len(violations) > 0 {
+				report.add(entry, IssueWeak, strings.Join(violations, "; "))
+			}
+		}
+
+		if opts.BreachChecker != nil {
+			count, err := opts.BreachChecker.Check(entry.Password)
+			if err != nil {
+				return nil, fmt.Errorf("breach check failed for %s: %w", entry.Name, err)
+			}
+			if count > 0 {
+				report.add(entry, IssueBreached, fmt.Sprintf("seen %d times in breach corpus", count))
+			}
+		}
+	}
+
+	for _, group := range byPassword {
+		if len(group) < 2 {
+			continue
+		}
+		for _, entry := range group {
+			report.add(entry, IssueReused, fmt.Sprintf("password shared with %d other entries", len(group)-1))
+		}
+	}
+
+	if opts.Access != nil {
+		report.checkAccess(entries, opts.Access, now)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		if report.Findings[i].EntryName != report.Findings[j].EntryName {
+			return report.Findings[i].EntryName < report.Findings[j].EntryName
+		}
+		return report.Findings[i].Type < report.Findings[j].Type
+	})
+
+	return report, nil
+}
+
+// Count returns the number of findings of the given type
+func (r *Report) Count(issue IssueType) int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Type == issue {
+			count++
+		}
+	}
+	return count
+}
+
+// Only returns the report narrowed to findings of the given type
+func (r *Report) Only(issue IssueType) *Report {
+	narrowed := &Report{Total: r.Total, Findings: make([]Finding, 0)}
+	for _, f := range r.Findings {
+		if f.Type == issue {
+			narrowed.Findings = append(narrowed.Findings, f)
+		}
+	}
+	return narrowed
+}
+
+func (r *Report) add(entry *models.Entry, issue IssueType, detail string) {
+	r.Findings = append(r.Findings, Finding{
+		EntryID:   entry.ID,
+		EntryName: entry.Name,
+		Category:  entry.Category,
+		Type:      issue,
+		Detail:    detail,
+	})
+}
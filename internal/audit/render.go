@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// ReportFormat identifies a shareable audit report layout
+type ReportFormat string
+
+const (
+	// ReportMarkdown renders the report as a Markdown document
+	ReportMarkdown ReportFormat = "markdown"
+
+	// ReportHTML renders the report as a standalone HTML page
+	ReportHTML ReportFormat = "html"
+)
+
+// ParseReportFormat validates a user-supplied report format
+func ParseReportFormat(name string) (ReportFormat, error) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return ReportMarkdown, nil
+	case "html":
+		return ReportHTML, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q (supported: markdown, html)", name)
+	}
+}
+
+// issueOrder is the order issue types appear in reports
+var issueOrder = []IssueType{IssueBreached, IssueWeak, IssueReused, IssueExpired, IssueExpiring, IssueIncomplete, IssueNo2FA, IssueUnusualAccess}
+
+// reportRow is one entry of a rendered report
+// Rows only carry the entry name, category and issue types; details and
+// secrets are deliberately left out so reports can be emailed
+type reportRow struct {
+	Name     string
+	Category string
+	Issues   []IssueType
+}
+
+type reportView struct {
+	GeneratedAt time.Time
+	Total       int
+	Counts      []issueCount
+	Rows        []reportRow
+}
+
+type issueCount struct {
+	Type  IssueType
+	Count int
+}
+
+func (r *Report) view(now time.Time) reportView {
+	v := reportView{GeneratedAt: now, Total: r.Total}
+	for _, issue := range issueOrder {
+		if count := r.Count(issue); count > 0 {
+			v.Counts = append(v.Counts, issueCount{Type: issue, Count: count})
+		}
+	}
+
+	// Findings are sorted by entry name, so issues of one entry are adjacent
+	for _, finding := range r.Findings {
+		if n := len(v.Rows); n > 0 && v.Rows[n-1].Name == finding.EntryName {
+			v.Rows[n-1].Issues = append(v.Rows[n-1].Issues, finding.Type)
+			continue
+		}
+		v.Rows = append(v.Rows, reportRow{
+			Name:     finding.EntryName,
+			Category: finding.Category,
+			Issues:   []IssueType{finding.Type},
+		})
+	}
+	return v
+}
+
+// Render writes the report in the given format
+func (r *Report) Render(w io.Writer, format ReportFormat, now time.Time) error {
+	switch format {
+	case ReportMarkdown:
+		return r.renderMarkdown(w, now)
+	case ReportHTML:
+		return htmlReport.Execute(w, r.view(now))
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+func (r *Report) renderMarkdown(w io.Writer, now time.Time) error {
+	v := r.view(now)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# gpasswd audit report\n\n")
+	fmt.Fprintf(&b, "Generated %s for %d entries.\n\n", v.GeneratedAt.Format("2006-01-02 15:04"), v.Total)
+
+	if len(v.Rows) == 0 {
+		b.WriteString("No issues found.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Issue | Entries |\n|---|---|\n")
+	for _, c := range v.Counts {
+		fmt.Fprintf(&b, "| %s | %d |\n", c.Type, c.Count)
+	}
+
+	b.WriteString("\n| Entry | Category | Issues |\n|---|---|---|\n")
+	for _, row := range v.Rows {
+		issues := make([]string, len(row.Issues))
+		for i, issue := range row.Issues {
+			issues[i] = string(issue)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(row.Name), markdownCell(row.Category), strings.Join(issues, ", "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes characters that would break a table cell or be
+// rendered as HTML
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;", "\n", " ", "\r", "").Replace(s)
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gpasswd audit report</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+th { background: #f4f4f4; }
+.ok { color: #2a7d2a; }
+</style>
+</head>
+<body>
+<h1>gpasswd audit report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04"}} for {{.Total}} entries.</p>
+{{if not .Rows}}<p class="ok">No issues found.</p>
+{{else}}<table>
+<tr><th>Issue</th><th>Entries</th></tr>
+{{range .Counts}}<tr><td>{{.Type}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>Entry</th><th>Category</th><th>Issues</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.Category}}</td><td>{{range $i, $issue := .Issues}}{{if $i}}, {{end}}{{$issue}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,75 @@
+// Package autotype types text into the focused window by simulating
+// keystrokes, for sites and applications that block pasting
+//
+// It drives an external tool: wtype under Wayland, xdotool under X11, or
+// ydotool (which works on both, through uinput). The text is passed on
+// stdin so it never shows up in the process list
+package autotype
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoTool is returned when no supported typing tool is installed
+var ErrNoTool = errors.New("no typing tool found; install wtype (Wayland), xdotool (X11) or ydotool")
+
+// tool is a typing program and the arguments that make it read stdin
+type tool struct {
+	name string
+	args []string
+}
+
+// Type types text into the focused window
+func Type(text string) error {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "openbsd" && runtime.GOOS != "netbsd" {
+		return fmt.Errorf("typing is not supported on %s", runtime.GOOS)
+	}
+
+	t, err := findTool()
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.name, t.args...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v %s", t.name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Tool returns the name of the typing tool Type would use
+func Tool() (string, error) {
+	t, err := findTool()
+	if err != nil {
+		return "", err
+	}
+	return t.name, nil
+}
+
+// findTool picks the first installed tool that works in this session
+func findTool() (tool, error) {
+	var candidates []tool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, tool{"wtype", []string{"-"}})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		candidates = append(candidates, tool{"xdotool", []string{"type", "--clearmodifiers", "--file", "-"}})
+	}
+	candidates = append(candidates, tool{"ydotool", []string{"type", "--file", "-"}})
+
+	for _, t := range candidates {
+		if _, err := exec.LookPath(t.name); err == nil {
+			return t, nil
+		}
+	}
+	return tool{}, ErrNoTool
+}
@@ -0,0 +1,78 @@
+// Package backup compares backups (read-only bundles written by
+// 'gpasswd export --viewer') with the live vault and restores from them
+package backup
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/share"
+)
+
+// Kind classifies a difference between backup and vault
+type Kind int
+
+const (
+	// OnlyInBackup is an entry missing from the vault; a restore brings it back
+	OnlyInBackup Kind = iota
+	// OnlyInVault is an entry added since the backup (or left out of it)
+	OnlyInVault
+	// Changed is an entry whose fields differ
+	Changed
+)
+
+// Difference is one entry that differs between backup and vault
+type Difference struct {
+	Kind   Kind
+	Name   string
+	Backup *models.Entry // nil for OnlyInVault
+	Live   *models.Entry // nil for OnlyInBackup
+	Fields []string      // Changed field names, for Changed
+}
+
+// Diff compares backup entries with live entries
+// Entries are matched by ID, since backups keep the vault's IDs, then by
+// name. Unchanged entries are counted but not returned
+func Diff(live, backup []*models.Entry) (differences []Difference, unchanged int) {
+	matched := make(map[*models.Entry]bool, len(live))
+	for _, entry := range backup {
+		current := Match(live, entry)
+		switch {
+		case current == nil:
+			differences = append(differences, Difference{Kind: OnlyInBackup, Name: entry.Name, Backup: entry})
+		default:
+			matched[current] = true
+			if fields := share.ChangedFields(entry, current); len(fields) > 0 {
+				differences = append(differences, Difference{Kind: Changed, Name: current.Name, Backup: entry, Live: current, Fields: fields})
+			} else {
+				unchanged++
+			}
+		}
+	}
+	for _, entry := range live {
+		if !matched[entry] {
+			differences = append(differences, Difference{Kind: OnlyInVault, Name: entry.Name, Live: entry})
+		}
+	}
+
+	sort.SliceStable(differences, func(i, j int) bool {
+		return strings.ToLower(differences[i].Name) < strings.ToLower(differences[j].Name)
+	})
+	return differences, unchanged
+}
+
+// Match finds the live counterpart of a backup entry, by ID then by name
+func Match(live []*models.Entry, entry *models.Entry) *models.Entry {
+	for _, candidate := range live {
+		if candidate.ID == entry.ID {
+			return candidate
+		}
+	}
+	for _, candidate := range live {
+		if strings.EqualFold(candidate.Name, entry.Name) {
+			return candidate
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package breach
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultRangeURL is the Have I Been Pwned range API
+const DefaultRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// rangePrefixLength is how many hex characters of a hash leave the machine
+const rangePrefixLength = 5
+
+// RangeChecker looks up passwords with the HIBP range API using
+// k-anonymity: only the first 5 characters of a password's SHA-1 are sent,
+// and the API answers with every hash suffix sharing them, which is then
+// searched locally. Responses are padded so their size doesn't give the
+// prefix away, and cached per prefix for the checker's lifetime
+type RangeChecker struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+	ranges    map[string]map[string]int // prefix -> suffix -> count
+}
+
+// NewRangeChecker creates a checker for a range API at baseURL (e.g.
+// DefaultRangeURL or a mirror), identifying itself as userAgent
+func NewRangeChecker(baseURL, userAgent string) *RangeChecker {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &RangeChecker{
+		baseURL:   baseURL,
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		ranges:    make(map[string]map[string]int),
+	}
+}
+
+// Check returns the breach count for a password (0 if not found)
+func (c *RangeChecker) Check(password string) (int, error) {
+	return c.CheckHash(HashPassword(password))
+}
+
+// CheckHash looks up an uppercase hex SHA-1 hash, sending only its prefix
+func (c *RangeChecker) CheckHash(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	if len(hash) != sha1HexLength {
+		return 0, fmt.Errorf("invalid SHA-1 hash length: %d", len(hash))
+	}
+
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+	suffixes, ok := c.ranges[prefix]
+	if !ok {
+		var err error
+		if suffixes, err = c.fetchRange(prefix); err != nil {
+			return 0, err
+		}
+		c.ranges[prefix] = suffixes
+	}
+	return suffixes[suffix], nil
+}
+
+// fetchRange downloads the hash suffixes sharing a prefix
+func (c *RangeChecker) fetchRange(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+prefix, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build breach API request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("breach API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("breach API answered %s", resp.Status)
+	}
+
+	// Lines are SUFFIX:COUNT; padding lines have a count of 0
+	suffixes := make(map[string]int)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		suffix, _, _ := strings.Cut(line, ":")
+		if len(suffix) != sha1HexLength-rangePrefixLength {
+			return nil, fmt.Errorf("malformed breach API response line %q", line)
+		}
+		count, err := parseCount(line)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			suffixes[strings.ToUpper(suffix)] = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breach API response: %w", err)
+	}
+	return suffixes, nil
+}
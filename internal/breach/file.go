@@ -0,0 +1,155 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec G505 -- SHA-1 is mandated by the HIBP hash format
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sha1HexLength is the length of a hex-encoded SHA-1 digest
+const sha1HexLength = 40
+
+// Checker reports how many times a password appears in a breach corpus
+// A count of 0 means the password was not found
+type Checker interface {
+	Check(password string) (int, error)
+}
+
+// FileChecker looks up passwords in a locally downloaded HIBP
+// "SHA-1 ordered by hash" file without loading it into memory
+//
+// Expected line format: <40 hex chars SHA-1>[:<count>]
+// Lines must be sorted by hash (as published by HIBP)
+type FileChecker struct {
+	file *os.File
+	size int64
+}
+
+// OpenFile opens an ordered HIBP hash file for lookups
+func OpenFile(path string) (*FileChecker, error) {
+	if path == "" {
+		return nil, errors.New("breach file path cannot be empty")
+	}
+
+	file, err := os.Open(path) // #nosec G304 -- path is provided by the user on purpose
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat breach file: %w", err)
+	}
+
+	return &FileChecker{file: file, size: info.Size()}, nil
+}
+
+// Close closes the underlying file
+func (c *FileChecker) Close() error {
+	return c.file.Close()
+}
+
+// Check returns the breach count for a password (0 if not found)
+func (c *FileChecker) Check(password string) (int, error) {
+	return c.CheckHash(HashPassword(password))
+}
+
+// CheckHash binary searches the file for an uppercase hex SHA-1 hash
+func (c *FileChecker) CheckHash(hash string) (int, error) {
+	target := strings.ToUpper(hash)
+	if len(target) != sha1HexLength {
+		return 0, fmt.Errorf("invalid SHA-1 hash length: %d", len(target))
+	}
+
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, next, line, err := c.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		// No line begins inside [mid, hi), so search the lower half
+		if start >= hi || line == "" {
+			hi = mid
+			continue
+		}
+
+		if len(line) < sha1HexLength {
+			return 0, fmt.Errorf("malformed line at offset %d", start)
+		}
+
+		switch cmp := strings.Compare(strings.ToUpper(line[:sha1HexLength]), target); {
+		case cmp == 0:
+			return parseCount(line)
+		case cmp < 0:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+// lineAt returns the first complete line starting at or after pos
+// along with its starting offset and the offset of the following line
+func (c *FileChecker) lineAt(pos int64) (int64, int64, string, error) {
+	start := pos
+	if pos > 0 {
+		// Step back one byte so a line starting exactly at pos is not skipped
+		start = pos - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(c.file, start, c.size-start))
+
+	if pos > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return c.size, c.size, "", nil
+			}
+			return 0, 0, "", fmt.Errorf("failed to read breach file: %w", err)
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, "", fmt.Errorf("failed to read breach file: %w", err)
+	}
+
+	next := start + int64(len(line))
+	line = strings.TrimRight(line, "\r\n")
+	return start, next, line, nil
+}
+
+// parseCount extracts the occurrence count from a "HASH:COUNT" line
+// Files without counts are treated as a single occurrence
+func parseCount(line string) (int, error) {
+	_, countStr, found := strings.Cut(line, ":")
+	if !found {
+		return 1, nil
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil {
+		return 0, fmt.Errorf("invalid breach count %q: %w", countStr, err)
+	}
+
+	return count, nil
+}
+
+// HashPassword returns the uppercase hex SHA-1 of a password
+// as used by Have I Been Pwned
+func HashPassword(password string) string {
+	sum := sha1.Sum([]byte(password)) // #nosec G401 -- required by HIBP
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
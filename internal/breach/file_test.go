@@ -0,0 +1,149 @@
+package breach
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeBreachFile writes content to a temporary file and opens it
+func writeBreachFile(t *testing.T, content string) *FileChecker {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	checker, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { checker.Close() })
+	return checker
+}
+
+// sortedCorpus returns HIBP lines for n passwords, sorted by hash, and the
+// count stored for each hash
+func sortedCorpus(n int) ([]string, map[string]int) {
+	counts := make(map[string]int, n)
+	lines := make([]string, 0, n)
+	for i := range n {
+		hash := HashPassword(fmt.Sprintf("password-%d", i))
+		counts[hash] = i + 1
+		lines = append(lines, fmt.Sprintf("%s:%d", hash, i+1))
+	}
+	sort.Strings(lines)
+	return lines, counts
+}
+
+func TestFileCheckerFindsEveryLine(t *testing.T) {
+	lines, counts := sortedCorpus(200)
+
+	for _, sep := range []string{"\n", "\r\n"} {
+		// With and without a newline after the last line
+		for _, trailer := range []string{"", sep} {
+			checker := writeBreachFile(t, strings.Join(lines, sep)+trailer)
+			for hash, want := range counts {
+				got, err := checker.CheckHash(hash)
+				if err != nil {
+					t.Fatalf("sep %q trailer %q: CheckHash(%s): %v", sep, trailer, hash, err)
+				}
+				if got != want {
+					t.Errorf("sep %q trailer %q: CheckHash(%s) = %d, want %d", sep, trailer, hash, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFileCheckerFirstAndLastLine(t *testing.T) {
+	lines, counts := sortedCorpus(50)
+	checker := writeBreachFile(t, strings.Join(lines, "\n")+"\n")
+
+	for _, line := range []string{lines[0], lines[len(lines)-1]} {
+		hash := line[:sha1HexLength]
+		got, err := checker.CheckHash(strings.ToLower(hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != counts[hash] {
+			t.Errorf("CheckHash(%s) = %d, want %d", hash, got, counts[hash])
+		}
+	}
+}
+
+func TestFileCheckerMissingHash(t *testing.T) {
+	lines, _ := sortedCorpus(50)
+	checker := writeBreachFile(t, strings.Join(lines, "\n")+"\n")
+
+	for _, hash := range []string{
+		strings.Repeat("0", sha1HexLength), // before the first line
+		strings.Repeat("F", sha1HexLength), // after the last line
+		HashPassword("not in the corpus"),
+	} {
+		got, err := checker.CheckHash(hash)
+		if err != nil {
+			t.Fatalf("CheckHash(%s): %v", hash, err)
+		}
+		if got != 0 {
+			t.Errorf("CheckHash(%s) = %d, want 0", hash, got)
+		}
+	}
+
+	if _, err := checker.CheckHash("ABC"); err == nil {
+		t.Error("a short hash was accepted")
+	}
+}
+
+func TestFileCheckerSingleLineAndEmpty(t *testing.T) {
+	hash := HashPassword("password")
+	checker := writeBreachFile(t, hash+"\n")
+	if got, err := checker.Check("password"); err != nil || got != 1 {
+		t.Errorf("line without count: Check = %d, %v, want 1", got, err)
+	}
+
+	empty := writeBreachFile(t, "")
+	if got, err := empty.Check("password"); err != nil || got != 0 {
+		t.Errorf("empty file: Check = %d, %v, want 0", got, err)
+	}
+}
+
+func TestFileCheckerTruncatedFinalLine(t *testing.T) {
+	lines, counts := sortedCorpus(50)
+	last := lines[len(lines)-1]
+	truncated := append(lines[:len(lines)-1:len(lines)-1], last[:sha1HexLength/2])
+	checker := writeBreachFile(t, strings.Join(truncated, "\n"))
+
+	// Lines before the cut are still found
+	first := lines[0][:sha1HexLength]
+	if got, err := checker.CheckHash(first); err != nil || got != counts[first] {
+		t.Errorf("CheckHash(%s) = %d, %v, want %d", first, got, err, counts[first])
+	}
+
+	// A lookup that lands on the cut line reports it instead of a miss
+	if _, err := checker.CheckHash(strings.Repeat("F", sha1HexLength)); err == nil {
+		t.Error("a truncated final line was not reported")
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	hash := strings.Repeat("A", sha1HexLength)
+	tests := []struct {
+		line    string
+		want    int
+		wantErr bool
+	}{
+		{hash + ":42", 42, false},
+		{hash + ": 7", 7, false},
+		{hash, 1, false},
+		{hash + ":many", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCount(tt.line)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCount(%q) = %d, %v, want %d (error %v)", tt.line, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/loginsteps"
 	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/sites"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
@@ -26,10 +29,44 @@ You can choose to:
 - Enter a password manually
 - Generate a strong password automatically
 
+Manually entered passwords must meet the strength policy configured in
+config.yaml (policy section). Use --allow-weak to store one anyway.
+
+With --type token the entry stores an API key or access token together
+with its issuing service, expiry and scopes. Token values are not checked
+against the strength policy, and 'gpasswd audit' reports tokens that are
+about to expire.
+
+With --security high the entry always asks for the master password again
+instead of reusing a cached unlock, its username is hidden in 'gpasswd list'
+and it is cleared from the clipboard sooner (clipboard.high_security_timeout).
+
+--login-steps stores the procedure 'gpasswd login' walks through, e.g.
+"open,user,say Click Next,password,otp" (see 'gpasswd login --help').
+
+--no-password stores a login without a password, for accounts that sign in
+through single sign-on, a passkey or a magic link; it needs a username.
+
+--listable-username also stores the username unencrypted, so scripts can
+read it with 'gpasswd user <name>' without the master password. Anyone with
+the vault file can read it too; it is not available for high-security
+entries.
+
+--template presets the entry from a template defined in config.yaml
+(templates section): type, category, tags, security level, field defaults
+and password generator options. Only the template's fields are prompted
+for; flags still override. A login password the template doesn't prompt
+for (or that is left empty) is generated with the template's generator.
+
 Example:
   gpasswd add github
   gpasswd add "Gmail Work"
-  gpasswd add`,
+  gpasswd add
+  gpasswd add github-ci --type token --service GitHub --expires 90d --scopes repo,workflow
+  gpasswd add bank --security high
+  gpasswd add work-sso --username me@corp.example --no-password
+  gpasswd add deploy-bot --username ci-deploy --listable-username
+  gpasswd add web01 --template server-root`,
 	RunE: runAdd,
 }
 
@@ -42,6 +79,17 @@ var (
 	addTags      []string
 	addGenerate  bool
 	addGenLength int
+	addAllowWeak bool
+	addType      string
+	addService   string
+	addExpires   string
+	addScopes    string
+	addSecurity  string
+	addListable  bool
+	addSteps     []string
+	addNoPass    bool
+	addTemplate  string
+	addIcon      string
 )
 
 func init() {
@@ -55,6 +103,17 @@ func init() {
 	addCmd.Flags().StringSliceVarP(&addTags, "tags", "t", []string{}, "Comma-separated tags")
 	addCmd.Flags().BoolVarP(&addGenerate, "generate", "g", false, "Generate a strong password")
 	addCmd.Flags().IntVar(&addGenLength, "gen-length", 20, "Length of generated password")
+	addCmd.Flags().BoolVar(&addAllowWeak, "allow-weak", false, "Store a password that fails the strength policy")
+	addCmd.Flags().BoolVar(&addNoPass, "no-password", false, "Store a login without a password (single sign-on, passkey)")
+	addCmd.Flags().StringVar(&addType, "type", models.EntryTypeLogin, "Entry type: login or token (API key / access token)")
+	addCmd.Flags().StringVar(&addService, "service", "", "Issuing service of a token (with --type token)")
+	addCmd.Flags().StringVar(&addExpires, "expires", "", "Token expiry: YYYY-MM-DD, e.g. 90d, or never (with --type token)")
+	addCmd.Flags().StringVar(&addScopes, "scopes", "", "Comma-separated token scopes (with --type token)")
+	addCmd.Flags().StringVar(&addSecurity, "security", models.SecurityLevelNormal, "Security level: normal or high")
+	addCmd.Flags().StringSliceVar(&addSteps, "login-steps", nil, "Steps for 'gpasswd login', e.g. user,password,otp")
+	addCmd.Flags().BoolVar(&addListable, "listable-username", false, "Store the username unencrypted for 'gpasswd user'")
+	addCmd.Flags().StringVar(&addIcon, "icon", "", "Emoji shown with the entry (default: suggested from --url)")
+	addCmd.Flags().StringVar(&addTemplate, "template", "", "Preset the entry from a template in config.yaml (templates section)")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
@@ -64,6 +123,43 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	// Reject a weak --password before asking for anything
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if addTemplate != "" {
+		return runAddTemplate(cmd, args, cfg, policy)
+	}
+	securityLevel, err := parseSecurityLevel(addSecurity)
+	if err != nil {
+		return err
+	}
+	if _, err := loginsteps.ParseAll(addSteps); err != nil {
+		return err
+	}
+	if addListable && securityLevel == models.SecurityLevelHigh {
+		return fmt.Errorf("--listable-username cannot be used with --security high")
+	}
+	if addType != models.EntryTypeLogin && addType != models.EntryTypeToken {
+		return fmt.Errorf("invalid --type %q (expected %s or %s)", addType, models.EntryTypeLogin, models.EntryTypeToken)
+	}
+	if addNoPass && (addPassword != "" || addGenerate || addType == models.EntryTypeToken) {
+		return fmt.Errorf("--no-password cannot be used with --password, --generate or --type token")
+	}
+	if addType == models.EntryTypeToken {
+		if addGenerate {
+			return fmt.Errorf("--generate cannot be used with --type token; tokens are issued by their service")
+		}
+		if _, err := parseExpiry(addExpires, time.Now()); err != nil {
+			return err
+		}
+	} else if addPassword != "" {
+		if err := enforcePolicy(policy, addPassword, addAllowWeak); err != nil {
+			return err
+		}
+	}
+
 	// Determine database path
 	dbPath := cfg.Database.Path
 	if dbPath == "" {
@@ -82,88 +178,99 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
+	// Unlock before collecting input so a wrong password doesn't discard it
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
 	// Create entry
 	entry := &models.Entry{
-		Category: addCategory,
+		Category:         addCategory,
+		SecurityLevel:    securityLevel,
+		ListableUsername: addListable,
+		LoginSteps:       addSteps,
+		Icon:             addIcon,
+	}
+	if addType == models.EntryTypeToken {
+		entry.Type = models.EntryTypeToken
+	}
+
+	// A --url suggests the name, and for known sites category and icon
+	suggestion, suggested := sites.Suggest(addURL)
+	if suggested {
+		if !cmd.Flags().Changed("category") && suggestion.Category != "" && !entry.IsToken() {
+			entry.Category = suggestion.Category
+		}
+		if !cmd.Flags().Changed("icon") {
+			entry.Icon = suggestion.Icon
+		}
 	}
 
 	// Get entry name
 	if len(args) > 0 {
 		entry.Name = args[0]
+	} else if suggested && !interactive() {
+		entry.Name = suggestion.Name
+		fmt.Printf("🔗 Name from URL: %s\n", entry.Name)
 	} else {
 		namePrompt := &survey.Input{
 			Message: "Entry name (e.g., 'GitHub', 'Gmail Work'):",
 		}
-		if err := survey.AskOne(namePrompt, &entry.Name, survey.WithValidator(survey.Required)); err != nil {
+		if suggested {
+			namePrompt.Default = suggestion.Name
+		}
+		if err := askOne(namePrompt, &entry.Name, survey.WithValidator(survey.Required), survey.WithValidator(entryNameAvailable(db))); err != nil {
 			return fmt.Errorf("name prompt failed: %w", err)
 		}
 	}
 
-	// Get username (interactive if not provided via flag)
-	if addUsername == "" {
-		usernamePrompt := &survey.Input{
-			Message: "Username or email (optional):",
-		}
-		survey.AskOne(usernamePrompt, &entry.Username)
-	} else {
-		entry.Username = addUsername
+	// Reject duplicate names before asking for the remaining fields
+	exists, err := db.EntryExists(entry.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("entry '%s' already exists. Use 'gpasswd edit %s' to change it", entry.Name, entry.Name)
 	}
 
-	// Get password
-	if addPassword != "" {
-		// Password provided via flag
+	if entry.IsToken() {
+		entry.Username = addUsername
+		entry.Service = addService
 		entry.Password = addPassword
-	} else if addGenerate {
-		// Generate password
-		genOptions := crypto.GenerateOptions{
-			UseUppercase:     cfg.PasswordGenerator.UseUppercase,
-			UseLowercase:     cfg.PasswordGenerator.UseLowercase,
-			UseDigits:        cfg.PasswordGenerator.UseDigits,
-			UseSymbols:       cfg.PasswordGenerator.UseSymbols,
-			ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+		if err := promptTokenFields(entry, addExpires, addScopes); err != nil {
+			return err
 		}
-
-		length := addGenLength
-		if length == 20 && cfg.PasswordGenerator.Length > 0 {
-			length = cfg.PasswordGenerator.Length
-		}
-
-		generated, err := crypto.Generate(length, genOptions)
-		if err != nil {
-			return fmt.Errorf("failed to generate password: %w", err)
-		}
-
-		entry.Password = generated
-		fmt.Printf("✓ Generated password: %s\n", generated)
-
-		// Show strength
-		strength := crypto.CheckStrength(generated)
-		fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
 	} else {
-		// Prompt for password choice
-		var choice string
-		choicePrompt := &survey.Select{
-			Message: "Password:",
-			Options: []string{
-				"Generate a strong password (recommended)",
-				"Enter password manually",
-			},
-		}
-		if err := survey.AskOne(choicePrompt, &choice); err != nil {
-			return fmt.Errorf("password choice failed: %w", err)
+		// Get username (interactive if not provided via flag)
+		if addUsername == "" {
+			if err := askUsername(entry, addNoPass); err != nil {
+				return err
+			}
+		} else {
+			entry.Username = addUsername
 		}
 
-		if strings.HasPrefix(choice, "Generate") {
+		// Get password
+		if addPassword != "" {
+			// Password provided via flag
+			entry.Password = addPassword
+		} else if addGenerate {
 			// Generate password
 			genOptions := crypto.GenerateOptions{
-				UseUppercase:     true,
-				UseLowercase:     true,
-				UseDigits:        true,
-				UseSymbols:       true,
+				UseUppercase:     cfg.PasswordGenerator.UseUppercase,
+				UseLowercase:     cfg.PasswordGenerator.UseLowercase,
+				UseDigits:        cfg.PasswordGenerator.UseDigits,
+				UseSymbols:       cfg.PasswordGenerator.UseSymbols,
 				ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
 			}
 
-			generated, err := crypto.Generate(20, genOptions)
+			length := addGenLength
+			if length == 20 && cfg.PasswordGenerator.Length > 0 {
+				length = cfg.PasswordGenerator.Length
+			}
+
+			generated, err := crypto.Generate(length, genOptions)
 			if err != nil {
 				return fmt.Errorf("failed to generate password: %w", err)
 			}
@@ -171,23 +278,66 @@ func runAdd(cmd *cobra.Command, args []string) error {
 			entry.Password = generated
 			fmt.Printf("✓ Generated password: %s\n", generated)
 
+			// Show strength
 			strength := crypto.CheckStrength(generated)
 			fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
-		} else {
-			// Manual password entry
-			passwordPrompt := &survey.Password{
-				Message: "Enter password:",
+		} else if !addNoPass {
+			// Prompt for password choice
+			var choice string
+			choicePrompt := &survey.Select{
+				Message: "Password:",
+				Options: []string{
+					"Generate a strong password (recommended)",
+					"Enter password manually",
+					"No password (single sign-on, passkey or magic link)",
+				},
 			}
-			if err := survey.AskOne(passwordPrompt, &entry.Password, survey.WithValidator(survey.Required)); err != nil {
-				return fmt.Errorf("password prompt failed: %w", err)
+			if err := askOne(choicePrompt, &choice); err != nil {
+				return fmt.Errorf("password choice failed: %w", err)
 			}
 
-			// Check strength
-			strength := crypto.CheckStrength(entry.Password)
-			fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+			if strings.HasPrefix(choice, "No password") {
+				if entry.Username == "" {
+					if err := askUsername(entry, true); err != nil {
+						return err
+					}
+				}
+			} else if strings.HasPrefix(choice, "Generate") {
+				// Generate password
+				genOptions := crypto.GenerateOptions{
+					UseUppercase:     true,
+					UseLowercase:     true,
+					UseDigits:        true,
+					UseSymbols:       true,
+					ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+				}
 
-			if strength.Level < crypto.Fair {
-				fmt.Println("  ⚠️  Weak password. Consider using a generated password.")
+				generated, err := crypto.Generate(20, genOptions)
+				if err != nil {
+					return fmt.Errorf("failed to generate password: %w", err)
+				}
+
+				entry.Password = generated
+				fmt.Printf("✓ Generated password: %s\n", generated)
+
+				strength := crypto.CheckStrength(generated)
+				fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+			} else {
+				// Manual password entry
+				passwordPrompt := &survey.Password{
+					Message: "Enter password:",
+				}
+				if err := askOne(passwordPrompt, &entry.Password, survey.WithValidator(survey.Required), survey.WithValidator(policyValidator(policy, addAllowWeak))); err != nil {
+					return fmt.Errorf("password prompt failed: %w", err)
+				}
+
+				// Check strength
+				strength := crypto.CheckStrength(entry.Password)
+				fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+
+				if strength.Level < crypto.Fair {
+					fmt.Println("  ⚠️  Weak password. Consider using a generated password.")
+				}
 			}
 		}
 	}
@@ -197,35 +347,32 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		urlPrompt := &survey.Input{
 			Message: "Website URL (optional):",
 		}
-		survey.AskOne(urlPrompt, &entry.URL)
+		askOne(urlPrompt, &entry.URL)
 	} else {
 		entry.URL = addURL
 	}
 
+	// Category and tags are picked from those already in use
+	picks, err := loadTaxonomy(db, key)
+	if err != nil {
+		return err
+	}
+
 	// Get category (already set from flag or default)
 	if addCategory == "general" {
-		categoryPrompt := &survey.Input{
-			Message: "Category (optional, default: general):",
-			Default: "general",
+		defaultCategory := entry.Category
+		if entry.IsToken() {
+			defaultCategory = "api-key"
+		}
+		if category, err := picks.pickCategory("Category:", defaultCategory); err == nil && category != "" {
+			entry.Category = category
 		}
-		survey.AskOne(categoryPrompt, &entry.Category)
 	}
 
 	// Get tags
 	if len(addTags) == 0 {
-		var tagsInput string
-		tagsPrompt := &survey.Input{
-			Message: "Tags (comma-separated, optional):",
-		}
-		survey.AskOne(tagsPrompt, &tagsInput)
-
-		if tagsInput != "" {
-			for _, tag := range strings.Split(tagsInput, ",") {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					entry.Tags = append(entry.Tags, trimmed)
-				}
-			}
+		if tags, err := picks.pickTags("Tags:", nil); err == nil {
+			entry.Tags = tags
 		}
 	} else {
 		entry.Tags = addTags
@@ -236,44 +383,24 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		notesPrompt := &survey.Multiline{
 			Message: "Notes (optional, press Ctrl+D when done):",
 		}
-		survey.AskOne(notesPrompt, &entry.Notes)
+		askOne(notesPrompt, &entry.Notes)
 	} else {
 		entry.Notes = addNotes
 	}
 
 	fmt.Println("\n🔐 Encrypting and storing entry...")
 
-	// Prompt for master password
-	var masterPassword string
-	masterPrompt := &survey.Password{
-		Message: "Master password:",
-	}
-	if err := survey.AskOne(masterPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("master password prompt failed: %w", err)
-	}
-
-	// Get salt and params
-	salt, err := db.GetSalt()
-	if err != nil {
-		return fmt.Errorf("failed to get salt: %w", err)
-	}
-
-	params, err := db.GetArgon2Params()
-	if err != nil {
-		return fmt.Errorf("failed to get Argon2 parameters: %w", err)
-	}
-
-	// Derive encryption key
-	key, err := crypto.DeriveKey(masterPassword, salt, params)
-	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
-	}
-
 	// Create entry in database
 	if err := db.CreateEntry(entry, key); err != nil {
 		return fmt.Errorf("failed to create entry: %w", err)
 	}
 
+	printAddedEntry(db, cfg, entry)
+	return nil
+}
+
+// printAddedEntry summarizes a newly added entry
+func printAddedEntry(db *storage.DB, cfg *config.Config, entry *models.Entry) {
 	fmt.Println("\n✅ Entry added successfully!")
 	fmt.Printf("   Name: %s\n", entry.Name)
 	fmt.Printf("   Category: %s\n", entry.Category)
@@ -286,12 +413,53 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	if len(entry.Tags) > 0 {
 		fmt.Printf("   Tags: %s\n", strings.Join(entry.Tags, ", "))
 	}
+	if entry.IsToken() {
+		fmt.Printf("   Expires: %s\n", formatExpiry(entry.ExpiresAt, time.Now()))
+	}
+	if entry.IsHighSecurity() {
+		fmt.Println("   Security: high")
+	}
 	fmt.Printf("   ID: %s\n", entry.ID)
+	warnSizeLimits(db, cfg, entry, os.Stdout)
 
 	fmt.Println("\n💡 Next steps:")
 	fmt.Println("   • View all entries: gpasswd list")
-	fmt.Println("   • Copy password: gpasswd copy " + entry.Name)
+	if entry.Password != "" {
+		fmt.Println("   • Copy password: gpasswd copy " + entry.Name)
+	}
 	fmt.Println("   • View entry details: gpasswd show " + entry.Name)
+}
 
+// askUsername prompts for the username, which password-less logins need
+func askUsername(entry *models.Entry, required bool) error {
+	if !required {
+		usernamePrompt := &survey.Input{
+			Message: "Username or email (optional):",
+		}
+		askOne(usernamePrompt, &entry.Username)
+		return nil
+	}
+
+	usernamePrompt := &survey.Input{
+		Message: "Username or email (required without a password):",
+	}
+	if err := askOne(usernamePrompt, &entry.Username, survey.WithValidator(survey.Required)); err != nil {
+		return fmt.Errorf("username prompt failed: %w", err)
+	}
 	return nil
 }
+
+// entryNameAvailable rejects names already used in the vault
+func entryNameAvailable(db *storage.DB) survey.Validator {
+	return func(ans interface{}) error {
+		name, _ := ans.(string)
+		exists, err := db.EntryExists(name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("entry '%s' already exists", name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/agent"
+	"github.com/kitsnail/gpasswd/internal/pinentry"
+	"github.com/kitsnail/gpasswd/internal/rotation"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep the vault key in memory so the master password is typed once",
+	Long: `Run an agent that remembers the derived vault key, like ssh-agent.
+
+While the agent runs, the first command that asks for the master password
+hands the key to the agent, and every later command gets it from there
+without asking. The key is kept in locked memory (never swapped out where
+the system allows it) and forgotten after session.timeout seconds without
+use, or right away with 'gpasswd agent lock' or 'gpasswd lock'.
+High-security entries still ask for the master password every time.
+
+While it holds the key, the agent also audits the vault in the background:
+right after unlocking and then every agent.check_interval (12h by default)
+it looks for weak, reused, expired and, with agent.pwned_file, breached
+passwords. Only the number of findings is kept, in memory; 'gpasswd agent
+status' shows them and a desktop notification tells you when they change.
+Run 'gpasswd audit' to see which entries they are.
+
+The agent hands the vault key only to programs running the gpasswd
+executable, told from the socket's peer credentials, and only they may
+hand it a key or lock and stop it. Other programs can get no more than
+the fields key, which opens metadata and notes but no passwords; 'gpasswd
+preview' only ever asks for that one. Where the system has no peer
+credentials (Windows) each such request is confirmed in a pinentry dialog.
+This protects the key, not your entries: any program running as you can
+still run 'gpasswd show' while the agent holds the key.
+
+The agent listens on a socket only your account can open: agent.sock in
+$XDG_RUNTIME_DIR/gpasswd or the gpasswd config directory, or the path in
+GPASSWD_AGENT_SOCK. On Windows this needs Windows 10 1803 or later.
+
+Examples:
+  gpasswd agent start
+  gpasswd agent status
+  gpasswd agent lock
+  gpasswd agent stop`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent in the background",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStart,
+}
+
+var agentServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the agent in the foreground (for systemd, launchd and the like)",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentServe,
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Forget all keys and stop the agent",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStop,
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the agent runs and holds a key",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStatus,
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Make the agent forget all keys",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentLock,
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentStartCmd, agentServeCmd, agentStopCmd, agentStatusCmd, agentLockCmd)
+}
+
+func runAgentStart(cmd *cobra.Command, args []string) error {
+	socket := agentSocket()
+	if status, err := agent.GetStatus(socket); err == nil {
+		fmt.Printf("✅ Agent already running (pid %d)\n", status.PID)
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gpasswd: %w", err)
+	}
+
+	serve := exec.Command(executable, "agent", "serve")
+	detach(serve)
+	if err := serve.Start(); err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+	serve.Process.Release()
+
+	// Wait for the socket to answer
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, err := agent.GetStatus(socket); err == nil {
+			fmt.Printf("✅ Agent started (pid %d)\n", status.PID)
+			fmt.Println("   The next command that asks for the master password unlocks it")
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("agent did not start; run 'gpasswd agent serve' to see why")
+}
+
+func runAgentServe(cmd *cobra.Command, args []string) error {
+	// An unreadable config falls back to the defaults
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	ln, err := agent.Listen(agentSocket())
+	if err != nil {
+		return err
+	}
+
+	server := agent.NewServer(time.Duration(cfg.Session.Timeout) * time.Second)
+	if cfg.Agent.CheckInterval != "" && cfg.Agent.CheckInterval != "0" {
+		interval, err := rotation.ParseAge(cfg.Agent.CheckInterval)
+		if err != nil {
+			ln.Close()
+			return fmt.Errorf("invalid agent.check_interval: %w", err)
+		}
+		server.CheckEvery(interval, agentCheck(cfg))
+	}
+	if prompter := agentPinentry(cfg); prompter != "" {
+		server.ConfirmRequests(confirmAgentRequest(prompter))
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Stop()
+	}()
+
+	err = server.Serve(ln)
+	os.Remove(agentSocket())
+	return err
+}
+
+func runAgentStop(cmd *cobra.Command, args []string) error {
+	if err := agent.Stop(agentSocket()); err != nil {
+		if errors.Is(err, agent.ErrNoAgent) {
+			fmt.Println("Agent is not running")
+			return nil
+		}
+		return err
+	}
+	fmt.Println("🛑 Agent stopped")
+	return nil
+}
+
+func runAgentStatus(cmd *cobra.Command, args []string) error {
+	status, err := agent.GetStatus(agentSocket())
+	if errors.Is(err, agent.ErrNoAgent) {
+		fmt.Println("Agent is not running (start it with 'gpasswd agent start')")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Agent running (pid %d) on %s\n", status.PID, agentSocket())
+	if status.Vaults > 0 {
+		fmt.Println("   Vault: unlocked")
+	} else {
+		fmt.Println("   Vault: locked")
+	}
+	if status.IdleTimeout > 0 {
+		fmt.Printf("   Idle timeout: %s\n", status.IdleTimeout)
+	} else {
+		fmt.Println("   Idle timeout: none")
+	}
+	fmt.Printf("   Protocol: %d\n", status.Protocol)
+
+	timeFmt := loadTimeFormat()
+	found := false
+	for _, check := range status.Checks {
+		found = found || check.Total() > 0
+		label := "Last check"
+		if len(status.Checks) > 1 {
+			label += " of " + check.Vault
+		}
+		switch {
+		case check.Error != "":
+			fmt.Printf("   %s: ❌ %s (%s)\n", label, check.Error, timeFmt.Format(check.CheckedAt))
+		case check.Total() == 0:
+			fmt.Printf("   %s: ✅ no issues (%s)\n", label, timeFmt.Format(check.CheckedAt))
+		default:
+			fmt.Printf("   %s: ⚠️  %s (%s)\n", label, findingsSummary(check.Findings), timeFmt.Format(check.CheckedAt))
+		}
+	}
+	if found {
+		fmt.Println("   💡 Run 'gpasswd audit' for details")
+	}
+	return nil
+}
+
+func runAgentLock(cmd *cobra.Command, args []string) error {
+	if err := agent.Lock(agentSocket()); err != nil {
+		if errors.Is(err, agent.ErrNoAgent) {
+			fmt.Println("Agent is not running")
+			return nil
+		}
+		return err
+	}
+	fmt.Println("🔒 Agent locked")
+	return nil
+}
+
+// agentSocket returns the agent's socket path: GPASSWD_AGENT_SOCK, or
+// agent.sock in agentDir
+func agentSocket() string {
+	if socket := os.Getenv("GPASSWD_AGENT_SOCK"); socket != "" {
+		return socket
+	}
+	return filepath.Join(agentDir(), "agent.sock")
+}
+
+// agentPinentry returns the pinentry program the agent confirms key
+// requests with: pinentry.fallback, or the first one found
+func agentPinentry(cfg *config.Config) string {
+	if cfg.Pinentry.Fallback != "" {
+		return cfg.Pinentry.Fallback
+	}
+	self, _ := os.Executable()
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+	return pinentry.FindFallback(self)
+}
+
+// confirmAgentRequest asks in a pinentry dialog whether a program the agent
+// cannot identify may get or replace the key of a vault, or lock or stop
+// the agent
+func confirmAgentRequest(prompter string) func(op, vault string) bool {
+	return func(op, vault string) bool {
+		client, err := pinentry.Start(prompter)
+		if err != nil {
+			return false
+		}
+		defer client.Close()
+
+		var action string
+		switch op {
+		case agent.OpGet:
+			action = "hand over the key of\n\n" + vault
+		case agent.OpPut:
+			action = "replace the key it holds for\n\n" + vault
+		case agent.OpLock:
+			action = "forget every key"
+		default:
+			action = "stop"
+		}
+		text := fmt.Sprintf("A program wants the gpasswd agent to %s\n\nAllow only if you just ran a gpasswd command.", action)
+		allowed, err := client.Confirm("gpasswd", text, "Allow", "Deny")
+		return err == nil && allowed
+	}
+}
+
+// agentKey returns the key a running agent holds for db's vault
+// A key that no longer opens the vault (the master password changed) is
+// ignored; the next prompt replaces it in the agent
+func agentKey(db *storage.DB) ([]byte, bool) {
+	key, err := agent.Get(agentSocket(), sessionVault(db))
+	if err != nil {
+		return nil, false
+	}
+	if err := db.VerifyKey(key); err != nil {
+		return nil, false
+	}
+	return key, true
+}
+
+// shareWithAgent hands a freshly verified key to the agent, if one runs
+func shareWithAgent(db *storage.DB, key []byte) {
+	_ = agent.Put(agentSocket(), sessionVault(db), key)
+}
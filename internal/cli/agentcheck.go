@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/agent"
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/breach"
+	"github.com/kitsnail/gpasswd/internal/notify"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// agentCheckExpiringWithin matches the default of 'gpasswd audit'
+const agentCheckExpiringWithin = 7 * 24 * time.Hour
+
+// agentCheckIssues are the findings the background check reports, most
+// urgent first; unusual access hints are left to 'gpasswd audit'
+var agentCheckIssues = []audit.IssueType{
+	audit.IssueBreached,
+	audit.IssueExpired,
+	audit.IssueWeak,
+	audit.IssueReused,
+	audit.IssueExpiring,
+	audit.IssueIncomplete,
+}
+
+// agentCheck returns the background check of 'gpasswd agent': an audit of
+// the vault in memory, with a desktop notification when its findings change
+// Checks run one at a time, so last needs no lock
+func agentCheck(cfg *config.Config) agent.CheckFunc {
+	last := make(map[string]map[string]int)
+	return func(vault string, key []byte) (map[string]int, error) {
+		findings, err := auditVault(cfg, vault, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Agent.Notify && !sameFindings(last[vault], findings) {
+			if summary := findingsSummary(findings); summary != "" {
+				body := summary + " in " + filepath.Base(vault) + ". Run 'gpasswd audit' for details"
+				_ = notify.Send("🛡️ gpasswd", body)
+			}
+		}
+		last[vault] = findings
+		return findings, nil
+	}
+}
+
+// auditVault audits the vault at path and counts the findings by kind;
+// entries are decrypted in memory only
+func auditVault(cfg *config.Config, path string, key []byte) (map[string]int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("vault not found: %w", err)
+	}
+
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := audit.Options{Policy: &policy, ExpiringWithin: agentCheckExpiringWithin}
+	if cfg.Agent.PwnedFile != "" {
+		pwnedFile := cfg.Agent.PwnedFile
+		if strings.HasPrefix(pwnedFile, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get home directory: %w", err)
+			}
+			pwnedFile = filepath.Join(home, pwnedFile[2:])
+		}
+		checker, err := breach.OpenFile(pwnedFile)
+		if err != nil {
+			return nil, err
+		}
+		defer checker.Close()
+		opts.BreachChecker = checker
+	}
+
+	db, err := storage.InitDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.VerifyKey(key); err != nil {
+		return nil, fmt.Errorf("the agent's key no longer opens the vault")
+	}
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	report, err := audit.Run(entries, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make(map[string]int)
+	for _, issue := range agentCheckIssues {
+		if count := report.Count(issue); count > 0 {
+			findings[string(issue)] = count
+		}
+	}
+	return findings, nil
+}
+
+// findingsSummary lists finding counts most urgent first, e.g.
+// "1 breached, 2 weak"; "" if there are none
+func findingsSummary(findings map[string]int) string {
+	var parts []string
+	for _, issue := range agentCheckIssues {
+		if count := findings[string(issue)]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, issue))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sameFindings reports whether two checks found the same counts
+func sameFindings(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for issue, count := range a {
+		if b[issue] != count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// errNoPrompt is returned when the master password is needed but there is
+// neither a terminal (or prompting is off) nor an askpass program to ask with
+var errNoPrompt = errors.New("cannot prompt for the master password; use --password-file, --password-stdin or GPASSWD_MASTER_PASSWORD, or set GPASSWD_ASKPASS to a program that prints it")
+
+// askpassPrompt is the prompt shown by the askpass program; commands that
+// start without a terminal set it to say what the password is for
+var askpassPrompt = "gpasswd master password:"
+
+// askpassHook replaces the askpass program when set; 'gpasswd pinentry
+// serve' asks through the real pinentry instead
+var askpassHook func(prompt string) (string, error)
+
+// askpassProgram returns the program to ask for the master password with
+// when there is no terminal: GPASSWD_ASKPASS, or like ssh, SSH_ASKPASS when
+// a graphical display is available. "" if there is none
+func askpassProgram() string {
+	if program := os.Getenv("GPASSWD_ASKPASS"); program != "" {
+		return program
+	}
+	if os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != "" {
+		return os.Getenv("SSH_ASKPASS")
+	}
+	return ""
+}
+
+// askpass runs the askpass program with the prompt as its only argument
+// and returns the first line it prints. A non-zero exit (the dialog was
+// cancelled) or empty output fails
+func askpass(prompt string) (string, error) {
+	if askpassHook != nil {
+		return askpassHook(prompt)
+	}
+
+	program := askpassProgram()
+	if program == "" {
+		return "", errNoPrompt
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(program, prompt)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("askpass program %s failed: %w", program, err)
+	}
+
+	password, _, _ := strings.Cut(stdout.String(), "\n")
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("askpass program %s returned no password", program)
+	}
+	return password, nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+// parseAsOf parses an --as-of time: a date (the end of that day), a date
+// and time, or RFC 3339
+func parseAsOf(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		// "As of a day" includes everything changed on that day
+		return date.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339} {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --as-of %q (expected YYYY-MM-DD, \"YYYY-MM-DD HH:MM\" or RFC 3339)", value)
+}
+
+// entriesAsOf replaces current entries with the versions current at the
+// given time, dropping entries created later
+func entriesAsOf(db *storage.DB, entries []*models.Entry, at time.Time, key []byte) ([]*models.Entry, error) {
+	versions := make([]*models.Entry, 0, len(entries))
+	for _, entry := range entries {
+		version, err := db.EntryAsOf(entry, at, key)
+		if errors.Is(err, storage.ErrNotYetCreated) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
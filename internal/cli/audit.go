@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/breach"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/rotation"
+	"github.com/kitsnail/gpasswd/internal/sites"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit stored passwords for weaknesses",
+	Long: `Audit all stored passwords and report problems.
+
+Checks performed:
+- Weak passwords (failing the strength policy in config.yaml)
+- Passwords reused across multiple entries
+- Breached passwords (with --pwned-file; 'gpasswd audit breach' checks
+  online with Have I Been Pwned)
+- Tokens and other entries that have expired or expire soon
+  (within 7 days by default, see --expiring-within)
+- Entries read at unusual hours or unusually often, compared with the
+  vault's own access history (see 'gpasswd log access')
+
+--no-2fa lists only logins on sites known to support TOTP two-factor
+authentication (from a bundled snapshot of 2fa.directory) that have no
+OTP secret stored: turn on 2FA there and save the secret with
+'gpasswd totp set <name>'.
+
+For air-gapped machines, download the Have I Been Pwned "SHA-1 ordered by hash"
+password file and pass it with --pwned-file. The file is binary searched on disk,
+so no network access is needed and the file is never loaded into memory.
+
+Passwords are never printed, only entry names and issue types.
+
+--report writes a Markdown or HTML report instead, e.g. for a cron job or
+hook to email. Reports list entry names, categories and issue types only;
+prompts and status go to stderr so the report can also go to stdout.
+
+Examples:
+  gpasswd audit
+  gpasswd audit --report html -o report.html
+  gpasswd audit --report markdown | mail -s "Password audit" me@example.com
+  gpasswd audit --pwned-file ~/Downloads/pwned-passwords-sha1-ordered-by-hash-v8.txt
+  gpasswd audit --expiring-within 30d
+  gpasswd audit --no-2fa`,
+	RunE: runAudit,
+}
+
+var (
+	auditPwnedFile      string
+	auditExpiringWithin string
+	auditReport         string
+	auditOut            string
+	auditNo2FA          bool
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVar(&auditPwnedFile, "pwned-file", "", "Path to a local HIBP SHA-1 file ordered by hash")
+	auditCmd.Flags().StringVar(&auditExpiringWithin, "expiring-within", "7d", "Report entries expiring within this window, e.g. 7d, 4w")
+	auditCmd.Flags().StringVar(&auditReport, "report", "", "Write a report instead (markdown, html)")
+	auditCmd.Flags().BoolVar(&auditNo2FA, "no-2fa", false, "List logins on sites supporting TOTP that have no OTP secret")
+	auditCmd.Flags().StringVarP(&auditOut, "out", "o", "", "With --report, output file (default: stdout)")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	var reportFormat audit.ReportFormat
+	if auditReport != "" {
+		format, err := audit.ParseReportFormat(auditReport)
+		if err != nil {
+			return err
+		}
+		reportFormat = format
+	} else if auditOut != "" {
+		return fmt.Errorf("--out requires --report")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open breach file before prompting so a bad path fails fast
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	expiringWithin, err := rotation.ParseAge(auditExpiringWithin)
+	if err != nil {
+		return fmt.Errorf("invalid --expiring-within: %w", err)
+	}
+	opts := audit.Options{Policy: &policy, ExpiringWithin: expiringWithin, Check2FA: auditNo2FA}
+	if auditPwnedFile != "" {
+		checker, err := breach.OpenFile(auditPwnedFile)
+		if err != nil {
+			return err
+		}
+		defer checker.Close()
+		opts.BreachChecker = checker
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Reports keep stdout clean; prompts and status go to stderr
+	status := os.Stdout
+	var key []byte
+	if reportFormat != "" {
+		status = os.Stderr
+		key, err = unlockVault(db, status, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	} else {
+		key, err = unlockVault(db, status)
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	access, err := db.AccessSince(time.Now().Add(-audit.AccessWindow))
+	if err != nil {
+		return err
+	}
+	opts.Access = accessTimes(access)
+
+	if auditPwnedFile != "" {
+		fmt.Fprintf(status, "🔎 Checking %d entries against %s...\n", len(entries), auditPwnedFile)
+	}
+
+	report, err := audit.Run(entries, opts)
+	if err != nil {
+		return fmt.Errorf("audit failed: %w", err)
+	}
+
+	if auditNo2FA {
+		report = report.Only(audit.IssueNo2FA)
+	}
+
+	if reportFormat != "" {
+		return writeAuditReport(report, reportFormat)
+	}
+
+	if auditNo2FA {
+		printNo2FAReport(report, entries)
+		return nil
+	}
+
+	printAuditReport(report, auditPwnedFile != "")
+
+	return nil
+}
+
+// writeAuditReport renders the report to --out or stdout
+func writeAuditReport(report *audit.Report, format audit.ReportFormat) error {
+	// Reports use the configured time zone
+	write := func(out io.Writer) error {
+		if err := report.Render(out, format, loadTimeFormat().In(time.Now())); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+	if auditOut == "" {
+		return write(os.Stdout)
+	}
+	if err := atomicfile.Write(auditOut, 0600, write); err != nil {
+		return err
+	}
+
+	if auditOut != "" {
+		fmt.Fprintf(os.Stderr, "✅ Audit report (%d findings) written to %s\n", len(report.Findings), auditOut)
+	}
+	return nil
+}
+
+// printAuditReport renders an audit report as human-readable text
+func printAuditReport(report *audit.Report, breachChecked bool) {
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🛡️  Audit of %d entries\n", report.Total)
+	fmt.Println(strings.Repeat("─", 60))
+
+	fmt.Printf("Weak:        %d\n", report.Count(audit.IssueWeak))
+	fmt.Printf("Reused:      %d\n", report.Count(audit.IssueReused))
+	if breachChecked {
+		fmt.Printf("Breached:    %d\n", report.Count(audit.IssueBreached))
+	}
+	if expiring := report.Count(audit.IssueExpiring); expiring > 0 {
+		fmt.Printf("Expiring:    %d\n", expiring)
+	}
+	if expired := report.Count(audit.IssueExpired); expired > 0 {
+		fmt.Printf("Expired:     %d\n", expired)
+	}
+	if incomplete := report.Count(audit.IssueIncomplete); incomplete > 0 {
+		fmt.Printf("Incomplete:  %d\n", incomplete)
+	}
+	if unusual := report.Count(audit.IssueUnusualAccess); unusual > 0 {
+		fmt.Printf("Unusual access: %d (see 'gpasswd log access <name>')\n", unusual)
+	}
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(report.Findings) == 0 {
+		fmt.Println("\n✅ No issues found")
+		return
+	}
+
+	fmt.Println()
+	for _, finding := range report.Findings {
+		fmt.Printf("⚠️  %-24s [%s] %s\n", finding.EntryName, finding.Type, finding.Detail)
+	}
+
+	fmt.Println("\n💡 Use 'gpasswd edit <name> --generate' to replace a password")
+}
+
+// printNo2FAReport lists logins that could have 2FA but store no OTP secret
+func printNo2FAReport(report *audit.Report, entries []*models.Entry) {
+	capable := 0
+	for _, entry := range entries {
+		if _, ok := sites.SupportsTOTP(entry.URL); ok && entry.IsLogin() {
+			capable++
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🔐 2FA coverage: %d of %d logins on sites with TOTP have no OTP secret\n", len(report.Findings), capable)
+	fmt.Println(strings.Repeat("─", 60))
+
+	if capable == 0 {
+		fmt.Println("\nNo logins on sites known to support TOTP")
+		return
+	}
+	if len(report.Findings) == 0 {
+		fmt.Println("\n✅ Every login on a site with TOTP has an OTP secret")
+		return
+	}
+
+	fmt.Println()
+	for _, finding := range report.Findings {
+		fmt.Printf("⚠️  %-24s %s\n", finding.EntryName, finding.Detail)
+	}
+
+	fmt.Println("\n💡 Turn on 2FA on the site, then store the secret with 'gpasswd totp set <name>'")
+}
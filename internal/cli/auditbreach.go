@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/breach"
+)
+
+var auditBreachCmd = &cobra.Command{
+	Use:   "breach",
+	Short: "Check stored passwords against Have I Been Pwned",
+	Long: `Check every stored password against the Have I Been Pwned password
+corpus and list the entries whose password has been seen in a breach.
+
+Passwords never leave the machine. Each one is hashed with SHA-1 and only
+the first 5 characters of the hash are sent to the range API
+(k-anonymity); the API answers with all of the hundreds of hashes sharing
+them, padded to hide which prefix was asked for, and the match is made
+locally. Each prefix is fetched once per run.
+
+--offline checks against a locally downloaded HIBP "SHA-1 ordered by hash"
+file instead and needs no network at all. --api-url points at a mirror of
+the range API.
+
+Examples:
+  gpasswd audit breach
+  gpasswd audit breach --offline ~/Downloads/pwned-passwords-sha1-ordered-by-hash-v8.txt`,
+	Args: cobra.NoArgs,
+	RunE: runAuditBreach,
+}
+
+var (
+	auditBreachOffline string
+	auditBreachAPIURL  string
+)
+
+func init() {
+	auditCmd.AddCommand(auditBreachCmd)
+
+	auditBreachCmd.Flags().StringVar(&auditBreachOffline, "offline", "", "Check against a local HIBP SHA-1 file instead of the API")
+	auditBreachCmd.Flags().StringVar(&auditBreachAPIURL, "api-url", breach.DefaultRangeURL, "Range API URL, e.g. a mirror")
+}
+
+func runAuditBreach(cmd *cobra.Command, args []string) error {
+	// Open the breach file before prompting so a bad path fails fast
+	var checker breach.Checker
+	if auditBreachOffline != "" {
+		file, err := breach.OpenFile(auditBreachOffline)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		checker = file
+	} else {
+		checker = breach.NewRangeChecker(auditBreachAPIURL, "gpasswd/"+Version)
+	}
+
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	if auditBreachOffline != "" {
+		fmt.Printf("🔎 Checking %d entries against %s...\n", len(entries), auditBreachOffline)
+	} else {
+		fmt.Printf("🌐 Checking %d entries against Have I Been Pwned (only hash prefixes are sent)...\n", len(entries))
+	}
+
+	report, err := audit.Run(entries, audit.Options{BreachChecker: checker})
+	if err != nil {
+		return fmt.Errorf("audit failed: %w", err)
+	}
+	report = report.Only(audit.IssueBreached)
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🛡️  Breach check of %d entries: %d compromised\n", report.Total, len(report.Findings))
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(report.Findings) == 0 {
+		fmt.Println("\n✅ No stored password was found in a breach")
+		return nil
+	}
+
+	fmt.Println()
+	for _, finding := range report.Findings {
+		fmt.Printf("🚨 %-24s %s\n", finding.EntryName, finding.Detail)
+	}
+
+	fmt.Println("\n💡 Change these passwords now, e.g. 'gpasswd rotate <name>'")
+	return nil
+}
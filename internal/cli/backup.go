@@ -0,0 +1,355 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/backup"
+	"github.com/kitsnail/gpasswd/internal/export"
+	"github.com/kitsnail/gpasswd/internal/filter"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/share"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Compare and restore vault backups",
+	Long: `Work with backups of the vault.
+
+A backup is a read-only bundle written by
+  gpasswd export --viewer -o backup.gpb --expires never
+It holds the entries in a single file protected by its own passphrase.`,
+}
+
+var backupDiffCmd = &cobra.Command{
+	Use:   "diff <backup.gpb>",
+	Short: "Show how a backup differs from the vault",
+	Long: `Compare a backup with the live vault and list entries only in the backup,
+only in the vault, or changed. For changed entries only the names of the
+differing fields are shown, never their values.
+
+Use it to check a backup, or to see what restoring from it would change.
+A backup made with --category or --match only covers part of the vault, so
+the other entries show up as only in the vault.
+
+Examples:
+  gpasswd backup diff backup.gpb`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupDiff,
+}
+
+var backupRestoreEntryCmd = &cobra.Command{
+	Use:   "restore-entry <backup.gpb> [name]",
+	Short: "Restore single entries from a backup",
+	Long: `Copy one entry, or the entries selected by --category, --match or --glob,
+from a backup into the vault. Nothing else in the vault is touched.
+
+Entries missing from the vault are added back. When the vault already has a
+different version of an entry, --on-conflict decides:
+  ask        prompt for each entry (default)
+  overwrite  replace the vault version; it stays in the entry's history
+  rename     restore the backup version as a copy, e.g. "github (2)"
+  skip       keep the vault version
+
+Examples:
+  gpasswd backup restore-entry backup.gpb github
+  gpasswd backup restore-entry backup.gpb --category work --on-conflict skip`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runBackupRestoreEntry,
+}
+
+var (
+	backupCategory   string
+	backupMatch      string
+	backupGlob       string
+	backupOnConflict string
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.AddCommand(backupDiffCmd)
+	backupCmd.AddCommand(backupRestoreEntryCmd)
+
+	backupRestoreEntryCmd.Flags().StringVarP(&backupCategory, "category", "c", "", "Restore entries in this category")
+	backupRestoreEntryCmd.Flags().StringVar(&backupMatch, "match", "", "Restore names/categories matching a regular expression")
+	backupRestoreEntryCmd.Flags().StringVar(&backupGlob, "glob", "", "Restore names/categories matching a glob pattern")
+	backupRestoreEntryCmd.Flags().StringVar(&backupOnConflict, "on-conflict", "ask", "When the vault has a different version (ask, overwrite, rename, skip)")
+}
+
+func runBackupDiff(cmd *cobra.Command, args []string) error {
+	bundle, err := readBackup(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	live, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	differences, unchanged := backup.Diff(live, bundle.Entries)
+
+	counts := make(map[backup.Kind]int)
+	for _, difference := range differences {
+		counts[difference.Kind]++
+	}
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🗂️  Backup from %s vs vault\n", timeFormat(cfg).Detailed(bundle.CreatedAt))
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(differences) == 0 {
+		fmt.Printf("✅ Backup matches the vault (%d entries)\n", unchanged)
+		return nil
+	}
+
+	for _, difference := range differences {
+		switch difference.Kind {
+		case backup.OnlyInBackup:
+			fmt.Printf("  + %-30s only in backup\n", difference.Name)
+		case backup.OnlyInVault:
+			fmt.Printf("  - %-30s only in vault\n", difference.Name)
+		case backup.Changed:
+			fmt.Printf("  ~ %-30s %s\n", difference.Name, strings.Join(difference.Fields, ", "))
+		}
+	}
+
+	fmt.Printf("\n%d only in backup, %d only in vault, %d changed, %d unchanged\n",
+		counts[backup.OnlyInBackup], counts[backup.OnlyInVault], counts[backup.Changed], unchanged)
+	return nil
+}
+
+// readBackup reads and decrypts a backup bundle
+func readBackup(path string) (*export.ViewerBundle, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("backup '%s' not found", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return openViewerBundle(data, time.Now())
+}
+
+func runBackupRestoreEntry(cmd *cobra.Command, args []string) error {
+	switch backupOnConflict {
+	case "ask", "overwrite", "rename", "skip":
+	default:
+		return fmt.Errorf("invalid --on-conflict %q (use ask, overwrite, rename or skip)", backupOnConflict)
+	}
+
+	filtered := backupCategory != "" || backupMatch != "" || backupGlob != ""
+	if len(args) == 2 && filtered {
+		return fmt.Errorf("give an entry name or --category/--match/--glob, not both")
+	}
+	if len(args) == 1 && !filtered {
+		return fmt.Errorf("give an entry name or select entries with --category, --match or --glob")
+	}
+	entryFilter, err := filter.New(filter.Options{
+		Category: backupCategory,
+		Match:    backupMatch,
+		Glob:     backupGlob,
+	})
+	if err != nil {
+		return err
+	}
+
+	bundle, err := readBackup(args[0])
+	if err != nil {
+		return err
+	}
+
+	var selected []*models.Entry
+	if len(args) == 2 {
+		for _, entry := range bundle.Entries {
+			if strings.EqualFold(entry.Name, args[1]) {
+				selected = append(selected, entry)
+			}
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("entry '%s' is not in the backup", args[1])
+		}
+	} else {
+		selected = entryFilter.Apply(bundle.Entries)
+		if len(selected) == 0 {
+			return fmt.Errorf("no entries in the backup match the filter")
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	live, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	names := make(map[string]bool, len(live))
+	for _, entry := range live {
+		names[strings.ToLower(entry.Name)] = true
+	}
+
+	var restored, overwritten, copied, skipped, unchanged int
+	var conflicts []storage.ImportConflict
+	for _, entry := range selected {
+		current := backup.Match(live, entry)
+		if current == nil {
+			names[strings.ToLower(entry.Name)] = true
+			if err := db.CreateEntry(entry, key); err != nil {
+				fmt.Printf("   ✗ Failed to restore '%s': %v\n", entry.Name, err)
+				continue
+			}
+			fmt.Printf("   + %s\n", entry.Name)
+			restored++
+			continue
+		}
+
+		fields := share.ChangedFields(entry, current)
+		if len(fields) == 0 {
+			unchanged++
+			continue
+		}
+
+		action, err := resolveRestoreConflict(current, fields)
+		if err != nil {
+			return err
+		}
+
+		switch action {
+		case "overwrite":
+			// Keep the vault's ID so history and access logs stay attached
+			entry.ID = current.ID
+			if !strings.EqualFold(entry.Name, current.Name) && names[strings.ToLower(entry.Name)] {
+				entry.Name = current.Name
+			}
+			names[strings.ToLower(entry.Name)] = true
+			if err := db.UpdateEntry(entry, key); err != nil {
+				fmt.Printf("   ✗ Failed to restore '%s': %v\n", current.Name, err)
+				continue
+			}
+			fmt.Printf("   ~ %s (previous version kept in history)\n", entry.Name)
+			overwritten++
+
+		case "rename":
+			original := entry.Name
+			entry.ID = ""
+			entry.Name = uniqueEntryName(entry.Name, names)
+			names[strings.ToLower(entry.Name)] = true
+			if err := db.CreateEntry(entry, key); err != nil {
+				fmt.Printf("   ✗ Failed to restore '%s': %v\n", original, err)
+				continue
+			}
+			fmt.Printf("   + %s (copy of '%s' from backup)\n", entry.Name, original)
+			copied++
+			conflicts = append(conflicts, storage.ImportConflict{
+				EntryID:    entry.ID,
+				EntryName:  entry.Name,
+				Original:   original,
+				Source:     "backup " + filepath.Base(args[0]),
+				ImportedAt: time.Now(),
+			})
+
+		default:
+			fmt.Printf("   ! %s: kept the vault version\n", current.Name)
+			skipped++
+		}
+	}
+
+	if err := db.AddImportConflicts(conflicts); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ %d restored, %d overwritten, %d restored as copies, %d skipped, %d already up to date\n",
+		restored, overwritten, copied, skipped, unchanged)
+	return nil
+}
+
+// resolveRestoreConflict applies --on-conflict, asking if needed
+func resolveRestoreConflict(current *models.Entry, fields []string) (string, error) {
+	if backupOnConflict != "ask" {
+		return backupOnConflict, nil
+	}
+
+	options := []string{
+		"Overwrite the vault version (kept in history)",
+		"Restore the backup version as a copy",
+		"Skip",
+	}
+	actions := []string{"overwrite", "rename", "skip"}
+
+	var choice int
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("'%s' differs from the backup (%s):", current.Name, strings.Join(fields, ", ")),
+		Options: options,
+	}
+	if err := askOne(prompt, &choice); err != nil {
+		return "", fmt.Errorf("selection failed: %w", err)
+	}
+	return actions[choice], nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/rotation"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// hygieneSummary counts the vault issues shown by the unlock banner
+type hygieneSummary struct {
+	Expired   int
+	Weak      int
+	Conflicts int
+}
+
+// String renders the non-zero counts, e.g. "3 passwords expired, 1 weak"
+func (s hygieneSummary) String() string {
+	var parts []string
+	if s.Expired > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s expired", s.Expired, plural(s.Expired, "password", "passwords")))
+	}
+	if s.Weak > 0 {
+		if len(parts) == 0 {
+			parts = append(parts, fmt.Sprintf("%d weak %s", s.Weak, plural(s.Weak, "password", "passwords")))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d weak", s.Weak))
+		}
+	}
+	if s.Conflicts > 0 {
+		parts = append(parts, fmt.Sprintf("%d import %s", s.Conflicts, plural(s.Conflicts, "conflict", "conflicts")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// showUnlockBanner prints a one-line hygiene summary after unlock when
+// enabled in the configuration. Problems computing it are reported but
+// never block the command
+func showUnlockBanner(db *storage.DB, key []byte, out io.Writer) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.UnlockBanner.Enabled {
+		return
+	}
+
+	summary, err := vaultHygiene(db, key, cfg)
+	if err != nil {
+		fmt.Fprintf(out, "⚠️  Could not check vault hygiene: %v\n", err)
+		return
+	}
+
+	if line := summary.String(); line != "" {
+		fmt.Fprintf(out, "📋 %s (see 'gpasswd audit' and 'gpasswd rotate --plan')\n", line)
+	}
+}
+
+// vaultHygiene counts expired and weak passwords and open import conflicts
+func vaultHygiene(db *storage.DB, key []byte, cfg *config.Config) (hygieneSummary, error) {
+	var summary hygieneSummary
+
+	expireAfter, err := rotation.ParseAge(cfg.UnlockBanner.ExpireAfter)
+	if err != nil {
+		return summary, fmt.Errorf("unlock_banner.expire_after: %w", err)
+	}
+
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return summary, err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return summary, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Password == "" {
+			continue
+		}
+
+		// Entries with an explicit expiry (tokens) ignore password age
+		if entry.ExpiresAt != nil {
+			if entry.ExpiresAt.Before(now) {
+				summary.Expired++
+			}
+		} else {
+			changedAt, err := db.PasswordChangedAt(entry, key)
+			if err != nil {
+				return summary, err
+			}
+			if now.Sub(changedAt) > expireAfter {
+				summary.Expired++
+			}
+		}
+
+		if !entry.IsToken() && !entry.IsKey() && len(policy.Violations(entry.Password)) > 0 {
+			summary.Weak++
+		}
+	}
+
+	conflicts, err := db.ImportConflicts()
+	if err != nil {
+		return summary, err
+	}
+	summary.Conflicts = len(conflicts)
+
+	return summary, nil
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
@@ -0,0 +1,918 @@
+package cli
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/nativemsg"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/pinentry"
+	"github.com/kitsnail/gpasswd/internal/securefs"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// browserHostName is the name browsers start gpasswd as
+const browserHostName = "gpasswd-browser-host"
+
+var browserHostCmd = &cobra.Command{
+	Use:     "browser-host",
+	Aliases: []string{"browser"},
+	Short:   "Let browser extensions fill and save logins (native messaging)",
+	Long: `Act as a native messaging host, so a browser extension can look up the
+logins for the page it is on, fill them in and save new ones.
+
+'browser-host install' links gpasswd-browser-host to gpasswd in the config
+directory and writes the host manifest ` + nativemsg.HostName + `
+that allows one extension to start it. The browser then runs it and talks
+JSON messages on stdin/stdout.
+
+The extension pairs once: it sends the public half of an X25519 key pair,
+gets the host's key and a pairing code, and shows the code. Running
+'gpasswd browser pair CODE' approves it. Every connection then opens with a
+hello carrying a fresh ephemeral key, and all other requests travel sealed
+with the session keys (AES-256-GCM, see internal/nativemsg/session.go):
+
+  {"action": "ping"}
+  {"action": "pair",   "public_key": "<base64>"}
+  {"action": "hello",  "public_key": "<base64>", "ephemeral": "<base64>"}
+  {"action": "sealed", "sealed": "<base64 sealed request>"}
+
+Sealed requests and their sealed replies are:
+
+  {"action": "query", "url": "https://github.com/login"}
+  {"action": "get",   "url": "https://github.com/login", "entry": "<id>"}
+  {"action": "save",  "url": "https://github.com/join", "username": "me", "password": "..."}
+
+Every reply has "ok" and, on failure, "error"; an "id" in a request is
+echoed back. query lists matching logins without secrets, get returns one
+with its password (and one-time code), save adds a login or updates the
+password of the one with the same username. 'browser pair' lists paired
+and pending extensions; --revoke KEY_ID unpairs one.
+
+A login matches a page with the same scheme, host and port as its URL (a
+URL without a scheme counts as https), so an https login is never filled
+into an http page. browser.match_subdomains in config.yaml also matches
+the subdomains of its host.
+
+Each web origin (scheme, host and port) must be approved before an
+extension can read or save its logins. The first request for an origin
+asks in the pinentry program (pinentry.fallback in config.yaml, or the
+first one found on PATH); 'browser-host approve' approves one ahead of
+time and 'browser-host approvals' lists and revokes them. The master
+password is asked with the GPASSWD_ASKPASS program or the pinentry, unless
+an agent or session holds the key. High-security entries ask for it on
+every get.
+
+Examples:
+  gpasswd browser-host install --browser chrome --extension-id abcdefghijklmnopabcdefghijklmnop
+  gpasswd browser-host install --browser firefox --extension-id gpasswd@example.org
+  gpasswd browser pair 482-019-736
+  gpasswd browser-host approve github.com       (https://github.com)
+  gpasswd browser-host approvals --revoke https://github.com`,
+}
+
+var browserHostServeCmd = &cobra.Command{
+	Use:                "serve",
+	Short:              "Speak native messaging on stdin/stdout (run by the browser)",
+	DisableFlagParsing: true, // Chrome on Windows passes --parent-window
+	RunE:               runBrowserHostServe,
+}
+
+var browserHostInstallCmd = &cobra.Command{
+	Use:   "install --browser NAME --extension-id ID",
+	Short: "Let a browser extension start gpasswd",
+	Args:  cobra.NoArgs,
+	RunE:  runBrowserHostInstall,
+}
+
+var browserHostUninstallCmd = &cobra.Command{
+	Use:   "uninstall --browser NAME",
+	Short: "Remove the host manifest of a browser",
+	Args:  cobra.NoArgs,
+	RunE:  runBrowserHostUninstall,
+}
+
+var browserHostApproveCmd = &cobra.Command{
+	Use:   "approve <origin>",
+	Short: "Let browser extensions use the logins of a web origin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBrowserHostApprove,
+}
+
+var browserHostApprovalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "List approved web origins, or revoke one",
+	Args:  cobra.NoArgs,
+	RunE:  runBrowserHostApprovals,
+}
+
+var browserHostPairCmd = &cobra.Command{
+	Use:   "pair [code]",
+	Short: "Approve a browser extension's pairing code, or list paired extensions",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runBrowserHostPair,
+}
+
+var (
+	browserHostBrowser     string
+	browserHostExtensionID string
+	browserHostRevoke      string
+	browserHostUnpair      string
+)
+
+func init() {
+	rootCmd.AddCommand(browserHostCmd)
+	browserHostCmd.AddCommand(browserHostServeCmd, browserHostInstallCmd, browserHostUninstallCmd, browserHostApproveCmd, browserHostApprovalsCmd, browserHostPairCmd)
+
+	browserList := strings.Join(nativemsg.Browsers, ", ")
+	browserHostInstallCmd.Flags().StringVar(&browserHostBrowser, "browser", "", "Browser: "+browserList+" (required)")
+	browserHostInstallCmd.Flags().StringVar(&browserHostExtensionID, "extension-id", "", "ID of the extension allowed to start gpasswd (required)")
+	browserHostInstallCmd.MarkFlagRequired("browser")
+	browserHostInstallCmd.MarkFlagRequired("extension-id")
+	browserHostUninstallCmd.Flags().StringVar(&browserHostBrowser, "browser", "", "Browser: "+browserList+" (required)")
+	browserHostUninstallCmd.MarkFlagRequired("browser")
+	browserHostApprovalsCmd.Flags().StringVar(&browserHostRevoke, "revoke", "", "Revoke the approval of this origin")
+	browserHostPairCmd.Flags().StringVar(&browserHostUnpair, "revoke", "", "Unpair the extension key with this ID")
+}
+
+// browserHostArgs maps an invocation as gpasswd-browser-host to
+// 'browser-host serve'
+func browserHostArgs(args []string) ([]string, bool) {
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	if name != browserHostName {
+		return nil, false
+	}
+	return append([]string{"browser-host", "serve"}, args[1:]...), true
+}
+
+// browserRequest is a message from the extension
+type browserRequest struct {
+	ID       json.RawMessage `json:"id,omitempty"`
+	Action   string          `json:"action"`
+	URL      string          `json:"url,omitempty"`
+	Entry    string          `json:"entry,omitempty"` // get: entry ID from query
+	Name     string          `json:"name,omitempty"`  // save: entry name; default the host
+	Username string          `json:"username,omitempty"`
+	Password string          `json:"password,omitempty"`
+
+	PublicKey string `json:"public_key,omitempty"` // pair, hello: the extension's X25519 key
+	Ephemeral string `json:"ephemeral,omitempty"`  // hello: the extension's key for this session
+	Sealed    string `json:"sealed,omitempty"`     // sealed: a request sealed with the session
+}
+
+// browserResponse is the reply to a browserRequest
+type browserResponse struct {
+	ID      json.RawMessage `json:"id,omitempty"`
+	OK      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Version string          `json:"version,omitempty"`
+	Logins  []browserLogin  `json:"logins,omitempty"`
+	Login   *browserLogin   `json:"login,omitempty"`
+	Created bool            `json:"created,omitempty"`
+
+	HostKey   string `json:"host_key,omitempty"`  // pair: the host's X25519 key
+	Code      string `json:"code,omitempty"`      // pair: the code to approve
+	Paired    bool   `json:"paired,omitempty"`    // pair: the key is already paired
+	Ephemeral string `json:"ephemeral,omitempty"` // hello: the host's key for this session
+	Sealed    string `json:"sealed,omitempty"`    // sealed: the sealed reply
+}
+
+// browserLogin is an entry as the extension sees it; Password and OTP are
+// only filled in by get
+type browserLogin struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Password string `json:"password,omitempty"`
+	OTP      string `json:"otp,omitempty"`
+}
+
+// browserHost answers the messages of one extension
+type browserHost struct {
+	db         *storage.DB
+	caller     string          // extension origin or ID, "" if unknown
+	pinentry   string          // program for approval prompts, "" if none
+	denied     map[string]bool // origins denied during this connection
+	subdomains bool            // browser.match_subdomains
+
+	session *nativemsg.Session // after a hello, nil before
+}
+
+// matches reports whether an entry is a login for the page origin
+func (h *browserHost) matches(entry *models.Entry, origin string) bool {
+	return loginMatchesPage(entry, origin, h.subdomains)
+}
+
+func runBrowserHostServe(cmd *cobra.Command, args []string) error {
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	prompter := cfg.Pinentry.Fallback
+	if prompter == "" {
+		self, _ := os.Executable()
+		if resolved, err := filepath.EvalSymlinks(self); err == nil {
+			self = resolved
+		}
+		prompter = pinentry.FindFallback(self)
+	}
+
+	// stdout carries the protocol, so the master password can only be
+	// asked in a dialog
+	askpassPrompt = "gpasswd: a browser extension wants to use your logins. Master password:"
+	if askpassProgram() == "" && prompter != "" {
+		askpassHook = masterPasswordPinentry(prompter, "Enter the gpasswd master password to let a browser extension use your logins.")
+	}
+
+	host := &browserHost{
+		db:         db,
+		caller:     nativemsg.Caller(args),
+		pinentry:   prompter,
+		denied:     map[string]bool{},
+		subdomains: cfg.Browser.MatchSubdomains,
+	}
+	for {
+		var req browserRequest
+		if err := nativemsg.Read(os.Stdin, &req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		resp := host.handle(&req)
+		resp.ID = req.ID
+		if err := nativemsg.Write(os.Stdout, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers one request; only ping, pair and hello are answered
+// outside a session
+func (h *browserHost) handle(req *browserRequest) *browserResponse {
+	var (
+		resp *browserResponse
+		err  error
+	)
+	switch req.Action {
+	case "ping":
+		resp = &browserResponse{Version: Version}
+	case "pair":
+		resp, err = h.pair(req)
+	case "hello":
+		resp, err = h.hello(req)
+	case "sealed":
+		resp, err = h.unseal(req)
+	case "query", "get", "save":
+		err = fmt.Errorf("%s must be sealed: pair the extension, then say hello", req.Action)
+	default:
+		err = fmt.Errorf("unknown action %q (expected ping, pair, hello or sealed)", req.Action)
+	}
+	return browserResult(resp, err)
+}
+
+// handleSealed answers a request opened from the session
+func (h *browserHost) handleSealed(req *browserRequest) *browserResponse {
+	var (
+		resp *browserResponse
+		err  error
+	)
+	switch req.Action {
+	case "ping":
+		resp = &browserResponse{Version: Version}
+	case "query":
+		resp, err = h.query(req)
+	case "get":
+		resp, err = h.get(req)
+	case "save":
+		resp, err = h.save(req)
+	default:
+		err = fmt.Errorf("unknown action %q (expected ping, query, get or save)", req.Action)
+	}
+	resp = browserResult(resp, err)
+	resp.ID = req.ID
+	return resp
+}
+
+// browserResult turns a handler's result into the reply
+func browserResult(resp *browserResponse, err error) *browserResponse {
+	if err != nil {
+		return &browserResponse{Error: err.Error()}
+	}
+	resp.OK = true
+	return resp
+}
+
+// pair records a pairing request for the extension's key and returns the
+// host key with the code to approve; a paired key gets only the host key
+func (h *browserHost) pair(req *browserRequest) (*browserResponse, error) {
+	extension, err := nativemsg.ParsePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := unlockIfPrivate(h.db, io.Discard); err != nil {
+		return nil, err
+	}
+	hostKey, err := loadBrowserHostKey(true)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := nativemsg.KeyID(extension)
+	paired, err := h.db.BrowserKeyPaired(keyID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &browserResponse{HostKey: nativemsg.EncodeKey(hostKey.PublicKey()), Paired: paired}
+	if paired {
+		return resp, nil
+	}
+
+	resp.Code = nativemsg.PairingCode(extension, hostKey.PublicKey())
+	request := storage.BrowserPairing{
+		KeyID:     keyID,
+		PublicKey: nativemsg.EncodeKey(extension),
+		Extension: h.caller,
+		Code:      resp.Code,
+	}
+	if err := h.db.RequestBrowserPairing(request); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// hello opens a session with a paired extension
+func (h *browserHost) hello(req *browserRequest) (*browserResponse, error) {
+	h.session = nil
+	extension, err := nativemsg.ParsePublicKey(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := nativemsg.ParsePublicKey(req.Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	if err := unlockIfPrivate(h.db, io.Discard); err != nil {
+		return nil, err
+	}
+
+	paired, err := h.db.BrowserKeyPaired(nativemsg.KeyID(extension))
+	if err != nil {
+		return nil, err
+	}
+	if !paired {
+		return nil, fmt.Errorf("this extension is not paired; send pair and approve the code with 'gpasswd browser pair CODE'")
+	}
+	hostKey, err := loadBrowserHostKey(false)
+	if err != nil {
+		return nil, err
+	}
+
+	session, hostEphemeral, err := nativemsg.AcceptSession(hostKey, extension, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	h.session = session
+	return &browserResponse{Ephemeral: nativemsg.EncodeKey(hostEphemeral)}, nil
+}
+
+// unseal opens a sealed request, answers it and seals the reply
+// A message that fails to open ends the session, since the counters no
+// longer agree
+func (h *browserHost) unseal(req *browserRequest) (*browserResponse, error) {
+	if h.session == nil {
+		return nil, fmt.Errorf("no session; say hello first")
+	}
+	var inner browserRequest
+	if err := h.session.Open(req.Sealed, &inner); err != nil {
+		h.session = nil
+		return nil, err
+	}
+	sealed, err := h.session.Seal(h.handleSealed(&inner))
+	if err != nil {
+		return nil, err
+	}
+	return &browserResponse{Sealed: sealed}, nil
+}
+
+// browserHostKeyPath is the host's X25519 key, which paired extensions know
+func browserHostKeyPath() string {
+	return filepath.Join(config.GetConfigDir(), "browser-host.key")
+}
+
+// loadBrowserHostKey reads the host key, generating it first if there is
+// none and create is set
+func loadBrowserHostKey(create bool) (*ecdh.PrivateKey, error) {
+	path := browserHostKeyPath()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid browser host key in %s", path)
+		}
+		key, err := ecdh.X25519().NewPrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid browser host key in %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read browser host key: %w", err)
+	}
+	if !create {
+		return nil, fmt.Errorf("the browser host has no key; pair the extension again")
+	}
+
+	key, err := nativemsg.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate browser host key: %w", err)
+	}
+	if err := securefs.MkdirAll(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key.Bytes())+"\n"), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save browser host key: %w", err)
+	}
+	if err := securefs.Restrict(path); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// open unlocks the vault and checks that the page's origin is approved
+// A private vault keeps the approved origins encrypted, so the vault is
+// unlocked first
+func (h *browserHost) open(rawURL string) (key []byte, origin, host string, err error) {
+	origin, host, err = pageOrigin(rawURL)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if h.denied[origin] {
+		return nil, "", "", fmt.Errorf("access to %s was denied", origin)
+	}
+	key, err = unlockVault(h.db, io.Discard)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := h.approve(origin); err != nil {
+		return nil, "", "", err
+	}
+	return key, origin, host, nil
+}
+
+// approve asks in the pinentry whether the extension may use the logins of
+// origin, unless it was approved before
+func (h *browserHost) approve(origin string) error {
+	approved, err := h.db.BrowserOriginApproved(origin)
+	if err != nil || approved {
+		return err
+	}
+	if h.pinentry == "" {
+		return fmt.Errorf("%s is not approved and there is no pinentry to ask with; run 'gpasswd browser-host approve %s'", origin, origin)
+	}
+
+	client, err := pinentry.Start(h.pinentry)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	caller := h.caller
+	if caller == "" {
+		caller = "unknown"
+	}
+	text := fmt.Sprintf("A browser extension wants to fill in and save gpasswd logins for\n\n%s\n\nExtension: %s", origin, caller)
+	allowed, err := client.Confirm("gpasswd", text, "Allow", "Deny")
+	if err != nil {
+		return fmt.Errorf("approval prompt failed: %w", err)
+	}
+	if !allowed {
+		h.denied[origin] = true
+		return fmt.Errorf("access to %s was denied", origin)
+	}
+	return h.db.ApproveBrowserOrigin(origin, h.caller)
+}
+
+// query lists the logins for a page, without secrets
+func (h *browserHost) query(req *browserRequest) (*browserResponse, error) {
+	key, origin, _, err := h.open(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := h.db.GetAllEntries(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	resp := &browserResponse{Logins: []browserLogin{}}
+	for _, entry := range entries {
+		if !h.matches(entry, origin) {
+			continue
+		}
+		login := browserLogin{ID: entry.ID, Name: entry.Name, Username: entry.Username, URL: entry.URL}
+		if entry.IsHighSecurity() {
+			login.Username = ""
+		}
+		resp.Logins = append(resp.Logins, login)
+	}
+	return resp, nil
+}
+
+// get returns one login of a page with its password
+func (h *browserHost) get(req *browserRequest) (*browserResponse, error) {
+	if req.Entry == "" {
+		return nil, fmt.Errorf("get needs the entry ID from query")
+	}
+	key, origin, _, err := h.open(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := h.db.GetEntry(req.Entry, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	// An approved origin only opens its own logins
+	if !h.matches(entry, origin) {
+		return nil, fmt.Errorf("entry is not a login for %s", origin)
+	}
+
+	recordAccess(h.db, entry, "browser")
+	if err := requireFreshUnlock(h.db, entry, io.Discard); err != nil {
+		return nil, err
+	}
+	if err := output.ConfirmReveal(entry, "fill it in the browser"); err != nil {
+		return nil, err
+	}
+
+	login := &browserLogin{ID: entry.ID, Name: entry.Name, Username: entry.Username, URL: entry.URL, Password: entry.Password}
+	if entry.OTP != "" {
+		if login.OTP, _, err = nextOTPCode(h.db, entry, key, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return &browserResponse{Login: login}, nil
+}
+
+// save adds a login for a page, or changes the password of the page's
+// login with the same username
+func (h *browserHost) save(req *browserRequest) (*browserResponse, error) {
+	if req.Password == "" {
+		return nil, fmt.Errorf("save needs a password")
+	}
+	key, origin, host, err := h.open(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := h.db.GetAllEntries(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !h.matches(entry, origin) || !strings.EqualFold(entry.Username, req.Username) {
+			continue
+		}
+		if entry.IsHighSecurity() {
+			if err := requireFreshUnlock(h.db, entry, io.Discard); err != nil {
+				return nil, err
+			}
+		}
+		if entry.Password != req.Password {
+			entry.Password = req.Password
+			if err := h.db.UpdateEntry(entry, key); err != nil {
+				return nil, fmt.Errorf("failed to update entry: %w", err)
+			}
+		}
+		return &browserResponse{Login: &browserLogin{ID: entry.ID, Name: entry.Name, Username: entry.Username, URL: entry.URL}}, nil
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = host
+		if exists, err := h.db.EntryExists(name); err != nil {
+			return nil, err
+		} else if exists && req.Username != "" {
+			name = fmt.Sprintf("%s (%s)", host, req.Username)
+		}
+	}
+	if exists, err := h.db.EntryExists(name); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("entry '%s' already exists; save it under another name", name)
+	}
+
+	entry := &models.Entry{
+		Name:     name,
+		Username: req.Username,
+		Password: req.Password,
+		URL:      origin,
+		Category: "general",
+	}
+	if err := h.db.CreateEntry(entry, key); err != nil {
+		return nil, fmt.Errorf("failed to create entry: %w", err)
+	}
+	return &browserResponse{
+		Login:   &browserLogin{ID: entry.ID, Name: entry.Name, Username: entry.Username, URL: entry.URL},
+		Created: true,
+	}, nil
+}
+
+// pageOrigin returns the origin (scheme://host[:port]) and host of a page
+// URL; only http and https pages have logins
+func pageOrigin(rawURL string) (origin, host string, err error) {
+	if strings.TrimSpace(rawURL) == "" {
+		return "", "", fmt.Errorf("missing page URL")
+	}
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid page URL: %w", err)
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", "", fmt.Errorf("no logins for %s pages", parsed.Scheme)
+	}
+	if parsed.Hostname() == "" {
+		return "", "", fmt.Errorf("invalid page URL %q: missing host", rawURL)
+	}
+	return scheme + "://" + strings.ToLower(parsed.Host), strings.ToLower(parsed.Hostname()), nil
+}
+
+// withScheme makes an origin typed without a scheme an https one
+func withScheme(origin string) string {
+	if !strings.Contains(origin, "://") {
+		return "https://" + origin
+	}
+	return origin
+}
+
+// loginMatchesPage reports whether an entry is a login for the page origin
+// (as pageOrigin returns it): its URL has the same scheme, host and port,
+// a URL without a scheme counting as https. With subdomains, a login for a
+// parent domain matches as well (github.com covers gist.github.com)
+func loginMatchesPage(entry *models.Entry, origin string, subdomains bool) bool {
+	if entry.IsToken() {
+		return false
+	}
+	login, ok := webOrigin(withScheme(strings.TrimSpace(entry.URL)))
+	if !ok {
+		return false
+	}
+	page, ok := webOrigin(origin)
+	if !ok || login.scheme != page.scheme || login.port != page.port {
+		return false
+	}
+	return page.host == login.host || (subdomains && strings.HasSuffix(page.host, "."+login.host))
+}
+
+// originParts is an http(s) origin with the port made explicit
+type originParts struct {
+	scheme, host, port string
+}
+
+// webOrigin splits an http or https URL into its origin parts
+func webOrigin(rawURL string) (originParts, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return originParts{}, false
+	}
+	parts := originParts{
+		scheme: strings.ToLower(parsed.Scheme),
+		host:   strings.ToLower(parsed.Hostname()),
+		port:   parsed.Port(),
+	}
+	if parts.port == "" {
+		switch parts.scheme {
+		case "https":
+			parts.port = "443"
+		case "http":
+			parts.port = "80"
+		}
+	}
+	if parts.port == "" {
+		return originParts{}, false
+	}
+	return parts, true
+}
+
+func runBrowserHostInstall(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gpasswd: %w", err)
+	}
+
+	link := browserHostLink()
+	manifest, err := nativemsg.NewManifest(browserHostBrowser, link, browserHostExtensionID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(link), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(link), err)
+	}
+	os.Remove(link)
+	if err := os.Symlink(executable, link); err != nil {
+		return fmt.Errorf("failed to create %s: %w", link, err)
+	}
+
+	path, err := nativemsg.Install(browserHostBrowser, manifest)
+	if err != nil {
+		if errors.Is(err, nativemsg.ErrUnsupported) {
+			return fmt.Errorf("%w; register this manifest yourself:\n%s", err, manifestJSON(manifest))
+		}
+		return err
+	}
+
+	fmt.Printf("✅ %s can now start %s\n", browserHostBrowser, link)
+	fmt.Printf("   Wrote %s\n", path)
+	fmt.Println("   Each web origin is approved on first use, or with 'gpasswd browser-host approve ORIGIN'")
+	return nil
+}
+
+func runBrowserHostUninstall(cmd *cobra.Command, args []string) error {
+	removed, err := nativemsg.Uninstall(browserHostBrowser)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Printf("The browser host was not installed for %s\n", browserHostBrowser)
+		return nil
+	}
+	fmt.Printf("✅ %s can no longer start gpasswd\n", browserHostBrowser)
+	fmt.Printf("   Other browsers still use %s; remove it after uninstalling them all\n", browserHostLink())
+	return nil
+}
+
+func runBrowserHostApprove(cmd *cobra.Command, args []string) error {
+	origin, _, err := pageOrigin(withScheme(args[0]))
+	if err != nil {
+		return err
+	}
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := unlockIfPrivate(db, os.Stdout); err != nil {
+		return err
+	}
+
+	if err := db.ApproveBrowserOrigin(origin, ""); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Browser extensions may now fill and save logins for %s\n", origin)
+	return nil
+}
+
+func runBrowserHostApprovals(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := unlockIfPrivate(db, os.Stdout); err != nil {
+		return err
+	}
+
+	if browserHostRevoke != "" {
+		origin := withScheme(browserHostRevoke)
+		if normalized, _, err := pageOrigin(origin); err == nil {
+			origin = normalized
+		}
+		removed, err := db.RevokeBrowserOrigin(origin)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s is not approved", origin)
+		}
+		fmt.Printf("✅ Revoked %s\n", origin)
+		return nil
+	}
+
+	origins, err := db.BrowserOrigins()
+	if err != nil {
+		return err
+	}
+	if len(origins) == 0 {
+		fmt.Println("No approved web origins")
+		return nil
+	}
+
+	fmt.Printf("🌐 Approved web origins: %d\n\n", len(origins))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ORIGIN\tAPPROVED\tEXTENSION")
+	fmt.Fprintln(w, "------\t--------\t---------")
+	for _, approval := range origins {
+		extension := approval.Extension
+		if extension == "" {
+			extension = "(approved with gpasswd)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", approval.Origin, approval.ApprovedAt.Local().Format("2006-01-02 15:04"), extension)
+	}
+	w.Flush()
+	return nil
+}
+
+func runBrowserHostPair(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Pairing hands out the logins of every approved origin, so it takes
+	// the master password even when the key is cached
+	if len(args) == 1 {
+		code, err := nativemsg.NormalizePairingCode(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := unlockVault(db, os.Stdout); err != nil {
+			return err
+		}
+		pairing, err := db.ApproveBrowserPairing(code)
+		if err != nil {
+			return err
+		}
+		extension := pairing.Extension
+		if extension == "" {
+			extension = "unknown extension"
+		}
+		fmt.Printf("✅ Paired %s (key %s)\n", extension, pairing.KeyID)
+		fmt.Println("   It can now open sessions with the browser host; each web origin still needs approval")
+		return nil
+	}
+
+	if err := unlockIfPrivate(db, os.Stdout); err != nil {
+		return err
+	}
+	if browserHostUnpair != "" {
+		removed, err := db.RevokeBrowserPairing(browserHostUnpair)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("no extension key %s is paired", browserHostUnpair)
+		}
+		fmt.Printf("✅ Unpaired key %s\n", browserHostUnpair)
+		return nil
+	}
+
+	pairings, err := db.BrowserPairings()
+	if err != nil {
+		return err
+	}
+	if len(pairings) == 0 {
+		fmt.Println("No paired browser extensions")
+		return nil
+	}
+
+	fmt.Printf("🔑 Browser extensions: %d\n\n", len(pairings))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY ID\tSTATUS\tSINCE\tEXTENSION")
+	fmt.Fprintln(w, "------\t------\t-----\t---------")
+	for _, pairing := range pairings {
+		status, since := "paired", pairing.RequestedAt
+		if pairing.Pending() {
+			status = "pending, code " + pairing.Code
+		} else {
+			since = *pairing.ApprovedAt
+		}
+		extension := pairing.Extension
+		if extension == "" {
+			extension = "(unknown)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pairing.KeyID, status, since.Local().Format("2006-01-02 15:04"), extension)
+	}
+	w.Flush()
+	fmt.Println("\n💡 Approve a pending extension with 'gpasswd browser pair CODE' if it shows the same code")
+	return nil
+}
+
+// browserHostLink is where install links gpasswd-browser-host to gpasswd
+func browserHostLink() string {
+	return filepath.Join(config.GetConfigDir(), browserHostName)
+}
+
+// manifestJSON formats a manifest for manual registration
+func manifestJSON(manifest *nativemsg.Manifest) string {
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	return string(data)
+}
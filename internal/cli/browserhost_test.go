@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/nativemsg"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+func TestLoginMatchesPage(t *testing.T) {
+	tests := []struct {
+		entryURL   string
+		page       string
+		subdomains bool
+		want       bool
+	}{
+		{"https://github.com/login", "https://github.com", false, true},
+		{"github.com", "https://github.com", false, true},
+		{"GitHub.com", "https://github.com", false, true},
+		{"github.com", "http://github.com", false, false},
+		{"https://github.com", "http://github.com", false, false},
+		{"http://intranet.local", "http://intranet.local", false, true},
+		{"http://intranet.local", "https://intranet.local", false, false},
+		{"https://example.com:8443", "https://example.com:8443", false, true},
+		{"https://example.com:8443", "https://example.com", false, false},
+		{"https://example.com", "https://example.com:443", false, true},
+		{"github.com", "https://gist.github.com", false, false},
+		{"github.com", "https://gist.github.com", true, true},
+		{"me.github.io", "https://attacker.github.io", true, false},
+		{"github.com", "https://evilgithub.com", true, false},
+		{"ssh://github.com", "https://github.com", false, false},
+		{"", "https://github.com", false, false},
+	}
+	for _, tt := range tests {
+		entry := &models.Entry{URL: tt.entryURL}
+		if got := loginMatchesPage(entry, tt.page, tt.subdomains); got != tt.want {
+			t.Errorf("loginMatchesPage(%q, %q, %v) = %v, want %v", tt.entryURL, tt.page, tt.subdomains, got, tt.want)
+		}
+	}
+
+	token := &models.Entry{URL: "https://github.com", Type: models.EntryTypeToken}
+	if loginMatchesPage(token, "https://github.com", false) {
+		t.Error("a token matched as a login")
+	}
+}
+
+// extensionSession derives the extension's side of a session the way an
+// extension does with WebCrypto: HKDF-SHA256 over the transcript of both
+// X25519 secrets and the four public keys
+func extensionSession(t *testing.T, extension, ephemeral *ecdh.PrivateKey, hostKey, hostEphemeral string) (toHost, toExtension []byte) {
+	t.Helper()
+	host, err := nativemsg.ParsePublicKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostEph, err := nativemsg.ParsePublicKey(hostEphemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	static, err := extension.ECDH(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := ephemeral.ECDH(hostEph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte("gpasswd-browser-session-v1 session"), static, fresh,
+		extension.PublicKey().Bytes(), host.Bytes(), ephemeral.PublicKey().Bytes(), hostEph.Bytes()} {
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		h.Write(field)
+	}
+	keys := hkdf.New(sha256.New, h.Sum(nil), nil, []byte("gpasswd-browser-session-v1 keys"))
+	toHost, toExtension = make([]byte, 32), make([]byte, 32)
+	for _, key := range [][]byte{toHost, toExtension} {
+		if _, err := io.ReadFull(keys, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return toHost, toExtension
+}
+
+func TestBrowserHostPairing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	host := &browserHost{db: db, caller: "chrome-extension://test/", denied: map[string]bool{}}
+
+	extension, err := nativemsg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeral, err := nativemsg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := nativemsg.EncodeKey(extension.PublicKey())
+	hello := &browserRequest{Action: "hello", PublicKey: publicKey, Ephemeral: nativemsg.EncodeKey(ephemeral.PublicKey())}
+
+	// Nothing but ping, pair and hello works outside a session
+	if resp := host.handle(&browserRequest{Action: "query", URL: "https://github.com"}); resp.OK {
+		t.Error("an unsealed query was answered")
+	}
+	if resp := host.handle(hello); resp.OK || !strings.Contains(resp.Error, "not paired") {
+		t.Errorf("hello before pairing = %+v", resp)
+	}
+
+	pair := host.handle(&browserRequest{Action: "pair", PublicKey: publicKey})
+	if !pair.OK || pair.Code == "" || pair.Paired {
+		t.Fatalf("pair = %+v", pair)
+	}
+	hostKey, err := nativemsg.ParsePublicKey(pair.HostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := nativemsg.PairingCode(extension.PublicKey(), hostKey); pair.Code != want {
+		t.Errorf("pairing code = %s, the extension computes %s", pair.Code, want)
+	}
+	if resp := host.handle(hello); resp.OK {
+		t.Error("hello before the code was approved succeeded")
+	}
+	if _, err := db.ApproveBrowserPairing(pair.Code); err != nil {
+		t.Fatal(err)
+	}
+	if again := host.handle(&browserRequest{Action: "pair", PublicKey: publicKey}); !again.OK || !again.Paired || again.HostKey != pair.HostKey {
+		t.Errorf("pair after approval = %+v", again)
+	}
+
+	resp := host.handle(hello)
+	if !resp.OK {
+		t.Fatalf("hello = %+v", resp)
+	}
+	toHost, toExtension := extensionSession(t, extension, ephemeral, pair.HostKey, resp.Ephemeral)
+	seal := newTestAEAD(t, toHost)
+	open := newTestAEAD(t, toExtension)
+
+	for seq := range uint64(2) {
+		sealed := seal.seal(t, seq, `{"id": 7, "action": "ping"}`)
+		resp := host.handle(&browserRequest{Action: "sealed", Sealed: sealed})
+		if !resp.OK {
+			t.Fatalf("sealed ping = %+v", resp)
+		}
+		if reply := open.open(t, seq, resp.Sealed); !strings.Contains(reply, `"ok":true`) || !strings.Contains(reply, `"id":7`) {
+			t.Errorf("sealed reply = %s", reply)
+		}
+	}
+
+	// A replayed request fails and ends the session
+	replayed := seal.seal(t, 0, `{"action": "ping"}`)
+	if resp := host.handle(&browserRequest{Action: "sealed", Sealed: replayed}); resp.OK {
+		t.Error("a replayed request was answered")
+	}
+	if resp := host.handle(&browserRequest{Action: "sealed", Sealed: seal.seal(t, 2, `{"action": "ping"}`)}); resp.OK {
+		t.Error("the session outlived a failed message")
+	}
+
+	// Unpairing stops new sessions
+	if _, err := db.RevokeBrowserPairing(nativemsg.KeyID(extension.PublicKey())); err != nil {
+		t.Fatal(err)
+	}
+	if resp := host.handle(hello); resp.OK {
+		t.Error("hello after unpairing succeeded")
+	}
+}
+
+// testAEAD seals and opens with AES-256-GCM and counter nonces, as the
+// extension does
+type testAEAD struct {
+	aead cipher.AEAD
+}
+
+func newTestAEAD(t *testing.T, key []byte) testAEAD {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testAEAD{aead}
+}
+
+func (a testAEAD) nonce(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 4), seq)
+}
+
+func (a testAEAD) seal(t *testing.T, seq uint64, message string) string {
+	t.Helper()
+	return base64.StdEncoding.EncodeToString(a.aead.Seal(nil, a.nonce(seq), []byte(message), nil))
+}
+
+func (a testAEAD) open(t *testing.T, seq uint64, sealed string) string {
+	t.Helper()
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := a.aead.Open(nil, a.nonce(seq), ciphertext, nil)
+	if err != nil {
+		t.Fatalf("reply %d does not open: %v", seq, err)
+	}
+	return string(plaintext)
+}
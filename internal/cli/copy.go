@@ -3,38 +3,52 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/kitsnail/gpasswd/internal/clipboard"
-	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
 
 var copyCmd = &cobra.Command{
 	Use:   "copy <name>",
-	Short: "Copy a password to clipboard",
+	Short: "Copy a password or another field to clipboard",
 	Long: `Copy a password entry to the system clipboard.
 
+Use --field to copy something else: username, url, notes or totp (the
+current one-time code; HOTP counters advance as with 'gpasswd totp code').
+
 The password will be automatically cleared from the clipboard after a timeout
 (default: 30 seconds, configurable in config.yaml).
 
 The master password is required to decrypt the entry.
 
+Use --sequence to copy several fields one after another for a two-step
+login: each field stays on the clipboard until you press Enter or the step
+timeout expires, then the next field replaces it. The clipboard is cleared
+after the last field as usual.
+
 Examples:
   gpasswd copy github
-  gpasswd copy "Gmail Work"`,
+  gpasswd copy "Gmail Work"
+  gpasswd copy github --field username
+  gpasswd copy github --field totp
+  gpasswd copy github --sequence user,password`,
 	Aliases: []string{"cp"},
 	Args:    cobra.ExactArgs(1),
 	RunE:    runCopy,
 }
 
 var (
-	copyNoClear bool
-	copyTimeout int
+	copyNoClear  bool
+	copyTimeout  int
+	copyField    string
+	copySequence []string
 )
 
 func init() {
@@ -42,11 +56,24 @@ func init() {
 
 	copyCmd.Flags().BoolVar(&copyNoClear, "no-clear", false, "Don't auto-clear clipboard")
 	copyCmd.Flags().IntVarP(&copyTimeout, "timeout", "t", 0, "Clipboard clear timeout in seconds (0 = use config default)")
+	copyCmd.Flags().StringVar(&copyField, "field", "password", "Field to copy (password, username, url, notes, totp, ...)")
+	copyCmd.Flags().StringSliceVar(&copySequence, "sequence", nil, "Copy fields in order, e.g. user,password (Enter advances)")
+	copyCmd.MarkFlagsMutuallyExclusive("field", "sequence")
 }
 
 func runCopy(cmd *cobra.Command, args []string) error {
 	entryName := args[0]
 
+	// Validate the fields before unlocking
+	for _, field := range append([]string{copyField}, copySequence...) {
+		if isOTPField(field) {
+			continue
+		}
+		if _, err := entryField(&models.Entry{}, field); err != nil {
+			return err
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -71,60 +98,63 @@ func runCopy(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
-	// Prompt for master password
-	var masterPassword string
-	masterPrompt := &survey.Password{
-		Message: "Master password:",
-	}
-	if err := survey.AskOne(masterPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("master password prompt failed: %w", err)
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
 	}
 
-	// Get salt and params
-	salt, err := db.GetSalt()
+	// Get entry by name
+	entry, err := db.GetEntryByName(entryName, key)
 	if err != nil {
-		return fmt.Errorf("failed to get salt: %w", err)
+		return fmt.Errorf("failed to get entry: %w", err)
 	}
+	recordAccess(db, entry, "copy")
 
-	params, err := db.GetArgon2Params()
-	if err != nil {
-		return fmt.Errorf("failed to get Argon2 parameters: %w", err)
+	if err := output.ConfirmReveal(entry, "copy it to the clipboard"); err != nil {
+		return err
 	}
 
-	// Derive encryption key
-	fmt.Println("🔓 Unlocking vault...")
-	key, err := crypto.DeriveKey(masterPassword, salt, params)
-	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
+	if err := requireFreshUnlock(db, entry, os.Stdout); err != nil {
+		return err
+	}
+	if entry.IsHighSecurity() && copyNoClear {
+		return fmt.Errorf("--no-clear is not allowed for high-security entries")
 	}
 
-	// Get entry by name
-	entry, err := db.GetEntryByName(entryName, key)
+	timeout := clipboardTimeout(cfg, entry, copyTimeout)
+
+	// Walk through all but the last field of a sequence
+	last := copyField
+	if len(copySequence) > 0 {
+		if err := copyFieldSequence(db, entry, key, copySequence[:len(copySequence)-1], time.Duration(timeout)*time.Second); err != nil {
+			return err
+		}
+		last = copySequence[len(copySequence)-1]
+	}
+
+	value, err := copyValue(db, entry, key, last)
 	if err != nil {
-		return fmt.Errorf("failed to get entry: %w", err)
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(last)))
 	}
 
-	// Copy password to clipboard
-	if err := clipboard.Copy(entry.Password); err != nil {
+	// Copy final field to clipboard
+	if err := clipboard.Copy(value); err != nil {
 		return fmt.Errorf("failed to copy to clipboard: %w", err)
 	}
 
-	fmt.Printf("✅ Password for '%s' copied to clipboard\n", entry.Name)
+	fmt.Printf("✅ %s for '%s' copied to clipboard\n", fieldLabel(last), entry.Name)
 
 	// Auto-clear clipboard after timeout
 	if !copyNoClear {
-		timeout := copyTimeout
-		if timeout == 0 {
-			timeout = cfg.Clipboard.ClearTimeout
-			if timeout == 0 {
-				timeout = 30 // Default 30 seconds
-			}
-		}
 
 		fmt.Printf("⏱️  Clipboard will be cleared in %d seconds\n", timeout)
 		fmt.Println("   (Press Ctrl+C to cancel and keep in clipboard)")
 
-		done, err := clipboard.CopyWithAutoClear(entry.Password, time.Duration(timeout)*time.Second)
+		done, err := clipboard.CopyWithAutoClear(value, time.Duration(timeout)*time.Second)
 		if err != nil {
 			return fmt.Errorf("failed to setup auto-clear: %w", err)
 		}
@@ -138,3 +168,99 @@ func runCopy(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// entryField returns the value of a copyable entry field
+func entryField(entry *models.Entry, field string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(field)) {
+	case "user", "username", "login":
+		return entry.Username, nil
+	case "password", "pass", "token":
+		return entry.Password, nil
+	case "url":
+		return entry.URL, nil
+	case "notes", "note":
+		return entry.Notes, nil
+	case "service":
+		return entry.Service, nil
+	case "scopes":
+		return strings.Join(entry.Scopes, ","), nil
+	case "expires":
+		if entry.ExpiresAt == nil {
+			return "", nil
+		}
+		return entry.ExpiresAt.Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("unknown field %q (expected user, password, token, url, notes, service, scopes or expires)", field)
+	}
+}
+
+// fieldLabel returns a display label for a copyable field
+func fieldLabel(field string) string {
+	switch strings.ToLower(strings.TrimSpace(field)) {
+	case "user", "username", "login":
+		return "Username"
+	case "url":
+		return "URL"
+	case "notes", "note":
+		return "Notes"
+	case "totp", "otp", "code":
+		return "One-time code"
+	case "token":
+		return "Token"
+	case "service":
+		return "Service"
+	case "scopes":
+		return "Scopes"
+	case "expires":
+		return "Expiry"
+	default:
+		return "Password"
+	}
+}
+
+// isOTPField reports whether field names the entry's one-time code
+func isOTPField(field string) bool {
+	switch strings.ToLower(strings.TrimSpace(field)) {
+	case "totp", "otp", "code":
+		return true
+	}
+	return false
+}
+
+// copyValue returns the value of a field for copy, generating the one-time
+// code for totp (which advances HOTP counters, see nextOTPCode)
+func copyValue(db *storage.DB, entry *models.Entry, key []byte, field string) (string, error) {
+	if !isOTPField(field) {
+		return entryField(entry, field)
+	}
+	code, _, err := nextOTPCode(db, entry, key, time.Now())
+	return code, err
+}
+
+// copyFieldSequence copies each field in turn, advancing when the user
+// presses Enter or the step timeout expires
+func copyFieldSequence(db *storage.DB, entry *models.Entry, key []byte, fields []string, step time.Duration) error {
+	lines := stdinLines()
+
+	for i, field := range fields {
+		value, err := copyValue(db, entry, key, field)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			fmt.Printf("⏭️  %s is empty, skipping\n", fieldLabel(field))
+			continue
+		}
+
+		if err := clipboard.Copy(value); err != nil {
+			return fmt.Errorf("failed to copy to clipboard: %w", err)
+		}
+
+		fmt.Printf("📋 [%d/%d] %s copied. Paste it, then press Enter (auto-advance in %s)\n",
+			i+1, len(fields)+1, fieldLabel(field), step)
+
+		waitForEnter(lines, step)
+	}
+
+	return nil
+}
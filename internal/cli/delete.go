@@ -20,7 +20,8 @@ var deleteCmd = &cobra.Command{
 This operation requires confirmation (unless --force is used).
 The entry will be permanently removed from the database.
 
-Note: Master password is NOT required for deletion (only metadata is accessed).
+The master password is required so the vault's signed manifest can be
+updated; otherwise the deletion would look like tampering at the next unlock.
 
 Examples:
   gpasswd delete github
@@ -103,6 +104,11 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("entry not found: %s", entryName)
 	}
 
+	// Unlock so the deletion can reseal the vault manifest
+	if _, err := unlockVault(db, os.Stdout); err != nil {
+		return err
+	}
+
 	// Display entry details
 	fmt.Println("\n" + strings.Repeat("─", 60))
 	fmt.Printf("🗑️  Entry to delete: %s\n", targetEntry.Name)
@@ -124,7 +130,7 @@ func runDelete(cmd *cobra.Command, args []string) error {
 			Default: false,
 		}
 
-		if err := survey.AskOne(confirmPrompt, &confirmed); err != nil {
+		if err := askOne(confirmPrompt, &confirmed); err != nil {
 			return fmt.Errorf("confirmation prompt failed: %w", err)
 		}
 
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package cli
+
+import "os/exec"
+
+// detach does nothing on platforms without process sessions
+func detach(cmd *exec.Cmd) {}
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/autotype"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var dmenuTypeCmd = &cobra.Command{
+	Use:   "dmenu-type",
+	Short: "Pick an entry with dmenu or bemenu and type its password",
+	Long: `Show the entry names in dmenu (X11) or bemenu (Wayland) and type the
+password of the picked entry into the focused window with wtype, xdotool
+or ydotool. Bound to a key in i3 or sway, it fills a login form without
+touching the mouse or the clipboard.
+
+--login types the username, Tab and the password; --enter presses Enter
+afterwards. --menu picks another menu program; it must read the choices on
+stdin and print the selection, like 'wofi --dmenu' or 'rofi -dmenu'.
+
+The master password comes from 'gpasswd agent', a 'gpasswd unlock'
+session or the GPASSWD_ASKPASS program, since a key binding has no
+terminal to ask on.
+
+Examples:
+  bindsym $mod+p exec gpasswd dmenu-type
+  bindsym $mod+Shift+p exec gpasswd dmenu-type --login --enter
+  gpasswd dmenu-type --menu "wofi --dmenu" --field user`,
+	Args: cobra.NoArgs,
+	RunE: runDmenuType,
+}
+
+var (
+	dmenuMenu  string
+	dmenuField string
+	dmenuLogin bool
+	dmenuEnter bool
+)
+
+func init() {
+	rootCmd.AddCommand(dmenuTypeCmd)
+
+	dmenuTypeCmd.Flags().StringVar(&dmenuMenu, "menu", "", "Menu command (default: bemenu under Wayland, dmenu under X11)")
+	dmenuTypeCmd.Flags().StringVar(&dmenuField, "field", "password", "Field to type (user, password, url, ...)")
+	dmenuTypeCmd.Flags().BoolVar(&dmenuLogin, "login", false, "Type the username, Tab, then the password")
+	dmenuTypeCmd.Flags().BoolVar(&dmenuEnter, "enter", false, "Press Enter after typing")
+}
+
+func runDmenuType(cmd *cobra.Command, args []string) error {
+	if dmenuLogin && cmd.Flags().Changed("field") {
+		return fmt.Errorf("--login and --field cannot be combined")
+	}
+	if _, err := entryField(&models.Entry{}, dmenuField); err != nil {
+		return err
+	}
+	if _, err := autotype.Tool(); err != nil {
+		return err
+	}
+	menu, err := dmenuCommand(dmenuMenu)
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	entries, err := db.ListEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list entries: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.ReplaceAll(entry.Name, "\n", " "))
+	}
+
+	name, err := runDmenu(menu, names)
+	if err != nil || name == "" {
+		// Nothing picked (Escape) is not an error
+		return err
+	}
+
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(name, key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "dmenu-type")
+
+	if err := output.ConfirmReveal(entry, "type it"); err != nil {
+		return err
+	}
+	if err := requireFreshUnlock(db, entry, os.Stderr); err != nil {
+		return err
+	}
+
+	text, err := dmenuText(entry)
+	if err != nil {
+		return err
+	}
+
+	// Let the menu close and focus return to the form
+	time.Sleep(menuTypeDelay)
+	return autotype.Type(text)
+}
+
+// dmenuCommand splits a --menu value into a command, or picks the menu
+// program for this session
+func dmenuCommand(menu string) ([]string, error) {
+	if fields := strings.Fields(menu); len(fields) > 0 {
+		return fields, nil
+	}
+
+	var candidates [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, []string{"bemenu", "-p", "gpasswd"}, []string{"wofi", "--dmenu", "--prompt", "gpasswd"})
+	}
+	candidates = append(candidates, []string{"dmenu", "-i", "-p", "gpasswd"}, []string{"bemenu", "-p", "gpasswd"})
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			return candidate, nil
+		}
+	}
+	return nil, errors.New("no menu program found; install dmenu (X11) or bemenu (Wayland), or pass --menu")
+}
+
+// runDmenu offers choices in the menu and returns the selection, or ""
+// when the menu was dismissed
+func runDmenu(menu []string, choices []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(menu[0], menu[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(choices, "\n") + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	selection := strings.TrimRight(stdout.String(), "\r\n")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && selection == "" {
+		// dmenu and bemenu exit non-zero when dismissed
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v %s", menu[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return selection, nil
+}
+
+// dmenuText builds the keystrokes to type for entry
+func dmenuText(entry *models.Entry) (string, error) {
+	var text string
+	if dmenuLogin {
+		if entry.Username == "" || entry.Password == "" {
+			return "", fmt.Errorf("entry '%s' needs a username and a password for --login", entry.Name)
+		}
+		text = entry.Username + "\t" + entry.Password
+	} else {
+		value, _ := entryField(entry, dmenuField)
+		if value == "" {
+			return "", fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(dmenuField)))
+		}
+		text = value
+	}
+
+	if dmenuEnter {
+		text += "\n"
+	}
+	return text, nil
+}
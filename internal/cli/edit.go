@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/loginsteps"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
@@ -22,27 +24,50 @@ You can update any field: username, password, URL, notes, category, or tags.
 Fields not specified will remain unchanged.
 
 The master password is required to decrypt and re-encrypt the entry.
+New passwords must meet the configured strength policy unless --allow-weak
+is given.
 
 Examples:
   gpasswd edit github
   gpasswd edit github --username newuser@example.com
   gpasswd edit github --password newpass123
-  gpasswd edit github --generate`,
+  gpasswd edit github --generate
+  gpasswd edit github --change-url https://github.com/settings/security
+  gpasswd edit github-ci --password <new-token> --expires 90d
+  gpasswd edit bank --security high
+  gpasswd edit deploy-bot --listable-username
+  gpasswd edit corp-sso --login-steps "open,user,say Click Next,password,otp"
+  gpasswd edit deploy-bot --listable-username=false
+  gpasswd edit bank --history-versions 50 --history-days 0
+  gpasswd edit bank --history-default`,
 	Aliases: []string{"update", "modify"},
 	Args:    cobra.ExactArgs(1),
 	RunE:    runEdit,
 }
 
 var (
-	editUsername string
-	editPassword string
-	editURL      string
-	editNotes    string
-	editCategory string
-	editTags     []string
-	editGenerate bool
-	editGenLen   int
-	editSetTags  bool
+	editUsername  string
+	editPassword  string
+	editURL       string
+	editNotes     string
+	editCategory  string
+	editTags      []string
+	editGenerate  bool
+	editGenLen    int
+	editSetTags   bool
+	editAllowWeak bool
+	editChangeURL string
+	editIcon      string
+	editService   string
+	editExpires   string
+	editScopes    string
+	editSecurity  string
+	editListable  bool
+	editSteps     []string
+
+	editHistoryVersions int
+	editHistoryDays     int
+	editHistoryDefault  bool
 )
 
 func init() {
@@ -57,6 +82,20 @@ func init() {
 	editCmd.Flags().BoolVarP(&editGenerate, "generate", "g", false, "Generate new password")
 	editCmd.Flags().IntVar(&editGenLen, "gen-length", 20, "Length of generated password")
 	editCmd.Flags().BoolVar(&editSetTags, "set-tags", false, "Replace tags (otherwise keep existing)")
+	editCmd.Flags().BoolVar(&editAllowWeak, "allow-weak", false, "Store a password that fails the strength policy")
+	editCmd.Flags().StringVar(&editChangeURL, "change-url", "", "Change-password page used by 'gpasswd rotate --open'")
+	editCmd.Flags().StringVar(&editIcon, "icon", "", "Emoji shown with the entry (\"\" to remove)")
+	editCmd.Flags().StringVar(&editService, "service", "", "Issuing service of a token")
+	editCmd.Flags().StringVar(&editExpires, "expires", "", "Expiry: YYYY-MM-DD, e.g. 90d, or never")
+	editCmd.Flags().StringVar(&editScopes, "scopes", "", "Comma-separated token scopes")
+	editCmd.Flags().StringVar(&editSecurity, "security", "", "Security level: normal or high")
+	editCmd.Flags().StringSliceVar(&editSteps, "login-steps", nil, "Steps for 'gpasswd login' (\"\" to remove)")
+	editCmd.Flags().BoolVar(&editListable, "listable-username", false, "Store the username unencrypted for 'gpasswd user' (=false to stop)")
+	editCmd.Flags().IntVar(&editHistoryVersions, "history-versions", 0, "Archived versions kept of this entry, instead of the vault's retention (0 = no limit)")
+	editCmd.Flags().IntVar(&editHistoryDays, "history-days", 0, "Days archived versions of this entry are kept, instead of the vault's retention (0 = no limit)")
+	editCmd.Flags().BoolVar(&editHistoryDefault, "history-default", false, "Use the vault's history retention again")
+	editCmd.MarkFlagsMutuallyExclusive("history-default", "history-versions")
+	editCmd.MarkFlagsMutuallyExclusive("history-default", "history-days")
 }
 
 func runEdit(cmd *cobra.Command, args []string) error {
@@ -68,6 +107,26 @@ func runEdit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("expires") {
+		if _, err := parseExpiry(editExpires, time.Now()); err != nil {
+			return err
+		}
+	}
+	securityLevel, err := parseSecurityLevel(editSecurity)
+	if err != nil {
+		return err
+	}
+	if _, err := loginsteps.ParseAll(editSteps); err != nil {
+		return err
+	}
+	if editHistoryVersions < 0 || editHistoryDays < 0 {
+		return fmt.Errorf("--history-versions and --history-days cannot be negative")
+	}
+
 	// Determine database path
 	dbPath := cfg.Database.Path
 	if dbPath == "" {
@@ -86,31 +145,10 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
-	// Prompt for master password
-	var masterPassword string
-	masterPrompt := &survey.Password{
-		Message: "Master password:",
-	}
-	if err := survey.AskOne(masterPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("master password prompt failed: %w", err)
-	}
-
-	// Get salt and params
-	salt, err := db.GetSalt()
-	if err != nil {
-		return fmt.Errorf("failed to get salt: %w", err)
-	}
-
-	params, err := db.GetArgon2Params()
-	if err != nil {
-		return fmt.Errorf("failed to get Argon2 parameters: %w", err)
-	}
-
-	// Derive encryption key
-	fmt.Println("🔓 Unlocking vault...")
-	key, err := crypto.DeriveKey(masterPassword, salt, params)
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
+		return err
 	}
 
 	// Get existing entry
@@ -119,8 +157,26 @@ func runEdit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get entry: %w", err)
 	}
 
+	if err := requireFreshUnlock(db, entry, os.Stdout); err != nil {
+		return err
+	}
+
 	fmt.Printf("\n📝 Editing entry: %s\n", entry.Name)
 
+	if entry.IsKey() && (editGenerate || cmd.Flags().Changed("password")) {
+		return fmt.Errorf("'%s' holds a private key; store a new one with 'gpasswd key add'", entry.Name)
+	}
+
+	// Token values are issued by their service and exempt from the policy
+	if cmd.Flags().Changed("password") && editPassword != "" && !editGenerate && !entry.IsToken() {
+		if err := enforcePolicy(policy, editPassword, editAllowWeak); err != nil {
+			return err
+		}
+	}
+	if entry.IsToken() && editGenerate {
+		return fmt.Errorf("--generate cannot be used on a token; store the value issued by %s with --password", entry.Service)
+	}
+
 	// Check if any flags provided
 	hasFlags := cmd.Flags().Changed("username") ||
 		cmd.Flags().Changed("password") ||
@@ -128,6 +184,17 @@ func runEdit(cmd *cobra.Command, args []string) error {
 		cmd.Flags().Changed("notes") ||
 		cmd.Flags().Changed("category") ||
 		cmd.Flags().Changed("tags") ||
+		cmd.Flags().Changed("change-url") ||
+		cmd.Flags().Changed("icon") ||
+		cmd.Flags().Changed("service") ||
+		cmd.Flags().Changed("expires") ||
+		cmd.Flags().Changed("scopes") ||
+		cmd.Flags().Changed("security") ||
+		cmd.Flags().Changed("listable-username") ||
+		cmd.Flags().Changed("login-steps") ||
+		cmd.Flags().Changed("history-versions") ||
+		cmd.Flags().Changed("history-days") ||
+		editHistoryDefault ||
 		editGenerate
 
 	if hasFlags {
@@ -175,9 +242,64 @@ func runEdit(cmd *cobra.Command, args []string) error {
 		if editSetTags || cmd.Flags().Changed("tags") {
 			entry.Tags = editTags
 		}
+
+		if cmd.Flags().Changed("change-url") {
+			entry.ChangePasswordURL = editChangeURL
+		}
+
+		if cmd.Flags().Changed("icon") {
+			entry.Icon = editIcon
+		}
+
+		if cmd.Flags().Changed("service") {
+			entry.Service = editService
+		}
+
+		if cmd.Flags().Changed("expires") {
+			entry.ExpiresAt, _ = parseExpiry(editExpires, time.Now())
+		}
+
+		if cmd.Flags().Changed("scopes") {
+			entry.Scopes = parseScopes(editScopes)
+		}
+
+		if cmd.Flags().Changed("security") {
+			entry.SecurityLevel = securityLevel
+		}
+
+		if cmd.Flags().Changed("listable-username") {
+			entry.ListableUsername = editListable
+		}
+
+		if cmd.Flags().Changed("login-steps") {
+			entry.LoginSteps = editSteps
+		}
+
+		if editHistoryDefault {
+			entry.HistoryRetention = nil
+		} else if cmd.Flags().Changed("history-versions") || cmd.Flags().Changed("history-days") {
+			// Start from the vault's retention when the entry has none yet
+			retention, err := db.HistoryRetention()
+			if err != nil {
+				return err
+			}
+			if entry.HistoryRetention != nil {
+				retention = *entry.HistoryRetention
+			}
+			if cmd.Flags().Changed("history-versions") {
+				retention.Versions = editHistoryVersions
+			}
+			if cmd.Flags().Changed("history-days") {
+				retention.Days = editHistoryDays
+			}
+			entry.HistoryRetention = &retention
+		}
+		if entry.ListableUsername && entry.IsHighSecurity() {
+			fmt.Println("⚠️  High-security entries never store a listable username; 'gpasswd user' won't find it")
+		}
 	} else {
 		// Interactive editing
-		fmt.Println("\nLeave blank to keep current value.\n")
+		fmt.Print("\nLeave blank to keep current value.\n\n")
 
 		// Username
 		var newUsername string
@@ -185,56 +307,62 @@ func runEdit(cmd *cobra.Command, args []string) error {
 			Message: "Username:",
 			Default: entry.Username,
 		}
-		if err := survey.AskOne(usernamePrompt, &newUsername); err == nil && newUsername != "" {
+		if err := askOne(usernamePrompt, &newUsername); err == nil && newUsername != "" {
 			entry.Username = newUsername
 		}
 
-		// Password choice
-		var passwordChoice string
-		passwordPrompt := &survey.Select{
-			Message: "Password:",
-			Options: []string{
-				"Keep current password",
-				"Generate new password",
-				"Enter new password manually",
-			},
-		}
-		if err := survey.AskOne(passwordPrompt, &passwordChoice); err != nil {
-			return fmt.Errorf("password choice failed: %w", err)
-		}
-
-		if strings.HasPrefix(passwordChoice, "Generate") {
-			genOptions := crypto.GenerateOptions{
-				UseUppercase:     true,
-				UseLowercase:     true,
-				UseDigits:        true,
-				UseSymbols:       true,
-				ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+		if entry.IsToken() {
+			if err := editTokenFields(entry); err != nil {
+				return err
 			}
-
-			generated, err := crypto.Generate(20, genOptions)
-			if err != nil {
-				return fmt.Errorf("failed to generate password: %w", err)
+		} else if !entry.IsNote() {
+			// Password choice
+			var passwordChoice string
+			passwordPrompt := &survey.Select{
+				Message: "Password:",
+				Options: []string{
+					"Keep current password",
+					"Generate new password",
+					"Enter new password manually",
+				},
+			}
+			if err := askOne(passwordPrompt, &passwordChoice); err != nil {
+				return fmt.Errorf("password choice failed: %w", err)
 			}
 
-			entry.Password = generated
-			fmt.Printf("✓ Generated new password: %s\n", generated)
+			if strings.HasPrefix(passwordChoice, "Generate") {
+				genOptions := crypto.GenerateOptions{
+					UseUppercase:     true,
+					UseLowercase:     true,
+					UseDigits:        true,
+					UseSymbols:       true,
+					ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+				}
 
-			strength := crypto.CheckStrength(generated)
-			fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
-		} else if strings.HasPrefix(passwordChoice, "Enter") {
-			var newPassword string
-			newPassPrompt := &survey.Password{
-				Message: "New password:",
-			}
-			if err := survey.AskOne(newPassPrompt, &newPassword, survey.WithValidator(survey.Required)); err != nil {
-				return fmt.Errorf("password prompt failed: %w", err)
-			}
+				generated, err := crypto.Generate(20, genOptions)
+				if err != nil {
+					return fmt.Errorf("failed to generate password: %w", err)
+				}
 
-			entry.Password = newPassword
+				entry.Password = generated
+				fmt.Printf("✓ Generated new password: %s\n", generated)
 
-			strength := crypto.CheckStrength(newPassword)
-			fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+				strength := crypto.CheckStrength(generated)
+				fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+			} else if strings.HasPrefix(passwordChoice, "Enter") {
+				var newPassword string
+				newPassPrompt := &survey.Password{
+					Message: "New password:",
+				}
+				if err := askOne(newPassPrompt, &newPassword, survey.WithValidator(survey.Required), survey.WithValidator(policyValidator(policy, editAllowWeak))); err != nil {
+					return fmt.Errorf("password prompt failed: %w", err)
+				}
+
+				entry.Password = newPassword
+
+				strength := crypto.CheckStrength(newPassword)
+				fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+			}
 		}
 
 		// URL
@@ -243,35 +371,24 @@ func runEdit(cmd *cobra.Command, args []string) error {
 			Message: "URL:",
 			Default: entry.URL,
 		}
-		if err := survey.AskOne(urlPrompt, &newURL); err == nil && newURL != "" {
+		if err := askOne(urlPrompt, &newURL); err == nil && newURL != "" {
 			entry.URL = newURL
 		}
 
-		// Category
-		var newCategory string
-		categoryPrompt := &survey.Input{
-			Message: "Category:",
-			Default: entry.Category,
+		// Category and tags are picked from those already in use
+		picks, err := loadTaxonomy(db, key)
+		if err != nil {
+			return err
 		}
-		if err := survey.AskOne(categoryPrompt, &newCategory); err == nil && newCategory != "" {
+
+		// Category
+		if newCategory, err := picks.pickCategory("Category:", entry.Category); err == nil && newCategory != "" {
 			entry.Category = newCategory
 		}
 
 		// Tags
-		var tagsInput string
-		currentTags := strings.Join(entry.Tags, ", ")
-		tagsPrompt := &survey.Input{
-			Message: "Tags (comma-separated):",
-			Default: currentTags,
-		}
-		if err := survey.AskOne(tagsPrompt, &tagsInput); err == nil && tagsInput != "" {
-			entry.Tags = []string{}
-			for _, tag := range strings.Split(tagsInput, ",") {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					entry.Tags = append(entry.Tags, trimmed)
-				}
-			}
+		if newTags, err := picks.pickTags("Tags:", entry.Tags); err == nil {
+			entry.Tags = newTags
 		}
 
 		// Notes
@@ -280,7 +397,7 @@ func runEdit(cmd *cobra.Command, args []string) error {
 			Message: "Notes (Ctrl+D when done):",
 			Default: entry.Notes,
 		}
-		if err := survey.AskOne(notesPrompt, &newNotes); err == nil && newNotes != "" {
+		if err := askOne(notesPrompt, &newNotes); err == nil && newNotes != "" {
 			entry.Notes = newNotes
 		}
 	}
@@ -300,6 +417,7 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	if entry.URL != "" {
 		fmt.Printf("   URL: %s\n", entry.URL)
 	}
+	warnSizeLimits(db, cfg, entry, os.Stdout)
 
 	return nil
 }
@@ -0,0 +1,472 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/export"
+	"github.com/kitsnail/gpasswd/internal/filter"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export entries to another password manager",
+	Long: `Export vault entries in a format another password manager can import.
+
+Supported formats:
+  chrome-csv    Chrome / Chromium "Import passwords" CSV
+  firefox-csv   Firefox "Import from a File" CSV
+  json          Every field of every entry as JSON
+  csv           Every field of every entry as CSV
+  gpx           Encrypted archive (.gpx)
+  pass          pass(1) password store, one GPG-encrypted file per entry
+
+⚠️  All formats but gpx and pass are PLAINTEXT. Anyone who can read the exported
+file can read every password in it, and json and csv also carry notes and
+2FA secrets. Delete the file as soon as the import is done.
+
+The browser formats only hold logins with a password and a web URL; notes,
+keys, tokens and logins without a password or URL are skipped because
+browsers cannot import them. URLs without a scheme get https://. Use
+--category, --match or --glob to export a subset of the vault.
+
+A gpx archive holds every field of the exported entries plus the vault's
+metadata and key derivation parameters, encrypted with AES-256-GCM under
+the vault key. It opens with the master password the vault had at export
+time, so store it like a vault backup.
+
+pass writes a password store into the --out directory, laid out the way
+'gpasswd import pass' reads it back: the category is the directory, and
+each file holds the password, then login:, url:, tags:, the otpauth://
+URI (for pass-otp) and the notes. Files are encrypted with gpg for the
+keys in the directory's .gpg-id, or for --gpg-id (which also writes
+.gpg-id when the directory has none).
+
+--viewer instead writes an encrypted, read-only bundle (.gpb) protected by
+its own passphrase, for someone without access to your vault, e.g. a
+family member. They open it with 'gpasswd view bundle.gpb' on their own
+machine; no vault or master password is needed. The bundle stops opening
+after --expires (default 30d; the expiry relies on the viewer's clock).
+A text file with opening instructions is written next to the bundle.
+The passphrase must reach policy.export_min_score (default 60, "Strong");
+--force accepts a weaker one.
+
+--metadata writes the vault's salt, key derivation parameters and key
+verifier (no entries) so 'gpasswd init --from-metadata' can recreate a vault
+that opens with the same master password. Keep it like a vault backup: the
+verifier allows offline guessing of the master password.
+
+--qr-stream shows the vault as an endless loop of QR codes for a phone or
+another machine's camera to scan. It is the vault file itself: entries stay
+encrypted with the master password, and plaintext metadata follows the
+vault's privacy level. Frames after the first pass carry fountain-coded
+redundancy, so a scanner that misses frames only needs to keep watching.
+
+Examples:
+  gpasswd export --format chrome-csv -o passwords.csv
+  gpasswd export --format firefox-csv > logins.csv
+  gpasswd export --format gpx -o vault.gpx
+  gpasswd export --format pass --gpg-id me@example.com -o ~/.password-store
+  gpasswd export --format chrome-csv --glob 'work-*' -o work.csv
+  gpasswd export --viewer --category family -o family.gpb --expires 90d
+  gpasswd export --metadata -o vault-metadata.json
+  gpasswd export --qr-stream --qr-fps 8`,
+	RunE: runExport,
+}
+
+var (
+	exportFormat   string
+	exportOut      string
+	exportForce    bool
+	exportCategory string
+	exportMatch    string
+	exportGlob     string
+	exportViewer   bool
+	exportExpires  string
+	exportMetadata bool
+	exportQRStream bool
+	exportQRFPS    int
+	exportGPGIDs   []string
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "Export format (chrome-csv, firefox-csv, json, csv, gpx, pass)")
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Output file, or directory for --format pass (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportForce, "force", false, "Skip the plaintext export confirmation, or accept a weak --viewer passphrase")
+	exportCmd.Flags().StringVarP(&exportCategory, "category", "c", "", "Only export entries in this category")
+	exportCmd.Flags().StringVar(&exportMatch, "match", "", "Only export names/categories matching a regular expression")
+	exportCmd.Flags().StringVar(&exportGlob, "glob", "", "Only export names/categories matching a glob pattern")
+	exportCmd.Flags().BoolVar(&exportViewer, "viewer", false, "Write an encrypted read-only bundle for 'gpasswd view'")
+	exportCmd.Flags().BoolVar(&exportMetadata, "metadata", false, "Write the vault's key derivation metadata for 'gpasswd init --from-metadata'")
+	exportCmd.Flags().BoolVar(&exportQRStream, "qr-stream", false, "Show the encrypted vault as an animated QR code stream")
+	exportCmd.Flags().IntVar(&exportQRFPS, "qr-fps", 5, "With --qr-stream, frames per second")
+	exportCmd.Flags().StringSliceVar(&exportGPGIDs, "gpg-id", nil, "With --format pass, GPG key to encrypt for (repeatable; default: the store's .gpg-id)")
+	exportCmd.Flags().StringVar(&exportExpires, "expires", "30d", "With --viewer, when the bundle stops opening (YYYY-MM-DD, e.g. 90d, or never)")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	if exportQRStream {
+		if exportMetadata || exportViewer || exportFormat != "" {
+			return fmt.Errorf("--qr-stream cannot be combined with --metadata, --viewer or --format")
+		}
+		return runQRStreamExport()
+	}
+	if exportMetadata {
+		if exportViewer || exportFormat != "" {
+			return fmt.Errorf("--metadata cannot be combined with --viewer or --format")
+		}
+		return runMetadataExport()
+	}
+	if exportViewer {
+		if exportFormat != "" {
+			return fmt.Errorf("--viewer and --format cannot be combined")
+		}
+		return runViewerExport()
+	}
+	if exportFormat == "" {
+		return fmt.Errorf("required flag \"format\" not set (or use --viewer)")
+	}
+
+	format, err := export.ParseFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+	if len(exportGPGIDs) > 0 && format != export.FormatPass {
+		return fmt.Errorf("--gpg-id applies to --format pass")
+	}
+	var recipients []string
+	if format == export.FormatPass {
+		if recipients, err = passRecipients(); err != nil {
+			return err
+		}
+	}
+
+	entryFilter, err := filter.New(filter.Options{
+		Category: exportCategory,
+		Match:    exportMatch,
+		Glob:     exportGlob,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Prompts and status go to stderr so stdout can carry the export itself
+	stdio := survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)
+
+	plaintext := !format.Encrypted()
+	if plaintext {
+		fmt.Fprintf(os.Stderr, "⚠️  Format %s is PLAINTEXT: exported passwords are NOT encrypted\n", format)
+		if format.Complete() {
+			fmt.Fprintln(os.Stderr, "⚠️  It also contains notes and 2FA secrets, so it defeats two-factor logins too")
+		}
+	}
+	if plaintext && !exportForce {
+		var confirmed bool
+		confirmPrompt := &survey.Confirm{
+			Message: "Export passwords in plaintext?",
+			Default: false,
+		}
+		if err := askOne(confirmPrompt, &confirmed, stdio); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Fprintln(os.Stderr, "❌ Export cancelled")
+			return nil
+		}
+	}
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stderr, stdio)
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	entries = entryFilter.Apply(entries)
+
+	// Drop entries the target format cannot represent
+	selected := make([]*models.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if reason := format.Unsupported(entry); reason != "" {
+			fmt.Fprintf(os.Stderr, "   • Skipping '%s' (%s)\n", entry.Name, reason)
+			continue
+		}
+		selected = append(selected, entry)
+	}
+
+	if format == export.FormatPass {
+		fmt.Fprintf(os.Stderr, "🔑 Encrypting %d entries with gpg...\n", len(selected))
+		written, err := export.WritePassStore(exportOut, recipients, selected, export.GPGEncrypt)
+		if err != nil {
+			return fmt.Errorf("%w (%d files written)", err, written)
+		}
+		fmt.Fprintf(os.Stderr, "✅ Exported %d of %d entries to the password store %s\n", written, len(entries), exportOut)
+		fmt.Fprintf(os.Stderr, "   Encrypted for: %s\n", strings.Join(recipients, ", "))
+		return nil
+	}
+
+	write := func(out io.Writer) error {
+		if err := export.Write(out, format, selected); err != nil {
+			return fmt.Errorf("failed to export entries: %w", err)
+		}
+		return nil
+	}
+	if format == export.FormatArchive {
+		data, err := sealArchive(db, key, selected)
+		if err != nil {
+			return err
+		}
+		write = func(out io.Writer) error {
+			_, err := out.Write(append(data, '\n'))
+			return err
+		}
+	}
+	if exportOut == "" {
+		err = write(os.Stdout)
+	} else {
+		err = atomicfile.Write(exportOut, 0600, write)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Exported %d of %d entries as %s\n", len(selected), len(entries), format)
+	if exportOut != "" {
+		fmt.Fprintf(os.Stderr, "   File: %s\n", exportOut)
+		if plaintext {
+			fmt.Fprintln(os.Stderr, "⚠️  Delete this file after importing it")
+		} else {
+			fmt.Fprintln(os.Stderr, "   It opens with the current master password; keep it like a vault backup")
+		}
+	}
+
+	return nil
+}
+
+// passRecipients returns the GPG keys a pass export encrypts for: --gpg-id,
+// or the .gpg-id of the existing store in --out
+func passRecipients() ([]string, error) {
+	if exportOut == "" {
+		return nil, fmt.Errorf("--format pass needs --out with the password store directory")
+	}
+	if info, err := os.Stat(exportOut); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", exportOut)
+	}
+	if len(exportGPGIDs) > 0 {
+		return exportGPGIDs, nil
+	}
+
+	recipients, err := export.PassRecipients(exportOut)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%s has no %s; pass --gpg-id with the key to encrypt for", exportOut, export.PassIDFile)
+	}
+	return recipients, nil
+}
+
+// sealArchive encrypts entries and the vault metadata into a .gpx archive
+func sealArchive(db *storage.DB, key []byte, entries []*models.Entry) ([]byte, error) {
+	metadata, err := db.ExportMetadata()
+	if err != nil {
+		return nil, err
+	}
+	archive := &export.Archive{
+		CreatedAt: time.Now().UTC(),
+		Metadata:  metadata,
+		Entries:   entries,
+	}
+	data, err := export.SealArchive(archive, key)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// runMetadataExport writes the key derivation metadata as JSON
+// No unlock is needed: nothing in it is encrypted with the vault key
+func runMetadataExport() error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	metadata, err := db.ExportMetadata()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	data = append(data, '\n')
+
+	if exportOut == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := atomicfile.WriteFile(exportOut, data, 0600); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Vault metadata written to %s\n", exportOut)
+	fmt.Fprintln(os.Stderr, "⚠️  It allows offline guessing of the master password; store it like a vault backup")
+	return nil
+}
+
+// runQRStreamExport loops QR frames of a vault snapshot until Enter
+func runQRStreamExport() error {
+	if exportQRFPS < 1 || exportQRFPS > 30 {
+		return fmt.Errorf("--qr-fps must be between 1 and 30")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	tmpDir, err := os.MkdirTemp("", "gpasswd-qr-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshotPath := filepath.Join(tmpDir, "vault.db")
+	if err := db.Snapshot(snapshotPath); err != nil {
+		return err
+	}
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault snapshot: %w", err)
+	}
+	fingerprint, err := db.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	payload, err := export.QRStreamPayload(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to compress vault: %w", err)
+	}
+	stream, err := export.NewQRStream(payload, export.DefaultQRBlockSize)
+	if err != nil {
+		return err
+	}
+
+	terminal := isTerminal(os.Stdout)
+	if terminal {
+		restore := enterAltScreen()
+		defer restore()
+	}
+
+	lines := stdinLines()
+	interval := time.Second / time.Duration(exportQRFPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for seq := uint32(0); ; seq++ {
+		code, err := qrcode.New(stream.Frame(seq), qrcode.Low)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code: %w", err)
+		}
+
+		if terminal {
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Println(code.ToSmallString(false))
+		fmt.Printf("Frame %d · %d blocks (%d KB) · 🔏 %s\n", seq+1, stream.Blocks(), (len(payload)+1023)/1024, formatFingerprint(fingerprint)[:19])
+		fmt.Print("Keep scanning until the scanner reports completion, then press Enter...")
+
+		select {
+		case _, ok := <-lines:
+			if ok {
+				fmt.Println()
+				return nil
+			}
+			// stdin is closed; keep streaming until interrupted
+			lines = nil
+		case <-ticker.C:
+		}
+	}
+}
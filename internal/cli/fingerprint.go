@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var fingerprintCmd = &cobra.Command{
+	Use:   "fingerprint",
+	Short: "Print the vault fingerprint and check for tampering",
+	Long: `Print a stable digest over every ciphertext in the vault (entries and
+history), the plaintext entry columns and the key derivation metadata.
+
+The fingerprint changes with every write. Compare it between a vault and its
+backup or copy on another machine to see whether they hold the same data; it
+can be printed without the master password.
+
+Every write also updates a manifest signed with a key derived from the
+master password. Unlocking checks it, so entries edited, deleted or rolled
+back outside gpasswd are reported. --verify checks it explicitly and
+--reseal accepts the vault's current state after such a warning.
+
+Examples:
+  gpasswd fingerprint
+  gpasswd fingerprint --verify
+  gpasswd fingerprint --reseal`,
+	Args: cobra.NoArgs,
+	RunE: runFingerprint,
+}
+
+var (
+	fingerprintVerify bool
+	fingerprintReseal bool
+)
+
+func init() {
+	rootCmd.AddCommand(fingerprintCmd)
+
+	fingerprintCmd.Flags().BoolVar(&fingerprintVerify, "verify", false, "Check the vault against its signed manifest")
+	fingerprintCmd.Flags().BoolVar(&fingerprintReseal, "reseal", false, "Accept the current vault state and re-sign the manifest")
+}
+
+func runFingerprint(cmd *cobra.Command, args []string) error {
+	if fingerprintVerify && fingerprintReseal {
+		return fmt.Errorf("--verify and --reseal cannot be combined")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	if fingerprintVerify || fingerprintReseal {
+		// unlockVault reports a tampered manifest itself
+		key, err := unlockVault(db, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		if fingerprintReseal {
+			if err := db.SealManifest(key); err != nil {
+				return fmt.Errorf("failed to seal vault manifest: %w", err)
+			}
+			fmt.Println("✅ Manifest re-signed for the current vault state")
+		} else if err := db.VerifyManifest(key); err != nil {
+			// Already explained by unlockVault; fail for scripts
+			return err
+		} else {
+			fmt.Println("✅ Vault matches its signed manifest")
+		}
+	}
+
+	fingerprint, err := db.Fingerprint()
+	if err != nil {
+		return err
+	}
+	count, err := db.CountEntries()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔏 %s\n", formatFingerprint(fingerprint))
+	fmt.Printf("   %d entries in %s\n", count, dbPath)
+	return nil
+}
+
+// formatFingerprint renders a digest as groups of four hex digits
+func formatFingerprint(digest []byte) string {
+	encoded := hex.EncodeToString(digest)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
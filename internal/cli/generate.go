@@ -2,8 +2,15 @@ package cli
 
 import (
 	"fmt"
+	"math"
+	"os"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -14,8 +21,15 @@ var (
 	generateUseDigits        bool
 	generateUseSymbols       bool
 	generateExcludeAmbiguous bool
+	generatePronounceable    bool
 	generateShowStrength     bool
 	generateCount            int
+	generateSave             string
+	generateUsername         string
+	generateURL              string
+	generateCategory         string
+	generateYes              bool
+	generateReveal           bool
 )
 
 // generateCmd represents the generate command
@@ -37,11 +51,22 @@ Examples:
   # Generate password excluding ambiguous characters (0, O, 1, l, I)
   gpasswd generate --exclude-ambiguous
 
+  # Generate a password of syllables that is easier to type on a phone
+  # (the estimated entropy is printed with it)
+  gpasswd generate --pronounceable
+
   # Generate 5 passwords
   gpasswd generate --count 5
 
   # Show password strength analysis
-  gpasswd generate --show-strength`,
+  gpasswd generate --show-strength
+
+  # Generate and store in one step (creates or updates the entry)
+  gpasswd generate --save github --username me@example.com
+
+  # Print JSON with strength details for scripts (--reveal includes the
+  # passwords, which are redacted otherwise)
+  gpasswd generate --count 3 --json --reveal`,
 	RunE: runGenerate,
 }
 
@@ -61,11 +86,26 @@ func init() {
 		"Include symbols (!@#$...)")
 	generateCmd.Flags().BoolVar(&generateExcludeAmbiguous, "exclude-ambiguous", false,
 		"Exclude ambiguous characters (0, O, 1, l, I)")
+	generateCmd.Flags().BoolVar(&generatePronounceable, "pronounceable", false,
+		"Generate consonant-vowel syllables, easier to type on a phone")
 	generateCmd.Flags().BoolVarP(&generateShowStrength, "show-strength", "s", false,
 		"Show password strength analysis")
 	generateCmd.Flags().IntVarP(&generateCount, "count", "c", 1,
 		"Number of passwords to generate (1-10)")
 
+	generateCmd.Flags().StringVar(&generateSave, "save", "",
+		"Save the password to this entry (created or updated)")
+	generateCmd.Flags().StringVarP(&generateUsername, "username", "u", "",
+		"Username for the saved entry (with --save)")
+	generateCmd.Flags().StringVar(&generateURL, "url", "",
+		"URL for the saved entry (with --save)")
+	generateCmd.Flags().StringVar(&generateCategory, "category", "",
+		"Category for a new saved entry (with --save)")
+	generateCmd.Flags().BoolVarP(&generateYes, "yes", "y", false,
+		"Skip the confirmation when saving")
+	generateCmd.Flags().BoolVarP(&generateReveal, "reveal", "r", false,
+		"With --json, include the passwords")
+
 	// Add convenience flags
 	generateCmd.Flags().BoolP("no-uppercase", "U", false, "Exclude uppercase letters")
 	generateCmd.Flags().BoolP("no-lowercase", "L", false, "Exclude lowercase letters")
@@ -92,6 +132,18 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if generateCount < 1 || generateCount > 10 {
 		return fmt.Errorf("count must be between 1 and 10")
 	}
+	if generateSave != "" && generateCount != 1 {
+		return fmt.Errorf("--save cannot be combined with --count")
+	}
+	if generateSave == "" && (generateUsername != "" || generateURL != "" || generateCategory != "") {
+		return fmt.Errorf("--username, --url and --category require --save")
+	}
+	if jsonFlag && generateSave != "" {
+		return fmt.Errorf("--json cannot be combined with --save")
+	}
+	if generateReveal && !jsonFlag {
+		return fmt.Errorf("--reveal requires --json (passwords are always printed as text)")
+	}
 
 	// Build options
 	options := crypto.GenerateOptions{
@@ -100,7 +152,9 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		UseDigits:        generateUseDigits,
 		UseSymbols:       generateUseSymbols,
 		ExcludeAmbiguous: generateExcludeAmbiguous,
+		Pronounceable:    generatePronounceable,
 	}
+	entropy := crypto.GeneratorEntropy(generateLength, options)
 
 	// Check if at least one character type is selected
 	if !options.UseUppercase && !options.UseLowercase &&
@@ -109,12 +163,31 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Generate passwords
+	var generated []generatedPassword
 	for i := 0; i < generateCount; i++ {
 		password, err := crypto.Generate(generateLength, options)
 		if err != nil {
 			return fmt.Errorf("failed to generate password: %w", err)
 		}
 
+		// Saved passwords are never printed
+		if generateSave != "" {
+			return saveGenerated(cmd, password)
+		}
+
+		if jsonFlag {
+			strength := crypto.CheckStrength(password)
+			generated = append(generated, generatedPassword{
+				Password: password,
+				Length:   len(password),
+				Strength: strength.Level.String(),
+				Score:    strength.Score,
+				Entropy:  math.Round(entropy*10) / 10,
+				Feedback: strength.Feedback,
+			})
+			continue
+		}
+
 		// Print password
 		fmt.Println(password)
 
@@ -122,6 +195,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		if generateShowStrength {
 			strength := crypto.CheckStrength(password)
 			fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level, strength.Score)
+			fmt.Printf("  Entropy: ~%.0f bits\n", entropy)
 			if len(strength.Feedback) > 0 {
 				fmt.Println("  Suggestions:")
 				for _, feedback := range strength.Feedback {
@@ -134,5 +208,120 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if jsonFlag {
+		return output.WriteJSON(os.Stdout, generated, output.Options{IncludeSecrets: generateReveal})
+	}
+
+	// Syllables are far more predictable than they look, so say how much
+	// they are worth
+	if generatePronounceable && !generateShowStrength {
+		fmt.Printf("\n💡 Estimated entropy: ~%.0f bits (the strength score overrates syllables)\n", entropy)
+	}
+	return nil
+}
+
+// generatedPassword is one password printed by generate --json
+type generatedPassword struct {
+	Password string   `json:"password"`
+	Length   int      `json:"length"`
+	Strength string   `json:"strength"`
+	Score    int      `json:"score"`
+	Entropy  float64  `json:"entropy"` // estimated bits, see crypto.GeneratorEntropy
+	Feedback []string `json:"feedback,omitempty"`
+}
+
+// saveGenerated stores a generated password in a new or existing entry
+func saveGenerated(cmd *cobra.Command, password string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	exists, err := db.EntryExists(generateSave)
+	if err != nil {
+		return err
+	}
+
+	if !generateYes {
+		message := fmt.Sprintf("Create entry '%s' with the generated password?", generateSave)
+		if exists {
+			message = fmt.Sprintf("Replace the password of existing entry '%s'?", generateSave)
+		}
+
+		var confirmed bool
+		if err := askOne(&survey.Confirm{Message: message, Default: !exists}, &confirmed); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("❌ Cancelled, nothing was saved")
+			return nil
+		}
+	}
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		entry, err := db.GetEntryByName(generateSave, key)
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+
+		entry.Password = password
+		if cmd.Flags().Changed("username") {
+			entry.Username = generateUsername
+		}
+		if cmd.Flags().Changed("url") {
+			entry.URL = generateURL
+		}
+		if cmd.Flags().Changed("category") {
+			entry.Category = generateCategory
+		}
+
+		if err := db.UpdateEntry(entry, key); err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+		fmt.Printf("✅ Generated password saved to existing entry '%s'\n", entry.Name)
+	} else {
+		entry := &models.Entry{
+			Name:     generateSave,
+			Username: generateUsername,
+			Password: password,
+			URL:      generateURL,
+			Category: generateCategory,
+		}
+
+		if err := db.CreateEntry(entry, key); err != nil {
+			return fmt.Errorf("failed to create entry: %w", err)
+		}
+		fmt.Printf("✅ Generated password saved to new entry '%s'\n", entry.Name)
+	}
+
+	strength := crypto.CheckStrength(password)
+	fmt.Printf("   Strength: %s (Score: %d/100)\n", strength.Level, strength.Score)
+	fmt.Printf("\n💡 Copy it with: gpasswd copy %s\n", generateSave)
+
 	return nil
 }
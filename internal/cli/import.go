@@ -0,0 +1,825 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/importer"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/otp"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/share"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import entries from another password manager",
+	Long: `Import entries exported from another password manager.
+
+Each source has its own subcommand that understands the quirks of that
+manager's export format. Entries whose name already exists in the vault
+are imported with a numeric suffix, e.g. "GitHub (2)".
+
+A record for the same site and username as an existing entry (compared by
+URL host, ignoring "www.", and case-insensitive username) is a duplicate
+even when the names differ. --on-duplicate decides what happens to it:
+  ask        prompt for each duplicate (default)
+  update     update the existing entry; its previous version stays in history
+  keep-both  import the record as a separate entry
+  skip       leave the existing entry alone
+
+--report writes what happened to every record (created, updated, skipped,
+failed, with reasons) to a file: Markdown for a .md path, JSON otherwise.
+--dry-run lists what the export contains and stops before touching the
+vault.
+After fixing the problems, --retry with a JSON report imports only the
+records that did not make it in the first time.
+
+Every record is committed as soon as it is stored and the vault keeps a
+checkpoint of the progress. If an import is interrupted (a failing prompt,
+Ctrl-C, a crash), run the same command again with --resume to continue
+after the last committed record.
+
+Authenticator apps (Aegis, andOTP) only hold OTP secrets, so instead of
+creating entries each secret is attached to the existing entry for the
+same site: an entry named after the issuer, or whose URL contains it,
+narrowed down by username when several match. Secrets without a matching
+entry are listed; add the entry and run the import again.
+
+Examples:
+  gpasswd import lastpass lastpass_export.csv
+  gpasswd import dashlane credentials.csv
+  gpasswd import dashlane dashlane_export.json
+  gpasswd import pass ~/.password-store
+  gpasswd import aegis aegis-export.json
+  gpasswd import andotp otp_accounts.json.aes
+  gpasswd import keepass Passwords.kdbx --dry-run
+  gpasswd import lastpass export.csv --report import-report.json
+  gpasswd import lastpass export.csv --retry import-report.json --allow-weak`,
+}
+
+var importLastPassCmd = &cobra.Command{
+	Use:   "lastpass <export.csv>",
+	Short: "Import a LastPass CSV export",
+	Long: `Import a LastPass CSV export.
+
+Folders ("Work\Email") become categories ("Work/Email"), favorites are
+tagged "favorite", and typed secure notes that contain a password
+(e.g. Server or Database notes) are imported as entries. Secure notes
+without a password are skipped.
+
+Example:
+  gpasswd import lastpass lastpass_export.csv`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportLastPass,
+}
+
+var importDashlaneCmd = &cobra.Command{
+	Use:   "dashlane <export.csv|export.json>",
+	Short: "Import a Dashlane CSV or JSON export",
+	Long: `Import a Dashlane export.
+
+Both the credentials.csv file from Dashlane's CSV export and the legacy
+JSON export are supported. The format is chosen by file extension.
+Only credentials are imported; other item types are reported as skipped.
+
+Examples:
+  gpasswd import dashlane credentials.csv
+  gpasswd import dashlane dashlane_export.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportDashlane,
+}
+
+var importPassCmd = &cobra.Command{
+	Use:   "pass [store-dir]",
+	Short: "Import a pass (password-store) directory",
+	Long: `Import entries from a pass (https://www.passwordstore.org/) store.
+
+Every .gpg file is decrypted with your gpg installation, so gpg-agent and
+pinentry will ask for your GPG passphrase if needed. Directories become
+categories and the file name becomes the entry name:
+
+  ~/.password-store/work/aws/root.gpg  →  name "root", category "work/aws"
+
+The first line of each file is the password. Conventional "login:",
+"username:", "email:" and "url:" lines fill the matching fields; all
+other lines are kept as notes.
+
+The store directory defaults to $PASSWORD_STORE_DIR or ~/.password-store.
+
+Examples:
+  gpasswd import pass
+  gpasswd import pass ~/.password-store`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImportPass,
+}
+
+var importAegisCmd = &cobra.Command{
+	Use:   "aegis <export.json>",
+	Short: "Import OTP secrets from an Aegis export",
+	Long: `Import OTP secrets from an Aegis Authenticator vault export.
+
+Both plain and password-encrypted exports are supported; the backup
+password is asked for when needed. See 'gpasswd import --help' for how OTP
+secrets are matched to entries.
+
+Example:
+  gpasswd import aegis aegis-export.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportAegis,
+}
+
+var importAndOTPCmd = &cobra.Command{
+	Use:   "andotp <backup.json|backup.json.aes>",
+	Short: "Import OTP secrets from an andOTP backup",
+	Long: `Import OTP secrets from an andOTP backup.
+
+Plain JSON backups and password-encrypted .json.aes backups (both the
+current and the legacy format) are supported. See 'gpasswd import --help'
+for how OTP secrets are matched to entries.
+
+Example:
+  gpasswd import andotp otp_accounts.json.aes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportAndOTP,
+}
+
+var importKeePassCmd = &cobra.Command{
+	Use:   "keepass <database.kdbx>",
+	Short: "Import a KeePass or KeePassXC database (KDBX 3.1 and 4.x)",
+	Long: `Import entries straight from a KeePass database file, without exporting
+it first. KDBX 3.1 and 4.x databases are supported, with AES, ChaCha20 or
+Twofish encryption and AES-KDF, Argon2d or Argon2id key derivation.
+
+Groups become categories ("Internet/Shopping", the root group is left out)
+and tags are kept. Custom string fields are appended to the notes as
+"Field: value" lines, except a KeePassXC "otp" field, which becomes the
+entry's OTP secret. Attachments are not imported; their names are listed in
+the notes. Entries in the recycle bin and entries without a password are
+skipped.
+
+The database password is asked for; use --key-file for databases that
+also (or only, with --no-password) need a key file.
+
+Examples:
+  gpasswd import keepass Passwords.kdbx --dry-run
+  gpasswd import keepass Passwords.kdbx
+  gpasswd import keepass Passwords.kdbx --key-file Passwords.keyx`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportKeePass,
+}
+
+var (
+	importKeePassKeyFile    string
+	importKeePassNoPassword bool
+)
+
+var (
+	importDryRun      bool
+	importAllowWeak   bool
+	importOnDuplicate string
+	importReportPath  string
+	importRetryPath   string
+	importResume      bool
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.PersistentFlags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without changing the vault")
+	importCmd.PersistentFlags().BoolVar(&importAllowWeak, "allow-weak", false, "Import passwords that fail the strength policy")
+	importCmd.PersistentFlags().StringVar(&importReportPath, "report", "", "Write a report of every record to this file (.md for Markdown, JSON otherwise)")
+	importCmd.PersistentFlags().StringVar(&importRetryPath, "retry", "", "Import only the records an earlier JSON report shows as not imported")
+	importCmd.PersistentFlags().BoolVar(&importResume, "resume", false, "Continue an interrupted import of the same export")
+	importCmd.PersistentFlags().StringVar(&importOnDuplicate, "on-duplicate", "ask", "For records matching an entry by URL and username (ask, update, keep-both, skip)")
+
+	importCmd.AddCommand(importLastPassCmd)
+	importCmd.AddCommand(importDashlaneCmd)
+	importCmd.AddCommand(importPassCmd)
+	importCmd.AddCommand(importAegisCmd)
+	importCmd.AddCommand(importAndOTPCmd)
+	importCmd.AddCommand(importKeePassCmd)
+
+	importKeePassCmd.Flags().StringVar(&importKeePassKeyFile, "key-file", "", "Key file of the database")
+	importKeePassCmd.Flags().BoolVar(&importKeePassNoPassword, "no-password", false, "The database is protected by the key file alone")
+}
+
+func runImportLastPass(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer file.Close()
+
+	result, err := importer.ParseLastPassCSV(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse LastPass export: %w", err)
+	}
+
+	return storeImported("LastPass", result)
+}
+
+func runImportDashlane(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer file.Close()
+
+	var result *importer.Result
+	if strings.EqualFold(filepath.Ext(args[0]), ".json") {
+		result, err = importer.ParseDashlaneJSON(file)
+	} else {
+		result, err = importer.ParseDashlaneCSV(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse Dashlane export: %w", err)
+	}
+
+	return storeImported("Dashlane", result)
+}
+
+func runImportPass(cmd *cobra.Command, args []string) error {
+	storeDir := os.Getenv("PASSWORD_STORE_DIR")
+	if len(args) > 0 {
+		storeDir = args[0]
+	}
+	if storeDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		storeDir = filepath.Join(homeDir, ".password-store")
+	}
+
+	fmt.Printf("🔑 Decrypting password store at %s with gpg...\n", storeDir)
+	result, err := importer.ParsePassStore(storeDir, importer.GPGDecrypt)
+	if err != nil {
+		return err
+	}
+
+	return storeImported("pass", result)
+}
+
+func runImportAegis(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read export file: %w", err)
+	}
+
+	result, err := importer.ParseAegis(data, backupPassword("Aegis"))
+	if err != nil {
+		return err
+	}
+
+	return storeImportedOTP("Aegis", result)
+}
+
+func runImportAndOTP(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+
+	result, err := importer.ParseAndOTP(data, backupPassword("andOTP"))
+	if err != nil {
+		return err
+	}
+
+	return storeImportedOTP("andOTP", result)
+}
+
+func runImportKeePass(cmd *cobra.Command, args []string) error {
+	if importKeePassNoPassword && importKeePassKeyFile == "" {
+		return fmt.Errorf("--no-password needs --key-file")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read database file: %w", err)
+	}
+
+	var keyFile []byte
+	if importKeePassKeyFile != "" {
+		if keyFile, err = os.ReadFile(importKeePassKeyFile); err != nil {
+			return fmt.Errorf("failed to read key file: %w", err)
+		}
+	}
+
+	password := importer.PasswordFunc(func() (string, error) {
+		var password string
+		prompt := &survey.Password{Message: "KeePass database password:"}
+		if err := askOne(prompt, &password); err != nil {
+			return "", fmt.Errorf("password prompt failed: %w", err)
+		}
+		fmt.Println("🔓 Opening KeePass database...")
+		return password, nil
+	})
+	if importKeePassNoPassword {
+		password = nil
+	}
+
+	result, err := importer.ParseKDBX(data, password, keyFile)
+	if err != nil {
+		return err
+	}
+
+	return storeImported("KeePass", result)
+}
+
+// backupPassword prompts for the password of an encrypted backup
+func backupPassword(source string) importer.PasswordFunc {
+	return func() (string, error) {
+		var password string
+		prompt := &survey.Password{Message: source + " backup password:"}
+		if err := askOne(prompt, &password, survey.WithValidator(survey.Required)); err != nil {
+			return "", fmt.Errorf("password prompt failed: %w", err)
+		}
+		return password, nil
+	}
+}
+
+// storeImportedOTP attaches imported OTP secrets to matching vault entries
+func storeImportedOTP(source string, result *importer.Result) error {
+	if importResume {
+		return fmt.Errorf("--resume applies to entry imports; run the OTP import again instead")
+	}
+	if err := retryImport(result); err != nil {
+		return err
+	}
+
+	report := importer.NewReport(source)
+	if err := attachImportedOTP(source, result, report); err != nil {
+		return err
+	}
+	return writeImportReport(report)
+}
+
+func attachImportedOTP(source string, result *importer.Result, report *importer.Report) error {
+	report.AddSkipped(result.Skipped)
+
+	fmt.Printf("📥 Parsed %d OTP secrets from %s\n", len(result.Entries), source)
+	for _, skipped := range result.Skipped {
+		fmt.Printf("   • Skipping %s: %s\n", skipped.Source, skipped.Reason)
+	}
+
+	if len(result.Entries) == 0 {
+		fmt.Println("\nNothing to import")
+		return nil
+	}
+
+	if importDryRun {
+		fmt.Printf("\n🔍 Dry run: %d OTP secrets would be matched against the vault\n", len(result.Entries))
+		for _, imported := range result.Entries {
+			fmt.Printf("   + %s\n", imported.Name)
+		}
+		fmt.Println("\nNothing was changed; run again without --dry-run to import")
+		return nil
+	}
+
+	db, key, _, err := openTOTPVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	attached := 0
+	var unmatched []string
+	for _, imported := range result.Entries {
+		label := imported.Name
+		if imported.Username != "" && imported.Username != imported.Name {
+			label += " (" + imported.Username + ")"
+		}
+
+		matches := matchOTPEntry(entries, imported)
+		if len(matches) != 1 {
+			reason := "no matching entry"
+			if len(matches) > 1 {
+				reason = fmt.Sprintf("%d entries match", len(matches))
+				label += ": " + reason
+			}
+			unmatched = append(unmatched, label)
+			report.Add(imported, importer.StatusSkipped, "", reason)
+			continue
+		}
+		target := matches[0]
+
+		if target.OTP != "" {
+			if sameOTPSecret(target.OTP, imported.OTP) {
+				fmt.Printf("   • '%s' already has this OTP secret\n", target.Name)
+				report.Add(imported, importer.StatusSkipped, target.Name, "already has this OTP secret")
+			} else {
+				fmt.Printf("   • Skipping %s: '%s' already has a different OTP secret\n", label, target.Name)
+				report.Add(imported, importer.StatusSkipped, target.Name, "entry already has a different OTP secret")
+			}
+			continue
+		}
+
+		target.OTP = imported.OTP
+		if err := db.UpdateEntry(target, key); err != nil {
+			fmt.Printf("   ✗ Failed to update '%s': %v\n", target.Name, err)
+			report.Add(imported, importer.StatusError, target.Name, err.Error())
+			continue
+		}
+		fmt.Printf("   ✓ %s → '%s'\n", label, target.Name)
+		report.Add(imported, importer.StatusUpdated, target.Name, "")
+		attached++
+	}
+
+	fmt.Printf("\n✅ Attached %d OTP secrets to existing entries\n", attached)
+	if len(unmatched) > 0 {
+		fmt.Printf("⚠️  %d secrets have no single matching entry:\n", len(unmatched))
+		for _, label := range unmatched {
+			fmt.Printf("   • %s\n", label)
+		}
+		fmt.Println("   Add or rename the entries, then run the import again")
+	}
+	fmt.Println("\n💡 Delete the backup file once you no longer need it")
+
+	return nil
+}
+
+// matchOTPEntry finds the vault entries an imported OTP secret belongs to
+func matchOTPEntry(entries []*models.Entry, imported *models.Entry) []*models.Entry {
+	issuer := strings.ToLower(strings.ReplaceAll(imported.Name, " ", ""))
+	if issuer == "" {
+		return nil
+	}
+
+	var matches []*models.Entry
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name, imported.Name) {
+			matches = append(matches, entry)
+		}
+	}
+
+	if len(matches) == 0 {
+		for _, entry := range entries {
+			name := strings.ToLower(strings.ReplaceAll(entry.Name, " ", ""))
+			host := storage.URLHost(entry.URL)
+			if strings.Contains(name, issuer) || (host != "" && strings.Contains(host, issuer)) {
+				matches = append(matches, entry)
+			}
+		}
+	}
+
+	if len(matches) > 1 && imported.Username != "" {
+		var byUser []*models.Entry
+		for _, entry := range matches {
+			if strings.EqualFold(entry.Username, imported.Username) {
+				byUser = append(byUser, entry)
+			}
+		}
+		if len(byUser) > 0 {
+			matches = byUser
+		}
+	}
+
+	return matches
+}
+
+// sameOTPSecret reports whether two stored OTP values share a secret
+func sameOTPSecret(a, b string) bool {
+	keyA, errA := otp.Parse(a)
+	keyB, errB := otp.Parse(b)
+	return errA == nil && errB == nil && bytes.Equal(keyA.Secret, keyB.Secret)
+}
+
+// storeImported unlocks the vault and stores parsed entries
+func storeImported(source string, result *importer.Result) error {
+	switch importOnDuplicate {
+	case "ask", "update", "keep-both", "skip":
+	default:
+		return fmt.Errorf("invalid --on-duplicate %q (use ask, update, keep-both or skip)", importOnDuplicate)
+	}
+	if err := retryImport(result); err != nil {
+		return err
+	}
+
+	report := importer.NewReport(source)
+	if err := storeImportedEntries(source, result, report); err != nil {
+		return err
+	}
+	return writeImportReport(report)
+}
+
+func storeImportedEntries(source string, result *importer.Result, report *importer.Report) error {
+	report.AddSkipped(result.Skipped)
+
+	fmt.Printf("📥 Parsed %d entries from %s export\n", len(result.Entries), source)
+	for _, skipped := range result.Skipped {
+		fmt.Printf("   • Skipping %s: %s\n", skipped.Source, skipped.Reason)
+	}
+
+	if len(result.Entries) == 0 {
+		fmt.Println("\nNothing to import")
+		return nil
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Hold back passwords that fail the strength policy
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	accepted := make([]*models.Entry, 0, len(result.Entries))
+	weak := 0
+	for _, entry := range result.Entries {
+		// Only chosen login passwords are held to the policy
+		if !importAllowWeak && entry.IsLogin() && entry.Password != "" {
+			if err := policy.Check(entry.Password); err != nil {
+				fmt.Printf("   • Skipping '%s': %v\n", entry.Name, err)
+				report.Add(entry, importer.StatusSkipped, "", "weak password: "+err.Error())
+				weak++
+				continue
+			}
+		}
+		accepted = append(accepted, entry)
+	}
+	if weak > 0 {
+		fmt.Printf("   %d weak passwords held back (use --allow-weak to import them)\n", weak)
+	}
+	if len(accepted) == 0 {
+		fmt.Println("\nNothing to import")
+		return nil
+	}
+
+	if importDryRun {
+		printImportPreview(accepted)
+		return nil
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// Collect existing names so imports never collide with the UNIQUE constraint
+	existing, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	names := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		names[strings.ToLower(entry.Name)] = true
+	}
+	duplicates := importer.NewDuplicates(existing)
+
+	digest := importer.Digest(accepted)
+	start, err := importStart(db, source, digest)
+	if err != nil {
+		return err
+	}
+
+	imported, updated, largeNotes := 0, 0, 0
+	var conflicts []storage.ImportConflict
+	for i := start; i < len(accepted); i++ {
+		// Everything before this record is committed
+		if err := db.SaveImportCheckpoint(storage.ImportCheckpoint{
+			Source:    source,
+			Digest:    digest,
+			Position:  i,
+			Total:     len(accepted),
+			UpdatedAt: time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		entry := accepted[i]
+		record := *entry
+		if current := duplicates.Find(entry); current != nil {
+			merged := importer.Merge(current, entry)
+			if share.SameContent(merged, current) {
+				fmt.Printf("   • '%s' is already up to date\n", current.Name)
+				report.Add(&record, importer.StatusSkipped, current.Name, "entry is already up to date")
+				continue
+			}
+
+			action, err := resolveImportDuplicate(entry, current)
+			if err != nil {
+				return err
+			}
+			switch action {
+			case "update":
+				if err := db.UpdateEntry(merged, key); err != nil {
+					fmt.Printf("   ✗ Failed to update '%s': %v\n", current.Name, err)
+					report.Add(&record, importer.StatusError, current.Name, err.Error())
+					continue
+				}
+				*current = *merged
+				fmt.Printf("   ~ %s → '%s' (previous version kept in history)\n", entry.Name, current.Name)
+				report.Add(&record, importer.StatusUpdated, current.Name, "")
+				updated++
+				if notesOverLimit(cfg, current) {
+					largeNotes++
+				}
+				continue
+			case "skip":
+				fmt.Printf("   • Skipping '%s': same site and username as '%s'\n", entry.Name, current.Name)
+				report.Add(&record, importer.StatusSkipped, current.Name, "same site and username as an existing entry")
+				continue
+			}
+		}
+
+		original := entry.Name
+		entry.Name = uniqueEntryName(entry.Name, names)
+		names[strings.ToLower(entry.Name)] = true
+
+		if err := db.CreateEntry(entry, key); err != nil {
+			fmt.Printf("   ✗ Failed to import '%s': %v\n", entry.Name, err)
+			report.Add(&record, importer.StatusError, "", err.Error())
+			continue
+		}
+		report.Add(&record, importer.StatusCreated, entry.Name, "")
+		imported++
+		duplicates.Add(entry)
+		if notesOverLimit(cfg, entry) {
+			largeNotes++
+		}
+
+		if entry.Name != original {
+			conflict := storage.ImportConflict{
+				EntryID:    entry.ID,
+				EntryName:  entry.Name,
+				Original:   original,
+				Source:     source,
+				ImportedAt: time.Now(),
+			}
+			// Renamed duplicates stay flagged until the user renames or deletes them
+			if err := db.AddImportConflicts([]storage.ImportConflict{conflict}); err != nil {
+				return err
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	if err := db.ClearImportCheckpoint(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ Imported %d entries, updated %d existing (%d skipped)\n", imported, updated, len(result.Skipped)+len(result.Entries)-imported-updated)
+	if len(conflicts) > 0 {
+		fmt.Printf("⚠️  %d entries clashed with existing names and were renamed, e.g. '%s'\n", len(conflicts), conflicts[0].EntryName)
+	}
+	if largeNotes > 0 {
+		fmt.Printf("⚠️  %d entries have notes over the %d KB limit (limits.notes_size_kb); see 'gpasswd stats --largest'\n", largeNotes, cfg.Limits.NotesSizeKB)
+	}
+	warnSizeLimits(db, cfg, nil, os.Stdout)
+	fmt.Println("\n💡 If you imported a plaintext export file, delete it now")
+
+	return nil
+}
+
+// printImportPreview lists the records a --dry-run would import
+func printImportPreview(entries []*models.Entry) {
+	fmt.Printf("\n🔍 Dry run: %d entries would be imported\n", len(entries))
+	for _, entry := range entries {
+		line := "   + " + entry.Name
+		if entry.Category != "" {
+			line += " [" + entry.Category + "]"
+		}
+		if entry.Username != "" {
+			line += " · " + entry.Username
+		}
+		if entry.OTP != "" {
+			line += " · 2FA"
+		}
+		fmt.Println(line)
+	}
+	fmt.Println("\nNothing was changed; run again without --dry-run to import")
+}
+
+// importStart returns the record to start at, checking --resume against
+// the checkpoint left by an interrupted import
+func importStart(db *storage.DB, source, digest string) (int, error) {
+	checkpoint, err := db.ImportCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+	same := checkpoint != nil && checkpoint.Source == source && checkpoint.Digest == digest
+
+	switch {
+	case importResume && !same:
+		return 0, fmt.Errorf("no interrupted import of this %s export to resume (run it with the same file and options)", source)
+	case importResume:
+		fmt.Printf("⏩ Resuming at record %d of %d\n", checkpoint.Position+1, checkpoint.Total)
+		return checkpoint.Position, nil
+	case same:
+		return 0, fmt.Errorf("an import of this export stopped at record %d of %d on %s; add --resume to continue it",
+			checkpoint.Position+1, checkpoint.Total, checkpoint.UpdatedAt.Local().Format(output.DefaultDateLayout))
+	case checkpoint != nil:
+		fmt.Printf("⚠️  Discarding the checkpoint of an unfinished %s import\n", checkpoint.Source)
+	}
+	return 0, nil
+}
+
+// retryImport drops records that an earlier run (--retry) already imported
+func retryImport(result *importer.Result) error {
+	if importRetryPath == "" {
+		return nil
+	}
+
+	previous, err := importer.ReadReport(importRetryPath)
+	if err != nil {
+		return err
+	}
+
+	remaining := result.Entries[:0]
+	for _, entry := range result.Entries {
+		if !previous.Done(entry) {
+			remaining = append(remaining, entry)
+		}
+	}
+	fmt.Printf("🔁 Retrying %d records not imported on %s (%d already done)\n",
+		len(remaining), previous.ImportedAt.Format(output.DefaultDateLayout), len(result.Entries)-len(remaining))
+	result.Entries = remaining
+	return nil
+}
+
+// writeImportReport saves the report if --report was given
+func writeImportReport(report *importer.Report) error {
+	if importReportPath == "" {
+		return nil
+	}
+	if err := report.Write(importReportPath); err != nil {
+		return err
+	}
+	fmt.Printf("📝 Import report written to %s\n", importReportPath)
+	return nil
+}
+
+// resolveImportDuplicate applies --on-duplicate, asking if needed
+func resolveImportDuplicate(imported, current *models.Entry) (string, error) {
+	if importOnDuplicate != "ask" {
+		return importOnDuplicate, nil
+	}
+
+	options := []string{
+		fmt.Sprintf("Update '%s' (previous version kept in history)", current.Name),
+		"Import as a separate entry",
+		"Skip",
+	}
+	actions := []string{"update", "keep-both", "skip"}
+
+	var choice int
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("'%s' has the same site and username as '%s':", imported.Name, current.Name),
+		Options: options,
+	}
+	if err := askOne(prompt, &choice); err != nil {
+		return "", fmt.Errorf("selection failed: %w", err)
+	}
+	return actions[choice], nil
+}
+
+// uniqueEntryName appends " (2)", " (3)", ... until the name is unused
+func uniqueEntryName(name string, taken map[string]bool) string {
+	if !taken[strings.ToLower(name)] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !taken[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/export"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
@@ -27,16 +29,61 @@ This command will:
 
 The vault will be created at: ~/.gpasswd/vault.db
 
-Example:
-  gpasswd init`,
+Use --privacy to choose which entry metadata is stored in plaintext so it can
+be listed and filtered without the master password:
+  open        name, category, tags and URL host
+  standard    name, category and tags (default)
+  private     name only; category and tag filters need the master password
+
+Use --profile to size the vault for the machine it runs on:
+  default     64 MB Argon2 (or security.argon2 from config.yaml), 64 MB
+              page cache, memory-mapped I/O on 64-bit platforms
+  low-memory  16 MB Argon2 with 4 passes, 2 MB page cache, no memory-mapped
+              I/O; for Raspberry Pi class and other small devices
+
+To move a vault to a new machine in one step:
+  --restore FILE        fill the new vault with the entries of a bundle
+                        written by 'gpasswd export --viewer'
+  --from-metadata FILE  reuse the salt and key derivation parameters saved by
+                        'gpasswd export --metadata', so the vault opens with
+                        the original master password (checked against the
+                        saved verifier) and derives the same key
+
+Examples:
+  gpasswd init
+  gpasswd init --privacy private
+  gpasswd init --profile low-memory
+  gpasswd init --restore backup.gpb
+  gpasswd init --from-metadata vault-metadata.json --restore backup.gpb`,
 	RunE: runInit,
 }
 
+var (
+	initPrivacy      string
+	initRestore      string
+	initFromMetadata string
+	initProfile      string
+)
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVar(&initPrivacy, "privacy", string(storage.PrivacyStandard), "Plaintext metadata level (open, standard, private)")
+	initCmd.Flags().StringVar(&initRestore, "restore", "", "Restore entries from a bundle written by 'gpasswd export --viewer'")
+	initCmd.Flags().StringVar(&initProfile, "profile", string(storage.ProfileDefault), "Resource profile (default, low-memory)")
+	initCmd.Flags().StringVar(&initFromMetadata, "from-metadata", "", "Reuse the salt and key parameters written by 'gpasswd export --metadata'")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
+	privacy, err := storage.ParsePrivacyLevel(initPrivacy)
+	if err != nil {
+		return err
+	}
+	profile, err := storage.ParseProfile(initProfile)
+	if err != nil {
+		return err
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -46,12 +93,37 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Determine database path
 	dbPath := cfg.Database.Path
 	if dbPath == "" {
-		// Default to ~/.gpasswd/vault.db
-		homeDir, err := os.UserHomeDir()
+		dbPath = config.GetVaultPath()
+	}
+
+	// Read restore sources before anything is overwritten
+	var metadata *storage.VaultMetadata
+	if initFromMetadata != "" {
+		data, err := os.ReadFile(initFromMetadata)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata file: %w", err)
+		}
+		metadata, err = storage.ParseVaultMetadata(data)
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
+		}
+		// Keep the original vault's privacy level unless asked otherwise
+		if !cmd.Flags().Changed("privacy") {
+			privacy = metadata.PrivacyLevel
 		}
-		dbPath = filepath.Join(homeDir, ".gpasswd", "vault.db")
+	}
+
+	var restored *export.ViewerBundle
+	if initRestore != "" {
+		data, err := os.ReadFile(initRestore)
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		restored, err = openViewerBundle(data, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📦 Bundle holds %d entries\n", len(restored.Entries))
 	}
 
 	// Check if vault already exists
@@ -63,7 +135,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 			Message: "Do you want to overwrite the existing vault? (ALL DATA WILL BE LOST)",
 			Default: false,
 		}
-		if err := survey.AskOne(prompt, &overwrite); err != nil {
+		if err := askOne(prompt, &overwrite); err != nil {
 			return fmt.Errorf("prompt failed: %w", err)
 		}
 
@@ -78,13 +150,149 @@ func runInit(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	var salt []byte
+	var argon2Params crypto.Argon2Params
+	var key []byte
+	if metadata != nil {
+		// The master password must match the original vault, so check it
+		// against the saved verifier instead of asking twice
+		var masterPassword string
+		passwordPrompt := &survey.Password{
+			Message: "Enter the original master password:",
+		}
+		if err := askOne(passwordPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
+			return fmt.Errorf("password prompt failed: %w", err)
+		}
+
+		fmt.Println("\n🔧 Initializing vault...")
+		fmt.Println("   • Deriving encryption key from saved parameters (this may take a moment)...")
+		salt = metadata.Salt
+		argon2Params = metadata.Argon2
+		key, err = crypto.DeriveKey(masterPassword, salt, argon2Params)
+		if err != nil {
+			return fmt.Errorf("failed to derive key: %w", err)
+		}
+		if err := metadata.VerifyKey(key); err != nil {
+			return fmt.Errorf("master password does not match the metadata file")
+		}
+	} else {
+		// The profile's preset replaces configured Argon2 parameters
+		if preset, ok := profile.Argon2Params(); ok {
+			cfg.Security.Argon2.Time = preset.Time
+			cfg.Security.Argon2.Memory = preset.Memory
+			cfg.Security.Argon2.Parallelism = preset.Parallelism
+			cfg.Security.Argon2.KeyLength = preset.KeyLen
+		}
+		salt, argon2Params, key, err = newMasterKey(cfg, "Initializing vault")
+		if err != nil || key == nil {
+			return err
+		}
+	}
+
+	// Initialize database
+	fmt.Printf("   • Creating database at: %s\n", dbPath)
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	// Store salt
+	fmt.Println("   • Storing cryptographic salt...")
+	if err := db.SetSalt(salt); err != nil {
+		return fmt.Errorf("failed to store salt: %w", err)
+	}
+
+	// Store Argon2 parameters
+	fmt.Println("   • Storing key derivation parameters...")
+	if err := db.SetArgon2Params(argon2Params); err != nil {
+		return fmt.Errorf("failed to store Argon2 parameters: %w", err)
+	}
+
+	// Store key verifier so wrong master passwords are detected up front
+	if err := db.SetKeyVerifier(key); err != nil {
+		return fmt.Errorf("failed to store key verifier: %w", err)
+	}
+
+	// Start the failed unlock counter at 0 and create the key that signs it
+	if err := db.ResetFailedUnlocks(); err != nil {
+		return fmt.Errorf("failed to initialize unlock throttling: %w", err)
+	}
+
+	if err := db.SetMetadata(storage.MetadataKeyPrivacyLevel, string(privacy)); err != nil {
+		return fmt.Errorf("failed to store privacy level: %w", err)
+	}
+
+	if err := db.SetProfile(profile); err != nil {
+		return err
+	}
+
+	// A new vault starts with a complete (empty) tag index
+	if err := db.SetMetadata(storage.MetadataKeyTagsIndexed, "1"); err != nil {
+		return fmt.Errorf("failed to initialize tag index: %w", err)
+	}
+	if err := db.SetMetadata(storage.MetadataKeyFieldsSealed, "1"); err != nil {
+		return fmt.Errorf("failed to initialize entry fields: %w", err)
+	}
+
+	// Store metadata
+	if err := db.SetMetadata("version", Version); err != nil {
+		return fmt.Errorf("failed to store version: %w", err)
+	}
+
+	if err := db.SetMetadata("created_at", fmt.Sprintf("%d", os.Getpid())); err != nil {
+		// Non-critical, just log
+		fmt.Fprintf(os.Stderr, "Warning: failed to store created_at: %v\n", err)
+	}
+
+	if restored != nil {
+		fmt.Println("   • Restoring entries...")
+		count := 0
+		for _, entry := range restored.Entries {
+			if err := db.CreateEntry(entry, key); err != nil {
+				fmt.Printf("   ✗ Failed to restore '%s': %v\n", entry.Name, err)
+				continue
+			}
+			count++
+		}
+		fmt.Printf("   • Restored %d of %d entries\n", count, len(restored.Entries))
+	}
+
+	// Sign the vault so later tampering is detected at unlock
+	if err := db.SealManifest(key); err != nil {
+		return fmt.Errorf("failed to seal vault manifest: %w", err)
+	}
+
+	// Success!
+	fmt.Println("\n✅ Vault initialized successfully!")
+	fmt.Printf("   Location: %s\n", dbPath)
+	fmt.Printf("   Encryption: AES-256-GCM\n")
+	fmt.Printf("   Privacy: %s (%s)\n", privacy, privacy.Description())
+	fmt.Printf("   Profile: %s\n", profile)
+	fmt.Printf("   Key Derivation: Argon2id (Time=%d, Memory=%dMB, Threads=%d)\n",
+		argon2Params.Time, argon2Params.Memory/1024, argon2Params.Parallelism)
+	fmt.Println("\n💡 Next steps:")
+	fmt.Println("   • Add your first password: gpasswd add")
+	fmt.Println("   • Generate a strong password: gpasswd generate")
+	fmt.Println("   • List all entries: gpasswd list")
+	fmt.Println("\n⚠️  IMPORTANT: Remember your master password!")
+	fmt.Println("   There is NO way to recover it if you forget.")
+
+	return nil
+}
+
+// newMasterKey asks for a new master password (checking strength and
+// confirmation) and derives its key with a fresh salt; heading introduces
+// the derivation steps
+// Returns a nil key if the user cancelled
+func newMasterKey(cfg *config.Config, heading string) (salt []byte, argon2Params crypto.Argon2Params, key []byte, err error) {
 	// Prompt for master password
 	var masterPassword string
 	passwordPrompt := &survey.Password{
 		Message: "Enter master password:",
 	}
-	if err := survey.AskOne(passwordPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("password prompt failed: %w", err)
+	if err := askOne(passwordPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("password prompt failed: %w", err)
 	}
 
 	// Check password strength
@@ -102,13 +310,13 @@ func runInit(cmd *cobra.Command, args []string) error {
 			Message: "Continue with this weak password?",
 			Default: false,
 		}
-		if err := survey.AskOne(confirmPrompt, &continueWeak); err != nil {
-			return fmt.Errorf("confirmation failed: %w", err)
+		if err := askOne(confirmPrompt, &continueWeak); err != nil {
+			return nil, crypto.Argon2Params{}, nil, fmt.Errorf("confirmation failed: %w", err)
 		}
 
 		if !continueWeak {
-			fmt.Println("✓ Initialization cancelled. Please choose a stronger password.")
-			return nil
+			fmt.Println("✓ Cancelled. Please choose a stronger password.")
+			return nil, crypto.Argon2Params{}, nil, nil
 		}
 	}
 
@@ -117,25 +325,24 @@ func runInit(cmd *cobra.Command, args []string) error {
 	confirmPrompt := &survey.Password{
 		Message: "Confirm master password:",
 	}
-	if err := survey.AskOne(confirmPrompt, &confirmPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("confirmation prompt failed: %w", err)
+	if err := askOne(confirmPrompt, &confirmPassword, survey.WithValidator(survey.Required)); err != nil {
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("confirmation prompt failed: %w", err)
 	}
 
 	if masterPassword != confirmPassword {
-		return fmt.Errorf("passwords do not match")
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("passwords do not match")
 	}
 
-	fmt.Println("\n🔧 Initializing vault...")
+	fmt.Printf("\n🔧 %s...\n", heading)
 
 	// Generate cryptographic salt
 	fmt.Println("   • Generating cryptographic salt...")
-	salt, err := crypto.GenerateSalt()
+	salt, err = crypto.GenerateSalt()
 	if err != nil {
-		return fmt.Errorf("failed to generate salt: %w", err)
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("failed to generate salt: %w", err)
 	}
 
 	// Get Argon2 parameters from config or use defaults
-	var argon2Params crypto.Argon2Params
 	if cfg.Security.Argon2.Time > 0 {
 		argon2Params = crypto.Argon2Params{
 			Time:        cfg.Security.Argon2.Time,
@@ -149,58 +356,19 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Validate parameters
 	if err := argon2Params.Validate(); err != nil {
-		return fmt.Errorf("invalid Argon2 parameters: %w", err)
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("invalid Argon2 parameters: %w", err)
 	}
 
-	// Test key derivation (to verify password works)
+	// Derive the key once so a verifier can be stored for later unlocks
 	fmt.Println("   • Deriving encryption key (this may take a moment)...")
-	_, err = crypto.DeriveKey(masterPassword, salt, argon2Params)
+	key, err = crypto.DeriveKey(masterPassword, salt, argon2Params)
 	if err != nil {
-		return fmt.Errorf("failed to derive key: %w", err)
+		return nil, crypto.Argon2Params{}, nil, fmt.Errorf("failed to derive key: %w", err)
 	}
-
-	// Initialize database
-	fmt.Printf("   • Creating database at: %s\n", dbPath)
-	db, err := storage.InitDB(dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+	if cfg.Display.PasswordFingerprint {
+		fmt.Printf("   • Password fingerprint: %s (shown after you enter it to unlock)\n",
+			crypto.PasswordFingerprint(key))
 	}
-	defer db.Close()
 
-	// Store salt
-	fmt.Println("   • Storing cryptographic salt...")
-	if err := db.SetSalt(salt); err != nil {
-		return fmt.Errorf("failed to store salt: %w", err)
-	}
-
-	// Store Argon2 parameters
-	fmt.Println("   • Storing key derivation parameters...")
-	if err := db.SetArgon2Params(argon2Params); err != nil {
-		return fmt.Errorf("failed to store Argon2 parameters: %w", err)
-	}
-
-	// Store metadata
-	if err := db.SetMetadata("version", Version); err != nil {
-		return fmt.Errorf("failed to store version: %w", err)
-	}
-
-	if err := db.SetMetadata("created_at", fmt.Sprintf("%d", os.Getpid())); err != nil {
-		// Non-critical, just log
-		fmt.Fprintf(os.Stderr, "Warning: failed to store created_at: %v\n", err)
-	}
-
-	// Success!
-	fmt.Println("\n✅ Vault initialized successfully!")
-	fmt.Printf("   Location: %s\n", dbPath)
-	fmt.Printf("   Encryption: AES-256-GCM\n")
-	fmt.Printf("   Key Derivation: Argon2id (Time=%d, Memory=%dMB, Threads=%d)\n",
-		argon2Params.Time, argon2Params.Memory/1024, argon2Params.Parallelism)
-	fmt.Println("\n💡 Next steps:")
-	fmt.Println("   • Add your first password: gpasswd add")
-	fmt.Println("   • Generate a strong password: gpasswd generate")
-	fmt.Println("   • List all entries: gpasswd list")
-	fmt.Println("\n⚠️  IMPORTANT: Remember your master password!")
-	fmt.Println("   There is NO way to recover it if you forget.")
-
-	return nil
+	return salt, argon2Params, key, nil
 }
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+// jsonFlag is the global --json flag
+var jsonFlag bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Print JSON on stdout (list, show, search, generate, stats, score); secrets need --reveal")
+}
+
+// wantJSON resolves a command's --output flag together with the global
+// --json flag
+func wantJSON(format string) (bool, error) {
+	if format != "text" && format != "json" {
+		return false, fmt.Errorf("unsupported output %q (supported: text, json)", format)
+	}
+	return format == "json" || jsonFlag, nil
+}
+
+// statusOut returns where status lines go: stderr when stdout carries JSON
+func statusOut(jsonOutput bool) io.Writer {
+	if jsonOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// unlockVaultFor unlocks the vault, prompting on stderr when stdout carries
+// JSON
+func unlockVaultFor(db *storage.DB, jsonOutput bool) ([]byte, error) {
+	if jsonOutput {
+		return unlockVault(db, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	}
+	return unlockVault(db, os.Stdout)
+}
+
+// entrySummary is an entry as list and search print it with --json
+// Password and OTP are only filled in by search --reveal, Score only by
+// search
+type entrySummary struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category,omitempty"`
+	Type      string    `json:"type"`
+	Username  string    `json:"username,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Password  string    `json:"password,omitempty"`
+	OTP       string    `json:"otp,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Score     *float64  `json:"score,omitempty"`
+}
+
+// summarizeEntries converts entries for JSON output; the usernames of
+// high-security entries stay hidden unless reveal is set
+func summarizeEntries(entries []*models.Entry, reveal bool) []entrySummary {
+	summaries := make([]entrySummary, 0, len(entries))
+	for _, entry := range entries {
+		summary := entrySummary{
+			ID:        entry.ID,
+			Name:      entry.Name,
+			Category:  entry.Category,
+			Type:      models.EntryTypeLogin,
+			Username:  entry.Username,
+			URL:       entry.URL,
+			Tags:      entry.Tags,
+			CreatedAt: entry.CreatedAt,
+			UpdatedAt: entry.UpdatedAt,
+		}
+		if entry.Type != "" {
+			summary.Type = entry.Type
+		}
+		if entry.IsHighSecurity() && !reveal {
+			summary.Username = ""
+		}
+		if reveal {
+			summary.Password = entry.Password
+			summary.OTP = entry.OTP
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
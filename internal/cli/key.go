@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/keys"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Store private keys and sign, encrypt or decrypt files with them",
+	Long: `Keep private keys in the vault as key entries, encrypted like every
+other secret, and use them without ever writing them to disk.
+
+Key types:
+  age       age X25519 identity: 'key encrypt' and 'key decrypt'
+  ssh       OpenSSH key (Ed25519, ECDSA or RSA): 'key sign', checked with
+            ssh-keygen -Y verify
+  ed25519   PKCS#8 Ed25519 key: 'key sign', a base64 signature for tools
+            such as openssl
+
+'key add' takes an existing key file (passphrase-protected SSH keys are
+stored without the passphrase); 'key generate' creates a new one. Key
+entries show up in list, search and show like any other entry, with the
+public key shown and the private key hidden.
+
+Examples:
+  gpasswd key generate backup-age --type age
+  gpasswd key add deploy-ssh --file ~/.ssh/id_ed25519
+  gpasswd key public deploy-ssh >> allowed_signers
+  gpasswd key sign deploy-ssh release.tar.gz
+  gpasswd key encrypt backup-age notes.txt
+  gpasswd key decrypt backup-age notes.txt.age -o notes.txt`,
+}
+
+var keyGenerateCmd = &cobra.Command{
+	Use:   "generate <name>",
+	Short: "Generate a new key and store it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKeyGenerate,
+}
+
+var keyAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Store an existing private key (from --file or stdin)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKeyAdd,
+}
+
+var keyPublicCmd = &cobra.Command{
+	Use:   "public <name>",
+	Short: "Print the public key of a key entry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKeyPublic,
+}
+
+var keySignCmd = &cobra.Command{
+	Use:   "sign <name> <file>",
+	Short: "Sign a file (writes <file>.sig)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runKeySign,
+}
+
+var keyEncryptCmd = &cobra.Command{
+	Use:   "encrypt <name> <file>",
+	Short: "Encrypt a file to an age key (writes <file>.age)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runKeyEncrypt,
+}
+
+var keyDecryptCmd = &cobra.Command{
+	Use:   "decrypt <name> <file.age>",
+	Short: "Decrypt an age file with an age key (to stdout or -o)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runKeyDecrypt,
+}
+
+var (
+	keyType      string
+	keyComment   string
+	keyFile      string
+	keyCategory  string
+	keyNamespace string
+	keyOut       string
+)
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyGenerateCmd, keyAddCmd, keyPublicCmd, keySignCmd, keyEncryptCmd, keyDecryptCmd)
+
+	keyGenerateCmd.Flags().StringVarP(&keyType, "type", "t", keys.TypeSSH, "Key type (age, ssh, ed25519)")
+	keyGenerateCmd.Flags().StringVar(&keyComment, "comment", "", "Comment for SSH keys (default: the entry name)")
+	keyAddCmd.Flags().StringVarP(&keyFile, "file", "f", "", "Private key file (default: read stdin)")
+	for _, cmd := range []*cobra.Command{keyGenerateCmd, keyAddCmd} {
+		cmd.Flags().StringVarP(&keyCategory, "category", "c", "keys", "Category")
+	}
+	keySignCmd.Flags().StringVarP(&keyNamespace, "namespace", "n", "file", "SSH signature namespace")
+	for _, cmd := range []*cobra.Command{keySignCmd, keyEncryptCmd, keyDecryptCmd} {
+		cmd.Flags().StringVarP(&keyOut, "out", "o", "", "Output file")
+	}
+}
+
+func runKeyGenerate(cmd *cobra.Command, args []string) error {
+	kind, err := keys.ParseType(keyType)
+	if err != nil {
+		return err
+	}
+	comment := keyComment
+	if comment == "" {
+		comment = args[0]
+	}
+
+	key, err := keys.Generate(kind, comment)
+	if err != nil {
+		return err
+	}
+	return storeKey(args[0], key)
+}
+
+func runKeyAdd(cmd *cobra.Command, args []string) error {
+	var data []byte
+	var err error
+	if keyFile != "" {
+		data, err = os.ReadFile(keyFile)
+	} else {
+		if isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, "Paste the private key, then press Ctrl+D:")
+		}
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read key: %w", err)
+	}
+
+	key, err := keys.Parse(string(data))
+	if errors.Is(err, keys.ErrPassphrase) {
+		var passphrase string
+		prompt := &survey.Password{Message: "Key passphrase:"}
+		if err := askOne(prompt, &passphrase, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+			return fmt.Errorf("passphrase prompt failed: %w", err)
+		}
+		key, err = keys.ParseWithPassphrase(string(data), []byte(passphrase))
+	}
+	if err != nil {
+		return err
+	}
+	return storeKey(args[0], key)
+}
+
+// storeKey saves a key as a new key entry
+func storeKey(name string, key *keys.Key) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exists, err := db.EntryExists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("entry '%s' already exists", name)
+	}
+
+	vaultKey, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.Entry{
+		Name:      name,
+		Category:  keyCategory,
+		Password:  key.Private,
+		Type:      models.EntryTypeKey,
+		KeyType:   key.Type,
+		PublicKey: key.Public,
+	}
+	if err := db.CreateEntry(entry, vaultKey); err != nil {
+		return fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	fmt.Printf("\n✅ Key '%s' stored (%s)\n", entry.Name, entry.KeyType)
+	fmt.Println("   Public key:")
+	for _, line := range strings.Split(strings.TrimSpace(entry.PublicKey), "\n") {
+		fmt.Printf("   %s\n", line)
+	}
+	return nil
+}
+
+func runKeyPublic(cmd *cobra.Command, args []string) error {
+	entry, _, err := loadKeyEntry(args[0], "")
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimSpace(entry.PublicKey))
+	return nil
+}
+
+func runKeySign(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	entry, key, err := loadKeyEntry(args[0], "sign")
+	if err != nil {
+		return err
+	}
+	signature, err := key.Sign(data, keyNamespace)
+	if err != nil {
+		return err
+	}
+
+	out := keyOut
+	if out == "" {
+		out = args[1] + ".sig"
+	}
+	if err := atomicfile.WriteFile(out, signature, 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✍️  Signed %s with '%s'\n", args[1], entry.Name)
+	fmt.Fprintf(os.Stderr, "   Signature: %s\n", out)
+	if key.Type == keys.TypeSSH {
+		fmt.Fprintf(os.Stderr, "   Verify:    ssh-keygen -Y verify -f allowed_signers -I <identity> -n %s -s %s < %s\n", keyNamespace, out, args[1])
+	}
+	return nil
+}
+
+func runKeyEncrypt(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	entry, key, err := loadKeyEntry(args[0], "")
+	if err != nil {
+		return err
+	}
+	encrypted, err := key.Encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	out := keyOut
+	if out == "" {
+		out = args[1] + ".age"
+	}
+	if err := atomicfile.WriteFile(out, encrypted, 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "🔐 Encrypted %s to '%s': %s\n", args[1], entry.Name, out)
+	return nil
+}
+
+func runKeyDecrypt(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	entry, key, err := loadKeyEntry(args[0], "decrypt")
+	if err != nil {
+		return err
+	}
+	plaintext, err := key.Decrypt(data)
+	if err != nil {
+		return err
+	}
+
+	if keyOut == "" {
+		_, err = os.Stdout.Write(plaintext)
+		return err
+	}
+	if err := atomicfile.WriteFile(keyOut, plaintext, 0600); err != nil {
+		return fmt.Errorf("failed to write decrypted file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "🔓 Decrypted %s with '%s': %s\n", args[1], entry.Name, keyOut)
+	return nil
+}
+
+// loadKeyEntry unlocks the vault and returns a key entry with its parsed
+// key. A non-empty action marks a use of the private key, which is logged
+// and honours the entry's reveal and high-security settings
+func loadKeyEntry(name, action string) (*models.Entry, *keys.Key, error) {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	vaultKey, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry, err := db.GetEntryByName(name, vaultKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	if !entry.IsKey() {
+		return nil, nil, fmt.Errorf("entry '%s' is not a key (add one with 'gpasswd key add')", entry.Name)
+	}
+
+	if action != "" {
+		if err := useKeyEntry(db, entry, action); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	key, err := keys.Parse(entry.Password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read key '%s': %w", entry.Name, err)
+	}
+	return entry, key, nil
+}
+
+// useKeyEntry records a use of a private key after the usual checks
+func useKeyEntry(db *storage.DB, entry *models.Entry, action string) error {
+	recordAccess(db, entry, "key "+action)
+
+	if err := output.ConfirmReveal(entry, "use the private key to "+action); err != nil {
+		return err
+	}
+	return requireFreshUnlock(db, entry, os.Stderr)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// warnSizeLimits warns on out when the notes of entry or the vault are over
+// the soft limits in config.yaml; entry may be nil
+func warnSizeLimits(db *storage.DB, cfg *config.Config, entry *models.Entry, out io.Writer) {
+	if entry != nil && notesOverLimit(cfg, entry) {
+		fmt.Fprintf(out, "⚠️  The notes of '%s' take %s, over the %d KB limit (limits.notes_size_kb)\n",
+			entry.Name, formatSize(int64(len(entry.Notes))), cfg.Limits.NotesSizeKB)
+	}
+	if size, over := vaultOverLimit(db, cfg); over {
+		fmt.Fprintf(out, "⚠️  The vault takes %s, over the %d MB limit (limits.vault_size_mb); see 'gpasswd stats --largest'\n",
+			formatSize(size), cfg.Limits.VaultSizeMB)
+	}
+}
+
+// notesOverLimit reports whether an entry's notes exceed limits.notes_size_kb
+func notesOverLimit(cfg *config.Config, entry *models.Entry) bool {
+	return cfg.Limits.NotesSizeKB > 0 && len(entry.Notes) > cfg.Limits.NotesSizeKB*1024
+}
+
+// vaultOverLimit returns the vault size and whether it exceeds
+// limits.vault_size_mb
+func vaultOverLimit(db *storage.DB, cfg *config.Config) (int64, bool) {
+	size, err := db.Size()
+	if err != nil {
+		return 0, false
+	}
+	return size, cfg.Limits.VaultSizeMB > 0 && size > int64(cfg.Limits.VaultSizeMB)*1024*1024
+}
+
+// formatSize formats a byte count as B, KB or MB
+func formatSize(bytes int64) string {
+	switch {
+	case bytes < 1024:
+		return fmt.Sprintf("%d B", bytes)
+	case bytes < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	}
+}
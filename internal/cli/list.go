@@ -3,11 +3,15 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/kitsnail/gpasswd/internal/filter"
 	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
@@ -20,12 +24,30 @@ var listCmd = &cobra.Command{
 Displays entry metadata without decrypting passwords (no master password required).
 Shows: Name, Category, Username, and creation date.
 
-You can filter by category using the --category flag.
+In a vault initialized with --privacy private, categories are not stored in
+plaintext: they show as "-", and filtering by category or tag asks for the
+master password.
+
+You can filter by category using the --category flag, by regular expression
+using --match, or by shell-style glob using --glob. Patterns are matched
+against entry names and categories.
 
 Examples:
   gpasswd list
   gpasswd list --category work
-  gpasswd list -c email`,
+  gpasswd list -c email
+  gpasswd list --match 'git.*'
+  gpasswd list --glob 'aws-*'
+  gpasswd list --tag work --tag aws              # entries tagged work AND aws
+  gpasswd list --tag work --tag aws --tag-mode any
+  gpasswd list --as-of 2024-06-01
+
+--as-of lists the entries as they were at a past date, using each entry's
+history (requires the master password). Entries created later are left out;
+deleted entries take their history with them and are not shown.
+
+--json prints the entries as a JSON array on stdout instead of a table
+(names, categories, usernames, tags and timestamps; never passwords).`,
 	Aliases: []string{"ls"},
 	RunE:    runList,
 }
@@ -33,6 +55,11 @@ Examples:
 var (
 	listCategory string
 	listVerbose  bool
+	listMatch    string
+	listGlob     string
+	listTags     []string
+	listTagMode  string
+	listAsOf     string
 )
 
 func init() {
@@ -40,9 +67,34 @@ func init() {
 
 	listCmd.Flags().StringVarP(&listCategory, "category", "c", "", "Filter by category")
 	listCmd.Flags().BoolVarP(&listVerbose, "verbose", "v", false, "Show additional details")
+	listCmd.Flags().StringVar(&listMatch, "match", "", "Filter names/categories by regular expression")
+	listCmd.Flags().StringVar(&listGlob, "glob", "", "Filter names/categories by glob pattern (e.g. 'aws-*')")
+	listCmd.Flags().StringSliceVar(&listTags, "tag", []string{}, "Filter by tag (repeatable)")
+	listCmd.Flags().StringVar(&listTagMode, "tag-mode", "all", "Combine --tag filters: all (AND) or any (OR)")
+	listCmd.Flags().StringVar(&listAsOf, "as-of", "", "List entries as they were at this date (YYYY-MM-DD)")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
+	out := statusOut(jsonFlag)
+
+	// Compile pattern filters before touching the vault
+	entryFilter, err := filter.New(filter.Options{
+		Match:   listMatch,
+		Glob:    listGlob,
+		Tags:    listTags,
+		TagMode: filter.TagMode(listTagMode),
+	})
+	if err != nil {
+		return err
+	}
+
+	var asOf time.Time
+	if listAsOf != "" {
+		if asOf, err = parseAsOf(listAsOf); err != nil {
+			return err
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -67,23 +119,97 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return fmt.Errorf("failed to read privacy level: %w", err)
+	}
+
+	// Private vaults keep category and tags only in the encrypted data,
+	// so filtering on them requires the master password
+	decryptAll := level == storage.PrivacyPrivate && (listCategory != "" || entryFilter.NeedsTags())
+
+	// Past versions live only in the encrypted history
+	if listAsOf != "" {
+		decryptAll = true
+	}
+
+	// Vaults created before the tag index existed need a one-time backfill,
+	// which requires decrypting every entry
+	reindex := false
+	if entryFilter.NeedsTags() && !decryptAll {
+		indexed, err := db.TagsIndexed()
+		if err != nil {
+			return fmt.Errorf("failed to check tag index: %w", err)
+		}
+		reindex = !indexed
+	}
+
+	var key []byte
+	if decryptAll || reindex {
+		if listAsOf != "" {
+			fmt.Fprintln(out, "🕰️  Reading entry history requires the master password")
+		} else if decryptAll {
+			fmt.Fprintln(out, "🔒 Private vault: filtering by category or tag requires the master password")
+		} else {
+			fmt.Fprintln(out, "🏷️  Building tag index (one-time, requires master password)")
+		}
+
+		// Unlock the vault
+		key, err = unlockVaultFor(db, jsonFlag)
+		if err != nil {
+			return err
+		}
+
+		if reindex {
+			if err := db.ReindexTags(key); err != nil {
+				return fmt.Errorf("failed to build tag index: %w", err)
+			}
+		}
+	}
+
 	// Get entries
 	var entries []*models.Entry
-	if listCategory != "" {
+	switch {
+	case decryptAll:
+		entries, err = db.GetAllEntries(key)
+		if err != nil {
+			return fmt.Errorf("failed to load entries: %w", err)
+		}
+		if listAsOf != "" {
+			if entries, err = entriesAsOf(db, entries, asOf, key); err != nil {
+				return err
+			}
+		}
+		categoryFilter, err := filter.New(filter.Options{Category: listCategory})
+		if err != nil {
+			return err
+		}
+		entries = categoryFilter.Apply(entries)
+	case listCategory != "":
 		entries, err = db.ListEntriesByCategory(listCategory)
 		if err != nil {
 			return fmt.Errorf("failed to list entries: %w", err)
 		}
-	} else {
+	default:
 		entries, err = db.ListEntries()
 		if err != nil {
 			return fmt.Errorf("failed to list entries: %w", err)
 		}
 	}
 
+	entries = entryFilter.Apply(entries)
+
+	if jsonFlag {
+		return output.WriteJSON(os.Stdout, summarizeEntries(entries, false), output.Options{})
+	}
+
 	// Check if empty
 	if len(entries) == 0 {
-		if listCategory != "" {
+		if listAsOf != "" {
+			fmt.Printf("No entries as of %s\n", listAsOf)
+		} else if !entryFilter.IsEmpty() {
+			fmt.Println("No entries match the given filters")
+		} else if listCategory != "" {
 			fmt.Printf("No entries found in category '%s'\n", listCategory)
 		} else {
 			fmt.Println("No entries in vault")
@@ -94,7 +220,11 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Display header
-	if listCategory != "" {
+	if listAsOf != "" {
+		fmt.Printf("📋 Entries as of %s: %d\n\n", listAsOf, len(entries))
+	} else if !entryFilter.IsEmpty() {
+		fmt.Printf("📋 Matching entries: %d\n\n", len(entries))
+	} else if listCategory != "" {
 		fmt.Printf("📋 Entries in category '%s': %d\n\n", listCategory, len(entries))
 	} else {
 		fmt.Printf("📋 Total entries: %d\n\n", len(entries))
@@ -103,43 +233,56 @@ func runList(cmd *cobra.Command, args []string) error {
 	// Create table writer
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 
-	// Print header
+	// Print header (timestamps can be turned off with display.show_timestamps)
+	showTimes := cfg.Display.ShowTimestamps
+	header := []string{"NAME", "CATEGORY", "USERNAME"}
+	if showTimes {
+		header = append(header, "CREATED")
+		if listVerbose {
+			header = append(header, "UPDATED")
+		}
+	}
 	if listVerbose {
-		fmt.Fprintln(w, "NAME\tCATEGORY\tUSERNAME\tCREATED\tUPDATED\tID")
-		fmt.Fprintln(w, "----\t--------\t--------\t-------\t-------\t--")
-	} else {
-		fmt.Fprintln(w, "NAME\tCATEGORY\tUSERNAME\tCREATED")
-		fmt.Fprintln(w, "----\t--------\t--------\t-------")
+		header = append(header, "ID")
+	}
+	underline := make([]string, len(header))
+	for i, column := range header {
+		underline[i] = strings.Repeat("-", len(column))
 	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	fmt.Fprintln(w, strings.Join(underline, "\t"))
 
 	// Print entries
-	dateFormat := "2006-01-02 15:04"
-	if cfg.Display.DateFormat != "" {
-		dateFormat = cfg.Display.DateFormat
-	}
+	times := timeFormat(cfg)
 
 	for _, entry := range entries {
 		name := entry.Name
 		category := entry.Category
+		if category == "" {
+			category = "-"
+		}
 		username := entry.Username
-		if username == "" {
+		if entry.IsHighSecurity() {
+			username = "(hidden)"
+		} else if username == "" {
 			username = "-"
 		}
 
-		created := entry.CreatedAt.Format(dateFormat)
-
+		row := []string{name, category, username}
+		if showTimes {
+			row = append(row, times.Format(entry.CreatedAt))
+			if listVerbose {
+				row = append(row, times.Format(entry.UpdatedAt))
+			}
+		}
 		if listVerbose {
-			updated := entry.UpdatedAt.Format(dateFormat)
 			id := entry.ID
 			if len(id) > 8 {
 				id = id[:8] + "..."
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-				name, category, username, created, updated, id)
-		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-				name, category, username, created)
+			row = append(row, id)
 		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
 
 	w.Flush()
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show vault activity logs",
+}
+
+var logAccessCmd = &cobra.Command{
+	Use:   "access <name>",
+	Short: "Show when an entry's secrets were read",
+	Long: `Show when an entry's password or other secrets were read, and by which
+command (show, copy, run, netrc, totp).
+
+Reads are recorded in the vault itself, so this is most useful on shared
+machines to spot use you don't recognize. 'gpasswd audit' also flags entries
+read at unusual hours or unusually often.
+
+Examples:
+  gpasswd log access github
+  gpasswd log access github --limit 0`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLogAccess,
+}
+
+var logAccessLimit int
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+
+	logCmd.AddCommand(logAccessCmd)
+
+	logAccessCmd.Flags().IntVarP(&logAccessLimit, "limit", "n", 50, "Number of reads to show (0 = all)")
+}
+
+func runLogAccess(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// The access history is as sensitive as the entry, so unlock first
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	total, err := db.CountAccess(entry.ID)
+	if err != nil {
+		return err
+	}
+	events, err := db.ListAccess(entry.ID, logAccessLimit)
+	if err != nil {
+		return err
+	}
+
+	times := timeFormat(cfg)
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("📜 Access log: %s (%d read(s))\n", entry.Name, total)
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(events) == 0 {
+		fmt.Println("No reads recorded")
+		return nil
+	}
+
+	for _, event := range events {
+		// The weekday helps spot reads at unusual times
+		fmt.Printf("  %-4s %-36s %s\n", times.In(event.At).Format("Mon"), times.Detailed(event.At), event.Action)
+	}
+	if total > len(events) {
+		fmt.Printf("  ... %d older read(s) (use --limit 0 to show all)\n", total-len(events))
+	}
+
+	return nil
+}
+
+// recordAccess logs that a command read an entry's secrets
+// Logging problems are reported but never block the command
+func recordAccess(db *storage.DB, entry *models.Entry, action string) {
+	if err := db.RecordAccess(entry.ID, action); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not record access: %v\n", err)
+	}
+}
+
+// accessTimes reduces access events to read times per entry for audit
+func accessTimes(events map[string][]storage.AccessEvent) map[string][]time.Time {
+	times := make(map[string][]time.Time, len(events))
+	for entryID, reads := range events {
+		for _, event := range reads {
+			times[entryID] = append(times[entryID], event.At)
+		}
+	}
+	return times
+}
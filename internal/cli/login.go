@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/browser"
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/loginsteps"
+	"github.com/kitsnail/gpasswd/internal/otp"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login <name>",
+	Short: "Walk through an entry's login procedure",
+	Long: `Walk through the login steps stored with an entry, for sites that ask
+for the username, password and one-time code on separate pages (corporate
+SSO and the like).
+
+Each copy step puts a value on the clipboard and waits until you press
+Enter (or the clipboard timeout expires) before moving on. The clipboard is
+cleared at the end.
+
+Steps are set with --login-steps on add or edit, comma-separated:
+  user, password, url   copy the field
+  otp                   copy the current one-time code
+  wait DURATION         pause, e.g. wait 3s (up to 5m)
+  open                  open the entry URL in the browser
+  say TEXT              show an instruction and wait for Enter
+
+Entries without steps use user, password and otp (if an OTP secret is set).
+
+Examples:
+  gpasswd edit corp-sso --login-steps "open,user,say Click Next,password,otp"
+  gpasswd login corp-sso`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLogin,
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+}
+
+func runLogin(cmd *cobra.Command, args []string) error {
+	entryName := args[0]
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(entryName, key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "login")
+
+	if err := output.ConfirmReveal(entry, "copy it to the clipboard"); err != nil {
+		return err
+	}
+	if err := requireFreshUnlock(db, entry, os.Stdout); err != nil {
+		return err
+	}
+
+	steps := loginsteps.Default(entry.OTP != "")
+	if len(entry.LoginSteps) > 0 {
+		if steps, err = loginsteps.ParseAll(entry.LoginSteps); err != nil {
+			return fmt.Errorf("entry '%s' has an invalid login procedure: %w", entry.Name, err)
+		}
+	}
+
+	timeout := time.Duration(clipboardTimeout(cfg, entry, 0)) * time.Second
+	lines := stdinLines()
+	copied := false
+
+	fmt.Printf("🔑 Logging in to '%s' (%d steps)\n", entry.Name, len(steps))
+	for i, step := range steps {
+		progress := fmt.Sprintf("[%d/%d]", i+1, len(steps))
+
+		switch step.Action {
+		case loginsteps.ActionCopy, loginsteps.ActionOTP:
+			label, value := "", ""
+			if step.Action == loginsteps.ActionOTP {
+				code, otpKey, err := nextOTPCode(db, entry, key, time.Now())
+				if err != nil {
+					return err
+				}
+				label, value = "One-time code", code
+				if otpKey.Type != otp.TypeHOTP {
+					label += fmt.Sprintf(" (valid for %s)", otpKey.Remaining(time.Now()))
+				}
+			} else {
+				if value, err = entryField(entry, step.Field); err != nil {
+					return err
+				}
+				label = fieldLabel(step.Field)
+			}
+			if value == "" {
+				fmt.Printf("⏭️  %s %s is empty, skipping\n", progress, label)
+				continue
+			}
+
+			if err := clipboard.Copy(value); err != nil {
+				return fmt.Errorf("failed to copy to clipboard: %w", err)
+			}
+			copied = true
+			fmt.Printf("📋 %s %s copied. Paste it, then press Enter (auto-advance in %s)\n", progress, label, timeout)
+			waitForEnter(lines, timeout)
+
+		case loginsteps.ActionWait:
+			fmt.Printf("⏳ %s Waiting %s...\n", progress, step.Wait)
+			time.Sleep(step.Wait)
+
+		case loginsteps.ActionOpen:
+			if entry.URL == "" {
+				fmt.Printf("⏭️  %s No URL to open, skipping\n", progress)
+				continue
+			}
+			if err := browser.Open(entry.URL); err != nil {
+				fmt.Printf("⚠️  %s %v\n   Open it manually: %s\n", progress, err, entry.URL)
+			} else {
+				fmt.Printf("🌐 %s Opened %s\n", progress, entry.URL)
+			}
+
+		case loginsteps.ActionSay:
+			fmt.Printf("👉 %s %s (press Enter to continue)\n", progress, step.Text)
+			<-lines
+		}
+	}
+
+	if copied {
+		if err := clipboard.Clear(); err != nil {
+			return fmt.Errorf("failed to clear clipboard: %w", err)
+		}
+		fmt.Println("🧹 Clipboard cleared")
+	}
+	fmt.Printf("✅ Login procedure for '%s' finished\n", entry.Name)
+	return nil
+}
@@ -0,0 +1,321 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/autotype"
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var menuCmd = &cobra.Command{
+	Use:   "menu [selection]",
+	Short: "List entries for rofi, wofi, dmenu or Alfred and act on the pick",
+	Long: `List entry names in the format a launcher expects, and copy or type the
+credential of the entry the launcher hands back.
+
+Without a selection the entries are listed (no master password needed).
+With a selection the vault is unlocked and the password (or --field) is
+copied to the clipboard, which is cleared after the usual timeout, or typed
+into the focused window with --type (wtype, xdotool or ydotool).
+Launchers run without a terminal, so the master password comes from
+'gpasswd agent', a 'gpasswd unlock' session or the GPASSWD_ASKPASS program.
+
+Formats:
+  (default)       one name per line, for dmenu, wofi --dmenu, fzf
+  --rofi          rofi script mode; rofi calls back with the selection
+  --alfred-json   Alfred Script Filter JSON; the argument filters the list
+
+Examples:
+  rofi -show gpasswd -modi "gpasswd:gpasswd menu --rofi"
+  rofi -show gpasswd -modi "gpasswd:gpasswd menu --rofi --type"
+  gpasswd menu | wofi --dmenu | xargs -r -d '\n' gpasswd menu
+  gpasswd menu --alfred-json "{query}"     (Script Filter)
+  gpasswd menu --field user "{query}"      (Run Script action)`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMenu,
+}
+
+var (
+	menuRofi       bool
+	menuAlfredJSON bool
+	menuField      string
+	menuType       bool
+
+	// Used by the helper process that outlives the launcher callback
+	menuFinish      string
+	menuFinishAfter time.Duration
+)
+
+// Helper process jobs
+const (
+	menuFinishClear = "clear" // clear the clipboard
+	menuFinishType  = "type"  // type the text read from stdin
+)
+
+// menuTypeDelay gives the launcher time to close and focus to return to
+// the target window before typing starts
+const menuTypeDelay = 300 * time.Millisecond
+
+// rofiSelected is the ROFI_RETV value of a callback for a picked row
+const rofiSelected = "1"
+
+func init() {
+	rootCmd.AddCommand(menuCmd)
+
+	menuCmd.Flags().BoolVar(&menuRofi, "rofi", false, "Speak the rofi script mode protocol")
+	menuCmd.Flags().BoolVar(&menuAlfredJSON, "alfred-json", false, "List entries as Alfred Script Filter JSON")
+	menuCmd.Flags().StringVar(&menuField, "field", "password", "Field to copy or type (user, password, url, ...)")
+	menuCmd.Flags().BoolVar(&menuType, "type", false, "Type the field into the focused window instead of copying it")
+
+	menuCmd.Flags().StringVar(&menuFinish, "finish", "", "")
+	menuCmd.Flags().DurationVar(&menuFinishAfter, "finish-after", 0, "")
+	menuCmd.Flags().MarkHidden("finish")
+	menuCmd.Flags().MarkHidden("finish-after")
+}
+
+func runMenu(cmd *cobra.Command, args []string) error {
+	if menuFinish != "" {
+		return finishMenu(menuFinish, menuFinishAfter)
+	}
+	if menuRofi && menuAlfredJSON {
+		return fmt.Errorf("--rofi and --alfred-json cannot be combined")
+	}
+	if _, err := entryField(&models.Entry{}, menuField); err != nil {
+		return err
+	}
+
+	// rofi calls the script again with the picked row; ROFI_INFO carries
+	// the exact entry name set when listing
+	selection := ""
+	if len(args) == 1 {
+		selection = args[0]
+	}
+	if menuRofi {
+		if os.Getenv("ROFI_RETV") != rofiSelected {
+			selection = ""
+		} else if info := os.Getenv("ROFI_INFO"); info != "" {
+			selection = info
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	if menuAlfredJSON || selection == "" {
+		entries, err := db.ListEntries()
+		if err != nil {
+			return fmt.Errorf("failed to list entries: %w", err)
+		}
+		switch {
+		case menuAlfredJSON:
+			return writeAlfredItems(os.Stdout, entries, selection)
+		case menuRofi:
+			writeRofiRows(os.Stdout, entries)
+		default:
+			for _, entry := range entries {
+				fmt.Println(entry.Name)
+			}
+		}
+		return nil
+	}
+
+	return pickMenuEntry(db, cfg, selection)
+}
+
+// pickMenuEntry copies or types the chosen field of an entry
+// Nothing is written to stdout: rofi would show it as a new menu
+func pickMenuEntry(db *storage.DB, cfg *config.Config, name string) error {
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(name, key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "menu")
+
+	if err := output.ConfirmReveal(entry, "copy it to the clipboard"); err != nil {
+		return err
+	}
+	if err := requireFreshUnlock(db, entry, os.Stderr); err != nil {
+		return err
+	}
+
+	value, err := entryField(entry, menuField)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(menuField)))
+	}
+
+	if menuType {
+		if _, err := autotype.Tool(); err != nil {
+			return err
+		}
+		if err := startMenuHelper(menuFinishType, menuTypeDelay, value); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "⌨️  Typing %s for '%s'\n", strings.ToLower(fieldLabel(menuField)), entry.Name)
+		return nil
+	}
+
+	if err := clipboard.Copy(value); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	timeout := time.Duration(clipboardTimeout(cfg, entry, 0)) * time.Second
+	if err := startMenuHelper(menuFinishClear, timeout, ""); err != nil {
+		// Don't leave the secret behind if the clear can't be scheduled
+		clipboard.Clear()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ %s for '%s' copied to clipboard (cleared in %s)\n", fieldLabel(menuField), entry.Name, timeout)
+	return nil
+}
+
+// startMenuHelper starts a detached gpasswd that finishes the job after
+// delay, so the launcher callback can return right away. Text is handed
+// over on a pipe, never on the command line
+func startMenuHelper(job string, delay time.Duration, text string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gpasswd: %w", err)
+	}
+
+	helper := exec.Command(executable, "menu", "--finish", job, "--finish-after", delay.String())
+	detach(helper)
+	stdin, err := helper.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start helper: %w", err)
+	}
+	if err := helper.Start(); err != nil {
+		return fmt.Errorf("failed to start helper: %w", err)
+	}
+	io.WriteString(stdin, text)
+	stdin.Close()
+	return helper.Process.Release()
+}
+
+// finishMenu runs in the helper process started by startMenuHelper
+func finishMenu(job string, after time.Duration) error {
+	switch job {
+	case menuFinishClear:
+		time.Sleep(after)
+		return clipboard.Clear()
+	case menuFinishType:
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		time.Sleep(after)
+		return autotype.Type(string(text))
+	default:
+		return fmt.Errorf("unknown menu job %q", job)
+	}
+}
+
+// writeRofiRows lists entries in rofi's script mode format: one row per
+// entry, with the name as row info and the category as extra search text
+func writeRofiRows(w io.Writer, entries []*models.Entry) {
+	fmt.Fprint(w, "\x00prompt\x1fgpasswd\n")
+	fmt.Fprint(w, "\x00no-custom\x1ftrue\n")
+	for _, entry := range entries {
+		name := rofiClean(entry.Name)
+		fmt.Fprintf(w, "%s\x00info\x1f%s", name, name)
+		if entry.Category != "" {
+			fmt.Fprintf(w, "\x1fmeta\x1f%s", rofiClean(entry.Category))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// rofiClean drops the characters rofi uses as row and option separators
+func rofiClean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\x00' || r == '\x1f' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// alfredItem is one row of an Alfred Script Filter
+type alfredItem struct {
+	UID          string `json:"uid"`
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle,omitempty"`
+	Arg          string `json:"arg"`
+	Autocomplete string `json:"autocomplete"`
+	Match        string `json:"match"`
+}
+
+// writeAlfredItems writes entries whose name or category contains query
+// as Alfred Script Filter JSON
+func writeAlfredItems(w io.Writer, entries []*models.Entry, query string) error {
+	items := make([]alfredItem, 0, len(entries))
+	for _, entry := range entries {
+		match, ok := launcherMatch(entry, query)
+		if !ok {
+			continue
+		}
+		items = append(items, alfredItem{
+			UID:          entry.ID,
+			Title:        entry.Name,
+			Subtitle:     entry.Category,
+			Arg:          entry.Name,
+			Autocomplete: entry.Name,
+			Match:        match,
+		})
+	}
+	return writeScriptFilter(w, items)
+}
+
+// launcherMatch returns the text a launcher matches entry against, and
+// whether it contains query (case-insensitive; an empty query matches all)
+func launcherMatch(entry *models.Entry, query string) (string, bool) {
+	match := strings.TrimSpace(entry.Name + " " + entry.Category)
+	query = strings.ToLower(strings.TrimSpace(query))
+	return match, query == "" || strings.Contains(strings.ToLower(match), query)
+}
+
+// writeScriptFilter writes items as a Script Filter JSON document
+func writeScriptFilter(w io.Writer, items any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var netrcCmd = &cobra.Command{
+	Use:   "netrc <name>...",
+	Short: "Print netrc credentials for HTTP tools",
+	Long: `Print a netrc stanza (machine/login/password) for each entry, so tools
+like curl, git, wget and ftp can use vault credentials.
+
+The machine is the host of the entry's URL unless --machine is given.
+Prompts and messages go to stderr, so stdout can be redirected.
+
+With --curl, nothing secret is printed. Instead the credentials are served
+once through a private temporary pipe and the matching curl argument is
+printed, for use in command substitution:
+
+  curl $(gpasswd netrc github --curl) https://api.github.com/user
+
+The pipe is removed as soon as curl has read it, or after --timeout. On
+systems without named pipes a private temporary file is used instead and
+removed after --timeout.
+
+Examples:
+  gpasswd netrc github
+  gpasswd netrc github gitlab >> ~/.netrc
+  gpasswd netrc registry --machine registry.example.com
+  curl $(gpasswd netrc github --curl) https://api.github.com/user`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runNetrc,
+}
+
+var (
+	netrcMachine string
+	netrcCurl    bool
+	netrcTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(netrcCmd)
+
+	netrcCmd.Flags().StringVar(&netrcMachine, "machine", "", "Machine name to use instead of the URL host (single entry only)")
+	netrcCmd.Flags().BoolVar(&netrcCurl, "curl", false, "Serve the credentials to curl via a one-shot --netrc-file")
+	netrcCmd.Flags().DurationVar(&netrcTimeout, "timeout", time.Minute, "How long --curl waits for curl to read the credentials")
+}
+
+func runNetrc(cmd *cobra.Command, args []string) error {
+	if netrcMachine != "" && len(args) > 1 {
+		return fmt.Errorf("--machine can only be used with a single entry")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault (status goes to stderr, stdout may be captured)
+	key, err := unlockVault(db, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	if err != nil {
+		return err
+	}
+
+	var stanzas strings.Builder
+	for _, name := range args {
+		entry, err := db.GetEntryByName(name, key)
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+		recordAccess(db, entry, "netrc")
+
+		if err := requireFreshUnlock(db, entry, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+			return err
+		}
+
+		if err := output.ConfirmReveal(entry, "print the password as netrc"); err != nil {
+			return err
+		}
+
+		stanza, err := netrcStanza(entry, netrcMachine)
+		if err != nil {
+			return err
+		}
+		stanzas.WriteString(stanza)
+	}
+
+	if !netrcCurl {
+		fmt.Print(stanzas.String())
+		return nil
+	}
+
+	return serveCurlNetrc([]byte(stanzas.String()), netrcTimeout)
+}
+
+// netrcStanza formats an entry as a netrc "machine" line
+func netrcStanza(entry *models.Entry, machine string) (string, error) {
+	if machine == "" {
+		machine = storage.URLHost(entry.URL)
+	}
+	if machine == "" {
+		return "", fmt.Errorf("entry '%s' has no URL; pass --machine <host>", entry.Name)
+	}
+	if entry.Password == "" {
+		return "", fmt.Errorf("entry '%s' has no password", entry.Name)
+	}
+
+	line := "machine " + netrcQuote(machine)
+	if entry.Username != "" {
+		line += " login " + netrcQuote(entry.Username)
+	}
+	line += " password " + netrcQuote(entry.Password)
+	return line + "\n", nil
+}
+
+// netrcQuote quotes values containing whitespace or quotes
+// Quoted netrc values are understood by curl 7.84+; unquoted values are
+// passed through unchanged for other tools
+func netrcQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\n\"\\") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// serveCurlNetrc prints a --netrc-file argument and serves the credentials
+// to the first reader. Stdout is closed before waiting so that command
+// substitution completes and curl can start
+func serveCurlNetrc(content []byte, timeout time.Duration) error {
+	path, serve, cleanup, err := oneShotFile(content)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	fmt.Printf("--netrc-file %s\n", path)
+	os.Stdout.Close()
+
+	if err := serve(timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// oneShotFile writes the content to a private temporary file, since named
+// pipes are not available. The file is removed after the timeout
+func oneShotFile(content []byte) (path string, serve func(time.Duration) error, cleanup func(), err error) {
+	file, err := os.CreateTemp("", "gpasswd-netrc-")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path = file.Name()
+	cleanup = func() { os.Remove(path) }
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	serve = func(timeout time.Duration) error {
+		time.Sleep(timeout)
+		return nil
+	}
+
+	return path, serve, cleanup, nil
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// oneShotFile creates a named pipe in a private temporary directory
+// serve blocks until a reader opens the pipe (or the timeout expires) and
+// writes the content once; nothing is ever stored on disk
+func oneShotFile(content []byte) (path string, serve func(time.Duration) error, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "gpasswd-")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	path = filepath.Join(dir, "netrc")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		cleanup()
+		return "", nil, nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+
+	serve = func(timeout time.Duration) error {
+		opened := make(chan error, 1)
+		go func() {
+			// Opening a pipe for writing blocks until a reader arrives
+			pipe, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				opened <- err
+				return
+			}
+			_, err = pipe.Write(content)
+			if closeErr := pipe.Close(); err == nil {
+				err = closeErr
+			}
+			opened <- err
+		}()
+
+		select {
+		case err := <-opened:
+			return err
+		case <-time.After(timeout):
+			return errors.New("credentials were not read before the timeout; pipe removed")
+		}
+	}
+
+	return path, serve, cleanup, nil
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// errNonInteractive is returned by askOne instead of showing a prompt
+var errNonInteractive = errors.New("input needed but running non-interactively")
+
+// Global flags for scripts, see interactive and scriptedPassword
+var (
+	nonInteractive bool
+	passwordFile   string
+	passwordStdin  bool
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt; fail when input is missing (implied when stdin is not a terminal)")
+	rootCmd.PersistentFlags().StringVar(&passwordFile, "password-file", "", "Read the master password from the first line of this file")
+	rootCmd.PersistentFlags().BoolVar(&passwordStdin, "password-stdin", false, "Read the master password from the first line of stdin")
+	rootCmd.MarkFlagsMutuallyExclusive("password-file", "password-stdin")
+}
+
+// interactive reports whether prompts may be shown: not with
+// --non-interactive, and not without a terminal to answer them on
+func interactive() bool {
+	return !nonInteractive && isTerminal(os.Stdin)
+}
+
+// askOne is survey.AskOne that fails right away when prompts can't be
+// shown, instead of hanging on or misreading a pipe
+// Optional prompts whose error is ignored are simply skipped
+func askOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if !interactive() {
+		return fmt.Errorf("%w: %q; use the command's flags instead", errNonInteractive, strings.TrimSuffix(promptMessage(prompt), ":"))
+	}
+	return survey.AskOne(prompt, response, opts...)
+}
+
+// promptMessage returns the question a survey prompt asks
+func promptMessage(prompt survey.Prompt) string {
+	switch p := prompt.(type) {
+	case *survey.Input:
+		return p.Message
+	case *survey.Password:
+		return p.Message
+	case *survey.Confirm:
+		return p.Message
+	case *survey.Select:
+		return p.Message
+	case *survey.MultiSelect:
+		return p.Message
+	case *survey.Multiline:
+		return p.Message
+	case *survey.Editor:
+		return p.Message
+	default:
+		return "prompt"
+	}
+}
+
+// scripted holds the master password once read by scriptedPassword; stdin
+// can only be read once
+var scripted struct {
+	read     bool
+	password string
+	ok       bool
+	err      error
+}
+
+// scriptedPassword returns the master password given by --password-stdin,
+// --password-file or GPASSWD_MASTER_PASSWORD, in that order; ok is false
+// if none is set
+func scriptedPassword() (password string, ok bool, err error) {
+	if !scripted.read {
+		scripted.read = true
+		scripted.password, scripted.ok, scripted.err = readScriptedPassword()
+	}
+	return scripted.password, scripted.ok, scripted.err
+}
+
+func readScriptedPassword() (string, bool, error) {
+	var (
+		source string
+		in     io.Reader
+	)
+	switch {
+	case passwordStdin:
+		source, in = "stdin", os.Stdin
+	case passwordFile != "":
+		file, err := os.Open(passwordFile)
+		if err != nil {
+			return "", true, fmt.Errorf("failed to open password file: %w", err)
+		}
+		defer file.Close()
+		source, in = passwordFile, file
+	default:
+		password, ok := os.LookupEnv("GPASSWD_MASTER_PASSWORD")
+		if ok && password == "" {
+			return "", true, fmt.Errorf("GPASSWD_MASTER_PASSWORD is empty")
+		}
+		return password, ok, nil
+	}
+
+	line, err := readLine(in)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read master password from %s: %w", source, err)
+	}
+	password := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if password == "" {
+		return "", true, fmt.Errorf("no master password in %s", source)
+	}
+	return password, true, nil
+}
+
+// readLine reads up to and including the first newline of in, one byte at a
+// time so nothing after it is consumed: a note body or key piped after the
+// password on stdin is still there for the command to read
+func readLine(in io.Reader) (string, error) {
+	var (
+		line []byte
+		b    [1]byte
+	)
+	for {
+		n, err := in.Read(b[:])
+		if n == 1 {
+			line = append(line, b[0])
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+		}
+		if err == io.EOF {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+)
+
+var noteCmd = &cobra.Command{
+	Use:   "note",
+	Short: "Keep secure notes: encrypted text without a password",
+	Long: `Store text such as recovery codes, licence keys or Wi-Fi details as a
+secure note: an entry with Markdown notes and no password.
+
+The text is read from --file (- for stdin), from stdin when it is piped,
+or written in $VISUAL/$EDITOR. The editor works on a private temporary
+file (in $XDG_RUNTIME_DIR when set) that is removed right after.
+
+'note show' and 'note edit' also work on the notes of any other entry.
+Secure notes are listed, searched and exported like other entries.
+
+Examples:
+  gpasswd note add recovery-codes
+  gpasswd note add wifi-home --file wifi.md --category home
+  pbpaste | gpasswd note add licence-key
+  gpasswd note show recovery-codes
+  gpasswd note show recovery-codes --raw > codes.txt
+  gpasswd note edit recovery-codes`,
+}
+
+var noteAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a secure note",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNoteAdd,
+}
+
+var noteShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a note (rendered on a terminal)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNoteShow,
+}
+
+var noteEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit a note in $EDITOR (or replace it from --file)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNoteEdit,
+}
+
+var (
+	noteFile     string
+	noteCategory string
+	noteTags     []string
+	noteRaw      bool
+)
+
+func init() {
+	rootCmd.AddCommand(noteCmd)
+	noteCmd.AddCommand(noteAddCmd, noteShowCmd, noteEditCmd)
+
+	for _, cmd := range []*cobra.Command{noteAddCmd, noteEditCmd} {
+		cmd.Flags().StringVarP(&noteFile, "file", "f", "", "Read the note from a file (- for stdin)")
+	}
+	noteAddCmd.Flags().StringVarP(&noteCategory, "category", "c", "notes", "Category")
+	noteAddCmd.Flags().StringSliceVarP(&noteTags, "tags", "t", []string{}, "Tags (comma-separated)")
+	noteShowCmd.Flags().BoolVar(&noteRaw, "raw", false, "Print the Markdown as stored")
+}
+
+func runNoteAdd(cmd *cobra.Command, args []string) error {
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exists, err := db.EntryExists(args[0])
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("entry '%s' already exists", args[0])
+	}
+
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	text, err := readNote("")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("the note is empty; nothing saved")
+	}
+
+	entry := &models.Entry{
+		Name:     args[0],
+		Category: noteCategory,
+		Tags:     noteTags,
+		Notes:    text,
+		Type:     models.EntryTypeNote,
+	}
+	if err := db.CreateEntry(entry, key); err != nil {
+		return fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	fmt.Printf("✅ Note '%s' saved (%d line(s))\n", entry.Name, strings.Count(strings.TrimRight(text, "\n"), "\n")+1)
+	warnSizeLimits(db, cfg, entry, os.Stdout)
+	return nil
+}
+
+func runNoteShow(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	if entry.Notes == "" {
+		return fmt.Errorf("entry '%s' has no notes", entry.Name)
+	}
+	recordAccess(db, entry, "note")
+
+	if err := output.ConfirmReveal(entry, "show the note"); err != nil {
+		return err
+	}
+	if err := requireFreshUnlock(db, entry, os.Stderr); err != nil {
+		return err
+	}
+
+	if !noteRaw && isTerminal(os.Stdout) {
+		fmt.Println(output.RenderMarkdown(entry.Notes, terminalWidth(os.Stdout)))
+		return nil
+	}
+	fmt.Print(entry.Notes)
+	if !strings.HasSuffix(entry.Notes, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+func runNoteEdit(cmd *cobra.Command, args []string) error {
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	if err := requireFreshUnlock(db, entry, os.Stderr); err != nil {
+		return err
+	}
+
+	text, err := readNote(entry.Notes)
+	if err != nil {
+		return err
+	}
+	if text == entry.Notes {
+		fmt.Println("No changes")
+		return nil
+	}
+	if entry.IsNote() && strings.TrimSpace(text) == "" {
+		return fmt.Errorf("a secure note can't be empty; delete it with 'gpasswd delete %s'", entry.Name)
+	}
+
+	entry.Notes = text
+	if err := db.UpdateEntry(entry, key); err != nil {
+		return fmt.Errorf("failed to update entry: %w", err)
+	}
+	fmt.Printf("✅ Note '%s' updated\n", entry.Name)
+	warnSizeLimits(db, cfg, entry, os.Stdout)
+	return nil
+}
+
+// readNote returns the note text from --file, piped stdin or the editor,
+// which starts out with current
+func readNote(current string) (string, error) {
+	var data []byte
+	var err error
+	switch {
+	case noteFile == "-" || (noteFile == "" && !isTerminal(os.Stdin)):
+		data, err = io.ReadAll(os.Stdin)
+	case noteFile != "":
+		data, err = os.ReadFile(noteFile)
+	default:
+		return editNote(current)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read note: %w", err)
+	}
+	return string(data), nil
+}
+
+// editNote opens text in the user's editor and returns the saved result
+// The temporary file lives in a private directory that is removed after
+func editNote(text string) (string, error) {
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "gpasswd-note-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], path)...) // #nosec G204 -- the user's own editor
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the edited note: %w", err)
+	}
+	return string(edited), nil
+}
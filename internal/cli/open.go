@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/browser"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/sites"
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open <name>",
+	Short: "Open an entry's website, or its password change page",
+	Long: `Open the URL of an entry in the default browser.
+
+With --change-password the site's password change page is opened instead:
+the entry's change-password URL if set (see 'gpasswd edit --change-url'),
+the page gpasswd knows for well-known sites, or otherwise the site's
+/.well-known/change-password URL, which sites that support it redirect to
+their password change page. 'gpasswd rotate --open' uses the same page.
+
+Examples:
+  gpasswd open github
+  gpasswd open github --change-password`,
+	Args: cobra.ExactArgs(1),
+	RunE: runOpen,
+}
+
+var openChangePassword bool
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+
+	openCmd.Flags().BoolVar(&openChangePassword, "change-password", false, "Open the site's password change page")
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "open")
+
+	target := entry.URL
+	if openChangePassword {
+		if target, err = changePasswordTarget(entry); err != nil {
+			return err
+		}
+	} else if target == "" {
+		return fmt.Errorf("entry '%s' has no URL. Set one with 'gpasswd edit %s --url <url>'", entry.Name, entry.Name)
+	}
+
+	if err := browser.Open(target); err != nil {
+		return err
+	}
+	fmt.Printf("🌐 Opened %s\n", target)
+	return nil
+}
+
+// changePasswordTarget returns the page to change an entry's password on:
+// its change-password URL, the bundled page of a well-known site, or the
+// site's well-known change-password URL
+func changePasswordTarget(entry *models.Entry) (string, error) {
+	if entry.ChangePasswordURL != "" {
+		return entry.ChangePasswordURL, nil
+	}
+	if page, _ := sites.ChangePasswordPage(entry.URL); page != "" {
+		return page, nil
+	}
+	return "", fmt.Errorf("entry '%s' has no URL. Set one with 'gpasswd edit %s --change-url <url>'", entry.Name, entry.Name)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/agent"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master password",
+	Long: `Change the master password and re-encrypt the vault under it.
+
+The current master password is checked first. The new one gets a fresh
+salt and the Argon2 parameters from config.yaml (or the vault's profile),
+so this is also how a vault moves to stronger key derivation settings.
+Every entry, its search text and its whole history are re-encrypted in a
+single transaction: if anything fails, nothing changes and the old
+password keeps working.
+
+'gpasswd unlock' sessions and keys held by 'gpasswd agent' are dropped.
+Archives (.gpx) and --metadata exports made earlier still open with the
+old password only; viewer bundles have their own passphrase.
+
+Example:
+  gpasswd passwd`,
+	Args: cobra.NoArgs,
+	RunE: runPasswd,
+}
+
+func init() {
+	rootCmd.AddCommand(passwdCmd)
+}
+
+func runPasswd(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Always ask for the current password, even with a session or agent
+	fmt.Println("Enter the current master password")
+	oldKey, err := promptUnlock(db, applySecurityConfig(db), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// Resealing under the new key would accept changes made behind our back
+	if err := db.VerifyManifest(oldKey); errors.Is(err, storage.ErrTampered) {
+		return fmt.Errorf("the vault changed outside gpasswd; check it and run 'gpasswd fingerprint --reseal' before changing the master password")
+	}
+
+	// The profile's preset replaces configured Argon2 parameters
+	profile, err := db.Profile()
+	if err != nil {
+		return err
+	}
+	if preset, ok := profile.Argon2Params(); ok {
+		cfg.Security.Argon2.Time = preset.Time
+		cfg.Security.Argon2.Memory = preset.Memory
+		cfg.Security.Argon2.Parallelism = preset.Parallelism
+		cfg.Security.Argon2.KeyLength = preset.KeyLen
+	}
+
+	fmt.Println("\nChoose the new master password")
+	salt, params, newKey, err := newMasterKey(cfg, "Changing master password")
+	if err != nil || newKey == nil {
+		return err
+	}
+
+	count, err := db.CountEntries()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   • Re-encrypting %d entries and their history...\n", count)
+	if err := db.Rekey(oldKey, newKey, salt, params); err != nil {
+		return fmt.Errorf("master password not changed: %w", err)
+	}
+	unlockedKeys[db.Path()] = newKey
+
+	// Cached copies of the old key no longer open the vault
+	if err := endSession(sessionVault(db)); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not end the unlock session: %v\n", err)
+	}
+	if err := agent.Lock(agentSocket()); err != nil && !errors.Is(err, agent.ErrNoAgent) {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not lock the agent: %v\n", err)
+	}
+
+	fmt.Println("\n✅ Master password changed")
+	fmt.Println("   Sessions and agent keys were dropped; unlock again with the new password")
+	fmt.Println("   Archives and metadata exports made before still need the old password")
+	return nil
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+// Picker choices for typing a value the vault doesn't use yet
+const (
+	newCategoryOption = "➕ New category…"
+	newTagsOption     = "➕ New tags…"
+)
+
+// taxonomy holds the categories and tags the vault's entries use
+type taxonomy struct {
+	categories []string
+	tags       []string
+}
+
+// loadTaxonomy collects the categories and tags in use, sorted by name
+// Entries are decrypted so private vaults, which keep neither in
+// plaintext, offer them too. Nothing is loaded when prompts can't be shown
+func loadTaxonomy(db *storage.DB, key []byte) (*taxonomy, error) {
+	if !interactive() {
+		return &taxonomy{}, nil
+	}
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	t := &taxonomy{}
+	seenCategories := map[string]bool{}
+	seenTags := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Category != "" && !seenCategories[entry.Category] {
+			seenCategories[entry.Category] = true
+			t.categories = append(t.categories, entry.Category)
+		}
+		for _, tag := range entry.Tags {
+			// Tags match case-insensitively, as in the tag index
+			if lower := strings.ToLower(tag); !seenTags[lower] {
+				seenTags[lower] = true
+				t.tags = append(t.tags, tag)
+			}
+		}
+	}
+	sort.Strings(t.categories)
+	sort.Slice(t.tags, func(i, j int) bool {
+		return strings.ToLower(t.tags[i]) < strings.ToLower(t.tags[j])
+	})
+	return t, nil
+}
+
+// pickCategory asks for a category from those in use, or a new one
+// Without existing categories it is a plain text prompt
+func (t *taxonomy) pickCategory(message, current string) (string, error) {
+	options := append([]string{}, t.categories...)
+	if current != "" && !containsFold(options, current) {
+		options = append(options, current)
+	}
+	if len(options) == 0 {
+		var category string
+		prompt := &survey.Input{Message: message, Default: current}
+		if err := askOne(prompt, &category); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(category), nil
+	}
+
+	var choice string
+	prompt := &survey.Select{
+		Message: message,
+		Options: append(options, newCategoryOption),
+	}
+	if current != "" {
+		prompt.Default = matchFold(options, current)
+	}
+	if err := askOne(prompt, &choice); err != nil {
+		return "", err
+	}
+	if choice != newCategoryOption {
+		return choice, nil
+	}
+
+	var category string
+	input := &survey.Input{Message: "New category:"}
+	if err := askOne(input, &category, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	category = strings.TrimSpace(category)
+	// Reuse the existing spelling rather than add a near-duplicate
+	if existing := matchFold(t.categories, category); existing != "" {
+		return existing, nil
+	}
+	return category, nil
+}
+
+// pickTags asks for tags from those in use, plus any new ones
+// Without existing tags it is a plain comma-separated prompt
+func (t *taxonomy) pickTags(message string, current []string) ([]string, error) {
+	options := append([]string{}, t.tags...)
+	var defaults []string
+	for _, tag := range current {
+		if existing := matchFold(options, tag); existing != "" {
+			defaults = append(defaults, existing)
+		} else {
+			options = append(options, tag)
+			defaults = append(defaults, tag)
+		}
+	}
+	if len(options) == 0 {
+		return promptNewTags(strings.TrimSuffix(message, ":")+" (comma-separated):", nil)
+	}
+
+	var chosen []string
+	prompt := &survey.MultiSelect{
+		Message: message,
+		Options: append(options, newTagsOption),
+		Default: defaults,
+	}
+	if err := askOne(prompt, &chosen); err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	addNew := false
+	for _, tag := range chosen {
+		if tag == newTagsOption {
+			addNew = true
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if !addNew {
+		return tags, nil
+	}
+	return promptNewTags("New tags (comma-separated):", tags)
+}
+
+// promptNewTags asks for comma-separated tags and adds them to tags,
+// skipping ones already there
+func promptNewTags(message string, tags []string) ([]string, error) {
+	var input string
+	prompt := &survey.Input{Message: message}
+	if err := askOne(prompt, &input); err != nil {
+		return nil, err
+	}
+	for _, tag := range strings.Split(input, ",") {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" && !containsFold(tags, trimmed) {
+			tags = append(tags, trimmed)
+		}
+	}
+	return tags, nil
+}
+
+// matchFold returns the value in values equal to s ignoring case, or ""
+func matchFold(values []string, s string) string {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return value
+		}
+	}
+	return ""
+}
+
+func containsFold(values []string, s string) bool {
+	return matchFold(values, s) != ""
+}
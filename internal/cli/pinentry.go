@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/pinentry"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// pinentryName is the name gpasswd answers to as gpg-agent's pinentry
+const pinentryName = "pinentry-gpasswd"
+
+var pinentryCmd = &cobra.Command{
+	Use:   "pinentry",
+	Short: "Give gpg-agent GPG key passphrases stored in the vault",
+	Long: `Act as gpg-agent's pinentry program, so unlocking gpasswd also unlocks
+gpg signing and decryption: one master password for both.
+
+'pinentry add' stores the passphrase of a GPG key in an entry and binds the
+key (with its subkeys) to it. 'pinentry install' links pinentry-gpasswd to
+gpasswd in the config directory and makes it gpg-agent's pinentry-program.
+
+When gpg-agent asks for a bound key's passphrase, it is taken from the
+vault: silently while a 'gpasswd unlock' session or 'gpasswd agent' holds
+the key, otherwise after the master password is asked in the real pinentry
+(which also hands the key to a running agent). Everything else goes to the
+real pinentry unchanged: keys without a stored passphrase, choosing a new
+passphrase, confirmations, and a retry after gpg rejected the stored one.
+The real pinentry is pinentry.fallback in config.yaml, or the first one
+found on PATH.
+
+Examples:
+  gpasswd pinentry add gpg-signing --key 0xA1B2C3D4E5F60708
+  gpasswd pinentry install
+  gpasswd pinentry remove gpg-signing
+  gpasswd pinentry uninstall`,
+}
+
+var pinentryServeCmd = &cobra.Command{
+	Use:                "serve",
+	Short:              "Speak the pinentry protocol on stdin/stdout (run by gpg-agent)",
+	DisableFlagParsing: true, // gpg-agent passes pinentry options such as --display
+	RunE:               runPinentryServe,
+}
+
+var pinentryAddCmd = &cobra.Command{
+	Use:   "add <name> --key KEYID",
+	Short: "Store a GPG key passphrase in an entry (or use an existing one)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPinentryAdd,
+}
+
+var pinentryRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Stop answering for the GPG keys bound to an entry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPinentryRemove,
+}
+
+var pinentryInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Make pinentry-gpasswd gpg-agent's pinentry program",
+	Args:  cobra.NoArgs,
+	RunE:  runPinentryInstall,
+}
+
+var pinentryUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Restore gpg-agent's previous pinentry program",
+	Args:  cobra.NoArgs,
+	RunE:  runPinentryUninstall,
+}
+
+var pinentryKey string
+
+func init() {
+	rootCmd.AddCommand(pinentryCmd)
+	pinentryCmd.AddCommand(pinentryServeCmd, pinentryAddCmd, pinentryRemoveCmd, pinentryInstallCmd, pinentryUninstallCmd)
+
+	pinentryAddCmd.Flags().StringVarP(&pinentryKey, "key", "k", "", "GPG key ID, fingerprint or user ID (required)")
+}
+
+// pinentryArgs maps an invocation as pinentry-gpasswd to 'pinentry serve'
+func pinentryArgs(args []string) ([]string, bool) {
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	if name != pinentryName {
+		return nil, false
+	}
+	return append([]string{"pinentry", "serve"}, args[1:]...), true
+}
+
+func runPinentryServe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	fallback := cfg.Pinentry.Fallback
+	if fallback == "" {
+		self, _ := os.Executable()
+		if resolved, err := filepath.EvalSymlinks(self); err == nil {
+			self = resolved
+		}
+		fallback = pinentry.FindFallback(self)
+	}
+
+	server := &pinentry.Server{
+		Fallback: fallback,
+		Version:  Version,
+		GetPIN: func(s *pinentry.Settings) (string, bool, error) {
+			return pinentryPassphrase(s, fallback)
+		},
+	}
+	return server.Serve(os.Stdin, os.Stdout)
+}
+
+// pinentryPassphrase looks up the stored passphrase of the key gpg-agent
+// asks for. ok is false when the prompt is left to the fallback pinentry
+// stdout carries the protocol, so nothing may be printed there
+func pinentryPassphrase(s *pinentry.Settings, fallback string) (string, bool, error) {
+	// A new passphrase is the user's to choose, and after a rejected
+	// passphrase asking again would only repeat it
+	if s.Repeat || s.Error != "" || s.Keygrip() == "" {
+		return "", false, nil
+	}
+
+	db, _, err := openVaultDB()
+	if err != nil {
+		return "", false, err
+	}
+	defer db.Close()
+
+	if fallback != "" {
+		text := "Enter the gpasswd master password to use the passphrase stored for this key."
+		if s.Description != "" {
+			text += "\n\n" + s.Description
+		}
+		askpassHook = masterPasswordPinentry(fallback, text)
+		defer func() { askpassHook = nil }()
+	}
+
+	// A private vault keeps the bindings encrypted, so every key asks for
+	// the master password there
+	if err := unlockIfPrivate(db, io.Discard); err != nil {
+		return "", false, fmt.Errorf("vault not unlocked, asking for the passphrase instead: %w", err)
+	}
+	entryID, err := db.GPGKeyEntry(s.Keygrip())
+	if err != nil || entryID == "" {
+		return "", false, err
+	}
+
+	key, err := unlockVault(db, io.Discard)
+	if err != nil {
+		return "", false, fmt.Errorf("vault not unlocked, asking for the passphrase instead: %w", err)
+	}
+
+	entry, err := db.GetEntry(entryID, key)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get the passphrase entry: %w", err)
+	}
+	recordAccess(db, entry, "pinentry")
+	if err := requireFreshUnlock(db, entry, io.Discard); err != nil {
+		return "", false, err
+	}
+	return entry.Password, true, nil
+}
+
+// masterPasswordPinentry asks for the master password in the fallback
+// pinentry; text says what it is needed for
+func masterPasswordPinentry(fallback, text string) func(string) (string, error) {
+	attempts := 0
+	return func(string) (string, error) {
+		client, err := pinentry.Start(fallback)
+		if err != nil {
+			return "", err
+		}
+		defer client.Close()
+
+		var errorText string
+		if attempts > 0 {
+			errorText = "Incorrect master password"
+		}
+		attempts++
+
+		return client.GetPIN("gpasswd", text, "Master password:", errorText)
+	}
+}
+
+func runPinentryAdd(cmd *cobra.Command, args []string) error {
+	if pinentryKey == "" {
+		return fmt.Errorf("--key is required (a GPG key ID, fingerprint or user ID)")
+	}
+	grips, err := pinentry.Keygrips(pinentryKey)
+	if err != nil {
+		return err
+	}
+
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	exists, err := db.EntryExists(args[0])
+	if err != nil {
+		return err
+	}
+	var entry *models.Entry
+	if exists {
+		entry, err = db.GetEntryByName(args[0], key)
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+	} else {
+		entry, err = createPassphraseEntry(db, key, args[0])
+		if err != nil {
+			return err
+		}
+	}
+	if entry.Password == "" {
+		return fmt.Errorf("entry '%s' has no password to use as the passphrase", entry.Name)
+	}
+
+	bindings := make([]storage.GPGKey, len(grips))
+	for i, grip := range grips {
+		bindings[i] = storage.GPGKey{Keygrip: grip, KeyID: pinentryKey, EntryID: entry.ID}
+	}
+	if err := db.SaveGPGKeys(bindings); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ gpg-agent gets the passphrase of %s from '%s' (%d keygrip(s))\n", pinentryKey, entry.Name, len(grips))
+	fmt.Println("   Install the pinentry once with: gpasswd pinentry install")
+	return nil
+}
+
+// createPassphraseEntry asks for a GPG key passphrase and stores it
+func createPassphraseEntry(db *storage.DB, key []byte, name string) (*models.Entry, error) {
+	var passphrase string
+	prompt := &survey.Password{Message: fmt.Sprintf("Passphrase of GPG key %s:", pinentryKey)}
+	if err := askOne(prompt, &passphrase, survey.WithValidator(survey.Required)); err != nil {
+		return nil, fmt.Errorf("passphrase prompt failed: %w", err)
+	}
+
+	entry := &models.Entry{
+		Name:     name,
+		Category: "gpg",
+		Password: passphrase,
+		Notes:    fmt.Sprintf("Passphrase of GPG key %s, given to gpg-agent by pinentry-gpasswd", pinentryKey),
+	}
+	if err := db.CreateEntry(entry, key); err != nil {
+		return nil, fmt.Errorf("failed to create entry: %w", err)
+	}
+	fmt.Printf("✅ Entry '%s' created\n", entry.Name)
+	return entry, nil
+}
+
+func runPinentryRemove(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	removed, err := db.RemoveGPGKeys(entry.ID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("no GPG keys are bound to '%s'", entry.Name)
+	}
+	fmt.Printf("✅ Removed %d key binding(s) from '%s'; the entry is kept\n", removed, entry.Name)
+	return nil
+}
+
+func runPinentryInstall(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gpasswd: %w", err)
+	}
+
+	link := pinentryLink()
+	os.Remove(link)
+	if err := os.Symlink(executable, link); err != nil {
+		return fmt.Errorf("failed to create %s: %w", link, err)
+	}
+
+	conf, err := pinentry.AgentConf()
+	if err != nil {
+		return err
+	}
+	previous, err := pinentry.Install(conf, link)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ gpg-agent now asks %s for passphrases\n", link)
+	fmt.Printf("   Updated %s\n", conf)
+	if previous != "" {
+		fmt.Printf("   The previous pinentry-program (%s) is commented out\n", previous)
+		fmt.Println("   Set pinentry.fallback in config.yaml to keep using it for other prompts")
+	}
+	if err := pinentry.Reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; restart gpg-agent to apply\n", err)
+	}
+	return nil
+}
+
+func runPinentryUninstall(cmd *cobra.Command, args []string) error {
+	conf, err := pinentry.AgentConf()
+	if err != nil {
+		return err
+	}
+	link := pinentryLink()
+	changed, err := pinentry.Uninstall(conf, link)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", link, err)
+	}
+
+	if !changed {
+		fmt.Println("pinentry-gpasswd was not installed")
+		return nil
+	}
+	fmt.Printf("✅ Restored %s\n", conf)
+	if err := pinentry.Reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; restart gpg-agent to apply\n", err)
+	}
+	return nil
+}
+
+// pinentryLink is where install links pinentry-gpasswd to gpasswd
+func pinentryLink() string {
+	return filepath.Join(config.GetConfigDir(), pinentryName)
+}
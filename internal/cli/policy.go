@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// passwordPolicy builds the strength policy from configuration
+func passwordPolicy(cfg *config.Config) (crypto.Policy, error) {
+	denylist := crypto.NewDenylist(cfg.Policy.Denylist...)
+
+	if cfg.Policy.DenylistFile != "" {
+		path := cfg.Policy.DenylistFile
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return crypto.Policy{}, fmt.Errorf("failed to get home directory: %w", err)
+			}
+			path = filepath.Join(home, path[2:])
+		}
+
+		fromFile, err := crypto.LoadDenylistFile(path)
+		if err != nil {
+			return crypto.Policy{}, err
+		}
+		for password := range fromFile {
+			denylist.Add(password)
+		}
+	}
+
+	return crypto.Policy{
+		MinScore:   cfg.Policy.MinScore,
+		MinEntropy: cfg.Policy.MinEntropy,
+		Denylist:   denylist,
+	}, nil
+}
+
+// exportPolicy builds the stricter policy for export passphrases
+// The denylists still apply; only the minimum score differs
+func exportPolicy(cfg *config.Config) (crypto.Policy, error) {
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return crypto.Policy{}, err
+	}
+	policy.MinScore = cfg.Policy.ExportMinScore
+	policy.MinEntropy = 0
+	return policy, nil
+}
+
+// enforcePolicy rejects a password that fails the policy unless allowWeak is set
+func enforcePolicy(policy crypto.Policy, password string, allowWeak bool) error {
+	err := policy.Check(password)
+	if err == nil {
+		return nil
+	}
+	if allowWeak {
+		fmt.Printf("  ⚠️  %v (allowed by --allow-weak)\n", err)
+		return nil
+	}
+	return fmt.Errorf("%w. Use --allow-weak to store it anyway", err)
+}
+
+// policyValidator re-prompts for passwords that fail the policy
+func policyValidator(policy crypto.Policy, allowWeak bool) survey.Validator {
+	return func(ans interface{}) error {
+		password, _ := ans.(string)
+		if allowWeak {
+			return nil
+		}
+		return policy.Check(password)
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/agent"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview <name>",
+	Short: "Show an entry's details and notes, never its password",
+	Long: `Show the metadata and notes of an entry, for pickers and previews.
+
+Notes and metadata are encrypted under a fields key derived from the vault
+key, separately from passwords, OTP secrets and private keys. A running
+'gpasswd agent' hands out only the fields key to preview, so the password
+can't be decrypted by this command at all. Without an agent the master
+password is asked and the fields key derived from it; --no-unlock fails
+instead, which suits previews that can't prompt.
+
+High-security entries keep their notes under the vault key and are not
+previewed. Notes of entries saved by older versions move under the fields
+key the next time the vault is unlocked with the master password.
+
+Examples:
+  gpasswd preview github
+  gpasswd menu | fzf --preview 'gpasswd preview --no-unlock {}'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPreview,
+}
+
+var previewNoUnlock bool
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+
+	previewCmd.Flags().BoolVar(&previewNoUnlock, "no-unlock", false, "Fail instead of asking for the master password when no agent holds the key")
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fieldsKey, err := previewKey(db)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryFieldsByName(args[0], fieldsKey)
+	if errors.Is(err, storage.ErrFieldsNotDisclosed) {
+		return fmt.Errorf("'%s' can't be previewed: %w (gpasswd show %s)", args[0], err, args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	fmt.Printf("📝 %s\n", entry.Name)
+	fmt.Printf("Category:    %s\n", entry.Category)
+	switch {
+	case entry.IsToken():
+		fmt.Println("Type:        token")
+		if entry.Service != "" {
+			fmt.Printf("Service:     %s\n", entry.Service)
+		}
+	case entry.IsKey():
+		fmt.Printf("Type:        key (%s)\n", entry.KeyType)
+	case entry.IsNote():
+		fmt.Println("Type:        secure note")
+	}
+	if entry.Username != "" {
+		fmt.Printf("Username:    %s\n", entry.Username)
+	}
+	if entry.URL != "" {
+		fmt.Printf("URL:         %s\n", entry.URL)
+	}
+	if entry.IsToken() || entry.ExpiresAt != nil {
+		fmt.Printf("Expires:     %s\n", formatExpiry(entry.ExpiresAt, time.Now()))
+	}
+	if len(entry.Tags) > 0 {
+		fmt.Printf("Tags:        %s\n", strings.Join(entry.Tags, ", "))
+	}
+	fmt.Printf("Updated:     %s\n", timeFormat(cfg).Format(entry.UpdatedAt))
+
+	if entry.Notes != "" {
+		fmt.Println()
+		if isTerminal(os.Stdout) {
+			fmt.Println(output.RenderMarkdown(entry.Notes, terminalWidth(os.Stdout)))
+		} else {
+			fmt.Println(strings.TrimRight(entry.Notes, "\n"))
+		}
+	}
+	return nil
+}
+
+// previewKey returns the fields key of the vault: from the agent when it
+// holds the vault, so the vault key never reaches this process, otherwise
+// derived after unlocking with a session or the master password; the agent
+// is never asked for the vault key itself
+func previewKey(db *storage.DB) ([]byte, error) {
+	if key, err := agent.GetFields(agentSocket(), sessionVault(db)); err == nil {
+		return key, nil
+	}
+	if previewNoUnlock {
+		return nil, fmt.Errorf("vault not unlocked in the agent (start one with 'gpasswd agent start')")
+	}
+
+	key, err := unlockWithoutAgent(db, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FieldsKey(key), nil
+}
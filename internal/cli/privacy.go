@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var privacyCmd = &cobra.Command{
+	Use:   "privacy [open|standard|private]",
+	Short: "Show or change which metadata is stored in plaintext",
+	Long: `Show or change the vault's privacy level.
+
+Every field is always stored in the encrypted entry data. The privacy level
+decides which metadata is also kept in plaintext, so it can be listed and
+filtered without the master password:
+
+  open        name, category, tags and URL host
+  standard    name, category and tags (default)
+  private     name only; category and tag filters need the master password
+
+A private vault also encrypts the lists kept next to the entries: approved
+browser origins, shared categories, GPG key bindings, the rotation plan and
+import conflicts. Commands reading them then ask for the master password.
+
+Changing the level decrypts every entry and rewrites its plaintext columns,
+so it requires the master password.
+
+Examples:
+  gpasswd privacy
+  gpasswd privacy private`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrivacy,
+}
+
+func init() {
+	rootCmd.AddCommand(privacyCmd)
+}
+
+func runPrivacy(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	current, err := db.PrivacyLevel()
+	if err != nil {
+		return fmt.Errorf("failed to read privacy level: %w", err)
+	}
+
+	if len(args) == 0 {
+		fmt.Printf("🔒 Privacy level: %s\n", current)
+		fmt.Printf("   %s\n", current.Description())
+		return nil
+	}
+
+	level, err := storage.ParsePrivacyLevel(args[0])
+	if err != nil {
+		return err
+	}
+
+	if level == current {
+		fmt.Printf("✓ Privacy level is already %s\n", level)
+		return nil
+	}
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if err := db.SetPrivacyLevel(level, key); err != nil {
+		return fmt.Errorf("failed to change privacy level: %w", err)
+	}
+
+	fmt.Printf("✅ Privacy level changed from %s to %s\n", current, level)
+	fmt.Printf("   %s\n", level.Description())
+	if level == storage.PrivacyPrivate {
+		fmt.Println("⚠️  Earlier database backups may still contain plaintext categories and tags")
+	}
+
+	return nil
+}
+
+// unlockIfPrivate unlocks the vault when it is private, so the metadata it
+// keeps encrypted (see storage.ErrMetadataLocked) can be read and written
+func unlockIfPrivate(db *storage.DB, out io.Writer) error {
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return fmt.Errorf("failed to read privacy level: %w", err)
+	}
+	if level != storage.PrivacyPrivate {
+		return nil
+	}
+	_, err = unlockVault(db, out)
+	return err
+}
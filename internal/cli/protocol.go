@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/deeplink"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var protocolHandlerCmd = &cobra.Command{
+	Use:   "protocol-handler",
+	Short: "Open gpasswd:// links from browsers, docs and launchers",
+	Long: `Register gpasswd as the handler for gpasswd:// links, so clicking a link
+such as gpasswd://copy/github copies that entry's password.
+
+Links:
+  gpasswd://copy/NAME              copy the password
+  gpasswd://copy/NAME?field=user   copy another field (user, url, ...)
+
+A link only names an entry. Opening one always asks for the master
+password, even while the vault is unlocked, so a web page cannot copy a
+secret without you noticing. Links are opened without a terminal, so the
+password is asked with the GPASSWD_ASKPASS program (or SSH_ASKPASS under a
+graphical session).
+
+Registration is supported on Linux and other XDG desktops (a desktop entry
+plus xdg-mime) and on Windows (the current user's registry).
+
+Examples:
+  gpasswd protocol-handler install
+  gpasswd protocol-handler open gpasswd://copy/github
+  gpasswd protocol-handler uninstall`,
+}
+
+var protocolInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Register gpasswd as the gpasswd:// handler",
+	Args:  cobra.NoArgs,
+	RunE:  runProtocolInstall,
+}
+
+var protocolUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the gpasswd:// handler registration",
+	Args:  cobra.NoArgs,
+	RunE:  runProtocolUninstall,
+}
+
+var protocolOpenCmd = &cobra.Command{
+	Use:   "open <link>",
+	Short: "Carry out a gpasswd:// link (run by the system when one is clicked)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProtocolOpen,
+}
+
+func init() {
+	rootCmd.AddCommand(protocolHandlerCmd)
+	protocolHandlerCmd.AddCommand(protocolInstallCmd, protocolUninstallCmd, protocolOpenCmd)
+}
+
+func runProtocolInstall(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gpasswd: %w", err)
+	}
+
+	if err := deeplink.Register(executable); err != nil {
+		if errors.Is(err, deeplink.ErrUnsupported) {
+			return fmt.Errorf("%w; launchers can run 'gpasswd protocol-handler open LINK' directly", err)
+		}
+		return err
+	}
+
+	fmt.Printf("✅ gpasswd now opens %s:// links\n", deeplink.Scheme)
+	if askpassProgram() == "" {
+		fmt.Println("⚠️  No askpass program found; set GPASSWD_ASKPASS so links can ask for the master password")
+	}
+	return nil
+}
+
+func runProtocolUninstall(cmd *cobra.Command, args []string) error {
+	if err := deeplink.Unregister(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ gpasswd no longer opens %s:// links\n", deeplink.Scheme)
+	return nil
+}
+
+func runProtocolOpen(cmd *cobra.Command, args []string) error {
+	link, err := deeplink.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	field := link.Field
+	if field == "" {
+		field = "password"
+	}
+	if _, err := entryField(&models.Entry{}, field); err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Always ask: the link may come from a page that should not be able to
+	// read secrets through an unlocked agent or session
+	askpassPrompt = fmt.Sprintf("gpasswd: a link wants to copy the %s of '%s'. Master password:",
+		strings.ToLower(fieldLabel(field)), link.Entry)
+	key, err := promptUnlock(db, applySecurityConfig(db), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(link.Entry, key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "link")
+
+	if err := output.ConfirmReveal(entry, "copy it to the clipboard"); err != nil {
+		return err
+	}
+
+	value, err := entryField(entry, field)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(field)))
+	}
+
+	timeout := clipboardTimeout(cfg, entry, 0)
+	done, err := clipboard.CopyWithAutoClear(value, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	fmt.Printf("✅ %s for '%s' copied to clipboard (cleared in %d seconds)\n", fieldLabel(field), entry.Name, timeout)
+
+	// Stay alive until the clipboard is cleared
+	<-done
+	return nil
+}
@@ -3,8 +3,11 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/platform"
 )
 
 var (
@@ -25,6 +28,15 @@ All data is stored locally - no cloud, no sync, full control.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
+	// gpg-agent runs its pinentry-program without arguments to choose from
+	if args, ok := pinentryArgs(os.Args); ok {
+		rootCmd.SetArgs(args)
+	}
+	// Browsers run the native messaging host with the extension's origin
+	if args, ok := browserHostArgs(os.Args); ok {
+		rootCmd.SetArgs(args)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -33,4 +45,11 @@ func Execute() {
 
 func init() {
 	// Global flags can be defined here
+
+	// Termux has no writable /tmp, and TMPDIR is missing when gpasswd is
+	// started outside a Termux shell (widgets, shortcuts); keep temporary
+	// files under the Termux prefix
+	if prefix := platform.TermuxPrefix(); prefix != "" && os.Getenv("TMPDIR") == "" {
+		os.Setenv("TMPDIR", filepath.Join(prefix, "tmp"))
+	}
 }
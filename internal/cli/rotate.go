@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/browser"
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/rotation"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate [name]",
+	Short: "Replace an entry's password with a new generated one",
+	Long: `Rotate the password of an entry.
+
+A new password that meets the strength policy is generated. With --open the
+rotation is guided through the site's change-password page:
+
+1. The OLD password is copied to the clipboard (most sites ask for it)
+2. The change-password URL is opened in your browser
+3. After pressing Enter, the NEW password is copied to the clipboard
+4. Once you confirm the site accepted it, the new password is saved
+
+The change-password URL is the entry's change-password URL if set (see
+'gpasswd edit --change-url'), otherwise the page gpasswd knows for
+well-known sites, or the site's /.well-known/change-password URL (see
+'gpasswd open --change-password'). The old password is kept in the
+entry's history, so nothing is lost if the site change fails.
+
+With --plan, every entry matching --filter is listed and you step through
+them one by one (rotate, skip or quit). Entries with a URL are rotated with
+the guided --open flow. Progress is saved in the vault, so running
+'gpasswd rotate --plan' again resumes where you left off; use --restart to
+build a fresh plan. Password age counts from the last password change, not
+from other edits. Filter units: h, d, w, m (30 days), y (365 days).
+
+Examples:
+  gpasswd rotate github
+  gpasswd rotate github --open
+  gpasswd rotate github --open --length 32
+  gpasswd rotate --plan
+  gpasswd rotate --plan --filter 'age > 180d'
+  gpasswd rotate --plan --restart`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if rotatePlan {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runRotate,
+}
+
+var (
+	rotateOpen    bool
+	rotateLength  int
+	rotatePlan    bool
+	rotateFilter  string
+	rotateRestart bool
+)
+
+func init() {
+	rootCmd.AddCommand(rotateCmd)
+
+	rotateCmd.Flags().BoolVar(&rotateOpen, "open", false, "Open the site's change-password page and guide the change")
+	rotateCmd.Flags().IntVarP(&rotateLength, "length", "l", 0, "Length of the new password (0 = use config default)")
+	rotateCmd.Flags().BoolVar(&rotatePlan, "plan", false, "Step through all entries due for rotation")
+	rotateCmd.Flags().StringVar(&rotateFilter, "filter", "age > 365d", "Which entries are due, e.g. 'age > 365d' (with --plan)")
+	rotateCmd.Flags().BoolVar(&rotateRestart, "restart", false, "Discard saved progress and build a new plan (with --plan)")
+}
+
+func runRotate(cmd *cobra.Command, args []string) error {
+	var condition rotation.Condition
+	if rotatePlan {
+		var err error
+		if condition, err = rotation.ParseCondition(rotateFilter); err != nil {
+			return err
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if rotatePlan {
+		return runRotationPlan(cmd, db, key, cfg, policy, condition)
+	}
+
+	// Get entry by name
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	_, err = rotateEntry(db, key, entry, cfg, policy, rotateOpen)
+	return err
+}
+
+// runRotationPlan steps through the entries due for rotation, saving
+// progress after every step so an interrupted session can be resumed
+func runRotationPlan(cmd *cobra.Command, db *storage.DB, key []byte, cfg *config.Config,
+	policy crypto.Policy, condition rotation.Condition) error {
+
+	plan, err := db.LoadRotationPlan()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case plan == nil || plan.Next() < 0 || rotateRestart:
+		plan = nil
+	case cmd.Flags().Changed("filter") && plan.Filter != rotateFilter:
+		fmt.Printf("ℹ️  Saved plan used filter '%s', building a new plan\n", plan.Filter)
+		plan = nil
+	}
+
+	if plan == nil {
+		plan, err = buildRotationPlan(db, key, condition)
+		if err != nil {
+			return err
+		}
+		if len(plan.Items) == 0 {
+			fmt.Printf("✅ No entries match '%s', nothing to rotate\n", rotateFilter)
+			return db.ClearRotationPlan()
+		}
+		if err := db.SaveRotationPlan(plan); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("▶️  Resuming rotation plan from %s (filter: '%s', %d of %d handled)\n",
+			timeFormat(cfg).Detailed(plan.CreatedAt), plan.Filter,
+			len(plan.Items)-plan.Count(rotation.StatusPending), len(plan.Items))
+	}
+
+	for i := plan.Next(); i >= 0; i = plan.Next() {
+		item := &plan.Items[i]
+
+		entry, err := db.GetEntry(item.EntryID, key)
+		if err != nil {
+			fmt.Printf("⚠️  '%s' no longer exists, skipping\n", item.EntryName)
+			item.Status = rotation.StatusSkipped
+			if err := db.SaveRotationPlan(plan); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(plan.Items), entry.Name)
+
+		var action string
+		prompt := &survey.Select{
+			Message: "What do you want to do?",
+			Options: []string{"Rotate", "Skip", "Quit (resume later)"},
+		}
+		if err := askOne(prompt, &action); err != nil {
+			return fmt.Errorf("selection failed: %w", err)
+		}
+
+		switch action {
+		case "Rotate":
+			open := rotateOpen || entry.ChangePasswordURL != "" || entry.URL != ""
+			rotated, err := rotateEntry(db, key, entry, cfg, policy, open)
+			if err != nil {
+				return err
+			}
+			if rotated {
+				item.Status = rotation.StatusDone
+			} else {
+				item.Status = rotation.StatusSkipped
+			}
+		case "Skip":
+			item.Status = rotation.StatusSkipped
+		default:
+			fmt.Printf("\n⏸️  Progress saved: %d of %d remaining. Run 'gpasswd rotate --plan' to resume\n",
+				plan.Count(rotation.StatusPending), len(plan.Items))
+			return nil
+		}
+
+		if err := db.SaveRotationPlan(plan); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\n🎉 Rotation plan complete: %d rotated, %d skipped\n",
+		plan.Count(rotation.StatusDone), plan.Count(rotation.StatusSkipped))
+	return db.ClearRotationPlan()
+}
+
+// buildRotationPlan lists the entries whose password age matches the
+// condition, oldest password first
+func buildRotationPlan(db *storage.DB, key []byte, condition rotation.Condition) (*rotation.Plan, error) {
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %w", err)
+	}
+
+	type dueEntry struct {
+		entry *models.Entry
+		age   time.Duration
+	}
+
+	now := time.Now()
+	var due []dueEntry
+	for _, entry := range entries {
+		// Tokens are reissued by their service and keys replaced by their
+		// owner, not generated here
+		if entry.Password == "" || entry.IsToken() || entry.IsKey() {
+			continue
+		}
+		changedAt, err := db.PasswordChangedAt(entry, key)
+		if err != nil {
+			return nil, err
+		}
+		if age := now.Sub(changedAt); condition.Matches(age) {
+			due = append(due, dueEntry{entry: entry, age: age})
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].age > due[j].age })
+
+	plan := &rotation.Plan{Filter: rotateFilter, CreatedAt: now}
+	if len(due) > 0 {
+		fmt.Printf("📋 %d entries due for rotation (%s):\n\n", len(due), rotateFilter)
+	}
+	for _, d := range due {
+		fmt.Printf("  %-30s %s\n", d.entry.Name, rotation.FormatAge(d.age))
+		plan.Items = append(plan.Items, rotation.Item{
+			EntryID:   d.entry.ID,
+			EntryName: d.entry.Name,
+			Status:    rotation.StatusPending,
+		})
+	}
+
+	return plan, nil
+}
+
+// rotateEntry generates a new password for an entry and saves it once the
+// user confirms. Reports whether the entry was updated
+func rotateEntry(db *storage.DB, key []byte, entry *models.Entry, cfg *config.Config,
+	policy crypto.Policy, open bool) (bool, error) {
+
+	if entry.IsToken() {
+		return false, fmt.Errorf("'%s' is a token issued by its service. Store the new value with 'gpasswd edit %s --password <token> --expires <date>'", entry.Name, entry.Name)
+	}
+
+	length := rotateLength
+	if length == 0 {
+		length = cfg.PasswordGenerator.Length
+	}
+	if length == 0 {
+		length = 20
+	}
+
+	options := crypto.GenerateOptions{
+		UseUppercase:     cfg.PasswordGenerator.UseUppercase,
+		UseLowercase:     cfg.PasswordGenerator.UseLowercase,
+		UseDigits:        cfg.PasswordGenerator.UseDigits,
+		UseSymbols:       cfg.PasswordGenerator.UseSymbols,
+		ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+	}
+
+	newPassword, err := crypto.GenerateCompliant(length, options, policy)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("\n🔄 Rotating password for '%s'\n", entry.Name)
+
+	if open {
+		target, err := changePasswordTarget(entry)
+		if err != nil {
+			return false, err
+		}
+
+		if err := clipboard.Copy(entry.Password); err != nil {
+			return false, fmt.Errorf("failed to copy to clipboard: %w", err)
+		}
+		fmt.Println("📋 Current password copied to clipboard")
+
+		if err := browser.Open(target); err != nil {
+			fmt.Printf("⚠️  %v\n   Open it manually: %s\n", err, target)
+		} else {
+			fmt.Printf("🌐 Opened %s\n", target)
+		}
+
+		fmt.Print("\nPaste the current password, then press Enter to copy the new one...")
+		waitForLine()
+
+		if err := clipboard.Copy(newPassword); err != nil {
+			return false, fmt.Errorf("failed to copy to clipboard: %w", err)
+		}
+		fmt.Println("📋 New password copied to clipboard")
+	}
+
+	var confirmed bool
+	message := "Save the new password?"
+	if open {
+		message = "Did the site accept the new password?"
+	}
+	if err := askOne(&survey.Confirm{Message: message, Default: false}, &confirmed); err != nil {
+		return false, fmt.Errorf("confirmation prompt failed: %w", err)
+	}
+
+	if open {
+		clipboard.Clear()
+	}
+
+	if !confirmed {
+		fmt.Println("❌ Rotation cancelled, the stored password is unchanged")
+		return false, nil
+	}
+
+	entry.Password = newPassword
+	if err := db.UpdateEntry(entry, key); err != nil {
+		return false, fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	strength := crypto.CheckStrength(newPassword)
+	fmt.Printf("✅ Password for '%s' rotated (%s, previous password kept in history)\n", entry.Name, strength.Level)
+
+	return true, nil
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [--profile name] [--env VAR=entry[:field]]... -- <command> [args...]",
+	Short: "Run a command with secrets injected as environment variables",
+	Long: `Run a command with vault secrets injected as environment variables.
+
+Each --env maps a variable to an entry and an optional field (default:
+password, which is the token value for token entries). Fields: user,
+password, token, url, service, scopes, expires. The secrets only exist in
+the child process's environment; nothing is written to disk or printed.
+
+--profile injects a named bundle of bindings defined in config.yaml:
+
+  profiles:
+    prod-deploy:
+      - AWS_ACCESS_KEY_ID=aws-prod:user
+      - AWS_SECRET_ACCESS_KEY=aws-prod
+
+Several profiles can be combined; --env bindings are applied last and
+override a profile's variable of the same name.
+
+Status messages go to stderr so the command's stdout is left untouched,
+and gpasswd exits with the command's exit code. Expired tokens are
+reported but still injected.
+
+Examples:
+  gpasswd run --env GITHUB_TOKEN=github-ci -- gh release create v1.0
+  gpasswd run --env AWS_ACCESS_KEY_ID=aws:user --env AWS_SECRET_ACCESS_KEY=aws -- terraform plan
+  gpasswd run --profile prod-deploy -- terraform apply`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+var (
+	runEnv      []string
+	runProfiles []string
+)
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringArrayVarP(&runEnv, "env", "e", nil, "VAR=entry[:field] to inject (repeatable)")
+	runCmd.Flags().StringArrayVarP(&runProfiles, "profile", "P", nil, "Inject a profile from config.yaml (repeatable)")
+}
+
+// envBinding maps an environment variable to an entry field
+type envBinding struct {
+	Variable string
+	Entry    string
+	Field    string
+}
+
+var envVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvBinding parses VAR=entry[:field]
+// The text after the last colon is only treated as a field when it names
+// one, so entry names containing colons still work
+func parseEnvBinding(spec string) (envBinding, error) {
+	variable, target, found := strings.Cut(spec, "=")
+	if !found || target == "" {
+		return envBinding{}, fmt.Errorf("invalid --env %q (expected VAR=entry[:field])", spec)
+	}
+	if !envVariablePattern.MatchString(variable) {
+		return envBinding{}, fmt.Errorf("invalid environment variable name %q", variable)
+	}
+
+	binding := envBinding{Variable: variable, Entry: target, Field: "password"}
+	if i := strings.LastIndex(target, ":"); i > 0 {
+		if _, err := entryField(&models.Entry{}, target[i+1:]); err == nil {
+			binding.Entry = target[:i]
+			binding.Field = target[i+1:]
+		}
+	}
+	return binding, nil
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if len(runEnv) == 0 && len(runProfiles) == 0 {
+		return fmt.Errorf("nothing to inject; use --profile <name> or --env VAR=entry[:field]")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Profiles first so explicit --env bindings override them
+	var specs []string
+	for _, name := range runProfiles {
+		profile, err := lookupProfile(cfg, name)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, profile...)
+	}
+	specs = append(specs, runEnv...)
+
+	bindings := make([]envBinding, 0, len(specs))
+	for _, spec := range specs {
+		binding, err := parseEnvBinding(spec)
+		if err != nil {
+			return err
+		}
+		bindings = append(bindings, binding)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+
+	// Unlock the vault (status goes to stderr, stdout belongs to the command)
+	key, err := unlockVault(db, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	env, err := resolveEnv(db, key, bindings)
+	db.Close()
+	if err != nil {
+		return err
+	}
+
+	return execWithEnv(args, env)
+}
+
+// lookupProfile returns the bindings of a configured profile
+// Profile names are case-insensitive since the config loader lower-cases keys
+func lookupProfile(cfg *config.Config, name string) ([]string, error) {
+	profile, ok := cfg.Profiles[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for configured := range cfg.Profiles {
+			names = append(names, configured)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q not found; define profiles in config.yaml", name)
+		}
+		return nil, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
+	}
+	if len(profile) == 0 {
+		return nil, fmt.Errorf("profile %q has no bindings", name)
+	}
+	return profile, nil
+}
+
+// resolveEnv looks up the bound entry fields and returns VAR=value pairs
+func resolveEnv(db *storage.DB, key []byte, bindings []envBinding) ([]string, error) {
+	entries := make(map[string]*models.Entry)
+	env := make([]string, 0, len(bindings))
+	now := time.Now()
+
+	for _, binding := range bindings {
+		entry, ok := entries[binding.Entry]
+		if !ok {
+			var err error
+			entry, err = db.GetEntryByName(binding.Entry, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get entry: %w", err)
+			}
+			entries[binding.Entry] = entry
+			recordAccess(db, entry, "run")
+
+			if err := requireFreshUnlock(db, entry, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+				return nil, err
+			}
+
+			if entry.ExpiresAt != nil && entry.ExpiresAt.Before(now) {
+				fmt.Fprintf(os.Stderr, "⚠️  '%s' expired on %s\n", entry.Name, entry.ExpiresAt.Format("2006-01-02"))
+			}
+		}
+
+		value, err := entryField(entry, binding.Field)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			return nil, fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(binding.Field)))
+		}
+
+		env = append(env, binding.Variable+"="+value)
+	}
+
+	return env, nil
+}
+
+// execWithEnv runs a command with extra environment variables and exits
+// with its exit code
+func execWithEnv(args []string, env []string) error {
+	child := exec.Command(args[0], args[1:]...) // #nosec G204 -- running the user's command is the point
+	child.Env = append(os.Environ(), env...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	// The terminal delivers Ctrl+C to the child too; let it decide when to exit
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	err := child.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/breach"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/stats"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+var scoreCmd = &cobra.Command{
+	Use:   "score",
+	Short: "Rate the health of the vault from 0 to 100",
+	Long: `Rate the vault with a single health score from 0 to 100, with a
+breakdown of what it is made of:
+
+  strength  30  strong passwords count fully, fair ones half
+  reuse     25  passwords not shared with another entry
+  breaches  15  passwords not found in a breach corpus (with --pwned-file)
+  age       15  passwords changed within a year count fully, within two half
+  2fa       15  logins with a one-time password secret
+
+Parts with nothing to measure, like breaches without --pwned-file, are
+left out and the others scaled up to 100.
+
+Each run records the day's score in the vault (the score only, no counts
+or entry names) and shows the trend; --no-record leaves it out.
+
+Examples:
+  gpasswd score
+  gpasswd score --pwned-file ~/pwned-passwords-sha1-ordered-by-hash-v8.txt
+  gpasswd score --json`,
+	Args: cobra.NoArgs,
+	RunE: runScore,
+}
+
+var (
+	scorePwnedFile string
+	scoreNoRecord  bool
+)
+
+// scoreTrendLength is how many past scores the sparkline shows
+const scoreTrendLength = 30
+
+func init() {
+	rootCmd.AddCommand(scoreCmd)
+
+	scoreCmd.Flags().StringVar(&scorePwnedFile, "pwned-file", "", "Include breached passwords using a local HIBP SHA-1 file")
+	scoreCmd.Flags().BoolVar(&scoreNoRecord, "no-record", false, "Don't record today's score for the trend")
+}
+
+// scoreJSON is what 'score --json' prints
+type scoreJSON struct {
+	*stats.Health
+	Trend []storage.HealthScoreRecord `json:"trend"`
+}
+
+func runScore(cmd *cobra.Command, args []string) error {
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	opts := audit.Options{Policy: &policy}
+	if scorePwnedFile != "" {
+		checker, err := breach.OpenFile(scorePwnedFile)
+		if err != nil {
+			return err
+		}
+		defer checker.Close()
+		opts.BreachChecker = checker
+	}
+
+	// JSON output keeps stdout clean for the score
+	key, err := unlockVaultFor(db, jsonFlag)
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	changedAt := make(map[string]time.Time, len(entries))
+	for _, entry := range entries {
+		changed, err := db.PasswordChangedAt(entry, key)
+		if err != nil {
+			return err
+		}
+		changedAt[entry.ID] = changed
+	}
+
+	report, err := audit.Run(entries, opts)
+	if err != nil {
+		return fmt.Errorf("audit failed: %w", err)
+	}
+
+	now := time.Now()
+	health := stats.HealthScore(stats.Compute(entries, changedAt, report, scorePwnedFile != "", now))
+
+	if !scoreNoRecord {
+		if err := db.RecordHealthScore(now, health.Score); err != nil {
+			return err
+		}
+	}
+	trend, err := db.HealthScores()
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		return output.WriteJSON(os.Stdout, scoreJSON{Health: health, Trend: trend}, output.Options{})
+	}
+
+	printHealth(health, trend, scoreNoRecord)
+	return nil
+}
+
+// printHealth renders the score, its breakdown and its trend
+func printHealth(h *stats.Health, trend []storage.HealthScoreRecord, unrecorded bool) {
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🩺 Vault health: %d/100 (%s)\n", h.Score, healthRating(h.Score))
+	fmt.Println(strings.Repeat("─", 60))
+
+	for _, c := range h.Components {
+		fmt.Printf("%-10s %4.1f/%-4.0f %s\n", c.Name, c.Points, c.Max, c.Detail)
+	}
+	for _, name := range h.Skipped {
+		switch name {
+		case stats.ComponentBreaches:
+			fmt.Printf("%-10s not checked (use --pwned-file)\n", name)
+		default:
+			fmt.Printf("%-10s nothing to rate\n", name)
+		}
+	}
+	fmt.Println(strings.Repeat("─", 60))
+
+	// Today's score is the last one unless it wasn't recorded
+	past := trend
+	if !unrecorded && len(past) > 0 {
+		past = past[:len(past)-1]
+	}
+	if len(past) == 0 {
+		fmt.Println("\n📈 Trend: run 'gpasswd score' again another day to see how it changes")
+		return
+	}
+
+	previous := past[len(past)-1]
+	fmt.Printf("\n📈 Trend: %d → %d (%+d since %s)\n", previous.Score, h.Score, h.Score-previous.Score, previous.Date)
+
+	shown := trend
+	if unrecorded {
+		shown = append(append([]storage.HealthScoreRecord(nil), trend...), storage.HealthScoreRecord{Score: h.Score})
+	}
+	if len(shown) > scoreTrendLength {
+		shown = shown[len(shown)-scoreTrendLength:]
+	}
+	fmt.Printf("   %s  (last %d scores)\n", sparkline(shown), len(shown))
+}
+
+// healthRating names a health score
+func healthRating(score int) string {
+	switch {
+	case score >= 90:
+		return "Excellent"
+	case score >= 75:
+		return "Good"
+	case score >= 50:
+		return "Fair"
+	default:
+		return "Poor"
+	}
+}
+
+// sparkline draws scores as block characters, ▁ for 0 to █ for 100
+func sparkline(scores []storage.HealthScoreRecord) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	var line strings.Builder
+	for _, s := range scores {
+		i := s.Score * (len(blocks) - 1) / 100
+		line.WriteRune(blocks[max(0, min(i, len(blocks)-1))])
+	}
+	return line.String()
+}
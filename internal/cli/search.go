@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>...",
+	Short: "Search entries by name, username, URL, category and tags",
+	Long: `Search entries, best matches first.
+
+Words are matched as prefixes against each entry's name, category, tags,
+username and URL; every word must match. The search index is encrypted, so
+the master password is required. It is decrypted into memory only for the
+search and matched with SQLite FTS5 when available.
+
+Matches are ranked by where each word is found (name, then username, tags
+and category, URL, notes), then by how recently the entry was read and
+whether it is tagged "favorite". Equal scores are listed by name.
+
+Restrict the words to other fields with --in (repeat or comma-separate):
+  --in notes      entry notes
+  --in fields     username, token service and scopes, key type and public key
+  --in urls       URL and password change URL
+Scoped words are matched as substrings of those fields.
+
+Narrow the results with filters:
+  tag:NAME        entry has the tag (repeat for several)
+  category:NAME   entry is in the category (repeat to allow several)
+  url:TEXT        entry URL contains TEXT
+
+Examples:
+  gpasswd search github
+  gpasswd search john@example.com
+  gpasswd search git tag:work
+  gpasswd search url:google.com category:email
+  gpasswd search "recovery codes" --in notes
+
+--json prints the matches as a JSON array on stdout, best first, each with
+its score; the master password prompt goes to stderr. Add --reveal to include passwords and OTP
+secrets:
+  gpasswd search github --json --reveal | jq -r '.[0].password'`,
+	Aliases: []string{"find"},
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runSearch,
+}
+
+var (
+	searchReveal bool
+	searchIn     []string
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().BoolVarP(&searchReveal, "reveal", "r", false, "With --json, include passwords and OTP secrets")
+	searchCmd.Flags().StringSliceVar(&searchIn, "in", nil, "Match words only in these fields: notes, fields, urls")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+	q := storage.ParseSearchQuery(query)
+	if q.Empty() {
+		return fmt.Errorf("search query cannot be empty")
+	}
+	scopes, err := storage.ParseSearchScopes(searchIn)
+	if err != nil {
+		return err
+	}
+	if len(scopes) > 0 && len(q.Terms) == 0 {
+		return fmt.Errorf("--in needs words to search for")
+	}
+	q.Scopes = scopes
+	if searchReveal && !jsonFlag {
+		return fmt.Errorf("--reveal requires --json (use 'gpasswd show <name> --reveal')")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	key, err := unlockVaultFor(db, jsonFlag)
+	if err != nil {
+		return err
+	}
+
+	results, err := db.Search(q, key)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	entries := make([]*models.Entry, len(results))
+	for i, result := range results {
+		entries[i] = result.Entry
+	}
+
+	if jsonFlag {
+		if searchReveal {
+			// Revealed secrets pass the same guards as 'gpasswd show'
+			for _, entry := range entries {
+				recordAccess(db, entry, "search")
+				if err := output.ConfirmReveal(entry, "print its secrets as JSON"); err != nil {
+					return err
+				}
+				if err := requireFreshUnlock(db, entry, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+					return err
+				}
+			}
+		}
+		summaries := summarizeEntries(entries, searchReveal)
+		for i := range summaries {
+			summaries[i].Score = &results[i].Score
+		}
+		return output.WriteJSON(os.Stdout, summaries, output.Options{IncludeSecrets: searchReveal})
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No entries match '%s'\n", query)
+		return nil
+	}
+
+	fmt.Printf("🔍 Found %d entries:\n", len(entries))
+	for i, entry := range entries {
+		username := entry.Username
+		if entry.IsHighSecurity() {
+			username = "(hidden)"
+		} else if username == "" {
+			username = "-"
+		}
+		fmt.Printf("  %d. %s (%s) - %s\n", i+1, entry.Name, entry.Category, username)
+	}
+
+	fmt.Println("\n💡 Use 'gpasswd show <name>' for details or 'gpasswd copy <name>' to copy a password")
+	return nil
+}
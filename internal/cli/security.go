@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// parseSecurityLevel validates a --security flag value
+// normal is stored as the empty string, like the login entry type
+func parseSecurityLevel(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", models.SecurityLevelNormal:
+		return "", nil
+	case models.SecurityLevelHigh:
+		return models.SecurityLevelHigh, nil
+	default:
+		return "", fmt.Errorf("invalid security level %q (expected %s or %s)", value, models.SecurityLevelNormal, models.SecurityLevelHigh)
+	}
+}
+
+// requireFreshUnlock asks for the master password again before a
+// high-security entry is used with a key that was not typed in for this
+// command (the cached key of a multi-step command or a 'gpasswd unlock'
+// session)
+func requireFreshUnlock(db *storage.DB, entry *models.Entry, out io.Writer, opts ...survey.AskOpt) error {
+	if !entry.IsHighSecurity() || freshUnlocks[db.Path()] {
+		return nil
+	}
+
+	fmt.Fprintf(out, "🔐 '%s' is a high-security entry; enter the master password again\n", entry.Name)
+	delete(unlockedKeys, db.Path())
+	_, err := promptUnlock(db, applySecurityConfig(db), out, opts...)
+	return err
+}
+
+// clipboardTimeout returns the clear timeout in seconds for an entry
+// requested is the --timeout flag (0 = config default); high-security
+// entries are capped at clipboard.high_security_timeout
+func clipboardTimeout(cfg *config.Config, entry *models.Entry, requested int) int {
+	timeout := requested
+	if timeout == 0 {
+		timeout = cfg.Clipboard.ClearTimeout
+		if timeout == 0 {
+			timeout = 30 // Default 30 seconds
+		}
+	}
+
+	if entry.IsHighSecurity() && cfg.Clipboard.HighSecurityTimeout > 0 {
+		timeout = min(timeout, cfg.Clipboard.HighSecurityTimeout)
+	}
+	return timeout
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/agent"
+	"github.com/kitsnail/gpasswd/internal/session"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Keep the vault unlocked for a while",
+	Long: `Verify the master password and start a session, so the following
+commands don't ask for it again until the session expires.
+
+The session lasts --for, or session.timeout from config.yaml when --for is
+not given. It is stored as a small token file in $XDG_RUNTIME_DIR, which
+must be a tmpfs (cleared at logout), wrapped with a secret kept in the
+kernel keyring until reboot, so it never reaches the disk and only your
+account on this machine can use it. Without a tmpfs runtime directory or
+the kernel keyring (macOS, Windows) use 'gpasswd agent start' instead.
+High-security entries still ask for the master password every time. Run
+'gpasswd lock' to end the session early.
+
+Examples:
+  gpasswd unlock
+  gpasswd unlock --for 15m
+  gpasswd lock`,
+	Args: cobra.NoArgs,
+	RunE: runUnlock,
+}
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "End the 'gpasswd unlock' session and lock the agent",
+	Args:  cobra.NoArgs,
+	RunE:  runLock,
+}
+
+var unlockFor time.Duration
+
+func init() {
+	rootCmd.AddCommand(unlockCmd)
+	rootCmd.AddCommand(lockCmd)
+
+	unlockCmd.Flags().DurationVar(&unlockFor, "for", 0, "Session length, e.g. 15m (default session.timeout)")
+}
+
+func runUnlock(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ttl := unlockFor
+	if !cmd.Flags().Changed("for") {
+		ttl = time.Duration(cfg.Session.Timeout) * time.Second
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("--for must be a positive duration (session.timeout is 0)")
+	}
+
+	dir, err := session.Dir()
+	if err != nil {
+		return err
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Always ask, so a running session can be extended
+	key, err := promptUnlock(db, applySecurityConfig(db), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	expires, err := session.Save(dir, sessionVault(db), key, ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Vault unlocked until %s\n", expires.Local().Format("15:04:05"))
+	fmt.Println("   Run 'gpasswd lock' to lock it earlier")
+	return nil
+}
+
+func runLock(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	if err := endSession(absPath(dbPath)); err != nil {
+		return err
+	}
+	if err := agent.Lock(agentSocket()); err != nil && !errors.Is(err, agent.ErrNoAgent) {
+		return err
+	}
+
+	fmt.Println("🔒 Vault locked")
+	return nil
+}
+
+// sessionKey returns the key of an active 'gpasswd unlock' session for db
+// A key that no longer opens the vault (the master password changed) ends
+// the session
+func sessionKey(db *storage.DB, out io.Writer) ([]byte, bool) {
+	dir, err := session.Dir()
+	if err != nil {
+		return nil, false
+	}
+	vault := sessionVault(db)
+	key, _, err := session.Load(dir, vault)
+	if err != nil {
+		return nil, false
+	}
+	if err := db.VerifyKey(key); err != nil {
+		session.Remove(dir, vault)
+		fmt.Fprintln(out, "⚠️  The unlock session no longer matches the vault and was ended")
+		return nil, false
+	}
+	return key, true
+}
+
+// endSession removes the unlock session of vault, if any
+func endSession(vault string) error {
+	dir, err := session.Dir()
+	if err != nil {
+		return nil
+	}
+	return session.Remove(dir, vault)
+}
+
+// agentDir is where the agent socket lives: the per-user runtime
+// directory when there is one (removed at logout), otherwise the config
+// directory
+func agentDir() string {
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		return filepath.Join(runtime, "gpasswd")
+	}
+	return filepath.Join(config.GetConfigDir(), "sessions")
+}
+
+// sessionVault identifies db's vault for its session token
+func sessionVault(db *storage.DB) string {
+	return absPath(db.Path())
+}
+
+// absPath returns path made absolute, or path itself if that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,574 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/age"
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/securefs"
+	"github.com/kitsnail/gpasswd/internal/share"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share a category with a team through encrypted bundles",
+	Long: `Share the entries of a category with a team, without a server.
+
+A shared category is exported as a bundle encrypted to every team member's
+age public key. Members import the bundle into their own vault; importing
+a newer bundle later updates the entries that changed. Subcategories are
+shared along with their parent ("team" covers "team/aws").
+
+Bundles are standard age files (https://age-encryption.org), so keys made
+with age-keygen work too, and 'age -d -i key.txt bundle.age' shows the
+content as JSON.
+
+Typical workflow:
+  gpasswd share keygen                         # each member, once
+  gpasswd share add team --recipient age1...   # list every member's key
+  gpasswd share export team -o team.age        # send the file around
+  gpasswd share import team.age                # on every other vault`,
+}
+
+var shareKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate your sharing key",
+	Long: `Generate an age key pair for receiving shared bundles.
+
+The private key is written to ~/.gpasswd/share.key (or --identity) and the
+public key ("age1...") is printed. Send the public key to whoever manages
+the shared category; keep the private key file to yourself.
+
+Example:
+  gpasswd share keygen`,
+	Args: cobra.NoArgs,
+	RunE: runShareKeygen,
+}
+
+var shareAddCmd = &cobra.Command{
+	Use:   "add <category>",
+	Short: "Share a category with a list of recipients",
+	Long: `Mark a category as shared and set who can open its bundles.
+
+Recipients are age public keys, given with --recipient or in a file with
+one key per line (blank lines and # comments are ignored). Running the
+command again replaces the list. Your own key is added automatically when
+~/.gpasswd/share.key exists, so you can import your own bundles.
+
+Examples:
+  gpasswd share add team --recipient age1abc... --recipient age1def...
+  gpasswd share add team --recipients-file team-keys.txt`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShareAdd,
+}
+
+var shareRemoveCmd = &cobra.Command{
+	Use:   "remove <category>",
+	Short: "Stop sharing a category",
+	Long: `Stop sharing a category. Its entries stay in the vault.
+
+Example:
+  gpasswd share remove team`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShareRemove,
+}
+
+var shareListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List shared categories",
+	Args:  cobra.NoArgs,
+	RunE:  runShareList,
+}
+
+var shareExportCmd = &cobra.Command{
+	Use:   "export <category>",
+	Short: "Export a shared category as an encrypted bundle",
+	Long: `Export all entries of a shared category, encrypted to its recipients.
+
+Only the listed recipients can open the bundle, so it can be sent over
+email or chat or kept in a shared drive.
+
+Examples:
+  gpasswd share export team -o team.age
+  gpasswd share export team > team.age`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShareExport,
+}
+
+var shareImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import or update entries from a shared bundle",
+	Long: `Import a bundle exported with 'gpasswd share export'.
+
+New entries are added and entries that changed are updated. When an entry
+was also edited locally after the bundle's version, the local version is
+kept and reported. The first import of a category also records it as
+shared with the bundle's recipients, so you can export updates back.
+
+The bundle is opened with ~/.gpasswd/share.key unless --identity is given.
+
+Examples:
+  gpasswd share import team.age
+  gpasswd share import team.age --identity ~/keys/age.txt`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShareImport,
+}
+
+var (
+	shareIdentity       string
+	shareForce          bool
+	shareRecipients     []string
+	shareRecipientsFile string
+	shareOut            string
+)
+
+func init() {
+	rootCmd.AddCommand(shareCmd)
+
+	shareCmd.AddCommand(shareKeygenCmd)
+	shareCmd.AddCommand(shareAddCmd)
+	shareCmd.AddCommand(shareRemoveCmd)
+	shareCmd.AddCommand(shareListCmd)
+	shareCmd.AddCommand(shareExportCmd)
+	shareCmd.AddCommand(shareImportCmd)
+
+	shareKeygenCmd.Flags().StringVarP(&shareIdentity, "identity", "i", "", "Private key file (default: ~/.gpasswd/share.key)")
+	shareKeygenCmd.Flags().BoolVar(&shareForce, "force", false, "Overwrite an existing key file")
+
+	shareAddCmd.Flags().StringArrayVarP(&shareRecipients, "recipient", "r", nil, "age public key of a member (repeatable)")
+	shareAddCmd.Flags().StringVarP(&shareRecipientsFile, "recipients-file", "R", "", "File with one age public key per line")
+
+	shareExportCmd.Flags().StringVarP(&shareOut, "out", "o", "", "Output file (default: stdout)")
+
+	shareImportCmd.Flags().StringVarP(&shareIdentity, "identity", "i", "", "Private key file (default: ~/.gpasswd/share.key)")
+}
+
+// shareIdentityPath returns the private key file to use
+func shareIdentityPath() string {
+	if shareIdentity != "" {
+		return shareIdentity
+	}
+	return filepath.Join(config.GetConfigDir(), "share.key")
+}
+
+// openShareVault opens and unlocks the vault for the share subcommands
+func openShareVault(out *os.File, opts ...survey.AskOpt) (*storage.DB, []byte, error) {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+
+	// Unlock the vault
+	key, err := unlockVault(db, out, opts...)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, key, nil
+}
+
+func runShareKeygen(cmd *cobra.Command, args []string) error {
+	path := shareIdentityPath()
+	if _, err := os.Stat(path); err == nil && !shareForce {
+		return fmt.Errorf("%s already exists; use --force to replace it (bundles encrypted to the old key can no longer be opened)", path)
+	}
+
+	identity, err := age.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+
+	if err := securefs.MkdirAll(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	content := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), identity.Recipient(), identity)
+	if err := atomicfile.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	fmt.Printf("✅ Private key written to %s\n", path)
+	fmt.Printf("\nPublic key: %s\n", identity.Recipient())
+	fmt.Println("\n💡 Send the public key to whoever manages the shared category")
+	return nil
+}
+
+// readIdentities loads the private keys from an identity file
+func readIdentities(path string) ([]*age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no key at %s. Run 'gpasswd share keygen' first", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	identities, err := age.ParseIdentities(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// readRecipientsFile reads one recipient per line, skipping blank lines
+// and comments
+func readRecipientsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recipients file: %w", err)
+	}
+	defer file.Close()
+
+	var recipients []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipients = append(recipients, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recipients file: %w", err)
+	}
+	return recipients, nil
+}
+
+func runShareAdd(cmd *cobra.Command, args []string) error {
+	category := args[0]
+
+	values := append([]string{}, shareRecipients...)
+	if shareRecipientsFile != "" {
+		fromFile, err := readRecipientsFile(shareRecipientsFile)
+		if err != nil {
+			return err
+		}
+		values = append(values, fromFile...)
+	}
+
+	// Include our own key so our exports can be imported on our other devices
+	if identities, err := readIdentities(shareIdentityPath()); err == nil {
+		values = append(values, identities[0].Recipient().String())
+	}
+
+	recipients, err := share.ParseRecipients(values)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients; use --recipient or --recipients-file")
+	}
+
+	db, _, err := openShareVault(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	existing, err := db.SharedCategory(category)
+	if err != nil {
+		return err
+	}
+	shared := storage.SharedCategory{Category: category}
+	if existing != nil {
+		shared = *existing
+	}
+	shared.Recipients = shared.Recipients[:0]
+	for _, recipient := range recipients {
+		shared.Recipients = append(shared.Recipients, recipient.String())
+	}
+
+	if err := db.SaveSharedCategory(shared); err != nil {
+		return err
+	}
+
+	entries, err := db.ListEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list entries: %w", err)
+	}
+	count := 0
+	for _, entry := range entries {
+		if share.InCategory(entry.Category, category) {
+			count++
+		}
+	}
+
+	fmt.Printf("✅ Category '%s' is shared with %d recipients (%d entries)\n", category, len(shared.Recipients), count)
+	if count == 0 {
+		fmt.Println("⚠️  The category has no entries yet")
+	}
+	return nil
+}
+
+func runShareRemove(cmd *cobra.Command, args []string) error {
+	db, _, err := openShareVault(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.RemoveSharedCategory(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Category '%s' is no longer shared; its entries were kept\n", args[0])
+	return nil
+}
+
+func runShareList(cmd *cobra.Command, args []string) error {
+	db, _, err := openShareVault(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	shared, err := db.SharedCategories()
+	if err != nil {
+		return err
+	}
+	if len(shared) == 0 {
+		fmt.Println("\nNo shared categories. Share one with 'gpasswd share add <category>'")
+		return nil
+	}
+
+	times := loadTimeFormat()
+	fmt.Println()
+	for _, s := range shared {
+		fmt.Printf("👥 %s (%d recipients)\n", s.Category, len(s.Recipients))
+		if s.LastExport != nil {
+			fmt.Printf("   Last export: %s\n", times.Detailed(*s.LastExport))
+		}
+		if s.LastImport != nil {
+			fmt.Printf("   Last import: %s\n", times.Detailed(*s.LastImport))
+		}
+	}
+	return nil
+}
+
+func runShareExport(cmd *cobra.Command, args []string) error {
+	category := args[0]
+	if shareOut == "" && isTerminal(os.Stdout) {
+		return fmt.Errorf("refusing to write a binary bundle to the terminal; use -o <file> or redirect stdout")
+	}
+
+	// Prompts and status go to stderr so stdout can carry the bundle itself
+	db, key, err := openShareVault(os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	shared, err := db.SharedCategory(category)
+	if err != nil {
+		return err
+	}
+	if shared == nil {
+		return fmt.Errorf("category '%s' is not shared. Share it with 'gpasswd share add %s --recipient age1...'", category, category)
+	}
+
+	all, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	var entries []*models.Entry
+	for _, entry := range all {
+		if share.InCategory(entry.Category, category) {
+			entries = append(entries, entry)
+		}
+	}
+
+	now := time.Now()
+	data, err := share.Seal(&share.Bundle{
+		Version:    share.BundleVersion,
+		Category:   category,
+		Recipients: shared.Recipients,
+		ExportedAt: now,
+		Entries:    entries,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+
+	if shareOut == "" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	} else if err := atomicfile.WriteFile(shareOut, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	shared.LastExport = &now
+	if err := db.SaveSharedCategory(*shared); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Exported %d entries from '%s' for %d recipients\n", len(entries), category, len(shared.Recipients))
+	return nil
+}
+
+func runShareImport(cmd *cobra.Command, args []string) error {
+	identities, err := readIdentities(shareIdentityPath())
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	bundle, err := share.Open(data, identities)
+	if errors.Is(err, age.ErrNoIdentityMatched) {
+		return fmt.Errorf("this bundle was not encrypted to your key; ask for your public key to be added with 'gpasswd share add'")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	fmt.Printf("📥 Bundle of '%s' from %s with %d entries\n", bundle.Category, loadTimeFormat().Detailed(bundle.ExportedAt), len(bundle.Entries))
+
+	db, key, err := openShareVault(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	local, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	names := make(map[string]bool, len(local))
+	for _, entry := range local {
+		names[strings.ToLower(entry.Name)] = true
+	}
+
+	var added, updated, unchanged, kept int
+	var conflicts []storage.ImportConflict
+	for _, change := range share.Plan(local, bundle) {
+		incoming := change.Incoming
+
+		switch change.Action {
+		case share.ActionAdd:
+			// A non-shared entry may already use the name
+			original := incoming.Name
+			incoming.Name = uniqueEntryName(incoming.Name, names)
+			names[strings.ToLower(incoming.Name)] = true
+
+			if err := db.CreateEntry(incoming, key); err != nil {
+				fmt.Printf("   ✗ Failed to add '%s': %v\n", original, err)
+				continue
+			}
+			fmt.Printf("   + %s\n", incoming.Name)
+			added++
+
+			if incoming.Name != original {
+				conflicts = append(conflicts, storage.ImportConflict{
+					EntryID:    incoming.ID,
+					EntryName:  incoming.Name,
+					Original:   original,
+					Source:     "share " + bundle.Category,
+					ImportedAt: time.Now(),
+				})
+			}
+
+		case share.ActionUpdate:
+			// Keep the local ID and name so renames stay with the entry
+			incoming.ID = change.Local.ID
+			if !strings.EqualFold(incoming.Name, change.Local.Name) && names[strings.ToLower(incoming.Name)] {
+				incoming.Name = change.Local.Name
+			}
+			names[strings.ToLower(incoming.Name)] = true
+			if err := db.UpdateEntry(incoming, key); err != nil {
+				fmt.Printf("   ✗ Failed to update '%s': %v\n", change.Local.Name, err)
+				continue
+			}
+			fmt.Printf("   ~ %s\n", incoming.Name)
+			updated++
+
+		case share.ActionKeepLocal:
+			fmt.Printf("   ! %s: changed locally since this bundle; kept your version\n", change.Local.Name)
+			kept++
+
+		case share.ActionUnchanged:
+			unchanged++
+		}
+	}
+
+	if err := db.AddImportConflicts(conflicts); err != nil {
+		return err
+	}
+
+	// Remember the category so updates can be exported back to the team
+	now := time.Now()
+	shared, err := db.SharedCategory(bundle.Category)
+	if err != nil {
+		return err
+	}
+	if shared == nil {
+		shared = &storage.SharedCategory{Category: bundle.Category, Recipients: bundle.Recipients}
+		fmt.Printf("\n👥 Category '%s' is now shared with the bundle's %d recipients\n", bundle.Category, len(bundle.Recipients))
+	} else if !sameRecipients(shared.Recipients, bundle.Recipients) {
+		fmt.Printf("\n⚠️  The bundle's recipients differ from your list for '%s'; update it with 'gpasswd share add'\n", bundle.Category)
+	}
+	shared.LastImport = &now
+	if err := db.SaveSharedCategory(*shared); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ %d added, %d updated, %d unchanged", added, updated, unchanged)
+	if kept > 0 {
+		fmt.Printf(", %d kept local", kept)
+	}
+	fmt.Println()
+	if len(conflicts) > 0 {
+		fmt.Printf("⚠️  %d entries clashed with existing names and were renamed, e.g. '%s'\n", len(conflicts), conflicts[0].EntryName)
+	}
+	return nil
+}
+
+// sameRecipients compares recipient lists ignoring order
+func sameRecipients(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, r := range a {
+		set[r] = true
+	}
+	for _, r := range b {
+		if !set[r] {
+			return false
+		}
+	}
+	return true
+}
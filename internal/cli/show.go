@@ -1,14 +1,17 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 
 	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/output"
 	"github.com/kitsnail/gpasswd/internal/storage"
 	"github.com/kitsnail/gpasswd/pkg/config"
 )
@@ -20,29 +23,112 @@ var showCmd = &cobra.Command{
 
 The master password is required to decrypt the entry.
 
-By default, the password is hidden. Use --reveal to display it.
+By default, the password is hidden. Use --reveal to display it. Add --for to
+show it only briefly: the password line is erased from the terminal after the
+given duration or when you press Enter, so it does not linger in scrollback.
 
 Examples:
   gpasswd show github
-  gpasswd show "Gmail Work" --reveal`,
-	Aliases: []string{"get", "view"},
+  gpasswd show "Gmail Work" --reveal
+  gpasswd show github --reveal --for 10s
+  gpasswd show github --reveal --secure-screen
+  gpasswd show wifi --spell
+
+--secure-screen shows the entry on the terminal's alternate screen buffer
+(like less or vim), so nothing is left in scrollback once you press Enter.
+Set display.secure_screen in config.yaml to make it the default.
+
+--spell reveals the password and spells it out character by character with
+the NATO alphabet (uppercase letters in capitals), digit and symbol names,
+for reading it aloud or typing it into a TV or console that has no paste.
+
+--json (or --output json) prints the entry as JSON on stdout (the master
+password prompt goes to stderr). Passwords and OTP secrets are redacted
+unless --reveal (or --include-secrets) is also given:
+  gpasswd show github --json
+  gpasswd show github --json --reveal | jq -r .password
+
+--as-of shows the version of the entry that was current at a past date, from
+the entry's history, e.g. to recover a password rotated by mistake. Look the
+entry up by its current name:
+  gpasswd show github --as-of 2024-06-01 --reveal
+
+Notes are written in Markdown and rendered on a terminal (headings, lists,
+code blocks, emphasis). Use --raw-notes to print them exactly as stored;
+redirected output always gets the raw text.`,
+	Aliases: []string{"get"},
 	Args:    cobra.ExactArgs(1),
 	RunE:    runShow,
 }
 
 var (
 	showReveal bool
+	showFor    time.Duration
+	showSecure bool
+
+	showOutput         string
+	showIncludeSecrets bool
+
+	showAsOf string
+
+	showRawNotes bool
+
+	showSpell bool
 )
 
 func init() {
 	rootCmd.AddCommand(showCmd)
 
 	showCmd.Flags().BoolVarP(&showReveal, "reveal", "r", false, "Reveal password in output")
+	showCmd.Flags().DurationVar(&showFor, "for", 0, "With --reveal, re-mask the password after this long (e.g. 10s)")
+	showCmd.Flags().BoolVar(&showSecure, "secure-screen", false, "Show on the alternate screen so nothing stays in scrollback")
+	showCmd.Flags().StringVar(&showOutput, "output", "text", "Output format (text, json)")
+	showCmd.Flags().BoolVar(&showIncludeSecrets, "include-secrets", false, "With --output json, include the password and OTP secret")
+	showCmd.Flags().StringVar(&showAsOf, "as-of", "", "Show the version current at this date (YYYY-MM-DD or \"YYYY-MM-DD HH:MM\")")
+	showCmd.Flags().BoolVar(&showSpell, "spell", false, "Reveal the password and spell it out (NATO alphabet)")
+	showCmd.Flags().BoolVar(&showRawNotes, "raw-notes", false, "Print notes as stored instead of rendering them as Markdown")
 }
 
 func runShow(cmd *cobra.Command, args []string) error {
 	entryName := args[0]
 
+	jsonOutput, err := wantJSON(showOutput)
+	if err != nil {
+		return err
+	}
+	if showIncludeSecrets && !jsonOutput {
+		return fmt.Errorf("--include-secrets requires --json (use --reveal for text output)")
+	}
+	if jsonOutput && (showFor > 0 || showSecure || showSpell) {
+		return fmt.Errorf("--json cannot be combined with --for, --spell or --secure-screen")
+	}
+	if showSpell {
+		if showFor > 0 {
+			return fmt.Errorf("--for cannot be combined with --spell")
+		}
+		showReveal = true
+	}
+
+	if showFor < 0 {
+		return fmt.Errorf("--for must be a positive duration")
+	}
+	if showFor > 0 && !showReveal {
+		return fmt.Errorf("--for requires --reveal")
+	}
+	if showFor > 0 && !isTerminal(os.Stdout) {
+		return fmt.Errorf("--for requires an interactive terminal (the password cannot be erased from redirected output)")
+	}
+	if showSecure && !isTerminal(os.Stdout) {
+		return fmt.Errorf("--secure-screen requires an interactive terminal")
+	}
+
+	var asOf time.Time
+	if showAsOf != "" {
+		if asOf, err = parseAsOf(showAsOf); err != nil {
+			return err
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -67,94 +153,233 @@ func runShow(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
-	// Prompt for master password
-	var masterPassword string
-	masterPrompt := &survey.Password{
-		Message: "Master password:",
-	}
-	if err := survey.AskOne(masterPrompt, &masterPassword, survey.WithValidator(survey.Required)); err != nil {
-		return fmt.Errorf("master password prompt failed: %w", err)
+	// Unlock the vault (JSON output keeps stdout clean for the entry)
+	key, err := unlockVaultFor(db, jsonOutput)
+	if err != nil {
+		return err
 	}
 
-	// Get salt and params
-	salt, err := db.GetSalt()
+	// Get entry by name
+	entry, err := db.GetEntryByName(entryName, key)
 	if err != nil {
-		return fmt.Errorf("failed to get salt: %w", err)
+		return fmt.Errorf("failed to get entry: %w", err)
 	}
+	recordAccess(db, entry, "show")
 
-	params, err := db.GetArgon2Params()
+	if jsonOutput {
+		err = requireFreshUnlock(db, entry, os.Stderr, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	} else {
+		err = requireFreshUnlock(db, entry, os.Stdout)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get Argon2 parameters: %w", err)
+		return err
 	}
 
-	// Derive encryption key
-	fmt.Println("🔓 Unlocking vault...")
-	key, err := crypto.DeriveKey(masterPassword, salt, params)
-	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
+	current := entry
+	if showAsOf != "" {
+		entry, err = db.EntryAsOf(entry, asOf, key)
+		if errors.Is(err, storage.ErrNotYetCreated) {
+			return fmt.Errorf("'%s' did not exist yet on %s", current.Name, showAsOf)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	// Get entry by name
-	entry, err := db.GetEntryByName(entryName, key)
-	if err != nil {
-		return fmt.Errorf("failed to get entry: %w", err)
+	if jsonOutput {
+		return output.WriteJSON(os.Stdout, entry, output.Options{IncludeSecrets: showIncludeSecrets || showReveal})
+	}
+
+	if showReveal {
+		if err := output.ConfirmReveal(entry, "reveal the password"); err != nil {
+			return err
+		}
+	}
+
+	// Config default only applies when there is a screen to switch
+	secureScreen := showSecure || (cfg.Display.SecureScreen && isTerminal(os.Stdout))
+	if secureScreen {
+		restore := enterAltScreen()
+		defer restore()
 	}
 
 	// Display entry details
 	fmt.Println("\n" + strings.Repeat("─", 60))
-	fmt.Printf("📝 Entry: %s\n", entry.Name)
+	icon := "📝"
+	if entry.Icon != "" {
+		icon = entry.Icon
+	}
+	if showAsOf != "" {
+		fmt.Printf("%s Entry: %s (as of %s)\n", icon, entry.Name, showAsOf)
+	} else {
+		fmt.Printf("%s Entry: %s\n", icon, entry.Name)
+	}
 	fmt.Println(strings.Repeat("─", 60))
 
 	fmt.Printf("Category:    %s\n", entry.Category)
 
+	if entry.IsHighSecurity() {
+		fmt.Println("Security:    high")
+	}
+
 	if entry.Username != "" {
-		fmt.Printf("Username:    %s\n", entry.Username)
+		if entry.ListableUsername && !entry.IsHighSecurity() {
+			fmt.Printf("Username:    %s (listable: gpasswd user)\n", entry.Username)
+		} else {
+			fmt.Printf("Username:    %s\n", entry.Username)
+		}
 	}
 
-	// Password display
-	if showReveal {
-		fmt.Printf("Password:    %s\n", entry.Password)
+	// Password display (a timed reveal is printed last so it can be erased)
+	secretLabel := "Password:   "
+	if entry.IsToken() {
+		fmt.Println("Type:        token")
+		if entry.Service != "" {
+			fmt.Printf("Service:     %s\n", entry.Service)
+		}
+		secretLabel = "Token:      "
+	}
+	if entry.IsKey() {
+		fmt.Println("Type:        key")
+		fmt.Printf("Key type:    %s\n", entry.KeyType)
+		fmt.Println("Public key:")
+		for _, line := range strings.Split(strings.TrimSpace(entry.PublicKey), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		secretLabel = "Private key:"
+	}
+
+	timedReveal := showReveal && showFor > 0
+	if entry.IsNote() {
+		// A secure note has nothing to reveal but its notes
+		fmt.Println("Type:        secure note")
+		timedReveal = false
+	} else if entry.Password == "" {
+		fmt.Printf("%s none (password-less sign-in)\n", secretLabel)
+		timedReveal = false
+	} else if timedReveal {
+		fmt.Printf("%s %s\n", secretLabel, strings.Repeat("•", 12))
+		fmt.Println("             (revealed below)")
+	} else if showReveal {
+		fmt.Printf("%s %s\n", secretLabel, entry.Password)
 
 		// Show strength
-		strength := crypto.CheckStrength(entry.Password)
-		fmt.Printf("Strength:    %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+		if !entry.IsToken() && !entry.IsKey() {
+			strength := crypto.CheckStrength(entry.Password)
+			fmt.Printf("Strength:    %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+		}
+
+		if showSpell {
+			printSpelled(entry.Password)
+		}
 	} else {
-		fmt.Printf("Password:    %s\n", strings.Repeat("•", 12))
+		fmt.Printf("%s %s\n", secretLabel, strings.Repeat("•", 12))
 		fmt.Println("             (use --reveal to show)")
 	}
 
+	if entry.IsToken() || entry.ExpiresAt != nil {
+		fmt.Printf("Expires:     %s\n", formatExpiry(entry.ExpiresAt, time.Now()))
+	}
+	if len(entry.Scopes) > 0 {
+		fmt.Printf("Scopes:      %s\n", strings.Join(entry.Scopes, ", "))
+	}
+
 	if entry.URL != "" {
 		fmt.Printf("URL:         %s\n", entry.URL)
 	}
 
+	if entry.ChangePasswordURL != "" {
+		fmt.Printf("Change URL:  %s\n", entry.ChangePasswordURL)
+	}
+
+	if entry.OTP != "" {
+		fmt.Println("OTP:         configured (gpasswd totp code)")
+	}
+
+	if len(entry.LoginSteps) > 0 {
+		fmt.Printf("Login steps: %s (gpasswd login)\n", strings.Join(entry.LoginSteps, " → "))
+	}
+
+	if entry.HistoryRetention != nil {
+		fmt.Printf("History:     %s (overrides the vault's retention)\n", entry.HistoryRetention)
+	}
+
 	if len(entry.Tags) > 0 {
 		fmt.Printf("Tags:        %s\n", strings.Join(entry.Tags, ", "))
 	}
 
 	if entry.Notes != "" {
 		fmt.Println("\nNotes:")
-		// Indent notes
-		for _, line := range strings.Split(entry.Notes, "\n") {
-			fmt.Printf("  %s\n", line)
+		if !showRawNotes && isTerminal(os.Stdout) {
+			// The renderer adds its own margin
+			fmt.Println(output.RenderMarkdown(entry.Notes, terminalWidth(os.Stdout)))
+		} else {
+			// Indent notes
+			for _, line := range strings.Split(entry.Notes, "\n") {
+				fmt.Printf("  %s\n", line)
+			}
 		}
 	}
 
 	fmt.Println("\nTimestamps:")
-	dateFormat := "2006-01-02 15:04:05"
-	if cfg.Display.DateFormat != "" {
-		dateFormat = cfg.Display.DateFormat
+	times := timeFormat(cfg)
+	fmt.Printf("  Created:   %s\n", times.Detailed(entry.CreatedAt))
+	if showAsOf != "" && entry != current {
+		fmt.Printf("  Version:   from %s (replaced since; current version updated %s)\n", times.Absolute(entry.UpdatedAt), times.Format(current.UpdatedAt))
+	} else {
+		fmt.Printf("  Updated:   %s\n", times.Detailed(entry.UpdatedAt))
+	}
+
+	if versions, err := db.CountHistory(entry.ID); err == nil && versions > 0 {
+		fmt.Printf("  History:   %d previous version(s)\n", versions)
 	}
-	fmt.Printf("  Created:   %s\n", entry.CreatedAt.Format(dateFormat))
-	fmt.Printf("  Updated:   %s\n", entry.UpdatedAt.Format(dateFormat))
 
 	fmt.Printf("\nID:          %s\n", entry.ID)
 	fmt.Println(strings.Repeat("─", 60))
 
 	// Helpful actions
 	fmt.Println("\n💡 Actions:")
-	fmt.Printf("   • Copy password:  gpasswd copy %s\n", entry.Name)
-	fmt.Printf("   • Edit entry:     gpasswd edit %s\n", entry.Name)
-	fmt.Printf("   • Delete entry:   gpasswd delete %s\n", entry.Name)
+	if entry.IsNote() {
+		fmt.Printf("   • Edit note:      gpasswd note edit %s\n", current.Name)
+	} else {
+		fmt.Printf("   • Copy password:  gpasswd copy %s\n", current.Name)
+		fmt.Printf("   • Edit entry:     gpasswd edit %s\n", current.Name)
+	}
+	fmt.Printf("   • Delete entry:   gpasswd delete %s\n", current.Name)
+
+	var lines <-chan struct{}
+	if timedReveal || secureScreen {
+		lines = stdinLines()
+	}
+
+	if timedReveal {
+		revealFor(entry.Password, showFor, lines)
+	}
+
+	if secureScreen {
+		fmt.Print("\nPress Enter to close (nothing will remain in scrollback)")
+		<-lines
+	}
 
 	return nil
 }
+
+// printSpelled prints the password one numbered character per line
+func printSpelled(password string) {
+	fmt.Println("Spelled:")
+	for i, c := range output.Spell(password) {
+		fmt.Printf("  %3d  %s  %-18s %s\n", i+1, c.Char, c.Spoken, c.Class)
+	}
+}
+
+// revealFor prints the password on its own line, then erases the line after
+// the duration or when Enter is pressed
+func revealFor(password string, d time.Duration, lines <-chan struct{}) {
+	fmt.Printf("\n🔓 Password: %s   (hidden in %s, press Enter to hide now)", password, d)
+
+	waitForEnter(lines, d)
+
+	// Pressing Enter moves the cursor down a line; erase both lines
+	fmt.Print("\r\033[2K\033[1A\r\033[2K")
+	fmt.Println("🔒 Password hidden")
+}
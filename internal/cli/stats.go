@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/breach"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/rotation"
+	"github.com/kitsnail/gpasswd/internal/stats"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show vault statistics",
+	Long: `Show statistics about the vault: entries per category and type, a
+password strength histogram, a password age histogram and a summary of
+the audit (see 'gpasswd audit'), and the size of the vault checked against
+the soft limits in config.yaml.
+
+--largest lists the entries taking the most space, history included, to
+find what bloats the vault. It needs no master password: only entry names
+and encrypted sizes are read.
+
+With --json (or --output json) the statistics are printed as JSON on stdout, for
+personal dashboards or periodic reports; the master password prompt goes
+to stderr. Statistics contain counts and category names only, never entry
+names or passwords.
+
+Examples:
+  gpasswd stats
+  gpasswd stats --json > stats.json
+  gpasswd stats --largest
+  gpasswd stats --largest=25
+  gpasswd stats --output json --pwned-file ~/pwned-passwords-sha1-ordered-by-hash-v8.txt`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+var (
+	statsOutput         string
+	statsPwnedFile      string
+	statsExpiringWithin string
+	statsLargest        int
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsOutput, "output", "text", "Output format (text, json)")
+	statsCmd.Flags().StringVar(&statsPwnedFile, "pwned-file", "", "Include breached passwords using a local HIBP SHA-1 file")
+	statsCmd.Flags().StringVar(&statsExpiringWithin, "expiring-within", "7d", "Count entries expiring within this window as expiring")
+	statsCmd.Flags().IntVar(&statsLargest, "largest", 0, "List the N largest entries instead (default 10)")
+	statsCmd.Flags().Lookup("largest").NoOptDefVal = "10"
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	jsonOutput, err := wantJSON(statsOutput)
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	if statsLargest != 0 {
+		return runStatsLargest(dbPath, cfg, jsonOutput)
+	}
+
+	policy, err := passwordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	expiringWithin, err := rotation.ParseAge(statsExpiringWithin)
+	if err != nil {
+		return fmt.Errorf("invalid --expiring-within: %w", err)
+	}
+	opts := audit.Options{Policy: &policy, ExpiringWithin: expiringWithin}
+	if statsPwnedFile != "" {
+		checker, err := breach.OpenFile(statsPwnedFile)
+		if err != nil {
+			return err
+		}
+		defer checker.Close()
+		opts.BreachChecker = checker
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault (JSON output keeps stdout clean for the report)
+	key, err := unlockVaultFor(db, jsonOutput)
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	changedAt := make(map[string]time.Time, len(entries))
+	for _, entry := range entries {
+		changed, err := db.PasswordChangedAt(entry, key)
+		if err != nil {
+			return err
+		}
+		changedAt[entry.ID] = changed
+	}
+
+	report, err := audit.Run(entries, opts)
+	if err != nil {
+		return fmt.Errorf("audit failed: %w", err)
+	}
+
+	result := stats.Compute(entries, changedAt, report, statsPwnedFile != "", time.Now())
+	result.Storage.VaultBytes, _ = db.Size()
+	for _, entry := range entries {
+		if notesOverLimit(cfg, entry) {
+			result.Storage.LargeNotes++
+		}
+	}
+
+	if jsonOutput {
+		return output.WriteJSON(os.Stdout, result, output.Options{})
+	}
+
+	printStats(result, cfg)
+	return nil
+}
+
+// entrySizeJSON is one line of 'stats --largest --output json'
+type entrySizeJSON struct {
+	Name     string `json:"name"`
+	Bytes    int64  `json:"bytes"`
+	Current  int64  `json:"current_bytes"`
+	History  int64  `json:"history_bytes"`
+	Versions int    `json:"versions"`
+}
+
+// runStatsLargest lists the entries taking the most space
+func runStatsLargest(dbPath string, cfg *config.Config, jsonOutput bool) error {
+	if statsLargest < 0 {
+		return fmt.Errorf("--largest must be a positive number")
+	}
+
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	sizes, err := db.EntrySizes()
+	if err != nil {
+		return err
+	}
+	if len(sizes) > statsLargest {
+		sizes = sizes[:statsLargest]
+	}
+
+	if jsonOutput {
+		list := make([]entrySizeJSON, len(sizes))
+		for i, s := range sizes {
+			list[i] = entrySizeJSON{Name: s.Name, Bytes: s.Total(), Current: s.Current, History: s.History, Versions: s.Versions}
+		}
+		return output.WriteJSON(os.Stdout, list, output.Options{})
+	}
+
+	vaultSize, _ := db.Size()
+	fmt.Printf("\n💾 Largest entries (vault: %s)\n\n", formatSize(vaultSize))
+	if len(sizes) == 0 {
+		fmt.Println("No entries")
+		return nil
+	}
+	fmt.Printf("%-30s %10s %10s %10s\n", "NAME", "TOTAL", "CURRENT", "HISTORY")
+	fmt.Printf("%-30s %10s %10s %10s\n", "----", "-----", "-------", "-------")
+	for _, s := range sizes {
+		history := formatSize(s.History)
+		if s.Versions > 0 {
+			history = fmt.Sprintf("%s (%d)", history, s.Versions)
+		}
+		fmt.Printf("%-30s %10s %10s %10s\n", s.Name, formatSize(s.Total()), formatSize(s.Current), history)
+	}
+	warnSizeLimits(db, cfg, nil, os.Stdout)
+	fmt.Println("\n💡 Sizes are encrypted bytes; trim long notes with 'gpasswd note edit <name>'")
+	return nil
+}
+
+// printStats renders statistics as text with simple bar charts
+func printStats(s *stats.Stats, cfg *config.Config) {
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("📊 Vault statistics (%d entries)\n", s.Total)
+	fmt.Println(strings.Repeat("─", 60))
+
+	fmt.Println("\nCategories:")
+	for _, c := range s.Categories {
+		fmt.Printf("  %-20s %d\n", c.Category, c.Count)
+	}
+
+	if len(s.Types) > 1 || s.WithOTP > 0 {
+		fmt.Println("\nTypes:")
+		types := make([]string, 0, len(s.Types))
+		for entryType := range s.Types {
+			types = append(types, entryType)
+		}
+		sort.Strings(types)
+		for _, entryType := range types {
+			fmt.Printf("  %-20s %d\n", entryType, s.Types[entryType])
+		}
+		if s.WithOTP > 0 {
+			fmt.Printf("  %-20s %d\n", "with 2FA secret", s.WithOTP)
+		}
+	}
+
+	fmt.Println("\nPassword strength:")
+	printHistogram(s.Strength)
+
+	fmt.Println("\nPassword age:")
+	printHistogram(s.Age)
+
+	fmt.Println("\nAudit:")
+	fmt.Printf("  %-20s %d\n", "entries with issues", s.Audit.EntriesWithIssues)
+	for _, issue := range []audit.IssueType{audit.IssueWeak, audit.IssueReused, audit.IssueBreached, audit.IssueExpiring, audit.IssueExpired} {
+		if count, ok := s.Audit.Issues[issue]; ok {
+			fmt.Printf("  %-20s %d\n", issue, count)
+		}
+	}
+
+	fmt.Println("\nStorage:")
+	size := formatSize(s.Storage.VaultBytes)
+	overLimit := cfg.Limits.VaultSizeMB > 0 && s.Storage.VaultBytes > int64(cfg.Limits.VaultSizeMB)*1024*1024
+	if cfg.Limits.VaultSizeMB > 0 {
+		size = fmt.Sprintf("%s of %d MB", size, cfg.Limits.VaultSizeMB)
+	}
+	if overLimit {
+		size += " ⚠️  over the limit"
+	}
+	fmt.Printf("  %-20s %s\n", "vault size", size)
+	if s.Storage.LargeNotes > 0 {
+		fmt.Printf("  %-20s %d (over %d KB)\n", "large notes", s.Storage.LargeNotes, cfg.Limits.NotesSizeKB)
+	}
+	if overLimit || s.Storage.LargeNotes > 0 {
+		fmt.Println("  See what takes the space with: gpasswd stats --largest")
+	}
+	fmt.Println(strings.Repeat("─", 60))
+}
+
+func printHistogram(buckets []stats.Bucket) {
+	largest := 0
+	for _, b := range buckets {
+		largest = max(largest, b.Count)
+	}
+	for _, b := range buckets {
+		bar := ""
+		if largest > 0 {
+			bar = strings.Repeat("█", b.Count*30/largest)
+		}
+		fmt.Printf("  %-12s %4d %s\n", b.Label, b.Count, bar)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List all tags",
+	Long: `List all tags in use along with the number of entries carrying each.
+
+Tags are read from the tag index, so no master password is required.
+
+Example:
+  gpasswd tags`,
+	RunE: runTags,
+}
+
+func init() {
+	rootCmd.AddCommand(tagsCmd)
+}
+
+func runTags(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return fmt.Errorf("failed to read privacy level: %w", err)
+	}
+	if level == storage.PrivacyPrivate {
+		fmt.Println("🔒 This vault uses the private privacy level: tags are not indexed in plaintext")
+		fmt.Println("   Use 'gpasswd list --tag <tag>' to filter by tag with the master password")
+		return nil
+	}
+
+	indexed, err := db.TagsIndexed()
+	if err != nil {
+		return fmt.Errorf("failed to check tag index: %w", err)
+	}
+	if !indexed {
+		fmt.Println("⚠️  The tag index has not been built for this vault yet")
+		fmt.Println("   Run 'gpasswd list --tag <tag>' once to build it")
+		return nil
+	}
+
+	tags, err := db.ListTags()
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	if len(tags) == 0 {
+		fmt.Println("No tags in vault")
+		return nil
+	}
+
+	fmt.Printf("🏷️  Total tags: %d\n\n", len(tags))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TAG\tENTRIES")
+	fmt.Fprintln(w, "---\t-------")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%s\t%d\n", tag.Name, tag.Count)
+	}
+	w.Flush()
+
+	fmt.Println("\n💡 Use 'gpasswd list --tag <tag>' to list tagged entries")
+
+	return nil
+}
@@ -0,0 +1,411 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// templateFields are the fields a template can prompt for, in prompt order
+// of the add command; each is also the name of the add flag that sets it
+var templateFields = []string{"username", "password", "url", "notes", "category", "tags", "service", "expires", "scopes"}
+
+// templatePrompts are the prompt messages of the text fields
+var templatePrompts = map[string]string{
+	"username": "Username or email:",
+	"url":      "Website URL:",
+	"category": "Category:",
+	"tags":     "Tags:",
+	"service":  "Issuing service:",
+	"expires":  "Expires (YYYY-MM-DD, e.g. 90d, or never):",
+	"scopes":   "Scopes (comma-separated):",
+}
+
+// loadTemplate looks up a template in config.yaml and checks its fields
+func loadTemplate(cfg *config.Config, name string) (*config.EntryTemplate, error) {
+	tmpl, ok := cfg.Templates[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(cfg.Templates))
+		for known := range cfg.Templates {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown template %q: config.yaml defines no templates (see config.example.yaml)", name)
+		}
+		return nil, fmt.Errorf("unknown template %q (defined: %s)", name, strings.Join(names, ", "))
+	}
+
+	invalid := func(what string) error {
+		return fmt.Errorf("template %q: %s", name, what)
+	}
+	if tmpl.Type != "" && tmpl.Type != models.EntryTypeLogin && tmpl.Type != models.EntryTypeToken {
+		return nil, invalid(fmt.Sprintf("invalid type %q (expected %s or %s)", tmpl.Type, models.EntryTypeLogin, models.EntryTypeToken))
+	}
+	if _, err := parseSecurityLevel(tmpl.Security); err != nil {
+		return nil, invalid(err.Error())
+	}
+	for _, field := range tmpl.Fields {
+		if !isTemplateField(field) {
+			return nil, invalid(fmt.Sprintf("unknown field %q (expected %s)", field, strings.Join(templateFields, ", ")))
+		}
+	}
+	for field, value := range tmpl.Defaults {
+		if !isTemplateField(field) || field == "password" {
+			return nil, invalid(fmt.Sprintf("no default allowed for %q", field))
+		}
+		if field == "expires" {
+			if _, err := parseExpiry(value, time.Now()); err != nil {
+				return nil, invalid(err.Error())
+			}
+		}
+	}
+	if tmpl.Type == models.EntryTypeToken && !hasTemplateField(&tmpl, "password") {
+		return nil, invalid("token templates must prompt for the password (the token value)")
+	}
+	return &tmpl, nil
+}
+
+func isTemplateField(field string) bool {
+	for _, known := range templateFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTemplateField(tmpl *config.EntryTemplate, field string) bool {
+	for _, listed := range tmpl.Fields {
+		if listed == field {
+			return true
+		}
+	}
+	return false
+}
+
+// templateGenerator returns the generator options and length of a template,
+// starting from password_generator
+func templateGenerator(cfg *config.Config, tmpl *config.EntryTemplate) (crypto.GenerateOptions, int) {
+	options := crypto.GenerateOptions{
+		UseUppercase:     cfg.PasswordGenerator.UseUppercase,
+		UseLowercase:     cfg.PasswordGenerator.UseLowercase,
+		UseDigits:        cfg.PasswordGenerator.UseDigits,
+		UseSymbols:       cfg.PasswordGenerator.UseSymbols,
+		ExcludeAmbiguous: cfg.PasswordGenerator.ExcludeAmbiguous,
+	}
+	override := func(option *bool, value *bool) {
+		if value != nil {
+			*option = *value
+		}
+	}
+	gen := tmpl.Generator
+	override(&options.UseUppercase, gen.UseUppercase)
+	override(&options.UseLowercase, gen.UseLowercase)
+	override(&options.UseDigits, gen.UseDigits)
+	override(&options.UseSymbols, gen.UseSymbols)
+	override(&options.ExcludeAmbiguous, gen.ExcludeAmbiguous)
+
+	length := cfg.PasswordGenerator.Length
+	if gen.Length > 0 {
+		length = gen.Length
+	}
+	if length <= 0 {
+		length = 20
+	}
+	return options, length
+}
+
+// setEntryField sets a template field from text, as typed or configured
+func setEntryField(entry *models.Entry, field, value string) error {
+	switch field {
+	case "username":
+		entry.Username = value
+	case "password":
+		entry.Password = value
+	case "url":
+		entry.URL = value
+	case "notes":
+		entry.Notes = value
+	case "category":
+		entry.Category = value
+	case "tags":
+		entry.Tags = nil
+		for _, tag := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				entry.Tags = append(entry.Tags, trimmed)
+			}
+		}
+	case "service":
+		entry.Service = value
+	case "expires":
+		expires, err := parseExpiry(value, time.Now())
+		if err != nil {
+			return err
+		}
+		entry.ExpiresAt = expires
+	case "scopes":
+		entry.Scopes = parseScopes(value)
+	}
+	return nil
+}
+
+// addFlagValue returns the value of the add flag that sets field
+func addFlagValue(field string) string {
+	switch field {
+	case "username":
+		return addUsername
+	case "password":
+		return addPassword
+	case "url":
+		return addURL
+	case "notes":
+		return addNotes
+	case "category":
+		return addCategory
+	case "tags":
+		return strings.Join(addTags, ",")
+	case "service":
+		return addService
+	case "expires":
+		return addExpires
+	case "scopes":
+		return addScopes
+	}
+	return ""
+}
+
+// runAddTemplate adds an entry preset by a template, prompting only for
+// the template's fields that no flag already set
+func runAddTemplate(cmd *cobra.Command, args []string, cfg *config.Config, policy crypto.Policy) error {
+	tmpl, err := loadTemplate(cfg, addTemplate)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("type") || addNoPass {
+		return fmt.Errorf("--type and --no-password cannot be used with --template; the template decides")
+	}
+
+	// Template first, then its defaults, then flags
+	entry := &models.Entry{
+		Type:             tmpl.Type,
+		Category:         tmpl.Category,
+		Tags:             tmpl.Tags,
+		ListableUsername: addListable,
+		LoginSteps:       addSteps,
+	}
+	if entry.Type == models.EntryTypeLogin {
+		entry.Type = ""
+	}
+	entry.SecurityLevel, _ = parseSecurityLevel(tmpl.Security)
+	if cmd.Flags().Changed("security") {
+		if entry.SecurityLevel, err = parseSecurityLevel(addSecurity); err != nil {
+			return err
+		}
+	}
+	for field, value := range tmpl.Defaults {
+		setEntryField(entry, field, value)
+	}
+	given := map[string]bool{}
+	for _, field := range templateFields {
+		if !cmd.Flags().Changed(field) {
+			continue
+		}
+		given[field] = true
+		if err := setEntryField(entry, field, addFlagValue(field)); err != nil {
+			return err
+		}
+	}
+	if given["password"] && !entry.IsToken() {
+		if err := enforcePolicy(policy, addPassword, addAllowWeak); err != nil {
+			return err
+		}
+	}
+	if entry.ListableUsername && entry.IsHighSecurity() {
+		return fmt.Errorf("--listable-username cannot be used with a high-security template")
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock before collecting input so a wrong password doesn't discard it
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// Get entry name
+	if len(args) > 0 {
+		entry.Name = args[0]
+	} else {
+		namePrompt := &survey.Input{
+			Message: "Entry name:",
+		}
+		if err := askOne(namePrompt, &entry.Name, survey.WithValidator(survey.Required), survey.WithValidator(entryNameAvailable(db))); err != nil {
+			return fmt.Errorf("name prompt failed: %w", err)
+		}
+	}
+	exists, err := db.EntryExists(entry.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("entry '%s' already exists. Use 'gpasswd edit %s' to change it", entry.Name, entry.Name)
+	}
+
+	picks, err := loadTaxonomy(db, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📄 Template '%s'\n", strings.ToLower(addTemplate))
+	for _, field := range tmpl.Fields {
+		if given[field] || (field == "password" && addGenerate) {
+			continue
+		}
+		if err := promptTemplateField(entry, field, tmpl, policy, picks); err != nil {
+			return err
+		}
+	}
+
+	// Logins left without a password get a generated one
+	if entry.Password == "" && !entry.IsToken() {
+		options, length := templateGenerator(cfg, tmpl)
+		if cmd.Flags().Changed("gen-length") {
+			length = addGenLength
+		}
+		generated, err := crypto.Generate(length, options)
+		if err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+		entry.Password = generated
+		fmt.Printf("✓ Generated password: %s\n", generated)
+
+		strength := crypto.CheckStrength(generated)
+		fmt.Printf("  Strength: %s (Score: %d/100)\n", strength.Level.String(), strength.Score)
+	}
+
+	if entry.Category == "" {
+		entry.Category = "general"
+	}
+
+	fmt.Println("\n🔐 Encrypting and storing entry...")
+
+	// Create entry in database
+	if err := db.CreateEntry(entry, key); err != nil {
+		return fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	printAddedEntry(db, cfg, entry)
+	return nil
+}
+
+// promptTemplateField asks for one template field, suggesting its current
+// value; category and tags are picked from those in use
+func promptTemplateField(entry *models.Entry, field string, tmpl *config.EntryTemplate, policy crypto.Policy, picks *taxonomy) error {
+	switch field {
+	case "password":
+		if entry.IsToken() {
+			prompt := &survey.Password{Message: "Token value:"}
+			if err := askOne(prompt, &entry.Password, survey.WithValidator(survey.Required)); err != nil {
+				return fmt.Errorf("token prompt failed: %w", err)
+			}
+			return nil
+		}
+		prompt := &survey.Password{Message: "Password (empty to generate):"}
+		if err := askOne(prompt, &entry.Password, survey.WithValidator(optionalPolicyValidator(policy, addAllowWeak))); err != nil {
+			return fmt.Errorf("password prompt failed: %w", err)
+		}
+		return nil
+
+	case "notes":
+		prompt := &survey.Multiline{
+			Message: "Notes (press Ctrl+D when done):",
+			Default: entry.Notes,
+		}
+		if err := askOne(prompt, &entry.Notes); err != nil {
+			return fmt.Errorf("notes prompt failed: %w", err)
+		}
+		return nil
+
+	case "category":
+		category, err := picks.pickCategory(templatePrompts[field], entry.Category)
+		if err != nil {
+			return fmt.Errorf("category prompt failed: %w", err)
+		}
+		entry.Category = category
+		return nil
+
+	case "tags":
+		tags, err := picks.pickTags(templatePrompts[field], entry.Tags)
+		if err != nil {
+			return fmt.Errorf("tags prompt failed: %w", err)
+		}
+		entry.Tags = tags
+		return nil
+	}
+
+	current := ""
+	switch field {
+	case "username":
+		current = entry.Username
+	case "url":
+		current = entry.URL
+	case "service":
+		current = entry.Service
+	case "expires":
+		current = tmpl.Defaults["expires"]
+	case "scopes":
+		current = strings.Join(entry.Scopes, ",")
+	}
+
+	var opts []survey.AskOpt
+	if field == "expires" {
+		opts = append(opts, survey.WithValidator(expiryValidator))
+	}
+	var value string
+	prompt := &survey.Input{
+		Message: templatePrompts[field],
+		Default: current,
+	}
+	if err := askOne(prompt, &value, opts...); err != nil {
+		return fmt.Errorf("%s prompt failed: %w", field, err)
+	}
+	return setEntryField(entry, field, value)
+}
+
+// optionalPolicyValidator applies the strength policy to non-empty answers
+func optionalPolicyValidator(policy crypto.Policy, allowWeak bool) survey.Validator {
+	check := policyValidator(policy, allowWeak)
+	return func(ans interface{}) error {
+		if password, _ := ans.(string); password == "" {
+			return nil
+		}
+		return check(ans)
+	}
+}
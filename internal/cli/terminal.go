@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is attached to an interactive terminal
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// terminalWidth returns the width of the terminal f, or 80 if unknown
+func terminalWidth(f *os.File) int {
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
+
+// waitForLine blocks until a line is read from stdin (or EOF)
+// Reads byte by byte so nothing is buffered away from later prompts
+func waitForLine() {
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil || (n == 1 && buf[0] == '\n') {
+			return
+		}
+	}
+}
+
+// stdinLines signals once per line read from stdin and is closed on EOF
+// The reader keeps consuming stdin, so don't combine it with later prompts
+func stdinLines() <-chan struct{} {
+	lines := make(chan struct{})
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				close(lines)
+				return
+			}
+			lines <- struct{}{}
+		}
+	}()
+	return lines
+}
+
+// waitForEnter blocks until a line arrives on lines or the timeout expires
+// A closed channel (stdin at EOF) waits out the full timeout
+func waitForEnter(lines <-chan struct{}, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case _, ok := <-lines:
+		if !ok {
+			<-timer.C
+		}
+	case <-timer.C:
+	}
+}
+
+// enterAltScreen switches stdout to the terminal's alternate screen buffer,
+// whose contents are discarded (not kept in scrollback) when it is left
+// The returned function restores the normal screen; it is also called if
+// the process is interrupted
+func enterAltScreen() (restore func()) {
+	fmt.Print("\033[?1049h\033[H")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+			fmt.Print("\033[?1049l")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		fmt.Print("\033[?1049l")
+	}
+}
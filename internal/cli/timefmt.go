@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// absoluteTimes is the global --absolute flag
+var absoluteTimes bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&absoluteTimes, "absolute", false, "Show full dates instead of relative times (\"3 days ago\")")
+}
+
+// timeFormat returns the timestamp format configured under display
+// An invalid time zone falls back to local time with a warning
+func timeFormat(cfg *config.Config) *output.TimeFormat {
+	relative := cfg.Display.RelativeTimes && !absoluteTimes
+	format, err := output.NewTimeFormat(cfg.Display.DateFormat, cfg.Display.Timezone, relative)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; using local time\n", err)
+		format, _ = output.NewTimeFormat(cfg.Display.DateFormat, "", relative)
+	}
+	return format
+}
+
+// loadTimeFormat is timeFormat for commands that don't load the config
+// themselves
+func loadTimeFormat() *output.TimeFormat {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	return timeFormat(cfg)
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/rotation"
+)
+
+// parseExpiry parses a token expiry: a date (2006-01-02), a relative age
+// such as "90d", or "never"/"" for no expiry
+func parseExpiry(value string, now time.Time) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "never") {
+		return nil, nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		// A token dated for a day is usable until the end of it
+		expires := date.Add(24*time.Hour - time.Second)
+		return &expires, nil
+	}
+
+	if age, err := rotation.ParseAge(value); err == nil {
+		expires := now.Add(age)
+		return &expires, nil
+	}
+
+	return nil, fmt.Errorf("invalid expiry %q (expected YYYY-MM-DD, e.g. 90d, or never)", value)
+}
+
+// parseScopes splits a comma- or space-separated scope list
+func parseScopes(value string) []string {
+	var scopes []string
+	for _, scope := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// expiryValidator rejects expiries parseExpiry doesn't understand
+func expiryValidator(ans interface{}) error {
+	value, _ := ans.(string)
+	_, err := parseExpiry(value, time.Now())
+	return err
+}
+
+// formatExpiry renders an expiry with a relative hint, e.g.
+// "2025-03-01 (in 12 days)"
+func formatExpiry(expires *time.Time, now time.Time) string {
+	if expires == nil {
+		return "never"
+	}
+
+	date := expires.Format("2006-01-02")
+	days := int(expires.Sub(now).Hours() / 24)
+	switch {
+	case expires.Before(now):
+		return date + " (expired)"
+	case days == 0:
+		return date + " (today)"
+	default:
+		return fmt.Sprintf("%s (in %d day(s))", date, days)
+	}
+}
+
+// promptTokenFields asks for the token-specific fields not given as flags
+func promptTokenFields(entry *models.Entry, expires, scopes string) error {
+	if entry.Service == "" {
+		servicePrompt := &survey.Input{
+			Message: "Issuing service (e.g. GitHub, AWS):",
+			Default: entry.Name,
+		}
+		askOne(servicePrompt, &entry.Service)
+	}
+
+	if entry.Password == "" {
+		tokenPrompt := &survey.Password{
+			Message: "Token value:",
+		}
+		if err := askOne(tokenPrompt, &entry.Password, survey.WithValidator(survey.Required)); err != nil {
+			return fmt.Errorf("token prompt failed: %w", err)
+		}
+	}
+
+	if expires == "" {
+		expiresPrompt := &survey.Input{
+			Message: "Expires (YYYY-MM-DD, e.g. 90d, or blank for never):",
+		}
+		if err := askOne(expiresPrompt, &expires, survey.WithValidator(expiryValidator)); err != nil {
+			return fmt.Errorf("expiry prompt failed: %w", err)
+		}
+	}
+	expiresAt, err := parseExpiry(expires, time.Now())
+	if err != nil {
+		return err
+	}
+	entry.ExpiresAt = expiresAt
+
+	if scopes == "" {
+		scopesPrompt := &survey.Input{
+			Message: "Scopes (comma-separated, optional):",
+		}
+		askOne(scopesPrompt, &scopes)
+	}
+	entry.Scopes = parseScopes(scopes)
+
+	return nil
+}
+
+// editTokenFields interactively edits the token value, expiry and scopes
+// Blank answers keep the current values
+func editTokenFields(entry *models.Entry) error {
+	var newToken string
+	tokenPrompt := &survey.Password{
+		Message: "New token value (blank keeps current):",
+	}
+	if err := askOne(tokenPrompt, &newToken); err != nil {
+		return fmt.Errorf("token prompt failed: %w", err)
+	}
+	if newToken != "" {
+		entry.Password = newToken
+	}
+
+	var newService string
+	servicePrompt := &survey.Input{
+		Message: "Issuing service:",
+		Default: entry.Service,
+	}
+	if err := askOne(servicePrompt, &newService); err == nil && newService != "" {
+		entry.Service = newService
+	}
+
+	currentExpiry := "never"
+	if entry.ExpiresAt != nil {
+		currentExpiry = entry.ExpiresAt.Format("2006-01-02")
+	}
+	var newExpiry string
+	expiresPrompt := &survey.Input{
+		Message: "Expires (YYYY-MM-DD, e.g. 90d, or never):",
+		Default: currentExpiry,
+	}
+	if err := askOne(expiresPrompt, &newExpiry, survey.WithValidator(expiryValidator)); err != nil {
+		return fmt.Errorf("expiry prompt failed: %w", err)
+	}
+	if newExpiry != currentExpiry {
+		expiresAt, err := parseExpiry(newExpiry, time.Now())
+		if err != nil {
+			return err
+		}
+		entry.ExpiresAt = expiresAt
+	}
+
+	var newScopes string
+	scopesPrompt := &survey.Input{
+		Message: "Scopes (comma-separated):",
+		Default: strings.Join(entry.Scopes, ","),
+	}
+	if err := askOne(scopesPrompt, &newScopes); err == nil {
+		entry.Scopes = parseScopes(newScopes)
+	}
+
+	return nil
+}
@@ -0,0 +1,426 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/otp"
+	"github.com/kitsnail/gpasswd/internal/securefs"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var totpCmd = &cobra.Command{
+	Use:   "totp",
+	Short: "Manage one-time password (2FA) secrets",
+	Long: `Store one-time password secrets alongside entries and generate codes.
+
+Secrets are kept inside the entry's encrypted data as otpauth:// URIs, the
+same format authenticator apps use in their QR codes.
+
+Examples:
+  gpasswd totp set github JBSWY3DPEHPK3PXP
+  gpasswd totp set github 'otpauth://totp/GitHub:alice?secret=...'
+  gpasswd totp code github
+  gpasswd totp export github --google-migration`,
+}
+
+var totpSetCmd = &cobra.Command{
+	Use:   "set <name> [secret|otpauth-uri]",
+	Short: "Store the OTP secret of an entry",
+	Long: `Store the OTP secret of an entry.
+
+The secret can be a base32 key as shown by sites under "can't scan the QR
+code?", or a full otpauth:// URI. Without an argument the secret is asked
+for interactively so it does not end up in your shell history.
+
+Examples:
+  gpasswd totp set github
+  gpasswd totp set github JBSWY3DPEHPK3PXP`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runTOTPSet,
+}
+
+var totpRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the OTP secret of an entry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTOTPRemove,
+}
+
+var totpCodeCmd = &cobra.Command{
+	Use:   "code <name>",
+	Short: "Show the current one-time code of an entry",
+	Long: `Show the current one-time code of an entry.
+
+Examples:
+  gpasswd totp code github
+  gpasswd totp code github --copy`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTOTPCode,
+}
+
+var totpExportCmd = &cobra.Command{
+	Use:   "export <name>...",
+	Short: "Export OTP secrets to an authenticator app",
+	Long: `Export OTP secrets so they can be scanned by a phone app.
+
+With --google-migration the secrets of all given entries are combined into
+otpauth-migration:// payloads, the format of Google Authenticator's
+"Transfer accounts" QR codes, which Google Authenticator and Aegis import.
+Like the app's own export, every 10 entries take one more QR code; scan
+them in order. Without it, each entry is exported as a standard otpauth://
+QR code.
+
+QR codes are shown on the terminal's alternate screen and disappear when
+you press Enter. Use --uri to print the payload instead, or --png to write
+an image file. Anyone who sees the QR code or payload can generate your
+codes, so treat it like a password.
+
+Examples:
+  gpasswd totp export github --google-migration
+  gpasswd totp export github gitlab aws --google-migration
+  gpasswd totp export github --png github-otp.png`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runTOTPExport,
+}
+
+var (
+	totpCodeCopy     bool
+	totpExportGoogle bool
+	totpExportURI    bool
+	totpExportPNG    string
+)
+
+func init() {
+	rootCmd.AddCommand(totpCmd)
+
+	totpCmd.AddCommand(totpSetCmd)
+	totpCmd.AddCommand(totpRemoveCmd)
+	totpCmd.AddCommand(totpCodeCmd)
+	totpCmd.AddCommand(totpExportCmd)
+
+	totpCodeCmd.Flags().BoolVarP(&totpCodeCopy, "copy", "c", false, "Copy the code to the clipboard")
+
+	totpExportCmd.Flags().BoolVar(&totpExportGoogle, "google-migration", false, "Export as a Google Authenticator migration payload")
+	totpExportCmd.Flags().BoolVar(&totpExportURI, "uri", false, "Print the payload URI instead of a QR code")
+	totpExportCmd.Flags().StringVar(&totpExportPNG, "png", "", "Write the QR code to a PNG file")
+}
+
+// openTOTPVault opens and unlocks the vault for the totp subcommands
+func openTOTPVault() (*storage.DB, []byte, *config.Config, error) {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, nil, nil, fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	return db, key, cfg, nil
+}
+
+// entryOTPKey parses the OTP secret stored in an entry
+func entryOTPKey(entry *models.Entry) (*otp.Key, error) {
+	if entry.OTP == "" {
+		return nil, fmt.Errorf("entry '%s' has no OTP secret. Add one with 'gpasswd totp set %s'", entry.Name, entry.Name)
+	}
+
+	key, err := otp.Parse(entry.OTP)
+	if err != nil {
+		return nil, fmt.Errorf("entry '%s' has an invalid OTP secret: %w", entry.Name, err)
+	}
+	return key, nil
+}
+
+// nextOTPCode returns the entry's one-time code at now
+// HOTP codes are single-use, so the stored counter is advanced
+func nextOTPCode(db *storage.DB, entry *models.Entry, key []byte, now time.Time) (string, *otp.Key, error) {
+	otpKey, err := entryOTPKey(entry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	code, err := otpKey.Code(now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if otpKey.Type == otp.TypeHOTP {
+		otpKey.Counter++
+		entry.OTP = otpKey.URI()
+		if err := db.UpdateEntry(entry, key); err != nil {
+			return "", nil, fmt.Errorf("failed to update entry: %w", err)
+		}
+	}
+	return code, otpKey, nil
+}
+
+func runTOTPSet(cmd *cobra.Command, args []string) error {
+	db, key, _, err := openTOTPVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	if entry.OTP != "" {
+		var replace bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("'%s' already has an OTP secret. Replace it?", entry.Name),
+			Default: false,
+		}
+		if err := askOne(prompt, &replace); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !replace {
+			fmt.Println("❌ Cancelled")
+			return nil
+		}
+	}
+
+	var value string
+	if len(args) > 1 {
+		value = args[1]
+	} else {
+		prompt := &survey.Password{Message: "OTP secret or otpauth:// URI:"}
+		if err := askOne(prompt, &value, survey.WithValidator(survey.Required)); err != nil {
+			return fmt.Errorf("secret prompt failed: %w", err)
+		}
+	}
+
+	otpKey, err := otp.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	// Label bare secrets so authenticator apps show something useful
+	if otpKey.Issuer == "" {
+		otpKey.Issuer = entry.Name
+	}
+	if otpKey.Account == "" {
+		otpKey.Account = entry.Username
+	}
+
+	code, err := otpKey.Code(time.Now())
+	if err != nil {
+		return err
+	}
+
+	entry.OTP = otpKey.URI()
+	if err := db.UpdateEntry(entry, key); err != nil {
+		return fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	fmt.Printf("✅ OTP secret saved for '%s' (current code: %s)\n", entry.Name, code)
+	return nil
+}
+
+func runTOTPRemove(cmd *cobra.Command, args []string) error {
+	db, key, _, err := openTOTPVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	if entry.OTP == "" {
+		return fmt.Errorf("entry '%s' has no OTP secret", entry.Name)
+	}
+
+	var confirmed bool
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Remove the OTP secret of '%s'? Make sure 2FA is disabled or set up elsewhere first", entry.Name),
+		Default: false,
+	}
+	if err := askOne(prompt, &confirmed); err != nil {
+		return fmt.Errorf("confirmation prompt failed: %w", err)
+	}
+	if !confirmed {
+		fmt.Println("❌ Cancelled")
+		return nil
+	}
+
+	entry.OTP = ""
+	if err := db.UpdateEntry(entry, key); err != nil {
+		return fmt.Errorf("failed to update entry: %w", err)
+	}
+
+	fmt.Printf("✅ OTP secret removed from '%s' (kept in history)\n", entry.Name)
+	return nil
+}
+
+func runTOTPCode(cmd *cobra.Command, args []string) error {
+	db, key, cfg, err := openTOTPVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entry, err := db.GetEntryByName(args[0], key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "totp")
+
+	if err := requireFreshUnlock(db, entry, os.Stdout); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	code, otpKey, err := nextOTPCode(db, entry, key, now)
+	if err != nil {
+		return err
+	}
+
+	if !totpCodeCopy {
+		fmt.Println(code)
+		if otpKey.Type != otp.TypeHOTP {
+			fmt.Printf("⏱️  Valid for %s\n", otpKey.Remaining(now))
+		}
+		return nil
+	}
+
+	timeout := time.Duration(clipboardTimeout(cfg, entry, 0)) * time.Second
+
+	done, err := clipboard.CopyWithAutoClear(code, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	fmt.Printf("✅ Code for '%s' copied to clipboard\n", entry.Name)
+	fmt.Printf("⏱️  Clipboard will be cleared in %s\n", timeout)
+
+	<-done
+	fmt.Println("🧹 Clipboard cleared")
+	return nil
+}
+
+func runTOTPExport(cmd *cobra.Command, args []string) error {
+	db, key, _, err := openTOTPVault()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var keys []*otp.Key
+	for _, name := range args {
+		entry, err := db.GetEntryByName(name, key)
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+		recordAccess(db, entry, "totp export")
+
+		if err := requireFreshUnlock(db, entry, os.Stdout); err != nil {
+			return err
+		}
+		otpKey, err := entryOTPKey(entry)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, otpKey)
+	}
+
+	var payloads []string
+	if totpExportGoogle {
+		payloads, err = otp.MigrationURIs(keys)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, otpKey := range keys {
+			payloads = append(payloads, otpKey.URI())
+		}
+	}
+
+	switch {
+	case totpExportURI:
+		for _, payload := range payloads {
+			fmt.Println(payload)
+		}
+		return nil
+	case totpExportPNG != "":
+		if len(payloads) > 1 && totpExportGoogle {
+			return fmt.Errorf("--png writes a single QR code; export at most %d entries at a time", otp.MigrationBatchSize)
+		}
+		if len(payloads) > 1 {
+			return fmt.Errorf("--png writes a single QR code; use --google-migration to combine %d entries", len(payloads))
+		}
+		if err := qrcode.WriteFile(payloads[0], qrcode.Medium, 512, totpExportPNG); err != nil {
+			return fmt.Errorf("failed to write QR code: %w", err)
+		}
+		if err := securefs.Restrict(totpExportPNG); err != nil {
+			return fmt.Errorf("failed to restrict QR code permissions: %w", err)
+		}
+		fmt.Printf("✅ QR code written to %s\n", totpExportPNG)
+		fmt.Println("⚠️  The image contains your OTP secrets. Delete it once scanned")
+		return nil
+	}
+
+	return showQRCodes(payloads)
+}
+
+// showQRCodes renders QR codes one at a time, on the alternate screen when
+// attached to a terminal so they don't linger in scrollback
+func showQRCodes(payloads []string) error {
+	terminal := isTerminal(os.Stdout)
+	if terminal {
+		restore := enterAltScreen()
+		defer restore()
+	}
+
+	for i, payload := range payloads {
+		code, err := qrcode.New(payload, qrcode.Medium)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code: %w", err)
+		}
+
+		if terminal {
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Println(code.ToSmallString(false))
+		if len(payloads) > 1 {
+			fmt.Printf("QR code %d of %d. ", i+1, len(payloads))
+		}
+		fmt.Print("Scan it with your authenticator app, then press Enter...")
+		waitForLine()
+	}
+
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,379 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/securefs"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/internal/transfer"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var transferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "Move the vault to another machine over the local network",
+	Long: `Copy the encrypted vault directly between two machines on the same network,
+without cloud storage or USB sticks.
+
+Run 'gpasswd transfer receive' on the new machine first. It prints its
+address and a one-time code; then run 'gpasswd transfer send ADDRESS' on the
+old machine and type the code. The code authenticates both ends (SPAKE2), so
+a wrong code or anyone else on the network gets nothing, and each code works
+for one attempt only.
+
+With 'receive --announce' the receiver also advertises itself over mDNS;
+'send' without an address then lists the receivers it finds so you can pick
+one by name.
+
+The vault stays encrypted with its master password; both machines print the
+vault fingerprint so you can check they match.
+
+Examples:
+  gpasswd transfer receive
+  gpasswd transfer send 192.168.1.20
+  gpasswd transfer receive --announce --name office-desktop
+  gpasswd transfer send
+  gpasswd transfer send 192.168.1.20:7361 --code 482-019-736`,
+}
+
+var transferSendCmd = &cobra.Command{
+	Use:   "send [address]",
+	Short: "Send the vault to a machine running 'transfer receive'",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTransferSend,
+}
+
+var transferReceiveCmd = &cobra.Command{
+	Use:   "receive",
+	Short: "Wait for a vault from 'transfer send' and install it",
+	Args:  cobra.NoArgs,
+	RunE:  runTransferReceive,
+}
+
+var (
+	transferCode     string
+	transferPort     int
+	transferTimeout  time.Duration
+	transferForce    bool
+	transferAnnounce bool
+	transferName     string
+)
+
+func init() {
+	rootCmd.AddCommand(transferCmd)
+
+	transferCmd.AddCommand(transferSendCmd)
+	transferCmd.AddCommand(transferReceiveCmd)
+
+	transferCmd.PersistentFlags().DurationVar(&transferTimeout, "timeout", 10*time.Minute, "Give up after this long")
+
+	transferSendCmd.Flags().StringVar(&transferCode, "code", "", "One-time code shown by the receiver")
+
+	transferReceiveCmd.Flags().IntVarP(&transferPort, "port", "p", transfer.DefaultPort, "TCP port to listen on")
+	transferReceiveCmd.Flags().BoolVar(&transferForce, "force", false, "Replace an existing vault without asking")
+	transferReceiveCmd.Flags().BoolVar(&transferAnnounce, "announce", false, "Advertise this receiver over mDNS so senders can pick it by name")
+	transferReceiveCmd.Flags().StringVar(&transferName, "name", "", "Name to announce (default: host name)")
+}
+
+func runTransferSend(cmd *cobra.Command, args []string) error {
+	var address string
+	if len(args) == 1 {
+		address = args[0]
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, strconv.Itoa(transfer.DefaultPort))
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Send a consistent copy rather than the live file and its WAL
+	tmpDir, err := os.MkdirTemp("", "gpasswd-transfer-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshotPath := filepath.Join(tmpDir, "vault.db")
+	if err := db.Snapshot(snapshotPath); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vault snapshot: %w", err)
+	}
+
+	fingerprint, err := db.Fingerprint()
+	if err != nil {
+		return err
+	}
+	count, err := db.CountEntries()
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(data)
+	offer := transfer.Offer{
+		Size:        int64(len(data)),
+		SHA256:      checksum[:],
+		Fingerprint: fingerprint,
+		Entries:     count,
+	}
+
+	if address == "" {
+		if address, err = discoverReceiver(); err != nil {
+			return err
+		}
+	}
+
+	code := transferCode
+	if code == "" {
+		if err := askOne(&survey.Input{Message: "Code shown by the receiver:"}, &code, survey.WithValidator(survey.Required)); err != nil {
+			return fmt.Errorf("code prompt failed: %w", err)
+		}
+	}
+	if code, err = transfer.NormalizeCode(code); err != nil {
+		return err
+	}
+
+	fmt.Printf("📡 Connecting to %s...\n", address)
+	conn, err := net.DialTimeout("tcp", address, 30*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(transferTimeout))
+
+	if err := transfer.Send(conn, code, offer, bytes.NewReader(data)); err != nil {
+		if errors.Is(err, transfer.ErrWrongCode) {
+			return fmt.Errorf("%w; start 'gpasswd transfer receive' again for a new code", err)
+		}
+		return fmt.Errorf("transfer failed: %w", err)
+	}
+
+	fmt.Printf("✅ Sent %d entries (%d KB)\n", count, (len(data)+1023)/1024)
+	fmt.Printf("🔏 %s\n", formatFingerprint(fingerprint))
+	fmt.Println("   Check that the receiver shows the same fingerprint")
+	return nil
+}
+
+func runTransferReceive(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	if _, err := os.Stat(dbPath); err == nil && !transferForce {
+		fmt.Fprintf(os.Stderr, "⚠️  Vault already exists at: %s\n", dbPath)
+
+		var overwrite bool
+		prompt := &survey.Confirm{
+			Message: "Replace it with the received vault? (ITS DATA WILL BE LOST)",
+			Default: false,
+		}
+		if err := askOne(prompt, &overwrite); err != nil {
+			return fmt.Errorf("prompt failed: %w", err)
+		}
+		if !overwrite {
+			fmt.Println("✓ Transfer cancelled")
+			return nil
+		}
+	}
+
+	code, err := transfer.NewCode()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", transferPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", transferPort, err)
+	}
+	defer listener.Close()
+	if tcp, ok := listener.(*net.TCPListener); ok {
+		tcp.SetDeadline(time.Now().Add(transferTimeout))
+	}
+
+	fmt.Println("📡 Waiting for the vault. On the other machine run:")
+	addresses := localAddresses()
+	if len(addresses) == 0 {
+		addresses = []string{"<this machine's address>"}
+	}
+	for _, address := range addresses {
+		fmt.Printf("   gpasswd transfer send %s\n", net.JoinHostPort(address, strconv.Itoa(transferPort)))
+	}
+	fmt.Printf("\n🔑 Code: %s\n\n", code)
+
+	if transferAnnounce {
+		name := transferName
+		if name == "" {
+			if name, err = os.Hostname(); err != nil {
+				name = "gpasswd"
+			}
+		}
+		advertiser, err := transfer.Advertise(name, transferPort, localAddresses())
+		if err != nil {
+			// The address above still works; discovery is a convenience
+			fmt.Fprintf(os.Stderr, "⚠️  Could not announce over mDNS: %v\n", err)
+		} else {
+			defer advertiser.Close()
+			fmt.Printf("📣 Announced as '%s'; 'gpasswd transfer send' without an address will find it\n\n", name)
+		}
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return fmt.Errorf("no sender connected: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(transferTimeout))
+
+	// Receive next to the vault so the final rename is atomic
+	if err := securefs.MkdirAll(filepath.Dir(dbPath)); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), ".vault-transfer-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	accept := func(offer transfer.Offer) error {
+		fmt.Printf("📥 Receiving %d entries (%d KB) from %s\n", offer.Entries, (offer.Size+1023)/1024, conn.RemoteAddr())
+		return nil
+	}
+	offer, err := transfer.Receive(conn, code, accept, tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if errors.Is(err, transfer.ErrWrongCode) {
+			return fmt.Errorf("%w; nothing was received", err)
+		}
+		return fmt.Errorf("transfer failed: %w", err)
+	}
+
+	// Stale WAL files of the replaced vault must not be applied to the new one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old vault journal: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), dbPath); err != nil {
+		return fmt.Errorf("failed to install received vault: %w", err)
+	}
+
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open received vault: %w", err)
+	}
+	defer db.Close()
+
+	// The unlock throttle is keyed per machine; start it fresh here
+	if err := db.ResetFailedUnlocks(); err != nil {
+		return fmt.Errorf("failed to initialize unlock throttling: %w", err)
+	}
+
+	fingerprint, err := db.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Vault installed at %s\n", dbPath)
+	fmt.Printf("🔏 %s\n", formatFingerprint(fingerprint))
+	if !bytes.Equal(fingerprint, offer.Fingerprint) {
+		fmt.Println("⚠️  Fingerprint differs from the sender's; the vault may be damaged")
+	} else {
+		fmt.Println("   Check that the sender shows the same fingerprint")
+	}
+	fmt.Println("💡 Unlock it with the same master password as on the other machine")
+	return nil
+}
+
+// discoverReceiver finds receivers announced with --announce and lets the
+// user pick one
+func discoverReceiver() (string, error) {
+	fmt.Println("🔍 Looking for receivers on the local network...")
+	peers, err := transfer.Browse(3 * time.Second)
+	if err != nil {
+		return "", err
+	}
+	if len(peers) == 0 {
+		return "", fmt.Errorf("no receivers found; run 'gpasswd transfer receive --announce' on the other machine or pass its address")
+	}
+
+	options := make([]string, len(peers))
+	for i, peer := range peers {
+		options[i] = fmt.Sprintf("%s (%s)", peer.Name, peer.Address)
+	}
+	var choice int
+	prompt := &survey.Select{
+		Message: "Send the vault to:",
+		Options: options,
+	}
+	if err := askOne(prompt, &choice); err != nil {
+		return "", fmt.Errorf("selection failed: %w", err)
+	}
+	return peers[choice].Address, nil
+}
+
+// localAddresses lists this machine's non-loopback IP addresses
+func localAddresses() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+
+	var addresses []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			addresses = append(addresses, ip.String())
+		}
+	}
+	return addresses
+}
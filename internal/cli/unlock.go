@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+// maxUnlockAttempts bounds master password retries within one command
+const maxUnlockAttempts = 3
+
+// unlockedKeys caches verified keys per vault path for the process lifetime,
+// so multi-step commands only ask for the master password once
+var unlockedKeys = make(map[string][]byte)
+
+// freshUnlocks records whether the key of a vault path was typed in by the
+// user during the current command step rather than taken from the cache
+var freshUnlocks = make(map[string]bool)
+
+// unlockVault prompts for the master password and returns the verified key
+// A wrong password is re-prompted up to maxUnlockAttempts times. Status lines
+// go to out; opts are passed through to the survey prompt
+// A running agent or a session started with 'gpasswd unlock' is used
+// instead of prompting
+func unlockVault(db *storage.DB, out io.Writer, opts ...survey.AskOpt) ([]byte, error) {
+	return unlock(db, out, true, opts...)
+}
+
+// unlockWithoutAgent is unlockVault for commands that must not take the
+// vault key from the agent, which only hands it to gpasswd's own commands
+func unlockWithoutAgent(db *storage.DB, out io.Writer, opts ...survey.AskOpt) ([]byte, error) {
+	return unlock(db, out, false, opts...)
+}
+
+// unlock returns the vault key from the process cache, the agent (when
+// useAgent is set), the unlock session or the master password prompt
+func unlock(db *storage.DB, out io.Writer, useAgent bool, opts ...survey.AskOpt) ([]byte, error) {
+	cfg := applySecurityConfig(db)
+
+	if key, ok := unlockedKeys[db.Path()]; ok {
+		// A new connection still needs the key to reseal the manifest
+		if err := db.VerifyKey(key); err != nil {
+			return nil, err
+		}
+		freshUnlocks[db.Path()] = false
+		return key, nil
+	}
+
+	var (
+		key []byte
+		ok  bool
+	)
+	if useAgent {
+		key, ok = agentKey(db)
+	}
+	if !ok {
+		key, ok = sessionKey(db, out)
+	}
+	if ok {
+		if err := checkManifest(db, key, out); err != nil {
+			return nil, err
+		}
+		unlockedKeys[db.Path()] = key
+		freshUnlocks[db.Path()] = false
+		sealLegacyFields(db, key, out)
+		return key, nil
+	}
+
+	key, err := promptUnlock(db, cfg, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sealLegacyFields(db, key, out)
+	return key, nil
+}
+
+// sealLegacyFields moves the notes of entries saved before the fields key
+// existed under it, once per vault, so they can be previewed
+func sealLegacyFields(db *storage.DB, key []byte, out io.Writer) {
+	sealed, err := db.FieldsSealed()
+	if err != nil || sealed {
+		return
+	}
+	if err := db.SealFields(key); err != nil {
+		fmt.Fprintf(out, "⚠️  Failed to move notes under the fields key: %v\n", err)
+	}
+}
+
+// promptUnlock always asks for the master password, ignoring cached keys
+// and sessions
+func promptUnlock(db *storage.DB, cfg *config.Config, out io.Writer, opts ...survey.AskOpt) ([]byte, error) {
+	// Get salt and params
+	salt, err := db.GetSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get salt: %w", err)
+	}
+
+	params, err := db.GetArgon2Params()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Argon2 parameters: %w", err)
+	}
+
+	opts = append(opts, survey.WithValidator(survey.Required))
+
+	for attempt := 1; attempt <= maxUnlockAttempts; attempt++ {
+		// Prompt for master password
+		masterPassword, err := askMasterPassword(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("master password prompt failed: %w", err)
+		}
+
+		// Derive encryption key
+		fmt.Fprintln(out, "🔓 Unlocking vault...")
+		key, err := crypto.DeriveKey(masterPassword, salt, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+		}
+		if cfg.Display.PasswordFingerprint {
+			fmt.Fprintf(out, "   Password fingerprint: %s\n", crypto.PasswordFingerprint(key))
+		}
+
+		err = db.VerifyKey(key)
+		if err == nil {
+			if err := db.ResetFailedUnlocks(); err != nil {
+				return nil, fmt.Errorf("failed to reset unlock throttling: %w", err)
+			}
+			if err := checkManifest(db, key, out); err != nil {
+				return nil, err
+			}
+			unlockedKeys[db.Path()] = key
+			freshUnlocks[db.Path()] = true
+			shareWithAgent(db, key)
+			showUnlockBanner(db, key, out)
+			return key, nil
+		}
+		if !errors.Is(err, storage.ErrWrongKey) {
+			return nil, err
+		}
+
+		// Slow down guessing; the counter survives across processes
+		failures, err := db.RecordFailedUnlock()
+		if err != nil {
+			return nil, fmt.Errorf("failed to record failed unlock: %w", err)
+		}
+		time.Sleep(failedUnlockDelay(cfg.Security.FailedUnlockDelay, failures))
+
+		// A scripted password won't change on retry
+		if _, scripted, _ := scriptedPassword(); scripted {
+			break
+		}
+		if remaining := maxUnlockAttempts - attempt; remaining > 0 {
+			fmt.Fprintf(out, "❌ Incorrect master password (%d attempt(s) left)\n", remaining)
+		}
+	}
+
+	return nil, storage.ErrWrongKey
+}
+
+// askMasterPassword prompts for the master password. A password given for
+// scripts (see scriptedPassword) is used without asking. Without a terminal
+// (cron, editors) the askpass program is asked instead; --non-interactive
+// only allows GPASSWD_ASKPASS
+func askMasterPassword(opts ...survey.AskOpt) (string, error) {
+	if password, ok, err := scriptedPassword(); ok || err != nil {
+		return password, err
+	}
+	if nonInteractive && askpassHook == nil && os.Getenv("GPASSWD_ASKPASS") == "" {
+		return "", errNoPrompt
+	}
+	if !isTerminal(os.Stdin) || nonInteractive {
+		return askpass(askpassPrompt)
+	}
+
+	var masterPassword string
+	masterPrompt := &survey.Password{
+		Message: "Master password:",
+	}
+	err := askOne(masterPrompt, &masterPassword, opts...)
+	return masterPassword, err
+}
+
+// applySecurityConfig applies the security settings that change how the
+// vault answers lookups and writes and returns the configuration. Every
+// command that resolves or stores entries unlocks first, so this is the one
+// place they need to be set. An unreadable config falls back to the defaults
+func applySecurityConfig(db *storage.DB) *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	db.SetUniformLookup(cfg.Security.UniformLookup)
+	db.SetVerifyWrites(cfg.Security.VerifyWrites)
+
+	if cfg.Security.ConfirmReveal && len(cfg.Security.ConfirmRevealTags) > 0 {
+		output.SetRevealGuard(&output.RevealGuard{
+			Tags:    cfg.Security.ConfirmRevealTags,
+			Confirm: confirmOnStderr,
+		})
+	} else {
+		output.SetRevealGuard(nil)
+	}
+	return cfg
+}
+
+// confirmOnStderr asks a yes/no question on the terminal without touching
+// stdout, which may be captured (show --output json, netrc)
+func confirmOnStderr(message string) (bool, error) {
+	var confirmed bool
+	prompt := &survey.Confirm{Message: message, Default: false}
+	err := askOne(prompt, &confirmed, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	return confirmed, err
+}
+
+// maxFailedUnlockDelay caps the delay after a failed unlock
+const maxFailedUnlockDelay = 30 * time.Second
+
+// failedUnlockDelay returns the pause after the given number of consecutive
+// failed unlocks: the base delay (seconds) doubled per earlier failure,
+// capped, with ±50% jitter so the timing can't be predicted
+func failedUnlockDelay(baseSeconds, failures int) time.Duration {
+	if baseSeconds <= 0 || failures <= 0 {
+		return 0
+	}
+
+	delay := time.Duration(baseSeconds) * time.Second
+	for i := 1; i < failures && delay < maxFailedUnlockDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxFailedUnlockDelay)
+
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(delay)))
+	if err != nil {
+		return delay
+	}
+	return delay/2 + time.Duration(jitter.Int64())
+}
+
+// checkManifest verifies the vault against its signed manifest after unlock
+// Tampering is reported but does not block the command; only vaults from
+// before manifests existed are sealed on their first unlock
+func checkManifest(db *storage.DB, key []byte, out io.Writer) error {
+	err := db.VerifyManifest(key)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrNoManifest):
+		if err := db.SealManifest(key); err != nil {
+			return fmt.Errorf("failed to seal vault manifest: %w", err)
+		}
+		return nil
+	case errors.Is(err, storage.ErrTampered):
+		fmt.Fprintln(out, "🚨 The vault changed outside gpasswd since its last write")
+		fmt.Fprintln(out, "   (entries or history edited, deleted or rolled back).")
+		fmt.Fprintln(out, "   Restore from a backup if you did not expect this, or accept the")
+		fmt.Fprintln(out, "   current state with 'gpasswd fingerprint --reseal'.")
+		return nil
+	default:
+		return fmt.Errorf("failed to verify vault manifest: %w", err)
+	}
+}
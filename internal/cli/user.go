@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user <name>",
+	Short: "Print an entry's username without the master password",
+	Long: `Print the username of an entry, without asking for the master password.
+
+Only entries added or edited with --listable-username keep a copy of their
+username outside the encrypted data; for every other entry the username
+stays encrypted and this command fails. Nothing but the username is
+printed, so the output can be used directly in scripts.
+
+Examples:
+  gpasswd edit deploy-bot --listable-username
+  gpasswd user deploy-bot
+  ssh "$(gpasswd user deploy-bot)@build.example.com"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUser,
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+}
+
+func runUser(cmd *cobra.Command, args []string) error {
+	entryName := args[0]
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// No unlock, but uniform lookup still applies
+	applySecurityConfig(db)
+
+	username, err := db.ListableUsername(entryName)
+	if errors.Is(err, storage.ErrUsernameNotListable) {
+		return fmt.Errorf("the username of '%s' is only stored encrypted; enable it with 'gpasswd edit %s --listable-username'", entryName, entryName)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(username)
+	return nil
+}
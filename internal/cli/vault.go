@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Maintain the vault file",
+}
+
+var vaultGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Prune old history, remove leftovers of deleted entries and compact the vault",
+	Long: `Shrink the vault: drop archived entry versions the history retention no
+longer covers, remove what deleted entries left behind (history, access
+records, unused tags, GPG key bindings) and compact the file with VACUUM.
+
+The retention is the vault's (see 'gpasswd vault retention') or an entry's
+own (edit --history-versions/--history-days). --history-keep and
+--history-days replace both for this run.
+
+The version replaced by an entry's current password is always kept, so
+password ages in audit and stats stay right. Pruned versions can no longer
+be shown with --as-of.
+
+--dry-run only reports what would be removed. Otherwise the report is shown
+and confirmed before anything is removed (unless --force).
+
+Examples:
+  gpasswd vault gc --dry-run
+  gpasswd vault gc --history-keep 10
+  gpasswd vault gc --history-keep 0       (keep all history, only clean up)`,
+	Args: cobra.NoArgs,
+	RunE: runVaultGC,
+}
+
+var vaultRetentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Show or set how much entry history the vault keeps",
+	Long: `Show or set the vault's history retention: how many archived versions
+of each entry are kept, and for how many days after they were replaced.
+0 means no limit; by default everything is kept.
+
+The retention is applied whenever an entry is updated and by
+'gpasswd vault gc'. Entries can override it with
+'gpasswd edit <name> --history-versions N --history-days N'. The version
+replaced by an entry's current password is always kept.
+
+Examples:
+  gpasswd vault retention
+  gpasswd vault retention --versions 10 --days 365
+  gpasswd vault retention --versions 0 --days 0   (keep everything)`,
+	Args: cobra.NoArgs,
+	RunE: runVaultRetention,
+}
+
+var (
+	vaultGCHistoryKeep int
+	vaultGCHistoryDays int
+	vaultGCDryRun      bool
+	vaultGCForce       bool
+
+	vaultRetentionVersions int
+	vaultRetentionDays     int
+)
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultGCCmd)
+	vaultCmd.AddCommand(vaultRetentionCmd)
+
+	vaultGCCmd.Flags().IntVar(&vaultGCHistoryKeep, "history-keep", 0, "Archived versions kept per entry, instead of the retention (0 = all)")
+	vaultGCCmd.Flags().IntVar(&vaultGCHistoryDays, "history-days", 0, "Days archived versions are kept, instead of the retention (0 = no limit)")
+	vaultGCCmd.Flags().BoolVarP(&vaultGCDryRun, "dry-run", "n", false, "Only report what would be removed")
+	vaultGCCmd.Flags().BoolVarP(&vaultGCForce, "force", "f", false, "Skip confirmation prompt")
+
+	vaultRetentionCmd.Flags().IntVar(&vaultRetentionVersions, "versions", 0, "Archived versions kept per entry (0 = no limit)")
+	vaultRetentionCmd.Flags().IntVar(&vaultRetentionDays, "days", 0, "Days archived versions are kept (0 = no limit)")
+}
+
+func runVaultGC(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// History is decrypted to find password changes, and the manifest is
+	// resealed after pruning
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	opts := storage.GCOptions{DryRun: true}
+	if cmd.Flags().Changed("history-keep") || cmd.Flags().Changed("history-days") {
+		opts.Retention = &models.Retention{Versions: vaultGCHistoryKeep, Days: vaultGCHistoryDays}
+	}
+	report, err := db.CollectGarbage(key, opts)
+	if err != nil {
+		return err
+	}
+	printGCReport(report)
+	if vaultGCDryRun {
+		fmt.Println("\nNothing was changed; run again without --dry-run to clean up")
+		return nil
+	}
+
+	if report.Removed() && !vaultGCForce {
+		var confirmed bool
+		prompt := &survey.Confirm{Message: "Remove these for good?", Default: false}
+		if err := askOne(prompt, &confirmed); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("\n❌ Garbage collection cancelled")
+			return nil
+		}
+	}
+
+	opts.DryRun = false
+	report, err = db.CollectGarbage(key, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n✅ Vault compacted: %s → %s\n", formatSize(report.SizeBefore), formatSize(report.SizeAfter))
+	return nil
+}
+
+func runVaultRetention(cmd *cobra.Command, args []string) error {
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	retention, err := db.HistoryRetention()
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("versions") && !cmd.Flags().Changed("days") {
+		fmt.Printf("🗂️  History retention: %s\n", retention)
+		return nil
+	}
+	if vaultRetentionVersions < 0 || vaultRetentionDays < 0 {
+		return fmt.Errorf("--versions and --days cannot be negative")
+	}
+
+	if cmd.Flags().Changed("versions") {
+		retention.Versions = vaultRetentionVersions
+	}
+	if cmd.Flags().Changed("days") {
+		retention.Days = vaultRetentionDays
+	}
+	if err := db.SetHistoryRetention(retention); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ History retention: %s\n", retention)
+	if !retention.Unlimited() {
+		fmt.Println("   Applied when entries are updated; run 'gpasswd vault gc' to prune existing history now")
+	}
+	return nil
+}
+
+// printGCReport lists what garbage collection removes
+func printGCReport(r *storage.GCReport) {
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Println("🧹 Garbage collection")
+	fmt.Println(strings.Repeat("─", 60))
+
+	lines := []struct {
+		label string
+		count int
+		extra string
+	}{
+		{"old history versions", r.HistoryVersions, fmt.Sprintf("of %d entries, %s", r.HistoryEntries, formatSize(r.HistoryBytes))},
+		{"orphaned history", r.OrphanedHistory, ""},
+		{"orphaned access records", r.OrphanedAccess, ""},
+		{"unused tags", r.OrphanedTags, ""},
+		{"orphaned GPG key bindings", r.OrphanedGPGKeys, ""},
+	}
+	for _, line := range lines {
+		if line.count == 0 {
+			continue
+		}
+		if line.extra != "" {
+			fmt.Printf("  %-28s %d (%s)\n", line.label, line.count, line.extra)
+		} else {
+			fmt.Printf("  %-28s %d\n", line.label, line.count)
+		}
+	}
+	if !r.Removed() {
+		fmt.Println("  Nothing to remove")
+	}
+	if r.KeptForAge > 0 {
+		fmt.Printf("  %-28s %d (they date the current passwords)\n", "older versions kept", r.KeptForAge)
+	}
+
+	fmt.Printf("\nVault size: %s, about %s after compacting\n", formatSize(r.SizeBefore), formatSize(r.SizeAfter))
+	fmt.Println(strings.Repeat("─", 60))
+}
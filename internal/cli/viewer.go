@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/export"
+	"github.com/kitsnail/gpasswd/internal/filter"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var viewCmd = &cobra.Command{
+	Use:   "view <bundle.gpb> [name]",
+	Short: "Open a read-only bundle made with export --viewer",
+	Long: `Open a read-only bundle created with 'gpasswd export --viewer'.
+
+No vault is needed: the bundle has its own passphrase, which you should
+have received separately from the file. Without a name the entries in the
+bundle are listed; with a name the entry is shown.
+
+Nothing in the bundle can be changed, and it stops opening after its
+expiry date.
+
+Examples:
+  gpasswd view family.gpb
+  gpasswd view family.gpb netflix --reveal
+  gpasswd view family.gpb netflix --copy`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runView,
+}
+
+var (
+	viewReveal bool
+	viewCopy   bool
+)
+
+func init() {
+	rootCmd.AddCommand(viewCmd)
+
+	viewCmd.Flags().BoolVarP(&viewReveal, "reveal", "r", false, "Reveal the password")
+	viewCmd.Flags().BoolVarP(&viewCopy, "copy", "c", false, "Copy the password to the clipboard")
+}
+
+func runViewerExport() error {
+	entryFilter, err := filter.New(filter.Options{
+		Category: exportCategory,
+		Match:    exportMatch,
+		Glob:     exportGlob,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expires, err := parseExpiry(exportExpires, now)
+	if err != nil {
+		return err
+	}
+
+	if exportOut == "" {
+		return fmt.Errorf("--viewer requires -o <file>%s", export.ViewerExtension)
+	}
+	out := exportOut
+	if !strings.HasSuffix(out, export.ViewerExtension) {
+		out += export.ViewerExtension
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open vault: %w", err)
+	}
+	defer db.Close()
+
+	// Unlock the vault
+	key, err := unlockVault(db, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	entries = entryFilter.Apply(entries)
+	if len(entries) == 0 {
+		return fmt.Errorf("no entries match; nothing to export")
+	}
+
+	fmt.Printf("📦 %d entries will be readable by anyone with the bundle passphrase\n", len(entries))
+	fmt.Println("   Use a passphrase different from your master password")
+
+	var passphrase, confirm string
+	if err := askOne(&survey.Password{Message: "Bundle passphrase:"}, &passphrase, survey.WithValidator(survey.Required)); err != nil {
+		return fmt.Errorf("password prompt failed: %w", err)
+	}
+	if err := askOne(&survey.Password{Message: "Confirm bundle passphrase:"}, &confirm); err != nil {
+		return fmt.Errorf("password prompt failed: %w", err)
+	}
+	if passphrase != confirm {
+		return fmt.Errorf("passphrases do not match")
+	}
+
+	// The bundle is only as safe as its passphrase once it leaves the machine
+	policy, err := exportPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(passphrase); err != nil {
+		if !exportForce {
+			return fmt.Errorf("bundle passphrase rejected: %w. Exports often end up in cloud storage; choose a stronger passphrase or use --force", err)
+		}
+		fmt.Printf("⚠️  Weak bundle passphrase: %v (allowed by --force)\n", err)
+	}
+
+	data, err := export.SealViewer(&export.ViewerBundle{
+		CreatedAt: now,
+		ExpiresAt: expires,
+		Entries:   entries,
+	}, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.WriteFile(out, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	instructionsPath := strings.TrimSuffix(out, export.ViewerExtension) + "-README.txt"
+	instructions := export.ViewerInstructions + "\n"
+	if expires != nil {
+		instructions += "\nThis bundle expires on " + expires.Format("2006-01-02 15:04") + ".\n"
+	}
+	if err := atomicfile.WriteFile(instructionsPath, []byte(instructions), 0644); err != nil {
+		return fmt.Errorf("failed to write instructions: %w", err)
+	}
+
+	fmt.Printf("\n✅ Exported %d entries to %s\n", len(entries), out)
+	fmt.Printf("   Instructions: %s\n", instructionsPath)
+	fmt.Printf("   Expires:      %s\n", formatExpiry(expires, now))
+	fmt.Println("\n💡 Share the passphrase through a different channel than the file")
+	return nil
+}
+
+func runView(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("bundle '%s' not found (to show a vault entry, use 'gpasswd show %s')", path, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	now := time.Now()
+	bundle, err := openViewerBundle(data, now)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		printViewerList(bundle, now)
+		return nil
+	}
+
+	entry := findViewerEntry(bundle.Entries, args[1])
+	if entry == nil {
+		return fmt.Errorf("entry '%s' not found in bundle", args[1])
+	}
+
+	if viewCopy {
+		if err := clipboard.Copy(entry.Password); err != nil {
+			return fmt.Errorf("failed to copy to clipboard: %w", err)
+		}
+	}
+	printViewerEntry(entry, viewReveal)
+	if viewCopy {
+		fmt.Println("\n✅ Password copied to clipboard; clear it when you are done")
+	}
+	return nil
+}
+
+// openViewerBundle asks for the bundle passphrase and decrypts a bundle
+// Expired bundles are refused before asking
+func openViewerBundle(data []byte, now time.Time) (*export.ViewerBundle, error) {
+	expires, err := export.ViewerExpiry(data)
+	if err != nil {
+		return nil, err
+	}
+	if expires != nil && now.After(*expires) {
+		return nil, fmt.Errorf("%w on %s; ask for a new one", export.ErrViewerExpired, expires.Format("2006-01-02"))
+	}
+
+	var passphrase string
+	if err := askOne(&survey.Password{Message: "Bundle passphrase:"}, &passphrase); err != nil {
+		return nil, fmt.Errorf("password prompt failed: %w", err)
+	}
+
+	return export.OpenViewer(data, passphrase, now)
+}
+
+// findViewerEntry looks up an entry by name, case-insensitively
+func findViewerEntry(entries []*models.Entry, name string) *models.Entry {
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name, name) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func printViewerList(bundle *export.ViewerBundle, now time.Time) {
+	fmt.Printf("\n📦 Read-only bundle created %s, expires %s\n\n",
+		bundle.CreatedAt.Format("2006-01-02"), formatExpiry(bundle.ExpiresAt, now))
+
+	for _, entry := range bundle.Entries {
+		line := fmt.Sprintf("  • %s", entry.Name)
+		if entry.Username != "" {
+			line += fmt.Sprintf(" (%s)", entry.Username)
+		}
+		fmt.Printf("%-40s [%s]\n", line, entry.Category)
+	}
+
+	fmt.Printf("\nTotal: %d entries\n", len(bundle.Entries))
+	fmt.Println("\n💡 Show one with: gpasswd view <bundle> <name> --reveal")
+}
+
+func printViewerEntry(entry *models.Entry, reveal bool) {
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("📝 Entry: %s (read-only)\n", entry.Name)
+	fmt.Println(strings.Repeat("─", 60))
+
+	fmt.Printf("Category:    %s\n", entry.Category)
+	if entry.Username != "" {
+		fmt.Printf("Username:    %s\n", entry.Username)
+	}
+	if reveal {
+		fmt.Printf("Password:    %s\n", entry.Password)
+	} else {
+		fmt.Printf("Password:    %s\n", strings.Repeat("•", 12))
+		fmt.Println("             (use --reveal to show)")
+	}
+	if entry.URL != "" {
+		fmt.Printf("URL:         %s\n", entry.URL)
+	}
+	if len(entry.Tags) > 0 {
+		fmt.Printf("Tags:        %s\n", strings.Join(entry.Tags, ", "))
+	}
+	if entry.Notes != "" {
+		fmt.Println("\nNotes:")
+		for _, line := range strings.Split(entry.Notes, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	fmt.Println(strings.Repeat("─", 60))
+}
@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kitsnail/gpasswd/internal/browser"
+	"github.com/kitsnail/gpasswd/internal/clipboard"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/output"
+	"github.com/kitsnail/gpasswd/internal/storage"
+	"github.com/kitsnail/gpasswd/pkg/config"
+)
+
+var workflowCmd = &cobra.Command{
+	Use:   "workflow [query]",
+	Short: "Emit Script Filter JSON with actions for Alfred and Raycast workflows",
+	Long: `List entries as Alfred Script Filter JSON where every item carries its
+actions, so a launcher workflow needs no glue scripts: a Script Filter that
+runs 'gpasswd workflow "{query}"', connected to a Run Script action that
+runs 'gpasswd workflow run "$action" "$1"'.
+
+Actions:
+  copy-password   ↩       copy the password (cleared after the usual timeout)
+  copy-user       ⌘ ↩     copy the username
+  open-url        ⌥ ↩     open the entry's URL in the default browser
+
+Each item sets the workflow variable "action" for the chosen key, and also
+lists its actions with the command line that runs them, for launchers such
+as Raycast that read the JSON directly. Listing needs no master password;
+actions unlock the vault through 'gpasswd agent', a 'gpasswd unlock'
+session or the GPASSWD_ASKPASS program.
+
+Examples:
+  gpasswd workflow github
+  gpasswd workflow run copy-password GitHub
+  gpasswd workflow run open-url GitHub`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWorkflow,
+}
+
+var workflowRunCmd = &cobra.Command{
+	Use:   "run <action> <entry>",
+	Short: "Run a workflow action (copy-password, copy-user, open-url) on an entry",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runWorkflowAction,
+}
+
+// Workflow actions
+const (
+	workflowCopyPassword = "copy-password"
+	workflowCopyUser     = "copy-user"
+	workflowOpenURL      = "open-url"
+)
+
+// workflowActions lists the actions in item order, with the Alfred
+// modifier that selects each one ("" for plain Enter)
+var workflowActions = []struct {
+	ID       string
+	Title    string
+	Modifier string
+}{
+	{workflowCopyPassword, "Copy password", ""},
+	{workflowCopyUser, "Copy username", "cmd"},
+	{workflowOpenURL, "Open URL", "alt"},
+}
+
+func init() {
+	rootCmd.AddCommand(workflowCmd)
+	workflowCmd.AddCommand(workflowRunCmd)
+}
+
+func runWorkflow(cmd *cobra.Command, args []string) error {
+	query := ""
+	if len(args) == 1 {
+		query = args[0]
+	}
+
+	db, _, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := db.ListEntries()
+	if err != nil {
+		return fmt.Errorf("failed to list entries: %w", err)
+	}
+	return writeWorkflowItems(os.Stdout, entries, query)
+}
+
+func runWorkflowAction(cmd *cobra.Command, args []string) error {
+	action, name := args[0], args[1]
+	switch action {
+	case workflowCopyPassword, workflowCopyUser, workflowOpenURL:
+	default:
+		return fmt.Errorf("unknown action %q (expected %s, %s or %s)", action, workflowCopyPassword, workflowCopyUser, workflowOpenURL)
+	}
+
+	db, cfg, err := openVaultDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key, err := unlockVault(db, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByName(name, key)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	recordAccess(db, entry, "workflow")
+
+	if action == workflowOpenURL {
+		if entry.URL == "" {
+			return fmt.Errorf("entry '%s' has no URL", entry.Name)
+		}
+		if err := browser.Open(entry.URL); err != nil {
+			return err
+		}
+		fmt.Printf("🌐 Opened %s\n", entry.URL)
+		return nil
+	}
+
+	if err := output.ConfirmReveal(entry, "copy it to the clipboard"); err != nil {
+		return err
+	}
+	if err := requireFreshUnlock(db, entry, os.Stderr); err != nil {
+		return err
+	}
+
+	field := "password"
+	if action == workflowCopyUser {
+		field = "user"
+	}
+	value, _ := entryField(entry, field)
+	if value == "" {
+		return fmt.Errorf("entry '%s' has no %s", entry.Name, strings.ToLower(fieldLabel(field)))
+	}
+
+	if err := clipboard.Copy(value); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	timeout := time.Duration(clipboardTimeout(cfg, entry, 0)) * time.Second
+	if err := startMenuHelper(menuFinishClear, timeout, ""); err != nil {
+		// Don't leave the secret behind if the clear can't be scheduled
+		clipboard.Clear()
+		return err
+	}
+
+	// Alfred can pass this line on to a notification
+	fmt.Printf("✅ %s for '%s' copied to clipboard (cleared in %s)\n", fieldLabel(field), entry.Name, timeout)
+	return nil
+}
+
+// openVaultDB opens the vault database without unlocking it
+func openVaultDB() (*storage.DB, *config.Config, error) {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine database path
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = config.GetVaultPath()
+	}
+
+	// Check if vault exists
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("vault not initialized. Run 'gpasswd init' first")
+	}
+
+	// Open database
+	db, err := storage.InitDB(dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open vault: %w", err)
+	}
+	return db, cfg, nil
+}
+
+// workflowItem is a Script Filter row with per-action modifiers
+type workflowItem struct {
+	UID          string                 `json:"uid"`
+	Title        string                 `json:"title"`
+	Subtitle     string                 `json:"subtitle"`
+	Arg          string                 `json:"arg"`
+	Autocomplete string                 `json:"autocomplete"`
+	Match        string                 `json:"match"`
+	Variables    map[string]string      `json:"variables"`
+	Mods         map[string]workflowMod `json:"mods"`
+	Actions      []workflowAction       `json:"actions"`
+}
+
+// workflowMod is the behaviour of an item with a modifier key held
+type workflowMod struct {
+	Subtitle  string            `json:"subtitle"`
+	Arg       string            `json:"arg"`
+	Variables map[string]string `json:"variables"`
+}
+
+// workflowAction describes one action for launchers that run commands
+// themselves
+type workflowAction struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Command []string `json:"command"`
+}
+
+// writeWorkflowItems writes entries whose name or category contains query
+// as Script Filter JSON with their actions
+func writeWorkflowItems(w io.Writer, entries []*models.Entry, query string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "gpasswd"
+	}
+
+	items := make([]workflowItem, 0, len(entries))
+	for _, entry := range entries {
+		match, ok := launcherMatch(entry, query)
+		if !ok {
+			continue
+		}
+
+		item := workflowItem{
+			UID:          entry.ID,
+			Title:        entry.Name,
+			Autocomplete: entry.Name,
+			Match:        match,
+			Arg:          entry.Name,
+			Mods:         make(map[string]workflowMod),
+		}
+		for _, action := range workflowActions {
+			subtitle := action.Title
+			if entry.Category != "" {
+				subtitle = entry.Category + " · " + subtitle
+			}
+			variables := map[string]string{"action": action.ID}
+
+			if action.Modifier == "" {
+				item.Subtitle, item.Variables = subtitle, variables
+			} else {
+				item.Mods[action.Modifier] = workflowMod{Subtitle: subtitle, Arg: entry.Name, Variables: variables}
+			}
+			item.Actions = append(item.Actions, workflowAction{
+				ID:      action.ID,
+				Title:   action.Title,
+				Command: []string{executable, "workflow", "run", action.ID, entry.Name},
+			})
+		}
+		items = append(items, item)
+	}
+	return writeScriptFilter(w, items)
+}
@@ -3,13 +3,11 @@ package clipboard
 import (
 	"fmt"
 	"time"
-
-	"github.com/atotto/clipboard"
 )
 
 // Copy copies text to the system clipboard
 func Copy(text string) error {
-	if err := clipboard.WriteAll(text); err != nil {
+	if err := writeText(text); err != nil {
 		return fmt.Errorf("failed to copy to clipboard: %w", err)
 	}
 	return nil
@@ -17,7 +15,7 @@ func Copy(text string) error {
 
 // Clear clears the clipboard
 func Clear() error {
-	if err := clipboard.WriteAll(""); err != nil {
+	if err := writeText(""); err != nil {
 		return fmt.Errorf("failed to clear clipboard: %w", err)
 	}
 	return nil
@@ -43,7 +41,7 @@ func CopyWithAutoClear(text string, duration time.Duration) (<-chan bool, error)
 
 // Get retrieves the current clipboard content
 func Get() (string, error) {
-	content, err := clipboard.ReadAll()
+	content, err := readText()
 	if err != nil {
 		return "", fmt.Errorf("failed to read clipboard: %w", err)
 	}
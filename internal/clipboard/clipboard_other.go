@@ -0,0 +1,27 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"github.com/atotto/clipboard"
+
+	"github.com/kitsnail/gpasswd/internal/platform"
+)
+
+// writeText replaces the clipboard content
+// Inside Termux the Android clipboard is used, even when X11 clipboard
+// tools are installed
+func writeText(text string) error {
+	if platform.InTermux() {
+		return termuxWrite(text)
+	}
+	return clipboard.WriteAll(text)
+}
+
+// readText returns the clipboard content
+func readText() (string, error) {
+	if platform.InTermux() {
+		return termuxRead()
+	}
+	return clipboard.ReadAll()
+}
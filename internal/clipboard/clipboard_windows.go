@@ -0,0 +1,120 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/atotto/clipboard"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32   = windows.NewLazySystemDLL("user32.dll")
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	openClipboard           = user32.NewProc("OpenClipboard")
+	closeClipboard          = user32.NewProc("CloseClipboard")
+	emptyClipboard          = user32.NewProc("EmptyClipboard")
+	setClipboardData        = user32.NewProc("SetClipboardData")
+	registerClipboardFormat = user32.NewProc("RegisterClipboardFormatW")
+
+	globalAlloc  = kernel32.NewProc("GlobalAlloc")
+	globalFree   = kernel32.NewProc("GlobalFree")
+	globalLock   = kernel32.NewProc("GlobalLock")
+	globalUnlock = kernel32.NewProc("GlobalUnlock")
+	moveMemory   = kernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+// privateFormats keep a copied secret out of clipboard history (Win+V),
+// cloud clipboard sync and clipboard monitors
+var privateFormats = []string{
+	"ExcludeClipboardContentFromMonitorProcessing",
+	"CanIncludeInClipboardHistory",
+	"CanUploadToCloudClipboard",
+}
+
+// readText returns the clipboard content
+func readText() (string, error) {
+	return clipboard.ReadAll()
+}
+
+// writeText replaces the clipboard content, marking it as private
+func writeText(text string) error {
+	// The clipboard belongs to the thread that opened it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Another program may hold the clipboard for a moment
+	for attempt := 0; ; attempt++ {
+		if r, _, err := openClipboard.Call(0); r != 0 {
+			break
+		} else if attempt == 20 {
+			return fmt.Errorf("failed to open clipboard: %w", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer closeClipboard.Call()
+
+	if r, _, err := emptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("failed to empty clipboard: %w", err)
+	}
+	if text == "" {
+		return nil
+	}
+
+	utf16, err := windows.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&utf16[0])), len(utf16)*2)
+	if err := setData(cfUnicodeText, data); err != nil {
+		return err
+	}
+
+	for _, name := range privateFormats {
+		namePtr, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			return err
+		}
+		format, _, _ := registerClipboardFormat.Call(uintptr(unsafe.Pointer(namePtr)))
+		if format == 0 {
+			continue // Older Windows versions lack these formats
+		}
+		if err := setData(format, []byte{0, 0, 0, 0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setData copies data into global memory and hands it to the clipboard
+func setData(format uintptr, data []byte) error {
+	handle, _, err := globalAlloc.Call(gmemMoveable, uintptr(len(data)))
+	if handle == 0 {
+		return fmt.Errorf("failed to allocate clipboard memory: %w", err)
+	}
+
+	ptr, _, err := globalLock.Call(handle)
+	if ptr == 0 {
+		globalFree.Call(handle)
+		return fmt.Errorf("failed to lock clipboard memory: %w", err)
+	}
+	moveMemory.Call(ptr, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)))
+	globalUnlock.Call(handle)
+
+	// On success the clipboard owns the memory
+	if r, _, err := setClipboardData.Call(format, handle); r == 0 {
+		globalFree.Call(handle)
+		return fmt.Errorf("failed to set clipboard data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Termux reaches the Android clipboard through the Termux:API add-on
+const (
+	termuxClipboardSet = "termux-clipboard-set"
+	termuxClipboardGet = "termux-clipboard-get"
+
+	// The termux-api commands hang when the Termux:API app is missing
+	termuxTimeout = 10 * time.Second
+)
+
+var errTermuxAPI = errors.New("the Android clipboard needs Termux:API: install the Termux:API app and run 'pkg install termux-api'")
+
+// termuxWrite replaces the Android clipboard content
+func termuxWrite(text string) error {
+	_, err := runTermux(termuxClipboardSet, text)
+	return err
+}
+
+// termuxRead returns the Android clipboard content
+func termuxRead() (string, error) {
+	out, err := runTermux(termuxClipboardGet, "")
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+func runTermux(command, stdin string) (string, error) {
+	if _, err := exec.LookPath(command); err != nil {
+		return "", errTermuxAPI
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), termuxTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%s did not respond; %w", command, errTermuxAPI)
+		}
+		return "", fmt.Errorf("%s failed: %w", command, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// commonPasswordsGz is the bundled common-password corpus: one lower-case
+// password per line, gzip-compressed. Regenerate it with
+// scripts/update-common-passwords.sh
+//
+//go:embed data/common-passwords.txt.gz
+var commonPasswordsGz []byte
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// isCommonPassword reports whether a password is in the bundled corpus
+func isCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		set, err := loadCommonPasswords()
+		if err != nil {
+			// The corpus is embedded at build time, so this is a packaging bug
+			panic(fmt.Sprintf("failed to load bundled common-password list: %v", err))
+		}
+		commonPasswords = set
+	})
+
+	_, found := commonPasswords[strings.ToLower(password)]
+	return found
+}
+
+func loadCommonPasswords() (map[string]struct{}, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(commonPasswordsGz))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return readPasswordList(gz)
+}
+
+// LoadDenylistFile reads a custom denylist with one password per line
+// Blank lines and lines starting with # are ignored. Files ending in .gz
+// are decompressed
+func LoadDenylistFile(path string) (Denylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open denylist file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress denylist file: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	set, err := readPasswordList(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read denylist file: %w", err)
+	}
+	return Denylist(set), nil
+}
+
+// readPasswordList reads one password per line into a lower-cased set
+func readPasswordList(r io.Reader) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strings"
+)
+
+// fingerprintEmoji are 64 easily told apart symbols, one per 6 bits
+var fingerprintEmoji = []string{
+	"🐶", "🐱", "🐭", "🐰", "🦊", "🐻", "🐼", "🐨",
+	"🐯", "🦁", "🐮", "🐷", "🐸", "🐵", "🐔", "🐧",
+	"🐦", "🦆", "🦉", "🐴", "🦄", "🐝", "🐛", "🦋",
+	"🐌", "🐞", "🐢", "🐍", "🐙", "🦀", "🐠", "🐬",
+	"🐳", "🦈", "🐊", "🦒", "🐘", "🦔", "🌵", "🌲",
+	"🌴", "🍀", "🍁", "🍄", "🌻", "🌹", "🌙", "⭐",
+	"🔥", "🌈", "💧", "⛄", "🍎", "🍋", "🍌", "🍇",
+	"🍓", "🥕", "🌽", "🍩", "🎈", "🎸", "🚀", "🔑",
+}
+
+// FingerprintLength is the number of emoji in a password fingerprint
+const FingerprintLength = 3
+
+// PasswordFingerprint returns a short emoji digest of the key derived from
+// a master password, so a typed password can be recognized after the key
+// derivation without showing anything about the password itself
+// It is computed from the Argon2 output rather than the password: someone
+// who records the screen and copies the vault still pays a full key
+// derivation per guess, and the three emoji (18 bits) only rule guesses out
+func PasswordFingerprint(derivedKey []byte) string {
+	mac := hmac.New(sha256.New, derivedKey)
+	mac.Write([]byte("gpasswd password fingerprint v2"))
+	sum := mac.Sum(nil)
+
+	var b strings.Builder
+	for i := 0; i < FingerprintLength; i++ {
+		b.WriteString(fingerprintEmoji[sum[i]&0x3f])
+	}
+	return b.String()
+}
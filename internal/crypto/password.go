@@ -33,6 +33,10 @@ type GenerateOptions struct {
 	UseDigits        bool
 	UseSymbols       bool
 	ExcludeAmbiguous bool
+
+	// Pronounceable makes consonant-vowel syllables instead of picking
+	// every character at random; see GeneratorEntropy for what that costs
+	Pronounceable bool
 }
 
 // StrengthLevel represents password strength
@@ -70,37 +74,11 @@ type StrengthResult struct {
 	Feedback []string // Suggestions for improvement
 }
 
-// Common weak passwords to check against
-var commonPasswords = map[string]bool{
-	"password":    true,
-	"password1":   true,
-	"password123": true,
-	"12345678":    true,
-	"123456789":   true,
-	"qwerty":      true,
-	"abc123":      true,
-	"monkey":      true,
-	"1234567":     true,
-	"letmein":     true,
-	"trustno1":    true,
-	"dragon":      true,
-	"baseball":    true,
-	"111111":      true,
-	"iloveyou":    true,
-	"master":      true,
-	"sunshine":    true,
-	"ashley":      true,
-	"bailey":      true,
-	"passw0rd":    true,
-	"shadow":      true,
-	"123123":      true,
-	"654321":      true,
-	"superman":    true,
-	"qazwsx":      true,
-}
-
 // Generate creates a random password with specified options
 func Generate(length int, options GenerateOptions) (string, error) {
+	if options.Pronounceable {
+		return generatePronounceable(length, options)
+	}
 	return generateWithRetries(length, options, 0)
 }
 
@@ -257,7 +235,7 @@ func CheckStrength(password string) StrengthResult {
 	}
 
 	// Check if it's a common password
-	if commonPasswords[strings.ToLower(password)] {
+	if isCommonPassword(password) {
 		result.Level = VeryWeak
 		result.Score = 0
 		result.Feedback = append(result.Feedback, "This is a commonly used password")
@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy is the minimum strength required for stored passwords
+// A zero Policy accepts every password
+type Policy struct {
+	MinScore   int      // Minimum CheckStrength score (0-100, 0 = disabled)
+	MinEntropy float64  // Minimum estimated entropy in bits (0 = disabled)
+	Denylist   Denylist // Passwords that are never allowed
+}
+
+// Denylist is a case-insensitive set of forbidden passwords
+type Denylist map[string]struct{}
+
+// NewDenylist builds a denylist from passwords
+func NewDenylist(passwords ...string) Denylist {
+	d := make(Denylist, len(passwords))
+	d.Add(passwords...)
+	return d
+}
+
+// Add adds passwords to the denylist
+func (d Denylist) Add(passwords ...string) {
+	for _, password := range passwords {
+		if password != "" {
+			d[strings.ToLower(password)] = struct{}{}
+		}
+	}
+}
+
+// Contains reports whether a password is on the denylist
+func (d Denylist) Contains(password string) bool {
+	_, found := d[strings.ToLower(password)]
+	return found
+}
+
+// DefaultPolicy rejects passwords that CheckStrength rates below Fair
+func DefaultPolicy() Policy {
+	return Policy{MinScore: 40}
+}
+
+// Violations returns the reasons a password fails the policy
+// An empty result means the password is acceptable
+func (p Policy) Violations(password string) []string {
+	var violations []string
+
+	if p.Denylist.Contains(password) {
+		violations = append(violations, "password is on the denylist")
+	}
+
+	if p.MinScore > 0 {
+		strength := CheckStrength(password)
+		if strength.Score < p.MinScore {
+			violations = append(violations,
+				fmt.Sprintf("strength score %d/100 is below the required %d", strength.Score, p.MinScore))
+		}
+	}
+
+	if p.MinEntropy > 0 {
+		if entropy := calculateEntropy(password); entropy < p.MinEntropy {
+			violations = append(violations,
+				fmt.Sprintf("estimated entropy %.0f bits is below the required %.0f", entropy, p.MinEntropy))
+		}
+	}
+
+	return violations
+}
+
+// Check returns an error describing every violation, or nil
+func (p Policy) Check(password string) error {
+	violations := p.Violations(password)
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("password does not meet the strength policy: %s", strings.Join(violations, "; "))
+}
+
+// maxCompliantAttempts bounds GenerateCompliant's retries
+const maxCompliantAttempts = 20
+
+// GenerateCompliant generates a password that satisfies the policy
+func GenerateCompliant(length int, options GenerateOptions, policy Policy) (string, error) {
+	for attempt := 0; attempt < maxCompliantAttempts; attempt++ {
+		password, err := Generate(length, options)
+		if err != nil {
+			return "", err
+		}
+		if len(policy.Violations(password)) == 0 {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("could not generate a %d-character password that meets the strength policy; try a longer length", length)
+}
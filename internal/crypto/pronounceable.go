@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Letters of pronounceable passwords; q, x and y are left out because they
+// make syllables hard to read out
+const (
+	syllableConsonants          = "bcdfghjkmnprstvwz" // Excluded: l (ambiguous)
+	syllableConsonantsAmbiguous = "bcdfghjklmnprstvwz"
+	syllableVowels              = "aeiou"
+	syllableVowelsUpper         = "AEU" // Excluded: I, O (ambiguous)
+)
+
+// generatePronounceable creates a password of consonant-vowel syllables,
+// e.g. "kobaTimeru7!": easy to type on a phone since it switches keyboards
+// at most twice. With uppercase and lowercase enabled one syllable starts
+// with a capital; a digit and a symbol, if enabled, end the password
+func generatePronounceable(length int, options GenerateOptions) (string, error) {
+	if length < MinPasswordLength {
+		return "", fmt.Errorf("password length must be at least %d", MinPasswordLength)
+	}
+	if length > MaxPasswordLength {
+		return "", fmt.Errorf("password length must not exceed %d", MaxPasswordLength)
+	}
+
+	consonants, vowels, err := syllableLetters(options)
+	if err != nil {
+		return "", err
+	}
+	letters := length - pronounceableTail(options)
+
+	var password strings.Builder
+	for i := 0; i < letters; i++ {
+		set := consonants
+		if i%2 == 1 {
+			set = vowels
+		}
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		password.WriteByte(c)
+	}
+
+	result := []byte(password.String())
+	if options.UseUppercase && options.UseLowercase {
+		syllable, err := randomIndex((letters + 1) / 2)
+		if err != nil {
+			return "", err
+		}
+		result[syllable*2] = byte(strings.ToUpper(string(result[syllable*2]))[0])
+	}
+
+	if options.UseDigits {
+		set := digitCharsAmbiguous
+		if options.ExcludeAmbiguous {
+			set = digitChars
+		}
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, c)
+	}
+	if options.UseSymbols {
+		c, err := randomChar(symbolChars)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, c)
+	}
+
+	return string(result), nil
+}
+
+// GeneratorEntropy estimates the entropy in bits of the passwords Generate
+// makes with these options, which is what an attacker who knows the
+// options has to guess; 0 if the options are invalid
+func GeneratorEntropy(length int, options GenerateOptions) float64 {
+	if !options.Pronounceable {
+		charset := buildCharset(options)
+		if charset == "" {
+			return 0
+		}
+		return float64(length) * math.Log2(float64(len(charset)))
+	}
+
+	consonants, vowels, err := syllableLetters(options)
+	if err != nil {
+		return 0
+	}
+	letters := length - pronounceableTail(options)
+	if letters <= 0 {
+		return 0
+	}
+
+	syllables := (letters + 1) / 2
+	bits := float64(syllables)*math.Log2(float64(len(consonants))) +
+		float64(letters/2)*math.Log2(float64(len(vowels)))
+	if options.UseUppercase && options.UseLowercase {
+		bits += math.Log2(float64(syllables))
+	}
+	if options.UseDigits {
+		if options.ExcludeAmbiguous {
+			bits += math.Log2(float64(len(digitChars)))
+		} else {
+			bits += math.Log2(float64(len(digitCharsAmbiguous)))
+		}
+	}
+	if options.UseSymbols {
+		bits += math.Log2(float64(len(symbolChars)))
+	}
+	return bits
+}
+
+// syllableLetters returns the consonants and vowels syllables are made of
+func syllableLetters(options GenerateOptions) (consonants, vowels string, err error) {
+	if !options.UseUppercase && !options.UseLowercase {
+		return "", "", errors.New("pronounceable passwords need uppercase or lowercase letters")
+	}
+
+	consonants, vowels = syllableConsonantsAmbiguous, syllableVowels
+	if options.ExcludeAmbiguous {
+		consonants = syllableConsonants
+	}
+	if !options.UseLowercase {
+		consonants = strings.ToUpper(consonants)
+		vowels = strings.ToUpper(vowels)
+		if options.ExcludeAmbiguous {
+			vowels = syllableVowelsUpper
+		}
+	}
+	return consonants, vowels, nil
+}
+
+// pronounceableTail returns how many digits and symbols end a pronounceable
+// password
+func pronounceableTail(options GenerateOptions) int {
+	tail := 0
+	if options.UseDigits {
+		tail++
+	}
+	if options.UseSymbols {
+		tail++
+	}
+	return tail
+}
+
+// randomChar returns a random character of set
+func randomChar(set string) (byte, error) {
+	i, err := randomIndex(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[i], nil
+}
+
+// randomIndex returns a random number in [0, n)
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return int(i.Int64()), nil
+}
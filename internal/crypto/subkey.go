@@ -0,0 +1,15 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// FieldsKey derives the sub-key that encrypts the disclosable fields of an
+// entry: its metadata and notes. Holding it reveals those fields but not
+// passwords, OTP secrets or private keys, which need the vault key itself
+func FieldsKey(key []byte) []byte {
+	derive := hmac.New(sha256.New, key)
+	derive.Write([]byte("gpasswd entry fields v1"))
+	return derive.Sum(nil)
+}
@@ -0,0 +1,69 @@
+// Package deeplink parses gpasswd:// links and registers gpasswd as the
+// handler for them with the operating system
+//
+// A link names an action and an entry, e.g. gpasswd://copy/github or
+// gpasswd://copy/Gmail%20Work?field=user. Links only ever identify an
+// entry; the handler always asks for the master password before acting, so
+// a web page cannot use one to read a secret on its own
+package deeplink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Scheme is the URL scheme gpasswd handles
+const Scheme = "gpasswd"
+
+// Actions
+const (
+	ActionCopy = "copy" // copy a field of the entry to the clipboard
+)
+
+// ErrUnsupported is returned by Register and Unregister on platforms
+// without a supported way to register URL handlers
+var ErrUnsupported = errors.New("registering a URL handler is not supported on this platform")
+
+// Link is a parsed gpasswd:// link
+type Link struct {
+	Action string
+	Entry  string // entry name
+	Field  string // field to copy; "" = password
+}
+
+// Parse parses a gpasswd:// link
+func Parse(raw string) (*Link, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(parsed.Scheme, Scheme) {
+		return nil, fmt.Errorf("not a %s:// link: %s", Scheme, raw)
+	}
+
+	// gpasswd://copy/github puts the action in the host; gpasswd:copy/github
+	// (written by some launchers) puts everything in the opaque part
+	action, name := parsed.Host, parsed.Path
+	if parsed.Opaque != "" {
+		action, name, _ = strings.Cut(parsed.Opaque, "/")
+		if name, err = url.PathUnescape(name); err != nil {
+			return nil, fmt.Errorf("invalid link: %w", err)
+		}
+	}
+	name = strings.Trim(name, "/")
+
+	link := &Link{
+		Action: strings.ToLower(action),
+		Entry:  name,
+		Field:  parsed.Query().Get("field"),
+	}
+	if link.Action != ActionCopy {
+		return nil, fmt.Errorf("unknown link action %q (expected %s)", action, ActionCopy)
+	}
+	if link.Entry == "" {
+		return nil, errors.New("link does not name an entry")
+	}
+	return link, nil
+}
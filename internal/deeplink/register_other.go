@@ -0,0 +1,14 @@
+//go:build (!unix || darwin) && !windows
+
+package deeplink
+
+// Register is not supported here: macOS only hands URLs to application
+// bundles, through Apple Events rather than the command line
+func Register(executable string) error {
+	return ErrUnsupported
+}
+
+// Unregister is not supported here, see Register
+func Unregister() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package deeplink
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// classesKey is the per-user key URL schemes are registered under
+const classesKey = `Software\Classes\` + Scheme
+
+// Register makes executable the handler for gpasswd:// links for the
+// current user
+func Register(executable string) error {
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, classesKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to create registry key: %w", err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue("", "URL:gpasswd Protocol"); err != nil {
+		return fmt.Errorf("failed to register scheme: %w", err)
+	}
+	if err := key.SetStringValue("URL Protocol", ""); err != nil {
+		return fmt.Errorf("failed to register scheme: %w", err)
+	}
+
+	command, _, err := registry.CreateKey(registry.CURRENT_USER, classesKey+`\shell\open\command`, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to create registry key: %w", err)
+	}
+	defer command.Close()
+	if err := command.SetStringValue("", fmt.Sprintf(`"%s" protocol-handler open "%%1"`, executable)); err != nil {
+		return fmt.Errorf("failed to register handler command: %w", err)
+	}
+	return nil
+}
+
+// Unregister removes the registry keys written by Register
+func Unregister() error {
+	for _, path := range []string{
+		classesKey + `\shell\open\command`,
+		classesKey + `\shell\open`,
+		classesKey + `\shell`,
+		classesKey,
+	} {
+		if err := registry.DeleteKey(registry.CURRENT_USER, path); err != nil && err != registry.ErrNotExist {
+			return fmt.Errorf("failed to remove registry key %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+//go:build unix && !darwin
+
+package deeplink
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+)
+
+// desktopFile is the name of the desktop entry that claims the scheme
+const desktopFile = "gpasswd-url-handler.desktop"
+
+// Register installs a desktop entry that runs executable for gpasswd://
+// links and makes it the default handler with xdg-mime
+func Register(executable string) error {
+	dir := applicationsDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	entry := strings.Join([]string{
+		"[Desktop Entry]",
+		"Type=Application",
+		"Name=gpasswd",
+		"Comment=Open gpasswd:// links",
+		fmt.Sprintf("Exec=%s protocol-handler open %%u", desktopQuote(executable)),
+		"Terminal=false",
+		"NoDisplay=true",
+		"MimeType=x-scheme-handler/" + Scheme + ";",
+		"",
+	}, "\n")
+	path := filepath.Join(dir, desktopFile)
+	if err := atomicfile.WriteFile(path, []byte(entry), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if out, err := exec.Command("xdg-mime", "default", desktopFile, "x-scheme-handler/"+Scheme).CombinedOutput(); err != nil {
+		return fmt.Errorf("wrote %s but xdg-mime failed: %v %s", path, err, strings.TrimSpace(string(out)))
+	}
+	// Refreshing the cache is optional; not every desktop ships the tool
+	exec.Command("update-desktop-database", dir).Run()
+	return nil
+}
+
+// Unregister removes the desktop entry written by Register
+func Unregister() error {
+	path := filepath.Join(applicationsDir(), desktopFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	exec.Command("update-desktop-database", applicationsDir()).Run()
+	return nil
+}
+
+// applicationsDir returns the per-user directory for desktop entries
+func applicationsDir() string {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "applications")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "applications")
+}
+
+// desktopQuote quotes an Exec argument as the desktop entry spec requires
+func desktopQuote(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"'\\`$") {
+		return arg
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	return `"` + replacer.Replace(arg) + `"`
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/storage"
+)
+
+// ArchiveExtension is the file extension of encrypted archives
+const ArchiveExtension = ".gpx"
+
+const (
+	archiveFormat  = "gpasswd-archive"
+	archiveVersion = 1
+)
+
+// ErrArchivePassword is returned when the archive password is wrong
+var ErrArchivePassword = errors.New("wrong master password or corrupted archive")
+
+// Archive is the decrypted content of a .gpx archive: every entry plus the
+// vault metadata needed to recreate the vault it came from
+type Archive struct {
+	CreatedAt time.Time              `json:"created_at"`
+	Metadata  *storage.VaultMetadata `json:"metadata"`
+	Entries   []*models.Entry        `json:"entries"`
+}
+
+// archiveEnvelope is the on-disk layout of an archive
+// The KDF parameters are the vault's, so the archive opens with the master
+// password the vault had when it was exported
+type archiveEnvelope struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Cipher  string    `json:"cipher"`
+	KDF     viewerKDF `json:"kdf"`
+	Data    []byte    `json:"data"`
+}
+
+// SealArchive encrypts an archive with AES-256-GCM under the vault key
+// derived from the metadata's salt and Argon2 parameters
+func SealArchive(archive *Archive, key []byte) ([]byte, error) {
+	if archive.Metadata == nil {
+		return nil, errors.New("archive has no vault metadata")
+	}
+
+	plaintext, err := json.Marshal(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive: %w", err)
+	}
+	ciphertext, err := crypto.Encrypt(plaintext, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt archive: %w", err)
+	}
+
+	params := archive.Metadata.Argon2
+	envelope := archiveEnvelope{
+		Format:  archiveFormat,
+		Version: archiveVersion,
+		Cipher:  "aes-256-gcm",
+		KDF: viewerKDF{
+			Salt:        archive.Metadata.Salt,
+			Time:        params.Time,
+			Memory:      params.Memory,
+			Parallelism: params.Parallelism,
+		},
+		Data: ciphertext,
+	}
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// OpenArchive decrypts an archive with the master password of the vault it
+// was exported from
+func OpenArchive(data []byte, password string) (*Archive, error) {
+	var envelope archiveEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Format != archiveFormat {
+		return nil, errors.New("not a gpasswd archive")
+	}
+	if envelope.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", envelope.Version)
+	}
+
+	params := crypto.Argon2Params{
+		Time:        envelope.KDF.Time,
+		Memory:      envelope.KDF.Memory,
+		Parallelism: envelope.KDF.Parallelism,
+		KeyLen:      32,
+	}
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid archive key parameters: %w", err)
+	}
+	key, err := crypto.DeriveKey(password, envelope.KDF.Salt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := crypto.Decrypt(envelope.Data, key)
+	if err != nil {
+		return nil, ErrArchivePassword
+	}
+
+	var archive Archive
+	if err := json.Unmarshal(plaintext, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse archive: %w", err)
+	}
+	return &archive, nil
+}
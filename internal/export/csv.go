@@ -0,0 +1,169 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Chrome's password import wizard expects exactly these columns
+var chromeCSVHeader = []string{"name", "url", "username", "password", "note"}
+
+// Firefox's "Import from a File" expects these columns (times in ms since epoch)
+var firefoxCSVHeader = []string{
+	"url", "username", "password", "httpRealm", "formActionOrigin",
+	"guid", "timeCreated", "timeLastUsed", "timePasswordChanged",
+}
+
+// writeChromeCSV writes entries in the layout produced and accepted by Chrome
+func writeChromeCSV(w io.Writer, entries []*models.Entry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(chromeCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, entry := range entries {
+		if browserUnsupported(entry) != "" {
+			continue
+		}
+		record := []string{entry.Name, browserURL(entry.URL), entry.Username, entry.Password, entry.Notes}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write entry %s: %w", entry.Name, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeFirefoxCSV writes entries in the layout produced and accepted by Firefox
+func writeFirefoxCSV(w io.Writer, entries []*models.Entry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(firefoxCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, entry := range entries {
+		if browserUnsupported(entry) != "" {
+			continue
+		}
+		created := strconv.FormatInt(entry.CreatedAt.UnixMilli(), 10)
+		updated := strconv.FormatInt(entry.UpdatedAt.UnixMilli(), 10)
+
+		record := []string{
+			browserURL(entry.URL), entry.Username, entry.Password,
+			"",                   // httpRealm (only used for HTTP auth logins)
+			urlOrigin(entry.URL), // formActionOrigin
+			"{" + entry.ID + "}", // Firefox GUIDs are brace-wrapped
+			created, updated, updated,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write entry %s: %w", entry.Name, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// browserUnsupported returns why a browser cannot import entry, "" if it can
+func browserUnsupported(entry *models.Entry) string {
+	switch {
+	case !entry.IsLogin():
+		return "not a login"
+	case entry.Password == "":
+		return "no password"
+	case entry.URL == "":
+		return "no URL"
+	case browserURL(entry.URL) == "":
+		return "not a web URL"
+	}
+	return ""
+}
+
+// browserURL returns rawURL with a missing https scheme added, or "" when
+// it is not an http(s) URL with a host
+func browserURL(rawURL string) string {
+	parsed := parseWebURL(rawURL)
+	if parsed == nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+// urlOrigin returns scheme://host[:port] of rawURL, as Firefox stores
+// form action origins; "" when it is not a web URL
+func urlOrigin(rawURL string) string {
+	parsed := parseWebURL(rawURL)
+	if parsed == nil {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// parseWebURL parses an http(s) URL, adding a missing https scheme
+func parseWebURL(rawURL string) *url.URL {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	return parsed
+}
+
+// csvHeader lists the columns of the generic CSV format
+var csvHeader = []string{
+	"name", "category", "type", "username", "password", "url", "notes", "tags",
+	"otp", "service", "scopes", "expires_at", "security_level",
+	"change_password_url", "created_at", "updated_at",
+}
+
+// writeCSV writes every field of entries; lists are comma-joined and
+// times are RFC 3339
+func writeCSV(w io.Writer, entries []*models.Entry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, entry := range entries {
+		expires := ""
+		if entry.ExpiresAt != nil {
+			expires = entry.ExpiresAt.Format(time.RFC3339)
+		}
+
+		record := []string{
+			entry.Name, entry.Category, entry.Type, entry.Username, entry.Password,
+			entry.URL, entry.Notes, strings.Join(entry.Tags, ","),
+			entry.OTP, entry.Service, strings.Join(entry.Scopes, ","), expires,
+			entry.SecurityLevel, entry.ChangePasswordURL,
+			entry.CreatedAt.Format(time.RFC3339), entry.UpdatedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write entry %s: %w", entry.Name, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+func TestBrowserCSV(t *testing.T) {
+	entries := []*models.Entry{
+		{ID: "1", Name: "GitHub", Username: "octocat", Password: "pw", URL: "GitHub.com/login"},
+		{ID: "2", Name: "Router", Username: "admin", Password: "pw", URL: "http://192.168.1.1:8080/admin"},
+		{ID: "3", Name: "Note", Type: models.EntryTypeNote, Notes: "text", URL: "https://example.com"},
+		{ID: "4", Name: "Key", Type: models.EntryTypeKey, Password: "secret", URL: "https://example.com"},
+		{ID: "5", Name: "SSO", Username: "me", URL: "https://sso.example.com"},
+		{ID: "6", Name: "Server", Username: "root", Password: "pw", URL: "ssh://host"},
+		{ID: "7", Name: "No URL", Username: "me", Password: "pw"},
+	}
+
+	var chrome bytes.Buffer
+	if err := Write(&chrome, FormatChromeCSV, entries); err != nil {
+		t.Fatal(err)
+	}
+	wantChrome := "name,url,username,password,note\n" +
+		"GitHub,https://github.com/login,octocat,pw,\n" +
+		"Router,http://192.168.1.1:8080/admin,admin,pw,\n"
+	if chrome.String() != wantChrome {
+		t.Errorf("chrome-csv:\n%s\nwant:\n%s", chrome.String(), wantChrome)
+	}
+
+	var firefox bytes.Buffer
+	if err := Write(&firefox, FormatFirefoxCSV, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(firefox.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("firefox-csv has %d lines, want 3:\n%s", len(lines), firefox.String())
+	}
+	for i, prefix := range []string{
+		"https://github.com/login,octocat,pw,,https://github.com,{1},",
+		"http://192.168.1.1:8080/admin,admin,pw,,http://192.168.1.1:8080,{2},",
+	} {
+		if !strings.HasPrefix(lines[i+1], prefix) {
+			t.Errorf("firefox-csv row %d = %q, want prefix %q", i+1, lines[i+1], prefix)
+		}
+	}
+
+	for _, entry := range entries[2:] {
+		if FormatChromeCSV.Unsupported(entry) == "" {
+			t.Errorf("%s: expected to be unsupported by chrome-csv", entry.Name)
+		}
+		if FormatJSON.Unsupported(entry) != "" {
+			t.Errorf("%s: expected to be supported by json", entry.Name)
+		}
+	}
+}
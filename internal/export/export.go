@@ -0,0 +1,90 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Format identifies an export file layout
+type Format string
+
+const (
+	// FormatChromeCSV is the CSV layout used by Chrome's password manager
+	FormatChromeCSV Format = "chrome-csv"
+
+	// FormatFirefoxCSV is the CSV layout used by Firefox's password manager
+	FormatFirefoxCSV Format = "firefox-csv"
+
+	// FormatJSON is every field of every entry as JSON
+	FormatJSON Format = "json"
+
+	// FormatCSV is every field of every entry as CSV, one row per entry
+	FormatCSV Format = "csv"
+
+	// FormatArchive is an encrypted .gpx archive (see SealArchive); it is
+	// not written by Write
+	FormatArchive Format = "gpx"
+
+	// FormatPass is a pass(1) password store of GPG-encrypted files (see
+	// WritePassStore); it is not written by Write
+	FormatPass Format = "pass"
+)
+
+// Formats lists all supported export formats
+var Formats = []Format{FormatChromeCSV, FormatFirefoxCSV, FormatJSON, FormatCSV, FormatArchive, FormatPass}
+
+// ParseFormat validates a user-supplied format name
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unsupported export format %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
+// Unsupported returns why entry cannot be represented in the format, ""
+// if it can. Browser importers only take logins with a password and a
+// web URL
+func (f Format) Unsupported(entry *models.Entry) string {
+	if f != FormatChromeCSV && f != FormatFirefoxCSV {
+		return ""
+	}
+	return browserUnsupported(entry)
+}
+
+// Complete reports whether every field is written, including notes and
+// one-time password secrets
+func (f Format) Complete() bool {
+	return f == FormatJSON || f == FormatCSV || f == FormatArchive || f == FormatPass
+}
+
+// Encrypted reports whether the export is unreadable without a password
+// or key
+func (f Format) Encrypted() bool {
+	return f == FormatArchive || f == FormatPass
+}
+
+// Write serializes decrypted entries to w in the given format
+func Write(w io.Writer, format Format, entries []*models.Entry) error {
+	switch format {
+	case FormatChromeCSV:
+		return writeChromeCSV(w, entries)
+	case FormatFirefoxCSV:
+		return writeFirefoxCSV(w, entries)
+	case FormatJSON:
+		return writeJSON(w, entries)
+	case FormatCSV:
+		return writeCSV(w, entries)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
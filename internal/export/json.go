@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+const (
+	jsonFormat  = "gpasswd-export"
+	jsonVersion = 1
+)
+
+// jsonExport is the layout of a JSON export
+type jsonExport struct {
+	Format     string          `json:"format"`
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Entries    []*models.Entry `json:"entries"`
+}
+
+// writeJSON writes every field of entries as an indented JSON document
+func writeJSON(w io.Writer, entries []*models.Entry) error {
+	doc := jsonExport{
+		Format:     jsonFormat,
+		Version:    jsonVersion,
+		ExportedAt: time.Now().UTC(),
+		Entries:    entries,
+	}
+	if doc.Entries == nil {
+		doc.Entries = []*models.Entry{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// PassIDFile lists the GPG key IDs a password store is encrypted for
+const PassIDFile = ".gpg-id"
+
+// Encrypter encrypts a password-store file for the given GPG recipients
+type Encrypter func(recipients []string, plaintext []byte) ([]byte, error)
+
+// GPGEncrypt encrypts with the gpg binary on PATH, with the options pass
+// itself uses
+func GPGEncrypt(recipients []string, plaintext []byte) ([]byte, error) {
+	args := []string{"--quiet", "--batch", "--yes", "--compress-algo=none", "--no-encrypt-to", "--encrypt"}
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gpg", args...) // #nosec G204 -- fixed binary, key ID arguments
+	cmd.Stdin = bytes.NewReader(plaintext)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// PassRecipients returns the key IDs in dir's .gpg-id, or nil when the
+// directory is not a password store yet
+func PassRecipients(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PassIDFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PassIDFile, err)
+	}
+
+	var recipients []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			recipients = append(recipients, line)
+		}
+	}
+	return recipients, nil
+}
+
+// WritePassStore writes every entry as a GPG-encrypted file in the
+// password-store layout under dir, the way 'gpasswd import pass' reads it:
+// the category becomes the directory ("work/aws/root.gpg") and the file
+// holds the password, then login:, url:, tags:, the otpauth:// URI and the
+// notes. A .gpg-id is written when dir has none
+// It returns the number of files written
+func WritePassStore(dir string, recipients []string, entries []*models.Entry, encrypt Encrypter) (int, error) {
+	if len(recipients) == 0 {
+		return 0, errors.New("no GPG recipients")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create password store: %w", err)
+	}
+
+	idFile := filepath.Join(dir, PassIDFile)
+	if _, err := os.Stat(idFile); errors.Is(err, os.ErrNotExist) {
+		ids := strings.Join(recipients, "\n") + "\n"
+		if err := atomicfile.WriteFile(idFile, []byte(ids), 0600); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", PassIDFile, err)
+		}
+	}
+
+	taken := make(map[string]bool)
+	for i, entry := range entries {
+		rel := passPath(entry)
+		for n := 2; taken[rel]; n++ {
+			rel = fmt.Sprintf("%s (%d)", passPath(entry), n)
+		}
+		taken[rel] = true
+
+		ciphertext, err := encrypt(recipients, []byte(PassFile(entry)))
+		if err != nil {
+			return i, fmt.Errorf("failed to encrypt '%s': %w", entry.Name, err)
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(rel)+".gpg")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return i, fmt.Errorf("failed to create directory for '%s': %w", entry.Name, err)
+		}
+		if err := atomicfile.WriteFile(path, ciphertext, 0600); err != nil {
+			return i, fmt.Errorf("failed to write '%s': %w", entry.Name, err)
+		}
+	}
+	return len(entries), nil
+}
+
+// PassFile returns the plaintext pass file for an entry
+func PassFile(entry *models.Entry) string {
+	var b strings.Builder
+	b.WriteString(strings.SplitN(entry.Password, "\n", 2)[0])
+	b.WriteString("\n")
+	if entry.Username != "" {
+		fmt.Fprintf(&b, "login: %s\n", entry.Username)
+	}
+	if entry.URL != "" {
+		fmt.Fprintf(&b, "url: %s\n", entry.URL)
+	}
+	if len(entry.Tags) > 0 {
+		fmt.Fprintf(&b, "tags: %s\n", strings.Join(entry.Tags, ", "))
+	}
+	if entry.OTP != "" {
+		// pass-otp keeps the URI on a line of its own
+		b.WriteString(entry.OTP + "\n")
+	}
+	if entry.Notes != "" {
+		b.WriteString(strings.TrimRight(entry.Notes, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// passPath returns the store path of an entry without the .gpg extension
+// Path separators in the name and ".." segments are neutralized so every
+// file lands inside the store
+func passPath(entry *models.Entry) string {
+	clean := func(segment string) string {
+		segment = strings.TrimSpace(strings.NewReplacer("/", "-", "\\", "-").Replace(segment))
+		if segment == "" || segment == "." || segment == ".." || strings.HasPrefix(segment, ".") {
+			segment = "_" + strings.TrimLeft(segment, ".")
+		}
+		return segment
+	}
+
+	var segments []string
+	for _, part := range strings.Split(entry.Category, "/") {
+		if strings.TrimSpace(part) != "" {
+			segments = append(segments, clean(part))
+		}
+	}
+	return strings.Join(append(segments, clean(entry.Name)), "/")
+}
@@ -0,0 +1,271 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+)
+
+// QR streams carry a vault snapshot as an endless sequence of QR frames
+// The first frames hold the payload blocks in order; every later frame is
+// the XOR of a pseudo-random subset of blocks (a rateless fountain code),
+// so a scanner that collects slightly more frames than there are blocks
+// can rebuild the payload no matter which frames it missed
+
+// QRStreamPrefix starts every frame so scanners can recognize them
+const QRStreamPrefix = "GPQR1:"
+
+const (
+	qrStreamHeaderSize = 4 + 4 + 4 + 4 // stream ID, blocks, payload size, sequence
+
+	// DefaultQRBlockSize keeps frames small enough for terminal rendering
+	DefaultQRBlockSize = 256
+
+	// maxQRStreamSize bounds the payload a decoder accepts; a larger one
+	// would take hours to scan, so frames claiming it are malformed
+	maxQRStreamSize = 4 << 20
+)
+
+// ErrQRStreamMismatch is returned for a frame of a different stream
+var ErrQRStreamMismatch = errors.New("frame belongs to a different QR stream")
+
+// QRStream encodes a payload into frames
+type QRStream struct {
+	id        uint32
+	blockSize int
+	blocks    [][]byte
+	size      int
+}
+
+// NewQRStream splits a payload (see QRStreamPayload) into blocks
+func NewQRStream(payload []byte, blockSize int) (*QRStream, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("empty payload")
+	}
+	if blockSize < 16 {
+		return nil, fmt.Errorf("block size %d is too small", blockSize)
+	}
+
+	digest := sha256.Sum256(payload)
+	s := &QRStream{
+		id:        binary.BigEndian.Uint32(digest[:4]),
+		blockSize: blockSize,
+		size:      len(payload),
+	}
+	for start := 0; start < len(payload); start += blockSize {
+		block := make([]byte, blockSize)
+		copy(block, payload[start:])
+		s.blocks = append(s.blocks, block)
+	}
+	return s, nil
+}
+
+// Blocks returns the number of blocks; at least this many frames are needed
+func (s *QRStream) Blocks() int {
+	return len(s.blocks)
+}
+
+// Frame returns frame seq as QR text; any seq is valid
+func (s *QRStream) Frame(seq uint32) string {
+	data := make([]byte, s.blockSize)
+	for i, included := range qrFrameMix(s.id, len(s.blocks), seq) {
+		if included {
+			for j, b := range s.blocks[i] {
+				data[j] ^= b
+			}
+		}
+	}
+
+	frame := make([]byte, 0, qrStreamHeaderSize+len(data))
+	frame = binary.BigEndian.AppendUint32(frame, s.id)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(s.blocks)))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(s.size))
+	frame = binary.BigEndian.AppendUint32(frame, seq)
+	frame = append(frame, data...)
+	return QRStreamPrefix + base64.RawURLEncoding.EncodeToString(frame)
+}
+
+// qrFrameMix returns which blocks frame seq combines
+// Frames below the block count are the blocks themselves; later frames
+// pick each block with probability 1/2 from a hash of the stream and seq
+func qrFrameMix(id uint32, blocks int, seq uint32) []bool {
+	mix := make([]bool, blocks)
+	if int(seq) < blocks {
+		mix[seq] = true
+		return mix
+	}
+
+	var seed [8]byte
+	binary.BigEndian.PutUint32(seed[:4], id)
+	binary.BigEndian.PutUint32(seed[4:], seq)
+	var stream []byte
+	for counter := uint32(0); len(stream)*8 < blocks; counter++ {
+		h := sha256.New()
+		h.Write(seed[:])
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		stream = h.Sum(stream)
+	}
+
+	picked := false
+	for i := range mix {
+		mix[i] = stream[i/8]&(1<<(i%8)) != 0
+		picked = picked || mix[i]
+	}
+	if !picked {
+		mix[int(seq)%blocks] = true
+	}
+	return mix
+}
+
+// QRStreamDecoder rebuilds a payload from frames in any order
+// Each frame is reduced against the ones already seen (Gaussian
+// elimination over GF(2)), so duplicate and dependent frames are harmless
+type QRStreamDecoder struct {
+	id        uint32
+	blocks    int
+	blockSize int
+	size      int
+	rows      map[int]qrRow // Pivot block index -> reduced row
+}
+
+type qrRow struct {
+	mix  []uint64
+	data []byte
+}
+
+// Add decodes one scanned frame and reports whether the payload is complete
+func (d *QRStreamDecoder) Add(text string) (bool, error) {
+	if !strings.HasPrefix(text, QRStreamPrefix) {
+		return false, errors.New("not a gpasswd QR stream frame")
+	}
+	frame, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, QRStreamPrefix))
+	if err != nil || len(frame) <= qrStreamHeaderSize {
+		return false, errors.New("malformed QR stream frame")
+	}
+
+	id := binary.BigEndian.Uint32(frame[0:])
+	blocks := int(binary.BigEndian.Uint32(frame[4:]))
+	size := int(binary.BigEndian.Uint32(frame[8:]))
+	seq := binary.BigEndian.Uint32(frame[12:])
+	data := append([]byte(nil), frame[qrStreamHeaderSize:]...)
+	if size == 0 || size > maxQRStreamSize || blocks != (size+len(data)-1)/len(data) {
+		return false, errors.New("malformed QR stream frame")
+	}
+
+	if d.rows == nil {
+		d.id, d.blocks, d.blockSize, d.size = id, blocks, len(data), size
+		d.rows = make(map[int]qrRow)
+	} else if id != d.id || blocks != d.blocks || len(data) != d.blockSize || size != d.size {
+		return false, ErrQRStreamMismatch
+	}
+
+	mix := make([]uint64, (blocks+63)/64)
+	for i, included := range qrFrameMix(id, blocks, seq) {
+		if included {
+			mix[i/64] |= 1 << (i % 64)
+		}
+	}
+	row := qrRow{mix: mix, data: data}
+
+	// Eliminate known pivots, then use the lowest remaining block as pivot
+	for pivot, known := range d.rows {
+		if row.has(pivot) {
+			row.xor(known)
+		}
+	}
+	pivot := row.lowest()
+	if pivot < 0 {
+		return d.Complete(), nil // Nothing new
+	}
+	for other, known := range d.rows {
+		if known.has(pivot) {
+			known.xor(row)
+			d.rows[other] = known
+		}
+	}
+	d.rows[pivot] = row
+	return d.Complete(), nil
+}
+
+// Complete reports whether every block has been recovered
+func (d *QRStreamDecoder) Complete() bool {
+	return d.rows != nil && len(d.rows) == d.blocks
+}
+
+// Progress returns recovered and total blocks
+func (d *QRStreamDecoder) Progress() (int, int) {
+	return len(d.rows), d.blocks
+}
+
+// Payload returns the rebuilt payload once Complete
+func (d *QRStreamDecoder) Payload() ([]byte, error) {
+	if !d.Complete() {
+		return nil, errors.New("QR stream is incomplete")
+	}
+	var payload []byte
+	for i := 0; i < d.blocks; i++ {
+		payload = append(payload, d.rows[i].data...)
+	}
+	return payload[:d.size], nil
+}
+
+func (r qrRow) has(block int) bool {
+	return r.mix[block/64]&(1<<(block%64)) != 0
+}
+
+func (r qrRow) xor(other qrRow) {
+	for i := range r.mix {
+		r.mix[i] ^= other.mix[i]
+	}
+	for i := range r.data {
+		r.data[i] ^= other.data[i]
+	}
+}
+
+func (r qrRow) lowest() int {
+	for i, word := range r.mix {
+		if word != 0 {
+			return i*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// QRStreamPayload compresses a vault snapshot and adds a checksum
+func QRStreamPayload(snapshot []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(snapshot); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(buf.Bytes())
+	return append(digest[:8], buf.Bytes()...), nil
+}
+
+// OpenQRStreamPayload verifies and decompresses a rebuilt payload
+func OpenQRStreamPayload(payload []byte) ([]byte, error) {
+	if len(payload) < 8 {
+		return nil, errors.New("QR stream payload is truncated")
+	}
+	digest := sha256.Sum256(payload[8:])
+	if !bytes.Equal(digest[:8], payload[:8]) {
+		return nil, errors.New("QR stream payload checksum mismatch")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(payload[8:]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QR stream payload: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,187 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// testSnapshot returns size bytes that gzip can't shrink much
+func testSnapshot(size int) []byte {
+	rng := rand.New(rand.NewPCG(1, 2))
+	snapshot := make([]byte, size)
+	for i := range snapshot {
+		snapshot[i] = byte(rng.Uint32())
+	}
+	return snapshot
+}
+
+func TestQRStreamRoundTrip(t *testing.T) {
+	snapshot := testSnapshot(5000)
+	payload, err := QRStreamPayload(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := NewQRStream(payload, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := stream.Blocks()
+
+	tests := []struct {
+		name string
+		seqs func() []uint32
+	}{
+		{"in order", func() []uint32 {
+			var seqs []uint32
+			for seq := range blocks {
+				seqs = append(seqs, uint32(seq))
+			}
+			return seqs
+		}},
+		{"fountain frames only", func() []uint32 {
+			var seqs []uint32
+			for seq := range 3 * blocks {
+				seqs = append(seqs, uint32(blocks+seq))
+			}
+			return seqs
+		}},
+		{"every other frame, repeated", func() []uint32 {
+			var seqs []uint32
+			for seq := 0; seq < 4*blocks; seq += 2 {
+				seqs = append(seqs, uint32(seq), uint32(seq))
+			}
+			return seqs
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoder QRStreamDecoder
+			complete := false
+			for _, seq := range tt.seqs() {
+				if complete, err = decoder.Add(stream.Frame(seq)); err != nil {
+					t.Fatal(err)
+				}
+				if complete {
+					break
+				}
+			}
+			if !complete {
+				got, total := decoder.Progress()
+				t.Fatalf("stream incomplete: %d of %d blocks", got, total)
+			}
+
+			rebuilt, err := decoder.Payload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			opened, err := OpenQRStreamPayload(rebuilt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(opened, snapshot) {
+				t.Error("rebuilt snapshot differs")
+			}
+		})
+	}
+}
+
+// rawFrame encodes a frame header and data without checking them
+func rawFrame(id, blocks, size, seq uint32, data []byte) string {
+	frame := binary.BigEndian.AppendUint32(nil, id)
+	frame = binary.BigEndian.AppendUint32(frame, blocks)
+	frame = binary.BigEndian.AppendUint32(frame, size)
+	frame = binary.BigEndian.AppendUint32(frame, seq)
+	return QRStreamPrefix + base64.RawURLEncoding.EncodeToString(append(frame, data...))
+}
+
+func TestQRStreamDecoderMalformedFrames(t *testing.T) {
+	block := make([]byte, 32)
+	for _, frame := range []string{
+		"",
+		"otpauth://totp/x",
+		QRStreamPrefix + "!!!",
+		QRStreamPrefix + base64.RawURLEncoding.EncodeToString(make([]byte, qrStreamHeaderSize)),
+		rawFrame(1, 0, 10, 0, block),          // no blocks
+		rawFrame(1, 1, 0, 0, block),           // empty payload
+		rawFrame(1, 1, 100, 0, block),         // more bytes than blocks hold
+		rawFrame(1, 1<<31, 100, 0, block),     // block count that doesn't fit the size
+		rawFrame(1, 1<<26, 1<<31, 0, block),   // oversized payload
+		rawFrame(1, 2, 64, 0, block[:31])[:5], // truncated prefix
+	} {
+		var decoder QRStreamDecoder
+		if _, err := decoder.Add(frame); err == nil {
+			t.Errorf("Add(%q) accepted a malformed frame", frame)
+		}
+		if decoder.Complete() {
+			t.Errorf("Add(%q) completed the stream", frame)
+		}
+	}
+}
+
+func TestQRStreamDecoderMismatch(t *testing.T) {
+	payload, err := QRStreamPayload(testSnapshot(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := NewQRStream(payload, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoder QRStreamDecoder
+	if _, err := decoder.Add(stream.Frame(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same stream ID, block count and size, but longer blocks
+	blocks, size := uint32(stream.Blocks()), uint32(len(payload))
+	longer := rawFrame(stream.id, blocks, size, blocks, make([]byte, 33))
+	if _, err := decoder.Add(longer); !errors.Is(err, ErrQRStreamMismatch) {
+		t.Errorf("frame with another block size: err = %v, want ErrQRStreamMismatch", err)
+	}
+
+	different, err := NewQRStream(append(payload, 0), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decoder.Add(different.Frame(1)); !errors.Is(err, ErrQRStreamMismatch) {
+		t.Errorf("frame of another stream: err = %v, want ErrQRStreamMismatch", err)
+	}
+	if _, err := decoder.Payload(); err == nil {
+		t.Error("an incomplete stream returned a payload")
+	}
+}
+
+func TestOpenQRStreamPayloadRejectsCorruption(t *testing.T) {
+	payload, err := QRStreamPayload([]byte("vault snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), payload...)
+	flipped[len(flipped)-1] ^= 1
+
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"truncated": payload[:len(payload)-4],
+		"flipped":   flipped,
+		"not gzip":  checksummed([]byte(strings.Repeat("x", 32))),
+	} {
+		if _, err := OpenQRStreamPayload(data); err == nil {
+			t.Errorf("%s payload was accepted", name)
+		}
+	}
+}
+
+// checksummed adds a valid checksum to data that is not gzip
+func checksummed(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	return append(digest[:8], data...)
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// ViewerExtension is the file extension of read-only viewer bundles
+const ViewerExtension = ".gpb"
+
+const (
+	viewerFormat  = "gpasswd-viewer"
+	viewerVersion = 1
+)
+
+// ViewerInstructions explains how to open a bundle; it is stored
+// unencrypted in the bundle and shown when exporting
+const ViewerInstructions = `This file is a read-only gpasswd bundle. To open it:
+
+  1. Install gpasswd (https://github.com/kitsnail/gpasswd)
+  2. Run: gpasswd view <file>.gpb
+  3. Enter the bundle passphrase you were given separately
+
+No vault or master password is needed. The bundle cannot be changed and
+stops opening after its expiry date.`
+
+// ErrViewerExpired is returned when opening a bundle past its expiry
+var ErrViewerExpired = errors.New("this bundle has expired")
+
+// ErrViewerPassphrase is returned when the bundle passphrase is wrong
+var ErrViewerPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// ViewerBundle is the decrypted content of a read-only bundle
+type ViewerBundle struct {
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Entries   []*models.Entry `json:"entries"`
+}
+
+// viewerEnvelope is the on-disk layout of a bundle
+// ExpiresAt is repeated outside the ciphertext so expired bundles are
+// refused before asking for the passphrase; the encrypted copy is the
+// authoritative one
+type viewerEnvelope struct {
+	Format       string     `json:"format"`
+	Version      int        `json:"version"`
+	Instructions string     `json:"instructions"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	KDF          viewerKDF  `json:"kdf"`
+	Data         []byte     `json:"data"`
+}
+
+type viewerKDF struct {
+	Salt        []byte `json:"salt"`
+	Time        uint32 `json:"time"`
+	Memory      uint32 `json:"memory"`
+	Parallelism uint8  `json:"parallelism"`
+}
+
+// SealViewer encrypts a bundle with its own passphrase
+func SealViewer(bundle *ViewerBundle, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("bundle passphrase cannot be empty")
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	params := crypto.DefaultArgon2Params()
+	key, err := crypto.DeriveKey(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	ciphertext, err := crypto.Encrypt(plaintext, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+
+	envelope := viewerEnvelope{
+		Format:       viewerFormat,
+		Version:      viewerVersion,
+		Instructions: ViewerInstructions,
+		ExpiresAt:    bundle.ExpiresAt,
+		KDF: viewerKDF{
+			Salt:        salt,
+			Time:        params.Time,
+			Memory:      params.Memory,
+			Parallelism: params.Parallelism,
+		},
+		Data: ciphertext,
+	}
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// ViewerExpiry returns the advertised expiry of a bundle without
+// decrypting it, so callers can refuse early
+func ViewerExpiry(data []byte) (*time.Time, error) {
+	envelope, err := parseViewerEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.ExpiresAt, nil
+}
+
+// OpenViewer decrypts a bundle, refusing it after its expiry
+func OpenViewer(data []byte, passphrase string, now time.Time) (*ViewerBundle, error) {
+	envelope, err := parseViewerEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	params := crypto.Argon2Params{
+		Time:        envelope.KDF.Time,
+		Memory:      envelope.KDF.Memory,
+		Parallelism: envelope.KDF.Parallelism,
+		KeyLen:      32,
+	}
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bundle key parameters: %w", err)
+	}
+	key, err := crypto.DeriveKey(passphrase, envelope.KDF.Salt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := crypto.Decrypt(envelope.Data, key)
+	if err != nil {
+		return nil, ErrViewerPassphrase
+	}
+
+	var bundle ViewerBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if bundle.ExpiresAt != nil && now.After(*bundle.ExpiresAt) {
+		return nil, ErrViewerExpired
+	}
+	return &bundle, nil
+}
+
+func parseViewerEnvelope(data []byte) (*viewerEnvelope, error) {
+	var envelope viewerEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Format != viewerFormat {
+		return nil, errors.New("not a gpasswd viewer bundle")
+	}
+	if envelope.Version != viewerVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", envelope.Version)
+	}
+	return &envelope, nil
+}
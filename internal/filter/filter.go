@@ -0,0 +1,181 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// TagMode controls how multiple tags are combined
+type TagMode string
+
+const (
+	// TagModeAll requires an entry to carry every requested tag
+	TagModeAll TagMode = "all"
+
+	// TagModeAny requires an entry to carry at least one requested tag
+	TagModeAny TagMode = "any"
+)
+
+// Options describes the user-facing selection flags shared by list, export
+// and other commands that operate on several entries at once
+type Options struct {
+	Category string   // Exact category (case-insensitive)
+	Match    string   // Regular expression matched against name or category
+	Glob     string   // Shell-style glob matched against name or category (case-insensitive)
+	Tags     []string // Tags to require (case-insensitive)
+	TagMode  TagMode  // How Tags are combined (default: all)
+}
+
+// Filter selects entries by metadata
+// The zero value matches every entry
+type Filter struct {
+	category string
+	match    *regexp.Regexp
+	glob     *regexp.Regexp
+	tags     []string
+	tagMode  TagMode
+}
+
+// New compiles filter options
+func New(opts Options) (*Filter, error) {
+	f := &Filter{
+		category: opts.Category,
+		tagMode:  opts.TagMode,
+	}
+
+	if f.tagMode == "" {
+		f.tagMode = TagModeAll
+	}
+	if f.tagMode != TagModeAll && f.tagMode != TagModeAny {
+		return nil, fmt.Errorf("invalid tag mode %q (expected all or any)", opts.TagMode)
+	}
+
+	for _, tag := range opts.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			f.tags = append(f.tags, strings.ToLower(tag))
+		}
+	}
+
+	if opts.Match != "" {
+		re, err := regexp.Compile(opts.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --match regular expression: %w", err)
+		}
+		f.match = re
+	}
+
+	if opts.Glob != "" {
+		re, err := compileGlob(opts.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --glob pattern: %w", err)
+		}
+		f.glob = re
+	}
+
+	return f, nil
+}
+
+// IsEmpty reports whether the filter matches everything
+func (f *Filter) IsEmpty() bool {
+	return f.category == "" && f.match == nil && f.glob == nil && len(f.tags) == 0
+}
+
+// NeedsTags reports whether matching requires entry tags to be populated
+func (f *Filter) NeedsTags() bool {
+	return len(f.tags) > 0
+}
+
+// Matches reports whether an entry satisfies every configured condition
+func (f *Filter) Matches(entry *models.Entry) bool {
+	if f.category != "" && !strings.EqualFold(entry.Category, f.category) {
+		return false
+	}
+
+	if f.match != nil && !f.match.MatchString(entry.Name) && !f.match.MatchString(entry.Category) {
+		return false
+	}
+
+	if f.glob != nil && !f.glob.MatchString(entry.Name) && !f.glob.MatchString(entry.Category) {
+		return false
+	}
+
+	if len(f.tags) > 0 && !f.matchesTags(entry.Tags) {
+		return false
+	}
+
+	return true
+}
+
+// matchesTags applies the tag mode to an entry's tags
+func (f *Filter) matchesTags(entryTags []string) bool {
+	has := make(map[string]bool, len(entryTags))
+	for _, tag := range entryTags {
+		has[strings.ToLower(tag)] = true
+	}
+
+	for _, tag := range f.tags {
+		if f.tagMode == TagModeAny && has[tag] {
+			return true
+		}
+		if f.tagMode == TagModeAll && !has[tag] {
+			return false
+		}
+	}
+
+	return f.tagMode == TagModeAll
+}
+
+// Apply returns the entries that match the filter, preserving order
+func (f *Filter) Apply(entries []*models.Entry) []*models.Entry {
+	if f.IsEmpty() {
+		return entries
+	}
+
+	matched := make([]*models.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if f.Matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// compileGlob converts a shell-style glob into an anchored regular expression
+// Supports * (any run of characters, including "/"), ? (one character)
+// and [...] character classes
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+	pattern.WriteString("(?i)^")
+
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		case '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			pattern.WriteString("[" + class + "]")
+			i = end
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	pattern.WriteString("$")
+	return regexp.Compile(pattern.String())
+}
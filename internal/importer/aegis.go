@@ -0,0 +1,232 @@
+package importer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/otp"
+)
+
+// PasswordFunc asks the user for a backup password when one is needed
+type PasswordFunc func() (string, error)
+
+// aegisSlotPassword is the key slot type protected by the backup password
+const aegisSlotPassword = 1
+
+type aegisExport struct {
+	Version int `json:"version"`
+	Header  struct {
+		Slots  []aegisSlot     `json:"slots"`
+		Params *aegisKeyParams `json:"params"`
+	} `json:"header"`
+	DB json.RawMessage `json:"db"` // object when plain, base64 string when encrypted
+}
+
+type aegisSlot struct {
+	Type      int            `json:"type"`
+	Key       string         `json:"key"`
+	KeyParams aegisKeyParams `json:"key_params"`
+	N         int            `json:"n"`
+	R         int            `json:"r"`
+	P         int            `json:"p"`
+	Salt      string         `json:"salt"`
+}
+
+type aegisKeyParams struct {
+	Nonce string `json:"nonce"`
+	Tag   string `json:"tag"`
+}
+
+type aegisDB struct {
+	Entries []struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Issuer string `json:"issuer"`
+		Note   string `json:"note"`
+		Info   struct {
+			Secret  string `json:"secret"`
+			Algo    string `json:"algo"`
+			Digits  int    `json:"digits"`
+			Period  int    `json:"period"`
+			Counter uint64 `json:"counter"`
+		} `json:"info"`
+	} `json:"entries"`
+}
+
+// ParseAegis parses an Aegis vault export, plain or password-encrypted
+//
+// Entries hold only an OTP secret (no password); the caller matches them to
+// existing vault entries. Steam, mOTP and Yandex entries are skipped since
+// they are not standard TOTP/HOTP
+func ParseAegis(data []byte, password PasswordFunc) (*Result, error) {
+	var export aegisExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("not an Aegis export: %w", err)
+	}
+	if len(export.DB) == 0 {
+		return nil, errors.New("not an Aegis export: missing db")
+	}
+
+	plainDB := []byte(export.DB)
+	if export.Header.Slots != nil {
+		decrypted, err := decryptAegisDB(&export, password)
+		if err != nil {
+			return nil, err
+		}
+		plainDB = decrypted
+	}
+
+	var db aegisDB
+	if err := json.Unmarshal(plainDB, &db); err != nil {
+		return nil, fmt.Errorf("failed to parse Aegis entries: %w", err)
+	}
+
+	result := &Result{}
+	for i, item := range db.Entries {
+		source := item.Issuer
+		if source == "" {
+			source = fmt.Sprintf("entry %d", i+1)
+		}
+
+		kind := otp.Type(strings.ToLower(item.Type))
+		if kind != otp.TypeTOTP && kind != otp.TypeHOTP {
+			result.skip(source, fmt.Sprintf("unsupported OTP type %q", item.Type))
+			continue
+		}
+
+		secret, err := otp.DecodeSecret(item.Info.Secret)
+		if err != nil {
+			result.skip(source, err.Error())
+			continue
+		}
+
+		key := otp.NewKey(secret)
+		key.Type = kind
+		key.Issuer = item.Issuer
+		key.Account = item.Name
+		key.Counter = item.Info.Counter
+		if item.Info.Algo != "" {
+			key.Algorithm = otp.Algorithm(strings.ToUpper(item.Info.Algo))
+		}
+		if item.Info.Digits != 0 {
+			key.Digits = item.Info.Digits
+		}
+		if item.Info.Period != 0 {
+			key.Period = item.Info.Period
+		}
+
+		result.Entries = append(result.Entries, otpEntry(key, item.Note))
+	}
+
+	return result, nil
+}
+
+// decryptAegisDB unlocks the master key with the backup password and
+// decrypts the vault contents
+func decryptAegisDB(export *aegisExport, password PasswordFunc) ([]byte, error) {
+	if export.Header.Params == nil {
+		return nil, errors.New("encrypted Aegis export is missing its parameters")
+	}
+
+	var encoded string
+	if err := json.Unmarshal(export.DB, &encoded); err != nil {
+		return nil, fmt.Errorf("encrypted Aegis export has an invalid db: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted Aegis export has an invalid db: %w", err)
+	}
+
+	pass, err := password()
+	if err != nil {
+		return nil, err
+	}
+
+	hasPasswordSlot := false
+	for _, slot := range export.Header.Slots {
+		if slot.Type != aegisSlotPassword {
+			continue
+		}
+		hasPasswordSlot = true
+
+		salt, err := hex.DecodeString(slot.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aegis key slot salt: %w", err)
+		}
+		derived, err := scrypt.Key([]byte(pass), salt, slot.N, slot.R, slot.P, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive Aegis slot key: %w", err)
+		}
+
+		encryptedKey, err := hex.DecodeString(slot.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aegis key slot: %w", err)
+		}
+		masterKey, err := aesGCMOpen(derived, slot.KeyParams, encryptedKey)
+		if err != nil {
+			// Wrong password for this slot; try the next one
+			continue
+		}
+
+		plaintext, err := aesGCMOpen(masterKey, *export.Header.Params, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt Aegis vault: %w", err)
+		}
+		return plaintext, nil
+	}
+
+	if !hasPasswordSlot {
+		return nil, errors.New("Aegis export has no password slot (biometric-only backups cannot be imported)")
+	}
+	return nil, errors.New("wrong Aegis backup password")
+}
+
+// aesGCMOpen decrypts Aegis' AES-256-GCM format, where the tag is stored
+// separately from the ciphertext
+func aesGCMOpen(key []byte, params aegisKeyParams, ciphertext []byte) ([]byte, error) {
+	nonce, err := hex.DecodeString(params.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	tag, err := hex.DecodeString(params.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(append(sealed, ciphertext...), tag...)
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// otpEntry builds an OTP-only entry named after the issuer
+func otpEntry(key *otp.Key, notes string) *models.Entry {
+	name := key.Issuer
+	if name == "" {
+		name = key.Account
+	}
+
+	return &models.Entry{
+		Name:     name,
+		Username: key.Account,
+		Notes:    notes,
+		OTP:      key.URI(),
+	}
+}
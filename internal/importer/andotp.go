@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/kitsnail/gpasswd/internal/otp"
+)
+
+type andOTPEntry struct {
+	Secret    string   `json:"secret"`
+	Issuer    string   `json:"issuer"`
+	Label     string   `json:"label"`
+	Digits    int      `json:"digits"`
+	Type      string   `json:"type"`
+	Algorithm string   `json:"algorithm"`
+	Period    int      `json:"period"`
+	Counter   uint64   `json:"counter"`
+	Tags      []string `json:"tags"`
+}
+
+// andOTP encrypted backup layout (since andOTP 0.6.3):
+// 4-byte big-endian PBKDF2 iteration count | 12-byte salt | 12-byte IV |
+// AES-256-GCM ciphertext with appended tag. Older backups omit the
+// iteration count and salt and use SHA-256(password) as the key
+const (
+	andOTPSaltSize     = 12
+	andOTPNonceSize    = 12
+	andOTPMaxIteration = 10_000_000
+)
+
+// ParseAndOTP parses an andOTP backup: plain JSON or an encrypted .json.aes
+//
+// Entries hold only an OTP secret (no password); the caller matches them to
+// existing vault entries. Steam entries are skipped
+func ParseAndOTP(data []byte, password PasswordFunc) (*Result, error) {
+	plaintext := data
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '[' {
+		pass, err := password()
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = decryptAndOTP(data, pass)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var items []andOTPEntry
+	if err := json.Unmarshal(plaintext, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse andOTP backup: %w", err)
+	}
+
+	result := &Result{}
+	for i, item := range items {
+		source := item.Issuer
+		if source == "" {
+			source = item.Label
+		}
+		if source == "" {
+			source = fmt.Sprintf("entry %d", i+1)
+		}
+
+		kind := otp.Type(strings.ToLower(item.Type))
+		if kind != otp.TypeTOTP && kind != otp.TypeHOTP {
+			result.skip(source, fmt.Sprintf("unsupported OTP type %q", item.Type))
+			continue
+		}
+
+		secret, err := otp.DecodeSecret(item.Secret)
+		if err != nil {
+			result.skip(source, err.Error())
+			continue
+		}
+
+		key := otp.NewKey(secret)
+		key.Type = kind
+		key.Issuer = item.Issuer
+		key.Account = item.Label
+		key.Counter = item.Counter
+		if item.Algorithm != "" {
+			key.Algorithm = otp.Algorithm(strings.ToUpper(item.Algorithm))
+		}
+		if item.Digits != 0 {
+			key.Digits = item.Digits
+		}
+		if item.Period != 0 {
+			key.Period = item.Period
+		}
+
+		entry := otpEntry(key, "")
+		entry.Tags = item.Tags
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// decryptAndOTP tries the current PBKDF2 format, then the legacy format
+func decryptAndOTP(data []byte, password string) ([]byte, error) {
+	if len(data) > 4+andOTPSaltSize+andOTPNonceSize {
+		iterations := binary.BigEndian.Uint32(data[:4])
+		if iterations > 0 && iterations <= andOTPMaxIteration {
+			salt := data[4 : 4+andOTPSaltSize]
+			key := pbkdf2.Key([]byte(password), salt, int(iterations), 32, sha1.New)
+			if plaintext, err := andOTPOpen(key, data[4+andOTPSaltSize:]); err == nil {
+				return plaintext, nil
+			}
+		}
+	}
+
+	legacyKey := sha256.Sum256([]byte(password))
+	if plaintext, err := andOTPOpen(legacyKey[:], data); err == nil {
+		return plaintext, nil
+	}
+
+	return nil, errors.New("failed to decrypt andOTP backup (wrong password or unsupported format)")
+}
+
+// andOTPOpen decrypts IV | ciphertext+tag with AES-256-GCM
+func andOTPOpen(key, data []byte) ([]byte, error) {
+	if len(data) < andOTPNonceSize {
+		return nil, errors.New("backup is too short")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, data[:andOTPNonceSize], data[andOTPNonceSize:], nil)
+}
@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// KeePass derives KDBX 4 keys with Argon2d by default, which
+// golang.org/x/crypto/argon2 does not expose, so that variant (RFC 9106,
+// version 0x13) is implemented here; Argon2id uses x/crypto
+
+// argon2d is the Argon2d variant number of RFC 9106
+const argon2d = 0
+
+const (
+	argon2Version    = 0x13
+	argon2BlockWords = 128 // 1 KiB blocks of 64-bit words
+	argon2SyncPoints = 4   // slices per pass
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2dKey derives keyLen bytes with Argon2d; memory is in KiB
+// secret and data are the optional key and associated data inputs
+func argon2dKey(password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if time < 1 {
+		time = 1
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	lanes := uint32(threads)
+
+	h0 := argon2InitHash(password, salt, secret, data, time, memory, lanes, keyLen)
+
+	memory = memory / (argon2SyncPoints * lanes) * (argon2SyncPoints * lanes)
+	if memory < 2*argon2SyncPoints*lanes {
+		memory = 2 * argon2SyncPoints * lanes
+	}
+
+	blocks := argon2InitBlocks(&h0, memory, lanes)
+	argon2Fill(blocks, time, memory, lanes)
+	return argon2Extract(blocks, memory, lanes, keyLen)
+}
+
+// argon2InitHash computes H0 followed by room for the block and lane index
+func argon2InitHash(password, salt, secret, data []byte, time, memory, lanes, keyLen uint32) [blake2b.Size + 8]byte {
+	var h0 [blake2b.Size + 8]byte
+	var word [4]byte
+
+	b2, _ := blake2b.New512(nil)
+	writeWord := func(v uint32) {
+		binary.LittleEndian.PutUint32(word[:], v)
+		b2.Write(word[:])
+	}
+	writeBytes := func(b []byte) {
+		writeWord(uint32(len(b)))
+		b2.Write(b)
+	}
+
+	writeWord(lanes)
+	writeWord(keyLen)
+	writeWord(memory)
+	writeWord(time)
+	writeWord(argon2Version)
+	writeWord(argon2d)
+	writeBytes(password)
+	writeBytes(salt)
+	writeBytes(secret)
+	writeBytes(data)
+	b2.Sum(h0[:0])
+	return h0
+}
+
+// argon2InitBlocks fills the first two blocks of every lane from H0
+func argon2InitBlocks(h0 *[blake2b.Size + 8]byte, memory, lanes uint32) []argon2Block {
+	var buf [1024]byte
+	blocks := make([]argon2Block, memory)
+	laneLength := memory / lanes
+
+	for lane := uint32(0); lane < lanes; lane++ {
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], i)
+			argon2Hash(buf[:], h0[:])
+			block := &blocks[lane*laneLength+i]
+			for w := range block {
+				block[w] = binary.LittleEndian.Uint64(buf[w*8:])
+			}
+		}
+	}
+	return blocks
+}
+
+// argon2Fill runs the passes; the lanes of a slice are filled in parallel
+// Argon2d picks every reference block from the previous block's contents
+func argon2Fill(blocks []argon2Block, time, memory, lanes uint32) {
+	laneLength := memory / lanes
+	segmentLength := laneLength / argon2SyncPoints
+
+	processSegment := func(pass, slice, lane uint32) {
+		index := uint32(0)
+		if pass == 0 && slice == 0 {
+			index = 2 // the first two blocks come from H0
+		}
+
+		offset := lane*laneLength + slice*segmentLength + index
+		for ; index < segmentLength; index, offset = index+1, offset+1 {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += laneLength // wrap to the last block of the lane
+			}
+
+			ref := argon2RefIndex(blocks[prev][0], laneLength, segmentLength, lanes, pass, slice, lane, index)
+			argon2Compress(&blocks[offset], &blocks[prev], &blocks[ref])
+		}
+	}
+
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < lanes; lane++ {
+				wg.Add(1)
+				go func(lane uint32) {
+					defer wg.Done()
+					processSegment(pass, slice, lane)
+				}(lane)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// argon2RefIndex maps a pseudo-random value to the block to mix in
+func argon2RefIndex(random uint64, laneLength, segmentLength, lanes, pass, slice, lane, index uint32) uint32 {
+	refLane := uint32(random>>32) % lanes
+	if pass == 0 && slice == 0 {
+		refLane = lane
+	}
+
+	area, start := 3*segmentLength, ((slice+1)%argon2SyncPoints)*segmentLength
+	if lane == refLane {
+		area += index
+	}
+	if pass == 0 {
+		area, start = slice*segmentLength, 0
+		if slice == 0 || lane == refLane {
+			area += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		area--
+	}
+
+	p := random & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * uint64(area)) >> 32
+	return refLane*laneLength + uint32((uint64(start)+uint64(area)-(p+1))%uint64(laneLength))
+}
+
+// argon2Extract XORs the last block of every lane into the final tag
+func argon2Extract(blocks []argon2Block, memory, lanes, keyLen uint32) []byte {
+	laneLength := memory / lanes
+	last := &blocks[memory-1]
+	for lane := uint32(0); lane < lanes-1; lane++ {
+		for w, v := range blocks[lane*laneLength+laneLength-1] {
+			last[w] ^= v
+		}
+	}
+
+	var buf [1024]byte
+	for w, v := range last {
+		binary.LittleEndian.PutUint64(buf[w*8:], v)
+	}
+	key := make([]byte, keyLen)
+	argon2Hash(key, buf[:])
+	return key
+}
+
+// argon2Hash is the variable-length hash H' of RFC 9106
+func argon2Hash(out, in []byte) {
+	var b2 hash.Hash
+	if len(out) < blake2b.Size {
+		b2, _ = blake2b.New(len(out), nil)
+	} else {
+		b2, _ = blake2b.New512(nil)
+	}
+
+	var buf [blake2b.Size]byte
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(out)))
+	b2.Write(buf[:4])
+	b2.Write(in)
+	if len(out) <= blake2b.Size {
+		b2.Sum(out[:0])
+		return
+	}
+
+	outLen := len(out)
+	b2.Sum(buf[:0])
+	b2.Reset()
+	copy(out, buf[:32])
+	out = out[32:]
+	for len(out) > blake2b.Size {
+		b2.Write(buf[:])
+		b2.Sum(buf[:0])
+		copy(out, buf[:32])
+		out = out[32:]
+		b2.Reset()
+	}
+	if outLen%blake2b.Size > 0 {
+		r := (outLen+31)/32 - 2
+		b2, _ = blake2b.New(outLen-32*r, nil)
+	}
+	b2.Write(buf[:])
+	b2.Sum(out[:0])
+}
+
+// argon2Compress is the compression function G, XORing the result into out
+// (a no-op XOR on the zeroed blocks of the first pass)
+func argon2Compress(out, x, y *argon2Block) {
+	var r argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	t := r
+
+	for i := 0; i < argon2BlockWords; i += 16 {
+		blamka(&t[i], &t[i+1], &t[i+2], &t[i+3], &t[i+4], &t[i+5], &t[i+6], &t[i+7],
+			&t[i+8], &t[i+9], &t[i+10], &t[i+11], &t[i+12], &t[i+13], &t[i+14], &t[i+15])
+	}
+	for i := 0; i < argon2BlockWords/8; i += 2 {
+		blamka(&t[i], &t[i+1], &t[16+i], &t[16+i+1], &t[32+i], &t[32+i+1], &t[48+i], &t[48+i+1],
+			&t[64+i], &t[64+i+1], &t[80+i], &t[80+i+1], &t[96+i], &t[96+i+1], &t[112+i], &t[112+i+1])
+	}
+
+	for i := range t {
+		out[i] ^= r[i] ^ t[i]
+	}
+}
+
+// blamka applies the BlaMka round to sixteen words: the columns, then the
+// diagonals of a 4x4 matrix
+func blamka(t00, t01, t02, t03, t04, t05, t06, t07, t08, t09, t10, t11, t12, t13, t14, t15 *uint64) {
+	g := func(a, b, c, d *uint64) {
+		*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+		*d = bits.RotateLeft64(*d^*a, -32)
+		*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+		*b = bits.RotateLeft64(*b^*c, -24)
+		*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+		*d = bits.RotateLeft64(*d^*a, -16)
+		*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+		*b = bits.RotateLeft64(*b^*c, -63)
+	}
+
+	g(t00, t04, t08, t12)
+	g(t01, t05, t09, t13)
+	g(t02, t06, t10, t14)
+	g(t03, t07, t11, t15)
+
+	g(t00, t05, t10, t15)
+	g(t01, t06, t11, t12)
+	g(t02, t07, t08, t13)
+	g(t03, t04, t09, t14)
+}
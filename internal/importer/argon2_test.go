@@ -0,0 +1,43 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// RFC 9106 section 5 test vector inputs
+var (
+	rfc9106Password = bytes.Repeat([]byte{0x01}, 32)
+	rfc9106Salt     = bytes.Repeat([]byte{0x02}, 16)
+	rfc9106Secret   = bytes.Repeat([]byte{0x03}, 8)
+	rfc9106Data     = bytes.Repeat([]byte{0x04}, 12)
+)
+
+func TestArgon2dRFC9106(t *testing.T) {
+	got := argon2dKey(rfc9106Password, rfc9106Salt, rfc9106Secret, rfc9106Data, 3, 32, 4, 32)
+	want := "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("Argon2d tag = %x, want %s", got, want)
+	}
+}
+
+// Tags from the independent implementation in testdata/kdbx4.py for
+// parameters the RFC vector does not cover: one lane, several passes and a
+// tag longer than one BLAKE2b output
+func TestArgon2dKnownAnswers(t *testing.T) {
+	for _, tt := range []struct {
+		passes, memory uint32
+		lanes          uint8
+		keyLen         uint32
+		want           string
+	}{
+		{1, 64, 1, 100, "9f9d2458cd08315ca4280ffd11bcc7e0bd517878874ad68aa830d21402558299c27a0a0bf1454103f15dac85dba522b34b9de953336155784fa8effbf4acb01d269c42f0bb552bc46799c29a765e29fc46ee603b2d2fc63f6bbc3bd368633292aa188fac"},
+		{2, 64, 2, 32, "d6af1b803d316222b7b0c0adfee22bcabee33f4834e1fb3d40e2137ac0bb33cf"},
+	} {
+		got := argon2dKey([]byte("password"), []byte("somesalt"), nil, nil, tt.passes, tt.memory, tt.lanes, tt.keyLen)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("Argon2d t=%d m=%d p=%d = %x, want %s", tt.passes, tt.memory, tt.lanes, got, tt.want)
+		}
+	}
+}
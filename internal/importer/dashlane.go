@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// ParseDashlaneCSV parses a Dashlane credentials.csv export
+//
+// Columns: username,username2,username3,title,password,note,url,category,otpSecret
+//
+// Dashlane quirks handled here:
+//   - Up to three usernames per credential; extras are kept in notes
+//   - Titles are often empty and fall back to the URL host
+//   - The secure notes CSV (title,note) has no password column and is skipped
+func ParseDashlaneCSV(r io.Reader) (*Result, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i, row := range rows {
+		source := fmt.Sprintf("row %d", i+2)
+
+		if _, ok := row["password"]; !ok {
+			result.skip(source, "secure notes and other non-credential exports are not supported")
+			continue
+		}
+
+		entry := &models.Entry{
+			Name:     strings.TrimSpace(row["title"]),
+			Category: dashlaneCategory(row["category"]),
+			Username: row["username"],
+			Password: row["password"],
+			URL:      row["url"],
+			Notes:    row["note"],
+		}
+
+		entry.Notes = appendNote(entry.Notes, "Alternate username", row["username2"])
+		entry.Notes = appendNote(entry.Notes, "Alternate username", row["username3"])
+		entry.Notes = appendNote(entry.Notes, "TOTP", firstNonEmpty(row["otpsecret"], row["otpurl"]))
+
+		if entry.Name == "" {
+			entry.Name = nameFromURL(entry.URL)
+		}
+		if entry.Name != "" {
+			source = fmt.Sprintf("%s (%s)", source, entry.Name)
+		}
+
+		if entry.Name == "" {
+			result.skip(source, "no title or URL")
+			continue
+		}
+		if reason := incomplete(entry); reason != "" {
+			result.skip(source, reason)
+			continue
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// dashlaneJSONCredential is a credential in Dashlane's legacy JSON export
+type dashlaneJSONCredential struct {
+	Title          string `json:"title"`
+	Domain         string `json:"domain"`
+	Email          string `json:"email"`
+	Login          string `json:"login"`
+	SecondaryLogin string `json:"secondaryLogin"`
+	Password       string `json:"password"`
+	Note           string `json:"note"`
+}
+
+// ParseDashlaneJSON parses Dashlane's legacy JSON export
+//
+// The export is an object of typed sections (AUTHENTIFIANT, SECURENOTE,
+// PAYMENTMEANS_CREDITCARD, ...). Only AUTHENTIFIANT holds credentials;
+// other sections are reported as skipped.
+func ParseDashlaneJSON(r io.Reader) (*Result, error) {
+	var sections map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&sections); err != nil {
+		return nil, fmt.Errorf("failed to parse Dashlane JSON: %w", err)
+	}
+
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		if name != "AUTHENTIFIANT" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := &Result{}
+	for _, name := range names {
+		var items []json.RawMessage
+		if err := json.Unmarshal(sections[name], &items); err == nil && len(items) > 0 {
+			result.skip(name, fmt.Sprintf("%d items of unsupported type", len(items)))
+		}
+	}
+
+	var credentials []dashlaneJSONCredential
+	if raw, ok := sections["AUTHENTIFIANT"]; ok {
+		if err := json.Unmarshal(raw, &credentials); err != nil {
+			return nil, fmt.Errorf("failed to parse Dashlane credentials: %w", err)
+		}
+	}
+
+	for i, c := range credentials {
+		source := fmt.Sprintf("credential %d", i+1)
+
+		entry := &models.Entry{
+			Name:     strings.TrimSpace(c.Title),
+			Category: "general",
+			Username: firstNonEmpty(c.Login, c.Email),
+			Password: c.Password,
+			URL:      c.Domain,
+			Notes:    c.Note,
+		}
+
+		// Keep whichever identifier was not used as the username
+		if c.Login != "" && c.Email != "" && c.Login != c.Email {
+			entry.Notes = appendNote(entry.Notes, "Email", c.Email)
+		}
+		entry.Notes = appendNote(entry.Notes, "Alternate username", c.SecondaryLogin)
+
+		if entry.Name == "" {
+			entry.Name = nameFromURL(entry.URL)
+		}
+		if entry.Name != "" {
+			source = fmt.Sprintf("%s (%s)", source, entry.Name)
+		}
+
+		if entry.Name == "" {
+			result.skip(source, "no title or domain")
+			continue
+		}
+		if reason := incomplete(entry); reason != "" {
+			result.skip(source, reason)
+			continue
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// dashlaneCategory normalizes Dashlane's category column
+func dashlaneCategory(category string) string {
+	category = strings.TrimSpace(category)
+	if category == "" {
+		return "general"
+	}
+	return strings.ToLower(category)
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
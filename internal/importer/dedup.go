@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Duplicates finds vault entries that an imported record is another copy
+// of: same site and same username, even when the names differ
+// ("GitHub" vs "github.com")
+type Duplicates struct {
+	byKey map[string]*models.Entry
+}
+
+// NewDuplicates indexes existing entries by site and username
+func NewDuplicates(entries []*models.Entry) *Duplicates {
+	d := &Duplicates{byKey: make(map[string]*models.Entry, len(entries))}
+	for _, entry := range entries {
+		d.Add(entry)
+	}
+	return d
+}
+
+// Add indexes an entry; earlier entries win when several share a key
+func (d *Duplicates) Add(entry *models.Entry) {
+	key := duplicateKey(entry)
+	if key == "" {
+		return
+	}
+	if _, ok := d.byKey[key]; !ok {
+		d.byKey[key] = entry
+	}
+}
+
+// Find returns the existing entry for the same site and username, or nil
+// Records without both a URL and a username never match
+func (d *Duplicates) Find(entry *models.Entry) *models.Entry {
+	key := duplicateKey(entry)
+	if key == "" {
+		return nil
+	}
+	return d.byKey[key]
+}
+
+// duplicateKey is the normalized host and username, or "" if either is missing
+func duplicateKey(entry *models.Entry) string {
+	host := normalizeHost(entry.URL)
+	username := strings.ToLower(strings.TrimSpace(entry.Username))
+	if host == "" || username == "" {
+		return ""
+	}
+	return host + "\x00" + username
+}
+
+// normalizeHost reduces a URL to its lower-cased host without "www."
+// so "https://www.GitHub.com/login" and "github.com" compare equal
+func normalizeHost(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// Merge returns a copy of existing updated with an imported record
+// The existing name, category and ID are kept; fields the import leaves
+// empty keep their current values, and tags are combined
+func Merge(existing, imported *models.Entry) *models.Entry {
+	merged := *existing
+	merged.Tags = append([]string(nil), existing.Tags...)
+	merged.Scopes = append([]string(nil), existing.Scopes...)
+
+	if imported.Password != "" {
+		merged.Password = imported.Password
+	}
+	if imported.Username != "" {
+		merged.Username = imported.Username
+	}
+	if imported.URL != "" {
+		merged.URL = imported.URL
+	}
+	if imported.Notes != "" {
+		merged.Notes = imported.Notes
+	}
+	if imported.OTP != "" {
+		merged.OTP = imported.OTP
+	}
+
+	has := make(map[string]bool, len(merged.Tags))
+	for _, tag := range merged.Tags {
+		has[strings.ToLower(tag)] = true
+	}
+	for _, tag := range imported.Tags {
+		if !has[strings.ToLower(tag)] {
+			has[strings.ToLower(tag)] = true
+			merged.Tags = append(merged.Tags, tag)
+		}
+	}
+
+	return &merged
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Result holds the entries parsed from a foreign export
+// Entries are not yet stored; the caller decides how to persist them
+type Result struct {
+	Entries []*models.Entry
+	Skipped []Skipped
+}
+
+// Skipped describes a source record that could not be imported
+type Skipped struct {
+	Source string `json:"source"` // Human-readable location, e.g. "row 12" or "Work/GitHub"
+	Reason string `json:"reason"`
+}
+
+func (r *Result) skip(source, reason string) {
+	r.Skipped = append(r.Skipped, Skipped{Source: source, Reason: reason})
+}
+
+// Digest identifies a list of records so an interrupted import is only
+// resumed with the same export; passwords and notes are left out
+func Digest(entries []*models.Entry) string {
+	h := sha256.New()
+	for _, entry := range entries {
+		for _, field := range []string{entry.Name, entry.Username, entry.URL, entry.Category} {
+			fmt.Fprintf(h, "%d:%s", len(field), field)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readCSV reads a CSV file with a header row and returns rows keyed by
+// lower-cased column name
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Exporters are not always consistent
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	for i, column := range header {
+		// Strip a UTF-8 BOM some exporters prepend to the first column
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// nameFromURL derives an entry name from a URL host when no title is present
+func nameFromURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
+
+// appendNote adds a labelled line to an entry's notes
+func appendNote(notes, label, value string) string {
+	if value == "" {
+		return notes
+	}
+
+	line := value
+	if label != "" {
+		line = label + ": " + value
+	}
+
+	if notes == "" {
+		return line
+	}
+	return notes + "\n" + line
+}
+
+// splitTags splits a tag list separated by commas or semicolons
+func splitTags(list string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(list, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// incomplete returns why an entry can't be imported, "" if it can
+// Records holding only notes become secure notes, and records with a
+// username but no password become password-less logins
+func incomplete(entry *models.Entry) string {
+	if entry.IsLogin() && entry.Password == "" && entry.Username == "" && strings.TrimSpace(entry.Notes) != "" {
+		entry.Type = models.EntryTypeNote
+	}
+	if missing := entry.Missing(); missing != "" {
+		return "needs " + missing
+	}
+	return ""
+}
@@ -0,0 +1,823 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20"
+	"golang.org/x/crypto/twofish"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+	"github.com/kitsnail/gpasswd/internal/otp"
+)
+
+// KDBX file signatures
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+)
+
+// Outer header field IDs
+const (
+	kdbxEndOfHeader         = 0
+	kdbxCipherID            = 2
+	kdbxCompressionFlags    = 3
+	kdbxMasterSeed          = 4
+	kdbxTransformSeed       = 5 // KDBX 3
+	kdbxTransformRounds     = 6 // KDBX 3
+	kdbxEncryptionIV        = 7
+	kdbxProtectedStreamKey  = 8  // KDBX 3
+	kdbxStreamStartBytes    = 9  // KDBX 3
+	kdbxInnerRandomStreamID = 10 // KDBX 3
+	kdbxKDFParameters       = 11 // KDBX 4
+)
+
+// Inner header field IDs (KDBX 4)
+const (
+	kdbxInnerEnd       = 0
+	kdbxInnerStreamID  = 1
+	kdbxInnerStreamKey = 2
+)
+
+// Inner random stream algorithms protecting in-memory values
+const (
+	kdbxStreamNone     = 0
+	kdbxStreamSalsa20  = 2
+	kdbxStreamChaCha20 = 3
+)
+
+// Cipher and KDF UUIDs, in their binary form
+var (
+	kdbxCipherAES      = mustUUID("31c1f2e6bf714350be5805216afc5aff")
+	kdbxCipherChaCha20 = mustUUID("d6038a2b8b6f4cb5a524339a31dbb59a")
+	kdbxCipherTwofish  = mustUUID("ad68f29f576f4bb9a36ad47af965346c")
+
+	kdbxKDFAES      = mustUUID("c9d9f39a628a4460bf740d08c18a4fea")
+	kdbxKDFAES4     = mustUUID("7c02bb8279a74ac0927d114a00648238")
+	kdbxKDFArgon2d  = mustUUID("ef636ddf8c29444b91f7a9a403e30a0c")
+	kdbxKDFArgon2id = mustUUID("9e298b1956db4773b23dfc3ec6f0a1e6")
+)
+
+// kdbxSalsa20Nonce is the fixed nonce of the Salsa20 inner stream
+var kdbxSalsa20Nonce = []byte{0xE8, 0x30, 0x09, 0x4B, 0x97, 0x20, 0x5D, 0x2A}
+
+// kdbxMaxArgon2Memory caps the KDF memory a file may ask for (4 GiB)
+const kdbxMaxArgon2Memory = 4 << 30
+
+// ErrKDBXCredentials is returned when the password or key file is wrong
+var ErrKDBXCredentials = errors.New("wrong KeePass master password or key file")
+
+// kdbxHeader holds the outer header fields needed to decrypt the payload
+type kdbxHeader struct {
+	major          uint16
+	cipherID       []byte
+	compressed     bool
+	masterSeed     []byte
+	encryptionIV   []byte
+	transformSeed  []byte
+	transformRound uint64
+	streamKey      []byte
+	streamStart    []byte
+	streamID       uint32
+	kdf            map[string]any
+}
+
+// kdbxFile is the XML document inside a KDBX file
+type kdbxFile struct {
+	Meta struct {
+		RecycleBinEnabled string `xml:"RecycleBinEnabled"`
+		RecycleBinUUID    string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []kdbxGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type kdbxGroup struct {
+	UUID    string      `xml:"UUID"`
+	Name    string      `xml:"Name"`
+	Entries []kdbxEntry `xml:"Entry"`
+	Groups  []kdbxGroup `xml:"Group"`
+}
+
+type kdbxEntry struct {
+	Strings  []kdbxString `xml:"String"`
+	Binaries []struct {
+		Key string `xml:"Key"`
+	} `xml:"Binary"`
+	Tags    string `xml:"Tags"`
+	History struct {
+		Entries []kdbxEntry `xml:"Entry"`
+	} `xml:"History"`
+}
+
+type kdbxString struct {
+	Key   string    `xml:"Key"`
+	Value kdbxValue `xml:"Value"`
+}
+
+// kdbxValue is a string value that may be protected by the inner stream
+// Protected values must be decrypted in document order, so the position of
+// each one is recorded while unmarshalling
+type kdbxValue struct {
+	Text      string
+	Protected bool
+	offset    int64
+}
+
+func (v *kdbxValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v.offset = d.InputOffset()
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "Protected" && strings.EqualFold(attr.Value, "True") {
+			v.Protected = true
+		}
+	}
+	return d.DecodeElement(&v.Text, &start)
+}
+
+// ParseKDBX parses a KeePass database (KDBX 3.1 or 4.x)
+//
+// keyFile is the content of the key file, or nil if none is used. The
+// password is only asked for if password is not nil.
+//
+// Mapping:
+//   - The group path below the root group becomes the category
+//     ("Internet/Shopping")
+//   - Title, UserName, Password, URL and Notes fill the matching fields
+//   - A KeePassXC "otp" field holding an otpauth:// URI becomes the OTP secret
+//   - Other custom fields are appended to the notes as "Key: value" lines
+//   - Entries in the recycle bin and entries without a password are skipped
+func ParseKDBX(data []byte, password PasswordFunc, keyFile []byte) (*Result, error) {
+	header, headerBytes, err := readKDBXHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pass string
+	if password != nil {
+		if pass, err = password(); err != nil {
+			return nil, err
+		}
+	}
+	composite, err := kdbxCompositeKey(pass, password != nil, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := kdbxTransformKey(header, composite)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	if header.major >= 4 {
+		payload, err = readKDBX4Payload(header, data, headerBytes, transformed)
+	} else {
+		payload, err = readKDBX3Payload(header, data[len(headerBytes):], transformed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if header.compressed {
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress KeePass database: %w", err)
+		}
+		if payload, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("failed to decompress KeePass database: %w", err)
+		}
+	}
+
+	if header.major >= 4 {
+		if payload, err = readKDBXInnerHeader(header, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	var file kdbxFile
+	if err := xml.Unmarshal(payload, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse KeePass database: %w", err)
+	}
+	if err := unprotectKDBX(&file, header); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	recycleBin := ""
+	if !strings.EqualFold(file.Meta.RecycleBinEnabled, "False") {
+		recycleBin = file.Meta.RecycleBinUUID
+	}
+	for _, root := range file.Root.Groups {
+		// The root group is named after the database; it is not a category
+		walkKDBXGroup(result, root, "", recycleBin)
+	}
+	return result, nil
+}
+
+// walkKDBXGroup converts the entries of a group and its subgroups
+func walkKDBXGroup(result *Result, group kdbxGroup, category, recycleBin string) {
+	if recycleBin != "" && group.UUID == recycleBin {
+		for _, entry := range group.Entries {
+			result.skip(kdbxSource(category, entry), "in the recycle bin")
+		}
+		return
+	}
+
+	for _, item := range group.Entries {
+		source := kdbxSource(category, item)
+		entry := kdbxToEntry(item, category)
+		if entry.Name == "" {
+			result.skip(source, "no title or URL")
+			continue
+		}
+		if reason := incomplete(entry); reason != "" {
+			result.skip(source, reason)
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	for _, sub := range group.Groups {
+		path := sub.Name
+		if category != "" {
+			path = category + "/" + sub.Name
+		}
+		walkKDBXGroup(result, sub, path, recycleBin)
+	}
+}
+
+// kdbxToEntry maps a KeePass entry to a vault entry
+func kdbxToEntry(item kdbxEntry, category string) *models.Entry {
+	entry := &models.Entry{Category: category}
+
+	var custom []kdbxString
+	for _, field := range item.Strings {
+		value := field.Value.Text
+		switch field.Key {
+		case "Title":
+			entry.Name = strings.TrimSpace(value)
+		case "UserName":
+			entry.Username = value
+		case "Password":
+			entry.Password = value
+		case "URL":
+			entry.URL = strings.TrimSpace(value)
+		case "Notes":
+			entry.Notes = value
+		case "otp":
+			if _, err := otp.Parse(value); err == nil {
+				entry.OTP = value
+				continue
+			}
+			custom = append(custom, field)
+		default:
+			custom = append(custom, field)
+		}
+	}
+	if entry.Name == "" {
+		entry.Name = nameFromURL(entry.URL)
+	}
+
+	for _, field := range custom {
+		entry.Notes = appendNote(entry.Notes, field.Key, field.Value.Text)
+	}
+	for _, binary := range item.Binaries {
+		entry.Notes = appendNote(entry.Notes, "Attachment (not imported)", binary.Key)
+	}
+
+	entry.Tags = splitTags(item.Tags)
+	return entry
+}
+
+// kdbxSource describes where an entry was found, e.g. "Internet/GitHub"
+func kdbxSource(category string, item kdbxEntry) string {
+	title := "(untitled)"
+	for _, field := range item.Strings {
+		if field.Key == "Title" && strings.TrimSpace(field.Value.Text) != "" {
+			title = strings.TrimSpace(field.Value.Text)
+		}
+	}
+	if category == "" {
+		return title
+	}
+	return category + "/" + title
+}
+
+// readKDBXHeader parses the outer header and returns it with its raw bytes
+func readKDBXHeader(data []byte) (*kdbxHeader, []byte, error) {
+	if len(data) < 12 ||
+		binary.LittleEndian.Uint32(data[0:4]) != kdbxSignature1 ||
+		binary.LittleEndian.Uint32(data[4:8]) != kdbxSignature2 {
+		return nil, nil, errors.New("not a KeePass KDBX database")
+	}
+
+	header := &kdbxHeader{major: binary.LittleEndian.Uint16(data[10:12])}
+	if header.major != 3 && header.major != 4 {
+		return nil, nil, fmt.Errorf("unsupported KDBX version %d.%d (3.1 and 4.x are supported)",
+			header.major, binary.LittleEndian.Uint16(data[8:10]))
+	}
+
+	pos := 12
+	for {
+		sizeLen := 2
+		if header.major >= 4 {
+			sizeLen = 4
+		}
+		if pos+1+sizeLen > len(data) {
+			return nil, nil, errors.New("truncated KeePass header")
+		}
+		id := data[pos]
+		var size int
+		if sizeLen == 2 {
+			size = int(binary.LittleEndian.Uint16(data[pos+1:]))
+		} else {
+			size = int(binary.LittleEndian.Uint32(data[pos+1:]))
+		}
+		pos += 1 + sizeLen
+		if size < 0 || pos+size > len(data) {
+			return nil, nil, errors.New("truncated KeePass header")
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch id {
+		case kdbxEndOfHeader:
+			return header, data[:pos], header.validate()
+		case kdbxCipherID:
+			header.cipherID = value
+		case kdbxCompressionFlags:
+			if len(value) == 4 {
+				header.compressed = binary.LittleEndian.Uint32(value) == 1
+			}
+		case kdbxMasterSeed:
+			header.masterSeed = value
+		case kdbxTransformSeed:
+			header.transformSeed = value
+		case kdbxTransformRounds:
+			if len(value) == 8 {
+				header.transformRound = binary.LittleEndian.Uint64(value)
+			}
+		case kdbxEncryptionIV:
+			header.encryptionIV = value
+		case kdbxProtectedStreamKey:
+			header.streamKey = value
+		case kdbxStreamStartBytes:
+			header.streamStart = value
+		case kdbxInnerRandomStreamID:
+			if len(value) == 4 {
+				header.streamID = binary.LittleEndian.Uint32(value)
+			}
+		case kdbxKDFParameters:
+			kdf, err := readVariantDictionary(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			header.kdf = kdf
+		}
+	}
+}
+
+// validate checks that the fields needed to open the database are present
+func (h *kdbxHeader) validate() error {
+	if len(h.masterSeed) != 32 || len(h.encryptionIV) == 0 || len(h.cipherID) != 16 {
+		return errors.New("KeePass header is missing required fields")
+	}
+	if h.major < 4 && (len(h.transformSeed) != 32 || len(h.streamStart) != 32) {
+		return errors.New("KeePass header is missing required fields")
+	}
+	if h.major >= 4 && h.kdf == nil {
+		return errors.New("KeePass header has no key derivation parameters")
+	}
+	return nil
+}
+
+// readVariantDictionary parses the KDBX 4 typed key/value format
+func readVariantDictionary(data []byte) (map[string]any, error) {
+	if len(data) < 2 {
+		return nil, errors.New("invalid KeePass KDF parameters")
+	}
+	dict := make(map[string]any)
+	pos := 2 // version
+	for pos < len(data) {
+		kind := data[pos]
+		pos++
+		if kind == 0 {
+			return dict, nil
+		}
+		if pos+4 > len(data) {
+			break
+		}
+		nameLen := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if nameLen < 0 || pos+nameLen+4 > len(data) {
+			break
+		}
+		name := string(data[pos : pos+nameLen])
+		pos += nameLen
+		valueLen := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if valueLen < 0 || pos+valueLen > len(data) {
+			break
+		}
+		value := data[pos : pos+valueLen]
+		pos += valueLen
+
+		switch {
+		case (kind == 0x04 || kind == 0x0C) && len(value) == 4: // UInt32, Int32
+			dict[name] = uint64(binary.LittleEndian.Uint32(value))
+		case (kind == 0x05 || kind == 0x0D) && len(value) == 8: // UInt64, Int64
+			dict[name] = binary.LittleEndian.Uint64(value)
+		case kind == 0x08 && len(value) == 1: // Bool
+			dict[name] = value[0] != 0
+		case kind == 0x18: // String
+			dict[name] = string(value)
+		case kind == 0x42: // Byte array
+			dict[name] = value
+		}
+	}
+	return nil, errors.New("invalid KeePass KDF parameters")
+}
+
+// kdbxCompositeKey combines the password and key file as KeePass does
+func kdbxCompositeKey(password string, usePassword bool, keyFile []byte) ([]byte, error) {
+	composite := sha256.New()
+	if usePassword {
+		sum := sha256.Sum256([]byte(password))
+		composite.Write(sum[:])
+	}
+	if keyFile != nil {
+		key, err := kdbxKeyFileKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		composite.Write(key)
+	}
+	if !usePassword && keyFile == nil {
+		return nil, errors.New("a KeePass database needs a password or a key file")
+	}
+	return composite.Sum(nil), nil
+}
+
+// kdbxKeyFileKey extracts the 32-byte key from a key file: the XML formats
+// (1.0 base64, 2.0 hex), a raw 32-byte file, 64 hex digits, or else the
+// SHA-256 of any other file
+func kdbxKeyFileKey(data []byte) ([]byte, error) {
+	var xmlKey struct {
+		XMLName xml.Name `xml:"KeyFile"`
+		Meta    struct {
+			Version string `xml:"Version"`
+		} `xml:"Meta"`
+		Key struct {
+			Data string `xml:"Data"`
+		} `xml:"Key"`
+	}
+	if err := xml.Unmarshal(data, &xmlKey); err == nil && xmlKey.Key.Data != "" {
+		if strings.HasPrefix(xmlKey.Meta.Version, "2.") {
+			key, err := hex.DecodeString(strings.Join(strings.Fields(xmlKey.Key.Data), ""))
+			if err != nil || len(key) != 32 {
+				return nil, errors.New("invalid KeePass key file")
+			}
+			return key, nil
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(xmlKey.Key.Data))
+		if err != nil {
+			return nil, errors.New("invalid KeePass key file")
+		}
+		return key, nil
+	}
+
+	if len(data) == 32 {
+		return data, nil
+	}
+	if len(data) == 64 {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return key, nil
+		}
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// kdbxTransformKey runs the key derivation function on the composite key
+func kdbxTransformKey(header *kdbxHeader, composite []byte) ([]byte, error) {
+	if header.major < 4 {
+		return aesKDF(composite, header.transformSeed, header.transformRound)
+	}
+
+	uuid, _ := header.kdf["$UUID"].([]byte)
+	seed, _ := header.kdf["S"].([]byte)
+	switch {
+	case bytes.Equal(uuid, kdbxKDFAES), bytes.Equal(uuid, kdbxKDFAES4):
+		rounds, _ := header.kdf["R"].(uint64)
+		return aesKDF(composite, seed, rounds)
+
+	case bytes.Equal(uuid, kdbxKDFArgon2d), bytes.Equal(uuid, kdbxKDFArgon2id):
+		iterations, _ := header.kdf["I"].(uint64)
+		memory, _ := header.kdf["M"].(uint64)
+		parallelism, _ := header.kdf["P"].(uint64)
+		version, _ := header.kdf["V"].(uint64)
+		secret, _ := header.kdf["K"].([]byte)
+		assoc, _ := header.kdf["A"].([]byte)
+		if version != argon2Version {
+			return nil, fmt.Errorf("unsupported Argon2 version 0x%x", version)
+		}
+		if len(seed) == 0 || iterations == 0 || iterations > 1<<32-1 ||
+			memory < 8*1024 || memory > kdbxMaxArgon2Memory || parallelism == 0 || parallelism > 255 {
+			return nil, errors.New("invalid Argon2 parameters in KeePass database")
+		}
+		if bytes.Equal(uuid, kdbxKDFArgon2id) {
+			if len(secret) > 0 || len(assoc) > 0 {
+				return nil, errors.New("unsupported Argon2id secret key or associated data in KeePass database")
+			}
+			return argon2.IDKey(composite, seed, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+		}
+		return argon2dKey(composite, seed, secret, assoc, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+
+	default:
+		return nil, errors.New("unsupported key derivation function in KeePass database")
+	}
+}
+
+// aesKDF is KeePass's AES-KDF: the composite key encrypted with the seed
+// as AES-256 key, rounds times, then hashed
+func aesKDF(composite, seed []byte, rounds uint64) ([]byte, error) {
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AES-KDF seed: %w", err)
+	}
+	key := append([]byte(nil), composite...)
+	for i := uint64(0); i < rounds; i++ {
+		block.Encrypt(key[0:16], key[0:16])
+		block.Encrypt(key[16:32], key[16:32])
+	}
+	sum := sha256.Sum256(key)
+	return sum[:], nil
+}
+
+// kdbxBlockKey is the HMAC key of block index in a KDBX 4 file
+func kdbxBlockKey(hmacKey []byte, index uint64) []byte {
+	h := sha512.New()
+	binary.Write(h, binary.LittleEndian, index)
+	h.Write(hmacKey)
+	return h.Sum(nil)
+}
+
+// readKDBX4Payload verifies the header and the HMAC block stream of a
+// KDBX 4 file and decrypts the payload
+func readKDBX4Payload(header *kdbxHeader, data, headerBytes, transformed []byte) ([]byte, error) {
+	pos := len(headerBytes)
+	if pos+64 > len(data) {
+		return nil, errors.New("truncated KeePass database")
+	}
+	sum := sha256.Sum256(headerBytes)
+	if !bytes.Equal(sum[:], data[pos:pos+32]) {
+		return nil, errors.New("KeePass header is corrupted")
+	}
+
+	hmacBase := sha512.New()
+	hmacBase.Write(header.masterSeed)
+	hmacBase.Write(transformed)
+	hmacBase.Write([]byte{1})
+	hmacKey := hmacBase.Sum(nil)
+
+	mac := hmac.New(sha256.New, kdbxBlockKey(hmacKey, ^uint64(0)))
+	mac.Write(headerBytes)
+	if !hmac.Equal(mac.Sum(nil), data[pos+32:pos+64]) {
+		return nil, ErrKDBXCredentials
+	}
+	pos += 64
+
+	var ciphertext []byte
+	for index := uint64(0); ; index++ {
+		if pos+36 > len(data) {
+			return nil, errors.New("truncated KeePass database")
+		}
+		blockMAC := data[pos : pos+32]
+		size := int(binary.LittleEndian.Uint32(data[pos+32:]))
+		if size < 0 || pos+36+size > len(data) {
+			return nil, errors.New("truncated KeePass database")
+		}
+		block := data[pos+36 : pos+36+size]
+
+		mac := hmac.New(sha256.New, kdbxBlockKey(hmacKey, index))
+		binary.Write(mac, binary.LittleEndian, index)
+		mac.Write(data[pos+32 : pos+36])
+		mac.Write(block)
+		if !hmac.Equal(mac.Sum(nil), blockMAC) {
+			return nil, errors.New("KeePass database is corrupted")
+		}
+		pos += 36 + size
+
+		if size == 0 {
+			break
+		}
+		ciphertext = append(ciphertext, block...)
+	}
+
+	return kdbxDecrypt(header, transformed, ciphertext)
+}
+
+// readKDBX3Payload decrypts a KDBX 3.1 payload and reassembles its hashed
+// blocks
+func readKDBX3Payload(header *kdbxHeader, ciphertext, transformed []byte) ([]byte, error) {
+	plaintext, err := kdbxDecrypt(header, transformed, ciphertext)
+	if err != nil || len(plaintext) < 32 || !bytes.Equal(plaintext[:32], header.streamStart) {
+		return nil, ErrKDBXCredentials
+	}
+
+	var payload []byte
+	pos := 32
+	for {
+		if pos+40 > len(plaintext) {
+			return nil, errors.New("truncated KeePass database")
+		}
+		hash := plaintext[pos+4 : pos+36]
+		size := int(binary.LittleEndian.Uint32(plaintext[pos+36:]))
+		pos += 40
+		if size == 0 {
+			return payload, nil
+		}
+		if size < 0 || pos+size > len(plaintext) {
+			return nil, errors.New("truncated KeePass database")
+		}
+		block := plaintext[pos : pos+size]
+		if sum := sha256.Sum256(block); !bytes.Equal(sum[:], hash) {
+			return nil, errors.New("KeePass database is corrupted")
+		}
+		payload = append(payload, block...)
+		pos += size
+	}
+}
+
+// kdbxDecrypt decrypts the payload with the database cipher
+func kdbxDecrypt(header *kdbxHeader, transformed, ciphertext []byte) ([]byte, error) {
+	keyHash := sha256.New()
+	keyHash.Write(header.masterSeed)
+	keyHash.Write(transformed)
+	key := keyHash.Sum(nil)
+
+	switch {
+	case bytes.Equal(header.cipherID, kdbxCipherAES), bytes.Equal(header.cipherID, kdbxCipherTwofish):
+		var block cipher.Block
+		var err error
+		if bytes.Equal(header.cipherID, kdbxCipherAES) {
+			block, err = aes.NewCipher(key)
+		} else {
+			block, err = twofish.NewCipher(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(header.encryptionIV) != block.BlockSize() || len(ciphertext)%block.BlockSize() != 0 || len(ciphertext) == 0 {
+			return nil, ErrKDBXCredentials
+		}
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, header.encryptionIV).CryptBlocks(plaintext, ciphertext)
+
+		// PKCS#7 padding; a wrong key almost always leaves invalid padding
+		padding := int(plaintext[len(plaintext)-1])
+		if padding == 0 || padding > block.BlockSize() {
+			return nil, ErrKDBXCredentials
+		}
+		for _, b := range plaintext[len(plaintext)-padding:] {
+			if int(b) != padding {
+				return nil, ErrKDBXCredentials
+			}
+		}
+		return plaintext[:len(plaintext)-padding], nil
+
+	case bytes.Equal(header.cipherID, kdbxCipherChaCha20):
+		stream, err := chacha20.NewUnauthenticatedCipher(key, header.encryptionIV)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ChaCha20 parameters: %w", err)
+		}
+		plaintext := make([]byte, len(ciphertext))
+		stream.XORKeyStream(plaintext, ciphertext)
+		return plaintext, nil
+
+	default:
+		return nil, errors.New("unsupported cipher in KeePass database")
+	}
+}
+
+// readKDBXInnerHeader reads the KDBX 4 inner header and returns the XML
+// that follows it
+func readKDBXInnerHeader(header *kdbxHeader, payload []byte) ([]byte, error) {
+	pos := 0
+	for {
+		if pos+5 > len(payload) {
+			return nil, errors.New("truncated KeePass inner header")
+		}
+		id := payload[pos]
+		size := int(binary.LittleEndian.Uint32(payload[pos+1:]))
+		pos += 5
+		if size < 0 || pos+size > len(payload) {
+			return nil, errors.New("truncated KeePass inner header")
+		}
+		value := payload[pos : pos+size]
+		pos += size
+
+		switch id {
+		case kdbxInnerEnd:
+			return payload[pos:], nil
+		case kdbxInnerStreamID:
+			if len(value) == 4 {
+				header.streamID = binary.LittleEndian.Uint32(value)
+			}
+		case kdbxInnerStreamKey:
+			header.streamKey = value
+		}
+	}
+}
+
+// unprotectKDBX decrypts the protected values of every entry and its
+// history. The inner stream runs over all of them in document order
+func unprotectKDBX(file *kdbxFile, header *kdbxHeader) error {
+	var values []*kdbxValue
+	var collectEntry func(entry *kdbxEntry)
+	collectEntry = func(entry *kdbxEntry) {
+		for i := range entry.Strings {
+			if entry.Strings[i].Value.Protected {
+				values = append(values, &entry.Strings[i].Value)
+			}
+		}
+		for i := range entry.History.Entries {
+			collectEntry(&entry.History.Entries[i])
+		}
+	}
+	var collectGroup func(group *kdbxGroup)
+	collectGroup = func(group *kdbxGroup) {
+		for i := range group.Entries {
+			collectEntry(&group.Entries[i])
+		}
+		for i := range group.Groups {
+			collectGroup(&group.Groups[i])
+		}
+	}
+	for i := range file.Root.Groups {
+		collectGroup(&file.Root.Groups[i])
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].offset < values[j].offset })
+
+	var stream []byte
+	decoded := make([][]byte, len(values))
+	for i, value := range values {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value.Text))
+		if err != nil {
+			return fmt.Errorf("invalid protected value in KeePass database: %w", err)
+		}
+		decoded[i] = raw
+		stream = append(stream, raw...)
+	}
+
+	switch header.streamID {
+	case kdbxStreamNone:
+	case kdbxStreamSalsa20:
+		key := sha256.Sum256(header.streamKey)
+		salsa20.XORKeyStream(stream, stream, kdbxSalsa20Nonce, &key)
+	case kdbxStreamChaCha20:
+		sum := sha512.Sum512(header.streamKey)
+		cipher, err := chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+		if err != nil {
+			return err
+		}
+		cipher.XORKeyStream(stream, stream)
+	default:
+		return fmt.Errorf("unsupported inner stream %d in KeePass database", header.streamID)
+	}
+
+	pos := 0
+	for i, value := range values {
+		value.Text = string(stream[pos : pos+len(decoded[i])])
+		pos += len(decoded[i])
+	}
+	return nil
+}
+
+// mustUUID decodes a UUID written as 32 hex digits
+func mustUUID(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		panic("invalid UUID " + s)
+	}
+	return b
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// The fixtures are written by testdata/kdbx4.py with password "gpasswd-test"
+func TestParseKDBX4Fixtures(t *testing.T) {
+	for _, name := range []string{"argon2d-aes.kdbx", "argon2id-chacha20.kdbx", "aeskdf-aes.kdbx"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := ParseKDBX(data, fixturePassword("gpasswd-test"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []*models.Entry{
+				{Name: "Mail", Username: "me@example.com", Password: "m@il-pass"},
+				{
+					Name:     "GitHub",
+					Category: "Internet/Code",
+					Username: "octocat",
+					Password: "gh-s3cret!",
+					URL:      "https://github.com/login",
+					Notes:    "work account\nRecovery: 1234-5678",
+					OTP:      "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub",
+					Tags:     []string{"dev", "work"},
+				},
+			}
+			if !reflect.DeepEqual(result.Entries, want) {
+				for _, entry := range result.Entries {
+					t.Logf("got %+v", *entry)
+				}
+				t.Fatal("entries differ")
+			}
+
+			skipped := []Skipped{
+				{Source: "(untitled)", Reason: "no title or URL"},
+				{Source: "Recycle Bin/Old", Reason: "in the recycle bin"},
+			}
+			if !reflect.DeepEqual(result.Skipped, skipped) {
+				t.Errorf("skipped = %+v, want %+v", result.Skipped, skipped)
+			}
+
+			if _, err := ParseKDBX(data, fixturePassword("wrong"), nil); !errors.Is(err, ErrKDBXCredentials) {
+				t.Errorf("wrong password: err = %v, want ErrKDBXCredentials", err)
+			}
+		})
+	}
+}
+
+func TestParseKDBXRejectsTampering(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "argon2d-aes.kdbx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-50] ^= 1
+	if _, err := ParseKDBX(data, fixturePassword("gpasswd-test"), nil); err == nil {
+		t.Error("a modified block was accepted")
+	}
+}
+
+func fixturePassword(password string) PasswordFunc {
+	return func() (string, error) { return password, nil }
+}
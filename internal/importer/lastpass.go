@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// LastPass marks secure notes with this pseudo URL
+const lastPassSecureNoteURL = "http://sn"
+
+// ParseLastPassCSV parses a LastPass CSV export
+//
+// Columns: url,username,password,totp,extra,name,grouping,fav
+//
+// LastPass quirks handled here:
+//   - Folders use backslash notation ("Work\Email") and map to categories
+//   - Names and folders may be HTML-escaped (&amp;)
+//   - Secure notes use the URL "http://sn"; typed notes carry "Key:Value"
+//     lines in the extra field and are imported when they contain a password
+//   - The extra field may span multiple lines
+func ParseLastPassCSV(r io.Reader) (*Result, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i, row := range rows {
+		source := fmt.Sprintf("row %d", i+2) // +1 for header, +1 for 1-based
+
+		entry := &models.Entry{
+			Name:     html.UnescapeString(strings.TrimSpace(row["name"])),
+			Category: lastPassCategory(row["grouping"]),
+			Username: row["username"],
+			Password: row["password"],
+			URL:      row["url"],
+			Notes:    strings.TrimRight(row["extra"], "\r\n"),
+		}
+
+		if entry.Name != "" {
+			source = fmt.Sprintf("%s (%s)", source, entry.Name)
+		}
+
+		if entry.URL == lastPassSecureNoteURL {
+			entry.URL = ""
+			parseLastPassNote(entry)
+		}
+
+		if entry.Name == "" {
+			entry.Name = nameFromURL(entry.URL)
+		}
+		if entry.Name == "" {
+			result.skip(source, "no name or URL")
+			continue
+		}
+		if reason := incomplete(entry); reason != "" {
+			result.skip(source, reason)
+			continue
+		}
+
+		if totp := row["totp"]; totp != "" {
+			entry.Notes = appendNote(entry.Notes, "TOTP", totp)
+		}
+		if row["fav"] == "1" {
+			entry.Tags = append(entry.Tags, models.FavoriteTag)
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// lastPassCategory converts LastPass folder notation into a category
+func lastPassCategory(grouping string) string {
+	grouping = html.UnescapeString(strings.TrimSpace(grouping))
+	if grouping == "" || grouping == "(none)" {
+		return "general"
+	}
+	return strings.ReplaceAll(grouping, `\`, "/")
+}
+
+// parseLastPassNote extracts credentials from a typed secure note
+// Typed notes look like:
+//
+//	NoteType:Server
+//	Hostname:db.example.com
+//	Username:admin
+//	Password:secret
+//	Notes:free text...
+//
+// Notes without credentials are imported as secure notes (see incomplete)
+func parseLastPassNote(entry *models.Entry) {
+	if !strings.HasPrefix(entry.Notes, "NoteType:") {
+		return
+	}
+
+	var notes string
+	lines := strings.Split(entry.Notes, "\n")
+	for i, line := range lines {
+		key, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !found {
+			notes = appendNote(notes, "", line)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "notetype":
+			// Folder wins over note type when both are present
+			if entry.Category == "general" {
+				entry.Category = strings.ToLower(value)
+			}
+		case "username":
+			entry.Username = value
+		case "password":
+			entry.Password = value
+		case "url", "hostname", "server":
+			if entry.URL == "" {
+				entry.URL = value
+			}
+			notes = appendNote(notes, key, value)
+		case "notes":
+			// Free-form notes are always last and may contain colons or newlines
+			rest := append([]string{value}, lines[i+1:]...)
+			notes = appendNote(notes, "", strings.Join(rest, "\n"))
+			entry.Notes = notes
+			return
+		default:
+			notes = appendNote(notes, key, value)
+		}
+	}
+
+	entry.Notes = notes
+}
@@ -0,0 +1,295 @@
+package importer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// The plain fixtures follow the JSON written by Aegis and andOTP; the tests
+// encrypt them with the apps' published backup formats
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// gcmSeal encrypts with AES-256-GCM and returns the ciphertext and tag
+func gcmSeal(t *testing.T, key, nonce, plaintext []byte) ([]byte, []byte) {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := gcm.Seal(nil, nonce, plaintext, nil)
+	return sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// encryptAegis turns a plain Aegis export into a password-protected one: a
+// random master key encrypts the db, and a scrypt key slot holds the
+// master key
+func encryptAegis(t *testing.T, plain []byte, password string) []byte {
+	t.Helper()
+	var export map[string]json.RawMessage
+	if err := json.Unmarshal(plain, &export); err != nil {
+		t.Fatal(err)
+	}
+
+	masterKey := randomBytes(t, 32)
+	salt := randomBytes(t, 32)
+	slotKey, err := scrypt.Key([]byte(password), salt, 1<<10, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slotNonce, dbNonce := randomBytes(t, 12), randomBytes(t, 12)
+	encryptedKey, slotTag := gcmSeal(t, slotKey, slotNonce, masterKey)
+	encryptedDB, dbTag := gcmSeal(t, masterKey, dbNonce, export["db"])
+
+	encrypted, err := json.Marshal(map[string]any{
+		"version": 1,
+		"header": map[string]any{
+			"slots": []map[string]any{
+				{"type": 2, "uuid": "biometric"}, // skipped: not a password slot
+				{
+					"type": aegisSlotPassword,
+					"key":  hex.EncodeToString(encryptedKey),
+					"key_params": map[string]string{
+						"nonce": hex.EncodeToString(slotNonce),
+						"tag":   hex.EncodeToString(slotTag),
+					},
+					"n": 1 << 10, "r": 8, "p": 1,
+					"salt": hex.EncodeToString(salt),
+				},
+			},
+			"params": map[string]string{
+				"nonce": hex.EncodeToString(dbNonce),
+				"tag":   hex.EncodeToString(dbTag),
+			},
+		},
+		"db": base64.StdEncoding.EncodeToString(encryptedDB),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+func TestParseAegis(t *testing.T) {
+	plain := readFixture(t, "aegis-plain.json")
+
+	want := []*models.Entry{
+		{
+			Name:     "GitHub",
+			Username: "octocat",
+			Notes:    "work account",
+			OTP:      "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP",
+		},
+		{
+			Name:     "me@example.com",
+			Username: "me@example.com",
+			OTP:      "otpauth://hotp/me@example.com?algorithm=SHA256&counter=42&digits=8&secret=GEZDGNBVGY3TQOJQ",
+		},
+	}
+	skipped := []Skipped{
+		{Source: "Steam", Reason: `unsupported OTP type "steam"`},
+		{Source: "Broken", Reason: "OTP secret is not valid base32: illegal base32 data at input byte 9"},
+	}
+
+	for name, data := range map[string][]byte{
+		"plain":     plain,
+		"encrypted": encryptAegis(t, plain, "backup-pass"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseAegis(data, fixturePassword("backup-pass"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Entries, want) {
+				for _, entry := range result.Entries {
+					t.Logf("got %+v", *entry)
+				}
+				t.Fatal("entries differ")
+			}
+			if !reflect.DeepEqual(result.Skipped, skipped) {
+				t.Errorf("skipped = %+v, want %+v", result.Skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestParseAegisMalformed(t *testing.T) {
+	encrypted := encryptAegis(t, readFixture(t, "aegis-plain.json"), "backup-pass")
+	if _, err := ParseAegis(encrypted, fixturePassword("wrong")); err == nil {
+		t.Error("wrong password: no error")
+	}
+
+	var export map[string]any
+	if err := json.Unmarshal(encrypted, &export); err != nil {
+		t.Fatal(err)
+	}
+	header := export["header"].(map[string]any)
+	mutate := func(change func(header map[string]any)) []byte {
+		copied := map[string]any{}
+		for k, v := range header {
+			copied[k] = v
+		}
+		change(copied)
+		data, err := json.Marshal(map[string]any{"version": 1, "header": copied, "db": export["db"]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	for name, data := range map[string][]byte{
+		"not JSON":           []byte("otpauth://totp/x"),
+		"empty":              nil,
+		"missing db":         []byte(`{"version": 1, "header": {"slots": null, "params": null}}`),
+		"entries not a list": []byte(`{"version": 1, "header": {"slots": null}, "db": {"entries": 5}}`),
+		"db not base64":      []byte(`{"version": 1, "header": {"slots": [], "params": {}}, "db": "***"}`),
+		"db not a string":    []byte(`{"version": 1, "header": {"slots": [], "params": {}}, "db": {}}`),
+		"no params":          mutate(func(h map[string]any) { delete(h, "params") }),
+		"biometric only":     mutate(func(h map[string]any) { h["slots"] = []map[string]any{{"type": 2}} }),
+		"bad slot salt": mutate(func(h map[string]any) {
+			h["slots"] = []map[string]any{{"type": 1, "salt": "zz", "n": 2, "r": 1, "p": 1}}
+		}),
+		"bad scrypt cost": mutate(func(h map[string]any) {
+			h["slots"] = []map[string]any{{"type": 1, "salt": "00", "n": 3, "r": 1, "p": 1}}
+		}),
+		"bad db nonce": mutate(func(h map[string]any) { h["params"] = map[string]string{"nonce": "zz", "tag": "00"} }),
+	} {
+		if _, err := ParseAegis(data, fixturePassword("backup-pass")); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// encryptAndOTP writes andOTP's current backup format:
+// iterations | salt | IV | AES-256-GCM ciphertext and tag
+func encryptAndOTP(t *testing.T, plain []byte, password string, iterations uint32) []byte {
+	t.Helper()
+	salt, nonce := randomBytes(t, andOTPSaltSize), randomBytes(t, andOTPNonceSize)
+	key := pbkdf2.Key([]byte(password), salt, int(iterations), 32, sha1.New)
+	ciphertext, tag := gcmSeal(t, key, nonce, plain)
+
+	data := binary.BigEndian.AppendUint32(nil, iterations)
+	data = append(data, salt...)
+	data = append(data, nonce...)
+	return append(append(data, ciphertext...), tag...)
+}
+
+// encryptAndOTPLegacy writes the format of andOTP before 0.6.3:
+// IV | AES-256-GCM ciphertext and tag, keyed with SHA-256(password)
+func encryptAndOTPLegacy(t *testing.T, plain []byte, password string) []byte {
+	t.Helper()
+	key := sha256.Sum256([]byte(password))
+	nonce := randomBytes(t, andOTPNonceSize)
+	ciphertext, tag := gcmSeal(t, key[:], nonce, plain)
+	return append(append(nonce, ciphertext...), tag...)
+}
+
+func TestParseAndOTP(t *testing.T) {
+	plain := readFixture(t, "andotp-plain.json")
+
+	want := []*models.Entry{
+		{
+			Name:     "GitHub",
+			Username: "octocat",
+			OTP:      "otpauth://totp/GitHub:octocat?issuer=GitHub&secret=JBSWY3DPEHPK3PXP",
+			Tags:     []string{"work"},
+		},
+		{
+			Name:     "me@example.com",
+			Username: "me@example.com",
+			OTP:      "otpauth://hotp/me@example.com?algorithm=SHA512&counter=7&digits=8&secret=GEZDGNBVGY3TQOJQ",
+			Tags:     []string{},
+		},
+	}
+	skipped := []Skipped{
+		{Source: "Steam", Reason: `unsupported OTP type "STEAM"`},
+		{Source: "entry 4", Reason: "OTP secret is not valid base32: illegal base32 data at input byte 0"},
+	}
+
+	for name, data := range map[string][]byte{
+		"plain":     plain,
+		"encrypted": encryptAndOTP(t, plain, "backup-pass", 1000),
+		"legacy":    encryptAndOTPLegacy(t, plain, "backup-pass"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseAndOTP(data, fixturePassword("backup-pass"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Entries, want) {
+				for _, entry := range result.Entries {
+					t.Logf("got %+v", *entry)
+				}
+				t.Fatal("entries differ")
+			}
+			if !reflect.DeepEqual(result.Skipped, skipped) {
+				t.Errorf("skipped = %+v, want %+v", result.Skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestParseAndOTPMalformed(t *testing.T) {
+	plain := readFixture(t, "andotp-plain.json")
+	encrypted := encryptAndOTP(t, plain, "backup-pass", 1000)
+
+	truncated := encrypted[:len(encrypted)-1]
+	flipped := append([]byte(nil), encrypted...)
+	flipped[len(flipped)/2] ^= 1
+	hugeIterations := append([]byte(nil), encrypted...)
+	binary.BigEndian.PutUint32(hugeIterations, andOTPMaxIteration+1)
+
+	for name, data := range map[string][]byte{
+		"not JSON":        []byte("[not json"),
+		"not a list":      []byte(`{"secret": "JBSWY3DPEHPK3PXP"}`),
+		"too short":       []byte("abc"),
+		"truncated":       truncated,
+		"flipped":         flipped,
+		"huge iterations": hugeIterations,
+		"not JSON inside": encryptAndOTP(t, []byte("[{oops"), "backup-pass", 1000),
+	} {
+		if _, err := ParseAndOTP(data, fixturePassword("backup-pass")); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	if _, err := ParseAndOTP(encrypted, fixturePassword("wrong")); err == nil {
+		t.Error("wrong password: no error")
+	}
+}
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Decrypter decrypts a single password-store file
+// The default implementation shells out to gpg so the user's gpg-agent
+// and pinentry handle key unlocking
+type Decrypter func(path string) ([]byte, error)
+
+// GPGDecrypt decrypts a file with the gpg binary on PATH
+func GPGDecrypt(path string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--yes", "--decrypt", path) // #nosec G204 -- fixed binary, file path argument
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// ParsePassStore walks a password-store directory and decrypts every .gpg file
+//
+// Layout conventions (https://www.passwordstore.org/):
+//   - The first line of each file is the password
+//   - Following lines may contain "key: value" pairs such as "login:",
+//     "username:", "user:", "email:", "url:" or "tags:", and an otpauth://
+//     URI as written by pass-otp; everything else is kept as notes
+//   - The directory path maps to the category ("work/aws/root.gpg" becomes
+//     category "work/aws", name "root")
+func ParsePassStore(root string, decrypt Decrypter) (*Result, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password store: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	result := &Result{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip git metadata and other hidden directories
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, ".gpg"))
+
+		plaintext, err := decrypt(path)
+		if err != nil {
+			result.skip(rel, err.Error())
+			return nil
+		}
+
+		entry := parsePassFile(rel, string(plaintext))
+		if reason := incomplete(entry); reason != "" {
+			result.skip(rel, reason)
+			return nil
+		}
+
+		result.Entries = append(result.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk password store: %w", err)
+	}
+
+	return result, nil
+}
+
+// parsePassFile converts the decrypted contents of a pass file into an entry
+func parsePassFile(rel, content string) *models.Entry {
+	entry := &models.Entry{
+		Name:     filepath.Base(rel),
+		Category: "general",
+	}
+
+	if dir := filepath.Dir(rel); dir != "." {
+		entry.Category = dir
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	entry.Password = lines[0]
+
+	var notes []string
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(strings.TrimSpace(line), "otpauth://") && entry.OTP == "" {
+			entry.OTP = strings.TrimSpace(line)
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "login", "username", "user", "email":
+				if entry.Username == "" {
+					entry.Username = value
+					continue
+				}
+			case "url", "website", "site":
+				if entry.URL == "" {
+					entry.URL = value
+					continue
+				}
+			case "tags":
+				if len(entry.Tags) == 0 {
+					entry.Tags = splitTags(value)
+					continue
+				}
+			}
+		}
+		notes = append(notes, line)
+	}
+
+	entry.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return entry
+}
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Report statuses
+const (
+	StatusCreated = "created" // stored as a new entry
+	StatusUpdated = "updated" // merged into an existing entry
+	StatusSkipped = "skipped" // deliberately not imported
+	StatusError   = "error"   // storing the record failed
+)
+
+// Report records what happened to every record of an import, so large
+// migrations can be reviewed and the failures imported again
+// It holds names and usernames but never passwords
+type Report struct {
+	Source     string      `json:"source"`
+	ImportedAt time.Time   `json:"imported_at"`
+	Rows       []ReportRow `json:"rows"`
+}
+
+// ReportRow is the outcome for one source record
+type ReportRow struct {
+	Name     string `json:"name"`               // Record name in the export
+	Username string `json:"username,omitempty"` // Record username in the export
+	Status   string `json:"status"`
+	Entry    string `json:"entry,omitempty"`  // Vault entry it went to, if any
+	Reason   string `json:"reason,omitempty"` // Why it was skipped or failed
+}
+
+// NewReport starts a report for an import from source
+func NewReport(source string) *Report {
+	return &Report{Source: source, ImportedAt: time.Now()}
+}
+
+// Add records the outcome for an imported record
+func (r *Report) Add(record *models.Entry, status, entry, reason string) {
+	r.Rows = append(r.Rows, ReportRow{
+		Name:     record.Name,
+		Username: record.Username,
+		Status:   status,
+		Entry:    entry,
+		Reason:   reason,
+	})
+}
+
+// AddSkipped records source records the parser could not use
+func (r *Report) AddSkipped(skipped []Skipped) {
+	for _, s := range skipped {
+		r.Rows = append(r.Rows, ReportRow{Name: s.Source, Status: StatusSkipped, Reason: s.Reason})
+	}
+}
+
+// Count returns the number of rows with a status
+func (r *Report) Count(status string) int {
+	n := 0
+	for _, row := range r.Rows {
+		if row.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Done reports whether a record was created or updated by this import,
+// matching by name and username
+func (r *Report) Done(record *models.Entry) bool {
+	for _, row := range r.Rows {
+		if (row.Status == StatusCreated || row.Status == StatusUpdated) &&
+			row.Name == record.Name && strings.EqualFold(row.Username, record.Username) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write saves the report as Markdown for a .md path and as JSON otherwise
+func (r *Report) Write(path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		data = []byte(r.markdown())
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(r, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write import report: %w", err)
+	}
+	return nil
+}
+
+func (r *Report) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Import from %s\n\n", r.Source)
+	fmt.Fprintf(&b, "%s\n\n", r.ImportedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Created: %d\n- Updated: %d\n- Skipped: %d\n- Errors: %d\n",
+		r.Count(StatusCreated), r.Count(StatusUpdated), r.Count(StatusSkipped), r.Count(StatusError))
+
+	for _, status := range []string{StatusError, StatusSkipped, StatusUpdated, StatusCreated} {
+		if r.Count(status) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| Record | Username | Entry | Reason |\n|---|---|---|---|\n",
+			strings.ToUpper(status[:1])+status[1:])
+		for _, row := range r.Rows {
+			if row.Status == status {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+					markdownCell(row.Name), markdownCell(row.Username), markdownCell(row.Entry), markdownCell(row.Reason))
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownCell keeps a value from breaking the table
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", " ")
+}
+
+// ReadReport loads a JSON report written by an earlier import
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid import report (only JSON reports can be retried): %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,317 @@
+#!/usr/bin/env python3
+"""Write the KDBX 4 fixtures used by kdbx_test.go
+
+Built from the KeePass file format documentation and RFC 9106, with the
+Python cryptography package for AES and ChaCha20 and the Argon2 below,
+independently of the Go parser. Password: gpasswd-test. Run from this
+directory:
+
+    python3 kdbx4.py
+"""
+
+import base64
+import gzip
+import hashlib
+import hmac
+import os
+import struct
+
+from cryptography.hazmat.primitives import padding
+from cryptography.hazmat.primitives.ciphers import Cipher, algorithms, modes
+
+PASSWORD = b"gpasswd-test"
+
+CIPHER_AES = bytes.fromhex("31c1f2e6bf714350be5805216afc5aff")
+CIPHER_CHACHA20 = bytes.fromhex("d6038a2b8b6f4cb5a524339a31dbb59a")
+KDF_AES = bytes.fromhex("c9d9f39a628a4460bf740d08c18a4fea")
+KDF_ARGON2D = bytes.fromhex("ef636ddf8c29444b91f7a9a403e30a0c")
+KDF_ARGON2ID = bytes.fromhex("9e298b1956db4773b23dfc3ec6f0a1e6")
+
+MASK = (1 << 64) - 1
+
+
+def blake2b_long(data, length):
+    """H' of RFC 9106 section 3.3"""
+    data = struct.pack("<I", length) + data
+    if length <= 64:
+        return hashlib.blake2b(data, digest_size=length).digest()
+    out = b""
+    v = hashlib.blake2b(data).digest()
+    while True:
+        out += v[:32]
+        if length - len(out) <= 64:
+            return out + hashlib.blake2b(v, digest_size=length - len(out)).digest()
+        v = hashlib.blake2b(v).digest()
+
+
+def gb(v, a, b, c, d):
+    def mul(x, y):
+        return 2 * (x & 0xFFFFFFFF) * (y & 0xFFFFFFFF)
+
+    def rotr(x, n):
+        return ((x >> n) | (x << (64 - n))) & MASK
+
+    v[a] = (v[a] + v[b] + mul(v[a], v[b])) & MASK
+    v[d] = rotr(v[d] ^ v[a], 32)
+    v[c] = (v[c] + v[d] + mul(v[c], v[d])) & MASK
+    v[b] = rotr(v[b] ^ v[c], 24)
+    v[a] = (v[a] + v[b] + mul(v[a], v[b])) & MASK
+    v[d] = rotr(v[d] ^ v[a], 16)
+    v[c] = (v[c] + v[d] + mul(v[c], v[d])) & MASK
+    v[b] = rotr(v[b] ^ v[c], 63)
+
+
+def permute(v):
+    gb(v, 0, 4, 8, 12)
+    gb(v, 1, 5, 9, 13)
+    gb(v, 2, 6, 10, 14)
+    gb(v, 3, 7, 11, 15)
+    gb(v, 0, 5, 10, 15)
+    gb(v, 1, 6, 11, 12)
+    gb(v, 2, 7, 8, 13)
+    gb(v, 3, 4, 9, 14)
+
+
+def compress(x, y):
+    """G of RFC 9106 section 3.5 on blocks of 128 words"""
+    r = [a ^ b for a, b in zip(x, y)]
+    q = list(r)
+    # Rows: eight 16-word registers
+    for i in range(8):
+        row = q[16 * i:16 * i + 16]
+        permute(row)
+        q[16 * i:16 * i + 16] = row
+    # Columns: word pairs 2i, 2i+1 of every row
+    for i in range(8):
+        idx = []
+        for row in range(8):
+            idx += [16 * row + 2 * i, 16 * row + 2 * i + 1]
+        col = [q[j] for j in idx]
+        permute(col)
+        for j, val in zip(idx, col):
+            q[j] = val
+    return [a ^ b for a, b in zip(q, r)]
+
+
+def to_words(b):
+    return list(struct.unpack("<128Q", b))
+
+
+def argon2(mode, password, salt, secret, ad, passes, memory, lanes, length):
+    """Argon2d (mode 0) or Argon2id (mode 2), version 0x13, memory in KiB"""
+    h = hashlib.blake2b()
+    for v in (lanes, length, memory, passes, 0x13, mode):
+        h.update(struct.pack("<I", v))
+    for b in (password, salt, secret, ad):
+        h.update(struct.pack("<I", len(b)) + b)
+    h0 = h.digest()
+
+    m = 4 * lanes * (memory // (4 * lanes))
+    q = m // lanes
+    seg = q // 4
+    B = [[None] * q for _ in range(lanes)]
+    for lane in range(lanes):
+        for j in range(2):
+            B[lane][j] = to_words(blake2b_long(h0 + struct.pack("<II", j, lane), 1024))
+    zero = [0] * 128
+
+    for r in range(passes):
+        for sl in range(4):
+            for lane in range(lanes):
+                independent = mode == 2 and r == 0 and sl < 2
+                counter = 0
+                addresses = []
+                start = 2 if r == 0 and sl == 0 else 0
+                for idx in range(start, seg):
+                    j = sl * seg + idx
+                    prev = B[lane][(j - 1) % q]
+                    if independent:
+                        if not addresses:
+                            counter += 1
+                            inp = [r, lane, sl, m, passes, mode, counter] + [0] * 121
+                            addresses = compress(zero, compress(zero, inp))
+                        rand = addresses[idx % 128]
+                        if idx % 128 == 127:
+                            addresses = []
+                    else:
+                        rand = prev[0]
+                    j1, j2 = rand & 0xFFFFFFFF, rand >> 32
+                    ref_lane = lane if r == 0 and sl == 0 else j2 % lanes
+                    same = ref_lane == lane
+                    if r == 0:
+                        area = sl * seg + idx - 1 if same else sl * seg - (1 if idx == 0 else 0)
+                        start_pos = 0
+                    else:
+                        area = q - seg + idx - 1 if same else q - seg - (1 if idx == 0 else 0)
+                        start_pos = ((sl + 1) % 4) * seg
+                    x = (j1 * j1) >> 32
+                    y = (area * x) >> 32
+                    z = area - 1 - y
+                    ref = B[ref_lane][(start_pos + z) % q]
+                    new = compress(prev, ref)
+                    if r > 0:
+                        new = [a ^ b for a, b in zip(new, B[lane][j])]
+                    B[lane][j] = new
+
+    c = B[0][q - 1]
+    for lane in range(1, lanes):
+        c = [a ^ b for a, b in zip(c, B[lane][q - 1])]
+    return blake2b_long(struct.pack("<128Q", *c), length)
+
+
+# RFC 9106 section 5 test vectors
+_vector = (b"\x01" * 32, b"\x02" * 16, b"\x03" * 8, b"\x04" * 12, 3, 32, 4, 32)
+assert argon2(0, *_vector).hex() == "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"
+assert argon2(2, *_vector).hex() == "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"
+
+
+def variant_dict(items):
+    out = struct.pack("<H", 0x0100)
+    for kind, name, value in items:
+        out += bytes([kind]) + struct.pack("<I", len(name)) + name.encode()
+        out += struct.pack("<I", len(value)) + value
+    return out + b"\x00"
+
+
+def field(fid, value):
+    return bytes([fid]) + struct.pack("<I", len(value)) + value
+
+
+def chacha20(key, nonce, data):
+    enc = Cipher(algorithms.ChaCha20(key, b"\x00" * 4 + nonce), mode=None).encryptor()
+    return enc.update(data) + enc.finalize()
+
+
+class Protector:
+    """The ChaCha20 inner stream, applied to protected values in order"""
+
+    def __init__(self, key):
+        digest = hashlib.sha512(key).digest()
+        self.key, self.nonce = digest[:32], digest[32:44]
+        self.used = b""
+
+    def protect(self, text):
+        data = text.encode()
+        stream = chacha20(self.key, self.nonce, self.used + data)
+        self.used += data
+        return base64.b64encode(stream[-len(data):] if data else b"").decode()
+
+
+def entry(p, strings, tags=""):
+    out = "<Entry>"
+    for key, value, protected in strings:
+        if protected:
+            out += f'<String><Key>{key}</Key><Value Protected="True">{p.protect(value)}</Value></String>'
+        else:
+            out += f"<String><Key>{key}</Key><Value>{value}</Value></String>"
+    if tags:
+        out += f"<Tags>{tags}</Tags>"
+    return out + "</Entry>"
+
+
+def document(stream_key):
+    # The inner stream runs over the protected values in document order
+    p = Protector(stream_key)
+    bin_uuid = base64.b64encode(b"recycle-bin-uuid").decode()
+    email = entry(p, [
+        ("Title", "Mail", False),
+        ("UserName", "me@example.com", False),
+        ("Password", "m@il-pass", True),
+    ])
+    empty = entry(p, [("Title", "", False), ("Password", "", True)])
+    github = entry(p, [
+        ("Title", "GitHub", False),
+        ("UserName", "octocat", False),
+        ("Password", "gh-s3cret!", True),
+        ("URL", "https://github.com/login", False),
+        ("Notes", "work account", False),
+        ("otp", "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub", True),
+        ("Recovery", "1234-5678", True),
+    ], tags="dev;work")
+    deleted = entry(p, [
+        ("Title", "Old", False),
+        ("UserName", "old", False),
+        ("Password", "old-pass", True),
+    ])
+    return (
+        '<?xml version="1.0" encoding="utf-8" standalone="yes"?>'
+        "<KeePassFile><Meta>"
+        f"<RecycleBinEnabled>True</RecycleBinEnabled><RecycleBinUUID>{bin_uuid}</RecycleBinUUID>"
+        "</Meta><Root><Group><UUID>cm9vdC1ncm91cC11dWlkLS0=</UUID><Name>Fixture</Name>"
+        f"{email}{empty}"
+        f"<Group><UUID>aW50ZXJuZXQtZ3JvdXAtLS0=</UUID><Name>Internet</Name>"
+        f"<Group><UUID>Y29kZS1ncm91cC11dWlkLS0=</UUID><Name>Code</Name>{github}</Group></Group>"
+        f"<Group><UUID>{bin_uuid}</UUID><Name>Recycle Bin</Name>{deleted}</Group>"
+        "</Group></Root></KeePassFile>"
+    ).encode()
+
+
+def write(path, cipher_id, kdf):
+    master_seed = os.urandom(32)
+    composite = hashlib.sha256(hashlib.sha256(PASSWORD).digest()).digest()
+
+    if kdf in ("argon2d", "argon2id"):
+        salt = os.urandom(32)
+        memory, iterations, lanes = 64 * 1024, 2, 2
+        mode, uuid = (0, KDF_ARGON2D) if kdf == "argon2d" else (2, KDF_ARGON2ID)
+        params = variant_dict([
+            (0x42, "$UUID", uuid),
+            (0x42, "S", salt),
+            (0x04, "P", struct.pack("<I", lanes)),
+            (0x05, "M", struct.pack("<Q", memory)),
+            (0x05, "I", struct.pack("<Q", iterations)),
+            (0x04, "V", struct.pack("<I", 0x13)),
+        ])
+        transformed = argon2(mode, composite, salt, b"", b"", iterations, memory // 1024, lanes, 32)
+    else:
+        seed, rounds = os.urandom(32), 1000
+        params = variant_dict([
+            (0x42, "$UUID", KDF_AES),
+            (0x05, "R", struct.pack("<Q", rounds)),
+            (0x42, "S", seed),
+        ])
+        key = bytearray(composite)
+        enc = Cipher(algorithms.AES(seed), modes.ECB()).encryptor()
+        for _ in range(rounds):
+            key = bytearray(enc.update(bytes(key)))
+        transformed = hashlib.sha256(bytes(key)).digest()
+
+    iv = os.urandom(16 if cipher_id == CIPHER_AES else 12)
+    header = struct.pack("<IIHH", 0x9AA2D903, 0xB54BFB67, 0, 4)
+    header += field(2, cipher_id) + field(3, struct.pack("<I", 1))
+    header += field(4, master_seed) + field(7, iv) + field(11, params)
+    header += field(0, b"\r\n\r\n")
+
+    stream_key = os.urandom(64)
+    inner = field(1, struct.pack("<I", 3)) + field(2, stream_key) + field(0, b"")
+    plaintext = gzip.compress(inner + document(stream_key))
+
+    key = hashlib.sha256(master_seed + transformed).digest()
+    if cipher_id == CIPHER_AES:
+        padder = padding.PKCS7(128).padder()
+        padded = padder.update(plaintext) + padder.finalize()
+        enc = Cipher(algorithms.AES(key), modes.CBC(iv)).encryptor()
+        ciphertext = enc.update(padded) + enc.finalize()
+    else:
+        ciphertext = chacha20(key, iv, plaintext)
+
+    hmac_key = hashlib.sha512(master_seed + transformed + b"\x01").digest()
+
+    def block_key(index):
+        return hashlib.sha512(struct.pack("<Q", index) + hmac_key).digest()
+
+    out = header + hashlib.sha256(header).digest()
+    out += hmac.new(block_key(0xFFFFFFFFFFFFFFFF), header, hashlib.sha256).digest()
+    blocks = [ciphertext[i:i + 4096] for i in range(0, len(ciphertext), 4096)] + [b""]
+    for index, block in enumerate(blocks):
+        size = struct.pack("<I", len(block))
+        mac = hmac.new(block_key(index), struct.pack("<Q", index) + size + block, hashlib.sha256).digest()
+        out += mac + size + block
+
+    with open(path, "wb") as f:
+        f.write(out)
+
+
+write("argon2d-aes.kdbx", CIPHER_AES, "argon2d")
+write("argon2id-chacha20.kdbx", CIPHER_CHACHA20, "argon2id")
+write("aeskdf-aes.kdbx", CIPHER_AES, "aes")
@@ -0,0 +1,288 @@
+// Package keys handles the private keys a vault can hold as key entries:
+// age identities, OpenSSH keys and plain Ed25519 keys. It generates and
+// parses them and performs the few operations gpasswd offers with them:
+// signing files, and encrypting and decrypting age files
+package keys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/kitsnail/gpasswd/internal/age"
+)
+
+// Key types
+const (
+	TypeAge     = "age"     // age X25519 identity: encrypt and decrypt
+	TypeSSH     = "ssh"     // OpenSSH private key: sign (ssh-keygen -Y compatible)
+	TypeEd25519 = "ed25519" // PKCS#8 Ed25519 key: sign (openssl compatible)
+)
+
+// Types lists the supported key types
+var Types = []string{TypeAge, TypeSSH, TypeEd25519}
+
+var (
+	// ErrUnsupported is returned for an operation the key type can't do
+	ErrUnsupported = errors.New("operation not supported by this key type")
+
+	// ErrPassphrase is returned by Parse for a passphrase-protected key
+	ErrPassphrase = errors.New("key is protected by a passphrase")
+)
+
+// Key is a parsed private key with its public half
+type Key struct {
+	Type    string
+	Private string // text form stored in the vault
+	Public  string // age1..., authorized_keys line, or PEM public key
+
+	identity *age.Identity
+	signer   crypto.Signer
+}
+
+// ParseType validates a user-supplied key type
+func ParseType(name string) (string, error) {
+	for _, t := range Types {
+		if strings.EqualFold(name, t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key type %q (expected age, ssh or ed25519)", name)
+}
+
+// Generate creates a new key of the given type
+// comment is stored in SSH keys and ignored otherwise
+func Generate(keyType, comment string) (*Key, error) {
+	if keyType == TypeAge {
+		identity, err := age.GenerateIdentity()
+		if err != nil {
+			return nil, err
+		}
+		return fromIdentity(identity), nil
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	switch keyType {
+	case TypeSSH:
+		return fromSSH(private, comment)
+	case TypeEd25519:
+		return fromEd25519(private)
+	default:
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
+}
+
+// Parse reads a private key in any supported text form: an age identity
+// (AGE-SECRET-KEY-1...), an OpenSSH or PEM SSH key, or a PKCS#8 Ed25519
+// key. A passphrase-protected key returns ErrPassphrase; see
+// ParseWithPassphrase
+func Parse(text string) (*Key, error) {
+	return parse(text, nil)
+}
+
+// ParseWithPassphrase reads a passphrase-protected SSH key; the key is
+// stored without the passphrase since the vault protects it
+func ParseWithPassphrase(text string, passphrase []byte) (*Key, error) {
+	return parse(text, passphrase)
+}
+
+func parse(text string, passphrase []byte) (*Key, error) {
+	text = strings.TrimSpace(text)
+
+	if identities, err := age.ParseIdentities([]byte(text)); err == nil {
+		if len(identities) > 1 {
+			return nil, errors.New("the file holds several age identities; add them one at a time")
+		}
+		return fromIdentity(identities[0]), nil
+	}
+
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, errors.New("not an age, SSH or Ed25519 private key")
+	}
+
+	if block.Type == "PRIVATE KEY" {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PKCS#8 key: %w", err)
+		}
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("PKCS#8 keys must be Ed25519; use an OpenSSH key for RSA and ECDSA")
+		}
+		return fromEd25519(private)
+	}
+
+	var raw any
+	var err error
+	if passphrase != nil {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(text), passphrase)
+	} else {
+		raw, err = ssh.ParseRawPrivateKey([]byte(text))
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, ErrPassphrase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH key: %w", err)
+	}
+
+	return fromSSH(raw, "")
+}
+
+func fromIdentity(identity *age.Identity) *Key {
+	return &Key{
+		Type:     TypeAge,
+		Private:  identity.String(),
+		Public:   identity.Recipient().String(),
+		identity: identity,
+	}
+}
+
+func fromSSH(raw any, comment string) (*Key, error) {
+	signer, ok := raw.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported SSH key algorithm")
+	}
+	sshSigner, err := ssh.NewSignerFromSigner(signer)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported SSH key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(raw, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SSH key: %w", err)
+	}
+
+	public := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey())))
+	if comment != "" {
+		public += " " + comment
+	}
+	return &Key{
+		Type:    TypeSSH,
+		Private: string(pem.EncodeToMemory(block)),
+		Public:  public,
+		signer:  signer,
+	}, nil
+}
+
+func fromEd25519(private ed25519.PrivateKey) (*Key, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return &Key{
+		Type:    TypeEd25519,
+		Private: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		Public:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		signer:  private,
+	}, nil
+}
+
+// Sign signs data and returns the signature file contents
+// SSH keys produce an armored SSH signature for namespace, checked with
+// 'ssh-keygen -Y verify -n namespace'; Ed25519 keys produce the raw
+// signature in base64, checked with 'openssl pkeyutl -verify -rawin'
+// after decoding
+func (k *Key) Sign(data []byte, namespace string) ([]byte, error) {
+	switch k.Type {
+	case TypeSSH:
+		return sshSign(k.signer, data, namespace)
+	case TypeEd25519:
+		signature, err := k.signer.Sign(rand.Reader, data, crypto.Hash(0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+		return []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+	default:
+		return nil, fmt.Errorf("%w: %s keys can't sign", ErrUnsupported, k.Type)
+	}
+}
+
+// Encrypt encrypts data to the key's own public key as an age file
+func (k *Key) Encrypt(data []byte) ([]byte, error) {
+	if k.Type != TypeAge {
+		return nil, fmt.Errorf("%w: only age keys encrypt", ErrUnsupported)
+	}
+	return age.Encrypt(data, []*age.Recipient{k.identity.Recipient()})
+}
+
+// Decrypt opens an age file encrypted to the key
+func (k *Key) Decrypt(data []byte) ([]byte, error) {
+	if k.Type != TypeAge {
+		return nil, fmt.Errorf("%w: only age keys decrypt", ErrUnsupported)
+	}
+	return age.Decrypt(data, []*age.Identity{k.identity})
+}
+
+// sshsigMagic starts SSH signatures (PROTOCOL.sshsig)
+const sshsigMagic = "SSHSIG"
+
+// sshSign creates an armored SSH signature over data
+func sshSign(signer crypto.Signer, data []byte, namespace string) ([]byte, error) {
+	if namespace == "" {
+		return nil, errors.New("SSH signatures need a namespace")
+	}
+	sshSigner, err := ssh.NewSignerFromSigner(signer)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported SSH key: %w", err)
+	}
+
+	digest := sha512.Sum512(data)
+	signed := ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      string
+	}{namespace, "", "sha512", string(digest[:])})
+
+	// RSA keys must not sign with SHA-1
+	var signature *ssh.Signature
+	message := append([]byte(sshsigMagic), signed...)
+	if algorithmSigner, ok := sshSigner.(ssh.AlgorithmSigner); ok && sshSigner.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, message, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = sshSigner.Sign(rand.Reader, message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature string
+	}{1, string(sshSigner.PublicKey().Marshal()), namespace, "", "sha512", string(ssh.Marshal(signature))})
+	blob = append([]byte(sshsigMagic), blob...)
+
+	var armored bytes.Buffer
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+	return armored.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+// Package loginsteps parses the login procedure stored with an entry: the
+// ordered steps 'gpasswd login' walks through for sites that ask for the
+// username, password and one-time code on separate pages
+package loginsteps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Actions a step can take
+const (
+	ActionCopy = "copy" // copy an entry field (Field)
+	ActionOTP  = "otp"  // copy the current one-time code
+	ActionWait = "wait" // pause for Wait before the next step
+	ActionOpen = "open" // open the entry URL in the browser
+	ActionSay  = "say"  // show an instruction (Text) and wait for Enter
+)
+
+// MaxWait bounds a wait step
+const MaxWait = 5 * time.Minute
+
+// Step is one parsed step of a login procedure
+type Step struct {
+	Action string
+	Field  string        // for ActionCopy: user, password, url, ...
+	Wait   time.Duration // for ActionWait
+	Text   string        // for ActionSay
+}
+
+// copyFields are the entry fields a copy step accepts, with their aliases
+var copyFields = map[string]string{
+	"user":     "user",
+	"username": "user",
+	"login":    "user",
+	"password": "password",
+	"pass":     "password",
+	"token":    "password",
+	"url":      "url",
+}
+
+// Parse parses one step:
+//
+//	user | password | url   copy the field
+//	otp                     copy the current one-time code
+//	wait 5s                 pause
+//	open                    open the entry URL in the browser
+//	say TEXT                show TEXT and wait for Enter
+func Parse(s string) (Step, error) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	word = strings.ToLower(word)
+	rest = strings.TrimSpace(rest)
+
+	switch word {
+	case "otp", "totp", "code":
+		return Step{Action: ActionOTP}, nil
+	case "open":
+		return Step{Action: ActionOpen}, nil
+	case "wait":
+		d, err := time.ParseDuration(rest)
+		if err != nil || d <= 0 || d > MaxWait {
+			return Step{}, fmt.Errorf("invalid login step %q: wait needs a duration up to %s, e.g. 'wait 3s'", s, MaxWait)
+		}
+		return Step{Action: ActionWait, Wait: d}, nil
+	case "say":
+		if rest == "" {
+			return Step{}, fmt.Errorf("invalid login step %q: say needs a text", s)
+		}
+		return Step{Action: ActionSay, Text: rest}, nil
+	}
+
+	if field, ok := copyFields[word]; ok && rest == "" {
+		return Step{Action: ActionCopy, Field: field}, nil
+	}
+	return Step{}, fmt.Errorf("invalid login step %q (expected user, password, url, otp, wait DURATION, open or say TEXT)", s)
+}
+
+// ParseAll parses every step of a procedure
+func ParseAll(steps []string) ([]Step, error) {
+	parsed := make([]Step, 0, len(steps))
+	for _, s := range steps {
+		step, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, step)
+	}
+	return parsed, nil
+}
+
+// Default is the procedure for entries without one: username, password
+// and, if the entry has an OTP secret, the one-time code
+func Default(hasOTP bool) []Step {
+	steps := []Step{{Action: ActionCopy, Field: "user"}, {Action: ActionCopy, Field: "password"}}
+	if hasOTP {
+		steps = append(steps, Step{Action: ActionOTP})
+	}
+	return steps
+}
+
+// String formats a step the way Parse reads it
+func (s Step) String() string {
+	switch s.Action {
+	case ActionCopy:
+		return s.Field
+	case ActionWait:
+		return "wait " + s.Wait.String()
+	case ActionSay:
+		return "say " + s.Text
+	default:
+		return s.Action
+	}
+}
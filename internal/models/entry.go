@@ -1,6 +1,27 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Entry types
+const (
+	EntryTypeLogin = "login" // website or service login (default)
+	EntryTypeToken = "token" // API key or access token
+	EntryTypeKey   = "key"   // private key (age, SSH or Ed25519)
+	EntryTypeNote  = "note"  // secure note: text in Notes, no password
+)
+
+// FavoriteTag marks an entry as a favorite, e.g. one starred in LastPass
+// Search ranks favorites higher
+const FavoriteTag = "favorite"
+
+// Security levels
+const (
+	SecurityLevelNormal = "normal" // default
+	SecurityLevelHigh   = "high"   // fresh master password, hidden username, short clipboard timeout
+)
 
 // Entry represents a password entry in the vault
 type Entry struct {
@@ -14,6 +35,111 @@ type Entry struct {
 	Tags      []string  `json:"tags"`     // e.g., ["work", "google"]
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Icon is an emoji shown with the entry, e.g. suggested from its URL
+	// (optional)
+	Icon string `json:"icon,omitempty"`
+
+	// ChangePasswordURL is the site's password change page (optional)
+	ChangePasswordURL string `json:"change_password_url,omitempty"`
+
+	// OTP is the one-time password secret as an otpauth:// URI (sensitive, optional)
+	OTP string `json:"otp,omitempty"`
+
+	// Type is EntryTypeLogin, EntryTypeToken, EntryTypeKey or EntryTypeNote
+	// (empty means login)
+	Type string `json:"type,omitempty"`
+
+	// Token fields; the token value itself is stored in Password
+	Service   string     `json:"service,omitempty"`    // issuing service, e.g. "GitHub"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = never expires
+	Scopes    []string   `json:"scopes,omitempty"`     // e.g. ["repo", "read:org"]
+
+	// Key fields; the private key itself is stored in Password
+	KeyType   string `json:"key_type,omitempty"`   // "age", "ssh" or "ed25519" (see package keys)
+	PublicKey string `json:"public_key,omitempty"` // in the key type's usual text form
+
+	// SecurityLevel is SecurityLevelNormal or SecurityLevelHigh (empty means normal)
+	SecurityLevel string `json:"security_level,omitempty"`
+
+	// ListableUsername also stores the username in plaintext so
+	// 'gpasswd user' can print it without the master password
+	ListableUsername bool `json:"listable_username,omitempty"`
+
+	// LoginSteps is the procedure 'gpasswd login' walks through, e.g.
+	// ["user", "wait 2s", "password", "otp"] (see package loginsteps)
+	LoginSteps []string `json:"login_steps,omitempty"`
+
+	// HistoryRetention overrides the vault's history retention for this
+	// entry (nil uses the vault's)
+	HistoryRetention *Retention `json:"history_retention,omitempty"`
+}
+
+// IsHighSecurity reports whether the entry has the high security level
+func (e *Entry) IsHighSecurity() bool {
+	return e.SecurityLevel == SecurityLevelHigh
+}
+
+// IsLogin reports whether the entry is a website or service login
+func (e *Entry) IsLogin() bool {
+	return e.Type == "" || e.Type == EntryTypeLogin
+}
+
+// IsToken reports whether the entry is an API key or access token
+func (e *Entry) IsToken() bool {
+	return e.Type == EntryTypeToken
+}
+
+// IsKey reports whether the entry holds a private key
+func (e *Entry) IsKey() bool {
+	return e.Type == EntryTypeKey
+}
+
+// IsNote reports whether the entry is a secure note
+func (e *Entry) IsNote() bool {
+	return e.Type == EntryTypeNote
+}
+
+// Missing returns what the entry lacks for its type, "" when it is complete
+// Tokens and keys need their value, notes their text. A login needs a
+// password or, for password-less sign-in (single sign-on, passkeys, magic
+// links), at least a username
+func (e *Entry) Missing() string {
+	switch e.Type {
+	case EntryTypeToken:
+		if e.Password == "" {
+			return "a token value"
+		}
+	case EntryTypeKey:
+		if e.Password == "" {
+			return "a private key"
+		}
+	case EntryTypeNote:
+		if strings.TrimSpace(e.Notes) == "" {
+			return "note text"
+		}
+	default:
+		if e.Password == "" && e.Username == "" {
+			return "a password or a username"
+		}
+	}
+	return ""
+}
+
+// ExpiresWithin reports whether the entry expires before now+d
+// Entries without an expiry never do; expired entries always do
+func (e *Entry) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return e.ExpiresAt != nil && e.ExpiresAt.Before(now.Add(d))
+}
+
+// IsFavorite reports whether the entry has the favorite tag
+func (e *Entry) IsFavorite() bool {
+	for _, tag := range e.Tags {
+		if strings.EqualFold(tag, FavoriteTag) {
+			return true
+		}
+	}
+	return false
 }
 
 // SearchText generates the plain-text search index for the entry
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Retention limits the archived versions kept of an entry
+// Zero fields don't limit, so the zero value keeps everything
+type Retention struct {
+	Versions int `json:"versions,omitempty"` // newest archived versions kept
+	Days     int `json:"days,omitempty"`     // days a version is kept after it was replaced
+}
+
+// Unlimited reports whether the retention keeps every version
+func (r Retention) Unlimited() bool {
+	return r.Versions <= 0 && r.Days <= 0
+}
+
+// Expired reports whether the archived version at index (0 = newest),
+// replaced at validTo, falls outside the retention at now
+func (r Retention) Expired(index int, validTo, now time.Time) bool {
+	if r.Versions > 0 && index >= r.Versions {
+		return true
+	}
+	return r.Days > 0 && validTo.Before(now.AddDate(0, 0, -r.Days))
+}
+
+// String describes the retention, e.g. "10 versions, 365 days"
+func (r Retention) String() string {
+	if r.Unlimited() {
+		return "keep all versions"
+	}
+	var parts []string
+	if r.Versions > 0 {
+		parts = append(parts, fmt.Sprintf("%d versions", r.Versions))
+	}
+	if r.Days > 0 {
+		parts = append(parts, fmt.Sprintf("%d days", r.Days))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,146 @@
+package nativemsg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+)
+
+// HostName is the name extensions pass to connectNative
+const HostName = "com.kitsnail.gpasswd"
+
+// Browsers lists the browsers Install knows, by the name users give
+var Browsers = []string{"chrome", "chromium", "brave", "edge", "firefox"}
+
+// ErrUnsupported is returned by Install and Uninstall where host manifests
+// are registered some other way (the Windows registry)
+var ErrUnsupported = errors.New("installing native messaging hosts is not supported on this platform")
+
+// Manifest is a native messaging host manifest
+// Chromium based browsers list allowed extension origins, Firefox
+// extension IDs
+type Manifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// NewManifest returns the manifest that lets extensionID start the host at
+// path in browser
+func NewManifest(browser, path, extensionID string) (*Manifest, error) {
+	if err := checkBrowser(browser); err != nil {
+		return nil, err
+	}
+	if extensionID == "" {
+		return nil, errors.New("extension ID cannot be empty")
+	}
+
+	manifest := &Manifest{
+		Name:        HostName,
+		Description: "gpasswd password manager",
+		Path:        path,
+		Type:        "stdio",
+	}
+	if browser == "firefox" {
+		manifest.AllowedExtensions = []string{extensionID}
+	} else {
+		id := strings.TrimSuffix(strings.TrimPrefix(extensionID, "chrome-extension://"), "/")
+		manifest.AllowedOrigins = []string{"chrome-extension://" + id + "/"}
+	}
+	return manifest, nil
+}
+
+// ManifestPath returns where browser looks for the host manifest of the
+// current user
+func ManifestPath(browser string) (string, error) {
+	if err := checkBrowser(browser); err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	var dir string
+	switch runtime.GOOS {
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		dir = map[string]string{
+			"chrome":   filepath.Join(support, "Google", "Chrome", "NativeMessagingHosts"),
+			"chromium": filepath.Join(support, "Chromium", "NativeMessagingHosts"),
+			"brave":    filepath.Join(support, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"),
+			"edge":     filepath.Join(support, "Microsoft Edge", "NativeMessagingHosts"),
+			"firefox":  filepath.Join(support, "Mozilla", "NativeMessagingHosts"),
+		}[browser]
+	case "windows":
+		return "", ErrUnsupported
+	default:
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(home, ".config")
+		}
+		dir = map[string]string{
+			"chrome":   filepath.Join(configDir, "google-chrome", "NativeMessagingHosts"),
+			"chromium": filepath.Join(configDir, "chromium", "NativeMessagingHosts"),
+			"brave":    filepath.Join(configDir, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"),
+			"edge":     filepath.Join(configDir, "microsoft-edge", "NativeMessagingHosts"),
+			"firefox":  filepath.Join(home, ".mozilla", "native-messaging-hosts"),
+		}[browser]
+	}
+	return filepath.Join(dir, HostName+".json"), nil
+}
+
+// Install writes the manifest where its browser finds it and returns the
+// file's path
+func Install(browser string, manifest *Manifest) (string, error) {
+	path, err := ManifestPath(browser)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := atomicfile.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Uninstall removes the manifest of browser; removed is false if there was
+// none
+func Uninstall(browser string) (removed bool, err error) {
+	path, err := ManifestPath(browser)
+	if err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return true, nil
+}
+
+func checkBrowser(browser string) error {
+	for _, known := range Browsers {
+		if browser == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown browser %q (expected %s)", browser, strings.Join(Browsers, ", "))
+}
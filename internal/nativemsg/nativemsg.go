@@ -0,0 +1,83 @@
+// Package nativemsg speaks the native messaging protocol Chrome, Chromium
+// based browsers and Firefox use to talk to a local program, and installs
+// the host manifests that tell a browser which program to start
+//
+// Each message is JSON preceded by its length as a 32-bit unsigned integer
+// in native byte order. The browser starts the host with the extension's
+// origin as an argument and talks to it on stdin and stdout
+// (https://developer.chrome.com/docs/extensions/develop/concepts/native-messaging)
+package nativemsg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxMessageSize caps a message in either direction; browsers refuse
+// larger messages from a host
+const MaxMessageSize = 1 << 20
+
+// ErrTooLarge is returned for messages over MaxMessageSize
+var ErrTooLarge = errors.New("native message too large")
+
+// Read reads one message into v. It returns io.EOF when the browser closed
+// the connection between messages
+func Read(r io.Reader, v any) error {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated message length: %w", err)
+		}
+		return err
+	}
+	if size > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrTooLarge, size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("truncated message: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	return nil
+}
+
+// Write writes v as one message
+func Write(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if len(data) > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrTooLarge, len(data))
+	}
+
+	message := make([]byte, 4, 4+len(data))
+	binary.NativeEndian.PutUint32(message, uint32(len(data)))
+	message = append(message, data...)
+	_, err = w.Write(message)
+	return err
+}
+
+// Caller returns the extension that started the host from the host's
+// arguments: Chrome passes the extension's origin
+// (chrome-extension://ID/), Firefox the manifest path and the extension ID
+// "" if neither is there
+func Caller(args []string) string {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "chrome-extension://") {
+			return arg
+		}
+	}
+	// Firefox: <manifest path> <extension ID>
+	if len(args) >= 2 && strings.HasSuffix(args[0], ".json") {
+		return args[1]
+	}
+	return ""
+}
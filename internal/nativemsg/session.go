@@ -0,0 +1,210 @@
+package nativemsg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Paired sessions
+//
+// An extension pairs once: it generates an X25519 key pair and sends its
+// public key; the host answers with its own and both show a pairing code
+// derived from the two keys, which the user approves with gpasswd. Every
+// later connection opens with a hello carrying fresh ephemeral keys on both
+// sides. The session keys mix the static-static and ephemeral-ephemeral
+// X25519 secrets, so only the paired extension and this host can derive
+// them and a recorded session stays sealed if a static key leaks later.
+// Messages are then AES-256-GCM sealed with one key per direction and
+// counter nonces, so they can't be replayed, reordered or reflected.
+// X25519, HKDF-SHA256 and AES-GCM are all in WebCrypto, so an extension
+// needs no crypto library of its own
+
+const sessionProtocol = "gpasswd-browser-session-v1"
+
+// ErrSessionAuth is returned for a sealed message that fails to
+// authenticate; the session can't be used after it
+var ErrSessionAuth = errors.New("sealed message failed authentication")
+
+// GenerateKey returns a new X25519 key pair for a host or extension
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// ParsePublicKey decodes a base64 X25519 public key
+func ParsePublicKey(encoded string) (*ecdh.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return key, nil
+}
+
+// EncodeKey encodes a public key as base64, as messages carry it
+func EncodeKey(key *ecdh.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key.Bytes())
+}
+
+// KeyID names an extension key for listing and revoking: the first 8
+// bytes of its SHA-256, in hex
+func KeyID(key *ecdh.PublicKey) string {
+	sum := sha256.Sum256(key.Bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// PairingCode returns the code that both the extension and gpasswd show
+// for a pairing, such as "482-019-736"
+func PairingCode(extension, host *ecdh.PublicKey) string {
+	sum := transcript("pairing code", extension.Bytes(), host.Bytes())
+	n := binary.BigEndian.Uint64(sum[:8]) % 1_000_000_000
+	return fmt.Sprintf("%03d-%03d-%03d", n/1_000_000, n/1_000%1_000, n%1_000)
+}
+
+// NormalizePairingCode accepts a code typed with or without separators
+func NormalizePairingCode(code string) (string, error) {
+	var digits strings.Builder
+	for _, r := range code {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == ' ':
+		default:
+			return "", fmt.Errorf("invalid pairing code %q", code)
+		}
+	}
+	d := digits.String()
+	if len(d) != 9 {
+		return "", fmt.Errorf("invalid pairing code %q: expected 9 digits", code)
+	}
+	return d[:3] + "-" + d[3:6] + "-" + d[6:], nil
+}
+
+// Session seals and opens the messages of one paired connection
+type Session struct {
+	send, recv cipher.AEAD
+	sendSeq    uint64
+	recvSeq    uint64
+}
+
+// AcceptSession answers an extension's hello: it derives the session keys
+// from the host key, the paired extension key and the extension's
+// ephemeral key, and returns the host's ephemeral public key to send back
+func AcceptSession(host *ecdh.PrivateKey, extension, extensionEphemeral *ecdh.PublicKey) (*Session, *ecdh.PublicKey, error) {
+	ephemeral, err := GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	static, err := host.ECDH(extension)
+	if err != nil {
+		return nil, nil, err
+	}
+	fresh, err := ephemeral.ECDH(extensionEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	toHost, toExtension, err := sessionKeys(static, fresh, extension, host.PublicKey(), extensionEphemeral, ephemeral.PublicKey())
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := newSession(toExtension, toHost)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, ephemeral.PublicKey(), nil
+}
+
+// sessionKeys derives the key for each direction from the two X25519
+// secrets and every public key of the handshake
+func sessionKeys(static, fresh []byte, extension, host, extensionEphemeral, hostEphemeral *ecdh.PublicKey) (toHost, toExtension []byte, err error) {
+	secret := transcript("session", static, fresh, extension.Bytes(), host.Bytes(), extensionEphemeral.Bytes(), hostEphemeral.Bytes())
+	keys := hkdf.New(sha256.New, secret, nil, []byte(sessionProtocol+" keys"))
+	toHost, toExtension = make([]byte, 32), make([]byte, 32)
+	for _, key := range [][]byte{toHost, toExtension} {
+		if _, err := io.ReadFull(keys, key); err != nil {
+			return nil, nil, err
+		}
+	}
+	return toHost, toExtension, nil
+}
+
+// transcript hashes length-prefixed fields under the protocol name
+func transcript(label string, fields ...[]byte) []byte {
+	h := sha256.New()
+	for _, field := range append([][]byte{[]byte(sessionProtocol + " " + label)}, fields...) {
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		h.Write(field)
+	}
+	return h.Sum(nil)
+}
+
+func newSession(sendKey, recvKey []byte) (*Session, error) {
+	send, err := newGCM(sendKey)
+	if err != nil {
+		return nil, err
+	}
+	recv, err := newGCM(recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{send: send, recv: recv}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts v as JSON for the other side, as base64
+func (s *Session) Seal(v any) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode message: %w", err)
+	}
+	sealed := s.send.Seal(nil, counterNonce(s.sendSeq), plaintext, nil)
+	s.sendSeq++
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed message from the other side into v
+func (s *Session) Open(sealed string, v any) error {
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return ErrSessionAuth
+	}
+	plaintext, err := s.recv.Open(nil, counterNonce(s.recvSeq), ciphertext, nil)
+	if err != nil {
+		return ErrSessionAuth
+	}
+	s.recvSeq++
+	if err := json.Unmarshal(plaintext, v); err != nil {
+		return fmt.Errorf("invalid sealed message: %w", err)
+	}
+	return nil
+}
+
+// counterNonce is the 12-byte GCM nonce of message seq: 4 zero bytes and
+// the big-endian counter
+func counterNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
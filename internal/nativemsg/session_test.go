@@ -0,0 +1,160 @@
+package nativemsg
+
+import (
+	"crypto/ecdh"
+	"errors"
+	"testing"
+)
+
+// connect runs the extension side of a hello against AcceptSession
+func connect(t *testing.T, extension, host *ecdh.PrivateKey) (extensionSession, hostSession *Session) {
+	t.Helper()
+	ephemeral, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSession, hostEphemeral, err := AcceptSession(host, extension.PublicKey(), ephemeral.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	static, err := extension.ECDH(host.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := ephemeral.ECDH(hostEphemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toHost, toExtension, err := sessionKeys(static, fresh, extension.PublicKey(), host.PublicKey(), ephemeral.PublicKey(), hostEphemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extensionSession, err = newSession(toHost, toExtension); err != nil {
+		t.Fatal(err)
+	}
+	return extensionSession, hostSession
+}
+
+func generateKeys(t *testing.T, n int) []*ecdh.PrivateKey {
+	t.Helper()
+	keys := make([]*ecdh.PrivateKey, n)
+	for i := range keys {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+type message struct {
+	Action string `json:"action"`
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	keys := generateKeys(t, 2)
+	extension, host := connect(t, keys[0], keys[1])
+
+	for _, action := range []string{"query", "get", "save"} {
+		sealed, err := extension.Seal(message{action})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got message
+		if err := host.Open(sealed, &got); err != nil || got.Action != action {
+			t.Fatalf("host opened %+v, %v", got, err)
+		}
+
+		reply, err := host.Seal(message{"ok " + action})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := extension.Open(reply, &got); err != nil || got.Action != "ok "+action {
+			t.Fatalf("extension opened %+v, %v", got, err)
+		}
+	}
+}
+
+func TestSessionRejectsReplayAndReflection(t *testing.T) {
+	keys := generateKeys(t, 2)
+	extension, host := connect(t, keys[0], keys[1])
+
+	first, err := extension.Seal(message{"get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got message
+	if err := host.Open(first, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := host.Open(first, &got); !errors.Is(err, ErrSessionAuth) {
+		t.Errorf("replayed message: err = %v, want ErrSessionAuth", err)
+	}
+
+	// A message sealed by the extension can't pass as the host's reply
+	second, err := extension.Seal(message{"get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := extension.Open(second, &got); !errors.Is(err, ErrSessionAuth) {
+		t.Errorf("reflected message: err = %v, want ErrSessionAuth", err)
+	}
+	if err := host.Open("not base64!", &got); !errors.Is(err, ErrSessionAuth) {
+		t.Errorf("garbage: err = %v, want ErrSessionAuth", err)
+	}
+}
+
+func TestSessionNeedsThePairedKeys(t *testing.T) {
+	keys := generateKeys(t, 3)
+	extension, impostor, host := keys[0], keys[1], keys[2]
+
+	// The impostor claims the paired extension's public key in its hello
+	// but only holds its own private key
+	ephemeral, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSession, hostEphemeral, err := AcceptSession(host, extension.PublicKey(), ephemeral.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	static, _ := impostor.ECDH(host.PublicKey())
+	fresh, _ := ephemeral.ECDH(hostEphemeral)
+	toHost, toExtension, err := sessionKeys(static, fresh, extension.PublicKey(), host.PublicKey(), ephemeral.PublicKey(), hostEphemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := newSession(toHost, toExtension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := forged.Seal(message{"get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got message
+	if err := hostSession.Open(sealed, &got); !errors.Is(err, ErrSessionAuth) {
+		t.Errorf("impostor's message: err = %v, want ErrSessionAuth", err)
+	}
+}
+
+func TestPairingCode(t *testing.T) {
+	keys := generateKeys(t, 3)
+	code := PairingCode(keys[0].PublicKey(), keys[2].PublicKey())
+	if normalized, err := NormalizePairingCode(code); err != nil || normalized != code {
+		t.Errorf("NormalizePairingCode(%q) = %q, %v", code, normalized, err)
+	}
+	if PairingCode(keys[1].PublicKey(), keys[2].PublicKey()) == code {
+		t.Error("two extension keys got the same code")
+	}
+	if normalized, err := NormalizePairingCode("482 019736"); err != nil || normalized != "482-019-736" {
+		t.Errorf("NormalizePairingCode = %q, %v", normalized, err)
+	}
+	for _, bad := range []string{"", "12-34", "482-019-73a", "4820197361"} {
+		if _, err := NormalizePairingCode(bad); err == nil {
+			t.Errorf("NormalizePairingCode(%q) succeeded", bad)
+		}
+	}
+}
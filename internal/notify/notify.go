@@ -0,0 +1,36 @@
+// Package notify shows desktop notifications
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// ErrUnsupported is returned where gpasswd can't show notifications
+var ErrUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// Send shows a notification with a title and a one-line body
+// It uses notify-send on Linux and the BSDs and osascript on macOS
+func Send(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		return ErrUnsupported
+	default:
+		cmd = exec.Command("notify-send", "--app-name=gpasswd", title, body)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if len(out) > 0 {
+			return fmt.Errorf("failed to show notification: %w: %s", err, out)
+		}
+		return fmt.Errorf("failed to show notification: %w", err)
+	}
+	return nil
+}
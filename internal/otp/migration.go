@@ -0,0 +1,144 @@
+package otp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Google Authenticator's export format is a protobuf MigrationPayload,
+// base64-encoded into an otpauth-migration://offline?data=... URI:
+//
+//	MigrationPayload { repeated OtpParameters otp_parameters = 1;
+//	                   int32 version = 2; int32 batch_size = 3;
+//	                   int32 batch_index = 4; int32 batch_id = 5; }
+//	OtpParameters    { bytes secret = 1; string name = 2; string issuer = 3;
+//	                   Algorithm algorithm = 4; DigitCount digits = 5;
+//	                   OtpType type = 6; int64 counter = 7; }
+//
+// The messages are small and fixed, so they are encoded by hand rather than
+// pulling in a protobuf dependency
+
+const (
+	migrationAlgorithmSHA1   = 1
+	migrationAlgorithmSHA256 = 2
+	migrationAlgorithmSHA512 = 3
+
+	migrationDigitsSix   = 1
+	migrationDigitsEight = 2
+
+	migrationTypeHOTP = 1
+	migrationTypeTOTP = 2
+)
+
+// MigrationBatchSize is the number of keys per payload; like Google
+// Authenticator's own export, larger sets are split so each QR code stays
+// small enough for a phone camera
+const MigrationBatchSize = 10
+
+// MigrationURIs encodes keys as otpauth-migration:// URIs, one per QR code
+// Keys are split into batches of MigrationBatchSize that share a batch ID
+// and carry their index, so the app imports them as one transfer. Google
+// Authenticator only supports 30 second periods and 6 or 8 digits, so
+// other keys are rejected
+func MigrationURIs(keys []*Key) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no OTP keys to export")
+	}
+
+	params := make([][]byte, len(keys))
+	for i, key := range keys {
+		var err error
+		if params[i], err = migrationParameters(key); err != nil {
+			return nil, err
+		}
+	}
+
+	var batchID [4]byte
+	if _, err := rand.Read(batchID[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+
+	batches := (len(params) + MigrationBatchSize - 1) / MigrationBatchSize
+	uris := make([]string, 0, batches)
+	for index := range batches {
+		var payload []byte
+		for _, p := range params[index*MigrationBatchSize : min((index+1)*MigrationBatchSize, len(params))] {
+			payload = appendBytesField(payload, 1, p)
+		}
+
+		payload = appendVarintField(payload, 2, 1) // version
+		payload = appendVarintField(payload, 3, uint64(batches))
+		payload = appendVarintField(payload, 4, uint64(index))
+		payload = appendVarintField(payload, 5, uint64(binary.BigEndian.Uint32(batchID[:])&0x7fffffff))
+
+		uris = append(uris, "otpauth-migration://offline?data="+url.QueryEscape(base64.StdEncoding.EncodeToString(payload)))
+	}
+	return uris, nil
+}
+
+func migrationParameters(key *Key) ([]byte, error) {
+	var algorithm uint64
+	switch key.Algorithm {
+	case "", AlgorithmSHA1:
+		algorithm = migrationAlgorithmSHA1
+	case AlgorithmSHA256:
+		algorithm = migrationAlgorithmSHA256
+	case AlgorithmSHA512:
+		algorithm = migrationAlgorithmSHA512
+	default:
+		return nil, fmt.Errorf("%s: unsupported OTP algorithm %q", key.label(), key.Algorithm)
+	}
+
+	var digits uint64
+	switch key.Digits {
+	case 0, 6:
+		digits = migrationDigitsSix
+	case 8:
+		digits = migrationDigitsEight
+	default:
+		return nil, fmt.Errorf("%s: Google Authenticator does not support %d-digit codes", key.label(), key.Digits)
+	}
+
+	otpType := uint64(migrationTypeTOTP)
+	if key.Type == TypeHOTP {
+		otpType = migrationTypeHOTP
+	} else if key.period() != 30 {
+		return nil, fmt.Errorf("%s: Google Authenticator only supports 30 second periods", key.label())
+	}
+
+	var params []byte
+	params = appendBytesField(params, 1, key.Secret)
+	params = appendBytesField(params, 2, []byte(key.Account))
+	params = appendBytesField(params, 3, []byte(key.Issuer))
+	params = appendVarintField(params, 4, algorithm)
+	params = appendVarintField(params, 5, digits)
+	params = appendVarintField(params, 6, otpType)
+	if key.Type == TypeHOTP {
+		params = appendVarintField(params, 7, key.Counter)
+	}
+	return params, nil
+}
+
+func (k *Key) label() string {
+	if k.Issuer != "" {
+		return k.Issuer + ":" + k.Account
+	}
+	return k.Account
+}
+
+// appendVarintField appends a protobuf varint field (wire type 0)
+func appendVarintField(buf []byte, field int, value uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3)
+	return binary.AppendUvarint(buf, value)
+}
+
+// appendBytesField appends a protobuf length-delimited field (wire type 2)
+func appendBytesField(buf []byte, field int, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
@@ -0,0 +1,192 @@
+package otp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// protoField is a decoded protobuf field: a varint or a length-delimited value
+type protoField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+// decodeProto decodes the varint and length-delimited fields of a message
+func decodeProto(t *testing.T, data []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("malformed field tag")
+		}
+		data = data[n:]
+
+		field := protoField{num: int(tag >> 3)}
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("malformed field %d", field.num)
+		}
+		data = data[n:]
+
+		switch tag & 7 {
+		case 0:
+			field.varint = value
+		case 2:
+			if uint64(len(data)) < value {
+				t.Fatalf("field %d overruns the message", field.num)
+			}
+			field.bytes, data = data[:value], data[value:]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// decodeMigrationURI returns the payload fields and the encoded OTP
+// parameters of an otpauth-migration:// URI
+func decodeMigrationURI(t *testing.T, uri string) (map[int]uint64, [][]protoField) {
+	t.Helper()
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth-migration" || u.Host != "offline" {
+		t.Fatalf("not a migration URI: %s", uri)
+	}
+	data, err := base64.StdEncoding.DecodeString(u.Query().Get("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := map[int]uint64{}
+	var params [][]protoField
+	for _, field := range decodeProto(t, data) {
+		if field.num == 1 {
+			params = append(params, decodeProto(t, field.bytes))
+		} else {
+			header[field.num] = field.varint
+		}
+	}
+	return header, params
+}
+
+// migrationKey rebuilds a key from decoded OTP parameters
+func migrationKey(t *testing.T, fields []protoField) *Key {
+	t.Helper()
+	key := &Key{Type: TypeTOTP, Algorithm: AlgorithmSHA1, Digits: 6, Period: 30}
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			key.Secret = field.bytes
+		case 2:
+			key.Account = string(field.bytes)
+		case 3:
+			key.Issuer = string(field.bytes)
+		case 4:
+			key.Algorithm = map[uint64]Algorithm{1: AlgorithmSHA1, 2: AlgorithmSHA256, 3: AlgorithmSHA512}[field.varint]
+		case 5:
+			key.Digits = map[uint64]int{1: 6, 2: 8}[field.varint]
+		case 6:
+			if field.varint == migrationTypeHOTP {
+				key.Type = TypeHOTP
+			}
+		case 7:
+			key.Counter = field.varint
+		}
+	}
+	return key
+}
+
+func TestMigrationURIsRoundTrip(t *testing.T) {
+	keys := []*Key{
+		{Type: TypeTOTP, Secret: []byte("12345678901234567890"), Issuer: "GitHub", Account: "octocat", Algorithm: AlgorithmSHA1, Digits: 6, Period: 30},
+		{Type: TypeTOTP, Secret: []byte("abcdefghij"), Account: "alice@example.com", Algorithm: AlgorithmSHA512, Digits: 8},
+		{Type: TypeHOTP, Secret: []byte{0, 1, 2, 0xff}, Issuer: "Bank", Account: "me", Algorithm: AlgorithmSHA256, Digits: 6, Counter: 300},
+	}
+
+	uris, err := MigrationURIs(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uris) != 1 {
+		t.Fatalf("%d keys took %d payloads, want 1", len(keys), len(uris))
+	}
+
+	header, params := decodeMigrationURI(t, uris[0])
+	if header[2] != 1 || header[3] != 1 || header[4] != 0 {
+		t.Errorf("version, batch_size, batch_index = %d, %d, %d, want 1, 1, 0", header[2], header[3], header[4])
+	}
+	if len(params) != len(keys) {
+		t.Fatalf("payload has %d keys, want %d", len(params), len(keys))
+	}
+	for i, fields := range params {
+		got, want := migrationKey(t, fields), keys[i]
+		if got.Type != want.Type || string(got.Secret) != string(want.Secret) || got.Issuer != want.Issuer ||
+			got.Account != want.Account || got.Algorithm != want.Algorithm || got.Digits != want.Digits ||
+			got.Counter != want.Counter {
+			t.Errorf("key %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestMigrationURIsBatches(t *testing.T) {
+	var keys []*Key
+	for i := range 2*MigrationBatchSize + 3 {
+		key := NewKey([]byte(fmt.Sprintf("secret-%02d", i)))
+		key.Account = fmt.Sprintf("account-%02d", i)
+		keys = append(keys, key)
+	}
+
+	uris, err := MigrationURIs(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uris) != 3 {
+		t.Fatalf("%d keys took %d payloads, want 3", len(keys), len(uris))
+	}
+
+	var batchID uint64
+	var accounts []string
+	for index, uri := range uris {
+		header, params := decodeMigrationURI(t, uri)
+		if header[3] != 3 || header[4] != uint64(index) {
+			t.Errorf("payload %d: batch_size %d, batch_index %d", index, header[3], header[4])
+		}
+		if index == 0 {
+			batchID = header[5]
+		} else if header[5] != batchID {
+			t.Errorf("payload %d has batch_id %d, want %d", index, header[5], batchID)
+		}
+		if want := min(MigrationBatchSize, len(keys)-index*MigrationBatchSize); len(params) != want {
+			t.Errorf("payload %d has %d keys, want %d", index, len(params), want)
+		}
+		for _, fields := range params {
+			accounts = append(accounts, migrationKey(t, fields).Account)
+		}
+	}
+	for i, account := range accounts {
+		if account != keys[i].Account {
+			t.Fatalf("keys out of order: %s", strings.Join(accounts, ", "))
+		}
+	}
+}
+
+func TestMigrationURIsRejectsUnsupportedKeys(t *testing.T) {
+	for _, key := range []*Key{
+		{Type: TypeTOTP, Secret: []byte("x"), Account: "a", Period: 60},
+		{Type: TypeTOTP, Secret: []byte("x"), Account: "a", Digits: 7},
+		{Type: TypeTOTP, Secret: []byte("x"), Account: "a", Algorithm: "MD5"},
+	} {
+		if _, err := MigrationURIs([]*Key{key}); err == nil {
+			t.Errorf("key %+v was accepted", key)
+		}
+	}
+	if _, err := MigrationURIs(nil); err == nil {
+		t.Error("an empty export was accepted")
+	}
+}
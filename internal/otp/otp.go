@@ -0,0 +1,252 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the kind of one-time password
+type Type string
+
+const (
+	TypeTOTP Type = "totp"
+	TypeHOTP Type = "hotp"
+)
+
+// Algorithm is the HMAC hash used to compute codes
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+// Key holds the parameters of an OTP secret
+type Key struct {
+	Type      Type
+	Secret    []byte
+	Issuer    string
+	Account   string
+	Algorithm Algorithm
+	Digits    int
+	Period    int    // seconds, TOTP only
+	Counter   uint64 // HOTP only
+}
+
+// NewKey returns a TOTP key with the usual defaults (SHA1, 6 digits, 30s)
+func NewKey(secret []byte) *Key {
+	return &Key{
+		Type:      TypeTOTP,
+		Secret:    secret,
+		Algorithm: AlgorithmSHA1,
+		Digits:    6,
+		Period:    30,
+	}
+}
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// DecodeSecret decodes a base32 secret, ignoring case, spaces and padding
+func DecodeSecret(secret string) ([]byte, error) {
+	cleaned := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret))
+	if cleaned == "" {
+		return nil, errors.New("OTP secret cannot be empty")
+	}
+
+	decoded, err := secretEncoding.DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("OTP secret is not valid base32: %w", err)
+	}
+	return decoded, nil
+}
+
+// EncodeSecret returns the unpadded base32 form of a secret
+func EncodeSecret(secret []byte) string {
+	return secretEncoding.EncodeToString(secret)
+}
+
+// Parse accepts either an otpauth:// URI or a bare base32 secret
+func Parse(value string) (*Key, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		return ParseURI(value)
+	}
+
+	secret, err := DecodeSecret(value)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(secret), nil
+}
+
+// ParseURI parses an otpauth:// URI as used by authenticator QR codes
+func ParseURI(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("invalid otpauth URI: unexpected scheme %q", u.Scheme)
+	}
+
+	query := u.Query()
+	secret, err := DecodeSecret(query.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+
+	key := NewKey(secret)
+	key.Type = Type(strings.ToLower(u.Host))
+	if key.Type != TypeTOTP && key.Type != TypeHOTP {
+		return nil, fmt.Errorf("invalid otpauth URI: unsupported type %q", u.Host)
+	}
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		key.Issuer = strings.TrimSpace(issuer)
+		key.Account = strings.TrimSpace(account)
+	} else {
+		key.Account = label
+	}
+	if issuer := query.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		key.Algorithm = Algorithm(strings.ToUpper(algorithm))
+		if _, err := key.hashFunc(); err != nil {
+			return nil, err
+		}
+	}
+
+	if digits := query.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil || key.Digits < 6 || key.Digits > 10 {
+			return nil, fmt.Errorf("invalid otpauth URI: unsupported digits %q", digits)
+		}
+	}
+
+	if period := query.Get("period"); period != "" {
+		if key.Period, err = strconv.Atoi(period); err != nil || key.Period <= 0 {
+			return nil, fmt.Errorf("invalid otpauth URI: invalid period %q", period)
+		}
+	}
+
+	if counter := query.Get("counter"); counter != "" {
+		if key.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid otpauth URI: invalid counter %q", counter)
+		}
+	}
+
+	return key, nil
+}
+
+// URI returns the otpauth:// form of the key
+func (k *Key) URI() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(k.Secret))
+	if k.Issuer != "" {
+		query.Set("issuer", k.Issuer)
+	}
+	if k.Algorithm != "" && k.Algorithm != AlgorithmSHA1 {
+		query.Set("algorithm", string(k.Algorithm))
+	}
+	if k.Digits != 0 && k.Digits != 6 {
+		query.Set("digits", strconv.Itoa(k.Digits))
+	}
+	if k.Type == TypeHOTP {
+		query.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else if k.Period != 0 && k.Period != 30 {
+		query.Set("period", strconv.Itoa(k.Period))
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     string(k.Type),
+		Path:     "/" + label,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// Code returns the TOTP code for the given time, or the HOTP code for the
+// key's current counter
+func (k *Key) Code(at time.Time) (string, error) {
+	counter := k.Counter
+	if k.Type != TypeHOTP {
+		counter = uint64(at.Unix()) / uint64(k.period())
+	}
+	return k.codeAt(counter)
+}
+
+// Remaining returns how long the current TOTP code stays valid
+func (k *Key) Remaining(at time.Time) time.Duration {
+	period := int64(k.period())
+	return time.Duration(period-at.Unix()%period) * time.Second
+}
+
+func (k *Key) codeAt(counter uint64) (string, error) {
+	newHash, err := k.hashFunc()
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(newHash, k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	digits := k.Digits
+	if digits == 0 {
+		digits = 6
+	}
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, uint64(value)%mod), nil
+}
+
+func (k *Key) period() int {
+	if k.Period <= 0 {
+		return 30
+	}
+	return k.Period
+}
+
+func (k *Key) hashFunc() (func() hash.Hash, error) {
+	switch k.Algorithm {
+	case "", AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported OTP algorithm %q", k.Algorithm)
+	}
+}
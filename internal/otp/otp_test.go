@@ -0,0 +1,84 @@
+package otp
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors, with the seed of each algorithm
+func TestTOTPRFC6238(t *testing.T) {
+	seeds := map[Algorithm][]byte{
+		AlgorithmSHA1:   []byte("12345678901234567890"),
+		AlgorithmSHA256: []byte("12345678901234567890123456789012"),
+		AlgorithmSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix int64
+		want map[Algorithm]string
+	}{
+		{59, map[Algorithm]string{AlgorithmSHA1: "94287082", AlgorithmSHA256: "46119246", AlgorithmSHA512: "90693936"}},
+		{1111111109, map[Algorithm]string{AlgorithmSHA1: "07081804", AlgorithmSHA256: "68084774", AlgorithmSHA512: "25091201"}},
+		{1111111111, map[Algorithm]string{AlgorithmSHA1: "14050471", AlgorithmSHA256: "67062674", AlgorithmSHA512: "99943326"}},
+		{1234567890, map[Algorithm]string{AlgorithmSHA1: "89005924", AlgorithmSHA256: "91819424", AlgorithmSHA512: "93441116"}},
+		{2000000000, map[Algorithm]string{AlgorithmSHA1: "69279037", AlgorithmSHA256: "90698825", AlgorithmSHA512: "38618901"}},
+		{20000000000, map[Algorithm]string{AlgorithmSHA1: "65353130", AlgorithmSHA256: "77737706", AlgorithmSHA512: "47863826"}},
+	}
+
+	for _, tt := range tests {
+		for algorithm, want := range tt.want {
+			key := &Key{Type: TypeTOTP, Secret: seeds[algorithm], Algorithm: algorithm, Digits: 8, Period: 30}
+			got, err := key.Code(time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s at %d = %s, want %s", algorithm, tt.unix, got, want)
+			}
+		}
+	}
+}
+
+// RFC 4226 appendix D test vectors
+func TestHOTPRFC4226(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		key := &Key{Type: TypeHOTP, Secret: []byte("12345678901234567890"), Counter: uint64(counter)}
+		got, err := key.Code(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != code {
+			t.Errorf("counter %d = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestParseURIRoundTrip(t *testing.T) {
+	key := &Key{
+		Type:      TypeTOTP,
+		Secret:    []byte("12345678901234567890"),
+		Issuer:    "Example Co",
+		Account:   "alice@example.com",
+		Algorithm: AlgorithmSHA256,
+		Digits:    8,
+		Period:    60,
+	}
+	parsed, err := ParseURI(key.URI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(parsed.Secret) != string(key.Secret) || parsed.Issuer != key.Issuer || parsed.Account != key.Account ||
+		parsed.Algorithm != key.Algorithm || parsed.Digits != key.Digits || parsed.Period != key.Period {
+		t.Errorf("ParseURI(URI()) = %+v, want %+v", parsed, key)
+	}
+
+	for _, uri := range []string{
+		"https://example.com",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=not*base32",
+	} {
+		if _, err := ParseURI(uri); err == nil {
+			t.Errorf("ParseURI(%q) succeeded", uri)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// ErrRevealDeclined is returned when the user does not confirm a reveal
+var ErrRevealDeclined = errors.New("reveal not confirmed")
+
+// RevealGuard requires confirmation before secrets of sensitive entries are
+// printed or copied
+type RevealGuard struct {
+	// Tags marks entries that need confirmation (case-insensitive)
+	Tags []string
+
+	// Confirm asks the user a yes/no question
+	Confirm func(message string) (bool, error)
+}
+
+// revealGuard is the process-wide guard; nil means no confirmation
+var revealGuard *RevealGuard
+
+// SetRevealGuard installs the guard consulted by ConfirmReveal and by
+// WriteJSON when secrets are included. nil disables it
+func SetRevealGuard(guard *RevealGuard) {
+	revealGuard = guard
+}
+
+// ConfirmReveal must be called before any secret of entry is printed or
+// copied; action describes what is about to happen, e.g. "copy the password"
+// Returns ErrRevealDeclined unless the entry is unguarded or confirmed
+func ConfirmReveal(entry *models.Entry, action string) error {
+	if revealGuard == nil || !revealGuard.guards(entry) {
+		return nil
+	}
+
+	ok, err := revealGuard.Confirm(fmt.Sprintf("'%s' is marked sensitive. %s?", entry.Name, capitalize(action)))
+	if err != nil {
+		return fmt.Errorf("confirmation prompt failed: %w", err)
+	}
+	if !ok {
+		return ErrRevealDeclined
+	}
+	return nil
+}
+
+// guards reports whether the entry carries one of the guarded tags
+func (g *RevealGuard) guards(entry *models.Entry) bool {
+	for _, tag := range entry.Tags {
+		for _, guarded := range g.Tags {
+			if strings.EqualFold(tag, guarded) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// confirmEntries runs ConfirmReveal for entries about to be written as JSON
+// with their secrets
+func confirmEntries(v any) error {
+	switch value := v.(type) {
+	case *models.Entry:
+		return ConfirmReveal(value, "print its secrets as JSON")
+	case []*models.Entry:
+		for _, entry := range value {
+			if err := ConfirmReveal(entry, "print its secrets as JSON"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
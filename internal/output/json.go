@@ -0,0 +1,100 @@
+// Package output writes machine-readable command output
+// JSON written through this package has secret fields redacted unless the
+// caller explicitly asks for them, so new commands can't leak secrets into
+// logs or pipelines by accident
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Redacted replaces the value of a secret field
+const Redacted = "[redacted]"
+
+// SecretFields are the JSON keys whose values are redacted, at any depth
+var SecretFields = map[string]bool{
+	"password": true,
+	"otp":      true,
+	"secret":   true,
+	"token":    true,
+}
+
+// Options controls how JSON is written
+type Options struct {
+	// IncludeSecrets writes secret fields as-is (--include-secrets)
+	IncludeSecrets bool
+}
+
+// WriteJSON writes v as indented JSON followed by a newline
+// Unless opts.IncludeSecrets is set, every non-empty value under a key in
+// SecretFields is replaced with Redacted, however deeply it is nested.
+// Entries written with their secrets go through the reveal guard first
+func WriteJSON(w io.Writer, v any, opts Options) error {
+	if opts.IncludeSecrets {
+		if err := confirmEntries(v); err != nil {
+			return err
+		}
+	} else {
+		redacted, err := redact(v)
+		if err != nil {
+			return err
+		}
+		v = redacted
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// redact round-trips v through JSON so redaction sees exactly the keys that
+// would be written, including those from custom MarshalJSON methods
+func redact(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return redactValue(generic), nil
+}
+
+func redactValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if SecretFields[key] && !isEmpty(field) {
+				value[key] = Redacted
+				continue
+			}
+			value[key] = redactValue(field)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// isEmpty reports whether a decoded JSON value carries nothing to hide
+// Empty secrets are kept so consumers can still tell "not set" apart
+func isEmpty(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	default:
+		return false
+	}
+}
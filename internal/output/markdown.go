@@ -0,0 +1,30 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// RenderMarkdown renders Markdown notes for a terminal of the given width
+// Headings, lists, code blocks and emphasis are styled; if rendering fails
+// the notes are returned unchanged so nothing is ever hidden
+func RenderMarkdown(notes string, width int) string {
+	if width <= 0 {
+		width = 80
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return notes
+	}
+
+	rendered, err := renderer.Render(notes)
+	if err != nil {
+		return notes
+	}
+	return strings.Trim(rendered, "\n")
+}
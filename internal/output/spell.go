@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// natoAlphabet spells letters a-z
+var natoAlphabet = []string{
+	"alfa", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliett", "kilo", "lima", "mike", "november", "oscar", "papa",
+	"quebec", "romeo", "sierra", "tango", "uniform", "victor", "whiskey",
+	"x-ray", "yankee", "zulu",
+}
+
+// digitNames spells digits 0-9
+var digitNames = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+}
+
+// symbolNames are the spoken names of ASCII symbols
+var symbolNames = map[rune]string{
+	' ': "space", '!': "exclamation mark", '"': "double quote", '#': "hash",
+	'$': "dollar", '%': "percent", '&': "ampersand", '\'': "apostrophe",
+	'(': "open parenthesis", ')': "close parenthesis", '*': "asterisk",
+	'+': "plus", ',': "comma", '-': "hyphen", '.': "period", '/': "slash",
+	':': "colon", ';': "semicolon", '<': "less than", '=': "equals",
+	'>': "greater than", '?': "question mark", '@': "at sign",
+	'[': "open bracket", '\\': "backslash", ']': "close bracket",
+	'^': "caret", '_': "underscore", '`': "backtick", '{': "open brace",
+	'|': "vertical bar", '}': "close brace", '~': "tilde",
+}
+
+// SpelledChar is one character of a spelled-out password
+type SpelledChar struct {
+	Char   string // The character as typed ("␠" for a space, "?" if invisible)
+	Spoken string // NATO word, digit or symbol name
+	Class  string // uppercase, lowercase, digit, symbol, letter (non-ASCII) or other
+}
+
+// Spell spells a password character by character for reading it aloud or
+// typing it on a device without paste. Uppercase letters are spoken in
+// capitals ("KILO") so the case is heard as well as read
+func Spell(password string) []SpelledChar {
+	spelled := make([]SpelledChar, 0, len(password))
+	for _, r := range password {
+		c := SpelledChar{Char: string(r)}
+		switch {
+		case r >= 'a' && r <= 'z':
+			c.Spoken, c.Class = natoAlphabet[r-'a'], "lowercase"
+		case r >= 'A' && r <= 'Z':
+			c.Spoken, c.Class = strings.ToUpper(natoAlphabet[r-'A']), "uppercase"
+		case r >= '0' && r <= '9':
+			c.Spoken, c.Class = digitNames[r-'0'], "digit"
+		case symbolNames[r] != "":
+			c.Spoken, c.Class = symbolNames[r], "symbol"
+		default:
+			c.Spoken, c.Class = fmt.Sprintf("U+%04X", r), "other"
+			if unicode.IsLetter(r) {
+				c.Class = "letter"
+			}
+		}
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			c.Char = "␠"
+			if r != ' ' {
+				c.Char = "?"
+			}
+		}
+		spelled = append(spelled, c)
+	}
+	return spelled
+}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultDateLayout is used when no date format is configured
+const DefaultDateLayout = "2006-01-02 15:04"
+
+// TimeFormat renders timestamps the same way in every command
+type TimeFormat struct {
+	Layout   string         // Go layout for absolute times
+	Location *time.Location // Time zone absolute times are shown in
+	Relative bool           // Prefer "3 days ago" over absolute times
+	Now      func() time.Time
+}
+
+// NewTimeFormat builds a TimeFormat from display settings
+// format is a Go layout or uses YYYY MM DD HH mm ss tokens; timezone is an
+// IANA name such as "Europe/Berlin", "UTC", or empty for the local zone
+func NewTimeFormat(format, timezone string, relative bool) (*TimeFormat, error) {
+	location := time.Local
+	if timezone != "" && !strings.EqualFold(timezone, "local") {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid display timezone %q: %w", timezone, err)
+		}
+		location = loc
+	}
+
+	return &TimeFormat{
+		Layout:   ParseDateLayout(format),
+		Location: location,
+		Relative: relative,
+		Now:      time.Now,
+	}, nil
+}
+
+// ParseDateLayout turns a configured date format into a Go layout
+// Formats written with tokens (YYYY-MM-DD HH:mm) are translated; anything
+// else is taken as a Go layout
+func ParseDateLayout(format string) string {
+	format = strings.TrimSpace(format)
+	if format == "" {
+		return DefaultDateLayout
+	}
+	if !strings.ContainsAny(format, "YDHhms") {
+		return format
+	}
+	return strings.NewReplacer(
+		"YYYY", "2006",
+		"YY", "06",
+		"MM", "01",
+		"DD", "02",
+		"HH", "15",
+		"hh", "03",
+		"mm", "04",
+		"ss", "05",
+	).Replace(format)
+}
+
+// Format renders t relatively or absolutely, per the settings
+func (f *TimeFormat) Format(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if f.Relative {
+		return RelativeTime(t, f.Now())
+	}
+	return f.Absolute(t)
+}
+
+// Absolute renders t with the configured layout and time zone
+func (f *TimeFormat) Absolute(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(f.Location).Format(f.Layout)
+}
+
+// Detailed renders the absolute time, followed by the relative one when
+// relative times are enabled, for views with room for both
+func (f *TimeFormat) Detailed(t time.Time) string {
+	if t.IsZero() || !f.Relative {
+		return f.Absolute(t)
+	}
+	return fmt.Sprintf("%s (%s)", f.Absolute(t), RelativeTime(t, f.Now()))
+}
+
+// In converts t to the configured time zone
+func (f *TimeFormat) In(t time.Time) time.Time {
+	return t.In(f.Location)
+}
+
+// RelativeTime describes t relative to now, e.g. "3 days ago" or "in 2 hours"
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	var amount int
+	var unit string
+	switch {
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		amount, unit = int(d/time.Hour), "hour"
+	case d < 14*24*time.Hour:
+		amount, unit = int(d/(24*time.Hour)), "day"
+	case d < 60*24*time.Hour:
+		amount, unit = int(d/(7*24*time.Hour)), "week"
+	case d < 365*24*time.Hour:
+		amount, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		amount, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", amount, unit)
+	}
+	return fmt.Sprintf("%d %s ago", amount, unit)
+}
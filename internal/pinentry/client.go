@@ -0,0 +1,162 @@
+package pinentry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Client drives a pinentry program
+type Client struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// Start runs a pinentry program and waits for its greeting
+func Start(program string) (*Client, error) {
+	cmd := exec.Command(program) // #nosec G204 -- configured pinentry program
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", program, err)
+	}
+
+	client := &Client{cmd: cmd, in: in, out: bufio.NewReader(out)}
+	if _, err := client.response(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s did not greet: %w", program, err)
+	}
+	return client, nil
+}
+
+// Command sends one command and returns its decoded data
+// An ERR response is returned as *Error
+func (c *Client) Command(line string) (string, error) {
+	if _, err := fmt.Fprintf(c.in, "%s\n", line); err != nil {
+		return "", err
+	}
+	lines, err := c.response()
+	if err != nil {
+		return "", err
+	}
+
+	var data strings.Builder
+	for _, line := range lines {
+		if strings.HasPrefix(line, "D ") {
+			data.WriteString(unescape(line[2:]))
+		}
+	}
+	if last := lines[len(lines)-1]; strings.HasPrefix(last, "ERR") {
+		return "", parseError(last)
+	}
+	return data.String(), nil
+}
+
+// GetPIN shows a passphrase prompt and returns what was entered
+func (c *Client) GetPIN(title, description, prompt, errorText string) (string, error) {
+	settings := []string{
+		"SETTITLE " + escape(title),
+		"SETDESC " + escape(description),
+		"SETPROMPT " + escape(prompt),
+	}
+	if errorText != "" {
+		settings = append(settings, "SETERROR "+escape(errorText))
+	}
+	for _, line := range settings {
+		if _, err := c.Command(line); err != nil {
+			return "", err
+		}
+	}
+	return c.Command("GETPIN")
+}
+
+// Confirm shows a question with ok and cancel buttons and reports whether
+// ok was chosen
+func (c *Client) Confirm(title, description, ok, cancel string) (bool, error) {
+	settings := []string{
+		"SETTITLE " + escape(title),
+		"SETDESC " + escape(description),
+		"SETOK " + escape(ok),
+		"SETCANCEL " + escape(cancel),
+	}
+	for _, line := range settings {
+		if _, err := c.Command(line); err != nil {
+			return false, err
+		}
+	}
+
+	_, err := c.Command("CONFIRM")
+	var pinentryErr *Error
+	if errors.As(err, &pinentryErr) {
+		// Cancelled, or "not confirmed" from pinentries with a third button
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Relay sends one command and returns its raw response lines, ending with
+// OK or ERR, to be passed on unchanged
+func (c *Client) Relay(line string) []string {
+	if _, err := fmt.Fprintf(c.in, "%s\n", line); err != nil {
+		return []string{errLine(codeCancelled, "Operation cancelled (fallback pinentry failed)")}
+	}
+	lines, err := c.response()
+	if err != nil {
+		return []string{errLine(codeCancelled, "Operation cancelled (fallback pinentry failed)")}
+	}
+	return lines
+}
+
+// Close ends the session and waits for the program to exit
+func (c *Client) Close() error {
+	fmt.Fprintln(c.in, "BYE")
+	c.in.Close()
+	return c.cmd.Wait()
+}
+
+// response reads lines up to and including OK or ERR
+func (c *Client) response() ([]string, error) {
+	var lines []string
+	for {
+		line, err := c.out.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("pinentry closed the connection: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+		if line == "OK" || strings.HasPrefix(line, "OK ") || line == "ERR" || strings.HasPrefix(line, "ERR ") {
+			return lines, nil
+		}
+	}
+}
+
+// parseError parses an ERR line
+func parseError(line string) *Error {
+	fields := strings.SplitN(strings.TrimPrefix(line, "ERR"), " ", 3)
+	e := &Error{Message: strings.TrimSpace(line)}
+	if len(fields) >= 2 {
+		if code, err := strconv.Atoi(fields[1]); err == nil {
+			e.Code = code
+		}
+	}
+	if len(fields) == 3 {
+		e.Message = fields[2]
+	}
+	return e
+}
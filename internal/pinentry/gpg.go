@@ -0,0 +1,187 @@
+package pinentry
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+)
+
+// Keygrips returns the keygrips of a secret key and its subkeys, the IDs
+// gpg-agent uses when it asks a pinentry for their passphrase
+func Keygrips(keyID string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--batch", "--with-colons", "--with-keygrip", "--list-secret-keys", "--", keyID) // #nosec G204 -- fixed binary, key ID argument
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("no secret key %s: %s", keyID, strings.TrimSpace(stderr.String()))
+	}
+
+	var grips []string
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if fields[0] == "grp" && len(fields) > 9 && fields[9] != "" {
+			grips = append(grips, fields[9])
+		}
+	}
+	if len(grips) == 0 {
+		return nil, fmt.Errorf("gpg listed no keygrips for %s", keyID)
+	}
+	return grips, nil
+}
+
+// AgentConf returns the path of gpg-agent.conf
+func AgentConf() (string, error) {
+	if home := os.Getenv("GNUPGHOME"); home != "" {
+		return filepath.Join(home, "gpg-agent.conf"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gnupg", "gpg-agent.conf"), nil
+}
+
+// disabledPrefix marks pinentry-program lines Install commented out, so
+// Uninstall can restore them
+const disabledPrefix = "#gpasswd# "
+
+// Install points gpg-agent.conf at program, keeping any previous
+// pinentry-program line commented out. It returns the previous program
+func Install(conf, program string) (string, error) {
+	lines, err := readLines(conf)
+	if err != nil {
+		return "", err
+	}
+
+	var previous string
+	var out []string
+	for _, line := range lines {
+		if value, ok := pinentryProgram(line); ok {
+			if value == program {
+				continue
+			}
+			previous = value
+			line = disabledPrefix + line
+		}
+		out = append(out, line)
+	}
+	out = append(out, "pinentry-program "+program)
+	return previous, writeLines(conf, out)
+}
+
+// Uninstall removes the pinentry-program line Install added and restores
+// the one it replaced. It reports whether gpg-agent.conf changed
+func Uninstall(conf, program string) (bool, error) {
+	lines, err := readLines(conf)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	var out []string
+	for _, line := range lines {
+		if value, ok := pinentryProgram(line); ok && value == program {
+			changed = true
+			continue
+		}
+		if restored := strings.TrimPrefix(line, disabledPrefix); restored != line {
+			line = restored
+			changed = true
+		}
+		out = append(out, line)
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, writeLines(conf, out)
+}
+
+// Reload makes a running gpg-agent reread its configuration
+func Reload() error {
+	out, err := exec.Command("gpgconf", "--reload", "gpg-agent").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gpgconf --reload gpg-agent failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pinentryProgram returns the value of an active pinentry-program line
+func pinentryProgram(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "pinentry-program" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "pinentry-program")), true
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func writeLines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	var data string
+	if len(lines) > 0 {
+		data = strings.Join(lines, "\n") + "\n"
+	}
+	if err := atomicfile.WriteFile(path, []byte(data), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// fallbackCandidates are pinentry programs tried in order when no fallback
+// is configured; graphical ones only under a graphical session
+var fallbackCandidates = []struct {
+	program   string
+	graphical bool
+}{
+	{"pinentry-mac", false},
+	{"pinentry-gnome3", true},
+	{"pinentry-qt", true},
+	{"pinentry-gtk-2", true},
+	{"pinentry-curses", false},
+	{"pinentry-tty", false},
+	{"pinentry", false},
+}
+
+// FindFallback returns the first pinentry program found on PATH that is not
+// self (the running executable, which may be installed as "pinentry"), or ""
+func FindFallback(self string) string {
+	graphical := os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	for _, candidate := range fallbackCandidates {
+		if candidate.graphical && !graphical {
+			continue
+		}
+		path, err := exec.LookPath(candidate.program)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved == self {
+			continue
+		}
+		return path
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+// Package pinentry speaks the Assuan pinentry protocol gpg-agent uses to
+// ask for passphrases (https://www.gnupg.org/documentation/manuals/assuan/).
+// Server answers gpg-agent; prompts it can't answer itself are handed to a
+// real pinentry program through Client
+package pinentry
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Assuan error codes (gpg-error source 5 = pinentry)
+const (
+	codeCancelled  = 83886179 // GPG_ERR_CANCELED
+	codeUnknownCmd = 83886355 // GPG_ERR_ASS_UNKNOWN_CMD
+	codeNotSupp    = 83886140 // GPG_ERR_NOT_SUPPORTED
+)
+
+// ErrCancelled is returned when the user dismissed a prompt
+var ErrCancelled = errors.New("operation cancelled")
+
+// Error is an ERR response of a pinentry
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pinentry error %d: %s", e.Code, e.Message)
+}
+
+// Is makes a cancelled prompt match ErrCancelled
+func (e *Error) Is(target error) bool {
+	return target == ErrCancelled && e.Code&0xffff == codeCancelled&0xffff
+}
+
+// Settings holds what gpg-agent told the pinentry before a prompt
+type Settings struct {
+	KeyInfo     string // SETKEYINFO, e.g. "n/<keygrip>"; empty if cleared
+	Description string // SETDESC
+	Prompt      string // SETPROMPT
+	Title       string // SETTITLE
+	Error       string // SETERROR: the last passphrase was wrong
+	Repeat      bool   // SETREPEAT: a new passphrase is being chosen
+
+	// lines are the OPTION and SET commands as received, replayed to a
+	// fallback pinentry so it shows the same prompt
+	lines []string
+}
+
+// Keygrip returns the keygrip of the key being unlocked, "" if unknown
+func (s *Settings) Keygrip() string {
+	_, grip, found := strings.Cut(s.KeyInfo, "/")
+	if !found {
+		return ""
+	}
+	return strings.ToUpper(grip)
+}
+
+// apply records a setting command; it reports false for other commands
+func (s *Settings) apply(command, arg string) bool {
+	switch command {
+	case "OPTION", "SETOK", "SETCANCEL", "SETNOTOK", "SETQUALITYBAR", "SETQUALITYBAR_TT",
+		"SETGENPIN", "SETGENPIN_TT", "SETREPEATERROR", "SETREPEATOK", "SETTIMEOUT", "CLEARPASSPHRASE":
+	case "SETKEYINFO":
+		if arg == "--clear" {
+			arg = ""
+		}
+		s.KeyInfo = arg
+	case "SETDESC":
+		s.Description = unescape(arg)
+	case "SETPROMPT":
+		s.Prompt = unescape(arg)
+	case "SETTITLE":
+		s.Title = unescape(arg)
+	case "SETERROR":
+		s.Error = unescape(arg)
+	case "SETREPEAT":
+		s.Repeat = true
+	default:
+		return false
+	}
+	s.lines = append(s.lines, strings.TrimSpace(command+" "+arg))
+	return true
+}
+
+// clearPrompt forgets settings that only apply to one prompt
+// gpg-agent sends them again before the next one
+func (s *Settings) clearPrompt() {
+	s.Error = ""
+	s.Repeat = false
+	kept := s.lines[:0]
+	for _, line := range s.lines {
+		if !strings.HasPrefix(line, "SETERROR") && !strings.HasPrefix(line, "SETREPEAT") {
+			kept = append(kept, line)
+		}
+	}
+	s.lines = kept
+}
+
+// escape encodes data for a D line
+func escape(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+// unescape decodes %XX escapes in command arguments and D lines
+func unescape(data string) string {
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return data
+	}
+	return decoded
+}
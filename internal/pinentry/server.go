@@ -0,0 +1,139 @@
+package pinentry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Server answers a pinentry session from gpg-agent
+type Server struct {
+	// GetPIN answers a passphrase prompt. It returns ok=false for prompts
+	// it leaves to the fallback pinentry
+	GetPIN func(s *Settings) (pin string, ok bool, err error)
+
+	// Fallback is the real pinentry program for everything else: prompts
+	// GetPIN passes on, confirmations and messages. Empty means none
+	Fallback string
+
+	// Version is reported to GETINFO version
+	Version string
+}
+
+// Serve runs the session on in and out until BYE or end of input
+func (srv *Server) Serve(in io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	reply := func(lines ...string) error {
+		for _, line := range lines {
+			w.WriteString(line + "\n")
+		}
+		return w.Flush()
+	}
+
+	if err := reply("OK gpasswd pinentry ready"); err != nil {
+		return err
+	}
+
+	settings := &Settings{}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		command, arg, _ := strings.Cut(line, " ")
+		command = strings.ToUpper(command)
+
+		var err error
+		switch {
+		case command == "" || strings.HasPrefix(command, "#"):
+			continue
+		case settings.apply(command, arg):
+			err = reply("OK")
+		case command == "GETINFO":
+			err = reply(srv.info(arg)...)
+		case command == "GETPIN":
+			err = reply(srv.getPIN(settings)...)
+			settings.clearPrompt()
+		case command == "CONFIRM", command == "MESSAGE":
+			err = reply(srv.forward(settings, line)...)
+			settings.clearPrompt()
+		case command == "RESET":
+			settings = &Settings{}
+			err = reply("OK")
+		case command == "NOP":
+			err = reply("OK")
+		case command == "BYE":
+			return reply("OK closing connection")
+		default:
+			err = reply(errLine(codeUnknownCmd, "Unknown IPC command"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// info answers GETINFO
+func (srv *Server) info(what string) []string {
+	switch strings.TrimSpace(what) {
+	case "flavor":
+		return []string{"D gpasswd", "OK"}
+	case "version":
+		return []string{"D " + escape(srv.Version), "OK"}
+	case "pid":
+		return []string{fmt.Sprintf("D %d", os.Getpid()), "OK"}
+	case "ttyinfo":
+		return []string{"D - - - - 0/0 -", "OK"}
+	default:
+		return []string{errLine(codeNotSupp, "Not supported")}
+	}
+}
+
+// getPIN answers GETPIN from the vault or the fallback pinentry
+func (srv *Server) getPIN(settings *Settings) []string {
+	if srv.GetPIN != nil {
+		pin, ok, err := srv.GetPIN(settings)
+		if errors.Is(err, ErrCancelled) {
+			return []string{errLine(codeCancelled, "Operation cancelled")}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pinentry-gpasswd: %v\n", err)
+		} else if ok {
+			return []string{"D " + escape(pin), "OK"}
+		}
+	}
+	return srv.forward(settings, "GETPIN")
+}
+
+// forward runs command in a fallback pinentry set up like this session
+// and relays its response
+func (srv *Server) forward(settings *Settings, command string) []string {
+	if srv.Fallback == "" {
+		if strings.HasPrefix(strings.ToUpper(command), "MESSAGE") {
+			return []string{"OK"}
+		}
+		return []string{errLine(codeCancelled, "Operation cancelled (no fallback pinentry)")}
+	}
+
+	client, err := Start(srv.Fallback)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pinentry-gpasswd: %v\n", err)
+		return []string{errLine(codeCancelled, "Operation cancelled (fallback pinentry failed)")}
+	}
+	defer client.Close()
+
+	for _, line := range settings.lines {
+		if _, err := client.Command(line); err != nil {
+			fmt.Fprintf(os.Stderr, "pinentry-gpasswd: fallback rejected %q: %v\n", line, err)
+		}
+	}
+	return client.Relay(command)
+}
+
+// errLine formats an ERR response
+func errLine(code int, message string) string {
+	return fmt.Sprintf("ERR %d %s <Pinentry>", code, message)
+}
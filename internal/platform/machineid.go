@@ -0,0 +1,38 @@
+package platform
+
+import (
+	"os"
+	"os/user"
+	"strings"
+)
+
+// MachineID returns a stable identifier of this machine, or the hostname
+// where none can be read. It identifies, it doesn't authenticate: any
+// local user can read it
+func MachineID() string {
+	if id := machineID(); id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// UserID returns the current user's ID (a SID on Windows)
+func UserID() string {
+	if u, err := user.Current(); err == nil {
+		return u.Uid
+	}
+	return ""
+}
+
+// readID returns the first non-empty, trimmed file content among paths
+func readID(paths ...string) string {
+	for _, path := range paths {
+		if data, err := os.ReadFile(path); err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
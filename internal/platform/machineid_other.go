@@ -0,0 +1,27 @@
+//go:build !windows
+
+package platform
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+)
+
+// ioPlatformUUID matches the hardware UUID in ioreg output on macOS
+var ioPlatformUUID = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+// machineID reads the systemd/D-Bus machine ID, or the hardware UUID on macOS
+func machineID() string {
+	if runtime.GOOS == "darwin" {
+		out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err != nil {
+			return ""
+		}
+		if m := ioPlatformUUID.FindSubmatch(out); m != nil {
+			return string(m[1])
+		}
+		return ""
+	}
+	return readID("/etc/machine-id", "/var/lib/dbus/machine-id", "/etc/hostid")
+}
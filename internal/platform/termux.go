@@ -0,0 +1,21 @@
+// Package platform detects environments that need special handling
+package platform
+
+import (
+	"os"
+	"strings"
+)
+
+// InTermux reports whether gpasswd runs inside Termux on Android
+func InTermux() bool {
+	return os.Getenv("TERMUX_VERSION") != "" || strings.Contains(os.Getenv("PREFIX"), "/com.termux/")
+}
+
+// TermuxPrefix returns the Termux installation prefix (usually
+// /data/data/com.termux/files/usr), or "" outside Termux
+func TermuxPrefix() string {
+	if !InTermux() {
+		return ""
+	}
+	return os.Getenv("PREFIX")
+}
@@ -0,0 +1,132 @@
+package rotation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition selects entries by password age, e.g. "age > 365d"
+type Condition struct {
+	Op    string
+	Value time.Duration
+}
+
+var (
+	conditionPattern = regexp.MustCompile(`^\s*age\s*(>=|<=|>|<|=)\s*(.+?)\s*$`)
+	agePattern       = regexp.MustCompile(`^\s*(\d+)\s*([hdwmy])\s*$`)
+)
+
+// unitDurations maps condition units to durations (months and years are
+// approximated as 30 and 365 days)
+var unitDurations = map[string]time.Duration{
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"m": 30 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// ParseCondition parses an expression of the form "age <op> <n><unit>"
+// Supported operators: > >= < <= =. Supported units: h, d, w, m, y
+func ParseCondition(expr string) (Condition, error) {
+	match := conditionPattern.FindStringSubmatch(strings.ToLower(expr))
+	if match == nil {
+		return Condition{}, fmt.Errorf("invalid filter %q (expected e.g. 'age > 365d')", expr)
+	}
+
+	value, err := ParseAge(match[2])
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+
+	return Condition{Op: match[1], Value: value}, nil
+}
+
+// ParseAge parses an age such as "365d", "6m" or "1y"
+func ParseAge(value string) (time.Duration, error) {
+	match := agePattern.FindStringSubmatch(strings.ToLower(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. '365d')", value)
+	}
+
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", value, err)
+	}
+
+	return time.Duration(n) * unitDurations[match[2]], nil
+}
+
+// Matches reports whether a password age satisfies the condition
+func (c Condition) Matches(age time.Duration) bool {
+	switch c.Op {
+	case ">":
+		return age > c.Value
+	case ">=":
+		return age >= c.Value
+	case "<":
+		return age < c.Value
+	case "<=":
+		return age <= c.Value
+	default:
+		// Ages are never exact; "=" matches within the same day
+		diff := age - c.Value
+		return diff > -12*time.Hour && diff < 12*time.Hour
+	}
+}
+
+// Status of a planned rotation step
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusDone    Status = "done"
+	StatusSkipped Status = "skipped"
+)
+
+// Item is one entry in a rotation plan
+type Item struct {
+	EntryID   string `json:"entry_id"`
+	EntryName string `json:"entry_name"`
+	Status    Status `json:"status"`
+}
+
+// Plan is a resumable list of entries to rotate
+type Plan struct {
+	Filter    string    `json:"filter"`
+	CreatedAt time.Time `json:"created_at"`
+	Items     []Item    `json:"items"`
+}
+
+// Next returns the index of the next pending item, or -1 when finished
+func (p *Plan) Next() int {
+	for i, item := range p.Items {
+		if item.Status == StatusPending {
+			return i
+		}
+	}
+	return -1
+}
+
+// Count returns the number of items with the given status
+func (p *Plan) Count(status Status) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// FormatAge renders an age in days, e.g. "412 days"
+func FormatAge(age time.Duration) string {
+	days := int(age.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
@@ -0,0 +1,24 @@
+// Package securefs keeps gpasswd's files private to the current user on
+// every platform: permission bits on Unix, owner-only ACLs on Windows
+package securefs
+
+import (
+	"errors"
+	"os"
+)
+
+// MkdirAll creates dir and any missing parents, restricting the directory
+// it creates to the current user. An existing directory is left as it is,
+// since it may be shared (e.g. a database.path under Documents)
+func MkdirAll(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return Restrict(dir)
+}
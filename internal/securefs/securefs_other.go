@@ -0,0 +1,17 @@
+//go:build !windows
+
+package securefs
+
+import "os"
+
+// Restrict limits an existing file (0600) or directory (0700) to its owner
+func Restrict(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return os.Chmod(path, 0700)
+	}
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package securefs
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Restrict replaces the ACL of an existing file or directory with one that
+// only grants the current user (and SYSTEM) access, without inheriting
+// entries from the parent. chmod cannot do this on Windows: it only toggles
+// the read-only attribute. Directories pass the ACL on to new children
+func Restrict(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return fmt.Errorf("failed to look up current user: %w", err)
+	}
+
+	inherit := ""
+	if info.IsDir() {
+		inherit = "OICI"
+	}
+	// P = protected (no inherited entries), FA = full access, SY = SYSTEM
+	sddl := fmt.Sprintf("D:P(A;%s;FA;;;%s)(A;%s;FA;;;SY)", inherit, user.User.Sid, inherit)
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("failed to build security descriptor: %w", err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("failed to read security descriptor: %w", err)
+	}
+
+	err = windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION,
+		nil, nil, dacl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to restrict access to %s: %w", path, err)
+	}
+	return nil
+}
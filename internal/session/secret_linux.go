@@ -0,0 +1,64 @@
+//go:build linux
+
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// secretDescription names the per-boot secret in the user keyring
+const secretDescription = "gpasswd:session-secret"
+
+// secretPerm lets the possessor do anything with the secret and other
+// processes of the same user view, read and search it, so a shell that
+// doesn't possess the user keyring can still resume a session
+const secretPerm = 0x3f000000 | 0x00010000 | 0x00020000 | 0x00080000
+
+// bootSecret returns the secret mixed into the wrapping key. It lives in
+// the kernel user keyring, never on disk, and is gone after a reboot or
+// once the user has no processes left. With create it is generated when
+// missing; without, a missing secret is ErrNoSession
+func bootSecret(create bool) ([]byte, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", secretDescription, 0)
+	if err == nil {
+		secret := make([]byte, 64)
+		n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, secret, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session secret: %w", err)
+		}
+		if n == 32 {
+			return secret[:n], nil
+		}
+	}
+	if !create {
+		return nil, ErrNoSession
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate session secret: %w", err)
+	}
+	id, err = unix.AddKey("user", secretDescription, secret, unix.KEY_SPEC_USER_KEYRING)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if err := unix.KeyctlSetperm(id, secretPerm); err != nil {
+		// Left with the default permissions the secret may be readable
+		// beyond what secretPerm allows; don't use it
+		unix.KeyctlInt(unix.KEYCTL_REVOKE, id, 0, 0, 0)
+		return nil, fmt.Errorf("failed to restrict session secret: %w", err)
+	}
+	return secret, nil
+}
+
+// onTmpfs reports whether dir is on a memory-backed file system
+func onTmpfs(dir string) bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return false
+	}
+	return st.Type == unix.TMPFS_MAGIC
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package session
+
+// bootSecret needs the Linux kernel keyring; other systems use the agent
+func bootSecret(create bool) ([]byte, error) {
+	if !create {
+		return nil, ErrNoSession
+	}
+	return nil, ErrUnsupported
+}
+
+// onTmpfs is not checked on this platform, where sessions are unsupported
+func onTmpfs(dir string) bool {
+	return false
+}
@@ -0,0 +1,186 @@
+// Package session stores the vault key for a limited time after
+// 'gpasswd unlock', so later commands don't ask for the master password
+//
+// The key is wrapped with a key derived from a per-boot secret held in the
+// kernel user keyring, a random salt kept in the token, the user ID, the
+// machine ID, the vault path and the expiry. Tokens are only written to a
+// tmpfs runtime directory, and the secret never touches the disk, so a
+// token copied off the machine, left in a backup or read after a reboot
+// no longer unwraps. Like ssh-agent it does not protect against programs
+// running as the same user on the same machine
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/platform"
+	"github.com/kitsnail/gpasswd/internal/securefs"
+)
+
+// tokenVersion is the format of session files
+const tokenVersion = 1
+
+// ErrNoSession is returned when there is no usable session for a vault:
+// none was started, it expired, or it belongs to another user or machine
+var ErrNoSession = errors.New("no active session")
+
+// ErrUnsupported is returned when this system can't keep session tokens
+// off the disk: there is no tmpfs $XDG_RUNTIME_DIR or no kernel keyring
+var ErrUnsupported = errors.New("unlock sessions need a tmpfs $XDG_RUNTIME_DIR and the Linux kernel keyring; run 'gpasswd agent start' instead")
+
+// tokenPattern matches the session files in a directory
+const tokenPattern = "session-*.json"
+
+// token is the on-disk session file
+type token struct {
+	Version    int       `json:"version"`
+	Vault      string    `json:"vault"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Salt       []byte    `json:"salt"`
+	WrappedKey []byte    `json:"wrapped_key"`
+}
+
+// Dir returns the directory session tokens are kept in: gpasswd under
+// $XDG_RUNTIME_DIR, which must be a tmpfs (cleared at logout)
+func Dir() (string, error) {
+	runtime := os.Getenv("XDG_RUNTIME_DIR")
+	if runtime == "" || !onTmpfs(runtime) {
+		return "", ErrUnsupported
+	}
+	return filepath.Join(runtime, "gpasswd"), nil
+}
+
+// Save starts a session for the vault at vaultPath that lasts ttl
+// The token is written to dir with owner-only permissions
+func Save(dir, vaultPath string, key []byte, ttl time.Duration) (time.Time, error) {
+	secret, err := bootSecret(true)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t := token{
+		Version:   tokenVersion,
+		Vault:     vaultPath,
+		ExpiresAt: time.Now().Add(ttl).UTC().Round(0),
+		Salt:      make([]byte, 32),
+	}
+	if _, err := rand.Read(t.Salt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to generate session salt: %w", err)
+	}
+
+	wrapped, err := crypto.Encrypt(key, t.wrappingKey(secret))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to wrap session key: %w", err)
+	}
+	t.WrappedKey = wrapped
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := securefs.MkdirAll(dir); err != nil {
+		return time.Time{}, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := atomicfile.WriteFile(path(dir, vaultPath), data, 0600); err != nil {
+		return time.Time{}, fmt.Errorf("failed to write session: %w", err)
+	}
+	return t.ExpiresAt, nil
+}
+
+// Load returns the key of the active session for the vault at vaultPath
+// An expired or unreadable token is removed and reported as ErrNoSession,
+// and expired tokens of other vaults in dir are removed along the way
+func Load(dir, vaultPath string) ([]byte, time.Time, error) {
+	prune(dir)
+
+	file := path(dir, vaultPath)
+	t, ok := readToken(file)
+	if !ok || t.Vault != vaultPath {
+		os.Remove(file)
+		return nil, time.Time{}, ErrNoSession
+	}
+
+	secret, err := bootSecret(false)
+	if err != nil {
+		os.Remove(file)
+		return nil, time.Time{}, ErrNoSession
+	}
+	key, err := crypto.Decrypt(t.WrappedKey, t.wrappingKey(secret))
+	if err != nil {
+		os.Remove(file)
+		return nil, time.Time{}, ErrNoSession
+	}
+	return key, t.ExpiresAt, nil
+}
+
+// Remove ends the session for the vault at vaultPath, if any
+func Remove(dir, vaultPath string) error {
+	err := os.Remove(path(dir, vaultPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
+
+// prune removes the tokens in dir that are expired or can't be read
+func prune(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, tokenPattern))
+	for _, file := range files {
+		if _, ok := readToken(file); !ok {
+			os.Remove(file)
+		}
+	}
+}
+
+// readToken reads the token in file, reporting false when it is missing,
+// malformed, of another version or expired
+func readToken(file string) (*token, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var t token
+	if err := json.Unmarshal(data, &t); err != nil || t.Version != tokenVersion {
+		return nil, false
+	}
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, false
+	}
+	return &t, true
+}
+
+// wrappingKey derives the key that wraps the vault key from the per-boot
+// secret and the token's own fields
+func (t *token) wrappingKey(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	for _, field := range []string{
+		"gpasswd-session-v1",
+		string(t.Salt),
+		platform.UserID(),
+		platform.MachineID(),
+		t.Vault,
+		t.ExpiresAt.Format(time.RFC3339Nano),
+	} {
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+	}
+	return mac.Sum(nil)
+}
+
+// path names the session file after the vault, so several vaults can
+// have sessions at once
+func path(dir, vaultPath string) string {
+	sum := sha256.Sum256([]byte(vaultPath))
+	return filepath.Join(dir, "session-"+hex.EncodeToString(sum[:8])+".json")
+}
@@ -0,0 +1,203 @@
+// Package share implements encrypted bundles of a shared category, used to
+// pass a team's entries between vaults without a server
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/age"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// BundleVersion is the current bundle format version
+const BundleVersion = 1
+
+// Bundle is the decrypted content of a shared category export
+type Bundle struct {
+	Version    int             `json:"version"`
+	Category   string          `json:"category"`
+	Recipients []string        `json:"recipients"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Entries    []*models.Entry `json:"entries"`
+}
+
+// InCategory reports whether a category is the shared category or one of
+// its subcategories ("team" covers "team/aws")
+func InCategory(category, shared string) bool {
+	return category == shared || strings.HasPrefix(category, shared+"/")
+}
+
+// ParseRecipients parses age recipients, rejecting duplicates
+func ParseRecipients(values []string) ([]*age.Recipient, error) {
+	recipients := make([]*age.Recipient, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		recipient, err := age.ParseRecipient(value)
+		if err != nil {
+			return nil, err
+		}
+		if seen[recipient.String()] {
+			continue
+		}
+		seen[recipient.String()] = true
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// Seal serializes the bundle and encrypts it to its recipients
+// The result is a standard age file
+func Seal(bundle *Bundle) ([]byte, error) {
+	recipients, err := ParseRecipients(bundle.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("shared category has no recipients")
+	}
+
+	plaintext, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	return age.Encrypt(plaintext, recipients)
+}
+
+// Open decrypts a bundle with any of the given identities
+func Open(data []byte, identities []*age.Identity) (*Bundle, error) {
+	plaintext, err := age.Decrypt(data, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	if bundle.Category == "" {
+		return nil, errors.New("bundle has no category")
+	}
+	for _, entry := range bundle.Entries {
+		if !InCategory(entry.Category, bundle.Category) {
+			return nil, fmt.Errorf("bundle entry '%s' is outside the shared category '%s'", entry.Name, bundle.Category)
+		}
+	}
+	return &bundle, nil
+}
+
+// Action is what an import does with one bundle entry
+type Action int
+
+const (
+	// ActionAdd creates an entry that does not exist locally
+	ActionAdd Action = iota
+	// ActionUpdate overwrites the local entry with the newer bundle entry
+	ActionUpdate
+	// ActionUnchanged means the local entry already matches the bundle
+	ActionUnchanged
+	// ActionKeepLocal means the local entry was changed after the bundle's
+	ActionKeepLocal
+)
+
+// Change pairs a bundle entry with its local counterpart (nil for ActionAdd)
+type Change struct {
+	Action   Action
+	Incoming *models.Entry
+	Local    *models.Entry
+}
+
+// Plan decides how to merge a bundle into the local entries
+// Entries are matched by ID first, since bundles keep the IDs of the vault
+// that created them, then by name within the shared category. The most
+// recently updated side wins
+func Plan(local []*models.Entry, bundle *Bundle) []Change {
+	byID := make(map[string]*models.Entry, len(local))
+	byName := make(map[string]*models.Entry)
+	for _, entry := range local {
+		byID[entry.ID] = entry
+		if InCategory(entry.Category, bundle.Category) {
+			byName[strings.ToLower(entry.Name)] = entry
+		}
+	}
+
+	changes := make([]Change, 0, len(bundle.Entries))
+	for _, incoming := range bundle.Entries {
+		existing, ok := byID[incoming.ID]
+		if !ok {
+			existing, ok = byName[strings.ToLower(incoming.Name)]
+		}
+
+		switch {
+		case !ok:
+			changes = append(changes, Change{Action: ActionAdd, Incoming: incoming})
+		case SameContent(existing, incoming):
+			changes = append(changes, Change{Action: ActionUnchanged, Incoming: incoming, Local: existing})
+		case existing.UpdatedAt.After(incoming.UpdatedAt):
+			changes = append(changes, Change{Action: ActionKeepLocal, Incoming: incoming, Local: existing})
+		default:
+			changes = append(changes, Change{Action: ActionUpdate, Incoming: incoming, Local: existing})
+		}
+	}
+	return changes
+}
+
+// SameContent reports whether two entries hold the same data, ignoring IDs
+// and timestamps
+func SameContent(a, b *models.Entry) bool {
+	return len(ChangedFields(a, b)) == 0
+}
+
+// ChangedFields lists the fields (by JSON name) whose values differ,
+// ignoring IDs and timestamps
+func ChangedFields(a, b *models.Entry) []string {
+	sameExpiry := (a.ExpiresAt == nil) == (b.ExpiresAt == nil) &&
+		(a.ExpiresAt == nil || a.ExpiresAt.Equal(*b.ExpiresAt))
+
+	var changed []string
+	for _, field := range []struct {
+		name string
+		same bool
+	}{
+		{"name", a.Name == b.Name},
+		{"category", a.Category == b.Category},
+		{"username", a.Username == b.Username},
+		{"password", a.Password == b.Password},
+		{"url", a.URL == b.URL},
+		{"notes", a.Notes == b.Notes},
+		{"tags", sameSet(a.Tags, b.Tags)},
+		{"icon", a.Icon == b.Icon},
+		{"change_password_url", a.ChangePasswordURL == b.ChangePasswordURL},
+		{"otp", a.OTP == b.OTP},
+		{"type", a.Type == b.Type},
+		{"service", a.Service == b.Service},
+		{"expires_at", sameExpiry},
+		{"scopes", sameSet(a.Scopes, b.Scopes)},
+		{"key_type", a.KeyType == b.KeyType},
+		{"public_key", a.PublicKey == b.PublicKey},
+		{"security_level", a.SecurityLevel == b.SecurityLevel},
+	} {
+		if !field.same {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// sameSet compares string slices ignoring order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
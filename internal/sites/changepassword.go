@@ -0,0 +1,87 @@
+package sites
+
+import (
+	"net/url"
+	"strings"
+)
+
+// WellKnownChangePassword is the path sites redirect to their password
+// change page, see https://w3c.github.io/webappsec-change-password-url/
+// Sites that don't serve it answer 404, which the browser shows
+const WellKnownChangePassword = "/.well-known/change-password"
+
+// changePasswordPages are the password change pages of well-known sites,
+// by Site.Host, for sites that don't serve the well-known URL
+var changePasswordPages = map[string]string{
+	"github.com":      "https://github.com/settings/security",
+	"gitlab.com":      "https://gitlab.com/-/user_settings/password/edit",
+	"google.com":      "https://myaccount.google.com/signinoptions/password",
+	"mail.google.com": "https://myaccount.google.com/signinoptions/password",
+	"live.com":        "https://account.live.com/password/change",
+	"outlook.com":     "https://account.live.com/password/change",
+	"microsoft.com":   "https://account.live.com/password/change",
+	"facebook.com":    "https://www.facebook.com/settings?tab=security",
+	"x.com":           "https://x.com/settings/password",
+	"twitter.com":     "https://x.com/settings/password",
+	"linkedin.com":    "https://www.linkedin.com/psettings/change-password",
+	"reddit.com":      "https://www.reddit.com/settings/account",
+	"apple.com":       "https://account.apple.com/account/manage",
+	"icloud.com":      "https://account.apple.com/account/manage",
+	"dropbox.com":     "https://www.dropbox.com/account/security",
+	"netflix.com":     "https://www.netflix.com/password",
+	"spotify.com":     "https://www.spotify.com/account/change-password/",
+	"paypal.com":      "https://www.paypal.com/myaccount/security/",
+}
+
+// ChangePasswordPage returns where to change the password of the login at
+// a URL: the bundled page of a well-known site, otherwise the site's
+// well-known change-password URL. known is false for the latter, which the
+// site may not serve; "" if the URL has no host
+func ChangePasswordPage(rawURL string) (page string, known bool) {
+	if page, ok := KnownChangePasswordPage(rawURL); ok {
+		return page, true
+	}
+	return WellKnownURL(rawURL), false
+}
+
+// KnownChangePasswordPage returns the bundled password change page of the
+// site a URL is on
+func KnownChangePasswordPage(rawURL string) (string, bool) {
+	site, found := Lookup(urlHost(rawURL))
+	if !found {
+		return "", false
+	}
+	page, ok := changePasswordPages[site.Host]
+	return page, ok
+}
+
+// WellKnownURL returns the well-known change-password URL of the origin of
+// a URL, "" if it has no host
+func WellKnownURL(rawURL string) string {
+	origin := urlOrigin(rawURL)
+	if origin == "" {
+		return ""
+	}
+	return origin + WellKnownChangePassword
+}
+
+// urlOrigin returns scheme://host[:port] of a web URL, adding a missing
+// https scheme; "" for other URLs
+func urlOrigin(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return ""
+	}
+	return scheme + "://" + strings.ToLower(parsed.Host)
+}
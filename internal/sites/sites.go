@@ -0,0 +1,150 @@
+// Package sites knows a few things about websites: a small table of
+// well-known sites, how to turn a login URL into a suggested entry name,
+// category and icon, where a site's password change page is and whether
+// it offers two-factor authentication
+package sites
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Site is a well-known website
+type Site struct {
+	Host     string // host or parent domain the site is served from
+	Name     string // entry name, lower case
+	Category string
+	Icon     string // emoji
+}
+
+// known are the well-known sites; a host matches the entry with the
+// longest Host it is on, so aws.amazon.com wins over amazon.com
+var known = []Site{
+	{"google.com", "google", "email", "🔎"},
+	{"mail.google.com", "gmail", "email", "📧"},
+	{"cloud.google.com", "google cloud", "cloud", "☁️"},
+	{"console.cloud.google.com", "google cloud console", "cloud", "☁️"},
+	{"outlook.com", "outlook", "email", "📧"},
+	{"live.com", "microsoft", "email", "🪟"},
+	{"microsoft.com", "microsoft", "work", "🪟"},
+	{"azure.com", "azure", "cloud", "☁️"},
+	{"yahoo.com", "yahoo", "email", "📧"},
+	{"proton.me", "proton", "email", "📧"},
+	{"icloud.com", "icloud", "cloud", "🍎"},
+	{"apple.com", "apple", "general", "🍎"},
+	{"amazon.com", "amazon", "shopping", "🛒"},
+	{"aws.amazon.com", "aws", "cloud", "☁️"},
+	{"ebay.com", "ebay", "shopping", "🛒"},
+	{"paypal.com", "paypal", "banking", "💳"},
+	{"stripe.com", "stripe", "banking", "💳"},
+	{"github.com", "github", "dev", "🐙"},
+	{"gitlab.com", "gitlab", "dev", "🦊"},
+	{"bitbucket.org", "bitbucket", "dev", "🪣"},
+	{"atlassian.net", "atlassian", "work", "🧩"},
+	{"npmjs.com", "npm", "dev", "📦"},
+	{"docker.com", "docker", "dev", "🐳"},
+	{"stackoverflow.com", "stack overflow", "dev", "💬"},
+	{"digitalocean.com", "digitalocean", "cloud", "☁️"},
+	{"cloudflare.com", "cloudflare", "cloud", "☁️"},
+	{"heroku.com", "heroku", "cloud", "☁️"},
+	{"dropbox.com", "dropbox", "cloud", "📁"},
+	{"slack.com", "slack", "work", "💬"},
+	{"notion.so", "notion", "work", "📝"},
+	{"zoom.us", "zoom", "work", "🎥"},
+	{"facebook.com", "facebook", "social", "👥"},
+	{"instagram.com", "instagram", "social", "📷"},
+	{"x.com", "x", "social", "🐦"},
+	{"twitter.com", "twitter", "social", "🐦"},
+	{"linkedin.com", "linkedin", "social", "💼"},
+	{"reddit.com", "reddit", "social", "👽"},
+	{"discord.com", "discord", "social", "🎮"},
+	{"netflix.com", "netflix", "entertainment", "🎬"},
+	{"spotify.com", "spotify", "entertainment", "🎵"},
+	{"twitch.tv", "twitch", "entertainment", "🎮"},
+	{"youtube.com", "youtube", "entertainment", "▶️"},
+}
+
+// noiseLabels are subdomains that say nothing about which account it is
+var noiseLabels = map[string]bool{
+	"www": true, "m": true, "login": true, "signin": true, "accounts": true,
+	"account": true, "auth": true, "id": true, "sso": true, "secure": true,
+	"my": true, "app": true, "web": true,
+}
+
+// Suggestion is what a login URL suggests for a new entry
+type Suggestion struct {
+	Name     string
+	Category string // "" when the site is not known
+	Icon     string // "" when the site is not known
+	Domain   string // registrable domain (eTLD+1), e.g. amazon.com
+}
+
+// Suggest derives an entry name, category and icon from a login URL:
+// https://console.aws.amazon.com suggests "aws console" in cloud. Unknown
+// sites are named after their registrable domain, without the public
+// suffix. ok is false if the URL has no usable host
+func Suggest(rawURL string) (s Suggestion, ok bool) {
+	host := urlHost(rawURL)
+	if host == "" {
+		return Suggestion{}, false
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		// A bare public suffix or an IP address
+		return Suggestion{Name: host, Domain: host}, true
+	}
+
+	s.Domain = domain
+	base := domain
+	if site, found := Lookup(host); found {
+		s.Name, s.Category, s.Icon = site.Name, site.Category, site.Icon
+		base = site.Host
+	} else {
+		s.Name = strings.TrimSuffix(domain, "."+suffixOf(domain))
+	}
+
+	// The subdomain right below the site says which part of it this is
+	if sub := strings.TrimSuffix(host, base); sub != host && sub != "" {
+		labels := strings.Split(strings.TrimSuffix(sub, "."), ".")
+		if label := labels[len(labels)-1]; !noiseLabels[label] && !strings.Contains(s.Name, label) {
+			s.Name += " " + label
+		}
+	}
+	return s, true
+}
+
+// Lookup returns the well-known site a host is on
+func Lookup(host string) (Site, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var best Site
+	for _, site := range known {
+		if (host == site.Host || strings.HasSuffix(host, "."+site.Host)) && len(site.Host) > len(best.Host) {
+			best = site
+		}
+	}
+	return best, best.Host != ""
+}
+
+// suffixOf returns the public suffix of a registrable domain
+func suffixOf(domain string) string {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix
+}
+
+// urlHost returns the lower-cased host of a URL, adding a missing scheme
+func urlHost(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
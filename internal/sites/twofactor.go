@@ -0,0 +1,49 @@
+package sites
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+// totpDomains are the domains of sites that support TOTP, one per line,
+// from 2fa.directory. Regenerate with scripts/update-2fa-directory.sh
+//
+//go:embed data/totp-domains.txt
+var totpDomains []byte
+
+var (
+	totpOnce sync.Once
+	totpSet  map[string]bool
+)
+
+// SupportsTOTP reports whether the site a URL is on is known to offer TOTP
+// two-factor authentication, and returns the domain that matched: the
+// host itself or a parent domain, e.g. github.com for gist.github.com
+func SupportsTOTP(rawURL string) (domain string, ok bool) {
+	totpOnce.Do(func() {
+		totpSet = make(map[string]bool)
+		scanner := bufio.NewScanner(bytes.NewReader(totpDomains))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				totpSet[strings.ToLower(line)] = true
+			}
+		}
+	})
+
+	host := urlHost(rawURL)
+	for host != "" {
+		if totpSet[host] {
+			return host, true
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			break
+		}
+		host = host[dot+1:]
+	}
+	return "", false
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Health score components
+const (
+	ComponentStrength = "strength"
+	ComponentReuse    = "reuse"
+	ComponentAge      = "age"
+	Component2FA      = "2fa"
+	ComponentBreaches = "breaches"
+)
+
+// componentWeights are the points each component is worth when all are
+// measured; they add up to 100
+var componentWeights = []struct {
+	Name   string
+	Weight float64
+}{
+	{ComponentStrength, 30},
+	{ComponentReuse, 25},
+	{ComponentBreaches, 15},
+	{ComponentAge, 15},
+	{Component2FA, 15},
+}
+
+// Component is one part of the health score
+type Component struct {
+	Name   string  `json:"name"`
+	Points float64 `json:"points"` // earned, out of Max
+	Max    float64 `json:"max"`
+	Detail string  `json:"detail"`
+}
+
+// Health is a 0-100 summary of how well the vault is kept
+type Health struct {
+	Score      int         `json:"score"`
+	Components []Component `json:"components"`
+	Skipped    []string    `json:"skipped,omitempty"` // components not measured, e.g. breaches without a breach file
+}
+
+// HealthScore rates the vault from its statistics:
+//   - strength: strong passwords count fully, fair ones half
+//   - reuse: passwords not shared with another entry
+//   - breaches: passwords not found in the breach corpus (if checked)
+//   - age: passwords changed within a year count fully, within two half
+//   - 2fa: logins with a one-time password secret
+//
+// Components with nothing to measure are skipped and the others scaled up,
+// so a vault without logins isn't marked down for lacking 2FA
+func HealthScore(s *Stats) *Health {
+	h := &Health{}
+
+	rated := 0
+	for _, bucket := range s.Strength {
+		rated += bucket.Count
+	}
+	logins := s.Types[models.EntryTypeLogin]
+
+	total, possible := 0.0, 0.0
+	for _, c := range componentWeights {
+		ratio, detail, ok := s.measure(c.Name, rated, logins)
+		if !ok {
+			h.Skipped = append(h.Skipped, c.Name)
+			continue
+		}
+		points := math.Round(ratio*c.Weight*10) / 10
+		h.Components = append(h.Components, Component{Name: c.Name, Points: points, Max: c.Weight, Detail: detail})
+		total += ratio * c.Weight
+		possible += c.Weight
+	}
+
+	if possible > 0 {
+		h.Score = int(math.Round(total / possible * 100))
+	} else {
+		// An empty vault has nothing wrong with it
+		h.Score = 100
+	}
+	return h
+}
+
+// measure returns the share of a component's points the vault earns and
+// what it is based on; ok is false if there is nothing to measure
+func (s *Stats) measure(component string, rated, logins int) (ratio float64, detail string, ok bool) {
+	switch component {
+	case ComponentStrength:
+		if rated == 0 {
+			return 0, "", false
+		}
+		strong, fair := 0, 0
+		for i, level := range strengthLevels {
+			switch {
+			case level >= crypto.Strong:
+				strong += s.Strength[i].Count
+			case level == crypto.Fair:
+				fair += s.Strength[i].Count
+			}
+		}
+		return (float64(strong) + float64(fair)/2) / float64(rated),
+			fmt.Sprintf("%d of %d passwords strong or better", strong, rated), true
+
+	case ComponentReuse:
+		if rated == 0 {
+			return 0, "", false
+		}
+		reused := min(s.Audit.Issues[audit.IssueReused], rated)
+		return 1 - float64(reused)/float64(rated), fmt.Sprintf("%d of %d passwords reused", reused, rated), true
+
+	case ComponentBreaches:
+		if !s.Audit.BreachChecked || rated == 0 {
+			return 0, "", false
+		}
+		breached := min(s.Audit.Issues[audit.IssueBreached], rated)
+		return 1 - float64(breached)/float64(rated), fmt.Sprintf("%d of %d passwords breached", breached, rated), true
+
+	case ComponentAge:
+		if s.Total == 0 {
+			return 0, "", false
+		}
+		// Buckets: < 30d, 30-90d, 90d-1y, 1-2y, > 2y
+		recent := s.Age[0].Count + s.Age[1].Count + s.Age[2].Count
+		return (float64(recent) + float64(s.Age[3].Count)/2) / float64(s.Total),
+			fmt.Sprintf("%d of %d passwords older than a year", s.Age[3].Count+s.Age[4].Count, s.Total), true
+
+	case Component2FA:
+		if logins == 0 {
+			return 0, "", false
+		}
+		withOTP := min(s.WithOTP, logins)
+		return float64(withOTP) / float64(logins), fmt.Sprintf("%d of %d logins with 2FA", withOTP, logins), true
+	}
+	return 0, "", false
+}
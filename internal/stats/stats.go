@@ -0,0 +1,156 @@
+// Package stats summarizes a vault for dashboards and reports
+// Stats never contain secrets or entry names, only counts
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/audit"
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// Bucket is one bar of a histogram
+type Bucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// CategoryCount is the number of entries in a category
+type CategoryCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// AuditSummary counts audit findings by type
+type AuditSummary struct {
+	EntriesWithIssues int                     `json:"entries_with_issues"`
+	Issues            map[audit.IssueType]int `json:"issues"`
+	BreachChecked     bool                    `json:"breach_checked"`
+}
+
+// StorageSummary describes the space the vault takes
+type StorageSummary struct {
+	VaultBytes int64 `json:"vault_bytes"`
+	LargeNotes int   `json:"large_notes"` // entries with notes over the size limit
+}
+
+// Stats is a point-in-time summary of the vault
+type Stats struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Total       int             `json:"total"`
+	Categories  []CategoryCount `json:"categories"`
+	Types       map[string]int  `json:"types"`
+	WithOTP     int             `json:"with_otp"`
+	Strength    []Bucket        `json:"strength"`
+	Age         []Bucket        `json:"age"`
+	Audit       AuditSummary    `json:"audit"`
+	Storage     StorageSummary  `json:"storage"`
+}
+
+// ageBuckets are the upper bounds of the password age histogram
+var ageBuckets = []struct {
+	Label string
+	Max   time.Duration
+}{
+	{"< 30d", 30 * 24 * time.Hour},
+	{"30-90d", 90 * 24 * time.Hour},
+	{"90d-1y", 365 * 24 * time.Hour},
+	{"1-2y", 2 * 365 * 24 * time.Hour},
+	{"> 2y", 1<<63 - 1},
+}
+
+var strengthLevels = []crypto.StrengthLevel{
+	crypto.VeryWeak, crypto.Weak, crypto.Fair, crypto.Strong, crypto.VeryStrong,
+}
+
+// Compute builds the statistics for decrypted entries
+// changedAt maps entry IDs to when their password last changed; report is
+// the audit of the same entries
+func Compute(entries []*models.Entry, changedAt map[string]time.Time, report *audit.Report, breachChecked bool, now time.Time) *Stats {
+	s := &Stats{
+		GeneratedAt: now,
+		Total:       len(entries),
+		Types:       make(map[string]int),
+		Strength:    make([]Bucket, len(strengthLevels)),
+		Age:         make([]Bucket, len(ageBuckets)),
+	}
+	for i, level := range strengthLevels {
+		s.Strength[i].Label = level.String()
+	}
+	for i, bucket := range ageBuckets {
+		s.Age[i].Label = bucket.Label
+	}
+
+	categories := make(map[string]int)
+	for _, entry := range entries {
+		categories[entry.Category]++
+
+		entryType := entry.Type
+		if entryType == "" {
+			entryType = models.EntryTypeLogin
+		}
+		s.Types[entryType]++
+
+		if entry.OTP != "" {
+			s.WithOTP++
+		}
+
+		// Token values are chosen by the issuer, so only rate login passwords
+		if entry.Password != "" && !entry.IsToken() && !entry.IsKey() {
+			level := crypto.CheckStrength(entry.Password).Level
+			for i, l := range strengthLevels {
+				if l == level {
+					s.Strength[i].Count++
+				}
+			}
+		}
+
+		changed, ok := changedAt[entry.ID]
+		if !ok {
+			changed = entry.CreatedAt
+		}
+		age := now.Sub(changed)
+		for i, bucket := range ageBuckets {
+			if age < bucket.Max {
+				s.Age[i].Count++
+				break
+			}
+		}
+	}
+
+	for category, count := range categories {
+		s.Categories = append(s.Categories, CategoryCount{Category: category, Count: count})
+	}
+	sort.Slice(s.Categories, func(i, j int) bool {
+		if s.Categories[i].Count != s.Categories[j].Count {
+			return s.Categories[i].Count > s.Categories[j].Count
+		}
+		return s.Categories[i].Category < s.Categories[j].Category
+	})
+
+	// Dashboards get a stable set of keys even when a count is zero
+	s.Audit = AuditSummary{
+		Issues: map[audit.IssueType]int{
+			audit.IssueWeak:     0,
+			audit.IssueReused:   0,
+			audit.IssueExpiring: 0,
+			audit.IssueExpired:  0,
+		},
+		BreachChecked: breachChecked,
+	}
+	if breachChecked {
+		s.Audit.Issues[audit.IssueBreached] = 0
+	}
+	if report != nil {
+		withIssues := make(map[string]bool)
+		for _, finding := range report.Findings {
+			s.Audit.Issues[finding.Type]++
+			withIssues[finding.EntryID] = true
+		}
+		s.Audit.EntriesWithIssues = len(withIssues)
+	}
+
+	return s
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AccessEvent is one read of an entry's secrets
+type AccessEvent struct {
+	EntryID string
+	At      time.Time
+	Action  string // command that read the entry, e.g. "show", "copy"
+}
+
+// RecordAccess logs a read of an entry
+func (db *DB) RecordAccess(entryID, action string) error {
+	if entryID == "" {
+		return errors.New("entry ID cannot be empty")
+	}
+
+	query := "INSERT INTO entry_access (entry_id, accessed_at, action) VALUES (?, ?, ?)"
+	if _, err := db.Exec(query, entryID, time.Now(), action); err != nil {
+		return fmt.Errorf("failed to record entry access: %w", err)
+	}
+	return nil
+}
+
+// ListAccess returns the most recent reads of an entry, newest first
+// limit <= 0 returns every read
+func (db *DB) ListAccess(entryID string, limit int) ([]AccessEvent, error) {
+	if entryID == "" {
+		return nil, errors.New("entry ID cannot be empty")
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	query := `
+		SELECT entry_id, accessed_at, action
+		FROM entry_access
+		WHERE entry_id = ?
+		ORDER BY accessed_at DESC, id DESC
+		LIMIT ?
+	`
+	return db.queryAccess(query, entryID, limit)
+}
+
+// CountAccess returns the total number of recorded reads of an entry
+func (db *DB) CountAccess(entryID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM entry_access WHERE entry_id = ?"
+	if err := db.QueryRow(query, entryID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count entry access: %w", err)
+	}
+	return count, nil
+}
+
+// AccessSince returns every read since the given time, grouped by entry ID
+// and oldest first
+func (db *DB) AccessSince(since time.Time) (map[string][]AccessEvent, error) {
+	query := `
+		SELECT entry_id, accessed_at, action
+		FROM entry_access
+		WHERE accessed_at >= ?
+		ORDER BY accessed_at ASC, id ASC
+	`
+	events, err := db.queryAccess(query, since)
+	if err != nil {
+		return nil, err
+	}
+
+	byEntry := make(map[string][]AccessEvent)
+	for _, event := range events {
+		byEntry[event.EntryID] = append(byEntry[event.EntryID], event)
+	}
+	return byEntry, nil
+}
+
+func (db *DB) queryAccess(query string, args ...any) ([]AccessEvent, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry access: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AccessEvent
+	for rows.Next() {
+		var event AccessEvent
+		if err := rows.Scan(&event.EntryID, &event.At, &event.Action); err != nil {
+			return nil, fmt.Errorf("failed to scan entry access: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry access: %w", err)
+	}
+
+	return events, nil
+}
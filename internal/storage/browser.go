@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MetadataKeyBrowserOrigins stores the web origins browser extensions may
+// read and save logins for
+const MetadataKeyBrowserOrigins = "browser_origins"
+
+// BrowserOrigin is a web origin (scheme://host[:port]) approved for the
+// browser host
+type BrowserOrigin struct {
+	Origin     string    `json:"origin"`
+	Extension  string    `json:"extension,omitempty"` // extension that asked first, for display
+	ApprovedAt time.Time `json:"approved_at"`
+}
+
+// BrowserOrigins returns the approved origins sorted by origin
+func (db *DB) BrowserOrigins() ([]BrowserOrigin, error) {
+	var origins []BrowserOrigin
+	if _, err := db.loadListedMetadata(MetadataKeyBrowserOrigins, &origins); err != nil {
+		return nil, err
+	}
+	sort.Slice(origins, func(i, j int) bool { return origins[i].Origin < origins[j].Origin })
+	return origins, nil
+}
+
+// BrowserOriginApproved reports whether origin was approved
+func (db *DB) BrowserOriginApproved(origin string) (bool, error) {
+	origins, err := db.BrowserOrigins()
+	if err != nil {
+		return false, err
+	}
+	for _, approved := range origins {
+		if strings.EqualFold(approved.Origin, origin) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ApproveBrowserOrigin adds an origin, or refreshes its approval
+func (db *DB) ApproveBrowserOrigin(origin, extension string) error {
+	origins, err := db.BrowserOrigins()
+	if err != nil {
+		return err
+	}
+
+	approval := BrowserOrigin{Origin: strings.ToLower(origin), Extension: extension, ApprovedAt: time.Now().UTC()}
+	replaced := false
+	for i := range origins {
+		if strings.EqualFold(origins[i].Origin, origin) {
+			origins[i] = approval
+			replaced = true
+		}
+	}
+	if !replaced {
+		origins = append(origins, approval)
+	}
+	return db.saveBrowserOrigins(origins)
+}
+
+// RevokeBrowserOrigin removes an origin's approval; removed is false if it
+// was not approved
+func (db *DB) RevokeBrowserOrigin(origin string) (removed bool, err error) {
+	origins, err := db.BrowserOrigins()
+	if err != nil {
+		return false, err
+	}
+
+	kept := origins[:0]
+	for _, approved := range origins {
+		if !strings.EqualFold(approved.Origin, origin) {
+			kept = append(kept, approved)
+		}
+	}
+	if len(kept) == len(origins) {
+		return false, nil
+	}
+	return true, db.saveBrowserOrigins(kept)
+}
+
+func (db *DB) saveBrowserOrigins(origins []BrowserOrigin) error {
+	if len(origins) == 0 {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyBrowserOrigins); err != nil {
+			return fmt.Errorf("failed to clear approved browser origins: %w", err)
+		}
+		return nil
+	}
+	return db.saveListedMetadata(MetadataKeyBrowserOrigins, origins)
+}
+
+// MetadataKeyBrowserPairings stores the browser extension keys paired with
+// the browser host, and pairings waiting for approval
+const MetadataKeyBrowserPairings = "browser_pairings"
+
+// BrowserPairRequestTTL is how long a pairing request can be approved
+const BrowserPairRequestTTL = 10 * time.Minute
+
+// ErrNoPairRequest is returned when no pending pairing has a code
+var ErrNoPairRequest = errors.New("no pending pairing request with this code; pair the extension again")
+
+// BrowserPairing is an extension key paired with the browser host, or
+// waiting to be
+type BrowserPairing struct {
+	KeyID       string     `json:"key_id"`
+	PublicKey   string     `json:"public_key"` // X25519, base64
+	Extension   string     `json:"extension,omitempty"`
+	Code        string     `json:"code,omitempty"` // pending: the code to approve
+	RequestedAt time.Time  `json:"requested_at"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"` // nil = pending
+}
+
+// Pending reports whether the pairing still waits for approval
+func (p BrowserPairing) Pending() bool {
+	return p.ApprovedAt == nil
+}
+
+// BrowserPairings returns paired and pending extension keys, oldest
+// request first; expired requests are left out
+func (db *DB) BrowserPairings() ([]BrowserPairing, error) {
+	var pairings []BrowserPairing
+	if _, err := db.loadListedMetadata(MetadataKeyBrowserPairings, &pairings); err != nil {
+		return nil, err
+	}
+	kept := pairings[:0]
+	for _, pairing := range pairings {
+		if !pairing.Pending() || time.Since(pairing.RequestedAt) < BrowserPairRequestTTL {
+			kept = append(kept, pairing)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].RequestedAt.Before(kept[j].RequestedAt) })
+	return kept, nil
+}
+
+// BrowserKeyPaired reports whether the extension key with keyID was
+// approved
+func (db *DB) BrowserKeyPaired(keyID string) (bool, error) {
+	pairings, err := db.BrowserPairings()
+	if err != nil {
+		return false, err
+	}
+	for _, pairing := range pairings {
+		if pairing.KeyID == keyID && !pairing.Pending() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RequestBrowserPairing records a pairing waiting for approval with code,
+// replacing an earlier request for the same key. A paired key stays paired
+func (db *DB) RequestBrowserPairing(request BrowserPairing) error {
+	pairings, err := db.BrowserPairings()
+	if err != nil {
+		return err
+	}
+
+	request.RequestedAt = time.Now().UTC()
+	request.ApprovedAt = nil
+	kept := pairings[:0]
+	for _, pairing := range pairings {
+		if pairing.KeyID != request.KeyID {
+			kept = append(kept, pairing)
+		} else if !pairing.Pending() {
+			return nil
+		}
+	}
+	return db.saveBrowserPairings(append(kept, request))
+}
+
+// ApproveBrowserPairing approves the pending pairing shown with code
+// If several requests share the code, one of them was forged to match it:
+// they are all dropped and nothing is approved
+func (db *DB) ApproveBrowserPairing(code string) (*BrowserPairing, error) {
+	pairings, err := db.BrowserPairings()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []int
+	for i, pairing := range pairings {
+		if pairing.Pending() && pairing.Code == code {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrNoPairRequest
+	case 1:
+		now := time.Now().UTC()
+		approved := &pairings[matches[0]]
+		approved.ApprovedAt = &now
+		approved.Code = ""
+		return approved, db.saveBrowserPairings(pairings)
+	}
+
+	kept := pairings[:0]
+	for _, pairing := range pairings {
+		if !pairing.Pending() || pairing.Code != code {
+			kept = append(kept, pairing)
+		}
+	}
+	if err := db.saveBrowserPairings(kept); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%d pairing requests show this code, so one is forged; all were dropped, pair the extension again", len(matches))
+}
+
+// RevokeBrowserPairing removes the paired or pending key with keyID;
+// removed is false if there is none
+func (db *DB) RevokeBrowserPairing(keyID string) (removed bool, err error) {
+	pairings, err := db.BrowserPairings()
+	if err != nil {
+		return false, err
+	}
+
+	kept := pairings[:0]
+	for _, pairing := range pairings {
+		if pairing.KeyID != keyID {
+			kept = append(kept, pairing)
+		}
+	}
+	if len(kept) == len(pairings) {
+		return false, nil
+	}
+	return true, db.saveBrowserPairings(kept)
+}
+
+func (db *DB) saveBrowserPairings(pairings []BrowserPairing) error {
+	if len(pairings) == 0 {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyBrowserPairings); err != nil {
+			return fmt.Errorf("failed to clear browser pairings: %w", err)
+		}
+		return nil
+	}
+	return db.saveListedMetadata(MetadataKeyBrowserPairings, pairings)
+}
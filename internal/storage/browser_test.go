@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBrowserPairings(t *testing.T) {
+	db, _ := newTestVault(t, filepath.Join(t.TempDir(), "vault.db"), "password", PrivacyPrivate)
+	defer db.Close()
+
+	request := func(keyID, code string) {
+		t.Helper()
+		if err := db.RequestBrowserPairing(BrowserPairing{KeyID: keyID, PublicKey: "key-" + keyID, Code: code}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request("a", "111-111-111")
+	if _, err := db.ApproveBrowserPairing("222-222-222"); !errors.Is(err, ErrNoPairRequest) {
+		t.Errorf("approving an unknown code = %v, want ErrNoPairRequest", err)
+	}
+	approved, err := db.ApproveBrowserPairing("111-111-111")
+	if err != nil || approved.KeyID != "a" || approved.Pending() {
+		t.Fatalf("approve = %+v, %v", approved, err)
+	}
+	if paired, err := db.BrowserKeyPaired("a"); err != nil || !paired {
+		t.Errorf("key a paired = %v, %v", paired, err)
+	}
+
+	// A new request for a paired key leaves it paired
+	request("a", "333-333-333")
+	if paired, _ := db.BrowserKeyPaired("a"); !paired {
+		t.Error("a repeated request unpaired the key")
+	}
+
+	// Two pending requests with one code: one is forged, neither is approved
+	request("b", "444-444-444")
+	request("forged", "444-444-444")
+	if _, err := db.ApproveBrowserPairing("444-444-444"); err == nil {
+		t.Error("a code shared by two requests was approved")
+	}
+	pairings, err := db.BrowserPairings()
+	if err != nil || len(pairings) != 1 || pairings[0].KeyID != "a" {
+		t.Errorf("pairings after a collision = %+v, %v", pairings, err)
+	}
+
+	// Expired requests can't be approved
+	request("c", "555-555-555")
+	pairings, _ = db.BrowserPairings()
+	pairings[len(pairings)-1].RequestedAt = time.Now().Add(-BrowserPairRequestTTL)
+	if err := db.saveBrowserPairings(pairings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ApproveBrowserPairing("555-555-555"); !errors.Is(err, ErrNoPairRequest) {
+		t.Errorf("approving an expired request = %v, want ErrNoPairRequest", err)
+	}
+
+	if removed, err := db.RevokeBrowserPairing("a"); err != nil || !removed {
+		t.Errorf("revoke = %v, %v", removed, err)
+	}
+	if paired, _ := db.BrowserKeyPaired("a"); paired {
+		t.Error("a revoked key is still paired")
+	}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ImportCheckpoint records how far an interrupted import got
+type ImportCheckpoint struct {
+	Source    string    // e.g. "LastPass"
+	Digest    string    // Identifies the records being imported
+	Position  int       // Records before this index are done
+	Total     int       // Number of records in the import
+	UpdatedAt time.Time // When the last record was committed
+}
+
+// SaveImportCheckpoint replaces the checkpoint
+func (db *DB) SaveImportCheckpoint(checkpoint ImportCheckpoint) error {
+	_, err := db.Exec(`
+		INSERT OR REPLACE INTO import_checkpoint (id, source, digest, position, total, updated_at)
+		VALUES (1, ?, ?, ?, ?, ?)
+	`, checkpoint.Source, checkpoint.Digest, checkpoint.Position, checkpoint.Total, checkpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save import checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ImportCheckpoint returns the checkpoint of an unfinished import, or nil
+func (db *DB) ImportCheckpoint() (*ImportCheckpoint, error) {
+	var checkpoint ImportCheckpoint
+	err := db.QueryRow(`
+		SELECT source, digest, position, total, updated_at FROM import_checkpoint WHERE id = 1
+	`).Scan(&checkpoint.Source, &checkpoint.Digest, &checkpoint.Position, &checkpoint.Total, &checkpoint.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// ClearImportCheckpoint removes the checkpoint once an import finishes
+func (db *DB) ClearImportCheckpoint() error {
+	if _, err := db.Exec("DELETE FROM import_checkpoint"); err != nil {
+		return fmt.Errorf("failed to clear import checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MetadataKeyImportConflicts stores entries that an import had to rename
+const MetadataKeyImportConflicts = "import_conflicts"
+
+// ImportConflict records an imported entry that clashed with an existing
+// name and was stored under a numbered name instead
+// The conflict stays open until the entry is renamed or deleted
+type ImportConflict struct {
+	EntryID    string    `json:"entry_id"`
+	EntryName  string    `json:"entry_name"`
+	Original   string    `json:"original"`
+	Source     string    `json:"source"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// AddImportConflicts appends conflicts to the list of open conflicts
+func (db *DB) AddImportConflicts(conflicts []ImportConflict) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	existing, err := db.loadImportConflicts()
+	if err != nil {
+		return err
+	}
+	return db.saveImportConflicts(append(existing, conflicts...))
+}
+
+// ImportConflicts returns the open import conflicts, dropping conflicts
+// whose entry has since been renamed or deleted
+func (db *DB) ImportConflicts() ([]ImportConflict, error) {
+	conflicts, err := db.loadImportConflicts()
+	if err != nil || len(conflicts) == 0 {
+		return nil, err
+	}
+
+	entries, err := db.ListEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		names[entry.ID] = entry.Name
+	}
+
+	open := make([]ImportConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		if name, ok := names[conflict.EntryID]; ok && strings.EqualFold(name, conflict.EntryName) {
+			open = append(open, conflict)
+		}
+	}
+
+	if len(open) != len(conflicts) {
+		if err := db.saveImportConflicts(open); err != nil {
+			return nil, err
+		}
+	}
+	return open, nil
+}
+
+func (db *DB) loadImportConflicts() ([]ImportConflict, error) {
+	var conflicts []ImportConflict
+	if _, err := db.loadListedMetadata(MetadataKeyImportConflicts, &conflicts); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+func (db *DB) saveImportConflicts(conflicts []ImportConflict) error {
+	if len(conflicts) == 0 {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyImportConflicts); err != nil {
+			return fmt.Errorf("failed to clear import conflicts: %w", err)
+		}
+		return nil
+	}
+	return db.saveListedMetadata(MetadataKeyImportConflicts, conflicts)
+}
@@ -4,16 +4,26 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kitsnail/gpasswd/internal/securefs"
 )
 
 // DB wraps sql.DB with additional functionality for gpasswd
 type DB struct {
 	*sql.DB
-	path string
+	path    string
+	privacy PrivacyLevel // Cached privacy level, loaded on first use
+
+	uniformLookup bool // Hardened name resolution, see SetUniformLookup
+	verifyWrites  bool // Read back written entries, see SetVerifyWrites
+
+	// Manifest state, see manifest.go
+	key      []byte // Verified vault key, set by VerifyKey
+	tampered bool   // VerifyManifest failed; don't reseal implicitly
 }
 
 // InitDB initializes and returns a new database connection
@@ -28,7 +38,7 @@ func InitDB(dbPath string) (*DB, error) {
 
 	// Ensure parent directory exists
 	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
+	if err := securefs.MkdirAll(dir); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
@@ -55,16 +65,37 @@ func InitDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to configure database: %w", err)
 	}
 
+	// The file now exists; keep it private to the current user
+	// Filesystems without permissions (e.g. Android shared storage) refuse
+	// the change, which is not worth failing over
+	if err := securefs.Restrict(dbPath); err != nil && !errors.Is(err, fs.ErrPermission) {
+		db.Close()
+		return nil, fmt.Errorf("failed to restrict vault permissions: %w", err)
+	}
+
 	// Create schema
 	if err := db.createSchema(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
 
+	// Upgrade schemas created by older versions
+	if err := db.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	// Cache and memory-map sizes depend on the vault's profile
+	if err := db.applyProfile(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure database: %w", err)
+	}
+
 	return db, nil
 }
 
 // configure sets up SQLite pragmas for optimal performance and security
+// Memory settings follow later, see applyProfile
 func (db *DB) configure() error {
 	pragmas := []string{
 		// Enable foreign key constraints
@@ -76,12 +107,6 @@ func (db *DB) configure() error {
 		// Synchronous NORMAL is safe with WAL and much faster
 		"PRAGMA synchronous = NORMAL",
 
-		// Memory-mapped I/O for better performance
-		"PRAGMA mmap_size = 30000000000", // 30GB
-
-		// Increase cache size (negative value = KB)
-		"PRAGMA cache_size = -64000", // 64MB
-
 		// Use busy timeout to handle lock contention
 		"PRAGMA busy_timeout = 5000", // 5 seconds
 	}
@@ -95,6 +120,17 @@ func (db *DB) configure() error {
 	return nil
 }
 
+// entriesTimestampTrigger bumps updated_at on every change to an entry
+// Rekey drops it for the duration of its transaction
+const entriesTimestampTrigger = `
+	CREATE TRIGGER IF NOT EXISTS update_entries_timestamp
+	AFTER UPDATE ON entries
+	BEGIN
+		UPDATE entries SET updated_at = CURRENT_TIMESTAMP
+		WHERE id = NEW.id;
+	END;
+`
+
 // createSchema creates all necessary tables and indexes
 func (db *DB) createSchema() error {
 	schema := `
@@ -133,26 +169,66 @@ func (db *DB) createSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_entries_created_at ON entries(created_at);
 	CREATE INDEX IF NOT EXISTS idx_entries_updated_at ON entries(updated_at);
 
-	-- Full-text search table (FTS5)
-	-- This will store decrypted search text temporarily during search operations
-	-- NOT persisted - populated on-demand during searches
-	-- NOTE: Temporarily disabled - requires SQLite with FTS5 support
-	-- Will be re-enabled in future iteration with proper SQLite build tags
-	-- CREATE VIRTUAL TABLE IF NOT EXISTS entries_fts USING fts5(
-	--	entry_id UNINDEXED,
-	--	search_text,
-	--	content='',
-	--	tokenize='porter unicode61'
-	-- );
+	-- Normalized tags for index lookups without decrypting entries
+	-- Tags are treated as low-sensitivity metadata (like name and category)
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE
+	);
+
+	CREATE TABLE IF NOT EXISTS entry_tags (
+		entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		PRIMARY KEY (entry_id, tag_id)
+	);
+
+	-- Index for tag -> entries lookups
+	CREATE INDEX IF NOT EXISTS idx_entry_tags_tag_id ON entry_tags(tag_id);
+
+	-- Previous versions of entries, archived on every update
+	-- valid_from/valid_to bound the period in which the version was current
+	CREATE TABLE IF NOT EXISTS entry_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		category TEXT NOT NULL DEFAULT '',
+		encrypted_data BLOB NOT NULL,
+		valid_from DATETIME NOT NULL,
+		valid_to DATETIME NOT NULL
+	);
+
+	-- Index for per-entry history lookups
+	CREATE INDEX IF NOT EXISTS idx_entry_history_entry_id ON entry_history(entry_id, valid_to);
+
+	-- Reads of entry secrets (show, copy, run, ...), for 'gpasswd log access'
+	-- and access anomaly hints in audit
+	CREATE TABLE IF NOT EXISTS entry_access (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		accessed_at DATETIME NOT NULL,
+		action TEXT NOT NULL
+	);
+
+	-- Index for per-entry access lookups
+	CREATE INDEX IF NOT EXISTS idx_entry_access_entry_id ON entry_access(entry_id, accessed_at);
+
+	-- Progress of an interrupted import, for 'gpasswd import --resume'
+	-- Holds at most one row and is emptied when the import finishes
+	CREATE TABLE IF NOT EXISTS import_checkpoint (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		source TEXT NOT NULL,
+		digest TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		total INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	-- Full-text search uses a temporary in-memory FTS5 table populated from
+	-- the decrypted search text on each search (see search.go); nothing
+	-- decrypted is stored here
 
 	-- Trigger to update updated_at timestamp
-	CREATE TRIGGER IF NOT EXISTS update_entries_timestamp
-	AFTER UPDATE ON entries
-	BEGIN
-		UPDATE entries SET updated_at = CURRENT_TIMESTAMP
-		WHERE id = NEW.id;
-	END;
-	`
+	` + entriesTimestampTrigger
 
 	_, err := db.Exec(schema)
 	if err != nil {
@@ -162,7 +238,91 @@ func (db *DB) createSchema() error {
 	return nil
 }
 
+// migrate adds columns introduced after the initial schema
+// CREATE TABLE IF NOT EXISTS does not alter existing tables, so new
+// columns must be added explicitly for vaults created by older versions
+func (db *DB) migrate() error {
+	columns := []struct {
+		table, column, definition string
+	}{
+		// Plaintext URL host, only populated at the "open" privacy level
+		{"entries", "url_host", "TEXT NOT NULL DEFAULT ''"},
+		// Plaintext username, only for entries that opt in (ListableUsername)
+		{"entries", "username", "TEXT NOT NULL DEFAULT ''"},
+		// Metadata and notes under the fields key (see fields.go); NULL for
+		// high-security entries and entries written before it existed
+		{"entries", "encrypted_fields", "BLOB"},
+		{"entry_history", "encrypted_fields", "BLOB"},
+	}
+
+	for _, c := range columns {
+		if err := db.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_entries_url_host ON entries(url_host)",
+	}
+
+	for _, index := range indexes {
+		if _, err := db.Exec(index); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan column info: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating column info: %w", err)
+	}
+	rows.Close()
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
 // Path returns the database file path
 func (db *DB) Path() string {
 	return db.path
 }
+
+// Snapshot writes a consistent single-file copy of the vault to path,
+// including changes still in the write-ahead log
+// path must not exist yet
+func (db *DB) Snapshot(path string) error {
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot vault: %w", err)
+	}
+	return securefs.Restrict(path)
+}
@@ -2,7 +2,6 @@ package storage
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -19,6 +18,52 @@ type EntryData struct {
 	URL      string   `json:"url"`
 	Notes    string   `json:"notes"`
 	Tags     []string `json:"tags"`
+	Category string   `json:"category,omitempty"`
+
+	Icon              string `json:"icon,omitempty"`
+	ChangePasswordURL string `json:"change_password_url,omitempty"`
+	OTP               string `json:"otp,omitempty"`
+
+	Type      string     `json:"type,omitempty"`
+	Service   string     `json:"service,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+
+	KeyType   string `json:"key_type,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+
+	SecurityLevel string `json:"security_level,omitempty"`
+
+	ListableUsername bool `json:"listable_username,omitempty"`
+
+	LoginSteps []string `json:"login_steps,omitempty"`
+
+	HistoryRetention *models.Retention `json:"history_retention,omitempty"`
+}
+
+// newEntryData collects the fields stored in the encrypted blob
+func newEntryData(entry *models.Entry) EntryData {
+	return EntryData{
+		Username:          entry.Username,
+		Password:          entry.Password,
+		URL:               entry.URL,
+		Notes:             entry.Notes,
+		Tags:              entry.Tags,
+		Category:          entry.Category,
+		Icon:              entry.Icon,
+		ChangePasswordURL: entry.ChangePasswordURL,
+		OTP:               entry.OTP,
+		Type:              entry.Type,
+		Service:           entry.Service,
+		ExpiresAt:         entry.ExpiresAt,
+		Scopes:            entry.Scopes,
+		KeyType:           entry.KeyType,
+		PublicKey:         entry.PublicKey,
+		SecurityLevel:     entry.SecurityLevel,
+		ListableUsername:  entry.ListableUsername,
+		LoginSteps:        entry.LoginSteps,
+		HistoryRetention:  entry.HistoryRetention,
+	}
 }
 
 // CreateEntry encrypts and stores a new password entry in the database
@@ -31,8 +76,8 @@ func (db *DB) CreateEntry(entry *models.Entry, key []byte) error {
 	if entry.Name == "" {
 		return errors.New("entry name cannot be empty")
 	}
-	if entry.Password == "" {
-		return errors.New("entry password cannot be empty")
+	if missing := entry.Missing(); missing != "" {
+		return fmt.Errorf("entry needs %s", missing)
 	}
 	if key == nil || len(key) != 32 {
 		return errors.New("encryption key must be 32 bytes")
@@ -53,26 +98,12 @@ func (db *DB) CreateEntry(entry *models.Entry, key []byte) error {
 		entry.Category = "general"
 	}
 
-	// Prepare data for encryption
-	data := EntryData{
-		Username: entry.Username,
-		Password: entry.Password,
-		URL:      entry.URL,
-		Notes:    entry.Notes,
-		Tags:     entry.Tags,
-	}
-
-	// Serialize to JSON
-	dataJSON, err := json.Marshal(data)
+	// Encrypt data, and metadata and notes under the fields key
+	sealed, err := sealEntry(entry, key)
 	if err != nil {
-		return fmt.Errorf("failed to marshal entry data: %w", err)
-	}
-
-	// Encrypt data
-	encryptedData, err := crypto.Encrypt(dataJSON, key)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt entry data: %w", err)
+		return err
 	}
+	encryptedData := sealed.data
 
 	// Generate search text (name + category + tags + username + URL)
 	searchText := entry.SearchText() + " " + entry.Username + " " + entry.URL
@@ -88,17 +119,30 @@ func (db *DB) CreateEntry(entry *models.Entry, key []byte) error {
 	dataNonce := encryptedData[:12]
 	searchNonce := encryptedSearch[:12]
 
+	// Decide which metadata is duplicated in plaintext
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return err
+	}
+	cols := plaintextFor(entry, level)
+
 	// Insert into database
 	query := `
 		INSERT INTO entries (
-			id, name, category, encrypted_data, encrypted_search,
-			created_at, updated_at, encryption_nonce, search_nonce
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			id, name, category, url_host, username, encrypted_data, encrypted_search,
+			encrypted_fields, created_at, updated_at, encryption_nonce, search_nonce
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = db.Exec(query,
-		entry.ID, entry.Name, entry.Category,
-		encryptedData, encryptedSearch,
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query,
+		entry.ID, entry.Name, cols.category, cols.urlHost, cols.username,
+		encryptedData, encryptedSearch, sealed.fields,
 		entry.CreatedAt, entry.UpdatedAt,
 		dataNonce, searchNonce,
 	)
@@ -106,7 +150,19 @@ func (db *DB) CreateEntry(entry *models.Entry, key []byte) error {
 		return fmt.Errorf("failed to insert entry: %w", err)
 	}
 
-	return nil
+	// Keep the tag index in sync with the encrypted data
+	if err := setEntryTags(tx, entry.ID, cols.tags); err != nil {
+		return err
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit entry: %w", err)
+	}
+
+	return db.verifyWrite(entry.ID, sealed, searchTextBytes, key)
 }
 
 // GetEntry retrieves and decrypts a password entry by ID
@@ -120,17 +176,17 @@ func (db *DB) GetEntry(id string, key []byte) (*models.Entry, error) {
 	}
 
 	query := `
-		SELECT id, name, category, encrypted_data,
+		SELECT id, name, category, encrypted_data, encrypted_fields,
 		       created_at, updated_at
 		FROM entries
 		WHERE id = ?
 	`
 
 	var entry models.Entry
-	var encryptedData []byte
+	var encryptedData, encryptedFields []byte
 
 	err := db.QueryRow(query, id).Scan(
-		&entry.ID, &entry.Name, &entry.Category, &encryptedData,
+		&entry.ID, &entry.Name, &entry.Category, &encryptedData, &encryptedFields,
 		&entry.CreatedAt, &entry.UpdatedAt,
 	)
 	if err != nil {
@@ -141,35 +197,27 @@ func (db *DB) GetEntry(id string, key []byte) (*models.Entry, error) {
 	}
 
 	// Decrypt data
-	decryptedData, err := crypto.Decrypt(encryptedData, key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt entry data: %w", err)
-	}
-
-	// Unmarshal JSON
-	var data EntryData
-	err = json.Unmarshal(decryptedData, &data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal entry data: %w", err)
+	if err := decryptInto(&entry, encryptedData, encryptedFields, key); err != nil {
+		return nil, err
 	}
 
-	// Populate entry fields
-	entry.Username = data.Username
-	entry.Password = data.Password
-	entry.URL = data.URL
-	entry.Notes = data.Notes
-	entry.Tags = data.Tags
-
 	return &entry, nil
 }
 
 // GetEntryByName retrieves and decrypts a password entry by name
+// In uniform lookup mode (see SetUniformLookup) every failure is
+// ErrEntryUnavailable and takes comparable time
 func (db *DB) GetEntryByName(name string, key []byte) (*models.Entry, error) {
 	// Validate input
 	if name == "" {
 		return nil, errors.New("entry name cannot be empty")
 	}
 
+	if db.uniformLookup {
+		defer padLookup(time.Now())
+		return db.getEntryByNameUniform(name, key)
+	}
+
 	// Get ID by name first
 	var id string
 	query := "SELECT id FROM entries WHERE name = ?"
@@ -185,8 +233,43 @@ func (db *DB) GetEntryByName(name string, key []byte) (*models.Entry, error) {
 	return db.GetEntry(id, key)
 }
 
+// ErrUsernameNotListable is returned for entries whose username is only
+// stored encrypted
+var ErrUsernameNotListable = errors.New("username is not listable")
+
+// ListableUsername returns an entry's username without the key, for
+// entries that opted in with ListableUsername
+// In uniform lookup mode both a missing entry and one without a listable
+// username fail with ErrEntryUnavailable
+func (db *DB) ListableUsername(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("entry name cannot be empty")
+	}
+
+	var username string
+	err := db.QueryRow("SELECT username FROM entries WHERE name = ?", name).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		if db.uniformLookup {
+			return "", ErrEntryUnavailable
+		}
+		return "", fmt.Errorf("entry with name %s not found", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query entry by name: %w", err)
+	}
+
+	if username == "" {
+		if db.uniformLookup {
+			return "", ErrEntryUnavailable
+		}
+		return "", ErrUsernameNotListable
+	}
+	return username, nil
+}
+
 // ListEntries returns a list of all entries (without decrypting passwords)
 // This is used for displaying entry lists in the CLI
+// Tags are filled in from the tag index
 func (db *DB) ListEntries() ([]*models.Entry, error) {
 	query := `
 		SELECT id, name, category, created_at, updated_at
@@ -217,6 +300,10 @@ func (db *DB) ListEntries() ([]*models.Entry, error) {
 		return nil, fmt.Errorf("error iterating entries: %w", err)
 	}
 
+	if err := db.attachTags(entries); err != nil {
+		return nil, err
+	}
+
 	return entries, nil
 }
 
@@ -252,9 +339,33 @@ func (db *DB) ListEntriesByCategory(category string) ([]*models.Entry, error) {
 		return nil, fmt.Errorf("error iterating entries: %w", err)
 	}
 
+	if err := db.attachTags(entries); err != nil {
+		return nil, err
+	}
+
 	return entries, nil
 }
 
+// GetAllEntries retrieves and decrypts every entry in the vault
+// Used by commands that need to inspect secrets across the whole vault (audit, export)
+func (db *DB) GetAllEntries(key []byte) ([]*models.Entry, error) {
+	entries, err := db.ListEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]*models.Entry, 0, len(entries))
+	for _, e := range entries {
+		entry, err := db.GetEntry(e.ID, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load entry %s: %w", e.Name, err)
+		}
+		decrypted = append(decrypted, entry)
+	}
+
+	return decrypted, nil
+}
+
 // UpdateEntry updates an existing entry with new encrypted data
 func (db *DB) UpdateEntry(entry *models.Entry, key []byte) error {
 	// Validate input
@@ -267,8 +378,8 @@ func (db *DB) UpdateEntry(entry *models.Entry, key []byte) error {
 	if entry.Name == "" {
 		return errors.New("entry name cannot be empty")
 	}
-	if entry.Password == "" {
-		return errors.New("entry password cannot be empty")
+	if missing := entry.Missing(); missing != "" {
+		return fmt.Errorf("entry needs %s", missing)
 	}
 	if key == nil || len(key) != 32 {
 		return errors.New("encryption key must be 32 bytes")
@@ -282,26 +393,12 @@ func (db *DB) UpdateEntry(entry *models.Entry, key []byte) error {
 		entry.Category = "general"
 	}
 
-	// Prepare data for encryption
-	data := EntryData{
-		Username: entry.Username,
-		Password: entry.Password,
-		URL:      entry.URL,
-		Notes:    entry.Notes,
-		Tags:     entry.Tags,
-	}
-
-	// Serialize to JSON
-	dataJSON, err := json.Marshal(data)
+	// Encrypt data, and metadata and notes under the fields key
+	sealed, err := sealEntry(entry, key)
 	if err != nil {
-		return fmt.Errorf("failed to marshal entry data: %w", err)
-	}
-
-	// Encrypt data
-	encryptedData, err := crypto.Encrypt(dataJSON, key)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt entry data: %w", err)
+		return err
 	}
+	encryptedData := sealed.data
 
 	// Generate and encrypt search text
 	searchText := entry.SearchText() + " " + entry.Username + " " + entry.URL
@@ -315,17 +412,35 @@ func (db *DB) UpdateEntry(entry *models.Entry, key []byte) error {
 	dataNonce := encryptedData[:12]
 	searchNonce := encryptedSearch[:12]
 
+	// Decide which metadata is duplicated in plaintext
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return err
+	}
+	cols := plaintextFor(entry, level)
+
 	// Update database
 	query := `
 		UPDATE entries
-		SET name = ?, category = ?, encrypted_data = ?, encrypted_search = ?,
-		    updated_at = ?, encryption_nonce = ?, search_nonce = ?
+		SET name = ?, category = ?, url_host = ?, username = ?, encrypted_data = ?, encrypted_search = ?,
+		    encrypted_fields = ?, updated_at = ?, encryption_nonce = ?, search_nonce = ?
 		WHERE id = ?
 	`
 
-	result, err := db.Exec(query,
-		entry.Name, entry.Category, encryptedData, encryptedSearch,
-		entry.UpdatedAt, dataNonce, searchNonce, entry.ID,
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Keep the previous version before overwriting it
+	if err := archiveEntry(tx, entry.ID, entry.UpdatedAt); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(query,
+		entry.Name, cols.category, cols.urlHost, cols.username, encryptedData, encryptedSearch,
+		sealed.fields, entry.UpdatedAt, dataNonce, searchNonce, entry.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update entry: %w", err)
@@ -340,7 +455,24 @@ func (db *DB) UpdateEntry(entry *models.Entry, key []byte) error {
 		return fmt.Errorf("entry with ID %s not found", entry.ID)
 	}
 
-	return nil
+	// Keep the tag index in sync with the encrypted data
+	if err := setEntryTags(tx, entry.ID, cols.tags); err != nil {
+		return err
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit entry update: %w", err)
+	}
+
+	if err := db.verifyWrite(entry.ID, sealed, searchTextBytes, key); err != nil {
+		return err
+	}
+
+	// Drop the versions the retention no longer covers
+	return db.applyRetention(entry, key)
 }
 
 // DeleteEntry removes an entry from the database
@@ -350,8 +482,14 @@ func (db *DB) DeleteEntry(id string) error {
 		return errors.New("entry ID cannot be empty")
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := "DELETE FROM entries WHERE id = ?"
-	result, err := db.Exec(query, id)
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete entry: %w", err)
 	}
@@ -365,9 +503,30 @@ func (db *DB) DeleteEntry(id string) error {
 		return fmt.Errorf("entry with ID %s not found", id)
 	}
 
+	// entry_tags rows are removed by ON DELETE CASCADE; drop tags left unused
+	if err := pruneTags(tx); err != nil {
+		return err
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit entry deletion: %w", err)
+	}
 	return nil
 }
 
+// EntryExists reports whether an entry with the given name exists
+func (db *DB) EntryExists(name string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM entries WHERE name = ?"
+	if err := db.QueryRow(query, name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check entry name: %w", err)
+	}
+	return count > 0, nil
+}
+
 // CountEntries returns the total number of entries
 func (db *DB) CountEntries() (int, error) {
 	var count int
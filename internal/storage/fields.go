@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// MetadataKeyFieldsSealed marks vaults whose entries all carry a fields blob
+// (or are high-security entries that never do)
+const MetadataKeyFieldsSealed = "fields_sealed"
+
+// ErrFieldsNotDisclosed is returned when an entry's metadata and notes can
+// only be read with the vault key: high-security entries, and entries not
+// rewritten since the fields key was introduced
+var ErrFieldsNotDisclosed = errors.New("entry details need the master password")
+
+// sealedEntry holds the ciphertexts stored for an entry
+type sealedEntry struct {
+	dataJSON, data     []byte
+	fieldsJSON, fields []byte // nil when everything stays under the vault key
+}
+
+// sealEntry encrypts an entry for storing
+// The data blob under the vault key holds everything but the notes; the
+// fields blob under the fields key (crypto.FieldsKey) holds the metadata and
+// the notes, so notes are never encrypted under the key that opens
+// passwords. High-security entries keep their notes in the data blob and
+// have no fields blob, so nothing of them is disclosed
+func sealEntry(entry *models.Entry, key []byte) (*sealedEntry, error) {
+	data := newEntryData(entry)
+	fields := data
+	fields.Password = ""
+	fields.OTP = ""
+	disclosed := !entry.IsHighSecurity()
+	if disclosed {
+		data.Notes = ""
+	}
+
+	sealed := &sealedEntry{}
+	var err error
+	if sealed.dataJSON, err = json.Marshal(data); err != nil {
+		return nil, fmt.Errorf("failed to marshal entry data: %w", err)
+	}
+	if sealed.data, err = crypto.Encrypt(sealed.dataJSON, key); err != nil {
+		return nil, fmt.Errorf("failed to encrypt entry data: %w", err)
+	}
+	if !disclosed {
+		return sealed, nil
+	}
+
+	if sealed.fieldsJSON, err = json.Marshal(fields); err != nil {
+		return nil, fmt.Errorf("failed to marshal entry fields: %w", err)
+	}
+	if sealed.fields, err = crypto.Encrypt(sealed.fieldsJSON, crypto.FieldsKey(key)); err != nil {
+		return nil, fmt.Errorf("failed to encrypt entry fields: %w", err)
+	}
+	return sealed, nil
+}
+
+// openFields decrypts a fields blob with the fields key
+func openFields(encryptedFields, fieldsKey []byte) (*EntryData, error) {
+	plaintext, err := crypto.Decrypt(encryptedFields, fieldsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt entry fields: %w", err)
+	}
+
+	var fields EntryData
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry fields: %w", err)
+	}
+	return &fields, nil
+}
+
+// GetEntryFields returns an entry's metadata and notes, decrypted with the
+// fields key alone; Password and OTP are always empty
+// Fails with ErrFieldsNotDisclosed for entries without a fields blob
+func (db *DB) GetEntryFields(id string, fieldsKey []byte) (*models.Entry, error) {
+	if id == "" {
+		return nil, errors.New("entry ID cannot be empty")
+	}
+	if len(fieldsKey) != 32 {
+		return nil, errors.New("fields key must be 32 bytes")
+	}
+
+	query := `
+		SELECT id, name, category, encrypted_fields, created_at, updated_at
+		FROM entries
+		WHERE id = ?
+	`
+
+	var entry models.Entry
+	var encryptedFields []byte
+	err := db.QueryRow(query, id).Scan(
+		&entry.ID, &entry.Name, &entry.Category, &encryptedFields,
+		&entry.CreatedAt, &entry.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("entry with ID %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to query entry: %w", err)
+	}
+	if encryptedFields == nil {
+		return nil, ErrFieldsNotDisclosed
+	}
+
+	fields, err := openFields(encryptedFields, fieldsKey)
+	if err != nil {
+		return nil, err
+	}
+	applyEntryData(&entry, fields)
+	return &entry, nil
+}
+
+// GetEntryFieldsByName is GetEntryFields by entry name
+// In uniform lookup mode every failure is ErrEntryUnavailable
+func (db *DB) GetEntryFieldsByName(name string, fieldsKey []byte) (*models.Entry, error) {
+	if name == "" {
+		return nil, errors.New("entry name cannot be empty")
+	}
+
+	if db.uniformLookup {
+		defer padLookup(time.Now())
+	}
+
+	var id string
+	err := db.QueryRow("SELECT id FROM entries WHERE name = ?", name).Scan(&id)
+	if err == nil {
+		var entry *models.Entry
+		entry, err = db.GetEntryFields(id, fieldsKey)
+		if err == nil {
+			return entry, nil
+		}
+	}
+
+	switch {
+	case db.uniformLookup:
+		return nil, ErrEntryUnavailable
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("entry with name %s not found", name)
+	default:
+		return nil, err
+	}
+}
+
+// FieldsSealed reports whether every entry that can have a fields blob has
+// one. Vaults written before the fields key existed need a one-time
+// SealFields
+func (db *DB) FieldsSealed() (bool, error) {
+	value, err := db.GetMetadata(MetadataKeyFieldsSealed)
+	if err == nil {
+		return value == "1", nil
+	}
+
+	count, err := db.CountEntries()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	// An empty vault has nothing to rewrite
+	if err := db.SetMetadata(MetadataKeyFieldsSealed, "1"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SealFields rewrites entries stored before the fields key existed, moving
+// their notes under it, in a single transaction. History versions are left
+// as they are. Entry timestamps are kept
+func (db *DB) SealFields(key []byte) error {
+	rows, err := db.Query("SELECT id FROM entries WHERE encrypted_fields IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query entries: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan entry: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	var entries []*models.Entry
+	for _, id := range ids {
+		entry, err := db.GetEntry(id, key)
+		if err != nil {
+			return err
+		}
+		if !entry.IsHighSecurity() {
+			entries = append(entries, entry)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Moving the notes is not an edit; keep updated_at as it is
+	if _, err := tx.Exec("DROP TRIGGER IF EXISTS update_entries_timestamp"); err != nil {
+		return fmt.Errorf("failed to suspend timestamp trigger: %w", err)
+	}
+
+	for _, entry := range entries {
+		sealed, err := sealEntry(entry, key)
+		if err != nil {
+			return err
+		}
+		query := `
+			UPDATE entries
+			SET encrypted_data = ?, encryption_nonce = ?, encrypted_fields = ?
+			WHERE id = ?
+		`
+		if _, err := tx.Exec(query, sealed.data, sealed.data[:12], sealed.fields, entry.ID); err != nil {
+			return fmt.Errorf("failed to seal fields of %s: %w", entry.Name, err)
+		}
+	}
+
+	if _, err := tx.Exec(entriesTimestampTrigger); err != nil {
+		return fmt.Errorf("failed to restore timestamp trigger: %w", err)
+	}
+	query := `
+		INSERT INTO metadata (key, value) VALUES (?, '1')
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`
+	if _, err := tx.Exec(query, MetadataKeyFieldsSealed); err != nil {
+		return fmt.Errorf("failed to mark fields as sealed: %w", err)
+	}
+	if len(entries) > 0 {
+		if err := db.resealIn(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sealed fields: %w", err)
+	}
+	return nil
+}
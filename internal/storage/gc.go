@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// GCOptions selects what CollectGarbage removes
+type GCOptions struct {
+	// Retention replaces the vault's history retention and per-entry
+	// overrides; nil applies them
+	Retention *models.Retention
+
+	// DryRun only reports what would be removed
+	DryRun bool
+}
+
+// GCReport counts what CollectGarbage removed, or would remove in a dry run
+type GCReport struct {
+	HistoryVersions int   // versions the retention no longer covers
+	HistoryEntries  int   // entries that lost versions
+	HistoryBytes    int64 // encrypted bytes of those versions
+	KeptForAge      int   // older versions kept to date the last password change
+
+	OrphanedHistory int // versions of entries that no longer exist
+	OrphanedAccess  int // access records of entries that no longer exist
+	OrphanedTags    int // tags no entry uses
+	OrphanedGPGKeys int // GPG key bindings of deleted entries
+
+	SizeBefore int64 // vault bytes before
+	SizeAfter  int64 // vault bytes after vacuuming; estimated in a dry run
+}
+
+// Removed reports whether anything was (or would be) removed
+func (r *GCReport) Removed() bool {
+	return r.HistoryVersions+r.OrphanedHistory+r.OrphanedAccess+r.OrphanedTags+r.OrphanedGPGKeys > 0
+}
+
+// orphanQueries count and delete rows left behind by deleted entries
+// Foreign keys normally cascade; these catch vaults edited with foreign
+// keys off, e.g. by the sqlite3 shell
+var orphanQueries = []struct {
+	count, delete string
+	field         func(*GCReport) *int
+}{
+	{
+		"SELECT COUNT(*) FROM entry_history WHERE entry_id NOT IN (SELECT id FROM entries)",
+		"DELETE FROM entry_history WHERE entry_id NOT IN (SELECT id FROM entries)",
+		func(r *GCReport) *int { return &r.OrphanedHistory },
+	},
+	{
+		"SELECT COUNT(*) FROM entry_access WHERE entry_id NOT IN (SELECT id FROM entries)",
+		"DELETE FROM entry_access WHERE entry_id NOT IN (SELECT id FROM entries)",
+		func(r *GCReport) *int { return &r.OrphanedAccess },
+	},
+	{
+		"SELECT COUNT(*) FROM tags WHERE id NOT IN (SELECT DISTINCT tag_id FROM entry_tags WHERE entry_id IN (SELECT id FROM entries))",
+		"DELETE FROM entry_tags WHERE entry_id NOT IN (SELECT id FROM entries)",
+		func(r *GCReport) *int { return &r.OrphanedTags },
+	},
+}
+
+// CollectGarbage prunes entry history the retention no longer covers,
+// removes rows and bindings left behind by deleted entries and vacuums the
+// file, returning what was removed. key decrypts the history: the version
+// replaced by the current password is always kept so password ages stay
+// right
+func (db *DB) CollectGarbage(key []byte, opts GCOptions) (*GCReport, error) {
+	report := &GCReport{}
+	var err error
+	if report.SizeBefore, err = db.Size(); err != nil {
+		return nil, err
+	}
+
+	prune, err := db.prunableHistory(key, opts.Retention, report)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range orphanQueries {
+		if err := db.QueryRow(q.count).Scan(q.field(report)); err != nil {
+			return nil, fmt.Errorf("failed to count orphaned rows: %w", err)
+		}
+	}
+
+	bindings, err := db.GPGKeys()
+	if err != nil {
+		return nil, err
+	}
+	kept := bindings[:0:0]
+	for _, binding := range bindings {
+		var exists int
+		if err := db.QueryRow("SELECT COUNT(*) FROM entries WHERE id = ?", binding.EntryID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check GPG key binding: %w", err)
+		}
+		if exists > 0 {
+			kept = append(kept, binding)
+		}
+	}
+	report.OrphanedGPGKeys = len(bindings) - len(kept)
+
+	if opts.DryRun {
+		var free, pageSize int64
+		db.QueryRow("PRAGMA freelist_count").Scan(&free)
+		db.QueryRow("PRAGMA page_size").Scan(&pageSize)
+		report.SizeAfter = max(report.SizeBefore-free*pageSize-report.HistoryBytes, 0)
+		return report, nil
+	}
+
+	if report.Removed() {
+		if err := db.removeGarbage(prune); err != nil {
+			return nil, err
+		}
+		if report.OrphanedGPGKeys > 0 {
+			if err := db.saveGPGKeys(kept); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return nil, fmt.Errorf("failed to vacuum vault: %w", err)
+	}
+	if report.SizeAfter, err = db.Size(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// prunableHistory returns the IDs of history versions the retention no
+// longer covers, adding them to report; override replaces the vault's and
+// the entries' retention
+func (db *DB) prunableHistory(key []byte, override *models.Retention, report *GCReport) ([]int64, error) {
+	vault, err := db.HistoryRetention()
+	if err != nil {
+		return nil, err
+	}
+	if override != nil && override.Unlimited() {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT entry_id FROM entry_history
+		WHERE entry_id IN (SELECT id FROM entries)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry history: %w", err)
+	}
+	var entryIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan entry history: %w", err)
+		}
+		entryIDs = append(entryIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry history: %w", err)
+	}
+
+	now := time.Now()
+	var prune []int64
+	for _, id := range entryIDs {
+		entry, err := db.GetEntry(id, key)
+		if err != nil {
+			return nil, err
+		}
+		retention := retentionFor(entry, vault)
+		if override != nil {
+			retention = *override
+		}
+		if retention.Unlimited() {
+			continue
+		}
+
+		versions, err := db.ListHistory(id, key)
+		if err != nil {
+			return nil, err
+		}
+		expired, keptForAge := expiredVersions(entry, versions, retention, now)
+		if keptForAge {
+			report.KeptForAge++
+		}
+		if len(expired) == 0 {
+			continue
+		}
+
+		for _, version := range expired {
+			var size int64
+			query := "SELECT length(encrypted_data) + COALESCE(length(encrypted_fields), 0) FROM entry_history WHERE id = ?"
+			if err := db.QueryRow(query, version.ID).Scan(&size); err != nil {
+				return nil, fmt.Errorf("failed to size history version: %w", err)
+			}
+			prune = append(prune, version.ID)
+			report.HistoryBytes += size
+		}
+		report.HistoryEntries++
+	}
+	report.HistoryVersions = len(prune)
+	return prune, nil
+}
+
+// removeGarbage deletes pruned history versions and orphaned rows in a
+// single transaction
+func (db *DB) removeGarbage(prune []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range prune {
+		if _, err := tx.Exec("DELETE FROM entry_history WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to prune history version: %w", err)
+		}
+	}
+	for _, q := range orphanQueries {
+		if _, err := tx.Exec(q.delete); err != nil {
+			return fmt.Errorf("failed to remove orphaned rows: %w", err)
+		}
+	}
+	if err := pruneTags(tx); err != nil {
+		return err
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit garbage collection: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MetadataKeyGPGKeys stores which entries hold the passphrases of GPG keys
+const MetadataKeyGPGKeys = "gpg_keys"
+
+// GPGKey binds a GPG (sub)key, identified by its keygrip as gpg-agent sends
+// it to pinentry, to the entry holding its passphrase
+type GPGKey struct {
+	Keygrip string `json:"keygrip"`
+	KeyID   string `json:"key_id"` // key the user named, for display
+	EntryID string `json:"entry_id"`
+}
+
+// GPGKeys returns all bindings sorted by key ID
+func (db *DB) GPGKeys() ([]GPGKey, error) {
+	var keys []GPGKey
+	if _, err := db.loadListedMetadata(MetadataKeyGPGKeys, &keys); err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].KeyID != keys[j].KeyID {
+			return keys[i].KeyID < keys[j].KeyID
+		}
+		return keys[i].Keygrip < keys[j].Keygrip
+	})
+	return keys, nil
+}
+
+// GPGKeyEntry returns the ID of the entry bound to a keygrip, "" if none
+func (db *DB) GPGKeyEntry(keygrip string) (string, error) {
+	keys, err := db.GPGKeys()
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if strings.EqualFold(key.Keygrip, keygrip) {
+			return key.EntryID, nil
+		}
+	}
+	return "", nil
+}
+
+// SaveGPGKeys adds or replaces bindings by keygrip
+func (db *DB) SaveGPGKeys(bindings []GPGKey) error {
+	keys, err := db.GPGKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, binding := range bindings {
+		replaced := false
+		for i := range keys {
+			if strings.EqualFold(keys[i].Keygrip, binding.Keygrip) {
+				keys[i] = binding
+				replaced = true
+			}
+		}
+		if !replaced {
+			keys = append(keys, binding)
+		}
+	}
+	return db.saveGPGKeys(keys)
+}
+
+// RemoveGPGKeys drops the bindings of an entry and returns how many there were
+func (db *DB) RemoveGPGKeys(entryID string) (int, error) {
+	keys, err := db.GPGKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := keys[:0]
+	for _, key := range keys {
+		if key.EntryID != entryID {
+			kept = append(kept, key)
+		}
+	}
+	removed := len(keys) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, db.saveGPGKeys(kept)
+}
+
+func (db *DB) saveGPGKeys(keys []GPGKey) error {
+	if len(keys) == 0 {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyGPGKeys); err != nil {
+			return fmt.Errorf("failed to clear GPG key bindings: %w", err)
+		}
+		return nil
+	}
+	return db.saveListedMetadata(MetadataKeyGPGKeys, keys)
+}
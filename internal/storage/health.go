@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MetadataKeyHealthScores stores past vault health scores ('gpasswd score')
+// Only the scores are kept, no counts or entry names
+const MetadataKeyHealthScores = "health_scores"
+
+// maxHealthScores bounds the history; older scores are dropped
+const maxHealthScores = 366
+
+// HealthScoreRecord is the vault health score on one day
+type HealthScoreRecord struct {
+	Date  string `json:"date"` // YYYY-MM-DD, local time
+	Score int    `json:"score"`
+}
+
+// HealthScores returns the recorded scores, oldest first
+func (db *DB) HealthScores() ([]HealthScoreRecord, error) {
+	value, err := db.GetMetadata(MetadataKeyHealthScores)
+	if err != nil {
+		return nil, nil
+	}
+
+	var scores []HealthScoreRecord
+	if err := json.Unmarshal([]byte(value), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse health scores: %w", err)
+	}
+	return scores, nil
+}
+
+// RecordHealthScore stores the score of the day at, replacing an earlier
+// score of the same day
+func (db *DB) RecordHealthScore(at time.Time, score int) error {
+	scores, err := db.HealthScores()
+	if err != nil {
+		return err
+	}
+
+	record := HealthScoreRecord{Date: at.Format("2006-01-02"), Score: score}
+	if n := len(scores); n > 0 && scores[n-1].Date == record.Date {
+		scores[n-1] = record
+	} else {
+		scores = append(scores, record)
+	}
+	if len(scores) > maxHealthScores {
+		scores = scores[len(scores)-maxHealthScores:]
+	}
+
+	jsonData, err := json.Marshal(scores)
+	if err != nil {
+		return fmt.Errorf("failed to marshal health scores: %w", err)
+	}
+	return db.SetMetadata(MetadataKeyHealthScores, string(jsonData))
+}
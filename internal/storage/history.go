@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// HistoryVersion is a previous version of an entry
+// The version was current from ValidFrom until ValidTo
+type HistoryVersion struct {
+	ID        int64
+	Entry     *models.Entry
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+// archiveEntry copies the current row of an entry into entry_history
+// Must be called in the same transaction as the update that replaces it
+func archiveEntry(tx execer, entryID string, archivedAt time.Time) error {
+	query := `
+		INSERT INTO entry_history (entry_id, name, category, encrypted_data, encrypted_fields, valid_from, valid_to)
+		SELECT id, name, category, encrypted_data, encrypted_fields, updated_at, ?
+		FROM entries
+		WHERE id = ?
+	`
+
+	if _, err := tx.Exec(query, archivedAt, entryID); err != nil {
+		return fmt.Errorf("failed to archive entry version: %w", err)
+	}
+	return nil
+}
+
+// ListHistory returns the previous versions of an entry, newest first
+func (db *DB) ListHistory(entryID string, key []byte) ([]*HistoryVersion, error) {
+	if entryID == "" {
+		return nil, errors.New("entry ID cannot be empty")
+	}
+	if key == nil || len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+
+	query := `
+		SELECT id, name, category, encrypted_data, encrypted_fields, valid_from, valid_to
+		FROM entry_history
+		WHERE entry_id = ?
+		ORDER BY valid_to DESC, id DESC
+	`
+
+	rows, err := db.Query(query, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry history: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*HistoryVersion
+	for rows.Next() {
+		version := &HistoryVersion{Entry: &models.Entry{ID: entryID}}
+		var encryptedData, encryptedFields []byte
+
+		if err := rows.Scan(&version.ID, &version.Entry.Name, &version.Entry.Category,
+			&encryptedData, &encryptedFields, &version.ValidFrom, &version.ValidTo); err != nil {
+			return nil, fmt.Errorf("failed to scan history version: %w", err)
+		}
+
+		if err := decryptInto(version.Entry, encryptedData, encryptedFields, key); err != nil {
+			return nil, err
+		}
+		version.Entry.UpdatedAt = version.ValidFrom
+
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry history: %w", err)
+	}
+
+	return versions, nil
+}
+
+// ErrNotYetCreated is returned by EntryAsOf for a time before the entry existed
+var ErrNotYetCreated = errors.New("entry did not exist yet")
+
+// EntryAsOf returns the version of entry that was current at the given time
+// entry must be the current version. Deleted entries take their history
+// with them, so only entries still in the vault can be looked up
+func (db *DB) EntryAsOf(entry *models.Entry, at time.Time, key []byte) (*models.Entry, error) {
+	if at.Before(entry.CreatedAt) {
+		return nil, ErrNotYetCreated
+	}
+	if !at.Before(entry.UpdatedAt) {
+		return entry, nil
+	}
+
+	versions, err := db.ListHistory(entry.ID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first; fall back to the oldest version still current after at
+	// in case the recorded periods leave a gap
+	var match *HistoryVersion
+	for _, version := range versions {
+		if !at.Before(version.ValidTo) {
+			break
+		}
+		match = version
+		if !at.Before(version.ValidFrom) {
+			break
+		}
+	}
+	if match == nil {
+		return entry, nil
+	}
+
+	version := match.Entry
+	version.CreatedAt = entry.CreatedAt
+	return version, nil
+}
+
+// CountHistory returns the number of archived versions of an entry
+func (db *DB) CountHistory(entryID string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM entry_history WHERE entry_id = ?"
+	if err := db.QueryRow(query, entryID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count entry history: %w", err)
+	}
+	return count, nil
+}
+
+// decryptInto decrypts an entry blob and fills in the entry's secret fields
+// The notes come from the fields blob, when the entry has one
+func decryptInto(entry *models.Entry, encryptedData, encryptedFields, key []byte) error {
+	decryptedData, err := crypto.Decrypt(encryptedData, key)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt entry data: %w", err)
+	}
+
+	var data EntryData
+	if err := json.Unmarshal(decryptedData, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal entry data: %w", err)
+	}
+
+	if encryptedFields != nil {
+		fields, err := openFields(encryptedFields, crypto.FieldsKey(key))
+		if err != nil {
+			return err
+		}
+		data.Notes = fields.Notes
+	}
+
+	applyEntryData(entry, &data)
+	return nil
+}
+
+// applyEntryData fills in an entry from decrypted data
+func applyEntryData(entry *models.Entry, data *EntryData) {
+	entry.Username = data.Username
+	entry.Password = data.Password
+	entry.URL = data.URL
+	entry.Notes = data.Notes
+	entry.Tags = data.Tags
+	entry.Icon = data.Icon
+	entry.ChangePasswordURL = data.ChangePasswordURL
+	entry.OTP = data.OTP
+	entry.Type = data.Type
+	entry.Service = data.Service
+	entry.ExpiresAt = data.ExpiresAt
+	entry.Scopes = data.Scopes
+	entry.KeyType = data.KeyType
+	entry.PublicKey = data.PublicKey
+	entry.SecurityLevel = data.SecurityLevel
+	entry.ListableUsername = data.ListableUsername
+	entry.LoginSteps = data.LoginSteps
+	entry.HistoryRetention = data.HistoryRetention
+
+	// The blob is authoritative; the plaintext column may be blank
+	// depending on the privacy level
+	if data.Category != "" {
+		entry.Category = data.Category
+	}
+}
+
+// PasswordChangedAt returns when the entry's current password was set
+// Edits that leave the password unchanged do not count
+func (db *DB) PasswordChangedAt(entry *models.Entry, key []byte) (time.Time, error) {
+	versions, err := db.ListHistory(entry.ID, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, version := range versions {
+		if version.Entry.Password != entry.Password {
+			return version.ValidTo, nil
+		}
+	}
+	return entry.CreatedAt, nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// ErrEntryUnavailable is the only error returned by name lookups in uniform
+// mode, whether the entry is missing or could not be decrypted
+var ErrEntryUnavailable = errors.New("entry not found or not readable")
+
+// SetUniformLookup enables hardened name resolution for GetEntryByName
+// Every row is scanned and compared in constant time, one decryption is
+// always attempted (a decoy when nothing matches) and all failures return
+// ErrEntryUnavailable, so neither the error nor the timing tells a local
+// observer whether a name exists
+func (db *DB) SetUniformLookup(enabled bool) {
+	db.uniformLookup = enabled
+}
+
+// getEntryByNameUniform implements GetEntryByName in uniform lookup mode
+func (db *DB) getEntryByNameUniform(name string, key []byte) (*models.Entry, error) {
+	rows, err := db.Query(`
+		SELECT id, name, category, encrypted_data, encrypted_fields, created_at, updated_at
+		FROM entries
+	`)
+	if err != nil {
+		return nil, ErrEntryUnavailable
+	}
+	defer rows.Close()
+
+	want := sha256.Sum256([]byte(name))
+
+	var (
+		match         models.Entry
+		matched       []byte
+		matchedFields []byte
+		decoy         []byte
+	)
+	for rows.Next() {
+		var (
+			entry                          models.Entry
+			encryptedData, encryptedFields []byte
+		)
+		if err := rows.Scan(
+			&entry.ID, &entry.Name, &entry.Category, &encryptedData, &encryptedFields,
+			&entry.CreatedAt, &entry.UpdatedAt,
+		); err != nil {
+			return nil, ErrEntryUnavailable
+		}
+
+		// Hashing first makes the comparison independent of name length
+		got := sha256.Sum256([]byte(entry.Name))
+		if subtle.ConstantTimeCompare(got[:], want[:]) == 1 {
+			match = entry
+			matched = encryptedData
+			matchedFields = encryptedFields
+		}
+		if decoy == nil {
+			decoy = encryptedData
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, ErrEntryUnavailable
+	}
+
+	if matched == nil {
+		// Spend a decryption anyway; an empty vault gets random bytes
+		if decoy == nil {
+			decoy = make([]byte, 128)
+			rand.Read(decoy)
+		}
+		var discard models.Entry
+		_ = decryptInto(&discard, decoy, nil, key)
+		return nil, ErrEntryUnavailable
+	}
+
+	if err := decryptInto(&match, matched, matchedFields, key); err != nil {
+		return nil, ErrEntryUnavailable
+	}
+	return &match, nil
+}
+
+// uniformLookupMinDuration is the floor for a uniform lookup, masking the
+// remaining difference between a successful and a decoy decryption
+const uniformLookupMinDuration = 50 * time.Millisecond
+
+// padLookup sleeps until at least uniformLookupMinDuration has passed since start
+func padLookup(start time.Time) {
+	padUntil(start, uniformLookupMinDuration)
+}
+
+// padUntil sleeps until at least d has passed since start
+func padUntil(start time.Time, d time.Duration) {
+	if remaining := d - time.Since(start); remaining > 0 {
+		time.Sleep(remaining)
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// MetadataKeyManifest stores the signed vault manifest
+const MetadataKeyManifest = "manifest"
+
+var (
+	// ErrNoManifest is returned for vaults from before manifests existed,
+	// which were never sealed
+	ErrNoManifest = errors.New("vault has no manifest")
+
+	// ErrTampered is returned when the vault no longer matches its manifest
+	ErrTampered = errors.New("vault was modified outside gpasswd")
+)
+
+// fingerprintedMetadata are the metadata keys covered by the fingerprint:
+// everything needed to derive and check the key, and the privacy level
+// that decides what is stored in plaintext
+var fingerprintedMetadata = []string{
+	MetadataKeySalt,
+	MetadataKeyArgon2Params,
+	MetadataKeyKeyVerifier,
+	MetadataKeyPrivacyLevel,
+}
+
+// manifest is the value stored under MetadataKeyManifest
+type manifest struct {
+	Version int    `json:"version"`
+	Entries int    `json:"entries"`
+	MAC     string `json:"mac"` // HMAC-SHA256 of the fingerprint, base64
+}
+
+// Fingerprint returns a stable digest over every entry and history
+// ciphertext, the plaintext entry columns and the key metadata
+// It changes with every write and needs no key, so two copies of a vault
+// can be compared by eye
+func (db *DB) Fingerprint() ([]byte, error) {
+	return fingerprint(db.DB)
+}
+
+// fingerprint computes the fingerprint as q sees the vault, so a write
+// transaction can reseal the manifest before it commits
+func fingerprint(q querier) ([]byte, error) {
+	h := sha256.New()
+	writeField(h, []byte("gpasswd fingerprint v1"))
+
+	for _, key := range fingerprintedMetadata {
+		var value string
+		err := q.QueryRow("SELECT value FROM metadata WHERE key = ?", key).Scan(&value)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to read metadata %s: %w", key, err)
+		}
+		writeField(h, []byte(key))
+		writeField(h, []byte(value))
+	}
+
+	rows, err := q.Query(`
+		SELECT id, name, category, url_host, username, encrypted_data, encrypted_fields
+		FROM entries
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name, category, urlHost, username string
+		var encryptedData, encryptedFields []byte
+		if err := rows.Scan(&id, &name, &category, &urlHost, &username, &encryptedData, &encryptedFields); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		writeField(h, []byte("entry"))
+		for _, field := range [][]byte{[]byte(id), []byte(name), []byte(category), []byte(urlHost), encryptedData} {
+			writeField(h, field)
+		}
+		// Only listable entries hash the column, so fingerprints of
+		// vaults that don't use it are unchanged
+		if username != "" {
+			writeField(h, []byte("username"))
+			writeField(h, []byte(username))
+		}
+		writeFields(h, encryptedFields)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+	rows.Close()
+
+	history, err := q.Query(`
+		SELECT id, entry_id, encrypted_data, encrypted_fields
+		FROM entry_history
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry history: %w", err)
+	}
+	defer history.Close()
+
+	for history.Next() {
+		var id int64
+		var entryID string
+		var encryptedData, encryptedFields []byte
+		if err := history.Scan(&id, &entryID, &encryptedData, &encryptedFields); err != nil {
+			return nil, fmt.Errorf("failed to scan history version: %w", err)
+		}
+		writeField(h, []byte("history"))
+		writeField(h, binary.BigEndian.AppendUint64(nil, uint64(id)))
+		writeField(h, []byte(entryID))
+		writeField(h, encryptedData)
+		writeFields(h, encryptedFields)
+	}
+	if err := history.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry history: %w", err)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// writeFields hashes a fields blob; rows without one hash nothing, so
+// fingerprints of vaults written before fields blobs are unchanged
+func writeFields(h hash.Hash, encryptedFields []byte) {
+	if encryptedFields != nil {
+		writeField(h, []byte("fields"))
+		writeField(h, encryptedFields)
+	}
+}
+
+// writeField writes a length-prefixed field so field boundaries can't shift
+func writeField(h hash.Hash, field []byte) {
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+	h.Write(field)
+}
+
+// SealManifest signs the current fingerprint with a key derived from the
+// vault key and stores it, accepting the vault's current state
+// Vaults from before manifests get a key verifier first, so from now on a
+// missing manifest counts as tampering
+func (db *DB) SealManifest(key []byte) error {
+	expected, err := db.manifestExpected(key)
+	if err != nil {
+		return err
+	}
+	if !expected {
+		if err := db.SetKeyVerifier(key); err != nil {
+			return err
+		}
+	}
+
+	if err := writeManifest(db.DB, key); err != nil {
+		return err
+	}
+	db.tampered = false
+	return nil
+}
+
+// resealIn updates the manifest within a write transaction, so the vault
+// never commits a state its manifest doesn't cover. Nothing is written
+// before the vault is unlocked or once it was found tampered, which is
+// left for the user to reseal
+func (db *DB) resealIn(tx querier) error {
+	if db.key == nil || db.tampered {
+		return nil
+	}
+	return writeManifest(tx, db.key)
+}
+
+// writeManifest stores the manifest of the vault as q sees it
+func writeManifest(q querier, key []byte) error {
+	mac, err := manifestMAC(q, key)
+	if err != nil {
+		return err
+	}
+	var count int
+	if err := q.QueryRow("SELECT COUNT(*) FROM entries").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	value, err := json.Marshal(manifest{Version: 1, Entries: count, MAC: base64.StdEncoding.EncodeToString(mac)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	query := `
+		INSERT INTO metadata (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`
+	if _, err := q.Exec(query, MetadataKeyManifest, string(value)); err != nil {
+		return fmt.Errorf("failed to store manifest: %w", err)
+	}
+	return nil
+}
+
+// VerifyManifest checks the vault against its manifest
+// Returns ErrNoManifest if the vault predates manifests and ErrTampered if
+// entries, history or key metadata changed without the manifest being
+// updated (edited, deleted or rolled back rows), including a sealed vault
+// whose manifest was deleted. A tampered vault is not resealed by later
+// writes; see SealManifest
+func (db *DB) VerifyManifest(key []byte) error {
+	expected, err := db.manifestExpected(key)
+	if err != nil {
+		return err
+	}
+
+	value, err := db.GetMetadata(MetadataKeyManifest)
+	if err != nil {
+		if expected {
+			db.tampered = true
+			return ErrTampered
+		}
+		return ErrNoManifest
+	}
+
+	var stored manifest
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		db.tampered = true
+		return ErrTampered
+	}
+	storedMAC, err := base64.StdEncoding.DecodeString(stored.MAC)
+	if err != nil {
+		db.tampered = true
+		return ErrTampered
+	}
+
+	mac, err := manifestMAC(db.DB, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, storedMAC) {
+		db.tampered = true
+		return ErrTampered
+	}
+
+	// A manifest without a verifier was written before the vault was
+	// first sealed; add the verifier so it can't be dropped unnoticed
+	if !expected {
+		return db.SealManifest(key)
+	}
+	return nil
+}
+
+// manifestMAC authenticates the fingerprint of the vault as q sees it
+func manifestMAC(q querier, key []byte) ([]byte, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+	fingerprint, err := fingerprint(q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Separate the MAC key from the encryption key
+	derive := hmac.New(sha256.New, key)
+	derive.Write([]byte("gpasswd manifest v1"))
+
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write(fingerprint)
+	return mac.Sum(nil), nil
+}
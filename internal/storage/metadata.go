@@ -6,16 +6,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/kitsnail/gpasswd/internal/crypto"
 )
 
 // Metadata keys
 const (
-	MetadataKeySalt          = "salt"
-	MetadataKeyArgon2Params  = "argon2_params"
-	MetadataKeyVersion       = "version"
-	MetadataKeyCreatedAt     = "created_at"
+	MetadataKeySalt         = "salt"
+	MetadataKeyArgon2Params = "argon2_params"
+	MetadataKeyVersion      = "version"
+	MetadataKeyCreatedAt    = "created_at"
+	MetadataKeyTagsIndexed  = "tags_indexed"
 )
 
 // SetMetadata stores a key-value pair in the metadata table
@@ -30,11 +32,29 @@ func (db *DB) SetMetadata(key, value string) error {
 		ON CONFLICT(key) DO UPDATE SET value = excluded.value
 	`
 
-	_, err := db.Exec(query, key, value)
+	if !slices.Contains(fingerprintedMetadata, key) {
+		if _, err := db.Exec(query, key, value); err != nil {
+			return fmt.Errorf("failed to set metadata %s: %w", key, err)
+		}
+		return nil
+	}
+
+	// Key metadata is fingerprinted; reseal the manifest with it
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to set metadata %s: %w", key, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
+	if _, err := tx.Exec(query, key, value); err != nil {
+		return fmt.Errorf("failed to set metadata %s: %w", key, err)
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit metadata %s: %w", key, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// MetadataKeyPrivacyLevel stores the vault's sensitive-metadata mode
+const MetadataKeyPrivacyLevel = "privacy_level"
+
+// PrivacyLevel controls which entry metadata is stored queryable in plaintext
+// Everything is always stored in the encrypted blob; the level only decides
+// what is duplicated into plaintext columns for fast lookups
+type PrivacyLevel string
+
+const (
+	// PrivacyOpen stores name, category, tags and URL host in plaintext
+	PrivacyOpen PrivacyLevel = "open"
+
+	// PrivacyStandard stores name, category and tags in plaintext (default)
+	PrivacyStandard PrivacyLevel = "standard"
+
+	// PrivacyPrivate stores only the entry name in plaintext
+	// Category and tag filtering require the master password
+	PrivacyPrivate PrivacyLevel = "private"
+)
+
+// PrivacyLevels lists the supported levels from most to least revealing
+var PrivacyLevels = []PrivacyLevel{PrivacyOpen, PrivacyStandard, PrivacyPrivate}
+
+// ParsePrivacyLevel validates a user-supplied privacy level
+func ParsePrivacyLevel(name string) (PrivacyLevel, error) {
+	for _, level := range PrivacyLevels {
+		if strings.EqualFold(name, string(level)) {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown privacy level %q (expected open, standard or private)", name)
+}
+
+// Description explains what the level stores in plaintext
+func (l PrivacyLevel) Description() string {
+	switch l {
+	case PrivacyOpen:
+		return "name, category, tags and URL host are stored in plaintext"
+	case PrivacyPrivate:
+		return "only the entry name is stored in plaintext"
+	default:
+		return "name, category and tags are stored in plaintext"
+	}
+}
+
+// PrivacyLevel returns the vault's privacy level (standard if never set)
+func (db *DB) PrivacyLevel() (PrivacyLevel, error) {
+	if db.privacy != "" {
+		return db.privacy, nil
+	}
+
+	value, err := db.GetMetadata(MetadataKeyPrivacyLevel)
+	if err != nil {
+		// Vaults created before privacy levels existed behave as standard
+		db.privacy = PrivacyStandard
+		return db.privacy, nil
+	}
+
+	level, err := ParsePrivacyLevel(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid privacy level in database: %w", err)
+	}
+
+	db.privacy = level
+	return level, nil
+}
+
+// plaintextColumns are the values duplicated outside the encrypted blob
+type plaintextColumns struct {
+	category string
+	urlHost  string
+	username string
+	tags     []string
+}
+
+// plaintextFor computes the plaintext columns for an entry at a privacy level
+// A listable username is kept at every level since the entry opted in, but
+// never for a high-security entry
+func plaintextFor(entry *models.Entry, level PrivacyLevel) plaintextColumns {
+	var cols plaintextColumns
+	switch level {
+	case PrivacyOpen:
+		cols = plaintextColumns{category: entry.Category, urlHost: URLHost(entry.URL), tags: entry.Tags}
+	case PrivacyPrivate:
+	default:
+		cols = plaintextColumns{category: entry.Category, tags: entry.Tags}
+	}
+	if entry.ListableUsername && !entry.IsHighSecurity() {
+		cols.username = entry.Username
+	}
+	return cols
+}
+
+// SetPrivacyLevel changes the vault's privacy level and rewrites the
+// plaintext columns of every entry and the listed metadata (see
+// listedMetadata) to match, in a single transaction
+func (db *DB) SetPrivacyLevel(level PrivacyLevel, key []byte) error {
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		cols := plaintextFor(entry, level)
+
+		// Re-encrypt so entries written before the category moved into the
+		// encrypted data keep it once the plaintext column is cleared
+		sealed, err := sealEntry(entry, key)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE entries
+			SET category = ?, url_host = ?, username = ?, encrypted_data = ?, encryption_nonce = ?, encrypted_fields = ?
+			WHERE id = ?
+		`
+		if _, err := tx.Exec(query, cols.category, cols.urlHost, cols.username, sealed.data, sealed.data[:12], sealed.fields, entry.ID); err != nil {
+			return fmt.Errorf("failed to rewrite metadata for %s: %w", entry.Name, err)
+		}
+
+		if err := setEntryTags(tx, entry.ID, cols.tags); err != nil {
+			return err
+		}
+	}
+
+	if err := resealListedMetadata(tx, key, key, level); err != nil {
+		return err
+	}
+
+	// Archived versions copied the category column at whatever level was
+	// set then; their blobs carry it too
+	if level == PrivacyPrivate {
+		if _, err := tx.Exec("UPDATE entry_history SET category = ''"); err != nil {
+			return fmt.Errorf("failed to clear history categories: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO metadata (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`
+	if _, err := tx.Exec(query, MetadataKeyPrivacyLevel, string(level)); err != nil {
+		return fmt.Errorf("failed to store privacy level: %w", err)
+	}
+	if _, err := tx.Exec(query, MetadataKeyTagsIndexed, "1"); err != nil {
+		return fmt.Errorf("failed to mark tags as indexed: %w", err)
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit privacy level change: %w", err)
+	}
+
+	db.privacy = level
+	return nil
+}
+
+// FindEntriesByHost returns entries whose URL host matches (open level only)
+func (db *DB) FindEntriesByHost(host string) ([]*models.Entry, error) {
+	query := `
+		SELECT id, name, category, created_at, updated_at
+		FROM entries
+		WHERE url_host = ?
+		ORDER BY name ASC
+	`
+
+	rows, err := db.Query(query, strings.ToLower(host))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries by host: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.Entry
+	for rows.Next() {
+		var entry models.Entry
+		if err := rows.Scan(&entry.ID, &entry.Name, &entry.Category, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// URLHost extracts the lower-cased host from a URL (scheme optional)
+func URLHost(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// MetadataKeyProfile stores the vault's resource profile
+const MetadataKeyProfile = "profile"
+
+// Profile tunes memory use for the machine the vault lives on
+type Profile string
+
+const (
+	// ProfileDefault suits desktops and laptops
+	ProfileDefault Profile = "default"
+
+	// ProfileLowMemory suits Raspberry Pi class and other small devices:
+	// 16 MB Argon2, a small page cache and no memory-mapped I/O
+	ProfileLowMemory Profile = "low-memory"
+)
+
+// Profiles lists the supported profiles
+var Profiles = []Profile{ProfileDefault, ProfileLowMemory}
+
+// ParseProfile validates a user-supplied profile name
+func ParseProfile(name string) (Profile, error) {
+	for _, profile := range Profiles {
+		if strings.EqualFold(name, string(profile)) {
+			return profile, nil
+		}
+	}
+	return "", fmt.Errorf("unknown profile %q (expected default or low-memory)", name)
+}
+
+// Argon2Params returns the key derivation preset of the profile, or false
+// if the profile leaves it to the configuration
+func (p Profile) Argon2Params() (crypto.Argon2Params, bool) {
+	if p != ProfileLowMemory {
+		return crypto.Argon2Params{}, false
+	}
+	// Less memory, more passes to keep the cost of guessing up
+	return crypto.Argon2Params{
+		Time:        4,
+		Memory:      16 * 1024, // 16 MB
+		Parallelism: 2,
+		KeyLen:      32,
+	}, true
+}
+
+// pragmas returns the cache and memory-map settings of the profile
+func (p Profile) pragmas() []string {
+	if p == ProfileLowMemory {
+		return []string{
+			"PRAGMA cache_size = -2000", // 2MB
+			"PRAGMA mmap_size = 0",
+		}
+	}
+
+	pragmas := []string{
+		// Increase cache size (negative value = KB)
+		"PRAGMA cache_size = -64000", // 64MB
+	}
+	// Memory-mapped I/O for better performance; 32-bit platforms don't
+	// have the address space to reserve for it
+	if strconv.IntSize == 64 {
+		pragmas = append(pragmas, "PRAGMA mmap_size = 30000000000") // 30GB
+	}
+	return pragmas
+}
+
+// Profile returns the vault's profile (default if never set)
+func (db *DB) Profile() (Profile, error) {
+	value, err := db.GetMetadata(MetadataKeyProfile)
+	if err != nil {
+		return ProfileDefault, nil
+	}
+
+	profile, err := ParseProfile(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid profile in database: %w", err)
+	}
+	return profile, nil
+}
+
+// SetProfile stores the vault's profile and applies its settings
+func (db *DB) SetProfile(profile Profile) error {
+	if err := db.SetMetadata(MetadataKeyProfile, string(profile)); err != nil {
+		return fmt.Errorf("failed to store profile: %w", err)
+	}
+	return db.applyProfile()
+}
+
+// applyProfile runs the pragmas of the stored profile
+func (db *DB) applyProfile() error {
+	profile, err := db.Profile()
+	if err != nil {
+		return err
+	}
+	for _, pragma := range profile.pragmas() {
+		if _, err := db.Exec(pragma); err != nil {
+			return fmt.Errorf("failed to execute pragma %s: %w", pragma, err)
+		}
+	}
+	return nil
+}
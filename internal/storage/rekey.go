@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// Rekey re-encrypts the whole vault under a key derived from a new master
+// password: every entry's data and search text, every history version and
+// the key verifier, together with the new salt and Argon2 parameters, in a
+// single transaction. Fields blobs and sealed metadata move from the old
+// fields key to the new one. On any failure the transaction is rolled back
+// and the vault still opens with the old key
+// Entry timestamps are kept; the manifest is resealed with the new key and
+// the failed unlock counter, whose MAC covers the salt, starts over at 0
+func (db *DB) Rekey(oldKey, newKey, salt []byte, params crypto.Argon2Params) error {
+	if len(newKey) != 32 {
+		return errors.New("encryption key must be 32 bytes")
+	}
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("invalid Argon2 parameters: %w", err)
+	}
+	if err := db.VerifyKey(oldKey); err != nil {
+		return err
+	}
+	failedUnlocksMAC, err := db.failedUnlocksMACFor(0, salt)
+	if err != nil {
+		return err
+	}
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Re-encrypting is not an edit; keep updated_at as it is
+	if _, err := tx.Exec("DROP TRIGGER IF EXISTS update_entries_timestamp"); err != nil {
+		return fmt.Errorf("failed to suspend timestamp trigger: %w", err)
+	}
+
+	if err := rekeyEntries(tx, oldKey, newKey); err != nil {
+		return err
+	}
+	if err := rekeyHistory(tx, oldKey, newKey); err != nil {
+		return err
+	}
+	if err := resealListedMetadata(tx, oldKey, newKey, level); err != nil {
+		return err
+	}
+
+	verifier, err := crypto.Encrypt(keyVerifierPlaintext, newKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key verifier: %w", err)
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Argon2 params: %w", err)
+	}
+	metadata := map[string]string{
+		MetadataKeySalt:         base64.StdEncoding.EncodeToString(salt),
+		MetadataKeyArgon2Params: string(paramsJSON),
+		MetadataKeyKeyVerifier:  base64.StdEncoding.EncodeToString(verifier),
+
+		// The old counter's MAC doesn't verify with the new salt
+		MetadataKeyFailedUnlocks: "0:" + failedUnlocksMAC,
+	}
+	for key, value := range metadata {
+		query := `
+			INSERT INTO metadata (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value
+		`
+		if _, err := tx.Exec(query, key, value); err != nil {
+			return fmt.Errorf("failed to set metadata %s: %w", key, err)
+		}
+	}
+
+	if _, err := tx.Exec(entriesTimestampTrigger); err != nil {
+		return fmt.Errorf("failed to restore timestamp trigger: %w", err)
+	}
+	if err := writeManifest(tx, newKey); err != nil {
+		return fmt.Errorf("failed to reseal vault manifest: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit new master key: %w", err)
+	}
+
+	db.key = newKey
+	db.tampered = false
+	return nil
+}
+
+// rekeyEntries re-encrypts the data, search text and fields of every entry
+func rekeyEntries(tx *sql.Tx, oldKey, newKey []byte) error {
+	rows, err := tx.Query("SELECT id, name, encrypted_data, encrypted_search, encrypted_fields FROM entries")
+	if err != nil {
+		return fmt.Errorf("failed to query entries: %w", err)
+	}
+
+	type blobs struct {
+		id, name             string
+		data, search, fields []byte
+	}
+	var entries []blobs
+	for rows.Next() {
+		var b blobs
+		if err := rows.Scan(&b.id, &b.name, &b.data, &b.search, &b.fields); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	for _, entry := range entries {
+		data, err := reencrypt(entry.data, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %s: %w", entry.name, err)
+		}
+		search, err := reencrypt(entry.search, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt search text of %s: %w", entry.name, err)
+		}
+		fields, err := reencryptFields(entry.fields, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt fields of %s: %w", entry.name, err)
+		}
+
+		query := `
+			UPDATE entries
+			SET encrypted_data = ?, encryption_nonce = ?, encrypted_search = ?, search_nonce = ?, encrypted_fields = ?
+			WHERE id = ?
+		`
+		if _, err := tx.Exec(query, data, data[:12], search, search[:12], fields, entry.id); err != nil {
+			return fmt.Errorf("failed to update %s: %w", entry.name, err)
+		}
+	}
+	return nil
+}
+
+// rekeyHistory re-encrypts every archived entry version
+func rekeyHistory(tx *sql.Tx, oldKey, newKey []byte) error {
+	rows, err := tx.Query("SELECT id, name, encrypted_data, encrypted_fields FROM entry_history")
+	if err != nil {
+		return fmt.Errorf("failed to query entry history: %w", err)
+	}
+
+	type version struct {
+		id           int64
+		name         string
+		data, fields []byte
+	}
+	var versions []version
+	for rows.Next() {
+		var v version
+		if err := rows.Scan(&v.id, &v.name, &v.data, &v.fields); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan history version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating entry history: %w", err)
+	}
+
+	for _, v := range versions {
+		data, err := reencrypt(v.data, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt a history version of %s: %w", v.name, err)
+		}
+		fields, err := reencryptFields(v.fields, oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt the fields of a history version of %s: %w", v.name, err)
+		}
+		query := "UPDATE entry_history SET encrypted_data = ?, encrypted_fields = ? WHERE id = ?"
+		if _, err := tx.Exec(query, data, fields, v.id); err != nil {
+			return fmt.Errorf("failed to update history of %s: %w", v.name, err)
+		}
+	}
+	return nil
+}
+
+// reencryptFields moves a fields blob, if any, to the fields key of newKey
+func reencryptFields(ciphertext, oldKey, newKey []byte) ([]byte, error) {
+	if ciphertext == nil {
+		return nil, nil
+	}
+	return reencrypt(ciphertext, crypto.FieldsKey(oldKey), crypto.FieldsKey(newKey))
+}
+
+// reencrypt moves a ciphertext from oldKey to newKey and checks the result
+// decrypts back to the same plaintext before it is written
+func reencrypt(ciphertext, oldKey, newKey []byte) ([]byte, error) {
+	plaintext, err := crypto.Decrypt(ciphertext, oldKey)
+	if err != nil {
+		return nil, err
+	}
+	rekeyed, err := crypto.Encrypt(plaintext, newKey)
+	if err != nil {
+		return nil, err
+	}
+	check, err := crypto.Decrypt(rekeyed, newKey)
+	if err != nil || !bytes.Equal(check, plaintext) {
+		return nil, errors.New("re-encrypted data does not read back")
+	}
+	return rekeyed, nil
+}
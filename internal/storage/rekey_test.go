@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// testArgon2Params keeps key derivation cheap in tests
+var testArgon2Params = crypto.Argon2Params{Time: 1, Memory: 8 * 1024, Parallelism: 1, KeyLen: 32}
+
+// newTestVault creates a sealed vault at path, as init does, and returns it
+// with its key
+func newTestVault(t *testing.T, path, password string, level PrivacyLevel) (*DB, []byte) {
+	t.Helper()
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.DeriveKey(password, salt, testArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []func() error{
+		func() error { return db.SetSalt(salt) },
+		func() error { return db.SetArgon2Params(testArgon2Params) },
+		func() error { return db.SetKeyVerifier(key) },
+		db.ResetFailedUnlocks,
+		func() error { return db.SetMetadata(MetadataKeyPrivacyLevel, string(level)) },
+		func() error { return db.SetMetadata(MetadataKeyFieldsSealed, "1") },
+		func() error { return db.SealManifest(key) },
+		func() error { return db.VerifyKey(key) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, key
+}
+
+// openTestVault reopens a vault and unlocks it with password, checking the
+// key and the manifest as unlock does
+func openTestVault(t *testing.T, path, password string) (*DB, []byte) {
+	t.Helper()
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	salt, err := db.GetSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := db.GetArgon2Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.DeriveKey(password, salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.VerifyKey(key); err != nil {
+		return db, nil
+	}
+	if err := db.VerifyManifest(key); err != nil {
+		t.Fatalf("manifest after reopening: %v", err)
+	}
+	return db, key
+}
+
+func TestRekey(t *testing.T) {
+	for _, level := range []PrivacyLevel{PrivacyStandard, PrivacyPrivate} {
+		t.Run(string(level), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vault.db")
+			db, oldKey := newTestVault(t, path, "old password", level)
+
+			entry := &models.Entry{
+				Name:     "github",
+				Category: "dev",
+				Username: "octocat",
+				Password: "first-password",
+				URL:      "https://github.com",
+				Notes:    "recovery codes: 1234",
+				Tags:     []string{"work"},
+			}
+			if err := db.CreateEntry(entry, oldKey); err != nil {
+				t.Fatal(err)
+			}
+			entry.Password = "second-password"
+			entry.Notes = "recovery codes: 5678"
+			if err := db.UpdateEntry(entry, oldKey); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.SaveSharedCategory(SharedCategory{Category: "dev", Recipients: []string{"age1example"}}); err != nil {
+				t.Fatal(err)
+			}
+			created, err := db.GetEntry(entry.ID, oldKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			newSalt, err := crypto.GenerateSalt()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newKey, err := crypto.DeriveKey("new password", newSalt, testArgon2Params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Rekey(oldKey, newKey, newSalt, testArgon2Params); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, key := openTestVault(t, path, "old password"); key != nil {
+				t.Fatal("the old password still unlocks the vault")
+			}
+			db, key := openTestVault(t, path, "new password")
+			if key == nil {
+				t.Fatal("the new password does not unlock the vault")
+			}
+
+			got, err := db.GetEntry(entry.ID, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Password != "second-password" || got.Notes != "recovery codes: 5678" ||
+				got.Username != "octocat" || got.URL != "https://github.com" {
+				t.Errorf("entry after rekey = %+v", got)
+			}
+			if !got.UpdatedAt.Equal(created.UpdatedAt) {
+				t.Errorf("rekey changed updated_at from %v to %v", created.UpdatedAt, got.UpdatedAt)
+			}
+			if _, err := db.GetEntry(entry.ID, oldKey); err == nil {
+				t.Error("the old key still decrypts the entry")
+			}
+
+			found, err := db.SearchEntries("github", key)
+			if err != nil || len(found) != 1 {
+				t.Errorf("search after rekey found %d entries, %v", len(found), err)
+			}
+
+			versions, err := db.ListHistory(entry.ID, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != 1 || versions[0].Entry.Password != "first-password" ||
+				versions[0].Entry.Notes != "recovery codes: 1234" {
+				t.Errorf("history after rekey = %+v", versions)
+			}
+
+			shared, err := db.SharedCategories()
+			if err != nil || len(shared) != 1 || shared[0].Category != "dev" {
+				t.Errorf("shared categories after rekey = %+v, %v", shared, err)
+			}
+			if raw, _ := db.GetMetadata(MetadataKeySharedCategories); strings.HasPrefix(raw, sealedPrefix) != (level == PrivacyPrivate) {
+				t.Errorf("shared categories stored as %q at level %s", raw, level)
+			}
+
+			failures, err := db.FailedUnlocks()
+			if err != nil || failures != 0 {
+				t.Errorf("failed unlocks after rekey = %d, %v, want 0", failures, err)
+			}
+		})
+	}
+}
+
+func TestRekeyWrongOldKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.db")
+	db, key := newTestVault(t, path, "old password", PrivacyStandard)
+	defer db.Close()
+
+	entry := &models.Entry{Name: "github", Password: "password"}
+	if err := db.CreateEntry(entry, key); err != nil {
+		t.Fatal(err)
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey, err := crypto.DeriveKey("wrong password", salt, testArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Rekey(wrongKey, wrongKey, salt, testArgon2Params); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("Rekey with the wrong old key = %v, want ErrWrongKey", err)
+	}
+
+	// Nothing changed: the old key still opens the vault and its entry
+	if got, err := db.GetEntry(entry.ID, key); err != nil || got.Password != "password" {
+		t.Errorf("entry after failed rekey = %+v, %v", got, err)
+	}
+	if err := db.VerifyManifest(key); err != nil {
+		t.Errorf("manifest after failed rekey: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+const (
+	vaultMetadataFormat  = "gpasswd-vault-metadata"
+	vaultMetadataVersion = 1
+)
+
+// VaultMetadata is the key derivation state of a vault, saved with
+// 'gpasswd export --metadata' so 'gpasswd init --from-metadata' can recreate
+// a vault that derives the same key from the same master password
+// It holds no entry data, but the key verifier allows offline guessing of
+// the master password, so it should be stored like a vault backup
+type VaultMetadata struct {
+	Format       string              `json:"format"`
+	Version      int                 `json:"version"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	Salt         []byte              `json:"salt"`
+	Argon2       crypto.Argon2Params `json:"argon2_params"`
+	KeyVerifier  []byte              `json:"key_verifier"`
+	PrivacyLevel PrivacyLevel        `json:"privacy_level"`
+}
+
+// ExportMetadata collects the vault's key derivation state
+func (db *DB) ExportMetadata() (*VaultMetadata, error) {
+	salt, err := db.GetSalt()
+	if err != nil {
+		return nil, err
+	}
+	params, err := db.GetArgon2Params()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := db.GetMetadata(MetadataKeyKeyVerifier)
+	if err != nil {
+		return nil, errors.New("vault has no key verifier yet; unlock it once first")
+	}
+	verifier, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key verifier: %w", err)
+	}
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return nil, err
+	}
+
+	return &VaultMetadata{
+		Format:       vaultMetadataFormat,
+		Version:      vaultMetadataVersion,
+		ExportedAt:   time.Now(),
+		Salt:         salt,
+		Argon2:       params,
+		KeyVerifier:  verifier,
+		PrivacyLevel: level,
+	}, nil
+}
+
+// ParseVaultMetadata reads a file written by ExportMetadata
+func ParseVaultMetadata(data []byte) (*VaultMetadata, error) {
+	var m VaultMetadata
+	if err := json.Unmarshal(data, &m); err != nil || m.Format != vaultMetadataFormat {
+		return nil, errors.New("not a gpasswd vault metadata file")
+	}
+	if m.Version != vaultMetadataVersion {
+		return nil, fmt.Errorf("unsupported vault metadata version %d", m.Version)
+	}
+	if len(m.Salt) == 0 || len(m.KeyVerifier) == 0 {
+		return nil, errors.New("vault metadata file is incomplete")
+	}
+	if err := m.Argon2.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Argon2 parameters in metadata: %w", err)
+	}
+	if _, err := ParsePrivacyLevel(string(m.PrivacyLevel)); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// VerifyKey checks a key derived from the metadata's salt and parameters
+// Returns ErrWrongKey if it is not the key of the original vault
+func (m *VaultMetadata) VerifyKey(key []byte) error {
+	plaintext, err := crypto.Decrypt(m.KeyVerifier, key)
+	if err != nil || !isKeyVerifier(plaintext) {
+		return ErrWrongKey
+	}
+	return nil
+}
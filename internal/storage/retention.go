@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// MetadataKeyHistoryRetention stores the vault's history retention
+const MetadataKeyHistoryRetention = "history_retention"
+
+// HistoryRetention returns the vault's history retention, which keeps
+// everything if never set
+func (db *DB) HistoryRetention() (models.Retention, error) {
+	value, err := db.GetMetadata(MetadataKeyHistoryRetention)
+	if err != nil {
+		return models.Retention{}, nil
+	}
+
+	var retention models.Retention
+	if err := json.Unmarshal([]byte(value), &retention); err != nil {
+		return models.Retention{}, fmt.Errorf("failed to parse history retention: %w", err)
+	}
+	return retention, nil
+}
+
+// SetHistoryRetention stores the vault's history retention
+// Existing history is pruned on the next update of each entry, or by
+// CollectGarbage
+func (db *DB) SetHistoryRetention(retention models.Retention) error {
+	if retention.Unlimited() {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyHistoryRetention); err != nil {
+			return fmt.Errorf("failed to clear history retention: %w", err)
+		}
+		return nil
+	}
+
+	jsonData, err := json.Marshal(retention)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history retention: %w", err)
+	}
+	return db.SetMetadata(MetadataKeyHistoryRetention, string(jsonData))
+}
+
+// retentionFor returns the retention that applies to entry
+func retentionFor(entry *models.Entry, vault models.Retention) models.Retention {
+	if entry.HistoryRetention != nil {
+		return *entry.HistoryRetention
+	}
+	return vault
+}
+
+// expiredVersions returns the archived versions of entry (newest first)
+// that retention no longer covers. The newest version with another password
+// dates the current one (see PasswordChangedAt), so it is always kept;
+// keptForAge reports whether that saved an expired version
+func expiredVersions(entry *models.Entry, versions []*HistoryVersion, retention models.Retention, now time.Time) (expired []*HistoryVersion, keptForAge bool) {
+	changed := -1
+	for i, version := range versions {
+		if version.Entry.Password != entry.Password {
+			changed = i
+			break
+		}
+	}
+
+	for i, version := range versions {
+		if !retention.Expired(i, version.ValidTo, now) {
+			continue
+		}
+		if i == changed {
+			keptForAge = true
+			continue
+		}
+		expired = append(expired, version)
+	}
+	return expired, keptForAge
+}
+
+// applyRetention deletes the archived versions of entry that its retention
+// no longer covers; entry must be the current version
+func (db *DB) applyRetention(entry *models.Entry, key []byte) error {
+	vault, err := db.HistoryRetention()
+	if err != nil {
+		return err
+	}
+	retention := retentionFor(entry, vault)
+	if retention.Unlimited() {
+		return nil
+	}
+
+	// Counting is enough to rule out a version limit
+	if retention.Days <= 0 {
+		count, err := db.CountHistory(entry.ID)
+		if err != nil {
+			return err
+		}
+		if count <= retention.Versions {
+			return nil
+		}
+	}
+
+	versions, err := db.ListHistory(entry.ID, key)
+	if err != nil {
+		return err
+	}
+	expired, _ := expiredVersions(entry, versions, retention, time.Now())
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(expired))
+	for i, version := range expired {
+		ids[i] = version.ID
+	}
+	return db.deleteHistory(ids)
+}
+
+// deleteHistory deletes archived versions by ID in a single transaction
+func (db *DB) deleteHistory(ids []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM entry_history WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to prune history version: %w", err)
+		}
+	}
+	if err := db.resealIn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit history pruning: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/kitsnail/gpasswd/internal/rotation"
+)
+
+// MetadataKeyRotationPlan stores the progress of 'gpasswd rotate --plan'
+const MetadataKeyRotationPlan = "rotation_plan"
+
+// LoadRotationPlan returns the saved rotation plan, or nil if there is none
+func (db *DB) LoadRotationPlan() (*rotation.Plan, error) {
+	var plan rotation.Plan
+	found, err := db.loadListedMetadata(MetadataKeyRotationPlan, &plan)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// SaveRotationPlan stores the rotation plan so it can be resumed
+func (db *DB) SaveRotationPlan(plan *rotation.Plan) error {
+	return db.saveListedMetadata(MetadataKeyRotationPlan, plan)
+}
+
+// ClearRotationPlan removes the saved rotation plan, if any
+func (db *DB) ClearRotationPlan() error {
+	if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeyRotationPlan); err != nil {
+		return fmt.Errorf("failed to clear rotation plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// ErrMetadataLocked is returned when metadata that a private vault keeps
+// encrypted is read or written before the vault is unlocked
+var ErrMetadataLocked = errors.New("this vault is private; unlock it first")
+
+// sealedPrefix marks a metadata value encrypted with the fields key
+const sealedPrefix = "sealed:"
+
+// listedMetadata are the metadata keys whose values name sites, entries or
+// categories: approved browser origins and paired extensions, shared
+// categories, the rotation plan, GPG key bindings and import conflicts.
+// They follow the privacy level: a private vault keeps them encrypted with
+// the fields key
+var listedMetadata = []string{
+	MetadataKeyBrowserOrigins,
+	MetadataKeyBrowserPairings,
+	MetadataKeySharedCategories,
+	MetadataKeyRotationPlan,
+	MetadataKeyGPGKeys,
+	MetadataKeyImportConflicts,
+}
+
+// loadListedMetadata unmarshals the JSON stored under a listed metadata
+// key into v, decrypting it with the unlocked vault key if it is sealed
+// found is false if nothing is stored
+func (db *DB) loadListedMetadata(name string, v any) (found bool, err error) {
+	value, err := db.GetMetadata(name)
+	if err != nil {
+		return false, nil
+	}
+	plaintext, err := openListedMetadata(value, db.key)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(plaintext, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// saveListedMetadata stores v as JSON under a listed metadata key, sealed
+// with the unlocked vault key if the vault is private
+func (db *DB) saveListedMetadata(name string, v any) error {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return err
+	}
+	value, err := sealListedMetadata(plaintext, level, db.key)
+	if err != nil {
+		return err
+	}
+	return db.SetMetadata(name, value)
+}
+
+// openListedMetadata returns the JSON of a stored value
+func openListedMetadata(value string, key []byte) ([]byte, error) {
+	encoded, sealed := strings.CutPrefix(value, sealedPrefix)
+	if !sealed {
+		return []byte(value), nil
+	}
+	if key == nil {
+		return nil, ErrMetadataLocked
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed metadata: %w", err)
+	}
+	plaintext, err := crypto.Decrypt(ciphertext, crypto.FieldsKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sealed metadata: %w", err)
+	}
+	return plaintext, nil
+}
+
+// sealListedMetadata returns the value to store for JSON at a privacy level
+func sealListedMetadata(plaintext []byte, level PrivacyLevel, key []byte) (string, error) {
+	if level != PrivacyPrivate {
+		return string(plaintext), nil
+	}
+	if key == nil {
+		return "", ErrMetadataLocked
+	}
+	ciphertext, err := crypto.Encrypt(plaintext, crypto.FieldsKey(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt metadata: %w", err)
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// resealListedMetadata rewrites every listed metadata value for a privacy
+// level and key, within a privacy level change or Rekey
+func resealListedMetadata(tx *sql.Tx, oldKey, newKey []byte, level PrivacyLevel) error {
+	for _, name := range listedMetadata {
+		var value string
+		err := tx.QueryRow("SELECT value FROM metadata WHERE key = ?", name).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		plaintext, err := openListedMetadata(value, oldKey)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		resealed, err := sealListedMetadata(plaintext, level, newKey)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE metadata SET value = ? WHERE key = ?", resealed, name); err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// SearchQuery is a parsed search: free-text terms plus field filters
+type SearchQuery struct {
+	Terms      []string // matched against name, category, tags, username and URL
+	Tags       []string // tag:x, all must be present
+	Categories []string // category:x, any may match
+	URLs       []string // url:x, substring of the URL, all must match
+
+	// Scopes restrict the terms to these fields (empty means the search
+	// index)
+	Scopes []SearchScope
+}
+
+// SearchScope is a group of fields a search can be restricted to
+type SearchScope string
+
+// Search scopes
+const (
+	ScopeNotes  SearchScope = "notes"  // notes
+	ScopeFields SearchScope = "fields" // username, service, scopes, key type and public key
+	ScopeURLs   SearchScope = "urls"   // URL and password change URL
+)
+
+// ParseSearchScopes validates scope names, e.g. from search --in
+func ParseSearchScopes(names []string) ([]SearchScope, error) {
+	var scopes []SearchScope
+	for _, name := range names {
+		switch scope := SearchScope(strings.ToLower(strings.TrimSpace(name))); scope {
+		case ScopeNotes, ScopeFields, ScopeURLs:
+			scopes = append(scopes, scope)
+		default:
+			return nil, fmt.Errorf("unknown search scope %q (supported: notes, fields, urls)", name)
+		}
+	}
+	return scopes, nil
+}
+
+// scopeTexts returns the text of an entry's fields in a scope
+func scopeTexts(entry *models.Entry, scope SearchScope) []string {
+	switch scope {
+	case ScopeNotes:
+		return []string{entry.Notes}
+	case ScopeFields:
+		return append([]string{entry.Username, entry.Service, entry.KeyType, entry.PublicKey}, entry.Scopes...)
+	case ScopeURLs:
+		return []string{entry.URL, entry.ChangePasswordURL}
+	}
+	return nil
+}
+
+// ParseSearchQuery splits a query like `git tag:work url:github.com` into
+// terms and filters. Filter values are compared case-insensitively
+func ParseSearchQuery(query string) SearchQuery {
+	var q SearchQuery
+	for _, word := range strings.Fields(query) {
+		field, value, ok := strings.Cut(word, ":")
+		if ok && value != "" {
+			switch strings.ToLower(field) {
+			case "tag":
+				q.Tags = append(q.Tags, strings.ToLower(value))
+				continue
+			case "category":
+				q.Categories = append(q.Categories, strings.ToLower(value))
+				continue
+			case "url":
+				q.URLs = append(q.URLs, strings.ToLower(value))
+				continue
+			}
+		}
+		q.Terms = append(q.Terms, word)
+	}
+	return q
+}
+
+// Empty reports whether the query has neither terms nor filters
+func (q SearchQuery) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Tags) == 0 && len(q.Categories) == 0 && len(q.URLs) == 0
+}
+
+// Ranking weights. Each term scores the weight of the best field it
+// matches; recent use and the favorite tag add smaller bonuses, so a name
+// match always outranks them
+const (
+	weightName     = 16
+	weightUsername = 8 // and the other fields of the fields scope
+	weightTag      = 4 // tags and category
+	weightURL      = 2
+	weightNotes    = 1
+
+	recencyBonus    = 6                   // read just now
+	recencyHalfLife = 14 * 24 * time.Hour // the bonus halves every two weeks
+	favoriteBonus   = 4
+)
+
+// SearchResult is a matching entry and its rank score
+type SearchResult struct {
+	Entry *models.Entry
+	Score float64
+}
+
+// SearchEntries returns the decrypted entries matching a query, best
+// matches first (see Search)
+func (db *DB) SearchEntries(query string, key []byte) ([]*models.Entry, error) {
+	results, err := db.Search(ParseSearchQuery(query), key)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*models.Entry, len(results))
+	for i, result := range results {
+		entries[i] = result.Entry
+	}
+	return entries, nil
+}
+
+// Search returns the entries matching a query with their scores, best
+// first
+//
+// The encrypted search text of every entry is decrypted into a temporary
+// in-memory FTS5 table that finds the entries matching every term (prefix
+// matches, stemmed). Nothing decrypted is written to the vault file. When the
+// SQLite build lacks FTS5 (built without the sqlite_fts5 tag) terms are
+// matched as substrings instead. Scoped queries skip the index, which holds
+// neither notes nor typed fields, and match the terms as substrings of the
+// scoped fields of each decrypted entry. Filters are applied to the decrypted
+// entries of the matches, which are then ranked by the field each term
+// matches (name > username > tag > URL > notes), how recently the entry was
+// read and the favorite tag. Equal scores are ordered by name, then ID
+func (db *DB) Search(q SearchQuery, key []byte) ([]SearchResult, error) {
+	texts, err := db.searchTexts(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if len(q.Terms) == 0 || len(q.Scopes) > 0 {
+		ids = make([]string, 0, len(texts))
+		for _, t := range texts {
+			ids = append(ids, t.id)
+		}
+	} else {
+		ids, err = matchFTS(texts, q.Terms)
+		if err != nil {
+			ids = matchSubstring(texts, q.Terms)
+		}
+	}
+
+	// Every read since the epoch, oldest first per entry
+	reads, err := db.AccessSince(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var results []SearchResult
+	for _, id := range ids {
+		entry, err := db.GetEntry(id, key)
+		if err != nil {
+			return nil, err
+		}
+		if !q.matchesScopes(entry) || !q.matchesFilters(entry) {
+			continue
+		}
+		var lastRead time.Time
+		if events := reads[id]; len(events) > 0 {
+			lastRead = events[len(events)-1].At
+		}
+		results = append(results, SearchResult{Entry: entry, Score: q.score(entry, lastRead, now)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if nameA, nameB := strings.ToLower(a.Entry.Name), strings.ToLower(b.Entry.Name); nameA != nameB {
+			return nameA < nameB
+		}
+		return a.Entry.ID < b.Entry.ID
+	})
+	return results, nil
+}
+
+// score ranks a matching entry; lastRead is zero if it was never read
+// Scores are rounded to two decimals so they print stably
+func (q SearchQuery) score(entry *models.Entry, lastRead, now time.Time) float64 {
+	fields := []struct {
+		weight float64
+		texts  []string
+	}{
+		{weightName, []string{entry.Name}},
+		{weightUsername, scopeTexts(entry, ScopeFields)},
+		{weightTag, append([]string{entry.Category}, entry.Tags...)},
+		{weightURL, []string{entry.URL}},
+		{weightNotes, []string{entry.Notes}},
+	}
+
+	var score float64
+	for _, term := range q.Terms {
+		term = strings.ToLower(term)
+	fields:
+		for _, field := range fields {
+			for _, text := range field.texts {
+				if strings.Contains(strings.ToLower(text), term) {
+					score += field.weight
+					break fields
+				}
+			}
+		}
+	}
+
+	if !lastRead.IsZero() {
+		age := max(now.Sub(lastRead), 0)
+		score += recencyBonus * math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+	}
+	if entry.IsFavorite() {
+		score += favoriteBonus
+	}
+	return math.Round(score*100) / 100
+}
+
+// searchText is the decrypted search text of one entry
+type searchText struct {
+	id, name, text string
+}
+
+// searchTexts decrypts the search text of every entry
+func (db *DB) searchTexts(key []byte) ([]searchText, error) {
+	rows, err := db.Query("SELECT id, name, encrypted_search FROM entries")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var texts []searchText
+	for rows.Next() {
+		var t searchText
+		var encrypted []byte
+		if err := rows.Scan(&t.id, &t.name, &encrypted); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		plain, err := crypto.Decrypt(encrypted, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt search text of %s: %w", t.name, err)
+		}
+		t.text = string(plain)
+		texts = append(texts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+	return texts, nil
+}
+
+// matchFTS loads entries into an in-memory FTS5 table and returns the IDs
+// of those matching every term
+// Fails if the SQLite build has no FTS5 module
+func matchFTS(texts []searchText, terms []string) ([]string, error) {
+	mem, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer mem.Close()
+	// Every connection to :memory: is a separate database
+	mem.SetMaxOpenConns(1)
+
+	schema := `
+		PRAGMA temp_store = MEMORY;
+		CREATE VIRTUAL TABLE search USING fts5(
+			entry_id UNINDEXED,
+			search_text,
+			tokenize = 'porter unicode61'
+		);
+	`
+	if _, err := mem.Exec(schema); err != nil {
+		return nil, err
+	}
+
+	tx, err := mem.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, t := range texts {
+		if _, err := tx.Exec("INSERT INTO search (entry_id, search_text) VALUES (?, ?)", t.id, t.text); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows, err := mem.Query("SELECT entry_id FROM search WHERE search MATCH ?", ftsQuery(terms))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ftsQuery turns terms into an FTS5 query requiring every term as a prefix
+// Terms are quoted so punctuation in them (e.g. "@" or ".") is not syntax
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// matchSubstring is the fallback without FTS5: the IDs of entries whose
+// search text contains every term
+func matchSubstring(texts []searchText, terms []string) []string {
+	var ids []string
+	for _, t := range texts {
+		text := strings.ToLower(t.text)
+		all := true
+		for _, term := range terms {
+			if !strings.Contains(text, strings.ToLower(term)) {
+				all = false
+				break
+			}
+		}
+		if all {
+			ids = append(ids, t.id)
+		}
+	}
+	return ids
+}
+
+// matchesScopes reports whether every term is in one of the scoped fields
+// Queries without scopes match any entry
+func (q SearchQuery) matchesScopes(entry *models.Entry) bool {
+	if len(q.Scopes) == 0 {
+		return true
+	}
+	var texts []string
+	for _, scope := range q.Scopes {
+		for _, text := range scopeTexts(entry, scope) {
+			texts = append(texts, strings.ToLower(text))
+		}
+	}
+	for _, term := range q.Terms {
+		term = strings.ToLower(term)
+		found := false
+		for _, text := range texts {
+			if strings.Contains(text, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFilters applies the tag:, category: and url: filters to an entry
+func (q SearchQuery) matchesFilters(entry *models.Entry) bool {
+	for _, want := range q.Tags {
+		found := false
+		for _, tag := range entry.Tags {
+			if strings.ToLower(tag) == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Categories) > 0 {
+		found := false
+		for _, want := range q.Categories {
+			if strings.ToLower(entry.Category) == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, want := range q.URLs {
+		if !strings.Contains(strings.ToLower(entry.URL), want) {
+			return false
+		}
+	}
+	return true
+}
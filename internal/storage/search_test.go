@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+func TestSearchRanksByField(t *testing.T) {
+	db, key := newTestVault(t, filepath.Join(t.TempDir(), "vault.db"), "password", PrivacyStandard)
+	defer db.Close()
+
+	// Created in reverse so the ranking, not insertion order, decides
+	for _, entry := range []*models.Entry{
+		{Name: "mail", URL: "https://git.example.com", Notes: "git"},
+		{Name: "work", Tags: []string{"git"}},
+		{Name: "laptop", Username: "git"},
+		{Name: "gitlab"},
+		{Name: "github"},
+	} {
+		entry.Password = "secret"
+		if err := db.CreateEntry(entry, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := db.Search(ParseSearchQuery("git"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name  string
+		score float64
+	}{
+		{"github", weightName},
+		{"gitlab", weightName},
+		{"laptop", weightUsername},
+		{"work", weightTag},
+		{"mail", weightURL},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("found %d entries, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].Entry.Name != w.name || results[i].Score != w.score {
+			t.Errorf("result %d = %s (%v), want %s (%v)", i, results[i].Entry.Name, results[i].Score, w.name, w.score)
+		}
+	}
+}
+
+func TestSearchRecencyAndFavorites(t *testing.T) {
+	db, key := newTestVault(t, filepath.Join(t.TempDir(), "vault.db"), "password", PrivacyStandard)
+	defer db.Close()
+
+	entries := map[string]*models.Entry{}
+	for _, entry := range []*models.Entry{
+		{Name: "bank a"},
+		{Name: "bank b", Tags: []string{models.FavoriteTag}},
+		{Name: "bank c"},
+		{Name: "bank d"},
+	} {
+		entry.Password = "secret"
+		if err := db.CreateEntry(entry, key); err != nil {
+			t.Fatal(err)
+		}
+		entries[entry.Name] = entry
+	}
+	if err := db.RecordAccess(entries["bank c"].ID, "show"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.Search(ParseSearchQuery("bank"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Entry.Name)
+	}
+	// Read just now beats the favorite; the rest tie and go by name
+	want := []string{"bank c", "bank b", "bank a", "bank d"}
+	for i := range want {
+		if i >= len(names) || names[i] != want[i] {
+			t.Fatalf("order = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestSearchScore(t *testing.T) {
+	now := time.Now()
+	entry := &models.Entry{Name: "GitHub", Username: "octocat", Notes: "recovery codes"}
+	q := ParseSearchQuery("git cat recovery")
+
+	if got, want := q.score(entry, time.Time{}, now), float64(weightName+weightUsername+weightNotes); got != want {
+		t.Errorf("score = %v, want %v", got, want)
+	}
+	// The recency bonus halves every half-life
+	base := float64(weightName + weightUsername + weightNotes)
+	if got, want := q.score(entry, now.Add(-recencyHalfLife), now), base+recencyBonus/2; got != want {
+		t.Errorf("score read a half-life ago = %v, want %v", got, want)
+	}
+	// A term counts once, for the best field it matches
+	if got := ParseSearchQuery("octo").score(&models.Entry{Name: "octo", Username: "octo"}, time.Time{}, now); got != weightName {
+		t.Errorf("term in name and username scored %v, want %v", got, weightName)
+	}
+}
+
+func TestSearchScopes(t *testing.T) {
+	db, key := newTestVault(t, filepath.Join(t.TempDir(), "vault.db"), "password", PrivacyStandard)
+	defer db.Close()
+
+	for _, entry := range []*models.Entry{
+		{Name: "bank", Notes: "recovery codes: 1234"},
+		{Name: "recovery email", Username: "me@example.com"},
+		{Name: "ci token", Type: models.EntryTypeToken, Service: "GitHub", Scopes: []string{"repo"}},
+		{Name: "router", URL: "http://192.168.1.1", ChangePasswordURL: "http://192.168.1.1/admin"},
+	} {
+		entry.Password = "secret"
+		if err := db.CreateEntry(entry, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		scopes []string
+		want   []string
+	}{
+		{"recovery", nil, []string{"recovery email"}},
+		{"recovery", []string{"notes"}, []string{"bank"}},
+		{"recovery 1234", []string{"notes"}, []string{"bank"}},
+		{"github repo", []string{"fields"}, []string{"ci token"}},
+		{"example.com", []string{"FIELDS"}, []string{"recovery email"}},
+		{"admin", []string{"urls"}, []string{"router"}},
+		{"admin", []string{"notes", "fields"}, nil},
+		{"recovery", []string{"notes", "fields"}, []string{"bank"}},
+	}
+	for _, tt := range tests {
+		q := ParseSearchQuery(tt.query)
+		var err error
+		if q.Scopes, err = ParseSearchScopes(tt.scopes); err != nil {
+			t.Fatal(err)
+		}
+		results, err := db.Search(q, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, result := range results {
+			names = append(names, result.Entry.Name)
+		}
+		if len(names) != len(tt.want) || (len(names) > 0 && names[0] != tt.want[0]) {
+			t.Errorf("search %q --in %v = %v, want %v", tt.query, tt.scopes, names, tt.want)
+		}
+	}
+
+	if _, err := ParseSearchScopes([]string{"passwords"}); err == nil {
+		t.Error("an unknown scope was accepted")
+	}
+}
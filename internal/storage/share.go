@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MetadataKeySharedCategories stores the categories shared with a team
+const MetadataKeySharedCategories = "shared_categories"
+
+// SharedCategory is a category whose entries are exported to a group of
+// age recipients with 'gpasswd share export'
+type SharedCategory struct {
+	Category   string     `json:"category"`
+	Recipients []string   `json:"recipients"`
+	LastExport *time.Time `json:"last_export,omitempty"`
+	LastImport *time.Time `json:"last_import,omitempty"`
+}
+
+// SharedCategories returns all shared categories sorted by name
+func (db *DB) SharedCategories() ([]SharedCategory, error) {
+	var shared []SharedCategory
+	if _, err := db.loadListedMetadata(MetadataKeySharedCategories, &shared); err != nil {
+		return nil, err
+	}
+	sort.Slice(shared, func(i, j int) bool { return shared[i].Category < shared[j].Category })
+	return shared, nil
+}
+
+// SharedCategory returns the shared category with the given name, or nil
+// if the category is not shared
+func (db *DB) SharedCategory(category string) (*SharedCategory, error) {
+	shared, err := db.SharedCategories()
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		if shared[i].Category == category {
+			return &shared[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveSharedCategory adds or replaces a shared category
+func (db *DB) SaveSharedCategory(category SharedCategory) error {
+	shared, err := db.SharedCategories()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range shared {
+		if shared[i].Category == category.Category {
+			shared[i] = category
+			replaced = true
+		}
+	}
+	if !replaced {
+		shared = append(shared, category)
+	}
+	return db.saveSharedCategories(shared)
+}
+
+// RemoveSharedCategory stops sharing a category; its entries are kept
+func (db *DB) RemoveSharedCategory(category string) error {
+	shared, err := db.SharedCategories()
+	if err != nil {
+		return err
+	}
+
+	kept := shared[:0]
+	for _, s := range shared {
+		if s.Category != category {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == len(shared) {
+		return fmt.Errorf("category '%s' is not shared", category)
+	}
+	return db.saveSharedCategories(kept)
+}
+
+func (db *DB) saveSharedCategories(shared []SharedCategory) error {
+	if len(shared) == 0 {
+		if _, err := db.Exec("DELETE FROM metadata WHERE key = ?", MetadataKeySharedCategories); err != nil {
+			return fmt.Errorf("failed to clear shared categories: %w", err)
+		}
+		return nil
+	}
+	return db.saveListedMetadata(MetadataKeySharedCategories, shared)
+}
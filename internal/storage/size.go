@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// EntrySize is the storage an entry takes, counted in encrypted bytes
+type EntrySize struct {
+	ID       string
+	Name     string
+	Current  int64 // data, search text and fields of the current version
+	History  int64 // archived versions
+	Versions int   // number of archived versions
+}
+
+// Total returns the bytes of the current version and its history
+func (s EntrySize) Total() int64 {
+	return s.Current + s.History
+}
+
+// Size returns the size of the vault database in bytes, including changes
+// still in the write-ahead log
+func (db *DB) Size() (int64, error) {
+	var pages, pageSize int64
+	if err := db.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
+
+// EntrySizes returns the size of every entry, largest first
+// Only ciphertext lengths are read, so no key is needed
+func (db *DB) EntrySizes() ([]EntrySize, error) {
+	query := `
+		SELECT e.id, e.name,
+		       length(e.encrypted_data) + length(e.encrypted_search) + COALESCE(length(e.encrypted_fields), 0),
+		       COALESCE(h.size, 0), COALESCE(h.versions, 0)
+		FROM entries e
+		LEFT JOIN (
+			SELECT entry_id,
+			       SUM(length(encrypted_data) + COALESCE(length(encrypted_fields), 0)) AS size,
+			       COUNT(*) AS versions
+			FROM entry_history
+			GROUP BY entry_id
+		) h ON h.entry_id = e.id
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry sizes: %w", err)
+	}
+	defer rows.Close()
+
+	var sizes []EntrySize
+	for rows.Next() {
+		var s EntrySize
+		if err := rows.Scan(&s.ID, &s.Name, &s.Current, &s.History, &s.Versions); err != nil {
+			return nil, fmt.Errorf("failed to scan entry size: %w", err)
+		}
+		sizes = append(sizes, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry sizes: %w", err)
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		if sizes[i].Total() != sizes[j].Total() {
+			return sizes[i].Total() > sizes[j].Total()
+		}
+		return sizes[i].Name < sizes[j].Name
+	})
+	return sizes, nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/models"
+)
+
+// TagCount is a tag together with the number of entries carrying it
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// querier is an execer that can also run multi-row queries
+type querier interface {
+	execer
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// setEntryTags replaces the indexed tags of an entry
+// Must be called in the same transaction as the entry write
+func setEntryTags(tx execer, entryID string, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id = ?", entryID); err != nil {
+		return fmt.Errorf("failed to clear entry tags: %w", err)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return fmt.Errorf("failed to insert tag %s: %w", tag, err)
+		}
+
+		var tagID int64
+		if err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", tag).Scan(&tagID); err != nil {
+			return fmt.Errorf("failed to look up tag %s: %w", tag, err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO entry_tags (entry_id, tag_id) VALUES (?, ?)", entryID, tagID); err != nil {
+			return fmt.Errorf("failed to link tag %s: %w", tag, err)
+		}
+	}
+
+	return pruneTags(tx)
+}
+
+// pruneTags removes tags that are no longer used by any entry
+func pruneTags(tx execer) error {
+	query := "DELETE FROM tags WHERE id NOT IN (SELECT DISTINCT tag_id FROM entry_tags)"
+	if _, err := tx.Exec(query); err != nil {
+		return fmt.Errorf("failed to prune unused tags: %w", err)
+	}
+	return nil
+}
+
+// attachTags fills in Tags for entries loaded without decryption
+func (db *DB) attachTags(entries []*models.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT et.entry_id, t.name
+		FROM entry_tags et
+		JOIN tags t ON t.id = et.tag_id
+		ORDER BY t.name
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to query entry tags: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string][]string)
+	for rows.Next() {
+		var entryID, name string
+		if err := rows.Scan(&entryID, &name); err != nil {
+			return fmt.Errorf("failed to scan entry tag: %w", err)
+		}
+		byID[entryID] = append(byID[entryID], name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating entry tags: %w", err)
+	}
+
+	for _, entry := range entries {
+		entry.Tags = byID[entry.ID]
+	}
+
+	return nil
+}
+
+// ListTags returns every tag with its entry count, ordered by name
+func (db *DB) ListTags() ([]TagCount, error) {
+	query := `
+		SELECT t.name, COUNT(et.entry_id)
+		FROM tags t
+		JOIN entry_tags et ON et.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name COLLATE NOCASE
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []TagCount
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// TagsIndexed reports whether the tag index covers every entry
+// Vaults created before the tags table existed need a one-time ReindexTags
+func (db *DB) TagsIndexed() (bool, error) {
+	value, err := db.GetMetadata(MetadataKeyTagsIndexed)
+	if err != nil {
+		count, countErr := db.CountEntries()
+		if countErr != nil {
+			return false, countErr
+		}
+		if count > 0 {
+			return false, nil
+		}
+		// An empty vault has nothing to backfill
+		if err := db.SetMetadata(MetadataKeyTagsIndexed, "1"); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return value == "1", nil
+}
+
+// ReindexTags rebuilds the tag index from the encrypted entry data
+func (db *DB) ReindexTags(key []byte) error {
+	entries, err := db.GetAllEntries(key)
+	if err != nil {
+		return err
+	}
+
+	level, err := db.PrivacyLevel()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		if err := setEntryTags(tx, entry.ID, plaintextFor(entry, level).tags); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO metadata (key, value) VALUES (?, '1')
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`
+	if _, err := tx.Exec(query, MetadataKeyTagsIndexed); err != nil {
+		return fmt.Errorf("failed to mark tags as indexed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+)
+
+// MetadataKeyFailedUnlocks stores the consecutive failed unlock counter
+const MetadataKeyFailedUnlocks = "failed_unlocks"
+
+// MaxFailedUnlocks caps the counter; a counter that fails its HMAC check
+// reads as this value
+const MaxFailedUnlocks = 10
+
+// throttleKeyFile holds the HMAC key for the counter, next to the vault
+// The MAC only stops the counter being reset through the database alone:
+// anyone who can write the vault directory can also delete the key file,
+// which starts the counter over
+const throttleKeyFile = "throttle.key"
+
+// FailedUnlocks returns the number of consecutive failed unlocks
+// A vault without a counter or without its throttle key (one from before
+// throttling, or copied without the key file) starts at 0. A counter that
+// fails its HMAC check counts as MaxFailedUnlocks
+func (db *DB) FailedUnlocks() (int, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM metadata WHERE key = ?", MetadataKeyFailedUnlocks).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read failed unlock counter: %w", err)
+	}
+
+	key, err := db.loadThrottleKey()
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		return 0, nil
+	}
+
+	countText, mac, ok := strings.Cut(value, ":")
+	if !ok {
+		return MaxFailedUnlocks, nil
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil || count < 0 {
+		return MaxFailedUnlocks, nil
+	}
+
+	expected, err := db.failedUnlocksMAC(count)
+	if err != nil {
+		return 0, err
+	}
+	if !hmac.Equal([]byte(mac), []byte(expected)) {
+		return MaxFailedUnlocks, nil
+	}
+
+	return min(count, MaxFailedUnlocks), nil
+}
+
+// RecordFailedUnlock increments the failed unlock counter and returns it
+func (db *DB) RecordFailedUnlock() (int, error) {
+	count, err := db.FailedUnlocks()
+	if err != nil {
+		return 0, err
+	}
+	count = min(count+1, MaxFailedUnlocks)
+	return count, db.setFailedUnlocks(count)
+}
+
+// ResetFailedUnlocks clears the counter after a successful unlock
+func (db *DB) ResetFailedUnlocks() error {
+	return db.setFailedUnlocks(0)
+}
+
+func (db *DB) setFailedUnlocks(count int) error {
+	mac, err := db.failedUnlocksMAC(count)
+	if err != nil {
+		return err
+	}
+	return db.SetMetadata(MetadataKeyFailedUnlocks, fmt.Sprintf("%d:%s", count, mac))
+}
+
+// failedUnlocksMAC authenticates a counter value for this vault
+// The vault salt is mixed in so a counter can't be copied between vaults
+func (db *DB) failedUnlocksMAC(count int) (string, error) {
+	salt, err := db.GetSalt()
+	if err != nil {
+		return "", fmt.Errorf("failed to get salt: %w", err)
+	}
+	return db.failedUnlocksMACFor(count, salt)
+}
+
+// failedUnlocksMACFor authenticates a counter value for a vault salt, for
+// Rekey to write the counter along with a new salt
+func (db *DB) failedUnlocksMACFor(count int, salt []byte) (string, error) {
+	key, err := db.throttleKey()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "gpasswd failed unlocks v1|%d|", count)
+	mac.Write(salt)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// throttleKey loads the counter HMAC key, creating it on first use
+func (db *DB) throttleKey() ([]byte, error) {
+	key, err := db.loadThrottleKey()
+	if err != nil || key != nil {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate throttle key: %w", err)
+	}
+	if err := atomicfile.WriteFile(db.throttleKeyPath(), []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write throttle key: %w", err)
+	}
+	return key, nil
+}
+
+// loadThrottleKey reads the counter HMAC key, or nil if there is none yet
+func (db *DB) loadThrottleKey() ([]byte, error) {
+	path := db.throttleKeyPath()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read throttle key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid throttle key in %s", path)
+	}
+	return key, nil
+}
+
+func (db *DB) throttleKeyPath() string {
+	return filepath.Join(filepath.Dir(db.path), throttleKeyFile)
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// MetadataKeyKeyVerifier stores a known plaintext encrypted with the vault key
+const MetadataKeyKeyVerifier = "key_verifier"
+
+// ErrWrongKey is returned when a derived key cannot decrypt the vault
+var ErrWrongKey = errors.New("incorrect master password")
+
+// keyVerifierPlaintext is encrypted with the vault key so a derived key can be
+// checked without touching any entry. Vaults with a verifier have been
+// sealed, so a missing manifest means tampering (see VerifyManifest)
+var keyVerifierPlaintext = []byte("gpasswd key verifier v1")
+
+// SetKeyVerifier stores the key verifier for the given key
+func (db *DB) SetKeyVerifier(key []byte) error {
+	encrypted, err := crypto.Encrypt(keyVerifierPlaintext, key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key verifier: %w", err)
+	}
+
+	return db.SetMetadata(MetadataKeyKeyVerifier, base64.StdEncoding.EncodeToString(encrypted))
+}
+
+// isKeyVerifier reports whether plaintext is the key verifier
+func isKeyVerifier(plaintext []byte) bool {
+	return subtle.ConstantTimeCompare(plaintext, keyVerifierPlaintext) == 1
+}
+
+// manifestExpected reports whether the vault has been sealed, going by its
+// key verifier: the verifier is authenticated by the key, so unlike a
+// marker row it can't be dropped or downgraded without the key. Vaults
+// without a verifier predate manifests
+func (db *DB) manifestExpected(key []byte) (bool, error) {
+	encoded, err := db.GetMetadata(MetadataKeyKeyVerifier)
+	if err != nil {
+		return false, nil
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode key verifier: %w", err)
+	}
+	plaintext, err := crypto.Decrypt(encrypted, key)
+	if err != nil || !isKeyVerifier(plaintext) {
+		return false, ErrWrongKey
+	}
+	return true, nil
+}
+
+// verifyKeyMinDuration is the floor for VerifyKey, so success, failure and
+// the unsealed-vault path take the same time whatever the vault contains
+const verifyKeyMinDuration = 100 * time.Millisecond
+
+// VerifyKey checks a derived key against the vault
+// Returns ErrWrongKey if the key is incorrect. Vaults created before the key
+// verifier existed are checked against an entry; SealManifest adds the
+// verifier. Exactly one decryption is performed on every path (a decoy for
+// an empty vault) and the call is padded to verifyKeyMinDuration. A
+// verified key is kept so writes can reseal the manifest
+func (db *DB) VerifyKey(key []byte) error {
+	defer padUntil(time.Now(), verifyKeyMinDuration)
+
+	encoded, err := db.GetMetadata(MetadataKeyKeyVerifier)
+	if err == nil {
+		encrypted, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("failed to decode key verifier: %w", err)
+		}
+
+		plaintext, err := crypto.Decrypt(encrypted, key)
+		if err != nil || !isKeyVerifier(plaintext) {
+			return ErrWrongKey
+		}
+		db.key = key
+		return nil
+	}
+
+	// No verifier yet: fall back to decrypting any entry
+	var encryptedData []byte
+	err = db.QueryRow("SELECT encrypted_data FROM entries LIMIT 1").Scan(&encryptedData)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Nothing to check against; the first key used becomes the vault key.
+		// Decrypt a decoy so this is not answered instantly
+		decoy, err := crypto.Encrypt(keyVerifierPlaintext, key)
+		if err == nil {
+			crypto.Decrypt(decoy, key)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to query entry for key check: %w", err)
+	default:
+		if _, err := crypto.Decrypt(encryptedData, key); err != nil {
+			return ErrWrongKey
+		}
+	}
+
+	// Unsealed until the caller seals the manifest (ErrNoManifest)
+	db.key = key
+	return nil
+}
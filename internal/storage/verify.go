@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/kitsnail/gpasswd/internal/crypto"
+)
+
+// ErrWriteVerification is returned when an entry read back right after a
+// write does not match what was written
+var ErrWriteVerification = errors.New("entry failed verification after writing")
+
+// SetVerifyWrites enables reading back every created or updated entry and
+// decrypting it, so storage corruption surfaces at write time rather than
+// at the next read
+func (db *DB) SetVerifyWrites(enabled bool) {
+	db.verifyWrites = enabled
+}
+
+// verifyWrite checks that the stored blobs of an entry decrypt to the
+// plaintext that was just written
+func (db *DB) verifyWrite(id string, sealed *sealedEntry, search, key []byte) error {
+	if !db.verifyWrites {
+		return nil
+	}
+
+	var encryptedData, encryptedSearch, encryptedFields []byte
+	err := db.QueryRow(
+		"SELECT encrypted_data, encrypted_search, encrypted_fields FROM entries WHERE id = ?", id,
+	).Scan(&encryptedData, &encryptedSearch, &encryptedFields)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read entry back: %v", ErrWriteVerification, err)
+	}
+
+	type check struct {
+		name      string
+		encrypted []byte
+		expected  []byte
+		key       []byte
+	}
+	checks := []check{
+		{"data", encryptedData, sealed.dataJSON, key},
+		{"search text", encryptedSearch, search, key},
+	}
+	if sealed.fields != nil {
+		checks = append(checks, check{"fields", encryptedFields, sealed.fieldsJSON, crypto.FieldsKey(key)})
+	} else if encryptedFields != nil {
+		return fmt.Errorf("%w: fields were not cleared", ErrWriteVerification)
+	}
+
+	for _, blob := range checks {
+		plaintext, err := crypto.Decrypt(blob.encrypted, blob.key)
+		if err != nil {
+			return fmt.Errorf("%w: %s does not decrypt: %v", ErrWriteVerification, blob.name, err)
+		}
+		if !bytes.Equal(plaintext, blob.expected) {
+			return fmt.Errorf("%w: %s differs from what was written", ErrWriteVerification, blob.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,398 @@
+package transfer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal DNS-SD over multicast DNS (RFC 6762/6763), just enough for
+// receivers to announce themselves and senders to find them by name
+
+const (
+	// ServiceType is the DNS-SD service receivers advertise
+	ServiceType = "_gpasswd-transfer._tcp.local."
+
+	mdnsTTL = 120
+
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+	typeANY = 255
+
+	classIN    = 1
+	cacheFlush = 0x8000
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Peer is a receiver found on the network
+type Peer struct {
+	Name    string // Instance name, usually the host name
+	Address string // host:port to pass to Send
+}
+
+// Advertiser answers discovery queries until closed
+type Advertiser struct {
+	conn *net.UDPConn
+	done chan struct{}
+}
+
+// Advertise announces a receiver called name listening on port with the
+// given IPv4 addresses, and answers browse queries until Close
+func Advertise(name string, port int, addresses []string) (*Advertiser, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join mDNS group: %w", err)
+	}
+
+	response := buildResponse(name, port, addresses)
+	a := &Advertiser{conn: conn, done: make(chan struct{})}
+
+	// Unsolicited announcement, then answer queries
+	conn.WriteToUDP(response, mdnsGroup)
+	go a.serve(response)
+	return a, nil
+}
+
+// Close stops answering queries
+func (a *Advertiser) Close() error {
+	err := a.conn.Close()
+	<-a.done
+	return err
+}
+
+func (a *Advertiser) serve(response []byte) {
+	defer close(a.done)
+
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if !asksForService(buf[:n]) {
+			continue
+		}
+		// Queries from port 5353 expect multicast answers; anything else
+		// is a one-shot (legacy unicast) query answered directly
+		if src.Port == mdnsGroup.Port {
+			a.conn.WriteToUDP(response, mdnsGroup)
+		} else {
+			a.conn.WriteToUDP(response, src)
+		}
+	}
+}
+
+// Browse asks for receivers and collects answers for the given time
+func Browse(wait time.Duration) ([]Peer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open discovery socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(buildQuery(), mdnsGroup); err != nil {
+		return nil, fmt.Errorf("failed to send discovery query: %w", err)
+	}
+
+	found := make(map[string]Peer)
+	deadline := time.Now().Add(wait)
+	buf := make([]byte, 9000)
+	for {
+		conn.SetReadDeadline(deadline)
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		for _, peer := range parseResponse(buf[:n], src.IP) {
+			found[peer.Name+"\x00"+peer.Address] = peer
+		}
+	}
+
+	peers := make([]Peer, 0, len(found))
+	for _, peer := range found {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Name != peers[j].Name {
+			return peers[i].Name < peers[j].Name
+		}
+		return peers[i].Address < peers[j].Address
+	})
+	return peers, nil
+}
+
+// record is a decoded resource record
+type record struct {
+	name  string
+	rtype uint16
+	data  []byte
+	msg   []byte // Whole message, for compressed names inside data
+	start int    // Offset of data in msg
+}
+
+func buildQuery() []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], 1) // One question
+	msg = appendName(msg, ServiceType)
+	msg = binary.BigEndian.AppendUint16(msg, typePTR)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+func buildResponse(name string, port int, addresses []string) []byte {
+	instance := escapeLabel(name) + "." + ServiceType
+	host := hostLabel(name) + ".local."
+
+	type rr struct {
+		name  string
+		rtype uint16
+		class uint16
+		data  []byte
+	}
+	records := []rr{
+		{ServiceType, typePTR, classIN, appendName(nil, instance)},
+		{instance, typeSRV, classIN | cacheFlush, appendName(binary.BigEndian.AppendUint16(make([]byte, 4), uint16(port)), host)},
+		{instance, typeTXT, classIN | cacheFlush, []byte("\x04v=1")},
+	}
+	for _, address := range addresses {
+		if ip := net.ParseIP(address).To4(); ip != nil {
+			records = append(records, rr{host, typeA, classIN | cacheFlush, ip})
+		}
+	}
+
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[2:], 0x8400) // Authoritative response
+	binary.BigEndian.PutUint16(msg[6:], 1)      // The PTR is the answer
+	binary.BigEndian.PutUint16(msg[10:], uint16(len(records)-1))
+	for _, r := range records {
+		msg = appendName(msg, r.name)
+		msg = binary.BigEndian.AppendUint16(msg, r.rtype)
+		msg = binary.BigEndian.AppendUint16(msg, r.class)
+		msg = binary.BigEndian.AppendUint32(msg, mdnsTTL)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(r.data)))
+		msg = append(msg, r.data...)
+	}
+	return msg
+}
+
+// asksForService reports whether a query asks for our service type
+func asksForService(msg []byte) bool {
+	if len(msg) < 12 || msg[2]&0x80 != 0 {
+		return false
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	offset := 12
+	for i := 0; i < questions; i++ {
+		name, next, err := readName(msg, offset)
+		if err != nil || next+4 > len(msg) {
+			return false
+		}
+		qtype := binary.BigEndian.Uint16(msg[next:])
+		offset = next + 4
+		if strings.EqualFold(name, ServiceType) && (qtype == typePTR || qtype == typeANY) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseResponse extracts receivers from an answer; src is used when the
+// answer carries no address record
+func parseResponse(msg []byte, src net.IP) []Peer {
+	records, err := parseRecords(msg)
+	if err != nil {
+		return nil
+	}
+
+	var instances []string
+	type target struct {
+		host string
+		port int
+	}
+	targets := make(map[string]target)
+	hosts := make(map[string][]string)
+	for _, r := range records {
+		switch r.rtype {
+		case typePTR:
+			if strings.EqualFold(r.name, ServiceType) {
+				if instance, _, err := readName(r.msg, r.start); err == nil {
+					instances = append(instances, instance)
+				}
+			}
+		case typeSRV:
+			if len(r.data) < 7 {
+				continue
+			}
+			host, _, err := readName(r.msg, r.start+6)
+			if err == nil {
+				targets[strings.ToLower(r.name)] = target{strings.ToLower(host), int(binary.BigEndian.Uint16(r.data[4:]))}
+			}
+		case typeA:
+			if len(r.data) == 4 {
+				key := strings.ToLower(r.name)
+				hosts[key] = append(hosts[key], net.IP(r.data).String())
+			}
+		}
+	}
+
+	var peers []Peer
+	for _, instance := range instances {
+		t, ok := targets[strings.ToLower(instance)]
+		if !ok {
+			continue
+		}
+		name := unescapeLabel(strings.TrimSuffix(instance, "."+ServiceType))
+		ips := hosts[t.host]
+		if len(ips) == 0 && src != nil {
+			ips = []string{src.String()}
+		}
+		for _, ip := range ips {
+			peers = append(peers, Peer{Name: name, Address: net.JoinHostPort(ip, strconv.Itoa(t.port))})
+		}
+	}
+	return peers
+}
+
+func parseRecords(msg []byte) ([]record, error) {
+	if len(msg) < 12 || msg[2]&0x80 == 0 {
+		return nil, errors.New("not a DNS response")
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	count := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	records := make([]record, 0, count)
+	for i := 0; i < count; i++ {
+		name, next, err := readName(msg, offset)
+		if err != nil || next+10 > len(msg) {
+			return nil, errors.New("truncated DNS record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return nil, errors.New("truncated DNS record")
+		}
+		records = append(records, record{name: name, rtype: rtype, data: msg[start : start+length], msg: msg, start: start})
+		offset = start + length
+	}
+	return records, nil
+}
+
+// readName decodes a possibly compressed name at offset and returns it
+// with a trailing dot, plus the offset after it
+func readName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errors.New("truncated DNS name")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("invalid DNS name pointer")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errors.New("truncated DNS name")
+			}
+			labels = append(labels, escapeLabel(string(msg[offset+1:offset+1+length])))
+			offset += 1 + length
+		}
+	}
+}
+
+// appendName encodes a dotted name; "\." inside a label is a literal dot
+func appendName(msg []byte, name string) []byte {
+	for _, label := range splitName(name) {
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0)
+}
+
+func splitName(name string) []string {
+	var labels []string
+	var label strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name):
+			i++
+			label.WriteByte(name[i])
+		case name[i] == '.':
+			labels = append(labels, label.String())
+			label.Reset()
+		default:
+			label.WriteByte(name[i])
+		}
+	}
+	if label.Len() > 0 {
+		labels = append(labels, label.String())
+	}
+	return labels
+}
+
+func escapeLabel(label string) string {
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	return strings.ReplaceAll(label, ".", `\.`)
+}
+
+func unescapeLabel(label string) string {
+	labels := splitName(label)
+	if len(labels) == 0 {
+		return ""
+	}
+	return labels[0]
+}
+
+// hostLabel turns an instance name into a usable host label
+func hostLabel(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	host := strings.Trim(b.String(), "-")
+	if host == "" {
+		host = "gpasswd"
+	}
+	return "gpasswd-" + host
+}
@@ -0,0 +1,105 @@
+package transfer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
+)
+
+// SPAKE2 (RFC 9382) over edwards25519, using filippo.io/edwards25519 for
+// the group arithmetic
+// Both sides know the low-entropy code; an active attacker gets one guess
+// per session and a passive one learns nothing about it
+
+// The blinding points M and N must have unknown discrete logs, so they are
+// derived by hashing fixed labels onto the prime-order subgroup
+var (
+	pointM = hashToPoint("gpasswd transfer SPAKE2 M")
+	pointN = hashToPoint("gpasswd transfer SPAKE2 N")
+)
+
+// hashToPoint maps a label to a point of the prime-order subgroup by
+// try-and-increment, clearing the cofactor of the first encoding that decodes
+func hashToPoint(label string) *edwards25519.Point {
+	for counter := uint32(0); ; counter++ {
+		h := sha256.New()
+		h.Write([]byte(label))
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		p, err := new(edwards25519.Point).SetBytes(h.Sum(nil))
+		if err != nil {
+			continue
+		}
+		p.MultByCofactor(p)
+		if p.Equal(edwards25519.NewIdentityPoint()) == 1 {
+			continue
+		}
+		return p
+	}
+}
+
+// spake2 holds one side of an exchange
+type spake2 struct {
+	w      *edwards25519.Scalar // Code mapped to a scalar
+	secret *edwards25519.Scalar
+	blind  *edwards25519.Point // Own blinding point (M for the sender, N for the receiver)
+	other  *edwards25519.Point // Peer's blinding point
+	share  []byte
+}
+
+// newSPAKE2 starts an exchange; sender selects the M/N roles
+func newSPAKE2(code string, sender bool) (*spake2, error) {
+	return newSPAKE2From(code, sender, rand.Reader)
+}
+
+// newSPAKE2From is newSPAKE2 drawing the secret scalar from random
+func newSPAKE2From(code string, sender bool, random io.Reader) (*spake2, error) {
+	digest := sha512.Sum512([]byte("gpasswd transfer code\x00" + code))
+	w, err := new(edwards25519.Scalar).SetUniformBytes(digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	seed := make([]byte, 64)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, fmt.Errorf("failed to generate SPAKE2 secret: %w", err)
+	}
+	secret, err := new(edwards25519.Scalar).SetUniformBytes(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spake2{w: w, secret: secret, blind: pointM, other: pointN}
+	if !sender {
+		s.blind, s.other = pointN, pointM
+	}
+
+	// share = secret·G + w·blind
+	share := new(edwards25519.Point).ScalarBaseMult(secret)
+	share.Add(share, new(edwards25519.Point).ScalarMult(w, s.blind))
+	s.share = share.Bytes()
+	return s, nil
+}
+
+// finish computes the shared point from the peer's share
+func (s *spake2) finish(peerShare []byte) ([]byte, error) {
+	peer, err := new(edwards25519.Point).SetBytes(peerShare)
+	if err != nil {
+		return nil, errors.New("invalid key share from peer")
+	}
+
+	// K = h·secret·(peer − w·other), h clearing any small-order component
+	k := new(edwards25519.Point).ScalarMult(s.w, s.other)
+	k.Subtract(peer, k)
+	k.MultByCofactor(k)
+	k.ScalarMult(s.secret, k)
+	if k.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, errors.New("invalid key share from peer")
+	}
+	return k.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+)
+
+// Known answers computed with an independent edwards25519 implementation
+// (RFC 8032 reference arithmetic) for code 482-019-736, with the sender's
+// secret drawn from bytes 0..63 and the receiver's from bytes 64..127
+const (
+	katCode          = "482-019-736"
+	katM             = "e2b4743433816f75ab698e8a8d2a281c0bde999ba4b0a0702ffb38674595b7b5"
+	katN             = "be8f725d7971328b2f51cdd84108e66c657ea49b38ac7eeec8d91d9ee724f4da"
+	katW             = "f1a89be58ae11a97e1e5dc249eaddfc5218bfacaae72b294eb8e79baa0cd4408"
+	katSenderShare   = "2728bbb99691928f45e91e3279a8fb5ae66c179e2875e12f4e215ac47cd418db"
+	katReceiverShare = "05275af0da4ef00f003d7b3a3d8e063e3e78f9fcc857a270a9cc89c6807319b5"
+	katShared        = "224a0fa57710230ebd75e55e300cb2f09450475e3a6085a86d2db015dfd46d54"
+)
+
+// countingReader yields consecutive byte values starting at next
+type countingReader struct{ next byte }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+func TestSPAKE2KnownAnswer(t *testing.T) {
+	sender, err := newSPAKE2From(katCode, true, &countingReader{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := newSPAKE2From(katCode, false, &countingReader{64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHex(t, "M", pointM.Bytes(), katM)
+	checkHex(t, "N", pointN.Bytes(), katN)
+	checkHex(t, "w", sender.w.Bytes(), katW)
+	checkHex(t, "sender share", sender.share, katSenderShare)
+	checkHex(t, "receiver share", receiver.share, katReceiverShare)
+
+	senderShared, err := sender.finish(receiver.share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverShared, err := receiver.finish(sender.share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHex(t, "sender K", senderShared, katShared)
+	checkHex(t, "receiver K", receiverShared, katShared)
+}
+
+func TestSPAKE2WrongCode(t *testing.T) {
+	sender, err := newSPAKE2("482-019-736", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := newSPAKE2("482-019-737", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := sender.finish(receiver.share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := receiver.finish(sender.share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("different codes produced the same shared point")
+	}
+}
+
+func TestSPAKE2RejectsInvalidShares(t *testing.T) {
+	s, err := newSPAKE2(katCode, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// w·N alone unblinds to the identity, and so does w·N plus a point of
+	// small order once the cofactor is cleared
+	blinding := new(edwards25519.Point).ScalarMult(s.w, s.other)
+	order2, err := new(edwards25519.Point).SetBytes(mustHex(t, "ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, share := range map[string][]byte{
+		"short":            s.share[:31],
+		"blinding only":    blinding.Bytes(),
+		"blinding+order 2": new(edwards25519.Point).Add(blinding, order2).Bytes(),
+	} {
+		if _, err := s.finish(share); err == nil {
+			t.Errorf("%s share: expected an error", name)
+		}
+	}
+}
+
+func checkHex(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	if hex.EncodeToString(got) != want {
+		t.Errorf("%s = %x, want %s", name, got, want)
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
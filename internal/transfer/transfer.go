@@ -0,0 +1,366 @@
+// Package transfer moves an encrypted vault file between two machines over
+// a direct TCP connection. The receiver shows a short one-time code; the
+// sender types it, and a SPAKE2 exchange turns it into session keys, so no
+// cloud storage, USB stick or pre-shared key is needed and a wrong or
+// intercepted code never reveals the vault
+package transfer
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	protocol = "gpasswd-transfer-v2"
+
+	// DefaultPort is the TCP port the receiver listens on
+	DefaultPort = 7361
+
+	// MaxVaultSize bounds what a receiver accepts
+	MaxVaultSize = 1 << 30
+
+	chunkSize     = 64 * 1024
+	maxFrameSize  = chunkSize + 1024
+	codeGroups    = 3
+	codeGroupSize = 3
+)
+
+var (
+	// ErrWrongCode is returned when the two sides used different codes, or
+	// someone else tried to guess the code
+	ErrWrongCode = errors.New("transfer code does not match")
+
+	// ErrRejected is returned to the sender when the receiver refused the vault
+	ErrRejected = errors.New("receiver rejected the vault")
+)
+
+// Offer describes the vault being sent; the receiver sees it before the data
+type Offer struct {
+	Size        int64  `json:"size"`
+	SHA256      []byte `json:"sha256"`
+	Fingerprint []byte `json:"fingerprint"`
+	Entries     int    `json:"entries"`
+}
+
+// NewCode returns a random one-time code such as "482-019-736"
+func NewCode() (string, error) {
+	groups := make([]string, codeGroups)
+	limit := big.NewInt(1000)
+	for i := range groups {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		groups[i] = fmt.Sprintf("%0*d", codeGroupSize, n.Int64())
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// NormalizeCode accepts a code typed with or without separators
+func NormalizeCode(code string) (string, error) {
+	var digits strings.Builder
+	for _, r := range code {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == ' ':
+		default:
+			return "", fmt.Errorf("invalid transfer code %q", code)
+		}
+	}
+	d := digits.String()
+	if len(d) != codeGroups*codeGroupSize {
+		return "", fmt.Errorf("invalid transfer code %q: expected %d digits", code, codeGroups*codeGroupSize)
+	}
+
+	groups := make([]string, codeGroups)
+	for i := range groups {
+		groups[i] = d[i*codeGroupSize : (i+1)*codeGroupSize]
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// Send authenticates with the code and sends the vault described by offer
+// It returns once the receiver has checked and stored the data
+func Send(conn net.Conn, code string, offer Offer, data io.Reader) error {
+	ch, err := handshake(conn, code, true)
+	if err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(offer)
+	if err != nil {
+		return err
+	}
+	if err := ch.write(header); err != nil {
+		return err
+	}
+	if err := readReply(ch, "accept"); err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	var sent int64
+	for sent < offer.Size {
+		n, err := io.ReadFull(data, buf[:min(int64(chunkSize), offer.Size-sent)])
+		if err != nil {
+			return fmt.Errorf("failed to read vault: %w", err)
+		}
+		if err := ch.write(buf[:n]); err != nil {
+			return err
+		}
+		sent += int64(n)
+	}
+
+	return readReply(ch, "ok")
+}
+
+// readReply waits for the receiver's verdict
+func readReply(ch *channel, want string) error {
+	reply, err := ch.read()
+	if err != nil {
+		return fmt.Errorf("no reply from receiver: %w", err)
+	}
+	if string(reply) != want {
+		return fmt.Errorf("%w: %s", ErrRejected, reply)
+	}
+	return nil
+}
+
+// Receive authenticates with the code and writes the vault to w
+// accept may refuse the offer before any data is written
+func Receive(conn net.Conn, code string, accept func(Offer) error, w io.Writer) (*Offer, error) {
+	ch, err := handshake(conn, code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := ch.read()
+	if err != nil {
+		return nil, err
+	}
+	var offer Offer
+	if err := json.Unmarshal(header, &offer); err != nil {
+		return nil, fmt.Errorf("invalid offer: %w", err)
+	}
+
+	reject := func(err error) (*Offer, error) {
+		ch.write([]byte(err.Error()))
+		return nil, err
+	}
+	if offer.Size <= 0 || offer.Size > MaxVaultSize {
+		return reject(fmt.Errorf("refusing vault of %d bytes", offer.Size))
+	}
+	if accept != nil {
+		if err := accept(offer); err != nil {
+			return reject(err)
+		}
+	}
+	if err := ch.write([]byte("accept")); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	var received int64
+	for received < offer.Size {
+		chunk, err := ch.read()
+		if err != nil {
+			return nil, err
+		}
+		received += int64(len(chunk))
+		if received > offer.Size {
+			return reject(errors.New("sender sent more data than offered"))
+		}
+		hash.Write(chunk)
+		if _, err := w.Write(chunk); err != nil {
+			return reject(fmt.Errorf("failed to write vault: %w", err))
+		}
+	}
+	if !bytes.Equal(hash.Sum(nil), offer.SHA256) {
+		return reject(errors.New("vault checksum mismatch"))
+	}
+
+	if err := ch.write([]byte("ok")); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// handshake runs SPAKE2 and key confirmation over conn
+// The sender speaks first; the receiver confirms first, so a sender with
+// the wrong code learns so before sending anything
+func handshake(conn net.Conn, code string, sender bool) (*channel, error) {
+	code, err := NormalizeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	pake, err := newSPAKE2(code, sender)
+	if err != nil {
+		return nil, err
+	}
+
+	var senderShare, receiverShare []byte
+	if sender {
+		if err := writeFrame(conn, append([]byte(protocol+"\x00"), pake.share...)); err != nil {
+			return nil, err
+		}
+		senderShare = pake.share
+		if receiverShare, err = readFrame(conn); err != nil {
+			return nil, err
+		}
+	} else {
+		hello, err := readFrame(conn)
+		if err != nil {
+			return nil, err
+		}
+		prefix := []byte(protocol + "\x00")
+		if !bytes.HasPrefix(hello, prefix) {
+			return nil, errors.New("peer is not a gpasswd transfer sender")
+		}
+		senderShare = hello[len(prefix):]
+		receiverShare = pake.share
+		if err := writeFrame(conn, receiverShare); err != nil {
+			return nil, err
+		}
+	}
+
+	peerShare := receiverShare
+	if !sender {
+		peerShare = senderShare
+	}
+	shared, err := pake.finish(peerShare)
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := sha256.New()
+	for _, field := range [][]byte{[]byte(protocol), senderShare, receiverShare, shared, pake.w.Bytes()} {
+		transcript.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		transcript.Write(field)
+	}
+	keys := hkdf.New(sha256.New, transcript.Sum(nil), nil, []byte(protocol+" keys"))
+	var senderKey, receiverKey, senderConfirm, receiverConfirm [32]byte
+	for _, key := range []*[32]byte{&senderKey, &receiverKey, &senderConfirm, &receiverConfirm} {
+		if _, err := io.ReadFull(keys, key[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	confirm := func(key []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(protocol + " confirm"))
+		return mac.Sum(nil)
+	}
+	ownConfirm, peerConfirm := confirm(receiverConfirm[:]), confirm(senderConfirm[:])
+	sendKey, recvKey := receiverKey[:], senderKey[:]
+	if sender {
+		ownConfirm, peerConfirm = peerConfirm, ownConfirm
+		sendKey, recvKey = recvKey, sendKey
+	}
+
+	if !sender {
+		if err := writeFrame(conn, ownConfirm); err != nil {
+			return nil, err
+		}
+	}
+	got, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(got, peerConfirm) {
+		return nil, ErrWrongCode
+	}
+	if sender {
+		if err := writeFrame(conn, ownConfirm); err != nil {
+			return nil, err
+		}
+	}
+
+	return newChannel(conn, sendKey, recvKey)
+}
+
+// channel encrypts frames with one key per direction and counter nonces,
+// so frames can't be replayed, reordered or reflected
+type channel struct {
+	conn       net.Conn
+	send, recv cipher.AEAD
+	sendSeq    uint64
+	recvSeq    uint64
+}
+
+func newChannel(conn net.Conn, sendKey, recvKey []byte) (*channel, error) {
+	send, err := chacha20poly1305.New(sendKey)
+	if err != nil {
+		return nil, err
+	}
+	recv, err := chacha20poly1305.New(recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &channel{conn: conn, send: send, recv: recv}, nil
+}
+
+func (c *channel) write(plaintext []byte) error {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[4:], c.sendSeq)
+	c.sendSeq++
+	return writeFrame(c.conn, c.send.Seal(nil, nonce, plaintext, nil))
+}
+
+func (c *channel) read() ([]byte, error) {
+	frame, err := readFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[4:], c.recvSeq)
+	c.recvSeq++
+	plaintext, err := c.recv.Open(nil, nonce, frame, nil)
+	if err != nil {
+		return nil, errors.New("transfer data was corrupted or tampered with")
+	}
+	return plaintext, nil
+}
+
+// writeFrame writes a length-prefixed frame
+func writeFrame(w io.Writer, frame []byte) error {
+	if _, err := w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(frame)))); err != nil {
+		return err
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads a length-prefixed frame
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("connection closed by peer")
+		}
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, errors.New("frame too large")
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,75 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestSendReceive(t *testing.T) {
+	vault := bytes.Repeat([]byte("vault data "), 20000)
+	sum := sha256.Sum256(vault)
+	offer := Offer{Size: int64(len(vault)), SHA256: sum[:], Entries: 3}
+
+	senderConn, receiverConn := net.Pipe()
+	defer senderConn.Close()
+	defer receiverConn.Close()
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- Send(senderConn, "123 456 789", offer, bytes.NewReader(vault))
+	}()
+
+	var got bytes.Buffer
+	received, err := Receive(receiverConn, "123-456-789", nil, &got)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if received.Entries != 3 || !bytes.Equal(got.Bytes(), vault) {
+		t.Fatal("received vault differs from the one sent")
+	}
+}
+
+func TestHandshakeWrongCode(t *testing.T) {
+	senderConn, receiverConn := net.Pipe()
+
+	sent := make(chan error, 1)
+	go func() {
+		_, err := handshake(senderConn, "123-456-789", true)
+		senderConn.Close()
+		sent <- err
+	}()
+
+	_, err := handshake(receiverConn, "123-456-780", false)
+	receiverConn.Close()
+	if err == nil {
+		t.Error("receiver completed a handshake with the wrong code")
+	}
+	if err := <-sent; !errors.Is(err, ErrWrongCode) {
+		t.Errorf("sender error = %v, want ErrWrongCode", err)
+	}
+}
+
+func TestReceiveRejectsOffer(t *testing.T) {
+	senderConn, receiverConn := net.Pipe()
+	defer senderConn.Close()
+	defer receiverConn.Close()
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- Send(senderConn, "111-222-333", Offer{Size: 10}, bytes.NewReader(make([]byte, 10)))
+	}()
+
+	refuse := errors.New("not now")
+	if _, err := Receive(receiverConn, "111-222-333", func(Offer) error { return refuse }, &bytes.Buffer{}); !errors.Is(err, refuse) {
+		t.Errorf("Receive error = %v, want %v", err, refuse)
+	}
+	if err := <-sent; !errors.Is(err, ErrRejected) {
+		t.Errorf("Send error = %v, want ErrRejected", err)
+	}
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/spf13/viper"
+
+	"github.com/kitsnail/gpasswd/internal/atomicfile"
+	"github.com/kitsnail/gpasswd/internal/securefs"
 )
 
 // Config represents the application configuration
@@ -15,11 +20,12 @@ type Config struct {
 	} `mapstructure:"database"`
 
 	Session struct {
-		Timeout int `mapstructure:"timeout"` // seconds, 0 = no timeout
+		Timeout int `mapstructure:"timeout"` // seconds, default length of 'gpasswd unlock' and agent idle timeout
 	} `mapstructure:"session"`
 
 	Clipboard struct {
-		ClearTimeout int `mapstructure:"clear_timeout"` // seconds
+		ClearTimeout        int `mapstructure:"clear_timeout"`         // seconds
+		HighSecurityTimeout int `mapstructure:"high_security_timeout"` // seconds, cap for high-security entries
 	} `mapstructure:"clipboard"`
 
 	PasswordGenerator struct {
@@ -33,7 +39,21 @@ type Config struct {
 
 	Security struct {
 		FailedAttemptsLimit int `mapstructure:"failed_attempts_limit"`
-		LockoutDuration     int `mapstructure:"lockout_duration"` // seconds
+		LockoutDuration     int `mapstructure:"lockout_duration"`    // seconds
+		FailedUnlockDelay   int `mapstructure:"failed_unlock_delay"` // seconds, doubled per consecutive failure, 0 = off
+
+		// UniformLookup hides whether an entry name exists: lookups fail with
+		// one generic error and take comparable time (hardened deployments)
+		UniformLookup bool `mapstructure:"uniform_lookup"`
+
+		// VerifyWrites reads back and decrypts every entry after it is
+		// written, to catch disk or driver corruption right away
+		VerifyWrites bool `mapstructure:"verify_writes"`
+
+		// ConfirmReveal asks y/n before a password of an entry carrying one
+		// of ConfirmRevealTags is printed or copied
+		ConfirmReveal     bool     `mapstructure:"confirm_reveal"`
+		ConfirmRevealTags []string `mapstructure:"confirm_reveal_tags"`
 
 		Argon2 struct {
 			Time        uint32 `mapstructure:"time"`
@@ -43,12 +63,95 @@ type Config struct {
 		} `mapstructure:"argon2"`
 	} `mapstructure:"security"`
 
+	Policy struct {
+		MinScore     int      `mapstructure:"min_score"`     // 0-100, 0 = disabled
+		MinEntropy   float64  `mapstructure:"min_entropy"`   // bits, 0 = disabled
+		Denylist     []string `mapstructure:"denylist"`      // never-allowed passwords
+		DenylistFile string   `mapstructure:"denylist_file"` // one password per line (.gz ok)
+
+		ExportMinScore int `mapstructure:"export_min_score"` // 0-100 for export passphrases, 0 = disabled
+	} `mapstructure:"policy"`
+
+	// Limits are soft size limits: exceeding them warns, nothing is refused
+	Limits struct {
+		VaultSizeMB int `mapstructure:"vault_size_mb"` // vault file, 0 = no warning
+		NotesSizeKB int `mapstructure:"notes_size_kb"` // notes of one entry, 0 = no warning
+	} `mapstructure:"limits"`
+
+	UnlockBanner struct {
+		Enabled     bool   `mapstructure:"enabled"`      // summarize vault hygiene after unlock
+		ExpireAfter string `mapstructure:"expire_after"` // password age counted as expired, e.g. "365d"
+	} `mapstructure:"unlock_banner"`
+
+	// Profiles are named bundles for 'gpasswd run --profile', each a list of
+	// VAR=entry[:field] bindings (a list because map keys are lower-cased)
+	Profiles map[string][]string `mapstructure:"profiles"`
+
+	// Templates are entry presets for 'gpasswd add --template <name>'
+	// Names are lower-cased like all map keys
+	Templates map[string]EntryTemplate `mapstructure:"templates"`
+
+	// Agent configures the background checks of 'gpasswd agent'
+	Agent struct {
+		CheckInterval string `mapstructure:"check_interval"` // audit unlocked vaults this often, e.g. "12h"; "" = off
+		PwnedFile     string `mapstructure:"pwned_file"`     // HIBP SHA-1 file for the breach check, "" = skip it
+		Notify        bool   `mapstructure:"notify"`         // desktop notification when findings change
+	} `mapstructure:"agent"`
+
+	// Pinentry configures 'pinentry-gpasswd' for gpg-agent
+	Pinentry struct {
+		// Fallback is the real pinentry for prompts the vault can't answer
+		// and for the master password; empty = first one found on PATH
+		Fallback string `mapstructure:"fallback"`
+	} `mapstructure:"pinentry"`
+
+	// Browser configures 'gpasswd browser-host'
+	Browser struct {
+		// MatchSubdomains offers the logins of a site on its subdomains too
+		// (github.com on gist.github.com); off, only the same origin matches
+		MatchSubdomains bool `mapstructure:"match_subdomains"`
+	} `mapstructure:"browser"`
+
 	Display struct {
 		ShowTimestamps bool   `mapstructure:"show_timestamps"`
-		DateFormat     string `mapstructure:"date_format"`
+		DateFormat     string `mapstructure:"date_format"`    // Go layout or YYYY-MM-DD HH:mm tokens
+		Timezone       string `mapstructure:"timezone"`       // IANA name, empty = local
+		RelativeTimes  bool   `mapstructure:"relative_times"` // "3 days ago"; --absolute overrides
+		SecureScreen   bool   `mapstructure:"secure_screen"`  // show secrets on the alternate screen
+
+		// PasswordFingerprint shows an emoji digest of the key derived from
+		// the entered master password, so a mistyped one can be recognized
+		PasswordFingerprint bool `mapstructure:"password_fingerprint"`
 	} `mapstructure:"display"`
 }
 
+// EntryTemplate presets a new entry; only its Fields are prompted for
+type EntryTemplate struct {
+	Type     string   `mapstructure:"type"`     // login (default) or token
+	Category string   `mapstructure:"category"` // default category
+	Tags     []string `mapstructure:"tags"`     // default tags
+	Security string   `mapstructure:"security"` // normal (default) or high
+
+	// Fields are prompted for, in order: username, password, url, notes,
+	// category, tags, service, expires, scopes. A login password not listed
+	// is generated
+	Fields []string `mapstructure:"fields"`
+
+	// Defaults preset field values (and prompt defaults), e.g. username: root
+	Defaults map[string]string `mapstructure:"defaults"`
+
+	// Generator overrides password_generator for generated passwords;
+	// unset options keep the password_generator value
+	Generator struct {
+		Length           int   `mapstructure:"length"`
+		UseUppercase     *bool `mapstructure:"use_uppercase"`
+		UseLowercase     *bool `mapstructure:"use_lowercase"`
+		UseDigits        *bool `mapstructure:"use_digits"`
+		UseSymbols       *bool `mapstructure:"use_symbols"`
+		ExcludeAmbiguous *bool `mapstructure:"exclude_ambiguous"`
+	} `mapstructure:"generator"`
+}
+
 // DefaultConfig returns a config with default values
 func DefaultConfig() *Config {
 	cfg := &Config{}
@@ -59,6 +162,7 @@ func DefaultConfig() *Config {
 	cfg.Session.Timeout = 300 // 5 minutes
 
 	cfg.Clipboard.ClearTimeout = 30
+	cfg.Clipboard.HighSecurityTimeout = 10
 
 	cfg.PasswordGenerator.Length = 20
 	cfg.PasswordGenerator.UseUppercase = true
@@ -69,24 +173,68 @@ func DefaultConfig() *Config {
 
 	cfg.Security.FailedAttemptsLimit = 5
 	cfg.Security.LockoutDuration = 30
+	cfg.Security.FailedUnlockDelay = 1
+	cfg.Security.UniformLookup = false
+	cfg.Security.VerifyWrites = false
+	cfg.Security.ConfirmReveal = false
+	cfg.Security.ConfirmRevealTags = []string{"high-security"}
 	cfg.Security.Argon2.Time = 3
 	cfg.Security.Argon2.Memory = 65536 // 64 MB
 	cfg.Security.Argon2.Parallelism = 4
 	cfg.Security.Argon2.KeyLength = 32
 
+	cfg.Policy.MinScore = 40 // Fair or better
+	cfg.Policy.MinEntropy = 0
+	cfg.Policy.Denylist = []string{}
+	cfg.Policy.ExportMinScore = 60 // Strong or better
+
+	cfg.Limits.VaultSizeMB = 50
+	cfg.Limits.NotesSizeKB = 64
+
+	cfg.UnlockBanner.Enabled = false
+	cfg.UnlockBanner.ExpireAfter = "365d"
+
+	cfg.Profiles = map[string][]string{}
+
+	cfg.Templates = map[string]EntryTemplate{}
+
+	cfg.Agent.CheckInterval = "12h"
+	cfg.Agent.PwnedFile = ""
+	cfg.Agent.Notify = true
+
+	cfg.Pinentry.Fallback = ""
+
+	cfg.Browser.MatchSubdomains = false
+
 	cfg.Display.ShowTimestamps = true
 	cfg.Display.DateFormat = "2006-01-02 15:04"
+	cfg.Display.Timezone = ""
+	cfg.Display.RelativeTimes = true
+	cfg.Display.SecureScreen = false
+	cfg.Display.PasswordFingerprint = false
 
 	return cfg
 }
 
 // GetConfigDir returns the configuration directory path
+// This is ~/.gpasswd, except on Windows, where new installs use
+// %LOCALAPPDATA%\gpasswd (local to the machine, never roamed to a server)
+// and an existing ~/.gpasswd keeps being used
 func GetConfigDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		panic(fmt.Sprintf("failed to get home directory: %v", err))
 	}
-	return filepath.Join(home, ".gpasswd")
+	legacy := filepath.Join(home, ".gpasswd")
+
+	if runtime.GOOS == "windows" {
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			if _, err := os.Stat(legacy); os.IsNotExist(err) {
+				return filepath.Join(local, "gpasswd")
+			}
+		}
+	}
+	return legacy
 }
 
 // GetVaultPath returns the path to the vault database
@@ -125,7 +273,7 @@ func (c *Config) Save() error {
 	configFile := filepath.Join(configDir, "config.yaml")
 
 	// Ensure config directory exists
-	if err := os.MkdirAll(configDir, 0700); err != nil {
+	if err := securefs.MkdirAll(configDir); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
@@ -138,16 +286,23 @@ func (c *Config) Save() error {
 	viper.Set("clipboard", c.Clipboard)
 	viper.Set("password_generator", c.PasswordGenerator)
 	viper.Set("security", c.Security)
+	viper.Set("policy", c.Policy)
+	viper.Set("limits", c.Limits)
+	viper.Set("unlock_banner", c.UnlockBanner)
+	viper.Set("profiles", c.Profiles)
+	viper.Set("templates", c.Templates)
+	viper.Set("agent", c.Agent)
+	viper.Set("pinentry", c.Pinentry)
+	viper.Set("browser", c.Browser)
 	viper.Set("display", c.Display)
 
-	if err := viper.WriteConfig(); err != nil {
-		// If config file doesn't exist, create it
-		if os.IsNotExist(err) {
-			if err := viper.SafeWriteConfig(); err != nil {
-				return fmt.Errorf("failed to write config: %w", err)
-			}
-			return nil
-		}
+	// Render first and replace the file in one step, so a crash never
+	// leaves a truncated config behind
+	var buf bytes.Buffer
+	if err := viper.WriteConfigTo(&buf); err != nil {
+		return fmt.Errorf("failed to render config: %w", err)
+	}
+	if err := atomicfile.WriteFile(configFile, buf.Bytes(), 0600); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
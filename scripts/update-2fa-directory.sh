@@ -0,0 +1,31 @@
+#!/bin/sh
+# Regenerate the bundled list of sites supporting TOTP used by
+# 'gpasswd audit --no-2fa'
+#
+# Usage: scripts/update-2fa-directory.sh [TOTP_JSON_URL_OR_FILE]
+#
+# The source is the 2fa.directory API's totp.json: an array of
+# [name, {"domain": ..., "additional-domains": [...], ...}] pairs. Domains
+# are lower-cased, de-duplicated and sorted into
+# internal/sites/data/totp-domains.txt. Needs jq
+
+set -eu
+
+SOURCE="${1:-https://api.2fa.directory/v3/totp.json}"
+OUT="$(dirname "$0")/../internal/sites/data/totp-domains.txt"
+
+case "$SOURCE" in
+    http://*|https://*) fetch() { curl -fsSL "$SOURCE"; } ;;
+    *) fetch() { cat "$SOURCE"; } ;;
+esac
+
+{
+    echo "# Domains of sites that support TOTP two-factor authentication"
+    echo "# Snapshot of the 2fa.directory dataset (https://2fa.directory, MIT license)"
+    echo "# Regenerate with scripts/update-2fa-directory.sh"
+    fetch | jq -r '.[] | .[1] | (.domain, (.["additional-domains"] // [])[])' |
+        tr -d '\r' | tr '[:upper:]' '[:lower:]' | sort -u
+} > "$OUT.tmp"
+mv "$OUT.tmp" "$OUT"
+
+echo "Wrote $(grep -vc '^#' "$OUT" | tr -d ' ') domains to $OUT"
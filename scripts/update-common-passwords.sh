@@ -0,0 +1,24 @@
+#!/bin/sh
+# Regenerate the bundled common-password corpus used by the strength checker
+#
+# Usage: scripts/update-common-passwords.sh [LIST_URL_OR_FILE]
+#
+# The source must contain one password per line, most common first (for
+# example SecLists' 10-million-password-list-top-100000.txt). Passwords are
+# lower-cased and de-duplicated before being compressed into
+# internal/crypto/data/common-passwords.txt.gz
+
+set -eu
+
+SOURCE="${1:-https://raw.githubusercontent.com/danielmiessler/SecLists/master/Passwords/Common-Credentials/10-million-password-list-top-100000.txt}"
+OUT="$(dirname "$0")/../internal/crypto/data/common-passwords.txt.gz"
+
+case "$SOURCE" in
+    http://*|https://*) fetch() { curl -fsSL "$SOURCE"; } ;;
+    *) fetch() { cat "$SOURCE"; } ;;
+esac
+
+fetch | tr -d '\r' | tr '[:upper:]' '[:lower:]' | awk 'length($0) > 0 && !seen[$0]++' | gzip -9n > "$OUT.tmp"
+mv "$OUT.tmp" "$OUT"
+
+echo "Wrote $(gzip -dc "$OUT" | wc -l | tr -d ' ') passwords to $OUT"